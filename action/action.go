@@ -0,0 +1,260 @@
+// Package action implements a GitHub Action that computes the SWHIDs of a
+// workflow's checked-out repository and publishes them as step outputs and
+// a job summary, so that every build or release records the identifiers
+// Software Heritage will archive it under.
+//
+// It reads its configuration from the environment GitHub Actions provides:
+// inputs as INPUT_<NAME> variables, the workspace, repository and ref as
+// GITHUB_* variables, and the files outputs and the summary are appended
+// to as GITHUB_OUTPUT and GITHUB_STEP_SUMMARY. The action.yml next to this
+// file runs it through cmd/swhid-action:
+//
+//	steps:
+//	  - uses: actions/checkout@v6
+//	  - id: swhid
+//	    uses: andrew/swhid-go/action@main
+//	  - run: echo "${{ steps.swhid.outputs.swhid }}"
+package action
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/andrew/swhid-go"
+)
+
+// Inputs are the action's inputs.
+type Inputs struct {
+	// Path is the file or directory to identify, relative to the
+	// workspace; "." identifies the whole repository.
+	Path string
+
+	// Summary writes a table of the identifiers to the job summary.
+	Summary bool
+}
+
+// InputsFromEnv reads the inputs from INPUT_* variables through getenv,
+// such as os.Getenv, applying the defaults action.yml declares.
+func InputsFromEnv(getenv func(string) string) (Inputs, error) {
+	in := Inputs{Path: input(getenv, "path"), Summary: true}
+	if in.Path == "" {
+		in.Path = "."
+	}
+	if s := input(getenv, "summary"); s != "" {
+		summary, err := strconv.ParseBool(s)
+		if err != nil {
+			return in, fmt.Errorf("invalid summary input %q: expected true or false", s)
+		}
+		in.Summary = summary
+	}
+	return in, nil
+}
+
+// input returns the input name, from the variable GitHub Actions sets for
+// it: INPUT_ and the name in upper case, spaces replaced by underscores.
+func input(getenv func(string) string, name string) string {
+	return strings.TrimSpace(getenv("INPUT_" + strings.ToUpper(strings.ReplaceAll(name, " ", "_"))))
+}
+
+// Result holds the identifiers computed for the workspace.
+type Result struct {
+	*swhid.Description
+
+	// Release is the annotated tag the workflow runs for, when it was
+	// triggered by one.
+	Release *swhid.Identifier
+
+	// Warnings are problems that left an identifier out, such as a
+	// lightweight tag, which has no release SWHID.
+	Warnings []string
+}
+
+// Compute describes in.Path below workspace with swhid.Describe, adds the
+// release SWHID when GITHUB_REF names a tag, and falls back to the
+// repository URL from GITHUB_SERVER_URL and GITHUB_REPOSITORY for the
+// origin when the checkout has no origin remote.
+func Compute(ctx context.Context, workspace string, in Inputs, getenv func(string) string) (*Result, error) {
+	path := in.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workspace, path)
+	}
+
+	d, err := swhid.Describe(ctx, path, swhid.TreeOptions{})
+	if err != nil {
+		return nil, err
+	}
+	r := &Result{Description: d}
+
+	if d.Origin == "" {
+		if server, repo := getenv("GITHUB_SERVER_URL"), getenv("GITHUB_REPOSITORY"); server != "" && repo != "" {
+			d.Origin = swhid.NormalizeOriginURL(strings.TrimSuffix(server, "/") + "/" + repo)
+			if d.Qualified != nil {
+				qualifiers := make(map[string]string, len(d.Qualified.Qualifiers)+1)
+				for k, v := range d.Qualified.Qualifiers {
+					qualifiers[k] = v
+				}
+				qualifiers[swhid.QualifierOrigin] = d.Origin
+				if d.Qualified, err = swhid.NewIdentifier(d.Qualified.ObjectType, d.Qualified.ObjectHash, qualifiers); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if tag, ok := strings.CutPrefix(getenv("GITHUB_REF"), "refs/tags/"); ok {
+		release, err := swhid.FromRelease(workspace, tag)
+		if err != nil {
+			r.Warnings = append(r.Warnings, fmt.Sprintf("no release SWHID for tag %s: %v", tag, err))
+		} else {
+			r.Release = release
+		}
+	}
+
+	return r, nil
+}
+
+// Output is a step output.
+type Output struct {
+	Name, Value string
+}
+
+// Outputs returns the step outputs, in the order action.yml declares them.
+// Identifiers that do not apply are empty.
+func (r *Result) Outputs() []Output {
+	str := func(id *swhid.Identifier) string {
+		if id == nil {
+			return ""
+		}
+		return id.String()
+	}
+
+	cited := r.Qualified
+	if cited == nil {
+		cited = r.Content
+	}
+	if cited == nil {
+		cited = r.Directory
+	}
+	browse := ""
+	if cited != nil {
+		browse = cited.QualifiedBrowseURL()
+	}
+
+	return []Output{
+		{"swhid", str(cited)},
+		{"content", str(r.Content)},
+		{"directory", str(r.Directory)},
+		{"revision", str(r.Revision)},
+		{"release", str(r.Release)},
+		{"origin", r.Origin},
+		{"browse-url", browse},
+	}
+}
+
+// WriteOutputs writes outputs in the format of the GITHUB_OUTPUT file:
+// name=value, or a heredoc for values spanning lines.
+func WriteOutputs(w io.Writer, outputs []Output) error {
+	for _, o := range outputs {
+		var err error
+		if strings.ContainsAny(o.Value, "\r\n") {
+			delimiter := "SWHID_EOF"
+			for strings.Contains(o.Value, delimiter) {
+				delimiter += "_"
+			}
+			_, err = fmt.Fprintf(w, "%s<<%s\n%s\n%s\n", o.Name, delimiter, o.Value, delimiter)
+		} else {
+			_, err = fmt.Fprintf(w, "%s=%s\n", o.Name, o.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Summary returns a Markdown table of the identifiers, each linked to the
+// archive, for the job summary.
+func (r *Result) Summary() string {
+	var b strings.Builder
+	b.WriteString("### Software Heritage identifiers\n\n")
+	b.WriteString("| Object | SWHID |\n|---|---|\n")
+	row := func(label string, id *swhid.Identifier) {
+		if id != nil {
+			fmt.Fprintf(&b, "| %s | [`%s`](%s) |\n", label, id, id.QualifiedBrowseURL())
+		}
+	}
+	row("Content", r.Content)
+	row("Directory", r.Directory)
+	row("Revision", r.Revision)
+	row("Release", r.Release)
+	if r.Origin != "" {
+		fmt.Fprintf(&b, "| Origin | %s |\n", r.Origin)
+	}
+	if r.Qualified != nil {
+		fmt.Fprintf(&b, "\nCite as [`%s`](%s)\n", r.Qualified, r.Qualified.QualifiedBrowseURL())
+	}
+	return b.String()
+}
+
+// Run computes the identifiers of the workspace as the inputs in getenv
+// request, appends them to the GITHUB_OUTPUT file and, unless disabled,
+// the job summary, and logs them to stdout, with warnings as workflow
+// commands. Without GITHUB_OUTPUT, as when run outside a workflow, the
+// outputs are only logged.
+func Run(ctx context.Context, getenv func(string) string, stdout io.Writer) error {
+	in, err := InputsFromEnv(getenv)
+	if err != nil {
+		return err
+	}
+	workspace := getenv("GITHUB_WORKSPACE")
+	if workspace == "" {
+		workspace = "."
+	}
+
+	r, err := Compute(ctx, workspace, in, getenv)
+	if err != nil {
+		return err
+	}
+
+	for _, w := range r.Warnings {
+		fmt.Fprintf(stdout, "::warning::%s\n", w)
+	}
+	outputs := r.Outputs()
+	for _, o := range outputs {
+		if o.Value != "" {
+			fmt.Fprintf(stdout, "%s: %s\n", o.Name, o.Value)
+		}
+	}
+
+	if name := getenv("GITHUB_OUTPUT"); name != "" {
+		if err := appendFile(name, func(w io.Writer) error { return WriteOutputs(w, outputs) }); err != nil {
+			return fmt.Errorf("failed to write outputs: %w", err)
+		}
+	}
+	if name := getenv("GITHUB_STEP_SUMMARY"); name != "" && in.Summary {
+		if err := appendFile(name, func(w io.Writer) error {
+			_, err := io.WriteString(w, r.Summary())
+			return err
+		}); err != nil {
+			return fmt.Errorf("failed to write job summary: %w", err)
+		}
+	}
+	return nil
+}
+
+func appendFile(name string, write func(io.Writer) error) error {
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,151 @@
+package action
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func env(vars map[string]string) func(string) string {
+	return func(name string) string { return vars[name] }
+}
+
+func TestInputsFromEnv(t *testing.T) {
+	in, err := InputsFromEnv(env(nil))
+	if err != nil {
+		t.Fatalf("InputsFromEnv() error = %v", err)
+	}
+	if in.Path != "." || !in.Summary {
+		t.Errorf("InputsFromEnv() = %+v, want defaults", in)
+	}
+
+	in, err = InputsFromEnv(env(map[string]string{"INPUT_PATH": " src ", "INPUT_SUMMARY": "false"}))
+	if err != nil {
+		t.Fatalf("InputsFromEnv() error = %v", err)
+	}
+	if in.Path != "src" || in.Summary {
+		t.Errorf("InputsFromEnv() = %+v, want src without summary", in)
+	}
+
+	if _, err := InputsFromEnv(env(map[string]string{"INPUT_SUMMARY": "sometimes"})); err == nil {
+		t.Error("InputsFromEnv() accepted an invalid summary")
+	}
+}
+
+func TestWriteOutputs(t *testing.T) {
+	var b bytes.Buffer
+	err := WriteOutputs(&b, []Output{{"swhid", "swh:1:dir:0000000000000000000000000000000000000000"}, {"note", "two\nlines"}, {"empty", ""}})
+	if err != nil {
+		t.Fatalf("WriteOutputs() error = %v", err)
+	}
+	want := "swhid=swh:1:dir:0000000000000000000000000000000000000000\nnote<<SWHID_EOF\ntwo\nlines\nSWHID_EOF\nempty=\n"
+	if b.String() != want {
+		t.Errorf("WriteOutputs() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestRun(t *testing.T) {
+	workspace := t.TempDir()
+	repo, err := git.PlainInit(workspace, false)
+	if err != nil {
+		t.Fatalf("Failed to init repository: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(workspace, "hello.txt"), []byte("hello\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Worktree() error = %v", err)
+	}
+	if _, err := wt.Add("hello.txt"); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	sig := &object.Signature{Name: "Test", Email: "test@example.com", When: time.Unix(1000000000, 0).UTC()}
+	commit, err := wt.Commit("Initial commit\n", &git.CommitOptions{Author: sig, Committer: sig})
+	if err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	tag, err := repo.CreateTag("v1.0.0", commit, &git.CreateTagOptions{Tagger: sig, Message: "v1.0.0\n"})
+	if err != nil {
+		t.Fatalf("CreateTag() error = %v", err)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference("refs/tags/light", commit)); err != nil {
+		t.Fatalf("SetReference() error = %v", err)
+	}
+
+	outputFile := filepath.Join(t.TempDir(), "output")
+	summaryFile := filepath.Join(t.TempDir(), "summary")
+	vars := map[string]string{
+		"GITHUB_WORKSPACE":    workspace,
+		"GITHUB_SERVER_URL":   "https://github.com",
+		"GITHUB_REPOSITORY":   "example/project",
+		"GITHUB_REF":          "refs/tags/v1.0.0",
+		"GITHUB_OUTPUT":       outputFile,
+		"GITHUB_STEP_SUMMARY": summaryFile,
+	}
+
+	var stdout bytes.Buffer
+	if err := Run(context.Background(), env(vars), &stdout); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	data, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	outputs := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		name, value, _ := strings.Cut(line, "=")
+		outputs[name] = value
+	}
+
+	if got, want := outputs["revision"], "swh:1:rev:"+commit.String(); got != want {
+		t.Errorf("revision = %q, want %q", got, want)
+	}
+	if got, want := outputs["release"], "swh:1:rel:"+tag.Hash().String(); got != want {
+		t.Errorf("release = %q, want %q", got, want)
+	}
+	if got, want := outputs["origin"], "https://github.com/example/project"; got != want {
+		t.Errorf("origin = %q, want %q", got, want)
+	}
+	if !strings.HasPrefix(outputs["swhid"], outputs["directory"]+";origin=https://github.com/example/project;anchor=swh:1:rev:") {
+		t.Errorf("swhid = %q, want directory %s qualified with origin and anchor", outputs["swhid"], outputs["directory"])
+	}
+	if outputs["content"] != "" {
+		t.Errorf("content = %q, want empty for a directory", outputs["content"])
+	}
+
+	summary, err := os.ReadFile(summaryFile)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !strings.Contains(string(summary), "| Release | [`"+outputs["release"]+"`]") {
+		t.Errorf("summary = %q, missing release row", summary)
+	}
+
+	// A lightweight tag has no release SWHID: a warning, not a failure.
+	vars["GITHUB_REF"] = "refs/tags/light"
+	vars["INPUT_PATH"] = "hello.txt"
+	vars["INPUT_SUMMARY"] = "false"
+	stdout.Reset()
+	if err := Run(context.Background(), env(vars), &stdout); err != nil {
+		t.Fatalf("Run(light) error = %v", err)
+	}
+	if !strings.Contains(stdout.String(), "::warning::no release SWHID for tag light") {
+		t.Errorf("Run(light) stdout = %q, want a warning", stdout.String())
+	}
+	if !strings.Contains(stdout.String(), "content: swh:1:cnt:ce013625030ba8dba906f756967f9e9ca394464a\n") {
+		t.Errorf("Run(light) stdout = %q, want the content SWHID", stdout.String())
+	}
+	if after, err := os.ReadFile(summaryFile); err != nil || !bytes.Equal(after, summary) {
+		t.Errorf("Run() with summary false changed the summary")
+	}
+}
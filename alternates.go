@@ -0,0 +1,89 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+// alternatesPath is where a repository lists the object directories of
+// other repositories it borrows objects from, as git clone --shared and
+// forges deduplicating forks set up.
+const alternatesPath = "objects/info/alternates"
+
+// alternatesFS serves go-git the object directories named in a
+// repository's alternates file. go-git takes each path in the file as
+// relative to the filesystem it is given, reading absolute paths from its
+// root and dropping any "../" from relative ones, which Git resolves from
+// the objects directory. alternatesFS maps the paths go-git asks for back
+// to the directories Git would use.
+type alternatesFS struct {
+	billy.Filesystem
+	paths map[string]string
+}
+
+// newAlternatesFS returns the filesystem for the alternates of the
+// repository whose Git directory dotGit is, or nil if it has none.
+func newAlternatesFS(dotGit billy.Filesystem) (billy.Filesystem, error) {
+	data, err := util.ReadFile(dotGit, alternatesPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Relative paths start from the objects directory, which linked
+	// worktrees share with the main repository through commondir.
+	gitDir := dotGit.Root()
+	if common, err := util.ReadFile(dotGit, "commondir"); err == nil {
+		if dir := strings.TrimSpace(string(common)); filepath.IsAbs(dir) {
+			gitDir = dir
+		} else {
+			gitDir = filepath.Join(gitDir, dir)
+		}
+	}
+	objectsDir := filepath.Join(gitDir, "objects")
+	fs := &alternatesFS{Filesystem: osfs.New("/"), paths: make(map[string]string)}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		asked, dir := line, line
+		if !filepath.IsAbs(line) {
+			asked = filepath.FromSlash(filepath.Join(string(filepath.Separator), filepath.ToSlash(line)))
+			dir = filepath.Join(objectsDir, line)
+		}
+		// go-git checks the objects directory and then opens its parent as
+		// the alternate's Git directory.
+		fs.paths[asked] = dir
+		fs.paths[filepath.Dir(asked)] = filepath.Dir(dir)
+	}
+	return fs, scanner.Err()
+}
+
+func (fs *alternatesFS) resolve(path string) string {
+	if dir, ok := fs.paths[path]; ok {
+		return dir
+	}
+	return path
+}
+
+func (fs *alternatesFS) Stat(path string) (os.FileInfo, error) {
+	return os.Stat(fs.resolve(path))
+}
+
+func (fs *alternatesFS) Chroot(path string) (billy.Filesystem, error) {
+	return osfs.New(fs.resolve(path)), nil
+}
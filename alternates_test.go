@@ -0,0 +1,142 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// newSharedRepo creates a bare repository holding no objects of its own,
+// only the references of base and an alternates file pointing at base's
+// objects, as git clone --shared does. alternate turns the path of base's
+// objects directory into the line written to the file.
+func newSharedRepo(t *testing.T, base *git.Repository, baseObjects string, alternate func(objectsDir, baseObjects string) string) string {
+	t.Helper()
+	dir := t.TempDir()
+	shared, err := git.PlainInit(dir, true)
+	if err != nil {
+		t.Fatalf("Failed to init shared repo: %v", err)
+	}
+	line := alternate(filepath.Join(dir, "objects"), baseObjects)
+	if err := os.WriteFile(filepath.Join(dir, alternatesPath), []byte(line+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write alternates: %v", err)
+	}
+	refs, err := base.References()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference {
+			return shared.Storer.SetReference(ref)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to copy references: %v", err)
+	}
+	return dir
+}
+
+func TestAlternates(t *testing.T) {
+	basePath, base, hash := newTestRepo(t)
+	sig := &object.Signature{Name: "Test", Email: "test@example.com", When: time.Unix(1000000000, 0).UTC()}
+	if _, err := base.CreateTag("v1.0.0", hash, &git.CreateTagOptions{Tagger: sig, Message: "v1.0.0\n"}); err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+	baseObjects := filepath.Join(basePath, ".git", "objects")
+
+	wantRev, _ := FromRevision(basePath, "HEAD")
+	wantRel, _ := FromRelease(basePath, "v1.0.0")
+	wantSnp, _ := FromSnapshot(basePath)
+
+	alternates := map[string]func(objectsDir, baseObjects string) string{
+		"absolute": func(_, baseObjects string) string { return baseObjects },
+		"relative": func(objectsDir, baseObjects string) string {
+			rel, err := filepath.Rel(objectsDir, baseObjects)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return filepath.ToSlash(rel)
+		},
+	}
+	for name, alternate := range alternates {
+		sharedPath := newSharedRepo(t, base, baseObjects, alternate)
+		for _, readOnly := range []bool{false, true} {
+			opts := GitOptions{ReadOnlyFS: readOnly}
+
+			rev, err := FromRevisionWithOptions(sharedPath, "HEAD", opts)
+			if err != nil {
+				t.Fatalf("%s: FromRevisionWithOptions() error = %v", name, err)
+			}
+			if !rev.Equal(wantRev) {
+				t.Errorf("%s: FromRevisionWithOptions() = %v, want %v", name, rev, wantRev)
+			}
+
+			rel, err := FromReleaseWithOptions(sharedPath, "v1.0.0", opts)
+			if err != nil {
+				t.Fatalf("%s: FromReleaseWithOptions() error = %v", name, err)
+			}
+			if !rel.Equal(wantRel) {
+				t.Errorf("%s: FromReleaseWithOptions() = %v, want %v", name, rel, wantRel)
+			}
+
+			snp, err := FromSnapshotWithOptions(sharedPath, opts)
+			if err != nil {
+				t.Fatalf("%s: FromSnapshotWithOptions() error = %v", name, err)
+			}
+			if !snp.Equal(wantSnp) {
+				t.Errorf("%s: FromSnapshotWithOptions() = %v, want %v", name, snp, wantSnp)
+			}
+		}
+	}
+}
+
+func TestAlternatesMissing(t *testing.T) {
+	_, base, _ := newTestRepo(t)
+	sharedPath := newSharedRepo(t, base, "", func(string, string) string { return "/nonexistent/objects" })
+	if _, err := FromRevision(sharedPath, "HEAD"); err == nil {
+		t.Error("FromRevision() succeeded without the alternate's objects")
+	}
+}
+
+func TestAlternatesLinkedWorktree(t *testing.T) {
+	basePath, base, hash := newTestRepo(t)
+	sharedPath := newSharedRepo(t, base, filepath.Join(basePath, ".git", "objects"), func(objectsDir, baseObjects string) string {
+		rel, err := filepath.Rel(objectsDir, baseObjects)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rel
+	})
+
+	// Relative alternates start from the shared objects directory, not the
+	// worktree's own Git directory
+	wtGitDir := filepath.Join(sharedPath, "worktrees", "wt")
+	wtPath := filepath.Join(t.TempDir(), "wt")
+	for path, content := range map[string]string{
+		filepath.Join(wtGitDir, "HEAD"):      hash.String() + "\n",
+		filepath.Join(wtGitDir, "commondir"): "../..\n",
+		filepath.Join(wtPath, ".git"):        "gitdir: " + wtGitDir + "\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rev, err := FromRevision(wtPath, "HEAD")
+	if err != nil {
+		t.Fatalf("FromRevision() error = %v", err)
+	}
+	if rev.ObjectHash != hash.String() {
+		t.Errorf("FromRevision() = %v, want %v", rev.ObjectHash, hash)
+	}
+}
@@ -0,0 +1,202 @@
+package swhid
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encodings reported by AnalyzeContent.
+const (
+	EncodingBinary  = "binary"   // a NUL byte near the start, as Git decides
+	EncodingASCII   = "ascii"    // 7-bit text, the same in every encoding below
+	EncodingUTF8    = "utf-8"    // valid UTF-8 beyond ASCII
+	EncodingUTF16LE = "utf-16le" // UTF-16 with a little-endian byte order mark
+	EncodingUTF16BE = "utf-16be" // UTF-16 with a big-endian byte order mark
+	EncodingOther   = "8-bit"    // neither of those, such as Latin-1 or Windows-1252
+)
+
+// Line ending styles reported by AnalyzeContent.
+const (
+	EOLNone  = "none"  // no line endings
+	EOLLF    = "lf"    // Unix
+	EOLCRLF  = "crlf"  // Windows
+	EOLCR    = "cr"    // classic Mac OS
+	EOLMixed = "mixed" // more than one of them
+)
+
+// Normalizations that ContentVariant reports.
+const (
+	NormalizeLF           = "lf"            // line endings turned into LF, as Git stores text with eol=lf or core.autocrlf
+	NormalizeCRLF         = "crlf"          // line endings turned into CRLF, as a Windows checkout writes them
+	NormalizeNoBOM        = "no-bom"        // the byte order mark removed
+	NormalizeUTF8         = "utf-8"         // UTF-16 transcoded to UTF-8 without a byte order mark
+	NormalizeFinalNewline = "final-newline" // a line ending added after the last line, as many editors do
+	NormalizeCanonical    = "canonical"     // all of the above but CRLF: UTF-8, no byte order mark, LF, final newline
+)
+
+// ContentAnalysis describes the bytes behind a content SWHID: how its text
+// is encoded and its lines end, and the SWHIDs it would have after the
+// normalizations editors, Git and file transfers commonly apply. Two files
+// that look the same but hash differently usually differ in one of these.
+type ContentAnalysis struct {
+	SWHID        *Identifier
+	Size         int
+	Encoding     string
+	BOM          bool // starts with a byte order mark
+	EOL          string
+	LF           int  // line endings of each kind
+	CRLF         int  //
+	CR           int  //
+	FinalNewline bool // the text ends with a line ending
+
+	// Variants lists the normalizations that change the content, in the
+	// order of the Normalize constants, each with the SWHID it leads to.
+	// Binary content has none.
+	Variants []ContentVariant
+}
+
+// ContentVariant is the SWHID content would have after a normalization.
+type ContentVariant struct {
+	Normalization string
+	SWHID         *Identifier
+}
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+)
+
+// AnalyzeContent returns the content SWHID of data with a description of
+// its encoding and line endings, and the SWHIDs of its normalized forms.
+// UTF-16 is only recognized by its byte order mark.
+func AnalyzeContent(data []byte) *ContentAnalysis {
+	a := &ContentAnalysis{SWHID: FromContent(data), Size: len(data)}
+
+	var (
+		text  string // the decoded text, without byte order mark
+		order interface {
+			binary.ByteOrder
+			binary.AppendByteOrder
+		}
+	)
+	switch {
+	case bytes.HasPrefix(data, bomUTF16LE) && len(data)%2 == 0:
+		a.Encoding, a.BOM, order = EncodingUTF16LE, true, binary.LittleEndian
+	case bytes.HasPrefix(data, bomUTF16BE) && len(data)%2 == 0:
+		a.Encoding, a.BOM, order = EncodingUTF16BE, true, binary.BigEndian
+	case isBinary(data):
+		a.Encoding, a.EOL = EncodingBinary, EOLNone
+		return a
+	case bytes.HasPrefix(data, bomUTF8):
+		a.Encoding, a.BOM = EncodingUTF8, true
+		text = string(data[len(bomUTF8):])
+		if !utf8.ValidString(text) {
+			a.Encoding = EncodingOther
+		}
+	default:
+		text = string(data)
+		switch {
+		case !utf8.ValidString(text):
+			a.Encoding = EncodingOther
+		case strings.IndexFunc(text, func(r rune) bool { return r >= utf8.RuneSelf }) >= 0:
+			a.Encoding = EncodingUTF8
+		default:
+			a.Encoding = EncodingASCII
+		}
+	}
+	if order != nil {
+		units := make([]uint16, len(data)/2-1)
+		for i := range units {
+			units[i] = order.Uint16(data[2+2*i:])
+		}
+		text = string(utf16.Decode(units))
+	}
+
+	a.CRLF = strings.Count(text, "\r\n")
+	a.LF = strings.Count(text, "\n") - a.CRLF
+	a.CR = strings.Count(text, "\r") - a.CRLF
+	a.FinalNewline = strings.HasSuffix(text, "\n") || strings.HasSuffix(text, "\r")
+	a.EOL = eolStyle(a.LF, a.CRLF, a.CR)
+
+	// encode writes text back in the content's encoding, with or without
+	// its byte order mark.
+	encode := func(text string, bom bool) []byte {
+		var out []byte
+		switch {
+		case order != nil:
+			if bom {
+				out = order.AppendUint16(out, 0xfeff)
+			}
+			for _, u := range utf16.Encode([]rune(text)) {
+				out = order.AppendUint16(out, u)
+			}
+			return out
+		case bom:
+			out = append(out, bomUTF8...)
+		}
+		return append(out, text...)
+	}
+	lf := strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+	withFinal := func(s string) string {
+		if s == "" || strings.HasSuffix(s, "\n") || strings.HasSuffix(s, "\r") {
+			return s
+		}
+		return s + "\n"
+	}
+	canonical := withFinal(lf)
+
+	variants := []struct {
+		name string
+		data []byte
+	}{
+		{NormalizeLF, encode(lf, a.BOM)},
+		{NormalizeCRLF, encode(strings.ReplaceAll(lf, "\n", "\r\n"), a.BOM)},
+		{NormalizeNoBOM, encode(text, false)},
+		{NormalizeUTF8, []byte(text)},
+		{NormalizeFinalNewline, encode(withFinal(text), a.BOM)},
+		{NormalizeCanonical, []byte(canonical)},
+	}
+	if order == nil {
+		variants[3].data = data // only UTF-16 is transcoded
+	}
+	for _, v := range variants {
+		id := FromContent(v.data)
+		if id.ObjectHash == a.SWHID.ObjectHash {
+			continue
+		}
+		a.Variants = append(a.Variants, ContentVariant{Normalization: v.name, SWHID: id})
+	}
+	return a
+}
+
+// Variant returns the SWHID after the named normalization, or the content
+// SWHID itself if the normalization leaves the content unchanged.
+func (a *ContentAnalysis) Variant(normalization string) *Identifier {
+	for _, v := range a.Variants {
+		if v.Normalization == normalization {
+			return v.SWHID
+		}
+	}
+	return a.SWHID
+}
+
+func eolStyle(lf, crlf, cr int) string {
+	styles, style := 0, EOLNone
+	for _, s := range []struct {
+		n    int
+		name string
+	}{{lf, EOLLF}, {crlf, EOLCRLF}, {cr, EOLCR}} {
+		if s.n > 0 {
+			styles++
+			style = s.name
+		}
+	}
+	if styles > 1 {
+		return EOLMixed
+	}
+	return style
+}
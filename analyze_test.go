@@ -0,0 +1,71 @@
+package swhid
+
+import (
+	"testing"
+)
+
+func TestAnalyzeContent(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		encoding string
+		bom      bool
+		eol      string
+		final    bool
+		variants []string
+	}{
+		{"unix", "hello\nworld\n", EncodingASCII, false, EOLLF, true, []string{NormalizeCRLF}},
+		{"windows", "hello\r\nworld\r\n", EncodingASCII, false, EOLCRLF, true, []string{NormalizeLF, NormalizeCanonical}},
+		{"mixed", "hello\r\nworld\n", EncodingASCII, false, EOLMixed, true, []string{NormalizeLF, NormalizeCRLF, NormalizeCanonical}},
+		{"no final newline", "héllo", EncodingUTF8, false, EOLNone, false, []string{NormalizeFinalNewline, NormalizeCanonical}},
+		{"utf-8 bom", "\xef\xbb\xbfhello\n", EncodingUTF8, true, EOLLF, true, []string{NormalizeCRLF, NormalizeNoBOM, NormalizeCanonical}},
+		{"utf-16le", "\xff\xfeh\x00i\x00\r\x00\n\x00", EncodingUTF16LE, true, EOLCRLF, true, []string{NormalizeLF, NormalizeNoBOM, NormalizeUTF8, NormalizeCanonical}},
+		{"latin-1", "caf\xe9\n", EncodingOther, false, EOLLF, true, []string{NormalizeCRLF}},
+		{"binary", "\x00\x01\r\n", EncodingBinary, false, EOLNone, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := AnalyzeContent([]byte(tt.data))
+			if !a.SWHID.Equal(FromContent([]byte(tt.data))) || a.Size != len(tt.data) {
+				t.Errorf("AnalyzeContent() SWHID = %v, size %d", a.SWHID, a.Size)
+			}
+			if a.Encoding != tt.encoding || a.BOM != tt.bom || a.EOL != tt.eol || a.FinalNewline != tt.final {
+				t.Errorf("AnalyzeContent() = %s bom=%v %s final=%v, want %s bom=%v %s final=%v",
+					a.Encoding, a.BOM, a.EOL, a.FinalNewline, tt.encoding, tt.bom, tt.eol, tt.final)
+			}
+			var got []string
+			for _, v := range a.Variants {
+				got = append(got, v.Normalization)
+			}
+			if len(got) != len(tt.variants) {
+				t.Fatalf("AnalyzeContent() variants = %v, want %v", got, tt.variants)
+			}
+			for i := range got {
+				if got[i] != tt.variants[i] {
+					t.Errorf("AnalyzeContent() variants = %v, want %v", got, tt.variants)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzeContentVariants(t *testing.T) {
+	// A Windows checkout and the repository's copy of the same file
+	windows := AnalyzeContent([]byte("\xef\xbb\xbfline one\r\nline two"))
+	unix := FromContent([]byte("line one\nline two\n"))
+	if got := windows.Variant(NormalizeCanonical); !got.Equal(unix) {
+		t.Errorf("Variant(canonical) = %v, want %v", got, unix)
+	}
+	if got := windows.Variant(NormalizeUTF8); !got.Equal(windows.SWHID) {
+		t.Errorf("Variant(utf-8) of UTF-8 = %v, want the content SWHID %v", got, windows.SWHID)
+	}
+
+	utf16 := AnalyzeContent([]byte("\xfe\xff\x00h\x00i\x00\n"))
+	if got, want := utf16.Variant(NormalizeUTF8), FromContent([]byte("hi\n")); !got.Equal(want) {
+		t.Errorf("Variant(utf-8) of UTF-16 = %v, want %v", got, want)
+	}
+	if utf16.LF != 1 || utf16.CRLF != 0 {
+		t.Errorf("UTF-16 line endings = %d LF, %d CRLF", utf16.LF, utf16.CRLF)
+	}
+}
@@ -0,0 +1,90 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/objects"
+)
+
+// Cache stores archive objects by SWHID. Contents are stored as their raw
+// bytes and directories as Git tree payloads (objects.SerializeDirectory),
+// so every stored object can be checked against its SWHID. Objects never
+// change, so entries do not expire.
+type Cache interface {
+	Get(id *swhid.Identifier) ([]byte, bool)
+	Put(id *swhid.Identifier, data []byte) error
+}
+
+// DiskCache is a Cache in a local directory, laid out as
+// <Dir>/<type>/<first two hex digits>/<remaining hex digits>. Entries that
+// no longer match their SWHID, for instance after a partial write or disk
+// corruption, are removed and reported as misses.
+type DiskCache struct {
+	Dir string
+}
+
+// DefaultCacheDir returns the per-user cache directory for archive objects.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "swhid", "objects"), nil
+}
+
+func (c *DiskCache) path(id *swhid.Identifier) string {
+	return filepath.Join(c.Dir, string(id.ObjectType), id.ObjectHash[:2], id.ObjectHash[2:])
+}
+
+// Get returns the cached object for id, if present and intact.
+func (c *DiskCache) Get(id *swhid.Identifier) ([]byte, bool) {
+	p := c.path(id)
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, false
+	}
+	if !matches(id, data) {
+		os.Remove(p)
+		return nil, false
+	}
+	return data, true
+}
+
+// Put stores data for id. The file is written under a temporary name and
+// renamed, so concurrent readers never see partial entries.
+func (c *DiskCache) Put(id *swhid.Identifier, data []byte) error {
+	p := c.path(id)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(p), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), p)
+}
+
+// matches reports whether data is the cached form of the object id names.
+func matches(id *swhid.Identifier, data []byte) bool {
+	switch id.ObjectType {
+	case swhid.ObjectTypeContent:
+		return objects.ComputeContentHash(data) == id.ObjectHash
+	case swhid.ObjectTypeDirectory:
+		entries, err := objects.ParseDirectory(data)
+		return err == nil && objects.ComputeDirectoryHash(entries) == id.ObjectHash
+	default:
+		return false
+	}
+}
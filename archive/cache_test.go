@@ -0,0 +1,38 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrew/swhid-go"
+)
+
+func TestDiskCache(t *testing.T) {
+	dir := t.TempDir()
+	c := &DiskCache{Dir: dir}
+	data := []byte("hello\n")
+	id := swhid.FromContent(data)
+
+	if _, ok := c.Get(id); ok {
+		t.Fatal("Get() hit on an empty cache")
+	}
+	if err := c.Put(id, data); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	got, ok := c.Get(id)
+	if !ok || string(got) != string(data) {
+		t.Fatalf("Get() = %q, %v", got, ok)
+	}
+
+	p := filepath.Join(dir, "cnt", id.ObjectHash[:2], id.ObjectHash[2:])
+	if err := os.WriteFile(p, []byte("corrupt"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get(id); ok {
+		t.Error("Get() returned a corrupt entry")
+	}
+	if _, err := os.Stat(p); !os.IsNotExist(err) {
+		t.Errorf("corrupt entry was not removed: %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+// Package archive fetches objects from the Software Heritage archive API,
+// checking each one against the SWHID it was requested by and optionally
+// keeping a local cache so repeated runs do not download them again.
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/objects"
+)
+
+// ErrNotFound is returned for objects the archive does not hold.
+var ErrNotFound = errors.New("object not found in the archive")
+
+// ErrMismatch is returned when the archive answers with data that does not
+// hash to the requested SWHID.
+var ErrMismatch = errors.New("archive object does not match its SWHID")
+
+// Client fetches contents and directory listings from the archive API.
+type Client struct {
+	BaseURL    string       // defaults to swhid.ArchiveURL
+	Token      string       // API token sent as a bearer token, if set
+	HTTPClient *http.Client // defaults to http.DefaultClient
+	Cache      Cache        // consulted before and filled after each fetch, if set
+}
+
+// Content returns the bytes of a content object.
+func (c *Client) Content(ctx context.Context, id *swhid.Identifier) ([]byte, error) {
+	if id.ObjectType != swhid.ObjectTypeContent {
+		return nil, fmt.Errorf("archive: %s is not a content SWHID", id.CoreSWHID())
+	}
+	return c.object(ctx, id, func() ([]byte, error) {
+		return c.get(ctx, "/api/1/content/sha1_git:"+id.ObjectHash+"/raw/")
+	})
+}
+
+// Directory returns the entries of a directory object.
+func (c *Client) Directory(ctx context.Context, id *swhid.Identifier) ([]objects.DirectoryEntry, error) {
+	if id.ObjectType != swhid.ObjectTypeDirectory {
+		return nil, fmt.Errorf("archive: %s is not a directory SWHID", id.CoreSWHID())
+	}
+	data, err := c.object(ctx, id, func() ([]byte, error) {
+		entries, err := c.listDirectory(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return objects.SerializeDirectory(entries), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objects.ParseDirectory(data)
+}
+
+// object returns the cached form of id, fetching and caching it on a miss.
+func (c *Client) object(ctx context.Context, id *swhid.Identifier, fetch func() ([]byte, error)) ([]byte, error) {
+	if c.Cache != nil {
+		if data, ok := c.Cache.Get(id); ok {
+			return data, nil
+		}
+	}
+
+	data, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	if !matches(id, data) {
+		return nil, fmt.Errorf("archive: %s: %w", id.CoreSWHID(), ErrMismatch)
+	}
+
+	if c.Cache != nil {
+		if err := c.Cache.Put(id, data); err != nil {
+			return nil, fmt.Errorf("archive: caching %s: %w", id.CoreSWHID(), err)
+		}
+	}
+	return data, nil
+}
+
+// apiDirectoryEntry is one element of the /api/1/directory/ response.
+type apiDirectoryEntry struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Target string `json:"target"`
+	Perms  int    `json:"perms"`
+}
+
+func (c *Client) listDirectory(ctx context.Context, id *swhid.Identifier) ([]objects.DirectoryEntry, error) {
+	body, err := c.get(ctx, "/api/1/directory/"+id.ObjectHash+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	var listing []apiDirectoryEntry
+	if err := json.Unmarshal(body, &listing); err != nil {
+		return nil, fmt.Errorf("archive: invalid directory listing for %s: %w", id.CoreSWHID(), err)
+	}
+
+	entries := make([]objects.DirectoryEntry, len(listing))
+	for i, e := range listing {
+		perms := strconv.FormatInt(int64(e.Perms), 8)
+		entries[i] = objects.DirectoryEntry{Name: e.Name, Target: e.Target, Perms: perms}
+		switch {
+		case e.Type == "dir":
+			entries[i].Type = objects.EntryTypeDirectory
+		case e.Type == "rev":
+			entries[i].Type = objects.EntryTypeRevision
+		case perms == "120000":
+			entries[i].Type = objects.EntryTypeSymlink
+		case perms == "100755":
+			entries[i].Type = objects.EntryTypeExecutable
+		default:
+			entries[i].Type = objects.EntryTypeFile
+		}
+	}
+	return entries, nil
+}
+
+func (c *Client) baseURL() string {
+	if c.BaseURL == "" {
+		return swhid.ArchiveURL
+	}
+	return strings.TrimSuffix(c.BaseURL, "/")
+}
+
+func (c *Client) get(ctx context.Context, path string) ([]byte, error) {
+	return c.getURL(ctx, c.baseURL()+path)
+}
+
+// getURL fetches an absolute URL, such as one returned by the API. The
+// token is only sent to the archive itself.
+func (c *Client) getURL(ctx context.Context, u string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.Token != "" && strings.HasPrefix(u, c.baseURL()+"/") {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("archive: %s: %w", u, ErrNotFound)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("failed to fetch %s: %s", u, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,117 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/objects"
+)
+
+func TestClientContent(t *testing.T) {
+	data := []byte("hello\n")
+	id := swhid.FromContent(data)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/api/1/content/sha1_git:"+id.ObjectHash+"/raw/" {
+			t.Errorf("request path = %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	c := &Client{BaseURL: server.URL, Token: "secret", Cache: &DiskCache{Dir: t.TempDir()}}
+	for i := 0; i < 2; i++ {
+		got, err := c.Content(context.Background(), id)
+		if err != nil {
+			t.Fatalf("Content() error = %v", err)
+		}
+		if string(got) != string(data) {
+			t.Errorf("Content() = %q, want %q", got, data)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("made %d requests, want 1", requests)
+	}
+}
+
+func TestClientDirectory(t *testing.T) {
+	file := swhid.FromContent([]byte("hello\n"))
+	entries := []objects.DirectoryEntry{
+		{Name: "README", Type: objects.EntryTypeFile, Target: file.ObjectHash},
+		{Name: "run.sh", Type: objects.EntryTypeExecutable, Target: file.ObjectHash},
+		{Name: "src", Type: objects.EntryTypeDirectory, Target: objects.ComputeDirectoryHash(nil)},
+	}
+	hash := objects.ComputeDirectoryHash(entries)
+	id, _ := swhid.NewIdentifier(swhid.ObjectTypeDirectory, hash, nil)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/api/1/directory/"+hash+"/" {
+			t.Errorf("request path = %s", r.URL.Path)
+		}
+		fmt.Fprintf(w, `[
+			{"name": "README", "type": "file", "target": %q, "perms": 33188},
+			{"name": "run.sh", "type": "file", "target": %q, "perms": 33261},
+			{"name": "src", "type": "dir", "target": %q, "perms": 16384}
+		]`, file.ObjectHash, file.ObjectHash, entries[2].Target)
+	}))
+	defer server.Close()
+
+	c := &Client{BaseURL: server.URL, Cache: &DiskCache{Dir: t.TempDir()}}
+	for i := 0; i < 2; i++ {
+		got, err := c.Directory(context.Background(), id)
+		if err != nil {
+			t.Fatalf("Directory() error = %v", err)
+		}
+		if len(got) != 3 {
+			t.Fatalf("Directory() returned %d entries, want 3", len(got))
+		}
+		for j, e := range got {
+			if e.Name != entries[j].Name || e.Type != entries[j].Type || e.Target != entries[j].Target {
+				t.Errorf("entry %d = %+v, want %+v", j, e, entries[j])
+			}
+		}
+	}
+	if requests != 1 {
+		t.Errorf("made %d requests, want 1", requests)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/1/content/sha1_git:"+swhid.FromContent([]byte("missing")).ObjectHash+"/raw/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("tampered"))
+	}))
+	defer server.Close()
+
+	cache := &DiskCache{Dir: t.TempDir()}
+	c := &Client{BaseURL: server.URL, Cache: cache}
+
+	_, err := c.Content(context.Background(), swhid.FromContent([]byte("missing")))
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Content() error = %v, want ErrNotFound", err)
+	}
+
+	id := swhid.FromContent([]byte("original"))
+	_, err = c.Content(context.Background(), id)
+	if !errors.Is(err, ErrMismatch) {
+		t.Errorf("Content() error = %v, want ErrMismatch", err)
+	}
+	if _, ok := cache.Get(id); ok {
+		t.Error("mismatched object was cached")
+	}
+}
@@ -0,0 +1,255 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/andrew/swhid-go"
+)
+
+// Citation gathers what the archive knows about where an object came from
+// and how to cite it: the extrinsic metadata recorded for the object and
+// its origins, such as deposit metadata sent by HAL or Zenodo, and the DOIs
+// found in that metadata.
+type Citation struct {
+	SWHID    *swhid.Identifier
+	Origins  []string   // origins named by the SWHID or the metadata
+	DOIs     []string   // normalized to "10.<registrant>/<suffix>"
+	Metadata []Metadata // object metadata first, then origin metadata
+}
+
+// Authority is the agent that vouches for a metadata record, such as a
+// deposit client or a forge.
+type Authority struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// Metadata is one raw extrinsic metadata record.
+type Metadata struct {
+	Target        string // SWHID or origin URL the record describes
+	Authority     Authority
+	Fetcher       string // name and version of the tool that fetched it
+	Format        string // e.g. "sword-v2-atom-codemeta", "gitlab-project-json"
+	DiscoveryDate time.Time
+	Origin        string // origin context, if recorded
+	Content       []byte // the metadata document itself
+}
+
+// apiAuthority is one element of the .../authorities/ response.
+type apiAuthority struct {
+	Type            string `json:"type"`
+	URL             string `json:"url"`
+	MetadataListURL string `json:"metadata_list_url"`
+}
+
+// apiMetadata is one element of a raw-extrinsic-metadata listing.
+type apiMetadata struct {
+	Target        string    `json:"target"`
+	DiscoveryDate time.Time `json:"discovery_date"`
+	Authority     Authority `json:"authority"`
+	Fetcher       struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"fetcher"`
+	Format      string `json:"format"`
+	Origin      string `json:"origin"`
+	MetadataURL string `json:"metadata_url"`
+}
+
+// doiRegex matches DOIs as recommended by Crossref, with or without a
+// https://doi.org/ or doi: prefix.
+var doiRegex = regexp.MustCompile(`(?i)\b10\.\d{4,9}/[-._;()/:a-z0-9]+`)
+
+// Citation returns the metadata recorded for id, typically a revision or
+// directory, and for the origins it is known under, with the DOIs that
+// metadata mentions. Objects without metadata yield a Citation with no
+// records rather than an error.
+func (c *Client) Citation(ctx context.Context, id *swhid.Identifier) (*Citation, error) {
+	core, _ := swhid.NewIdentifier(id.ObjectType, id.ObjectHash, nil)
+	citation := &Citation{SWHID: id}
+
+	records, err := c.metadata(ctx, "/api/1/raw-extrinsic-metadata/swhid/"+core.CoreSWHID()+"/authorities/")
+	if err != nil {
+		return nil, err
+	}
+	citation.Metadata = records
+
+	seen := make(map[string]bool)
+	addOrigin := func(origin string) {
+		if origin != "" && !seen[origin] {
+			seen[origin] = true
+			citation.Origins = append(citation.Origins, origin)
+		}
+	}
+	addOrigin(id.Qualifiers[swhid.QualifierOrigin])
+	for _, m := range records {
+		addOrigin(m.Origin)
+	}
+
+	for _, origin := range citation.Origins {
+		records, err := c.metadata(ctx, "/api/1/raw-extrinsic-metadata/origin/"+origin+"/authorities/")
+		if err != nil {
+			return nil, err
+		}
+		citation.Metadata = append(citation.Metadata, records...)
+	}
+
+	dois := make(map[string]bool)
+	for _, m := range citation.Metadata {
+		for _, doi := range FindDOIs(m.Content) {
+			if key := strings.ToLower(doi); !dois[key] {
+				dois[key] = true
+				citation.DOIs = append(citation.DOIs, doi)
+			}
+		}
+	}
+	return citation, nil
+}
+
+// metadata lists the authorities at authoritiesPath and fetches every
+// record each of them holds. A target the archive knows nothing about has
+// no records.
+func (c *Client) metadata(ctx context.Context, authoritiesPath string) ([]Metadata, error) {
+	body, err := c.get(ctx, authoritiesPath)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var authorities []apiAuthority
+	if err := json.Unmarshal(body, &authorities); err != nil {
+		return nil, fmt.Errorf("archive: invalid authority list: %w", err)
+	}
+
+	var records []Metadata
+	for _, a := range authorities {
+		listURL := a.MetadataListURL
+		if listURL == "" {
+			listURL = c.baseURL() + strings.TrimSuffix(authoritiesPath, "authorities/") + "?authority=" + url.QueryEscape(a.Type+" "+a.URL)
+		}
+		body, err := c.getURL(ctx, listURL)
+		if err != nil {
+			return nil, err
+		}
+
+		var listing []apiMetadata
+		if err := json.Unmarshal(body, &listing); err != nil {
+			return nil, fmt.Errorf("archive: invalid metadata list: %w", err)
+		}
+		for _, m := range listing {
+			content, err := c.getURL(ctx, m.MetadataURL)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, Metadata{
+				Target:        m.Target,
+				Authority:     m.Authority,
+				Fetcher:       strings.TrimSpace(m.Fetcher.Name + " " + m.Fetcher.Version),
+				Format:        m.Format,
+				DiscoveryDate: m.DiscoveryDate,
+				Origin:        m.Origin,
+				Content:       content,
+			})
+		}
+	}
+	return records, nil
+}
+
+// schemaDOIPrefix starts the DOIs of CodeMeta namespaces, which every
+// deposit document declares and which do not identify the software.
+const schemaDOIPrefix = "10.5063/schema/"
+
+// FindDOIs returns the DOIs mentioned in a metadata document, in order of
+// first appearance and without duplicates, leaving out CodeMeta schema
+// DOIs.
+func FindDOIs(content []byte) []string {
+	var dois []string
+	seen := make(map[string]bool)
+	for _, m := range doiRegex.FindAll(content, -1) {
+		doi := string(bytes.TrimRight(m, ".,;:)"))
+		key := strings.ToLower(doi)
+		if strings.HasPrefix(key, schemaDOIPrefix) {
+			continue
+		}
+		if !seen[key] {
+			seen[key] = true
+			dois = append(dois, doi)
+		}
+	}
+	return dois
+}
+
+// NormalizeDOI strips a https://doi.org/ or doi: prefix and surrounding
+// space from a DOI.
+func NormalizeDOI(doi string) string {
+	doi = strings.TrimSpace(doi)
+	for _, prefix := range []string{"https://doi.org/", "http://doi.org/", "https://dx.doi.org/", "http://dx.doi.org/", "doi:"} {
+		if len(doi) >= len(prefix) && strings.EqualFold(doi[:len(prefix)], prefix) {
+			return doi[len(prefix):]
+		}
+	}
+	return doi
+}
+
+// apiOriginMetadata is one element of the /api/1/origin/metadata-search/
+// response; the metadata is only searched for the DOI.
+type apiOriginMetadata struct {
+	URL      string          `json:"url"`
+	Metadata json.RawMessage `json:"metadata"`
+}
+
+// FindDOI returns the snapshots of the origins whose metadata mentions doi,
+// each qualified with its origin: the archive's metadata search is asked
+// for the DOI, matches whose metadata does not actually contain it are
+// dropped, and the latest visit with a snapshot is taken for each origin.
+func (c *Client) FindDOI(ctx context.Context, doi string) ([]*swhid.Identifier, error) {
+	doi = NormalizeDOI(doi)
+	if !doiRegex.MatchString(doi) {
+		return nil, fmt.Errorf("archive: invalid DOI %q", doi)
+	}
+
+	body, err := c.get(ctx, "/api/1/origin/metadata-search/?fulltext="+url.QueryEscape(doi))
+	if err != nil {
+		return nil, err
+	}
+	var matches []apiOriginMetadata
+	if err := json.Unmarshal(body, &matches); err != nil {
+		return nil, fmt.Errorf("archive: invalid metadata search response: %w", err)
+	}
+
+	var ids []*swhid.Identifier
+	for _, m := range matches {
+		if !mentionsDOI(m.Metadata, doi) {
+			continue
+		}
+
+		id, err := c.LatestSnapshot(ctx, m.URL)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func mentionsDOI(content []byte, doi string) bool {
+	for _, found := range FindDOIs(content) {
+		if strings.EqualFold(found, doi) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/andrew/swhid-go"
+)
+
+const depositXML = `<entry xmlns="http://www.w3.org/2005/Atom" xmlns:codemeta="https://doi.org/10.5063/SCHEMA/CODEMETA-2.0">
+  <codemeta:name>Example</codemeta:name>
+  <codemeta:identifier>https://doi.org/10.5281/zenodo.1234567</codemeta:identifier>
+</entry>`
+
+func TestClientCitation(t *testing.T) {
+	dir, _ := swhid.Parse("swh:1:dir:d198bc9d7a6bcf6db04f476d29314f157507d505")
+	const origin = "https://hal.archives-ouvertes.fr/hal-01234567"
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/1/raw-extrinsic-metadata/swhid/" + dir.CoreSWHID() + "/authorities/":
+			json.NewEncoder(w).Encode([]map[string]string{{
+				"type":              "deposit_client",
+				"url":               "https://hal.archives-ouvertes.fr/",
+				"metadata_list_url": server.URL + "/api/1/raw-extrinsic-metadata/swhid/" + dir.CoreSWHID() + "/?authority=deposit_client+https://hal.archives-ouvertes.fr/",
+			}})
+		case "/api/1/raw-extrinsic-metadata/swhid/" + dir.CoreSWHID() + "/":
+			if r.URL.Query().Get("authority") != "deposit_client https://hal.archives-ouvertes.fr/" {
+				t.Errorf("authority = %q", r.URL.Query().Get("authority"))
+			}
+			json.NewEncoder(w).Encode([]map[string]interface{}{{
+				"target":         dir.CoreSWHID(),
+				"discovery_date": "2021-05-04T10:00:00+00:00",
+				"authority":      map[string]string{"type": "deposit_client", "url": "https://hal.archives-ouvertes.fr/"},
+				"fetcher":        map[string]string{"name": "swh-deposit", "version": "0.14.0"},
+				"format":         "sword-v2-atom-codemeta",
+				"origin":         origin,
+				"metadata_url":   server.URL + "/api/1/raw-extrinsic-metadata/get/abc/",
+			}})
+		case "/api/1/raw-extrinsic-metadata/get/abc/":
+			w.Write([]byte(depositXML))
+		case "/api/1/raw-extrinsic-metadata/origin/" + origin + "/authorities/":
+			http.NotFound(w, r)
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := &Client{BaseURL: server.URL}
+	citation, err := c.Citation(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("Citation() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(citation.DOIs, []string{"10.5281/zenodo.1234567"}) {
+		t.Errorf("DOIs = %v", citation.DOIs)
+	}
+	if !reflect.DeepEqual(citation.Origins, []string{origin}) {
+		t.Errorf("Origins = %v", citation.Origins)
+	}
+	if len(citation.Metadata) != 1 {
+		t.Fatalf("Metadata = %v, want 1 record", citation.Metadata)
+	}
+	m := citation.Metadata[0]
+	if m.Format != "sword-v2-atom-codemeta" || m.Fetcher != "swh-deposit 0.14.0" || m.DiscoveryDate.Year() != 2021 {
+		t.Errorf("Metadata[0] = %+v", m)
+	}
+}
+
+func TestClientCitationNoMetadata(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	rev, _ := swhid.Parse("swh:1:rev:309cf2674ee7a0749978cf8265ab91a60aea0f7d")
+	citation, err := (&Client{BaseURL: server.URL}).Citation(context.Background(), rev)
+	if err != nil {
+		t.Fatalf("Citation() error = %v", err)
+	}
+	if len(citation.Metadata) != 0 || len(citation.DOIs) != 0 {
+		t.Errorf("Citation() = %+v, want no records", citation)
+	}
+}
+
+func TestClientFindDOI(t *testing.T) {
+	const snapshot = "c7c108084bc0bf3d81436bf980b46e98bd338453"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/1/origin/metadata-search/":
+			if r.URL.Query().Get("fulltext") != "10.5281/zenodo.1234567" {
+				t.Errorf("fulltext = %q", r.URL.Query().Get("fulltext"))
+			}
+			json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"url": "https://github.com/example/repo", "metadata": map[string]string{"identifier": "https://doi.org/10.5281/ZENODO.1234567"}},
+				{"url": "https://github.com/example/other", "metadata": map[string]string{"identifier": "10.5281/zenodo.12345678"}},
+			})
+		case strings.HasSuffix(r.URL.Path, "/visit/latest/"):
+			if r.URL.Path != "/api/1/origin/https://github.com/example/repo/visit/latest/" {
+				t.Errorf("unexpected visit request %s", r.URL.Path)
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"snapshot": snapshot, "visit": 3})
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ids, err := (&Client{BaseURL: server.URL}).FindDOI(context.Background(), "doi:10.5281/zenodo.1234567")
+	if err != nil {
+		t.Fatalf("FindDOI() error = %v", err)
+	}
+	if len(ids) != 1 {
+		t.Fatalf("FindDOI() = %v, want 1 snapshot", ids)
+	}
+	if want := "swh:1:snp:" + snapshot + ";origin=https://github.com/example/repo"; ids[0].String() != want {
+		t.Errorf("FindDOI() = %v, want %v", ids[0], want)
+	}
+
+	if _, err := (&Client{BaseURL: server.URL}).FindDOI(context.Background(), "not-a-doi"); err == nil {
+		t.Error("FindDOI() expected error for invalid DOI")
+	}
+}
+
+func TestFindDOIs(t *testing.T) {
+	got := FindDOIs([]byte(`See doi:10.1000/xyz123. Also https://doi.org/10.1000/XYZ123 and (10.5281/zenodo.42).`))
+	want := []string{"10.1000/xyz123", "10.5281/zenodo.42"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindDOIs() = %v, want %v", got, want)
+	}
+}
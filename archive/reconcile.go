@@ -0,0 +1,132 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/objects"
+)
+
+// Difference kinds reported by ReconcileWithArchive.
+const (
+	NotArchived    = "not-archived"    // present locally, absent from the archived directory
+	HashMismatch   = "hash-mismatch"   // present on both sides with different SWHIDs or entry types
+	MissingLocally = "missing-locally" // archived but absent locally
+)
+
+// Difference is one entry on which a local directory and its archived copy
+// disagree. Local is nil for entries missing locally and Archived is nil
+// for entries that are not archived.
+type Difference struct {
+	Path         string // slash-separated path from the reconciled directory
+	Kind         string
+	Local        *swhid.Identifier
+	Archived     *swhid.Identifier
+	LocalType    objects.EntryType
+	ArchivedType objects.EntryType
+}
+
+// Reconciliation is the outcome of ReconcileWithArchive. A directory whose
+// archived copy is complete and up to date has no differences, and Local
+// equals Archived.
+type Reconciliation struct {
+	Local       *swhid.Identifier
+	Archived    *swhid.Identifier
+	Differences []Difference // sorted by path
+}
+
+// ReconcileWithArchive hashes the directory at localPath and compares it
+// entry by entry with the archived directory dirSWHID, descending into
+// subdirectories whose hashes differ on both sides, so only the files and
+// directories that actually differ are reported. Archived subdirectories
+// whose listing the archive cannot serve are reported as mismatches
+// without being descended into.
+func (c *Client) ReconcileWithArchive(ctx context.Context, localPath string, dirSWHID *swhid.Identifier) (*Reconciliation, error) {
+	if dirSWHID.ObjectType != swhid.ObjectTypeDirectory {
+		return nil, fmt.Errorf("archive: %s is not a directory SWHID", dirSWHID.CoreSWHID())
+	}
+	local, err := swhid.TreeFromDirectoryPath(localPath)
+	if err != nil {
+		return nil, err
+	}
+
+	archived, _ := swhid.NewIdentifier(swhid.ObjectTypeDirectory, dirSWHID.ObjectHash, nil)
+	r := &Reconciliation{Local: local.ID, Archived: archived}
+	if local.ID.ObjectHash == archived.ObjectHash {
+		return r, nil
+	}
+
+	entries, err := c.Directory(ctx, archived)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.reconcile(ctx, r, "", local.Children, entries); err != nil {
+		return nil, err
+	}
+	sort.Slice(r.Differences, func(i, j int) bool {
+		return r.Differences[i].Path < r.Differences[j].Path
+	})
+	return r, nil
+}
+
+// reconcile compares the children of one local directory with the entries
+// of its archived counterpart.
+func (c *Client) reconcile(ctx context.Context, r *Reconciliation, dir string, children []*swhid.Node, entries []objects.DirectoryEntry) error {
+	archived := make(map[string]objects.DirectoryEntry, len(entries))
+	for _, e := range entries {
+		archived[e.Name] = e
+	}
+
+	for _, child := range children {
+		p := path.Join(dir, child.Name)
+		e, ok := archived[child.Name]
+		if !ok {
+			r.Differences = append(r.Differences, Difference{Path: p, Kind: NotArchived, Local: child.ID, LocalType: child.Type})
+			continue
+		}
+		delete(archived, child.Name)
+		if e.Type == child.Type && e.Target == child.ID.ObjectHash {
+			continue
+		}
+
+		diff := Difference{Path: p, Kind: HashMismatch, Local: child.ID, Archived: entryIdentifier(e), LocalType: child.Type, ArchivedType: e.Type}
+		if e.Type != objects.EntryTypeDirectory || child.Type != objects.EntryTypeDirectory {
+			r.Differences = append(r.Differences, diff)
+			continue
+		}
+		sub, err := c.Directory(ctx, diff.Archived)
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrMismatch) {
+			r.Differences = append(r.Differences, diff)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := c.reconcile(ctx, r, p, child.Children, sub); err != nil {
+			return err
+		}
+	}
+
+	for _, e := range archived {
+		r.Differences = append(r.Differences, Difference{Path: path.Join(dir, e.Name), Kind: MissingLocally, Archived: entryIdentifier(e), ArchivedType: e.Type})
+	}
+	return nil
+}
+
+// entryIdentifier returns the SWHID of the object a directory entry points
+// to.
+func entryIdentifier(e objects.DirectoryEntry) *swhid.Identifier {
+	objectType := swhid.ObjectTypeContent
+	switch e.Type {
+	case objects.EntryTypeDirectory:
+		objectType = swhid.ObjectTypeDirectory
+	case objects.EntryTypeRevision:
+		objectType = swhid.ObjectTypeRevision
+	}
+	id, _ := swhid.NewIdentifier(objectType, e.Target, nil)
+	return id
+}
@@ -0,0 +1,128 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/objects"
+)
+
+func TestReconcileWithArchive(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"README":       "hello\n",
+		"new.txt":      "local only\n",
+		"src/main.go":  "package main\n",
+		"src/util.go":  "package util\n",
+		"docs/old.txt": "unchanged\n",
+	} {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755)
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+
+	file := func(name, content string) objects.DirectoryEntry {
+		return objects.DirectoryEntry{Name: name, Type: objects.EntryTypeFile, Target: swhid.FromContent([]byte(content)).ObjectHash}
+	}
+	docs := []objects.DirectoryEntry{file("old.txt", "unchanged\n")}
+	src := []objects.DirectoryEntry{
+		file("main.go", "package main // archived\n"),
+		file("util.go", "package util\n"),
+		file("gone.go", "package gone\n"),
+	}
+	root := []objects.DirectoryEntry{
+		file("README", "hello\n"),
+		file("LICENSE", "MIT\n"),
+		{Name: "docs", Type: objects.EntryTypeDirectory, Target: objects.ComputeDirectoryHash(docs)},
+		{Name: "src", Type: objects.EntryTypeDirectory, Target: objects.ComputeDirectoryHash(src)},
+	}
+	listings := map[string][]objects.DirectoryEntry{
+		objects.ComputeDirectoryHash(root): root,
+		objects.ComputeDirectoryHash(src):  src,
+		objects.ComputeDirectoryHash(docs): docs,
+	}
+
+	requested := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hash := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/1/directory/"), "/")
+		requested[hash] = true
+		entries, ok := listings[hash]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		var listing []apiDirectoryEntry
+		for _, e := range entries {
+			typ := "file"
+			if e.Type == objects.EntryTypeDirectory {
+				typ = "dir"
+			}
+			perms, _ := strconv.ParseInt(e.DefaultPerms(), 8, 32)
+			listing = append(listing, apiDirectoryEntry{Name: e.Name, Type: typ, Target: e.Target, Perms: int(perms)})
+		}
+		json.NewEncoder(w).Encode(listing)
+	}))
+	defer server.Close()
+
+	id, _ := swhid.NewIdentifier(swhid.ObjectTypeDirectory, objects.ComputeDirectoryHash(root), nil)
+	c := &Client{BaseURL: server.URL}
+	got, err := c.ReconcileWithArchive(context.Background(), dir, id)
+	if err != nil {
+		t.Fatalf("ReconcileWithArchive() error = %v", err)
+	}
+
+	want := []struct{ path, kind string }{
+		{"LICENSE", MissingLocally},
+		{"new.txt", NotArchived},
+		{"src/gone.go", MissingLocally},
+		{"src/main.go", HashMismatch},
+	}
+	if len(got.Differences) != len(want) {
+		t.Fatalf("Differences = %+v, want %d entries", got.Differences, len(want))
+	}
+	for i, w := range want {
+		d := got.Differences[i]
+		if d.Path != w.path || d.Kind != w.kind {
+			t.Errorf("difference %d = %s %s, want %s %s", i, d.Path, d.Kind, w.path, w.kind)
+		}
+	}
+	main := got.Differences[3]
+	if main.Local.String() != swhid.FromContent([]byte("package main\n")).String() ||
+		main.Archived.String() != swhid.FromContent([]byte("package main // archived\n")).String() {
+		t.Errorf("src/main.go = %s / %s", main.Local, main.Archived)
+	}
+	if got.Differences[0].Local != nil || got.Differences[1].Archived != nil {
+		t.Error("one-sided differences have identifiers on both sides")
+	}
+	if requested[objects.ComputeDirectoryHash(docs)] {
+		t.Error("fetched a subdirectory whose hash matches")
+	}
+
+	same, err := c.ReconcileWithArchive(context.Background(), filepath.Join(dir, "docs"), mustDirectory(t, docs))
+	if err != nil {
+		t.Fatalf("ReconcileWithArchive() error = %v", err)
+	}
+	if len(same.Differences) != 0 || !same.Local.Equal(same.Archived) {
+		t.Errorf("ReconcileWithArchive() of identical directory = %+v", same)
+	}
+
+	if _, err := c.ReconcileWithArchive(context.Background(), dir, swhid.FromContent(nil)); err == nil {
+		t.Error("ReconcileWithArchive() accepted a content SWHID")
+	}
+}
+
+func mustDirectory(t *testing.T, entries []objects.DirectoryEntry) *swhid.Identifier {
+	t.Helper()
+	id, err := swhid.NewIdentifier(swhid.ObjectTypeDirectory, objects.ComputeDirectoryHash(entries), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
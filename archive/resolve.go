@@ -0,0 +1,151 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/andrew/swhid-go"
+)
+
+// Client resolves qualifiers against the archive.
+var _ swhid.Resolver = (*Client)(nil)
+
+// apiTarget is the part of the revision, release and snapshot branch
+// responses that Lookup follows.
+type apiTarget struct {
+	Directory  string `json:"directory"`   // revisions
+	Target     string `json:"target"`      // releases and branches
+	TargetType string `json:"target_type"` // releases and branches
+}
+
+// Lookup implements swhid.Resolver, following the anchor down to its root
+// directory and reading directory listings along path, through the cache
+// if there is one. Anchors and paths the archive does not hold are
+// swhid.ErrUnresolved as well as ErrNotFound.
+func (c *Client) Lookup(ctx context.Context, anchor *swhid.Identifier, path string) (*swhid.Identifier, error) {
+	root, err := c.rootDirectory(ctx, anchor)
+	if err != nil {
+		return nil, unresolved(err)
+	}
+
+	current := root
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return current, nil
+	}
+	names := strings.Split(path, "/")
+	for i, name := range names {
+		if current.ObjectType != swhid.ObjectTypeDirectory {
+			return nil, fmt.Errorf("%w: %s in %s is not a directory", swhid.ErrUnresolved, strings.Join(names[:i], "/"), anchor.CoreSWHID())
+		}
+		entries, err := c.Directory(ctx, current)
+		if err != nil {
+			return nil, unresolved(err)
+		}
+		next := -1
+		for j, e := range entries {
+			if e.Name == name {
+				next = j
+				break
+			}
+		}
+		if next < 0 {
+			return nil, fmt.Errorf("%w: %s not found in %s: %w", swhid.ErrUnresolved, path, anchor.CoreSWHID(), ErrNotFound)
+		}
+		current = entryIdentifier(entries[next])
+	}
+	return current, nil
+}
+
+// rootDirectory returns the directory a snapshot, release, revision or
+// directory anchor leads to.
+func (c *Client) rootDirectory(ctx context.Context, anchor *swhid.Identifier) (*swhid.Identifier, error) {
+	objectType, hash := anchor.ObjectType, anchor.ObjectHash
+	if objectType == swhid.ObjectTypeSnapshot {
+		branch, err := c.branch(ctx, hash, "HEAD")
+		if err != nil {
+			return nil, err
+		}
+		if branch.TargetType == "alias" {
+			if branch, err = c.branch(ctx, hash, branch.Target); err != nil {
+				return nil, err
+			}
+		}
+		objectType, hash = branchObjectType(branch.TargetType), branch.Target
+	}
+
+	for {
+		var target apiTarget
+		switch objectType {
+		case swhid.ObjectTypeDirectory:
+			return swhid.NewIdentifier(swhid.ObjectTypeDirectory, hash, nil)
+		case swhid.ObjectTypeRevision:
+			if err := c.getJSON(ctx, "/api/1/revision/"+hash+"/", &target); err != nil {
+				return nil, err
+			}
+			objectType, hash = swhid.ObjectTypeDirectory, target.Directory
+		case swhid.ObjectTypeRelease:
+			if err := c.getJSON(ctx, "/api/1/release/"+hash+"/", &target); err != nil {
+				return nil, err
+			}
+			objectType, hash = branchObjectType(target.TargetType), target.Target
+		default:
+			return nil, fmt.Errorf("archive: %s does not lead to a directory", anchor.CoreSWHID())
+		}
+	}
+}
+
+// branch returns one branch of a snapshot.
+func (c *Client) branch(ctx context.Context, snapshot, name string) (apiTarget, error) {
+	var listing struct {
+		Branches map[string]*apiTarget `json:"branches"`
+	}
+	err := c.getJSON(ctx, "/api/1/snapshot/"+snapshot+"/?branches_from="+url.QueryEscape(name)+"&branches_count=1", &listing)
+	if err != nil {
+		return apiTarget{}, err
+	}
+	branch := listing.Branches[name]
+	if branch == nil {
+		return apiTarget{}, fmt.Errorf("archive: snapshot %s has no branch %s: %w", snapshot, name, ErrNotFound)
+	}
+	return *branch, nil
+}
+
+func (c *Client) getJSON(ctx context.Context, path string, v interface{}) error {
+	body, err := c.get(ctx, path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("archive: invalid response for %s: %w", path, err)
+	}
+	return nil
+}
+
+// branchObjectType maps the target types of releases and snapshot branches
+// to SWHID object types.
+func branchObjectType(targetType string) swhid.ObjectType {
+	switch targetType {
+	case "revision":
+		return swhid.ObjectTypeRevision
+	case "release":
+		return swhid.ObjectTypeRelease
+	case "directory":
+		return swhid.ObjectTypeDirectory
+	case "content":
+		return swhid.ObjectTypeContent
+	}
+	return swhid.ObjectType(targetType)
+}
+
+// unresolved marks an object the archive does not hold as unresolved.
+func unresolved(err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("%w: %w", swhid.ErrUnresolved, err)
+	}
+	return err
+}
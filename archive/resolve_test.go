@@ -0,0 +1,92 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/objects"
+)
+
+func TestClientLookup(t *testing.T) {
+	file := swhid.FromContent([]byte("package main\n"))
+	src := []objects.DirectoryEntry{{Name: "main.go", Type: objects.EntryTypeFile, Target: file.ObjectHash}}
+	srcHash := objects.ComputeDirectoryHash(src)
+	root := []objects.DirectoryEntry{{Name: "src", Type: objects.EntryTypeDirectory, Target: srcHash}}
+	rootHash := objects.ComputeDirectoryHash(root)
+	listings := map[string][]objects.DirectoryEntry{rootHash: root, srcHash: src}
+
+	const rev = "309cf2674ee7a0749978cf8265ab91a60aea0f7d"
+	const rel = "e2ed8d2e3c5a0e2ab2dffe5c1d0e9bb2e0d1a3b4"
+	const snp = "c84502e821eb21ed84e9fd3ec40973abc8b32353"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/1/snapshot/" + snp + "/":
+			branches := map[string]map[string]string{
+				"HEAD":            {"target": "refs/heads/main", "target_type": "alias"},
+				"refs/heads/main": {"target": rel, "target_type": "release"},
+			}
+			name := r.URL.Query().Get("branches_from")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"branches": map[string]interface{}{name: branches[name]},
+			})
+		case "/api/1/release/" + rel + "/":
+			fmt.Fprintf(w, `{"target": %q, "target_type": "revision"}`, rev)
+		case "/api/1/revision/" + rev + "/":
+			fmt.Fprintf(w, `{"directory": %q}`, rootHash)
+		default:
+			var hash string
+			if _, err := fmt.Sscanf(r.URL.Path, "/api/1/directory/%40s", &hash); err == nil && listings[hash] != nil {
+				var listing []apiDirectoryEntry
+				for _, e := range listings[hash] {
+					typ, perms := "file", 0100644
+					if e.Type == objects.EntryTypeDirectory {
+						typ, perms = "dir", 040000
+					}
+					listing = append(listing, apiDirectoryEntry{Name: e.Name, Type: typ, Target: e.Target, Perms: perms})
+				}
+				json.NewEncoder(w).Encode(listing)
+				return
+			}
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := &Client{BaseURL: server.URL}
+	ctx := context.Background()
+	for _, anchor := range []string{"swh:1:snp:" + snp, "swh:1:rel:" + rel, "swh:1:rev:" + rev, "swh:1:dir:" + rootHash} {
+		id, _ := swhid.Parse(anchor)
+		got, err := c.Lookup(ctx, id, "/src/main.go")
+		if err != nil {
+			t.Fatalf("Lookup(%s) error = %v", anchor, err)
+		}
+		if !got.Equal(file) {
+			t.Errorf("Lookup(%s) = %s, want %s", anchor, got, file)
+		}
+	}
+
+	id, _ := swhid.Parse("swh:1:rev:" + rev)
+	got, err := c.Lookup(ctx, id, "/src/")
+	if err != nil || got.ObjectHash != srcHash || got.ObjectType != swhid.ObjectTypeDirectory {
+		t.Errorf("Lookup(src) = %v, %v", got, err)
+	}
+	if _, err := c.Lookup(ctx, id, "/src/missing.go"); !errors.Is(err, swhid.ErrUnresolved) || !errors.Is(err, ErrNotFound) {
+		t.Errorf("Lookup() of a missing path error = %v", err)
+	}
+	unknown, _ := swhid.Parse("swh:1:rev:0000000000000000000000000000000000000000")
+	if _, err := c.Lookup(ctx, unknown, "/src/main.go"); !errors.Is(err, swhid.ErrUnresolved) {
+		t.Errorf("Lookup() with an unknown anchor error = %v", err)
+	}
+
+	main, _ := swhid.Parse(file.CoreSWHID() + ";anchor=swh:1:rev:" + rev + ";path=/src/main.go")
+	if err := swhid.ValidateQualifiers(ctx, main, swhid.NewCachingResolver(c, 16)); err != nil {
+		t.Errorf("ValidateQualifiers() error = %v", err)
+	}
+}
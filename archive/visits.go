@@ -0,0 +1,159 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/objects"
+)
+
+// visitsPageSize is the number of visits asked for per request, the most
+// the API returns at once.
+const visitsPageSize = 1000
+
+// branchesPageSize is the number of snapshot branches asked for per
+// request.
+const branchesPageSize = 1000
+
+// Visit is one visit of an origin by the archive's crawlers.
+type Visit struct {
+	Origin   string
+	Visit    int       // visit number, increasing over the origin's visits
+	Date     time.Time // when the visit started
+	Type     string    // loader type, such as "git" or "tar"
+	Status   string    // "full", "partial", "ongoing", "failed", "not_found", ...
+	Snapshot *swhid.Identifier
+}
+
+// apiVisit is one element of the /api/1/origin/<url>/visits/ response and
+// the /visit/latest/ response.
+type apiVisit struct {
+	Origin   string    `json:"origin"`
+	Visit    int       `json:"visit"`
+	Date     time.Time `json:"date"`
+	Type     string    `json:"type"`
+	Status   string    `json:"status"`
+	Snapshot string    `json:"snapshot"`
+}
+
+// Visits returns every visit of an origin, most recent first. Snapshot is
+// nil for visits that did not record one and is otherwise qualified with
+// the origin, ready to anchor other qualified SWHIDs to.
+func (c *Client) Visits(ctx context.Context, originURL string) ([]Visit, error) {
+	var visits []Visit
+	last := ""
+	for {
+		path := "/api/1/origin/" + originURL + "/visits/?per_page=" + strconv.Itoa(visitsPageSize)
+		if last != "" {
+			path += "&last_visit=" + last
+		}
+		body, err := c.get(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		var page []apiVisit
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("archive: invalid visit list for %s: %w", originURL, err)
+		}
+		for _, v := range page {
+			visit, err := v.visit(originURL)
+			if err != nil {
+				return nil, err
+			}
+			visits = append(visits, visit)
+		}
+		if len(page) < visitsPageSize {
+			return visits, nil
+		}
+		last = strconv.Itoa(page[len(page)-1].Visit)
+	}
+}
+
+// LatestSnapshot returns the snapshot of the most recent visit of an origin
+// that recorded one, qualified with the origin. Origins the archive has
+// never taken a snapshot of yield ErrNotFound, which is also
+// swhid.ErrUnresolved; LatestSnapshot implements swhid.Resolver.
+func (c *Client) LatestSnapshot(ctx context.Context, originURL string) (*swhid.Identifier, error) {
+	body, err := c.get(ctx, "/api/1/origin/"+originURL+"/visit/latest/?require_snapshot=true")
+	if err != nil {
+		return nil, unresolved(err)
+	}
+	var v apiVisit
+	if err := json.Unmarshal(body, &v); err != nil {
+		return nil, fmt.Errorf("archive: invalid visit for %s: %w", originURL, err)
+	}
+	visit, err := v.visit(originURL)
+	if err != nil {
+		return nil, err
+	}
+	if visit.Snapshot == nil {
+		return nil, unresolved(fmt.Errorf("archive: latest visit of %s: %w", originURL, ErrNotFound))
+	}
+	return visit.Snapshot, nil
+}
+
+// SnapshotBranches returns every branch of a snapshot the archive holds,
+// as GitOptions.Branches takes them to complete the snapshot of a
+// repository that fetched only some references. Branches the archive
+// lists without a target are dangling.
+func (c *Client) SnapshotBranches(ctx context.Context, snapshot *swhid.Identifier) ([]objects.Branch, error) {
+	if snapshot.ObjectType != swhid.ObjectTypeSnapshot {
+		return nil, fmt.Errorf("archive: %s is not a snapshot", snapshot.CoreSWHID())
+	}
+
+	var branches []objects.Branch
+	from := ""
+	for {
+		path := "/api/1/snapshot/" + snapshot.ObjectHash + "/?branches_count=" + strconv.Itoa(branchesPageSize)
+		if from != "" {
+			path += "&branches_from=" + url.QueryEscape(from)
+		}
+		var page struct {
+			Branches   map[string]*apiTarget `json:"branches"`
+			NextBranch *string               `json:"next_branch"`
+		}
+		if err := c.getJSON(ctx, path, &page); err != nil {
+			return nil, unresolved(err)
+		}
+
+		names := make([]string, 0, len(page.Branches))
+		for name := range page.Branches {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			branch := objects.Branch{Name: name, TargetType: objects.BranchTargetDangling}
+			if t := page.Branches[name]; t != nil {
+				branch.TargetType, branch.Target = objects.BranchTargetType(t.TargetType), t.Target
+			}
+			branches = append(branches, branch)
+		}
+
+		if page.NextBranch == nil || *page.NextBranch == "" {
+			return branches, nil
+		}
+		from = *page.NextBranch
+	}
+}
+
+func (v apiVisit) visit(originURL string) (Visit, error) {
+	if v.Origin != "" {
+		originURL = v.Origin
+	}
+	visit := Visit{Origin: originURL, Visit: v.Visit, Date: v.Date, Type: v.Type, Status: v.Status}
+	if v.Snapshot == "" {
+		return visit, nil
+	}
+	id, err := swhid.NewIdentifier(swhid.ObjectTypeSnapshot, v.Snapshot, map[string]string{swhid.QualifierOrigin: originURL})
+	if err != nil {
+		return Visit{}, fmt.Errorf("archive: invalid snapshot for %s: %w", originURL, err)
+	}
+	visit.Snapshot = id
+	return visit, nil
+}
@@ -0,0 +1,146 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/andrew/swhid-go"
+)
+
+func TestClientVisits(t *testing.T) {
+	const origin = "https://github.com/example/repo"
+	snapshot := func(visit int) string {
+		return fmt.Sprintf("%040x", visit)
+	}
+
+	// visitsPageSize+1 visits, the oldest without a snapshot.
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/api/1/origin/"+origin+"/visits/" {
+			t.Errorf("request path = %s", r.URL.Path)
+		}
+		last := visitsPageSize + 2
+		if s := r.URL.Query().Get("last_visit"); s != "" {
+			last, _ = strconv.Atoi(s)
+		}
+		var page []map[string]interface{}
+		for v := last - 1; v >= 1 && len(page) < visitsPageSize; v-- {
+			visit := map[string]interface{}{
+				"origin": origin,
+				"visit":  v,
+				"date":   "2024-01-02T03:04:05.123456+00:00",
+				"type":   "git",
+				"status": "full",
+			}
+			if v > 1 {
+				visit["snapshot"] = snapshot(v)
+			} else {
+				visit["status"] = "failed"
+			}
+			page = append(page, visit)
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	defer server.Close()
+
+	c := &Client{BaseURL: server.URL}
+	visits, err := c.Visits(context.Background(), origin)
+	if err != nil {
+		t.Fatalf("Visits() error = %v", err)
+	}
+	if len(visits) != visitsPageSize+1 || requests != 2 {
+		t.Fatalf("Visits() returned %d visits in %d requests", len(visits), requests)
+	}
+	first, last := visits[0], visits[len(visits)-1]
+	if first.Visit != visitsPageSize+1 || first.Type != "git" || first.Date.Year() != 2024 {
+		t.Errorf("visits[0] = %+v", first)
+	}
+	want := "swh:1:snp:" + snapshot(first.Visit) + ";origin=" + origin
+	if first.Snapshot.String() != want {
+		t.Errorf("visits[0].Snapshot = %s, want %s", first.Snapshot, want)
+	}
+	if last.Visit != 1 || last.Status != "failed" || last.Snapshot != nil {
+		t.Errorf("last visit = %+v", last)
+	}
+}
+
+func TestClientLatestSnapshot(t *testing.T) {
+	const origin = "https://github.com/example/repo"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("require_snapshot") != "true" {
+			t.Errorf("require_snapshot = %q", r.URL.Query().Get("require_snapshot"))
+		}
+		if r.URL.Path != "/api/1/origin/"+origin+"/visit/latest/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"origin": %q, "visit": 7, "date": "2024-01-02T03:04:05+00:00", "status": "full", "snapshot": "%040x"}`, origin, 7)
+	}))
+	defer server.Close()
+
+	c := &Client{BaseURL: server.URL}
+	id, err := c.LatestSnapshot(context.Background(), origin)
+	if err != nil {
+		t.Fatalf("LatestSnapshot() error = %v", err)
+	}
+	if id.ObjectType != swhid.ObjectTypeSnapshot || id.Qualifiers[swhid.QualifierOrigin] != origin {
+		t.Errorf("LatestSnapshot() = %s", id)
+	}
+
+	if _, err := c.LatestSnapshot(context.Background(), "https://example.com/unknown"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("LatestSnapshot() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestClientSnapshotBranches(t *testing.T) {
+	const snapshot = "1a8893e6a86f444e8be8e7bda6cb34fb1735a00e"
+	target := fmt.Sprintf("%040x", 1)
+
+	pages := map[string]string{
+		"": `{"branches": {"HEAD": {"target": "refs/heads/main", "target_type": "alias"},
+			"refs/heads/dev": {"target": "` + target + `", "target_type": "revision"}},
+			"next_branch": "refs/heads/main"}`,
+		"refs/heads/main": `{"branches": {"refs/heads/main": {"target": "` + target + `", "target_type": "revision"},
+			"refs/heads/gone": null}, "next_branch": null}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/1/snapshot/"+snapshot+"/" {
+			t.Errorf("request path = %s", r.URL.Path)
+		}
+		page, ok := pages[r.URL.Query().Get("branches_from")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(page))
+	}))
+	defer server.Close()
+
+	c := &Client{BaseURL: server.URL}
+	id, _ := swhid.Parse("swh:1:snp:" + snapshot)
+	branches, err := c.SnapshotBranches(context.Background(), id)
+	if err != nil {
+		t.Fatalf("SnapshotBranches() error = %v", err)
+	}
+	want := []string{"HEAD alias refs/heads/main", "refs/heads/dev revision " + target, "refs/heads/gone dangling ", "refs/heads/main revision " + target}
+	if len(branches) != len(want) {
+		t.Fatalf("SnapshotBranches() = %+v", branches)
+	}
+	for i, b := range branches {
+		if got := b.Name + " " + string(b.TargetType) + " " + b.Target; got != want[i] {
+			t.Errorf("branches[%d] = %q, want %q", i, got, want[i])
+		}
+	}
+
+	rev, _ := swhid.Parse("swh:1:rev:" + snapshot)
+	if _, err := c.SnapshotBranches(context.Background(), rev); err == nil {
+		t.Error("SnapshotBranches() of a revision succeeded")
+	}
+}
@@ -0,0 +1,155 @@
+// Package attest records the evidence that an object was checked against
+// its SWHID, in a JSON form with a published schema, so audit trails can
+// store verification results alongside the artifacts they cover.
+//
+// A VerificationResult can be signed by wrapping it in an Envelope, which
+// follows the DSSE (Dead Simple Signing Envelope) layout used by in-toto
+// and Sigstore. Signing is pluggable through the Signer interface.
+//
+// A Statement binds artifacts to their SWHIDs. Signed with a key or keyless
+// through Sigstore's Fulcio and Rekor, it is stored as a Bundle that cosign
+// can also read.
+package attest
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/andrew/swhid-go"
+)
+
+// SchemaID is the $id of the JSON schema a VerificationResult conforms to.
+// It is written to the "schema" field of every result.
+const SchemaID = "https://github.com/andrew/swhid-go/schema/verification-result/v1"
+
+// Schema is the JSON schema (draft 2020-12) of a VerificationResult.
+//
+//go:embed verification-result.schema.json
+var Schema []byte
+
+// Methods describing how the recomputed SWHID was obtained.
+const (
+	MethodFile        = "file"         // hash of a regular file's bytes
+	MethodDirectory   = "directory"    // Merkle tree of a directory on disk
+	MethodGitRevision = "git-revision" // commit read from a Git repository
+	MethodGitRelease  = "git-release"  // annotated tag read from a Git repository
+	MethodGitSnapshot = "git-snapshot" // references of a Git repository
+)
+
+// Environment describes the machine a verification ran on.
+type Environment struct {
+	OS        string
+	Arch      string
+	GoVersion string
+	Hostname  string
+}
+
+// CurrentEnvironment returns the Environment of the running process.
+func CurrentEnvironment() Environment {
+	hostname, _ := os.Hostname()
+	return Environment{
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		GoVersion: runtime.Version(),
+		Hostname:  hostname,
+	}
+}
+
+// VerificationResult is the outcome of recomputing an object's SWHID and
+// comparing it with the SWHID it was expected to have.
+type VerificationResult struct {
+	Target      *swhid.Identifier // the expected SWHID
+	Recomputed  *swhid.Identifier // the SWHID computed from Subject
+	Match       bool              // whether the core SWHIDs are equal
+	Method      string            // one of the Method constants
+	Subject     string            // what was hashed, such as a path
+	ToolVersion string            // version of the tool that computed Recomputed
+	Timestamp   time.Time
+	Environment Environment
+}
+
+// NewResult returns the result of comparing recomputed with target, stamped
+// with the current time and environment.
+func NewResult(target, recomputed *swhid.Identifier, method, subject, toolVersion string) *VerificationResult {
+	return &VerificationResult{
+		Target:      target,
+		Recomputed:  recomputed,
+		Match:       target.CoreSWHID() == recomputed.CoreSWHID(),
+		Method:      method,
+		Subject:     subject,
+		ToolVersion: toolVersion,
+		Timestamp:   time.Now().UTC(),
+		Environment: CurrentEnvironment(),
+	}
+}
+
+type resultJSON struct {
+	Schema      string          `json:"schema"`
+	Target      string          `json:"target"`
+	Recomputed  string          `json:"recomputed"`
+	Match       bool            `json:"match"`
+	Method      string          `json:"method"`
+	Subject     string          `json:"subject,omitempty"`
+	ToolVersion string          `json:"tool_version"`
+	Timestamp   time.Time       `json:"timestamp"`
+	Environment environmentJSON `json:"environment"`
+}
+
+type environmentJSON struct {
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	GoVersion string `json:"go_version"`
+	Hostname  string `json:"hostname,omitempty"`
+}
+
+// MarshalJSON encodes the result in the layout described by Schema.
+func (r *VerificationResult) MarshalJSON() ([]byte, error) {
+	if r.Target == nil || r.Recomputed == nil {
+		return nil, fmt.Errorf("attest: result without target or recomputed SWHID")
+	}
+	return json.Marshal(resultJSON{
+		Schema:      SchemaID,
+		Target:      r.Target.String(),
+		Recomputed:  r.Recomputed.String(),
+		Match:       r.Match,
+		Method:      r.Method,
+		Subject:     r.Subject,
+		ToolVersion: r.ToolVersion,
+		Timestamp:   r.Timestamp,
+		Environment: environmentJSON(r.Environment),
+	})
+}
+
+// UnmarshalJSON decodes a result written by MarshalJSON.
+func (r *VerificationResult) UnmarshalJSON(data []byte) error {
+	var v resultJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.Schema != SchemaID {
+		return fmt.Errorf("attest: unsupported schema %q", v.Schema)
+	}
+	target, err := swhid.Parse(v.Target)
+	if err != nil {
+		return fmt.Errorf("attest: target: %w", err)
+	}
+	recomputed, err := swhid.Parse(v.Recomputed)
+	if err != nil {
+		return fmt.Errorf("attest: recomputed: %w", err)
+	}
+	*r = VerificationResult{
+		Target:      target,
+		Recomputed:  recomputed,
+		Match:       v.Match,
+		Method:      v.Method,
+		Subject:     v.Subject,
+		ToolVersion: v.ToolVersion,
+		Timestamp:   v.Timestamp,
+		Environment: Environment(v.Environment),
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package attest
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/andrew/swhid-go"
+)
+
+func testResult(t *testing.T) *VerificationResult {
+	t.Helper()
+	target, err := swhid.Parse("swh:1:cnt:ce013625030ba8dba906f756967f9e9ca394464a;origin=https://example.org/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewResult(target, swhid.FromContent([]byte("hello\n")), MethodFile, "hello.txt", "v1.2.3")
+}
+
+func TestNewResult(t *testing.T) {
+	r := testResult(t)
+	if !r.Match {
+		t.Error("Match = false for equal core SWHIDs")
+	}
+	if r.Environment.GoVersion == "" || r.Timestamp.IsZero() {
+		t.Errorf("result not stamped: %+v", r)
+	}
+
+	other := NewResult(r.Target, swhid.FromContent([]byte("bye\n")), MethodFile, "hello.txt", "v1.2.3")
+	if other.Match {
+		t.Error("Match = true for different SWHIDs")
+	}
+}
+
+func TestResultJSON(t *testing.T) {
+	r := testResult(t)
+	r.Timestamp = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	var got VerificationResult
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !got.Target.Equal(r.Target) || !got.Recomputed.Equal(r.Recomputed) || got.Match != r.Match ||
+		got.Method != r.Method || got.Subject != r.Subject || got.ToolVersion != r.ToolVersion ||
+		!got.Timestamp.Equal(r.Timestamp) || got.Environment != r.Environment {
+		t.Errorf("round trip = %+v, want %+v", got, r)
+	}
+
+	if err := json.Unmarshal([]byte(`{"schema":"other"}`), &got); err == nil {
+		t.Error("Unmarshal() accepted an unknown schema")
+	}
+}
+
+// TestSchema checks that the embedded schema describes the encoded fields.
+func TestSchema(t *testing.T) {
+	var schema struct {
+		ID         string `json:"$id"`
+		Required   []string
+		Properties map[string]struct {
+			Required   []string
+			Properties map[string]interface{}
+		}
+	}
+	if err := json.Unmarshal(Schema, &schema); err != nil {
+		t.Fatalf("Schema is not valid JSON: %v", err)
+	}
+	if schema.ID != SchemaID {
+		t.Errorf("schema $id = %q, want %q", schema.ID, SchemaID)
+	}
+
+	data, err := json.Marshal(testResult(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for key := range fields {
+		if _, ok := schema.Properties[key]; !ok {
+			t.Errorf("field %q is not in the schema", key)
+		}
+	}
+	for _, key := range schema.Required {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("required field %q is missing", key)
+		}
+	}
+	env := fields["environment"].(map[string]interface{})
+	for key := range env {
+		if _, ok := schema.Properties["environment"].Properties[key]; !ok {
+			t.Errorf("environment field %q is not in the schema", key)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package attest
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// BundleMediaType identifies the Sigstore bundle format written by Bundle.
+const BundleMediaType = "application/vnd.dev.sigstore.bundle.v0.3+json"
+
+// bundleMediaTypes are the bundle versions Bundle reads. Before 0.3 the
+// certificate came in a chain, and the inclusion proof was optional.
+var bundleMediaTypes = map[string]bool{
+	"application/vnd.dev.sigstore.bundle+json;version=0.1": true,
+	"application/vnd.dev.sigstore.bundle+json;version=0.2": true,
+	"application/vnd.dev.sigstore.bundle.v0.3+json":        true,
+}
+
+// ErrUntrusted is returned by Bundle.Verify when a bundle is validly
+// signed, but not by an identity or key the caller trusts.
+var ErrUntrusted = errors.New("attest: signer is not trusted")
+
+// Bundle is a signed statement with the material needed to verify it, in
+// the JSON form of the Sigstore bundle format.
+type Bundle struct {
+	Envelope    *Envelope
+	Certificate *x509.Certificate // signing certificate for keyless signing; nil with a key
+	TlogEntries []TlogEntry
+}
+
+// BundleOptions says whom to trust when verifying a bundle. Key verifies
+// bundles signed with a key. Keyless bundles need Roots, Identity and
+// RekorKey: their short-lived certificate must chain to Roots, name
+// Identity (and Issuer, if set), and have been valid when the Rekor log
+// integrated the signature, as shown by an entry with an inclusion proof
+// up to a checkpoint signed with RekorKey.
+type BundleOptions struct {
+	Key           crypto.PublicKey
+	Roots         *x509.CertPool
+	Intermediates *x509.CertPool
+	Identity      string // email or URI subject alternative name
+	Issuer        string // OIDC issuer recorded in the certificate
+	RekorKey      crypto.PublicKey
+}
+
+// Verify checks the bundle's signature and returns its statement.
+func (b *Bundle) Verify(opts BundleOptions) (*Statement, error) {
+	if err := b.verify(opts); err != nil {
+		return nil, err
+	}
+	return b.Envelope.Statement()
+}
+
+// verify checks the bundle's signature, whatever its payload.
+func (b *Bundle) verify(opts BundleOptions) error {
+	// Only entries recording this signature by this signer tell when it
+	// was made; others, such as an entry for the same statement signed by
+	// another key, are ignored.
+	var signer []byte
+	switch {
+	case b.Certificate != nil:
+		signer = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: b.Certificate.Raw})
+	case opts.Key != nil:
+		der, err := x509.MarshalPKIXPublicKey(opts.Key)
+		if err != nil {
+			return err
+		}
+		signer = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	}
+	var integrated []time.Time
+	if opts.RekorKey != nil && signer != nil {
+		for _, t := range b.TlogEntries {
+			err := t.Verify(b.Envelope, signer, opts.RekorKey)
+			if errors.Is(err, errOtherSignature) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			// The inclusion proof does not cover the integrated time;
+			// only the signed entry timestamp vouches for it
+			if t.SignedEntryTimestamp != nil {
+				integrated = append(integrated, time.Unix(t.IntegratedTime, 0))
+			}
+		}
+	}
+
+	var verifier Verifier
+	switch {
+	case b.Certificate != nil:
+		if opts.Roots == nil || opts.Identity == "" {
+			return fmt.Errorf("%w: keyless bundles need trusted roots and an identity", ErrUntrusted)
+		}
+		if len(integrated) == 0 {
+			return fmt.Errorf("%w: no verified transparency log entry with a signed timestamp", ErrUntrusted)
+		}
+		_, err := b.Certificate.Verify(x509.VerifyOptions{
+			Roots:         opts.Roots,
+			Intermediates: opts.Intermediates,
+			CurrentTime:   integrated[0],
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		})
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrUntrusted, err)
+		}
+		if !certificateHasIdentity(b.Certificate, opts.Identity) {
+			return fmt.Errorf("%w: certificate is not for %s", ErrUntrusted, opts.Identity)
+		}
+		if opts.Issuer != "" && certificateIssuer(b.Certificate) != opts.Issuer {
+			return fmt.Errorf("%w: certificate was not issued for %s", ErrUntrusted, opts.Issuer)
+		}
+		verifier = &KeyVerifier{Key: b.Certificate.PublicKey}
+	case opts.Key != nil:
+		verifier = &KeyVerifier{Key: opts.Key}
+	default:
+		return fmt.Errorf("%w: no key given", ErrUntrusted)
+	}
+
+	return b.Envelope.VerifySignatures(verifier)
+}
+
+func certificateHasIdentity(cert *x509.Certificate, identity string) bool {
+	for _, email := range cert.EmailAddresses {
+		if email == identity {
+			return true
+		}
+	}
+	for _, uri := range cert.URIs {
+		if uri.String() == identity {
+			return true
+		}
+	}
+	return false
+}
+
+// Fulcio records the OIDC issuer in extension 1.3.6.1.4.1.57264.1.8 as a
+// DER string, and in the deprecated 1.3.6.1.4.1.57264.1.1 as raw bytes.
+var (
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+	oidIssuerV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+)
+
+func certificateIssuer(cert *x509.Certificate) string {
+	var legacy string
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidIssuerV2):
+			var issuer string
+			if _, err := asn1.Unmarshal(ext.Value, &issuer); err == nil {
+				return issuer
+			}
+		case ext.Id.Equal(oidIssuerV1):
+			legacy = string(ext.Value)
+		}
+	}
+	return legacy
+}
+
+type bundleJSON struct {
+	MediaType            string `json:"mediaType"`
+	VerificationMaterial struct {
+		Certificate *struct {
+			RawBytes []byte `json:"rawBytes"`
+		} `json:"certificate,omitempty"`
+		X509CertificateChain *struct {
+			Certificates []struct {
+				RawBytes []byte `json:"rawBytes"`
+			} `json:"certificates"`
+		} `json:"x509CertificateChain,omitempty"`
+		PublicKey *struct {
+			Hint string `json:"hint"`
+		} `json:"publicKey,omitempty"`
+		TlogEntries []tlogEntryJSON `json:"tlogEntries"`
+	} `json:"verificationMaterial"`
+	DSSEEnvelope *Envelope `json:"dsseEnvelope"`
+}
+
+// tlogEntryJSON follows the protobuf JSON mapping, which writes 64-bit
+// integers as strings.
+type tlogEntryJSON struct {
+	LogIndex int64 `json:"logIndex,string"`
+	LogID    struct {
+		KeyID []byte `json:"keyId"`
+	} `json:"logId"`
+	KindVersion struct {
+		Kind    string `json:"kind"`
+		Version string `json:"version"`
+	} `json:"kindVersion"`
+	IntegratedTime   int64 `json:"integratedTime,string"`
+	InclusionPromise struct {
+		SignedEntryTimestamp []byte `json:"signedEntryTimestamp"`
+	} `json:"inclusionPromise"`
+	InclusionProof    *inclusionProofJSON `json:"inclusionProof,omitempty"`
+	CanonicalizedBody []byte              `json:"canonicalizedBody"`
+}
+
+type inclusionProofJSON struct {
+	LogIndex   int64    `json:"logIndex,string"`
+	RootHash   []byte   `json:"rootHash"`
+	TreeSize   int64    `json:"treeSize,string"`
+	Hashes     [][]byte `json:"hashes"`
+	Checkpoint struct {
+		Envelope string `json:"envelope"`
+	} `json:"checkpoint"`
+}
+
+// MarshalJSON encodes the bundle in the Sigstore bundle v0.3 layout.
+func (b *Bundle) MarshalJSON() ([]byte, error) {
+	v := bundleJSON{MediaType: BundleMediaType, DSSEEnvelope: b.Envelope}
+	if b.Certificate != nil {
+		v.VerificationMaterial.Certificate = &struct {
+			RawBytes []byte `json:"rawBytes"`
+		}{b.Certificate.Raw}
+	} else {
+		v.VerificationMaterial.PublicKey = &struct {
+			Hint string `json:"hint"`
+		}{}
+	}
+	v.VerificationMaterial.TlogEntries = []tlogEntryJSON{}
+	for _, t := range b.TlogEntries {
+		var e tlogEntryJSON
+		e.LogIndex = t.LogIndex
+		e.LogID.KeyID = t.LogID
+		e.KindVersion.Kind = t.Kind
+		e.KindVersion.Version = t.Version
+		e.IntegratedTime = t.IntegratedTime
+		e.InclusionPromise.SignedEntryTimestamp = t.SignedEntryTimestamp
+		if p := t.InclusionProof; p != nil {
+			e.InclusionProof = &inclusionProofJSON{LogIndex: p.LogIndex, RootHash: p.RootHash, TreeSize: p.TreeSize, Hashes: p.Hashes}
+			e.InclusionProof.Checkpoint.Envelope = p.Checkpoint
+		}
+		e.CanonicalizedBody = t.CanonicalizedBody
+		v.VerificationMaterial.TlogEntries = append(v.VerificationMaterial.TlogEntries, e)
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes a bundle written by MarshalJSON or another
+// Sigstore client, in bundle format 0.1 to 0.3.
+func (b *Bundle) UnmarshalJSON(data []byte) error {
+	var v bundleJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if !bundleMediaTypes[v.MediaType] {
+		return fmt.Errorf("attest: unsupported bundle media type %q", v.MediaType)
+	}
+	if v.DSSEEnvelope == nil {
+		return fmt.Errorf("attest: bundle has no DSSE envelope")
+	}
+
+	*b = Bundle{Envelope: v.DSSEEnvelope}
+	var certDER []byte
+	if c := v.VerificationMaterial.Certificate; c != nil {
+		certDER = c.RawBytes
+	} else if c := v.VerificationMaterial.X509CertificateChain; c != nil && len(c.Certificates) > 0 {
+		certDER = c.Certificates[0].RawBytes
+	}
+	if certDER != nil {
+		cert, err := x509.ParseCertificate(certDER)
+		if err != nil {
+			return fmt.Errorf("attest: invalid bundle certificate: %w", err)
+		}
+		b.Certificate = cert
+	}
+	for _, e := range v.VerificationMaterial.TlogEntries {
+		t := TlogEntry{
+			LogIndex:             e.LogIndex,
+			LogID:                e.LogID.KeyID,
+			Kind:                 e.KindVersion.Kind,
+			Version:              e.KindVersion.Version,
+			IntegratedTime:       e.IntegratedTime,
+			SignedEntryTimestamp: e.InclusionPromise.SignedEntryTimestamp,
+			CanonicalizedBody:    e.CanonicalizedBody,
+		}
+		if p := e.InclusionProof; p != nil {
+			t.InclusionProof = &InclusionProof{
+				LogIndex:   p.LogIndex,
+				RootHash:   p.RootHash,
+				TreeSize:   p.TreeSize,
+				Hashes:     p.Hashes,
+				Checkpoint: p.Checkpoint.Envelope,
+			}
+		}
+		b.TlogEntries = append(b.TlogEntries, t)
+	}
+	return nil
+}
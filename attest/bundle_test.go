@@ -0,0 +1,462 @@
+package attest
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andrew/swhid-go"
+	"github.com/transparency-dev/merkle/rfc6962"
+	"github.com/transparency-dev/merkle/testonly"
+)
+
+const testIssuer = "https://oauth2.example.org/auth"
+
+// fakeFulcio issues certificates for the email in the identity token,
+// signed by a test root.
+func fakeFulcio(t *testing.T) (*httptest.Server, *x509.CertPool) {
+	t.Helper()
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, _ := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	ca, _ := x509.ParseCertificate(caDER)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Credentials struct {
+				OIDCIdentityToken string
+			}
+			PublicKeyRequest struct {
+				PublicKey struct {
+					Content string
+				}
+				ProofOfPossession []byte
+			}
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid request: %v", err)
+		}
+		email, _ := tokenSubject(req.Credentials.OIDCIdentityToken)
+		pub, err := LoadPublicKey([]byte(req.PublicKeyRequest.PublicKey.Content))
+		if err != nil {
+			t.Fatalf("invalid public key: %v", err)
+		}
+		digest := sha256.Sum256([]byte(email))
+		if !ecdsa.VerifyASN1(pub.(*ecdsa.PublicKey), digest[:], req.PublicKeyRequest.ProofOfPossession) {
+			http.Error(w, "bad proof of possession", http.StatusBadRequest)
+			return
+		}
+
+		issuer, _ := asn1.MarshalWithParams(testIssuer, "utf8")
+		leaf := &x509.Certificate{
+			SerialNumber:    big.NewInt(2),
+			NotBefore:       time.Now().Add(-time.Minute),
+			NotAfter:        time.Now().Add(10 * time.Minute),
+			EmailAddresses:  []string{email},
+			KeyUsage:        x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+			ExtraExtensions: []pkix.Extension{{Id: oidIssuerV2, Value: issuer}},
+		}
+		leafDER, _ := x509.CreateCertificate(rand.Reader, leaf, ca, pub, caKey)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"signedCertificateEmbeddedSct": map[string]interface{}{
+				"chain": map[string]interface{}{
+					"certificates": []string{
+						string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER})),
+						string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})),
+					},
+				},
+			},
+		})
+	}))
+	return server, roots
+}
+
+// fakeRekor accepts dsse entries into a Merkle tree, and returns signed
+// inclusion promises and inclusion proofs up to a signed checkpoint.
+func fakeRekor(t *testing.T) (*httptest.Server, crypto.PublicKey) {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalPKIXPublicKey(key.Public())
+	logID := sha256.Sum256(der)
+
+	// Earlier entries give the proofs a few levels
+	var mu sync.Mutex
+	tree := testonly.New(rfc6962.DefaultHasher)
+	tree.AppendData([]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e"))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Spec struct {
+				ProposedContent struct {
+					Envelope  string
+					Verifiers [][]byte
+				}
+			}
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid request: %v", err)
+		}
+		var env Envelope
+		json.Unmarshal([]byte(req.Spec.ProposedContent.Envelope), &env)
+		payloadHash := sha256.Sum256(env.Payload)
+		var signatures []map[string]interface{}
+		for _, sig := range env.Signatures {
+			signatures = append(signatures, map[string]interface{}{
+				"signature": base64.StdEncoding.EncodeToString(sig.Sig),
+				"verifier":  req.Spec.ProposedContent.Verifiers[0],
+			})
+		}
+		body, _ := json.Marshal(map[string]interface{}{
+			"apiVersion": "0.0.1",
+			"kind":       "dsse",
+			"spec": map[string]interface{}{
+				"payloadHash": map[string]string{"algorithm": "sha256", "value": hex.EncodeToString(payloadHash[:])},
+				"signatures":  signatures,
+			},
+		})
+
+		mu.Lock()
+		index := tree.Size()
+		tree.AppendData(body)
+		size := tree.Size()
+		hashes, _ := tree.InclusionProof(index, size)
+		root := tree.Hash()
+		mu.Unlock()
+
+		// Entries are numbered across shards of the log, proofs within one
+		logIndex := 1000 + int64(index)
+		integrated := time.Now().Unix()
+		promise, _ := json.Marshal(map[string]interface{}{
+			"body":           body,
+			"integratedTime": integrated,
+			"logID":          hex.EncodeToString(logID[:]),
+			"logIndex":       logIndex,
+		})
+		set, _ := (&KeySigner{Key: key}).Sign(promise)
+		var hexHashes []string
+		for _, h := range hashes {
+			hexHashes = append(hexHashes, hex.EncodeToString(h))
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"24296fb24b8ad77a": map[string]interface{}{
+				"body":           body,
+				"integratedTime": integrated,
+				"logID":          hex.EncodeToString(logID[:]),
+				"logIndex":       logIndex,
+				"verification": map[string]interface{}{
+					"signedEntryTimestamp": set,
+					"inclusionProof": map[string]interface{}{
+						"checkpoint": signCheckpoint(key, size, root),
+						"hashes":     hexHashes,
+						"logIndex":   index,
+						"rootHash":   hex.EncodeToString(root),
+						"treeSize":   size,
+					},
+				},
+			},
+		})
+	}))
+	return server, key.Public()
+}
+
+// signCheckpoint returns a checkpoint for a tree of size with root, signed
+// by key as Rekor signs them.
+func signCheckpoint(key *ecdsa.PrivateKey, size uint64, root []byte) string {
+	der, _ := x509.MarshalPKIXPublicKey(key.Public())
+	keyHash := sha256.Sum256(der)
+	text := fmt.Sprintf("rekor.example.org - 1\n%d\n%s\n", size, base64.StdEncoding.EncodeToString(root))
+	sig, _ := (&KeySigner{Key: key}).Sign([]byte(text))
+	return text + "\n\u2014 rekor.example.org " + base64.StdEncoding.EncodeToString(append(keyHash[:4], sig...)) + "\n"
+}
+
+func testToken(claims string) string {
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString([]byte(`{"alg":"RS256"}`)) + "." + enc.EncodeToString([]byte(claims)) + ".sig"
+}
+
+func testStatement() *Statement {
+	return NewStatement("v1.2.3", Subject{Name: "hello.txt", SWHID: swhid.FromContent([]byte("hello\n"))})
+}
+
+func roundTrip(t *testing.T, b *Bundle) *Bundle {
+	t.Helper()
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var got Bundle
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	return &got
+}
+
+func TestBundleKeyless(t *testing.T) {
+	fulcio, roots := fakeFulcio(t)
+	defer fulcio.Close()
+	rekor, rekorKey := fakeRekor(t)
+	defer rekor.Close()
+	ctx := context.Background()
+
+	signer, err := (&Fulcio{URL: fulcio.URL}).Signer(ctx, testToken(`{"sub":"123","email":"dev@example.org"}`))
+	if err != nil {
+		t.Fatalf("Signer() error = %v", err)
+	}
+	env, err := SignStatement(testStatement(), signer)
+	if err != nil {
+		t.Fatalf("SignStatement() error = %v", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: signer.Chain[0].Raw})
+	entry, err := (&Rekor{URL: rekor.URL}).Upload(ctx, env, certPEM)
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+
+	b := roundTrip(t, &Bundle{Envelope: env, Certificate: signer.Chain[0], TlogEntries: []TlogEntry{*entry}})
+	opts := BundleOptions{Roots: roots, Identity: "dev@example.org", Issuer: testIssuer, RekorKey: rekorKey}
+	st, err := b.Verify(opts)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if len(st.Subjects) != 1 || st.Subjects[0].Name != "hello.txt" ||
+		st.Subjects[0].SWHID.CoreSWHID() != "swh:1:cnt:ce013625030ba8dba906f756967f9e9ca394464a" {
+		t.Errorf("Verify() statement = %+v", st)
+	}
+
+	for name, bad := range map[string]BundleOptions{
+		"identity": {Roots: roots, Identity: "other@example.org", RekorKey: rekorKey},
+		"issuer":   {Roots: roots, Identity: "dev@example.org", Issuer: "https://other.example.org", RekorKey: rekorKey},
+		"roots":    {Roots: x509.NewCertPool(), Identity: "dev@example.org", RekorKey: rekorKey},
+		"no log":   {Roots: roots, Identity: "dev@example.org"},
+	} {
+		if _, err := b.Verify(bad); !errors.Is(err, ErrUntrusted) {
+			t.Errorf("Verify() with wrong %s error = %v, want ErrUntrusted", name, err)
+		}
+	}
+
+	// Without its signed timestamp the entry still proves inclusion, but
+	// not when the short-lived certificate was used
+	unstamped := *entry
+	unstamped.SignedEntryTimestamp = nil
+	b2 := roundTrip(t, &Bundle{Envelope: env, Certificate: signer.Chain[0], TlogEntries: []TlogEntry{unstamped}})
+	if _, err := b2.Verify(opts); !errors.Is(err, ErrUntrusted) {
+		t.Errorf("Verify() without a signed entry timestamp error = %v, want ErrUntrusted", err)
+	}
+
+	b.Envelope.Payload = []byte(`{}`)
+	if _, err := b.Verify(opts); err == nil {
+		t.Error("Verify() accepted a modified payload")
+	}
+}
+
+func TestBundleKeylessOtherSigner(t *testing.T) {
+	fulcio, roots := fakeFulcio(t)
+	defer fulcio.Close()
+	rekor, rekorKey := fakeRekor(t)
+	defer rekor.Close()
+	ctx := context.Background()
+
+	signer, err := (&Fulcio{URL: fulcio.URL}).Signer(ctx, testToken(`{"sub":"123","email":"dev@example.org"}`))
+	if err != nil {
+		t.Fatalf("Signer() error = %v", err)
+	}
+	env, err := SignStatement(testStatement(), signer)
+	if err != nil {
+		t.Fatalf("SignStatement() error = %v", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: signer.Chain[0].Raw})
+	entry, err := (&Rekor{URL: rekor.URL}).Upload(ctx, env, certPEM)
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+
+	// The same payload signed with another key has a log entry of its own,
+	// with the same payload hash
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	otherEnv, err := SignPayload(env.PayloadType, env.Payload, &KeySigner{Key: other})
+	if err != nil {
+		t.Fatalf("SignPayload() error = %v", err)
+	}
+	otherDER, _ := x509.MarshalPKIXPublicKey(other.Public())
+	otherEntry, err := (&Rekor{URL: rekor.URL}).Upload(ctx, otherEnv, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: otherDER}))
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+
+	opts := BundleOptions{Roots: roots, Identity: "dev@example.org", RekorKey: rekorKey}
+	b := roundTrip(t, &Bundle{Envelope: env, Certificate: signer.Chain[0], TlogEntries: []TlogEntry{*otherEntry}})
+	if _, err := b.Verify(opts); !errors.Is(err, ErrUntrusted) {
+		t.Errorf("Verify() with another signer's log entry error = %v, want ErrUntrusted", err)
+	}
+	if err := otherEntry.Verify(env, certPEM, rekorKey); err == nil {
+		t.Error("TlogEntry.Verify() accepted an entry for another signer")
+	}
+
+	b = roundTrip(t, &Bundle{Envelope: env, Certificate: signer.Chain[0], TlogEntries: []TlogEntry{*otherEntry, *entry}})
+	if _, err := b.Verify(opts); err != nil {
+		t.Errorf("Verify() with the signer's entry after another's error = %v", err)
+	}
+}
+
+func TestBundleKey(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	env, err := SignStatement(testStatement(), &KeySigner{Key: key})
+	if err != nil {
+		t.Fatalf("SignStatement() error = %v", err)
+	}
+	b := roundTrip(t, &Bundle{Envelope: env})
+
+	if _, err := b.Verify(BundleOptions{Key: key.Public()}); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if _, err := b.Verify(BundleOptions{Key: other.Public()}); !errors.Is(err, ErrNoValidSignature) {
+		t.Errorf("Verify() with another key error = %v, want ErrNoValidSignature", err)
+	}
+	if _, err := b.Verify(BundleOptions{}); !errors.Is(err, ErrUntrusted) {
+		t.Errorf("Verify() without a key error = %v, want ErrUntrusted", err)
+	}
+}
+
+func TestTlogEntryInclusionProof(t *testing.T) {
+	rekor, rekorKey := fakeRekor(t)
+	defer rekor.Close()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	env, err := SignStatement(testStatement(), &KeySigner{Key: key})
+	if err != nil {
+		t.Fatalf("SignStatement() error = %v", err)
+	}
+	der, _ := x509.MarshalPKIXPublicKey(key.Public())
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	entry, err := (&Rekor{URL: rekor.URL}).Upload(context.Background(), env, keyPEM)
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	entry = &roundTrip(t, &Bundle{Envelope: env, TlogEntries: []TlogEntry{*entry}}).TlogEntries[0]
+	if err := entry.Verify(env, keyPEM, rekorKey); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	otherLog, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	for name, modify := range map[string]func(*TlogEntry){
+		"no proof":    func(e *TlogEntry) { e.InclusionProof = nil },
+		"proof hash":  func(e *TlogEntry) { e.InclusionProof.Hashes[0] = make([]byte, 32) },
+		"proof index": func(e *TlogEntry) { e.InclusionProof.LogIndex++ },
+		"other body":  func(e *TlogEntry) { e.CanonicalizedBody = append([]byte(" "), e.CanonicalizedBody...) },
+		"unsigned root": func(e *TlogEntry) {
+			e.InclusionProof.Checkpoint = signCheckpoint(otherLog, uint64(e.InclusionProof.TreeSize), e.InclusionProof.RootHash)
+		},
+		"checkpoint": func(e *TlogEntry) {
+			e.InclusionProof.Checkpoint = strings.Replace(e.InclusionProof.Checkpoint, "\n\n", "\n", 1)
+		},
+		"promise":        func(e *TlogEntry) { e.IntegratedTime++ },
+		"missing hashes": func(e *TlogEntry) { e.InclusionProof.Hashes = e.InclusionProof.Hashes[1:] },
+	} {
+		bad := *entry
+		proof := *entry.InclusionProof
+		proof.Hashes = append([][]byte(nil), proof.Hashes...)
+		bad.InclusionProof = &proof
+		modify(&bad)
+		if err := bad.Verify(env, keyPEM, rekorKey); err == nil {
+			t.Errorf("Verify() accepted an entry with a bad %s", name)
+		}
+	}
+}
+
+// TestBundlePublicGood verifies a bundle from the public Sigstore instance:
+// the npm provenance of sigstore-js 2.0.0, signed keylessly in GitHub
+// Actions and logged in rekor.sigstore.dev as an intoto entry. It comes from
+// sigstore-go's test data, with the Fulcio certificates and Rekor key from
+// its public-good trusted root.
+func TestBundlePublicGood(t *testing.T) {
+	data, err := os.ReadFile("testdata/sigstore-js-provenance.sigstore.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b Bundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	opts := BundleOptions{
+		Roots:         x509.NewCertPool(),
+		Intermediates: x509.NewCertPool(),
+		Identity:      "https://github.com/sigstore/sigstore-js/.github/workflows/release.yml@refs/heads/main",
+		Issuer:        "https://token.actions.githubusercontent.com",
+	}
+	chain, err := os.ReadFile("testdata/fulcio.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for block, rest := pem.Decode(chain); block != nil; block, rest = pem.Decode(rest) {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cert.CheckSignatureFrom(cert) == nil {
+			opts.Roots.AddCert(cert)
+		} else {
+			opts.Intermediates.AddCert(cert)
+		}
+	}
+	rekorPEM, err := os.ReadFile("testdata/rekor.pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.RekorKey, err = LoadPublicKey(rekorPEM); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.verify(opts); err != nil {
+		t.Fatalf("verify() error = %v", err)
+	}
+	// It is an attestation, but not of SWHIDs
+	if _, err := b.Verify(opts); err == nil || errors.Is(err, ErrUntrusted) {
+		t.Errorf("Verify() error = %v, want the statement refused", err)
+	}
+
+	// Checks that fail on the real entry, not only on the fake log's
+	proof := b.TlogEntries[0].InclusionProof
+	proof.Hashes[3][0] ^= 1
+	if err := b.verify(opts); err == nil {
+		t.Error("verify() accepted a modified inclusion proof")
+	}
+	proof.Hashes[3][0] ^= 1
+	proof.Checkpoint = strings.Replace(proof.Checkpoint, "27657875", "27657876", 1)
+	if err := b.verify(opts); err == nil {
+		t.Error("verify() accepted a modified checkpoint")
+	}
+}
@@ -0,0 +1,115 @@
+package attest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// PayloadType is the DSSE payload type of a signed VerificationResult.
+const PayloadType = "application/vnd.swhid.verification-result+json"
+
+// ErrNoValidSignature is returned when no signature of an envelope checks
+// out against the given verifiers.
+var ErrNoValidSignature = errors.New("attest: no valid signature")
+
+// Signer signs messages with one key. It is the hook through which results
+// are signed: implementations may hold a key in memory, call out to an
+// external tool, or use a signing service.
+type Signer interface {
+	KeyID() string // identifies the key to verifiers; may be empty
+	Sign(message []byte) ([]byte, error)
+}
+
+// Verifier checks signatures made by one key.
+type Verifier interface {
+	KeyID() string
+	Verify(message, sig []byte) error
+}
+
+// Signature is one signature of an Envelope.
+type Signature struct {
+	KeyID string `json:"keyid"`
+	Sig   []byte `json:"sig"`
+}
+
+// Envelope is a DSSE envelope: the encoded payload and signatures over
+// its pre-authentication encoding. Its JSON form is the standard DSSE
+// layout, so generic DSSE tooling can check it.
+type Envelope struct {
+	Payload     []byte      `json:"payload"`
+	PayloadType string      `json:"payloadType"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+// Sign encodes r and signs it with each of signers.
+func Sign(r *VerificationResult, signers ...Signer) (*Envelope, error) {
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return SignPayload(PayloadType, payload, signers...)
+}
+
+// SignPayload returns an envelope of payload signed with each of signers.
+func SignPayload(payloadType string, payload []byte, signers ...Signer) (*Envelope, error) {
+	env := &Envelope{Payload: payload, PayloadType: payloadType}
+	message := env.Message()
+	for _, s := range signers {
+		sig, err := s.Sign(message)
+		if err != nil {
+			return nil, fmt.Errorf("attest: signing with %q: %w", s.KeyID(), err)
+		}
+		env.Signatures = append(env.Signatures, Signature{KeyID: s.KeyID(), Sig: sig})
+	}
+	return env, nil
+}
+
+// Verify checks that at least one signature was made by one of verifiers
+// and returns the decoded result.
+func (e *Envelope) Verify(verifiers ...Verifier) (*VerificationResult, error) {
+	if e.PayloadType != PayloadType {
+		return nil, fmt.Errorf("attest: unexpected payload type %q", e.PayloadType)
+	}
+	if err := e.VerifySignatures(verifiers...); err != nil {
+		return nil, err
+	}
+
+	var r VerificationResult
+	if err := json.Unmarshal(e.Payload, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// VerifySignatures checks that at least one signature was made by one of
+// verifiers. A signature is only tried against verifiers with the same key
+// ID, unless either ID is empty.
+func (e *Envelope) VerifySignatures(verifiers ...Verifier) error {
+	message := e.Message()
+	for _, sig := range e.Signatures {
+		for _, v := range verifiers {
+			if sig.KeyID != "" && v.KeyID() != "" && sig.KeyID != v.KeyID() {
+				continue
+			}
+			if v.Verify(message, sig.Sig) == nil {
+				return nil
+			}
+		}
+	}
+	return ErrNoValidSignature
+}
+
+// Message returns the DSSE pre-authentication encoding of the payload,
+// which is what signatures cover.
+func (e *Envelope) Message() []byte {
+	b := []byte("DSSEv1 ")
+	b = strconv.AppendInt(b, int64(len(e.PayloadType)), 10)
+	b = append(b, ' ')
+	b = append(b, e.PayloadType...)
+	b = append(b, ' ')
+	b = strconv.AppendInt(b, int64(len(e.Payload)), 10)
+	b = append(b, ' ')
+	return append(b, e.Payload...)
+}
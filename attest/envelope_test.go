@@ -0,0 +1,71 @@
+package attest
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type ed25519Key struct {
+	id   string
+	priv ed25519.PrivateKey
+}
+
+func newKey(t *testing.T, id string) *ed25519Key {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &ed25519Key{id: id, priv: priv}
+}
+
+func (k *ed25519Key) KeyID() string { return k.id }
+
+func (k *ed25519Key) Sign(message []byte) ([]byte, error) {
+	return ed25519.Sign(k.priv, message), nil
+}
+
+func (k *ed25519Key) Verify(message, sig []byte) error {
+	if !ed25519.Verify(k.priv.Public().(ed25519.PublicKey), message, sig) {
+		return errors.New("bad signature")
+	}
+	return nil
+}
+
+func TestEnvelope(t *testing.T) {
+	key := newKey(t, "release")
+	r := testResult(t)
+
+	env, err := Sign(r, key)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	data, err := json.Marshal(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Envelope
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := decoded.Verify(newKey(t, "other"), key)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if !got.Target.Equal(r.Target) || got.Match != r.Match {
+		t.Errorf("Verify() = %+v, want %+v", got, r)
+	}
+
+	if _, err := decoded.Verify(newKey(t, "release")); !errors.Is(err, ErrNoValidSignature) {
+		t.Errorf("Verify() with the wrong key error = %v, want ErrNoValidSignature", err)
+	}
+
+	decoded.Payload[len(decoded.Payload)-2] ^= 1
+	if _, err := decoded.Verify(key); !errors.Is(err, ErrNoValidSignature) {
+		t.Errorf("Verify() of a modified payload error = %v, want ErrNoValidSignature", err)
+	}
+}
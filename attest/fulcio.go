@@ -0,0 +1,184 @@
+package attest
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultFulcioURL is the public Sigstore certificate authority.
+const DefaultFulcioURL = "https://fulcio.sigstore.dev"
+
+// Fulcio requests short-lived signing certificates bound to an OIDC
+// identity, for keyless signing.
+type Fulcio struct {
+	URL    string       // defaults to DefaultFulcioURL
+	Client *http.Client // defaults to http.DefaultClient
+}
+
+// CertSigner is a Signer whose key is certified by Fulcio.
+type CertSigner struct {
+	KeySigner
+	Chain []*x509.Certificate // leaf first
+}
+
+// Signer generates an ephemeral key and has Fulcio certify it for the
+// identity in idToken, an OIDC identity token issued for the "sigstore"
+// audience.
+func (f *Fulcio) Signer(ctx context.Context, idToken string) (*CertSigner, error) {
+	subject, err := tokenSubject(idToken)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(subject))
+	proof, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	req := map[string]interface{}{
+		"credentials": map[string]string{"oidcIdentityToken": idToken},
+		"publicKeyRequest": map[string]interface{}{
+			"publicKey": map[string]string{
+				"algorithm": "ECDSA",
+				"content":   string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})),
+			},
+			"proofOfPossession": proof,
+		},
+	}
+	var resp struct {
+		SignedCertificateEmbeddedSct *struct{ Chain certificateChain }
+		SignedCertificateDetachedSct *struct{ Chain certificateChain }
+	}
+	base := f.URL
+	if base == "" {
+		base = DefaultFulcioURL
+	}
+	if err := postJSON(ctx, f.Client, strings.TrimSuffix(base, "/")+"/api/v2/signingCert", req, &resp); err != nil {
+		return nil, err
+	}
+
+	var chain certificateChain
+	switch {
+	case resp.SignedCertificateEmbeddedSct != nil:
+		chain = resp.SignedCertificateEmbeddedSct.Chain
+	case resp.SignedCertificateDetachedSct != nil:
+		chain = resp.SignedCertificateDetachedSct.Chain
+	}
+	certs, err := parseCertificates([]byte(strings.Join(chain.Certificates, "")))
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("attest: Fulcio returned no certificate")
+	}
+	if !key.PublicKey.Equal(certs[0].PublicKey) {
+		return nil, fmt.Errorf("attest: Fulcio certificate is for a different key")
+	}
+	return &CertSigner{KeySigner: KeySigner{Key: key}, Chain: certs}, nil
+}
+
+type certificateChain struct {
+	Certificates []string
+}
+
+// tokenSubject returns the identity Fulcio expects a proof of possession
+// for: the email claim of email tokens, the sub claim of others. The token
+// is not verified here; Fulcio does that.
+func tokenSubject(idToken string) (string, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("attest: identity token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("attest: invalid identity token: %w", err)
+	}
+	var claims struct {
+		Sub   string `json:"sub"`
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", fmt.Errorf("attest: invalid identity token: %w", err)
+	}
+	if claims.Email != "" {
+		return claims.Email, nil
+	}
+	if claims.Sub == "" {
+		return "", fmt.Errorf("attest: identity token has no subject")
+	}
+	return claims.Sub, nil
+}
+
+// parseCertificates parses every CERTIFICATE block of a PEM bundle.
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return certs, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("attest: invalid certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+}
+
+// postJSON posts req as JSON and decodes a 2xx response into resp.
+func postJSON(ctx context.Context, client *http.Client, url string, req, resp interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to post to %s: %w", url, err)
+	}
+	defer httpResp.Body.Close()
+
+	data, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return err
+	}
+	if httpResp.StatusCode < 200 || httpResp.StatusCode > 299 {
+		return fmt.Errorf("failed to post to %s: %s: %s", url, httpResp.Status, bytes.TrimSpace(data))
+	}
+	return json.Unmarshal(data, resp)
+}
@@ -0,0 +1,199 @@
+package attest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+// ErrPassword is returned by LoadPrivateKey when an encrypted key cannot be
+// decrypted with the given password.
+var ErrPassword = errors.New("attest: wrong password for encrypted key")
+
+// KeySigner signs with an in-memory ECDSA, Ed25519 or RSA key, producing
+// the signatures cosign produces for the same key: ECDSA over the SHA-2
+// digest matching the curve, Ed25519 over the message itself and RSA
+// PKCS #1 v1.5 over SHA-256.
+type KeySigner struct {
+	Key crypto.Signer
+	ID  string // key ID recorded in signatures; cosign leaves it empty
+}
+
+// KeyID returns s.ID.
+func (s *KeySigner) KeyID() string { return s.ID }
+
+// Sign signs message.
+func (s *KeySigner) Sign(message []byte) ([]byte, error) {
+	if _, ok := s.Key.Public().(ed25519.PublicKey); ok {
+		return s.Key.Sign(rand.Reader, message, crypto.Hash(0))
+	}
+	h, err := hashFor(s.Key.Public())
+	if err != nil {
+		return nil, err
+	}
+	d := h.New()
+	d.Write(message)
+	return s.Key.Sign(rand.Reader, d.Sum(nil), h)
+}
+
+// KeyVerifier checks signatures made by a KeySigner.
+type KeyVerifier struct {
+	Key crypto.PublicKey
+	ID  string
+}
+
+// KeyID returns v.ID.
+func (v *KeyVerifier) KeyID() string { return v.ID }
+
+// Verify checks sig over message.
+func (v *KeyVerifier) Verify(message, sig []byte) error {
+	if pub, ok := v.Key.(ed25519.PublicKey); ok {
+		if !ed25519.Verify(pub, message, sig) {
+			return ErrNoValidSignature
+		}
+		return nil
+	}
+
+	h, err := hashFor(v.Key)
+	if err != nil {
+		return err
+	}
+	d := h.New()
+	d.Write(message)
+	digest := d.Sum(nil)
+
+	switch pub := v.Key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(pub, digest, sig) {
+			return ErrNoValidSignature
+		}
+		return nil
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(pub, h, digest, sig)
+	}
+	return fmt.Errorf("attest: unsupported key type %T", v.Key)
+}
+
+func hashFor(pub crypto.PublicKey) (crypto.Hash, error) {
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			return crypto.SHA256, nil
+		case elliptic.P384():
+			return crypto.SHA384, nil
+		case elliptic.P521():
+			return crypto.SHA512, nil
+		}
+		return 0, fmt.Errorf("attest: unsupported curve %s", pub.Curve.Params().Name)
+	case *rsa.PublicKey:
+		return crypto.SHA256, nil
+	}
+	return 0, fmt.Errorf("attest: unsupported key type %T", pub)
+}
+
+// LoadPrivateKey parses a PEM private key: PKCS #8, SEC 1 ("EC PRIVATE
+// KEY") or PKCS #1, or a key written by cosign generate-key-pair, which is
+// encrypted with password.
+func LoadPrivateKey(data, password []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("attest: no PEM private key found")
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "ENCRYPTED SIGSTORE PRIVATE KEY", "ENCRYPTED COSIGN PRIVATE KEY":
+		der, decErr := decryptCosignKey(block.Bytes, password)
+		if decErr != nil {
+			return nil, decErr
+		}
+		key, err = x509.ParsePKCS8PrivateKey(der)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("attest: unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("attest: invalid private key: %w", err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("attest: unsupported key type %T", key)
+	}
+	return signer, nil
+}
+
+// LoadPublicKey parses a PEM "PUBLIC KEY" block, as written by cosign
+// generate-key-pair and openssl pkey -pubout.
+func LoadPublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("attest: no PEM public key found")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("attest: invalid public key: %w", err)
+	}
+	return pub, nil
+}
+
+// cosignKey is the JSON body of an encrypted cosign private key.
+type cosignKey struct {
+	KDF struct {
+		Name   string `json:"name"`
+		Params struct {
+			N int `json:"N"`
+			R int `json:"r"`
+			P int `json:"p"`
+		} `json:"params"`
+		Salt []byte `json:"salt"`
+	} `json:"kdf"`
+	Cipher struct {
+		Name  string `json:"name"`
+		Nonce []byte `json:"nonce"`
+	} `json:"cipher"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func decryptCosignKey(data, password []byte) ([]byte, error) {
+	var k cosignKey
+	if err := json.Unmarshal(data, &k); err != nil {
+		return nil, fmt.Errorf("attest: invalid encrypted key: %w", err)
+	}
+	if k.KDF.Name != "scrypt" || k.Cipher.Name != "nacl/secretbox" || len(k.Cipher.Nonce) != 24 {
+		return nil, fmt.Errorf("attest: unsupported key encryption %s/%s", k.KDF.Name, k.Cipher.Name)
+	}
+
+	secret, err := scrypt.Key(password, k.KDF.Salt, k.KDF.Params.N, k.KDF.Params.R, k.KDF.Params.P, 32)
+	if err != nil {
+		return nil, fmt.Errorf("attest: invalid encrypted key: %w", err)
+	}
+	var key [32]byte
+	var nonce [24]byte
+	copy(key[:], secret)
+	copy(nonce[:], k.Cipher.Nonce)
+
+	der, ok := secretbox.Open(nil, k.Ciphertext, &nonce, &key)
+	if !ok {
+		return nil, ErrPassword
+	}
+	return der, nil
+}
@@ -0,0 +1,99 @@
+package attest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+// encryptCosignKey writes key the way cosign generate-key-pair does.
+func encryptCosignKey(t *testing.T, key crypto.Signer, password []byte) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var k cosignKey
+	k.KDF.Name = "scrypt"
+	k.KDF.Params.N, k.KDF.Params.R, k.KDF.Params.P = 1<<10, 8, 1
+	k.KDF.Salt = make([]byte, 32)
+	k.Cipher.Name = "nacl/secretbox"
+	k.Cipher.Nonce = make([]byte, 24)
+	rand.Read(k.KDF.Salt)
+	rand.Read(k.Cipher.Nonce)
+
+	secret, err := scrypt.Key(password, k.KDF.Salt, k.KDF.Params.N, k.KDF.Params.R, k.KDF.Params.P, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var box [32]byte
+	var nonce [24]byte
+	copy(box[:], secret)
+	copy(nonce[:], k.Cipher.Nonce)
+	k.Ciphertext = secretbox.Seal(nil, der, &nonce, &box)
+
+	data, err := json.Marshal(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED SIGSTORE PRIVATE KEY", Bytes: data})
+}
+
+func TestLoadPrivateKey(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(edKey)
+	sec1, _ := x509.MarshalECPrivateKey(ecKey)
+
+	tests := []struct {
+		name string
+		data []byte
+		key  crypto.Signer
+	}{
+		{"pkcs8", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}), edKey},
+		{"sec1", pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1}), ecKey},
+		{"cosign", encryptCosignKey(t, ecKey, []byte("hunter2")), ecKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := LoadPrivateKey(tt.data, []byte("hunter2"))
+			if err != nil {
+				t.Fatalf("LoadPrivateKey() error = %v", err)
+			}
+
+			pubDER, _ := x509.MarshalPKIXPublicKey(tt.key.Public())
+			pub, err := LoadPublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}))
+			if err != nil {
+				t.Fatalf("LoadPublicKey() error = %v", err)
+			}
+
+			sig, err := (&KeySigner{Key: key}).Sign([]byte("message"))
+			if err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+			v := &KeyVerifier{Key: pub}
+			if err := v.Verify([]byte("message"), sig); err != nil {
+				t.Errorf("Verify() error = %v", err)
+			}
+			if err := v.Verify([]byte("other"), sig); err == nil {
+				t.Error("Verify() accepted a signature over another message")
+			}
+		})
+	}
+
+	_, err := LoadPrivateKey(encryptCosignKey(t, ecKey, []byte("hunter2")), []byte("wrong"))
+	if !errors.Is(err, ErrPassword) {
+		t.Errorf("LoadPrivateKey() with the wrong password error = %v, want ErrPassword", err)
+	}
+}
@@ -0,0 +1,328 @@
+package attest
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/transparency-dev/merkle/proof"
+	"github.com/transparency-dev/merkle/rfc6962"
+)
+
+// DefaultRekorURL is the public Sigstore transparency log.
+const DefaultRekorURL = "https://rekor.sigstore.dev"
+
+// Rekor records signed envelopes in a transparency log, which proves when
+// they were signed: keyless certificates are only valid for minutes, so a
+// signature is checked against its certificate at the time the log
+// integrated it.
+type Rekor struct {
+	URL    string       // defaults to DefaultRekorURL
+	Client *http.Client // defaults to http.DefaultClient
+}
+
+// TlogEntry is a transparency log entry for an envelope, with the log's
+// signed promise to include it and the proof that it did.
+type TlogEntry struct {
+	LogIndex             int64
+	LogID                []byte // SHA-256 of the log's public key
+	Kind                 string
+	Version              string
+	IntegratedTime       int64 // Unix seconds
+	SignedEntryTimestamp []byte
+	InclusionProof       *InclusionProof
+	CanonicalizedBody    []byte
+}
+
+// InclusionProof is a Merkle audit path from an entry to the root of the
+// log's tree, and the checkpoint in which the log signed that root.
+type InclusionProof struct {
+	LogIndex   int64 // index within the tree, which on a sharded log differs from TlogEntry.LogIndex
+	RootHash   []byte
+	TreeSize   int64
+	Hashes     [][]byte
+	Checkpoint string // signed note: origin, tree size and base64 root hash
+}
+
+// Upload adds env to the log as a "dsse" entry. verifier is the PEM
+// certificate or public key that checks its signatures.
+func (r *Rekor) Upload(ctx context.Context, env *Envelope, verifier []byte) (*TlogEntry, error) {
+	envJSON, err := json.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+	req := map[string]interface{}{
+		"apiVersion": "0.0.1",
+		"kind":       "dsse",
+		"spec": map[string]interface{}{
+			"proposedContent": map[string]interface{}{
+				"envelope":  string(envJSON),
+				"verifiers": [][]byte{verifier},
+			},
+		},
+	}
+
+	var resp map[string]struct {
+		Body           []byte
+		IntegratedTime int64
+		LogID          string
+		LogIndex       int64
+		Verification   struct {
+			SignedEntryTimestamp []byte
+			InclusionProof       *struct {
+				Checkpoint string
+				Hashes     []string
+				LogIndex   int64
+				RootHash   string
+				TreeSize   int64
+			}
+		}
+	}
+	base := r.URL
+	if base == "" {
+		base = DefaultRekorURL
+	}
+	if err := postJSON(ctx, r.Client, strings.TrimSuffix(base, "/")+"/api/v1/log/entries", req, &resp); err != nil {
+		return nil, err
+	}
+	for _, e := range resp {
+		logID, err := hex.DecodeString(e.LogID)
+		if err != nil {
+			return nil, fmt.Errorf("attest: invalid Rekor log ID %q", e.LogID)
+		}
+		entry := &TlogEntry{
+			LogIndex:             e.LogIndex,
+			LogID:                logID,
+			Kind:                 "dsse",
+			Version:              "0.0.1",
+			IntegratedTime:       e.IntegratedTime,
+			SignedEntryTimestamp: e.Verification.SignedEntryTimestamp,
+			CanonicalizedBody:    e.Body,
+		}
+		if p := e.Verification.InclusionProof; p != nil {
+			// Rekor's API writes hashes in hex, unlike the bundle format
+			proof := &InclusionProof{LogIndex: p.LogIndex, TreeSize: p.TreeSize, Checkpoint: p.Checkpoint}
+			if proof.RootHash, err = hex.DecodeString(p.RootHash); err != nil {
+				return nil, fmt.Errorf("attest: invalid Rekor root hash %q", p.RootHash)
+			}
+			for _, h := range p.Hashes {
+				b, err := hex.DecodeString(h)
+				if err != nil {
+					return nil, fmt.Errorf("attest: invalid Rekor proof hash %q", h)
+				}
+				proof.Hashes = append(proof.Hashes, b)
+			}
+			entry.InclusionProof = proof
+		}
+		return entry, nil
+	}
+	return nil, fmt.Errorf("attest: Rekor returned no entry")
+}
+
+// errOtherSignature is returned by TlogEntry.Verify for an entry that
+// records a different signature or signer than the one being verified.
+var errOtherSignature = errors.New("attest: log entry records another signature")
+
+// Verify checks that the log holding key included the entry, and that the
+// entry records one of env's signatures made by verifier, the PEM
+// certificate or public key given to Upload. Inclusion is shown by the
+// entry's Merkle inclusion proof, which must lead to the root hash of a
+// checkpoint signed by key; a signed entry timestamp, if present, must be
+// signed by key too. Both "dsse" entries and the "intoto" entries written
+// by other Sigstore clients are understood.
+func (t *TlogEntry) Verify(env *Envelope, verifier []byte, key crypto.PublicKey) error {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return err
+	}
+	keyID := sha256.Sum256(der)
+	if string(keyID[:]) != string(t.LogID) {
+		return fmt.Errorf("attest: log entry is from a different log")
+	}
+
+	if t.InclusionProof == nil {
+		return fmt.Errorf("attest: log entry has no inclusion proof")
+	}
+	if err := t.InclusionProof.verify(t.CanonicalizedBody, key); err != nil {
+		return err
+	}
+
+	if t.SignedEntryTimestamp != nil {
+		// The promise is a signature over the RFC 8785 canonical JSON of
+		// these fields; encoding/json sorts map keys and adds no
+		// whitespace.
+		promise, err := json.Marshal(map[string]interface{}{
+			"body":           t.CanonicalizedBody,
+			"integratedTime": t.IntegratedTime,
+			"logID":          hex.EncodeToString(t.LogID),
+			"logIndex":       t.LogIndex,
+		})
+		if err != nil {
+			return err
+		}
+		if err := (&KeyVerifier{Key: key}).Verify(promise, t.SignedEntryTimestamp); err != nil {
+			return fmt.Errorf("attest: invalid log inclusion promise")
+		}
+	}
+
+	type hash struct {
+		Algorithm string
+		Value     string
+	}
+	var body struct {
+		Kind string
+		Spec struct {
+			// dsse 0.0.1
+			PayloadHash hash
+			Signatures  []struct {
+				Signature string // base64, as in the envelope
+				Verifier  []byte // PEM
+			}
+
+			// intoto 0.0.2, which encodes the envelope's base64
+			// signatures and the PEM verifier in base64 again
+			Content struct {
+				PayloadHash hash
+				Envelope    struct {
+					Signatures []struct {
+						Sig       []byte
+						PublicKey []byte
+					}
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(t.CanonicalizedBody, &body); err != nil {
+		return fmt.Errorf("attest: invalid log entry body: %w", err)
+	}
+	type loggedSignature struct {
+		signature string
+		verifier  []byte
+	}
+	var payload hash
+	var logged []loggedSignature
+	switch body.Kind {
+	case "dsse":
+		payload = body.Spec.PayloadHash
+		for _, sig := range body.Spec.Signatures {
+			logged = append(logged, loggedSignature{sig.Signature, sig.Verifier})
+		}
+	case "intoto":
+		payload = body.Spec.Content.PayloadHash
+		for _, sig := range body.Spec.Content.Envelope.Signatures {
+			logged = append(logged, loggedSignature{string(sig.Sig), sig.PublicKey})
+		}
+	}
+	payloadHash := sha256.Sum256(env.Payload)
+	if payload.Algorithm != "sha256" || payload.Value != hex.EncodeToString(payloadHash[:]) {
+		return fmt.Errorf("attest: log entry does not record this envelope")
+	}
+
+	// The payload hash alone would let an entry for the same statement
+	// signed by someone else vouch for when this signature was made.
+	for _, l := range logged {
+		if !sameVerifier(l.verifier, verifier) {
+			continue
+		}
+		for _, sig := range env.Signatures {
+			if l.signature == base64.StdEncoding.EncodeToString(sig.Sig) {
+				return nil
+			}
+		}
+	}
+	return errOtherSignature
+}
+
+// verify checks that p proves the inclusion of the entry with body in a
+// tree whose root the log holding key signed.
+func (p *InclusionProof) verify(body []byte, key crypto.PublicKey) error {
+	if p.LogIndex < 0 || p.TreeSize < 0 {
+		return fmt.Errorf("attest: invalid inclusion proof")
+	}
+	leaf := rfc6962.DefaultHasher.HashLeaf(body)
+	if err := proof.VerifyInclusion(rfc6962.DefaultHasher, uint64(p.LogIndex), uint64(p.TreeSize), leaf, p.Hashes, p.RootHash); err != nil {
+		return fmt.Errorf("attest: invalid inclusion proof: %w", err)
+	}
+
+	size, root, err := verifyCheckpoint(p.Checkpoint, key)
+	if err != nil {
+		return err
+	}
+	if size != p.TreeSize || !bytes.Equal(root, p.RootHash) {
+		return fmt.Errorf("attest: checkpoint is for another tree than the inclusion proof")
+	}
+	return nil
+}
+
+// errCheckpoint is returned for a checkpoint that is not a signed note.
+var errCheckpoint = errors.New("attest: invalid checkpoint")
+
+// verifyCheckpoint checks that note is a checkpoint signed by key, in the
+// signed note format, and returns the tree size and root hash it records.
+func verifyCheckpoint(note string, key crypto.PublicKey) (int64, []byte, error) {
+	i := strings.LastIndex(note, "\n\n")
+	if i < 0 {
+		return 0, nil, errCheckpoint
+	}
+	text, sigs := note[:i+1], note[i+2:]
+
+	// Signatures start with the first four bytes of the SHA-256 of the
+	// signing key, which tell the log's own signature from witnesses'.
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return 0, nil, err
+	}
+	keyHash := sha256.Sum256(der)
+	verified := false
+	for _, line := range strings.Split(strings.TrimSuffix(sigs, "\n"), "\n") {
+		rest, ok := strings.CutPrefix(line, "\u2014 ")
+		fields := strings.Fields(rest)
+		if !ok || len(fields) != 2 {
+			return 0, nil, errCheckpoint
+		}
+		sig, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil || len(sig) < 5 {
+			return 0, nil, errCheckpoint
+		}
+		if bytes.Equal(sig[:4], keyHash[:4]) && (&KeyVerifier{Key: key}).Verify([]byte(text), sig[4:]) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return 0, nil, fmt.Errorf("attest: checkpoint is not signed by the log")
+	}
+
+	lines := strings.Split(text, "\n")
+	if len(lines) < 4 {
+		return 0, nil, errCheckpoint
+	}
+	size, err := strconv.ParseInt(lines[1], 10, 64)
+	if err != nil {
+		return 0, nil, errCheckpoint
+	}
+	root, err := base64.StdEncoding.DecodeString(lines[2])
+	if err != nil {
+		return 0, nil, errCheckpoint
+	}
+	return size, root, nil
+}
+
+// sameVerifier reports whether the PEM blocks a and b hold the same
+// certificate or public key.
+func sameVerifier(a, b []byte) bool {
+	blockA, _ := pem.Decode(a)
+	blockB, _ := pem.Decode(b)
+	return blockA != nil && blockB != nil && blockA.Type == blockB.Type && bytes.Equal(blockA.Bytes, blockB.Bytes)
+}
@@ -0,0 +1,133 @@
+package attest
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/andrew/swhid-go"
+)
+
+// Identifiers of the in-toto statements produced by this package.
+const (
+	StatementType        = "https://in-toto.io/Statement/v1"
+	StatementPayloadType = "application/vnd.in-toto+json"
+	PredicateType        = "https://github.com/andrew/swhid-go/attestation/swhid/v1"
+)
+
+// digestAlgorithms maps SWHID object types to in-toto digest names. The
+// hash of every type but snapshots is the Git object ID.
+var digestAlgorithms = map[swhid.ObjectType]string{
+	swhid.ObjectTypeContent:   "gitBlob",
+	swhid.ObjectTypeDirectory: "gitTree",
+	swhid.ObjectTypeRevision:  "gitCommit",
+	swhid.ObjectTypeRelease:   "gitTag",
+	swhid.ObjectTypeSnapshot:  "swhSnapshot",
+}
+
+// Subject is an artifact a Statement makes a claim about.
+type Subject struct {
+	Name  string // such as the path that was hashed
+	SWHID *swhid.Identifier
+}
+
+// Statement is an in-toto statement binding artifacts to their SWHIDs. In
+// a signed envelope it records who claims the artifacts have those SWHIDs.
+type Statement struct {
+	Subjects    []Subject
+	ToolVersion string
+	Created     time.Time
+}
+
+// NewStatement returns a statement about subjects, stamped with the
+// current time.
+func NewStatement(toolVersion string, subjects ...Subject) *Statement {
+	return &Statement{Subjects: subjects, ToolVersion: toolVersion, Created: time.Now().UTC()}
+}
+
+type statementJSON struct {
+	Type          string        `json:"_type"`
+	Subject       []subjectJSON `json:"subject"`
+	PredicateType string        `json:"predicateType"`
+	Predicate     predicateJSON `json:"predicate"`
+}
+
+type subjectJSON struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+type predicateJSON struct {
+	SWHIDs      []string  `json:"swhids"`
+	ToolVersion string    `json:"tool_version"`
+	Created     time.Time `json:"created"`
+}
+
+// MarshalJSON encodes the statement in the in-toto v1 layout. Each subject
+// carries its Git object ID as digest; the full SWHIDs, including
+// qualifiers, are listed in the predicate in subject order.
+func (s *Statement) MarshalJSON() ([]byte, error) {
+	v := statementJSON{
+		Type:          StatementType,
+		PredicateType: PredicateType,
+		Predicate:     predicateJSON{ToolVersion: s.ToolVersion, Created: s.Created},
+	}
+	for _, sub := range s.Subjects {
+		v.Subject = append(v.Subject, subjectJSON{
+			Name:   sub.Name,
+			Digest: map[string]string{digestAlgorithms[sub.SWHID.ObjectType]: sub.SWHID.ObjectHash},
+		})
+		v.Predicate.SWHIDs = append(v.Predicate.SWHIDs, sub.SWHID.String())
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes a statement written by MarshalJSON, checking that
+// subject digests agree with the SWHIDs of the predicate.
+func (s *Statement) UnmarshalJSON(data []byte) error {
+	var v statementJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.Type != StatementType || v.PredicateType != PredicateType {
+		return fmt.Errorf("attest: unsupported statement %q with predicate %q", v.Type, v.PredicateType)
+	}
+	if len(v.Subject) != len(v.Predicate.SWHIDs) {
+		return fmt.Errorf("attest: %d subjects but %d SWHIDs", len(v.Subject), len(v.Predicate.SWHIDs))
+	}
+
+	*s = Statement{ToolVersion: v.Predicate.ToolVersion, Created: v.Predicate.Created}
+	for i, sub := range v.Subject {
+		id, err := swhid.Parse(v.Predicate.SWHIDs[i])
+		if err != nil {
+			return fmt.Errorf("attest: subject %q: %w", sub.Name, err)
+		}
+		if sub.Digest[digestAlgorithms[id.ObjectType]] != id.ObjectHash {
+			return fmt.Errorf("attest: subject %q: digest does not match %s", sub.Name, id.CoreSWHID())
+		}
+		s.Subjects = append(s.Subjects, Subject{Name: sub.Name, SWHID: id})
+	}
+	return nil
+}
+
+// SignStatement encodes s and signs it with each of signers.
+func SignStatement(s *Statement, signers ...Signer) (*Envelope, error) {
+	payload, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return SignPayload(StatementPayloadType, payload, signers...)
+}
+
+// Statement decodes the statement in an envelope, without checking
+// signatures.
+func (e *Envelope) Statement() (*Statement, error) {
+	if e.PayloadType != StatementPayloadType {
+		return nil, fmt.Errorf("attest: unexpected payload type %q", e.PayloadType)
+	}
+	var s Statement
+	if err := json.Unmarshal(e.Payload, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
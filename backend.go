@@ -0,0 +1,47 @@
+package swhid
+
+import "github.com/andrew/swhid-go/objects"
+
+// RepoBackend reads what revision and snapshot SWHIDs are computed from
+// out of a Git repository, for FromRevisionBackend and FromSnapshotBackend.
+// *RepoSession implements it with go-git. The path-based functions such as
+// FromRevision and FromSnapshot read repositories with go-git directly and
+// do not go through a RepoBackend.
+//
+// Other implementations let callers hash repositories go-git reads slowly
+// or not at all, such as ones borrowing objects through alternates or
+// worktrees sharing a common directory, with another Git implementation:
+// libgit2 bindings, the git command, or a service's own object store.
+// Backends that need cgo or other dependencies belong in their own module,
+// so this one stays pure Go and builds for WebAssembly.
+type RepoBackend interface {
+	// RevisionMetadata returns the metadata of the commit ref resolves
+	// to, extra headers and message byte for byte.
+	RevisionMetadata(ref string) (objects.RevisionMetadata, error)
+
+	// SnapshotBranches returns the repository's branches as the Software
+	// Heritage loader records them: HEAD and the references it keeps,
+	// each with the type of the object it points to.
+	SnapshotBranches() ([]objects.Branch, error)
+}
+
+// FromRevisionBackend returns the revision SWHID of the commit ref
+// resolves to in b.
+func FromRevisionBackend(b RepoBackend, ref string) (*Identifier, error) {
+	meta, err := b.RevisionMetadata(ref)
+	if err != nil {
+		return nil, err
+	}
+	return FromRevisionMetadata(meta), nil
+}
+
+// FromSnapshotBackend returns the snapshot SWHID of the branches of b,
+// after checking them as FromSnapshotBranchesStrict does, since a backend
+// may report branches go-git would never produce.
+func FromSnapshotBackend(b RepoBackend) (*Identifier, error) {
+	branches, err := b.SnapshotBranches()
+	if err != nil {
+		return nil, err
+	}
+	return FromSnapshotBranchesStrict(branches)
+}
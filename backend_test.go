@@ -0,0 +1,70 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/andrew/swhid-go/objects"
+)
+
+// fakeBackend is a RepoBackend serving fixed data, as one over another Git
+// implementation would.
+type fakeBackend struct {
+	meta     objects.RevisionMetadata
+	branches []objects.Branch
+	err      error
+}
+
+func (f *fakeBackend) RevisionMetadata(string) (objects.RevisionMetadata, error) {
+	return f.meta, f.err
+}
+
+func (f *fakeBackend) SnapshotBranches() ([]objects.Branch, error) {
+	return f.branches, f.err
+}
+
+func TestRepoBackend(t *testing.T) {
+	repoPath, _, head := newTestRepo(t)
+	session, err := OpenRepo(repoPath)
+	if err != nil {
+		t.Fatalf("OpenRepo() error = %v", err)
+	}
+
+	rev, err := FromRevisionBackend(session, "HEAD")
+	if err != nil {
+		t.Fatalf("FromRevisionBackend() error = %v", err)
+	}
+	if rev.ObjectHash != head.String() {
+		t.Errorf("FromRevisionBackend() = %v, want %v", rev.ObjectHash, head)
+	}
+	snp, err := FromSnapshotBackend(session)
+	if err != nil {
+		t.Fatalf("FromSnapshotBackend() error = %v", err)
+	}
+	if want, _ := FromSnapshot(repoPath); !snp.Equal(want) {
+		t.Errorf("FromSnapshotBackend() = %v, want %v", snp, want)
+	}
+
+	// Another backend reporting the same data gives the same SWHIDs
+	meta, _ := session.RevisionMetadata("HEAD")
+	branches, _ := session.SnapshotBranches()
+	fake := &fakeBackend{meta: meta, branches: branches}
+	if got, _ := FromRevisionBackend(fake, "HEAD"); !got.Equal(rev) {
+		t.Errorf("FromRevisionBackend(fake) = %v, want %v", got, rev)
+	}
+	if got, _ := FromSnapshotBackend(fake); !got.Equal(snp) {
+		t.Errorf("FromSnapshotBackend(fake) = %v, want %v", got, snp)
+	}
+
+	broken := errors.New("object store offline")
+	if _, err := FromRevisionBackend(&fakeBackend{err: broken}, "HEAD"); !errors.Is(err, broken) {
+		t.Errorf("FromRevisionBackend() error = %v, want %v", err, broken)
+	}
+	bad := &fakeBackend{branches: []objects.Branch{{Name: "refs/heads/main", TargetType: objects.BranchTargetRevision, Target: "not a hash"}}}
+	var branchErr *objects.BranchError
+	if _, err := FromSnapshotBackend(bad); !errors.As(err, &branchErr) {
+		t.Errorf("FromSnapshotBackend(bad) error = %v, want *objects.BranchError", err)
+	}
+}
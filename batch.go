@@ -0,0 +1,129 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// BatchOptions controls SnapshotMany.
+type BatchOptions struct {
+	GitOptions
+
+	// Concurrency is how many repositories are snapshotted at once;
+	// runtime.NumCPU() when 0.
+	Concurrency int
+
+	// Progress, if set, is called after each repository is done. Calls
+	// are never concurrent.
+	Progress func(BatchProgress)
+}
+
+// BatchProgress reports how far SnapshotMany has got.
+type BatchProgress struct {
+	Done   int // repositories finished so far, failed or not
+	Failed int // of Done, how many failed
+	Total  int
+	Result BatchResult // the repository just finished
+}
+
+// BatchResult is the outcome for one repository of SnapshotMany: either
+// its snapshot or the error that stopped it.
+type BatchResult struct {
+	Path     string
+	Snapshot *Identifier
+	Err      error
+	Duration time.Duration
+}
+
+// SnapshotMany computes the snapshot SWHIDs of many repositories, at most
+// opts.Concurrency at a time, and returns a result for each path in the
+// order given. Paths may name repositories or bundle files. A repository
+// that fails, even by panicking inside go-git on corrupt data, only fails
+// its own result. Once ctx is done no further repository is started, and
+// the ones not started fail with ctx's error.
+func SnapshotMany(ctx context.Context, repoPaths []string, opts BatchOptions) []BatchResult {
+	workers := opts.Concurrency
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(repoPaths) {
+		workers = len(repoPaths)
+	}
+
+	results := make([]BatchResult, len(repoPaths))
+	indexes := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		progress = BatchProgress{Total: len(repoPaths)}
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = snapshotOne(repoPaths[i], opts.GitOptions)
+
+				mu.Lock()
+				progress.Done++
+				if results[i].Err != nil {
+					progress.Failed++
+				}
+				progress.Result = results[i]
+				if opts.Progress != nil {
+					opts.Progress(progress)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	next := 0
+feed:
+	for ; next < len(repoPaths) && ctx.Err() == nil; next++ {
+		select {
+		case indexes <- next:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	for i := next; i < len(repoPaths); i++ {
+		results[i] = BatchResult{Path: repoPaths[i], Err: ctx.Err()}
+	}
+	return results
+}
+
+// snapshotOne computes the snapshot of one repository or bundle for
+// SnapshotMany, turning a panic into the result's error.
+func snapshotOne(repoPath string, opts GitOptions) (result BatchResult) {
+	result.Path = repoPath
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			result.Snapshot = nil
+			result.Err = fmt.Errorf("snapshot of %s panicked: %v", repoPath, r)
+		}
+		result.Duration = time.Since(start)
+	}()
+
+	if info, err := os.Stat(repoPath); err == nil && info.Mode().IsRegular() {
+		session, err := OpenBundleWithOptions(repoPath, opts)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		result.Snapshot, result.Err = session.Snapshot()
+		return result
+	}
+	result.Snapshot, result.Err = FromSnapshotWithOptions(repoPath, opts)
+	return result
+}
@@ -0,0 +1,72 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshotMany(t *testing.T) {
+	var paths []string
+	var want []*Identifier
+	for i := 0; i < 5; i++ {
+		dir, _, _ := newTestRepo(t)
+		id, err := FromSnapshot(dir)
+		if err != nil {
+			t.Fatalf("FromSnapshot() error = %v", err)
+		}
+		paths = append(paths, dir)
+		want = append(want, id)
+	}
+	missing := filepath.Join(t.TempDir(), "missing")
+	paths = append(paths[:2], append([]string{missing}, paths[2:]...)...)
+	want = append(want[:2], append([]*Identifier{nil}, want[2:]...)...)
+
+	var calls, lastDone, lastFailed int
+	results := SnapshotMany(context.Background(), paths, BatchOptions{
+		Concurrency: 3,
+		Progress: func(p BatchProgress) {
+			calls++
+			lastDone, lastFailed = p.Done, p.Failed
+			if p.Total != len(paths) {
+				t.Errorf("Total = %d, want %d", p.Total, len(paths))
+			}
+		},
+	})
+
+	if len(results) != len(paths) {
+		t.Fatalf("SnapshotMany() returned %d results, want %d", len(results), len(paths))
+	}
+	for i, r := range results {
+		if r.Path != paths[i] {
+			t.Errorf("results[%d].Path = %s, want %s", i, r.Path, paths[i])
+		}
+		if want[i] == nil {
+			if r.Err == nil {
+				t.Errorf("results[%d] of a missing repository has no error", i)
+			}
+			continue
+		}
+		if r.Err != nil || !r.Snapshot.Equal(want[i]) {
+			t.Errorf("results[%d] = %v, %v, want %v", i, r.Snapshot, r.Err, want[i])
+		}
+	}
+	if calls != len(paths) || lastDone != len(paths) || lastFailed != 1 {
+		t.Errorf("progress: %d calls, done %d, failed %d", calls, lastDone, lastFailed)
+	}
+}
+
+func TestSnapshotManyCanceled(t *testing.T) {
+	dir, _, _ := newTestRepo(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := SnapshotMany(ctx, []string{dir, dir, dir}, BatchOptions{Concurrency: 1})
+	for i, r := range results {
+		if r.Err != context.Canceled || r.Path != dir {
+			t.Errorf("results[%d] = %+v, want context.Canceled", i, r)
+		}
+	}
+}
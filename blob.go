@@ -0,0 +1,53 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+)
+
+// ErrNotBlob is returned when a path in a tree names a directory or a
+// submodule rather than a file or symlink.
+var ErrNotBlob = errors.New("path is not a file or symlink")
+
+// FromGitBlob returns the content SWHID of the file or symlink at path in
+// the tree of the commit ref resolves to. The blob hash recorded in the tree
+// is the content SWHID's hash, so only the trees along path are read, not
+// the file itself.
+func FromGitBlob(repoPath, ref, path string) (*Identifier, error) {
+	repo, err := openRepository(repoPath, false, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	return FromGitBlobRepo(repo, ref, path)
+}
+
+// FromGitBlobRepo is like FromGitBlob for an already open repository.
+func FromGitBlobRepo(repo *git.Repository, ref, path string) (*Identifier, error) {
+	commit, err := resolveCommitIn(repo, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree: %w", err)
+	}
+
+	path = strings.Trim(path, "/")
+	entry, err := tree.FindEntry(path)
+	if err != nil {
+		return nil, fmt.Errorf("path %s not found in %s: %w", path, ref, err)
+	}
+	if entry.Mode == filemode.Dir || entry.Mode == filemode.Submodule {
+		return nil, fmt.Errorf("%w: %s", ErrNotBlob, path)
+	}
+
+	return NewIdentifier(ObjectTypeContent, entry.Hash.String(), nil)
+}
@@ -0,0 +1,50 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFromGitBlob(t *testing.T) {
+	dir, repo, first := newTestRepo(t)
+
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("changed\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	commitAll(t, repo, "Second commit\n")
+
+	tests := []struct {
+		ref, path string
+		content   string
+	}{
+		{"HEAD", "src/main.go", "package main\n"},
+		{"HEAD", "/hello.txt", "changed\n"},
+		{first.String(), "hello.txt", "hello\n"},
+	}
+	for _, tt := range tests {
+		id, err := FromGitBlob(dir, tt.ref, tt.path)
+		if err != nil {
+			t.Fatalf("FromGitBlob(%s, %s) error = %v", tt.ref, tt.path, err)
+		}
+		if want := FromContent([]byte(tt.content)); !id.Equal(want) {
+			t.Errorf("FromGitBlob(%s, %s) = %v, want %v", tt.ref, tt.path, id, want)
+		}
+	}
+
+	if _, err := FromGitBlob(dir, "HEAD", "src"); !errors.Is(err, ErrNotBlob) {
+		t.Errorf("FromGitBlob() of a directory error = %v, want ErrNotBlob", err)
+	}
+	if _, err := FromGitBlob(dir, "HEAD", "missing.txt"); err == nil {
+		t.Error("FromGitBlob() of a missing path succeeded")
+	}
+}
@@ -0,0 +1,131 @@
+// Package buildinfo lets a Go binary carry the SWHIDs of the source it was
+// built from.
+//
+// The identifiers are injected at link time:
+//
+//	go build -ldflags "$(swhid version --ldflags .)" ./cmd/app
+//
+// or, from Go code such as a build script:
+//
+//	flags, err := buildinfo.LDFlags(".")
+//
+// At run time Provenance returns whatever was embedded. When no revision was
+// injected, the Git commit recorded by the Go toolchain (-buildvcs) is used;
+// revisions of other version control systems are ignored.
+package buildinfo
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+
+	"github.com/andrew/swhid-go"
+)
+
+// Set with -ldflags "-X github.com/andrew/swhid-go/buildinfo.Directory=swh:1:dir:..." etc.
+var (
+	Directory string // SWHID of the source tree
+	Revision  string // SWHID of the source commit
+	Origin    string // optional origin URL of the source repository
+)
+
+// importPath is the package path used in -X linker flags.
+const importPath = "github.com/andrew/swhid-go/buildinfo"
+
+// Info describes the source provenance embedded in a binary.
+type Info struct {
+	Directory *swhid.Identifier
+	Revision  *swhid.Identifier
+	Origin    string
+	Modified  bool // the toolchain recorded uncommitted changes at build time
+}
+
+// Provenance returns the SWHIDs embedded in the running binary. Fields are
+// nil when nothing was embedded. An error is returned if an embedded value
+// is not a valid SWHID of the expected type.
+func Provenance() (*Info, error) {
+	info := &Info{Origin: Origin}
+
+	var err error
+	if info.Directory, err = parseEmbedded(Directory, swhid.ObjectTypeDirectory); err != nil {
+		return nil, err
+	}
+	if info.Revision, err = parseEmbedded(Revision, swhid.ObjectTypeRevision); err != nil {
+		return nil, err
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info.addBuildSettings(bi.Settings)
+	}
+
+	return info, nil
+}
+
+// addBuildSettings fills in what the toolchain recorded with -buildvcs.
+// vcs.revision is only a Git commit, and so a revision SWHID, when vcs is
+// git; Mercurial changeset IDs are also 40 hex digits.
+func (info *Info) addBuildSettings(settings []debug.BuildSetting) {
+	var vcs, revision string
+	for _, setting := range settings {
+		switch setting.Key {
+		case "vcs":
+			vcs = setting.Value
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+	if info.Revision == nil && vcs == "git" && revision != "" {
+		info.Revision, _ = swhid.NewIdentifier(swhid.ObjectTypeRevision, revision, nil)
+	}
+}
+
+func parseEmbedded(value string, want swhid.ObjectType) (*swhid.Identifier, error) {
+	if value == "" {
+		return nil, nil
+	}
+	id, err := swhid.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("embedded %s SWHID: %w", want, err)
+	}
+	if id.ObjectType != want {
+		return nil, fmt.Errorf("embedded SWHID %s is not a %s", value, want)
+	}
+	return id, nil
+}
+
+// LDFlags computes the SWHIDs of the HEAD commit of the Git repository at
+// repoPath and of its tree, and returns the matching -X linker flags, ready
+// to pass to go build -ldflags. The directory is the committed tree, as
+// Software Heritage archives it, so untracked files and build outputs in
+// the checkout do not change it.
+func LDFlags(repoPath string) (string, error) {
+	dir, err := swhid.FromGitTree(repoPath, "HEAD^{tree}")
+	if err != nil {
+		return "", fmt.Errorf("failed to compute directory SWHID: %w", err)
+	}
+
+	rev, err := swhid.FromRevision(repoPath, "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to compute revision SWHID: %w", err)
+	}
+
+	return Flags(dir, rev, ""), nil
+}
+
+// Flags returns -X linker flags embedding the given identifiers. Nil
+// identifiers and an empty origin are omitted.
+func Flags(dir, rev *swhid.Identifier, origin string) string {
+	var flags []string
+	if dir != nil {
+		flags = append(flags, fmt.Sprintf("-X %s.Directory=%s", importPath, dir.CoreSWHID()))
+	}
+	if rev != nil {
+		flags = append(flags, fmt.Sprintf("-X %s.Revision=%s", importPath, rev.CoreSWHID()))
+	}
+	if origin != "" {
+		flags = append(flags, fmt.Sprintf("-X %s.Origin=%s", importPath, origin))
+	}
+	return strings.Join(flags, " ")
+}
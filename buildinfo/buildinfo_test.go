@@ -0,0 +1,131 @@
+package buildinfo
+
+import (
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrew/swhid-go"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestFlags(t *testing.T) {
+	dir, _ := swhid.NewIdentifier(swhid.ObjectTypeDirectory, "d198bc9d7a6bcf6db04f476d29314f157507d505", nil)
+	rev, _ := swhid.NewIdentifier(swhid.ObjectTypeRevision, "309cf2674ee7a0749978cf8265ab91a60aea0f7d", nil)
+
+	got := Flags(dir, rev, "")
+	want := "-X github.com/andrew/swhid-go/buildinfo.Directory=swh:1:dir:d198bc9d7a6bcf6db04f476d29314f157507d505" +
+		" -X github.com/andrew/swhid-go/buildinfo.Revision=swh:1:rev:309cf2674ee7a0749978cf8265ab91a60aea0f7d"
+	if got != want {
+		t.Errorf("Flags() = %v, want %v", got, want)
+	}
+
+	if got := Flags(nil, nil, "https://example.com/repo"); !strings.HasSuffix(got, ".Origin=https://example.com/repo") {
+		t.Errorf("Flags() = %v, want origin flag", got)
+	}
+}
+
+func TestProvenance(t *testing.T) {
+	defer func(d, r, o string) { Directory, Revision, Origin = d, r, o }(Directory, Revision, Origin)
+
+	Directory = "swh:1:dir:d198bc9d7a6bcf6db04f476d29314f157507d505"
+	Revision = "swh:1:rev:309cf2674ee7a0749978cf8265ab91a60aea0f7d"
+	Origin = "https://example.com/repo"
+
+	info, err := Provenance()
+	if err != nil {
+		t.Fatalf("Provenance() error = %v", err)
+	}
+	if info.Directory.String() != Directory {
+		t.Errorf("Directory = %v, want %v", info.Directory, Directory)
+	}
+	if info.Revision.String() != Revision {
+		t.Errorf("Revision = %v, want %v", info.Revision, Revision)
+	}
+	if info.Origin != Origin {
+		t.Errorf("Origin = %v, want %v", info.Origin, Origin)
+	}
+}
+
+func TestProvenanceWrongType(t *testing.T) {
+	defer func(d string) { Directory = d }(Directory)
+
+	Directory = "swh:1:rev:309cf2674ee7a0749978cf8265ab91a60aea0f7d"
+	if _, err := Provenance(); err == nil {
+		t.Error("Provenance() expected error for revision SWHID in Directory")
+	}
+}
+
+func TestBuildSettings(t *testing.T) {
+	const hash = "309cf2674ee7a0749978cf8265ab91a60aea0f7d"
+	tests := []struct {
+		vcs      string
+		want     string
+		modified bool
+	}{
+		{"git", "swh:1:rev:" + hash, true},
+		{"hg", "", true}, // a Mercurial changeset ID, not a Git commit
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		info := &Info{}
+		info.addBuildSettings([]debug.BuildSetting{
+			{Key: "vcs", Value: tt.vcs},
+			{Key: "vcs.revision", Value: hash},
+			{Key: "vcs.modified", Value: "true"},
+		})
+		got := ""
+		if info.Revision != nil {
+			got = info.Revision.String()
+		}
+		if got != tt.want || info.Modified != tt.modified {
+			t.Errorf("vcs %q: Revision = %q, Modified = %v; want %q, %v", tt.vcs, got, info.Modified, tt.want, tt.modified)
+		}
+	}
+}
+
+func TestLDFlags(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("PlainInit() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Add("main.go"); err != nil {
+		t.Fatal(err)
+	}
+	sig := &object.Signature{Name: "Test", Email: "test@example.com", When: time.Unix(1000000000, 0).UTC()}
+	hash, err := wt.Commit("Initial commit\n", &git.CommitOptions{Author: sig, Committer: sig})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A build output left in the checkout is not part of the source
+	if err := os.WriteFile(filepath.Join(dir, "app"), []byte("\x7fELF"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	flags, err := LDFlags(dir)
+	if err != nil {
+		t.Fatalf("LDFlags() error = %v", err)
+	}
+	for _, want := range []string{"Directory=swh:1:dir:" + commit.TreeHash.String(), "Revision=swh:1:rev:" + hash.String()} {
+		if !strings.Contains(flags, want) {
+			t.Errorf("LDFlags() = %q, want %s", flags, want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// Bundle errors.
+var (
+	ErrNotBundle        = errors.New("not a git bundle")
+	ErrIncompleteBundle = errors.New("bundle does not contain every object it references")
+)
+
+// FromBundle computes the snapshot SWHID of a git bundle file, as
+// FromSnapshot does for a repository. The bundle is read into memory; it is
+// not unpacked on disk. Bundles do not record symbolic references, so HEAD
+// appears only if the bundle lists it, pointing directly at its commit.
+func FromBundle(path string) (*Identifier, error) {
+	session, err := OpenBundle(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return session.Snapshot()
+}
+
+// OpenBundle reads a git bundle file into memory and returns a session for
+// computing its snapshot, revision, release and tree SWHIDs.
+func OpenBundle(path string) (*RepoSession, error) {
+	return OpenBundleWithOptions(path, GitOptions{})
+}
+
+// OpenBundleWithOptions is like OpenBundle but reads the bundle as opts
+// requests; set opts.Head to restore the symbolic HEAD a bundle drops.
+func OpenBundleWithOptions(path string, opts GitOptions) (*RepoSession, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	repo, err := readBundle(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return NewRepoSession(repo, opts), nil
+}
+
+// readBundle parses a v2 or v3 bundle: a header line, optional capability
+// lines (v3), prerequisite lines, reference lines, a blank line and a
+// packfile. It returns a repository backed by in-memory storage.
+func readBundle(r *bufio.Reader) (*git.Repository, error) {
+	header, err := r.ReadString('\n')
+	if err != nil {
+		return nil, ErrNotBundle
+	}
+	switch header {
+	case "# v2 git bundle\n", "# v3 git bundle\n":
+	default:
+		return nil, ErrNotBundle
+	}
+
+	var refs []*plumbing.Reference
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle header: %w", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			break
+		}
+
+		switch {
+		case strings.HasPrefix(line, "@"):
+			if err := checkBundleCapability(line[1:]); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(line, "-"):
+			hash, _, _ := strings.Cut(line[1:], " ")
+			return nil, fmt.Errorf("%w: requires commit %s", ErrIncompleteBundle, hash)
+		default:
+			hash, name, ok := strings.Cut(line, " ")
+			if !ok || !plumbing.IsHash(hash) {
+				return nil, fmt.Errorf("invalid bundle reference line %q", line)
+			}
+			refs = append(refs, plumbing.NewHashReference(plumbing.ReferenceName(name), plumbing.NewHash(hash)))
+		}
+	}
+
+	storage := memory.NewStorage()
+	repo, err := git.Init(storage, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Init points HEAD at a default branch; a bundle's HEAD is only what it lists.
+	if err := storage.RemoveReference(plumbing.HEAD); err != nil {
+		return nil, err
+	}
+
+	if err := packfile.UpdateObjectStorage(storage, r); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read bundle packfile: %w", err)
+	}
+
+	for _, ref := range refs {
+		if err := storage.SetReference(ref); err != nil {
+			return nil, err
+		}
+	}
+
+	return repo, nil
+}
+
+func checkBundleCapability(capability string) error {
+	key, value, _ := strings.Cut(capability, "=")
+	switch key {
+	case "object-format":
+		if value != "sha1" {
+			return fmt.Errorf("unsupported bundle object format %q", value)
+		}
+		return nil
+	case "filter":
+		return fmt.Errorf("%w: created with filter %s", ErrIncompleteBundle, value)
+	default:
+		return fmt.Errorf("unsupported bundle capability %q", key)
+	}
+}
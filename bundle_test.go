@@ -0,0 +1,139 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrew/swhid-go/objects"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+)
+
+// writeBundle writes every object and the given references of repo to a
+// v2 bundle file, as "git bundle create --all" would.
+func writeBundle(t *testing.T, repo *git.Repository, refs ...*plumbing.Reference) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "repo.bundle")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create bundle: %v", err)
+	}
+	defer f.Close()
+
+	fmt.Fprintln(f, "# v2 git bundle")
+	for _, ref := range refs {
+		fmt.Fprintf(f, "%s %s\n", ref.Hash(), ref.Name())
+	}
+	fmt.Fprintln(f)
+
+	iter, err := repo.Storer.IterEncodedObjects(plumbing.AnyObject)
+	if err != nil {
+		t.Fatalf("Failed to list objects: %v", err)
+	}
+	var hashes []plumbing.Hash
+	iter.ForEach(func(obj plumbing.EncodedObject) error {
+		hashes = append(hashes, obj.Hash())
+		return nil
+	})
+
+	if _, err := packfile.NewEncoder(f, repo.Storer, false).Encode(hashes, 10); err != nil {
+		t.Fatalf("Failed to write packfile: %v", err)
+	}
+	return path
+}
+
+func TestFromBundle(t *testing.T) {
+	repoPath, repo, hash := newTestRepo(t)
+
+	if err := os.WriteFile(filepath.Join(repoPath, "hello.txt"), []byte("hello again\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	head := commitAll(t, repo, "Second commit\n")
+
+	bundle := writeBundle(t, repo,
+		plumbing.NewHashReference(plumbing.HEAD, head),
+		plumbing.NewHashReference("refs/heads/master", head),
+		plumbing.NewHashReference("refs/tags/v0", hash),
+	)
+
+	id, err := FromBundle(bundle)
+	if err != nil {
+		t.Fatalf("FromBundle() error = %v", err)
+	}
+	want := FromSnapshotBranches([]objects.Branch{
+		{Name: "HEAD", TargetType: objects.BranchTargetRevision, Target: head.String()},
+		{Name: "refs/heads/master", TargetType: objects.BranchTargetRevision, Target: head.String()},
+		{Name: "refs/tags/v0", TargetType: objects.BranchTargetRevision, Target: hash.String()},
+	})
+	if !id.Equal(want) {
+		t.Errorf("FromBundle() = %v, want %v", id, want)
+	}
+
+	// Restoring the symbolic HEAD gives the repository's own snapshot
+	// once the extra tag is accounted for.
+	session, err := OpenBundleWithOptions(bundle, GitOptions{Head: "refs/heads/master"})
+	if err != nil {
+		t.Fatalf("OpenBundleWithOptions() error = %v", err)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference("refs/tags/v0", hash)); err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+	snp, err := session.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	wantSnp, _ := FromSnapshot(repoPath)
+	if !snp.Equal(wantSnp) {
+		t.Errorf("bundle Snapshot() = %v, want %v", snp, wantSnp)
+	}
+
+	rev, err := session.Revision("refs/tags/v0")
+	if err != nil {
+		t.Fatalf("Revision() error = %v", err)
+	}
+	if rev.ObjectHash != hash.String() {
+		t.Errorf("bundle Revision() = %v, want %v", rev.ObjectHash, hash)
+	}
+
+	file, err := session.Tree("HEAD", "hello.txt")
+	if err != nil {
+		t.Fatalf("Tree() error = %v", err)
+	}
+	if !file.Equal(FromContent([]byte("hello again\n"))) {
+		t.Errorf("bundle Tree() = %v", file)
+	}
+}
+
+func TestFromBundleErrors(t *testing.T) {
+	dir := t.TempDir()
+	hash := "ce013625030ba8dba906f756967f9e9ca394464a"
+
+	tests := []struct {
+		name    string
+		content string
+		want    error
+	}{
+		{"not a bundle", "PACK", ErrNotBundle},
+		{"prerequisites", "# v2 git bundle\n-" + hash + " parent\n" + hash + " refs/heads/main\n\n", ErrIncompleteBundle},
+		{"filtered", "# v3 git bundle\n@object-format=sha1\n@filter=blob:none\n" + hash + " refs/heads/main\n\n", ErrIncompleteBundle},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "test.bundle")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write bundle: %v", err)
+			}
+			if _, err := FromBundle(path); !errors.Is(err, tt.want) {
+				t.Errorf("FromBundle() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
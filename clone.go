@@ -0,0 +1,84 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/andrew/swhid-go/objects"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// CloneOptions controls how CloneAndSnapshot fetches a remote repository.
+type CloneOptions struct {
+	GitOptions
+
+	// Depth limits the clone to that many commits from each reference.
+	// Snapshot and HEAD revision SWHIDs only need the tips, so a depth of 1
+	// gives the same identifiers as a full clone while fetching far less.
+	// Zero clones the full history.
+	Depth int
+
+	// InMemory keeps the clone in memory instead of a temporary directory.
+	InMemory bool
+
+	// Auth authenticates against the remote, if it requires it.
+	Auth transport.AuthMethod
+}
+
+// RemoteSnapshot is what CloneAndSnapshot computes for a remote repository.
+type RemoteSnapshot struct {
+	Snapshot *Identifier      // snapshot of the remote's references
+	Head     *Identifier      // revision HEAD resolves to, or nil if it doesn't resolve to a commit
+	Branches []objects.Branch // branches the snapshot hashes
+}
+
+// CloneAndSnapshot mirrors every reference of the repository at url into a
+// bare clone, computes its snapshot and HEAD revision SWHIDs, and removes
+// the clone before returning.
+func CloneAndSnapshot(ctx context.Context, url string, opts CloneOptions) (*RemoteSnapshot, error) {
+	cloneOpts := &git.CloneOptions{
+		URL:    url,
+		Auth:   opts.Auth,
+		Mirror: true,
+		Depth:  opts.Depth,
+		Tags:   git.AllTags,
+	}
+
+	var repo *git.Repository
+	var err error
+	if opts.InMemory {
+		repo, err = git.CloneContext(ctx, memory.NewStorage(), nil, cloneOpts)
+	} else {
+		dir, tmpErr := os.MkdirTemp("", "swhid-clone-*")
+		if tmpErr != nil {
+			return nil, tmpErr
+		}
+		defer os.RemoveAll(dir)
+		repo, err = git.PlainCloneContext(ctx, dir, true, cloneOpts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone %s: %w", url, err)
+	}
+
+	branches, err := snapshotBranchesIn(repo, opts.GitOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RemoteSnapshot{
+		Snapshot: FromSnapshotBranches(branches),
+		Branches: branches,
+	}
+	if commit, err := resolveCommitIn(repo, "HEAD"); err == nil {
+		if result.Head, err = revisionWithOptions(repo, commit, opts.GitOptions); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
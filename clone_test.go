@@ -0,0 +1,51 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestCloneAndSnapshot(t *testing.T) {
+	repoPath, repo, hash := newTestRepo(t)
+	if err := repo.Storer.SetReference(plumbing.NewHashReference("refs/notes/commits", hash)); err != nil {
+		t.Fatalf("Failed to set reference: %v", err)
+	}
+
+	want, err := FromSnapshot(repoPath)
+	if err != nil {
+		t.Fatalf("FromSnapshot() error = %v", err)
+	}
+
+	for _, opts := range []CloneOptions{
+		{},
+		{Depth: 1, InMemory: true},
+	} {
+		got, err := CloneAndSnapshot(context.Background(), repoPath, opts)
+		if err != nil {
+			t.Fatalf("CloneAndSnapshot(%+v) error = %v", opts, err)
+		}
+		if !got.Snapshot.Equal(want) {
+			t.Errorf("CloneAndSnapshot(%+v) snapshot = %v, want %v", opts, got.Snapshot, want)
+			for _, b := range got.Branches {
+				t.Logf("  %s %s %s", b.Name, b.TargetType, b.Target)
+			}
+		}
+		if got.Head == nil || got.Head.ObjectHash != hash.String() {
+			t.Errorf("CloneAndSnapshot(%+v) head = %v, want %v", opts, got.Head, hash)
+		}
+	}
+}
+
+func TestCloneAndSnapshotCanceled(t *testing.T) {
+	repoPath, _, _ := newTestRepo(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := CloneAndSnapshot(ctx, repoPath, CloneOptions{InMemory: true}); err == nil {
+		t.Error("CloneAndSnapshot() expected error for canceled context")
+	}
+}
@@ -0,0 +1,19 @@
+// Command swhid-action is the entry point of the GitHub Action in the
+// action directory: it computes the SWHIDs of the workflow's workspace and
+// publishes them as step outputs and a job summary. See package action.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/andrew/swhid-go/action"
+)
+
+func main() {
+	if err := action.Run(context.Background(), os.Getenv, os.Stdout); err != nil {
+		fmt.Printf("::error::%v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,189 @@
+//go:build js && wasm
+
+// Command swhid-wasm exposes SWHID parsing and content and directory
+// hashing to JavaScript, so web pages can compute SWHIDs of files a user
+// picks without uploading them. Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o swhid.wasm ./cmd/swhid-wasm
+//
+// and load it with the wasm_exec.js shipped in $(go env GOROOT)/lib/wasm.
+// It defines a global swhid object:
+//
+//	swhid.parse(string) → {swhid, objectType, hash, qualifiers} or {error, suggestions}
+//	swhid.fromContent(Uint8Array) → {swhid} or {error}
+//	swhid.fromFiles([{path, data, executable}]) → {swhid} or {error}
+//
+// fromFiles hashes the directory holding the given files, with paths
+// slash-separated and relative to that directory, as in the
+// webkitRelativePath of a picked folder once its first element is removed.
+// Empty directories cannot be picked, so they never appear in the tree,
+// which is also how Git stores them.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"syscall/js"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/objects"
+)
+
+func main() {
+	js.Global().Set("swhid", js.ValueOf(map[string]any{
+		"parse":       js.FuncOf(parse),
+		"fromContent": js.FuncOf(fromContent),
+		"fromFiles":   js.FuncOf(fromFiles),
+	}))
+	select {}
+}
+
+func parse(this js.Value, args []js.Value) any {
+	if len(args) != 1 || args[0].Type() != js.TypeString {
+		return errorResult(errors.New("parse takes a string"))
+	}
+
+	id, err := swhid.Parse(args[0].String())
+	if err != nil {
+		result := errorResult(err)
+		var parseErr *swhid.ParseError
+		if errors.As(err, &parseErr) {
+			suggestions := make([]any, len(parseErr.Suggestions))
+			for i, s := range parseErr.Suggestions {
+				suggestions[i] = s
+			}
+			result["suggestions"] = suggestions
+		}
+		return result
+	}
+
+	qualifiers := make(map[string]any, len(id.Qualifiers))
+	for k, v := range id.Qualifiers {
+		qualifiers[k] = v
+	}
+	return map[string]any{
+		"swhid":      id.String(),
+		"objectType": string(id.ObjectType),
+		"hash":       id.ObjectHash,
+		"qualifiers": qualifiers,
+	}
+}
+
+func fromContent(this js.Value, args []js.Value) any {
+	if len(args) != 1 {
+		return errorResult(errors.New("fromContent takes a Uint8Array"))
+	}
+	data, err := bytesOf(args[0])
+	if err != nil {
+		return errorResult(err)
+	}
+	return map[string]any{"swhid": swhid.FromContent(data).String()}
+}
+
+func fromFiles(this js.Value, args []js.Value) any {
+	if len(args) != 1 || args[0].Type() != js.TypeObject {
+		return errorResult(errors.New("fromFiles takes an array of {path, data, executable}"))
+	}
+
+	root := &dir{}
+	files := args[0]
+	for i := 0; i < files.Get("length").Int(); i++ {
+		file := files.Index(i)
+		name := file.Get("path").String()
+		clean := path.Clean(name)
+		if clean == "." || clean == ".." || strings.HasPrefix(clean, "../") || strings.HasPrefix(clean, "/") {
+			return errorResult(fmt.Errorf("invalid path %q", name))
+		}
+
+		entryType := objects.EntryTypeFile
+		if file.Get("executable").Truthy() {
+			entryType = objects.EntryTypeExecutable
+		}
+		data, err := bytesOf(file.Get("data"))
+		if err != nil {
+			return errorResult(fmt.Errorf("%s: %w", name, err))
+		}
+		if err := root.add(strings.Split(clean, "/"), entryType, data); err != nil {
+			return errorResult(fmt.Errorf("%s: %w", name, err))
+		}
+	}
+
+	id, err := root.hash()
+	if err != nil {
+		return errorResult(err)
+	}
+	return map[string]any{"swhid": id.String()}
+}
+
+// dir is a directory assembled from the paths of picked files.
+type dir struct {
+	files map[string]objects.DirectoryEntry
+	dirs  map[string]*dir
+}
+
+func (d *dir) add(parts []string, entryType objects.EntryType, data []byte) error {
+	name := parts[0]
+	if name == ".git" {
+		return nil
+	}
+
+	if len(parts) == 1 {
+		if _, ok := d.dirs[name]; ok {
+			return errors.New("both a file and a directory")
+		}
+		if d.files == nil {
+			d.files = make(map[string]objects.DirectoryEntry)
+		}
+		d.files[name] = objects.DirectoryEntry{
+			Name:   name,
+			Type:   entryType,
+			Target: swhid.FromContent(data).ObjectHash,
+			Size:   int64(len(data)),
+		}
+		return nil
+	}
+
+	if _, ok := d.files[name]; ok {
+		return errors.New("both a file and a directory")
+	}
+	sub, ok := d.dirs[name]
+	if !ok {
+		if d.dirs == nil {
+			d.dirs = make(map[string]*dir)
+		}
+		sub = &dir{}
+		d.dirs[name] = sub
+	}
+	return sub.add(parts[1:], entryType, data)
+}
+
+func (d *dir) hash() (*swhid.Identifier, error) {
+	entries := make([]objects.DirectoryEntry, 0, len(d.files)+len(d.dirs))
+	for _, entry := range d.files {
+		entries = append(entries, entry)
+	}
+	for name, sub := range d.dirs {
+		id, err := sub.hash()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, objects.DirectoryEntry{Name: name, Type: objects.EntryTypeDirectory, Target: id.ObjectHash})
+	}
+	return swhid.FromDirectoryStrict(entries)
+}
+
+// bytesOf copies a Uint8Array into Go memory.
+func bytesOf(v js.Value) ([]byte, error) {
+	if !v.InstanceOf(js.Global().Get("Uint8Array")) {
+		return nil, errors.New("data is not a Uint8Array")
+	}
+	data := make([]byte, v.Get("length").Int())
+	js.CopyBytesToGo(data, v)
+	return data, nil
+}
+
+func errorResult(err error) map[string]any {
+	return map[string]any{"error": err.Error()}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/andrew/swhid-go"
+)
+
+// runAnalyzeContent prints the content SWHID of data with its encoding
+// and line endings, followed by the SWHID after each normalization that
+// changes it.
+func runAnalyzeContent(data []byte) error {
+	a := swhid.AnalyzeContent(data)
+	id := applyQualifiers(a.SWHID)
+
+	if formatFlag == "json" {
+		variants := make(map[string]string, len(a.Variants))
+		for _, v := range a.Variants {
+			variants[v.Normalization] = v.SWHID.String()
+		}
+		return writeJSON(map[string]interface{}{
+			"swhid":         id.String(),
+			"size":          a.Size,
+			"encoding":      a.Encoding,
+			"bom":           a.BOM,
+			"eol":           a.EOL,
+			"lf":            a.LF,
+			"crlf":          a.CRLF,
+			"cr":            a.CR,
+			"final_newline": a.FinalNewline,
+			"variants":      variants,
+		})
+	}
+
+	fmt.Println(id)
+	fmt.Printf("  size:          %d bytes\n", a.Size)
+	fmt.Printf("  encoding:      %s\n", a.Encoding)
+	if a.Encoding == swhid.EncodingBinary {
+		return nil
+	}
+	fmt.Printf("  bom:           %v\n", a.BOM)
+	fmt.Printf("  line endings:  %s (%d LF, %d CRLF, %d CR)\n", a.EOL, a.LF, a.CRLF, a.CR)
+	fmt.Printf("  final newline: %v\n", a.FinalNewline)
+	if len(a.Variants) == 0 {
+		fmt.Println("No normalization changes this content.")
+		return nil
+	}
+	fmt.Println("After normalizing:")
+	for _, v := range a.Variants {
+		fmt.Printf("  %-14s %s\n", v.Normalization, v.SWHID)
+	}
+	return nil
+}
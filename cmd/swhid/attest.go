@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/attest"
+)
+
+// runAttest writes an in-toto statement binding paths to their SWHIDs.
+// With --sign it is signed into a Sigstore bundle, with --key or keyless
+// through Fulcio and Rekor.
+func runAttest(args []string) error {
+	if len(args) > 0 && args[0] == "verify" {
+		return runAttestVerify(args[1:])
+	}
+	if len(args) < 1 {
+		return fmt.Errorf("path required")
+	}
+
+	var subjects []attest.Subject
+	for _, path := range args {
+		id, err := pathSWHID(path)
+		if err != nil {
+			return err
+		}
+		subjects = append(subjects, attest.Subject{Name: path, SWHID: applyQualifiers(id)})
+	}
+	st := attest.NewStatement(cliVersion(), subjects...)
+
+	var out interface{} = st
+	if signFlag {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		bundle, err := signStatement(ctx, st)
+		if err != nil {
+			return err
+		}
+		out = bundle
+	}
+	return writeDocument(out)
+}
+
+// signStatement signs st with the --key private key, or keyless with a
+// Fulcio certificate for the identity token, recorded in Rekor.
+func signStatement(ctx context.Context, st *attest.Statement) (*attest.Bundle, error) {
+	if keyFlag != "" {
+		data, err := os.ReadFile(keyFlag)
+		if err != nil {
+			return nil, err
+		}
+		key, err := attest.LoadPrivateKey(data, []byte(os.Getenv("COSIGN_PASSWORD")))
+		if err != nil {
+			return nil, err
+		}
+		env, err := attest.SignStatement(st, &attest.KeySigner{Key: key})
+		if err != nil {
+			return nil, err
+		}
+		return &attest.Bundle{Envelope: env}, nil
+	}
+
+	token := identityTokenFlag
+	if token == "" {
+		token = os.Getenv("SIGSTORE_ID_TOKEN")
+	}
+	if token == "" {
+		return nil, fmt.Errorf("keyless signing needs an OIDC token (--identity-token or SIGSTORE_ID_TOKEN); use --key to sign with a key file")
+	}
+
+	signer, err := (&attest.Fulcio{URL: fulcioURLFlag}).Signer(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	env, err := attest.SignStatement(st, signer)
+	if err != nil {
+		return nil, err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: signer.Chain[0].Raw})
+	entry, err := (&attest.Rekor{URL: rekorURLFlag}).Upload(ctx, env, certPEM)
+	if err != nil {
+		return nil, err
+	}
+	return &attest.Bundle{Envelope: env, Certificate: signer.Chain[0], TlogEntries: []attest.TlogEntry{*entry}}, nil
+}
+
+// runAttestVerify checks a bundle's signature and, given paths, that they
+// still have the SWHIDs it attests.
+func runAttestVerify(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("bundle file required")
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	var bundle attest.Bundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+
+	opts, err := bundleOptions()
+	if err != nil {
+		return err
+	}
+	st, err := bundle.Verify(opts)
+	if err != nil {
+		return err
+	}
+
+	attested := make(map[string]*swhid.Identifier)
+	for _, s := range st.Subjects {
+		attested[s.Name] = s.SWHID
+	}
+	for _, path := range args[1:] {
+		want, ok := attested[path]
+		if !ok {
+			return fmt.Errorf("%s is not a subject of the attestation", path)
+		}
+		got, err := pathSWHID(path)
+		if err != nil {
+			return err
+		}
+		if got.CoreSWHID() != want.CoreSWHID() {
+			return fmt.Errorf("%s: SWHID mismatch: attested %s, computed %s", path, want.CoreSWHID(), got.CoreSWHID())
+		}
+	}
+
+	if formatFlag == "json" {
+		var subjects []map[string]interface{}
+		for _, s := range st.Subjects {
+			subjects = append(subjects, map[string]interface{}{"name": s.Name, "swhid": s.SWHID.String()})
+		}
+		result := map[string]interface{}{"verified": true, "subjects": subjects}
+		if bundle.Certificate != nil {
+			result["identity"] = opts.Identity
+		}
+		return writeJSON(result)
+	}
+	for _, s := range st.Subjects {
+		fmt.Printf("%s  %s\n", s.SWHID, s.Name)
+	}
+	fmt.Fprintln(os.Stderr, "Verified OK")
+	return nil
+}
+
+// bundleOptions returns whom to trust from --key, or from
+// --certificate-chain, --certificate-identity, --certificate-oidc-issuer
+// and --rekor-key.
+func bundleOptions() (attest.BundleOptions, error) {
+	var opts attest.BundleOptions
+	if keyFlag != "" {
+		data, err := os.ReadFile(keyFlag)
+		if err != nil {
+			return opts, err
+		}
+		opts.Key, err = attest.LoadPublicKey(data)
+		if err != nil {
+			return opts, err
+		}
+	}
+
+	if certChainFlag != "" {
+		data, err := os.ReadFile(certChainFlag)
+		if err != nil {
+			return opts, err
+		}
+		opts.Roots, opts.Intermediates = x509.NewCertPool(), x509.NewCertPool()
+		for len(data) > 0 {
+			var block *pem.Block
+			block, data = pem.Decode(data)
+			if block == nil {
+				break
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return opts, fmt.Errorf("%s: %w", certChainFlag, err)
+			}
+			if cert.CheckSignatureFrom(cert) == nil {
+				opts.Roots.AddCert(cert)
+			} else {
+				opts.Intermediates.AddCert(cert)
+			}
+		}
+	}
+
+	if rekorKeyFlag != "" {
+		data, err := os.ReadFile(rekorKeyFlag)
+		if err != nil {
+			return opts, err
+		}
+		opts.RekorKey, err = attest.LoadPublicKey(data)
+		if err != nil {
+			return opts, err
+		}
+	}
+
+	opts.Identity = certIdentityFlag
+	opts.Issuer = certIssuerFlag
+	return opts, nil
+}
+
+// pathSWHID returns the SWHID of a regular file or directory.
+func pathSWHID(path string) (*swhid.Identifier, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		node, err := swhid.TreeFromDirectoryPathWithOptions(path, treeOptions())
+		if err != nil {
+			return nil, err
+		}
+		return node.ID, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return swhid.FromContent(data), nil
+}
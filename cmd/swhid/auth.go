@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/zalando/go-keyring"
+)
+
+const (
+	keyringService = "swhid"
+	keyringUser    = "api-token"
+)
+
+// apiToken returns the SWH API token and where it came from. An explicit
+// token from the environment or config file wins over the OS keyring.
+func apiToken() (token, source string) {
+	if cfg.APIToken != "" {
+		if os.Getenv("SWHID_API_TOKEN") != "" {
+			return cfg.APIToken, "environment"
+		}
+		return cfg.APIToken, "config file"
+	}
+
+	token, err := keyring.Get(keyringService, keyringUser)
+	if err != nil {
+		return "", ""
+	}
+	return token, "keyring"
+}
+
+func runAuth(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("auth subcommand required (login, status, logout)")
+	}
+
+	switch args[0] {
+	case "login":
+		return runAuthLogin(args[1:])
+	case "status":
+		return runAuthStatus()
+	case "logout":
+		return runAuthLogout()
+	default:
+		return fmt.Errorf("unknown auth subcommand: %s", args[0])
+	}
+}
+
+func runAuthLogin(args []string) error {
+	var token string
+	if len(args) > 0 {
+		token = args[0]
+	} else {
+		fmt.Fprint(os.Stderr, "Paste your Software Heritage API token: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("failed to read token: %w", err)
+		}
+		token = line
+	}
+
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return fmt.Errorf("token cannot be empty")
+	}
+
+	if err := keyring.Set(keyringService, keyringUser, token); err != nil {
+		return fmt.Errorf("failed to store token in keyring: %w", err)
+	}
+
+	if formatFlag == "json" {
+		return writeJSON(map[string]interface{}{"stored": true})
+	}
+	fmt.Println("Token stored in the system keyring")
+	return nil
+}
+
+func runAuthStatus() error {
+	token, source := apiToken()
+	if formatFlag == "json" {
+		doc := map[string]interface{}{"logged_in": token != ""}
+		if token != "" {
+			doc["source"] = source
+			doc["token"] = maskToken(token)
+		}
+		return writeJSON(doc)
+	}
+	if token == "" {
+		fmt.Println("Not logged in")
+		return nil
+	}
+
+	fmt.Printf("Logged in (token from %s: %s)\n", source, maskToken(token))
+	return nil
+}
+
+func runAuthLogout() error {
+	err := keyring.Delete(keyringService, keyringUser)
+	if errors.Is(err, keyring.ErrNotFound) {
+		if formatFlag == "json" {
+			return writeJSON(map[string]interface{}{"removed": false})
+		}
+		fmt.Println("No token stored in the system keyring")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove token from keyring: %w", err)
+	}
+
+	if formatFlag == "json" {
+		return writeJSON(map[string]interface{}{"removed": true})
+	}
+	fmt.Println("Token removed from the system keyring")
+	return nil
+}
+
+func maskToken(token string) string {
+	if len(token) <= 8 {
+		return strings.Repeat("*", len(token))
+	}
+	return token[:4] + strings.Repeat("*", len(token)-8) + token[len(token)-4:]
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+func TestAPITokenSource(t *testing.T) {
+	keyring.MockInit()
+	if err := keyring.Set(keyringService, keyringUser, "keyring-token"); err != nil {
+		t.Fatal(err)
+	}
+
+	// loadConfig puts SWHID_API_TOKEN, when set, in place of the file's
+	tests := []struct {
+		env, configured string
+		token, source   string
+	}{
+		{"", "", "keyring-token", "keyring"},
+		{"", "file-token", "file-token", "config file"},
+		{"env-token", "env-token", "env-token", "environment"},
+	}
+	for _, tt := range tests {
+		t.Setenv("SWHID_API_TOKEN", tt.env)
+		cfg = &config{APIToken: tt.configured}
+		if token, source := apiToken(); token != tt.token || source != tt.source {
+			t.Errorf("apiToken() with %q configured = %q, %q; want %q, %q", tt.configured, token, source, tt.token, tt.source)
+		}
+	}
+
+	cfg = &config{}
+	keyring.Delete(keyringService, keyringUser)
+	if token, src := apiToken(); token != "" || src != "" {
+		t.Errorf("apiToken() with no token = %q, %q", token, src)
+	}
+}
+
+func TestAuthCommands(t *testing.T) {
+	keyring.MockInit()
+	clearConfigEnv(t)
+
+	status := func() map[string]interface{} {
+		t.Helper()
+		out, err := runCommand(t, "auth", "status", "--json")
+		if err != nil {
+			t.Fatalf("auth status error = %v", err)
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal([]byte(out), &doc); err != nil {
+			t.Fatalf("auth status wrote %q: %v", out, err)
+		}
+		return doc
+	}
+
+	if doc := status(); doc["logged_in"] != false {
+		t.Errorf("auth status before login = %v", doc)
+	}
+	if _, err := runCommand(t, "auth", "login", "  abcdefghijklmnop\n"); err != nil {
+		t.Fatalf("auth login error = %v", err)
+	}
+	if token, _ := keyring.Get(keyringService, keyringUser); token != "abcdefghijklmnop" {
+		t.Errorf("auth login stored %q", token)
+	}
+	if doc := status(); doc["logged_in"] != true || doc["source"] != "keyring" || doc["token"] != "abcd********mnop" {
+		t.Errorf("auth status after login = %v", doc)
+	}
+
+	if _, err := runCommand(t, "auth", "login", " "); err == nil {
+		t.Error("auth login stored an empty token")
+	}
+
+	out, err := runCommand(t, "auth", "logout", "--json")
+	if err != nil || out != "{\"removed\":true,\"schema\":\"swhid-cli/1\"}\n" {
+		t.Errorf("auth logout = %q, %v", out, err)
+	}
+	out, err = runCommand(t, "auth", "logout", "--json")
+	if err != nil || out != "{\"removed\":false,\"schema\":\"swhid-cli/1\"}\n" {
+		t.Errorf("second auth logout = %q, %v", out, err)
+	}
+	if doc := status(); doc["logged_in"] != false {
+		t.Errorf("auth status after logout = %v", doc)
+	}
+}
+
+func TestMaskToken(t *testing.T) {
+	for token, want := range map[string]string{
+		"":                 "",
+		"short":            "*****",
+		"12345678":         "********",
+		"abcdefghijklmnop": "abcd********mnop",
+	} {
+		if got := maskToken(token); got != want {
+			t.Errorf("maskToken(%q) = %q, want %q", token, got, want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/andrew/swhid-go"
+)
+
+// runBatchSnapshot snapshots every repository listed in a file, one path
+// per line, --jobs at a time. Blank lines and lines starting with # are
+// skipped, and "-" reads the list from stdin. Failures are reported per
+// repository and make the command fail once all are done.
+func runBatchSnapshot(listPath string) error {
+	paths, err := readPathList(listPath)
+	if err != nil {
+		return err
+	}
+	opts, err := gitOptions()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	batchOpts := swhid.BatchOptions{GitOptions: opts, Concurrency: jobsFlag}
+	if progressFlag {
+		batchOpts.Progress = func(p swhid.BatchProgress) {
+			fmt.Fprintf(os.Stderr, "\rSnapshotted %d/%d repositories (%d failed)", p.Done, p.Total, p.Failed)
+			if p.Done == p.Total {
+				fmt.Fprintln(os.Stderr)
+			}
+		}
+	}
+	results := swhid.SnapshotMany(ctx, paths, batchOpts)
+
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+
+	if formatFlag == "json" {
+		list := make([]map[string]interface{}, len(results))
+		for i, r := range results {
+			list[i] = map[string]interface{}{
+				"path":        r.Path,
+				"duration_ms": r.Duration.Milliseconds(),
+			}
+			if r.Err != nil {
+				list[i]["error"] = r.Err.Error()
+			} else {
+				list[i]["swhid"] = r.Snapshot.String()
+			}
+		}
+		if err := writeJSON(map[string]interface{}{
+			"total":   len(results),
+			"failed":  failed,
+			"results": list,
+		}); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range results {
+			if r.Err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", r.Path, r.Err)
+				continue
+			}
+			fmt.Printf("%s  %s\n", r.Snapshot, r.Path)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d repositories failed", failed, len(results))
+	}
+	return nil
+}
+
+// readPathList reads one path per line from a file, or stdin for "-".
+func readPathList(listPath string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if listPath != "-" {
+		f, err := os.Open(listPath)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var paths []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		paths = append(paths, line)
+	}
+	return paths, scanner.Err()
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/archive"
+)
+
+// runCite looks up the archive metadata and DOIs recorded for a SWHID, or
+// with a DOI argument the archived snapshots of the origins citing it.
+func runCite(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("SWHID or DOI required")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	token, _ := apiToken()
+	client := &archive.Client{Token: token}
+
+	if !strings.HasPrefix(args[0], swhid.Scheme+":") {
+		ids, err := client.FindDOI(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return fmt.Errorf("no archived origin mentions %s", archive.NormalizeDOI(args[0]))
+		}
+		if formatFlag == "json" {
+			list := make([]string, len(ids))
+			for i, id := range ids {
+				list[i] = id.String()
+			}
+			return writeJSON(map[string]interface{}{
+				"doi":    archive.NormalizeDOI(args[0]),
+				"swhids": list,
+			})
+		}
+		for _, id := range ids {
+			fmt.Println(id)
+		}
+		return nil
+	}
+
+	id, err := swhid.Parse(args[0])
+	if err != nil {
+		return err
+	}
+	citation, err := client.Citation(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if formatFlag == "json" {
+		records := make([]map[string]interface{}, len(citation.Metadata))
+		for i, m := range citation.Metadata {
+			records[i] = map[string]interface{}{
+				"target":         m.Target,
+				"authority":      m.Authority,
+				"fetcher":        m.Fetcher,
+				"format":         m.Format,
+				"discovery_date": m.DiscoveryDate.Format(time.RFC3339),
+				"origin":         m.Origin,
+				"content":        string(m.Content),
+			}
+		}
+		return writeJSON(map[string]interface{}{
+			"swhid":    citation.SWHID.String(),
+			"origins":  nonNil(citation.Origins),
+			"dois":     nonNil(citation.DOIs),
+			"metadata": records,
+		})
+	}
+
+	fmt.Printf("SWHID:   %s\n", citation.SWHID)
+	for _, origin := range citation.Origins {
+		fmt.Printf("Origin:  %s\n", origin)
+	}
+	for _, doi := range citation.DOIs {
+		fmt.Printf("DOI:     https://doi.org/%s\n", doi)
+	}
+	for _, m := range citation.Metadata {
+		fmt.Printf("Metadata: %s from %s (%s), %s\n", m.Format, m.Authority.URL, m.Fetcher, m.DiscoveryDate.Format("2006-01-02"))
+	}
+	if len(citation.Metadata) == 0 {
+		fmt.Println("No metadata recorded in the archive")
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// config holds CLI defaults read from the config file and environment.
+type config struct {
+	APIToken    string     `toml:"api_token"`
+	Origin      string     `toml:"origin"`
+	Exclude     []string   `toml:"exclude"`
+	Format      string     `toml:"format"`
+	Concurrency int        `toml:"concurrency"`
+	Hooks       hookConfig `toml:"hooks"`
+}
+
+// hookConfig controls the Git hooks written by "swhid hook install".
+type hookConfig struct {
+	Trailer      string `toml:"trailer"`       // commit message trailer key
+	ReleasesFile string `toml:"releases_file"` // tag-to-SWHID records checked before push
+}
+
+// configPath returns the location of the config file. SWHID_CONFIG takes
+// precedence, then $XDG_CONFIG_HOME/swhid/config.toml, then
+// ~/.config/swhid/config.toml.
+func configPath() string {
+	if p := os.Getenv("SWHID_CONFIG"); p != "" {
+		return p
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "swhid", "config.toml")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "swhid", "config.toml")
+}
+
+// loadConfig reads the config file, if any, and applies environment
+// variable overrides. A missing config file is not an error.
+func loadConfig() (*config, error) {
+	cfg := &config{
+		Format: "text",
+		Hooks: hookConfig{
+			Trailer:      "Source-SWHID",
+			ReleasesFile: ".swhid-releases",
+		},
+	}
+
+	if path := configPath(); path != "" {
+		if _, err := toml.DecodeFile(path, cfg); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+		}
+	}
+
+	if v := os.Getenv("SWHID_API_TOKEN"); v != "" {
+		cfg.APIToken = v
+	}
+	if v := os.Getenv("SWHID_ORIGIN"); v != "" {
+		cfg.Origin = v
+	}
+	if v := os.Getenv("SWHID_EXCLUDE"); v != "" {
+		cfg.Exclude = strings.Split(v, ",")
+	}
+	if v := os.Getenv("SWHID_FORMAT"); v != "" {
+		cfg.Format = v
+	}
+	if v := os.Getenv("SWHID_CONCURRENCY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SWHID_CONCURRENCY: %s", v)
+		}
+		cfg.Concurrency = n
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// clearConfigEnv unsets the environment variables loadConfig reads, so
+// that the caller's environment does not leak into a test.
+func clearConfigEnv(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{"SWHID_API_TOKEN", "SWHID_ORIGIN", "SWHID_EXCLUDE", "SWHID_FORMAT", "SWHID_CONCURRENCY"} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("SWHID_CONFIG", filepath.Join(t.TempDir(), "missing.toml"))
+
+	got, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	want := &config{Format: "text", Hooks: hookConfig{Trailer: "Source-SWHID", ReleasesFile: ".swhid-releases"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loadConfig() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	clearConfigEnv(t)
+	path := filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(path, []byte(`api_token = "file-token"
+origin = "https://github.com/example/repo"
+exclude = ["node_modules", "*.log"]
+format = "json"
+concurrency = 8
+
+[hooks]
+trailer = "Tree-SWHID"
+`), 0644)
+	t.Setenv("SWHID_CONFIG", path)
+
+	got, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	want := &config{
+		APIToken:    "file-token",
+		Origin:      "https://github.com/example/repo",
+		Exclude:     []string{"node_modules", "*.log"},
+		Format:      "json",
+		Concurrency: 8,
+		Hooks:       hookConfig{Trailer: "Tree-SWHID", ReleasesFile: ".swhid-releases"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loadConfig() = %+v, want %+v", got, want)
+	}
+
+	// The environment overrides the file
+	t.Setenv("SWHID_API_TOKEN", "env-token")
+	t.Setenv("SWHID_ORIGIN", "https://example.com/other")
+	t.Setenv("SWHID_EXCLUDE", "vendor,*.tmp")
+	t.Setenv("SWHID_FORMAT", "text")
+	t.Setenv("SWHID_CONCURRENCY", "2")
+	got, err = loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	want.APIToken, want.Origin, want.Format, want.Concurrency = "env-token", "https://example.com/other", "text", 2
+	want.Exclude = []string{"vendor", "*.tmp"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loadConfig() with environment = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadConfigXDG(t *testing.T) {
+	clearConfigEnv(t)
+	dir := t.TempDir()
+	t.Setenv("SWHID_CONFIG", "")
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	os.MkdirAll(filepath.Join(dir, "swhid"), 0755)
+	os.WriteFile(filepath.Join(dir, "swhid", "config.toml"), []byte("origin = \"https://example.com/xdg\"\n"), 0644)
+
+	got, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if got.Origin != "https://example.com/xdg" {
+		t.Errorf("loadConfig() origin = %q, want the one in $XDG_CONFIG_HOME", got.Origin)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	clearConfigEnv(t)
+	path := filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(path, []byte("concurrency = \"many\"\n"), 0644)
+	t.Setenv("SWHID_CONFIG", path)
+	if _, err := loadConfig(); err == nil {
+		t.Error("loadConfig() accepted a malformed config file")
+	}
+
+	t.Setenv("SWHID_CONFIG", filepath.Join(t.TempDir(), "missing.toml"))
+	t.Setenv("SWHID_CONCURRENCY", "many")
+	if _, err := loadConfig(); err == nil {
+		t.Error("loadConfig() accepted SWHID_CONCURRENCY=many")
+	}
+}
+
+func TestConcurrencyIsJobsDefault(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("SWHID_CONCURRENCY", "3")
+
+	if _, err := runCommand(t, "parse", helloSWHID); err != nil {
+		t.Fatalf("parse error = %v", err)
+	}
+	if jobsFlag != 3 {
+		t.Errorf("--jobs default = %d, want SWHID_CONCURRENCY 3", jobsFlag)
+	}
+	if _, err := runCommand(t, "parse", helloSWHID, "--jobs", "5"); err != nil {
+		t.Fatalf("parse error = %v", err)
+	}
+	if jobsFlag != 5 {
+		t.Errorf("--jobs = %d, want 5 over SWHID_CONCURRENCY", jobsFlag)
+	}
+}
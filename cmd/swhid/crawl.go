@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/archive"
+	"github.com/andrew/swhid-go/forge"
+)
+
+// runCrawl identifies every repository of a GitHub organization or user or
+// a GitLab group: with --archive from the archive's latest visit where
+// there is one, otherwise from a shallow clone. Requests to the forge and
+// to the archive are each paced by their own rate limit. Like --batch,
+// failures are reported per repository and fail the command at the end.
+func runCrawl(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("forge (github or gitlab) and namespace required")
+	}
+
+	forgeLimit := &forge.RateLimit{Interval: intervalFlag}
+	forgeClient := &http.Client{Transport: forgeLimit}
+	var lister forge.Lister
+	switch args[0] {
+	case "github":
+		lister = &forge.GitHub{BaseURL: forgeURLFlag, Token: os.Getenv("GITHUB_TOKEN"), HTTPClient: forgeClient}
+	case "gitlab":
+		lister = &forge.GitLab{BaseURL: forgeURLFlag, Token: os.Getenv("GITLAB_TOKEN"), HTTPClient: forgeClient}
+	default:
+		return fmt.Errorf("unknown forge %q (expected github or gitlab)", args[0])
+	}
+
+	opts, err := gitOptions()
+	if err != nil {
+		return err
+	}
+	crawlOpts := forge.CrawlOptions{
+		NoClone:      noCloneFlag,
+		Clone:        swhid.CloneOptions{GitOptions: opts, Depth: depthFlag},
+		SkipForks:    skipForksFlag,
+		SkipArchived: skipArchivedFlag,
+		Concurrency:  jobsFlag,
+	}
+	if archiveFlag {
+		token, _ := apiToken()
+		crawlOpts.Archive = &archive.Client{
+			Token:      token,
+			HTTPClient: &http.Client{Transport: &forge.RateLimit{Interval: intervalFlag}},
+		}
+	}
+	if progressFlag {
+		done := 0
+		crawlOpts.Progress = func(r forge.CrawlResult) {
+			done++
+			fmt.Fprintf(os.Stderr, "\rIdentified %d repositories", done)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	results, err := forge.Crawl(ctx, lister, args[1], crawlOpts)
+	if progressFlag {
+		fmt.Fprintln(os.Stderr)
+	}
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+
+	if formatFlag == "json" {
+		list := make([]map[string]interface{}, len(results))
+		for i, r := range results {
+			list[i] = map[string]interface{}{
+				"name":        r.Name,
+				"origin":      r.Origin,
+				"fork":        r.Fork,
+				"archived":    r.Archived,
+				"duration_ms": r.Duration.Milliseconds(),
+			}
+			if r.Err != nil {
+				list[i]["error"] = r.Err.Error()
+				continue
+			}
+			list[i]["swhid"] = r.Snapshot.String()
+			list[i]["source"] = r.Source
+			if r.Head != nil {
+				list[i]["head"] = r.Head.String()
+			}
+		}
+		if err := writeJSON(map[string]interface{}{
+			"forge":     args[0],
+			"namespace": args[1],
+			"total":     len(results),
+			"failed":    failed,
+			"results":   list,
+		}); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range results {
+			if r.Err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", r.Name, r.Err)
+				continue
+			}
+			fmt.Printf("%s  %-7s  %s\n", r.Snapshot.CoreSWHID(), r.Source, r.Origin)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d repositories failed", failed, len(results))
+	}
+	return nil
+}
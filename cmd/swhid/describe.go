@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/andrew/swhid-go"
+)
+
+// runDescribe prints every SWHID that applies to a path: the content or
+// directory, and inside a repository the HEAD revision, origin, snapshot
+// and the fully qualified form of a committed path.
+func runDescribe(args []string) error {
+	path := "."
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	d, err := swhid.Describe(ctx, path, treeOptions())
+	if err != nil {
+		return err
+	}
+	if d.Qualified != nil {
+		d.Qualified = applyQualifiers(d.Qualified)
+	}
+
+	fields := []struct {
+		key, label string
+		id         *swhid.Identifier
+	}{
+		{"content", "Content:  ", d.Content},
+		{"directory", "Directory:", d.Directory},
+		{"revision", "Revision: ", d.Revision},
+		{"snapshot", "Snapshot: ", d.Snapshot},
+		{"qualified", "Qualified:", d.Qualified},
+	}
+
+	// With --also-version, each SWHID is followed by its equivalent in
+	// that version, or a note that it cannot be derived.
+	also := func(id *swhid.Identifier) (string, bool) {
+		if alsoVersionFlag == 0 || alsoVersionFlag == id.Version {
+			return "", false
+		}
+		other, err := id.As(alsoVersionFlag)
+		if err != nil {
+			return "", true
+		}
+		return other.String(), true
+	}
+
+	if formatFlag == "json" {
+		doc := map[string]interface{}{"path": d.Path, "kind": d.Kind}
+		versions := map[string]string{}
+		for _, f := range fields {
+			if f.id != nil {
+				doc[f.key] = f.id.String()
+				if other, ok := also(f.id); ok && other != "" {
+					versions[f.key] = other
+				}
+			}
+		}
+		if d.Origin != "" {
+			doc["origin"] = d.Origin
+		}
+		if alsoVersionFlag != 0 {
+			doc[fmt.Sprintf("swh:%d", alsoVersionFlag)] = versions
+		}
+		return writeJSON(doc)
+	}
+
+	fmt.Printf("Path:      %s\n", d.Path)
+	fmt.Printf("Kind:      %s\n", d.Kind)
+	for _, f := range fields {
+		if f.id == nil {
+			continue
+		}
+		fmt.Printf("%s %s\n", f.label, f.id)
+		if other, ok := also(f.id); ok {
+			if other == "" {
+				other = fmt.Sprintf("(cannot be derived in swh:%d)", alsoVersionFlag)
+			}
+			fmt.Printf("           %s\n", other)
+		}
+	}
+	if d.Origin != "" {
+		fmt.Printf("Origin:    %s\n", d.Origin)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/andrew/swhid-go"
+)
+
+// maxDoctorPaths bounds the paths listed per finding in text output.
+const maxDoctorPaths = 10
+
+// runDoctor explains why the directory SWHID of a path may differ from the
+// archive's. With a SWHID argument it also says which of the directory on
+// disk and the committed tree that SWHID matches.
+func runDoctor(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("directory path required")
+	}
+
+	var expected *swhid.Identifier
+	if len(args) > 1 {
+		var err error
+		if expected, err = swhid.Parse(args[1]); err != nil {
+			return err
+		}
+	}
+
+	diagnosis, err := swhid.Diagnose(args[0], treeOptions())
+	if err != nil {
+		return err
+	}
+
+	if formatFlag == "json" {
+		findings := make([]map[string]interface{}, len(diagnosis.Findings))
+		for i, f := range diagnosis.Findings {
+			findings[i] = map[string]interface{}{
+				"check":   f.Check,
+				"summary": f.Summary,
+				"advice":  f.Advice,
+				"paths":   nonNil(f.Paths),
+			}
+		}
+		report := map[string]interface{}{
+			"path":      args[0],
+			"directory": diagnosis.Directory.String(),
+			"committed": nil,
+			"findings":  findings,
+		}
+		if diagnosis.Committed != nil {
+			report["committed"] = diagnosis.Committed.String()
+		}
+		if expected != nil {
+			report["expected"] = expected.String()
+			report["matches"] = matches(diagnosis, expected)
+		}
+		return writeJSON(report)
+	}
+
+	fmt.Printf("Directory: %s\n", diagnosis.Directory)
+	if diagnosis.Committed != nil {
+		fmt.Printf("Committed: %s\n", diagnosis.Committed)
+	} else {
+		fmt.Println("Committed: none (not in a Git repository, or not committed)")
+	}
+	if expected != nil {
+		fmt.Printf("Expected:  %s (matches %s)\n", expected.CoreSWHID(), matches(diagnosis, expected))
+	}
+
+	for _, f := range diagnosis.Findings {
+		fmt.Printf("\n[%s] %s\n", f.Check, f.Summary)
+		for i, p := range f.Paths {
+			if i == maxDoctorPaths {
+				fmt.Printf("  ... and %d more\n", len(f.Paths)-maxDoctorPaths)
+				break
+			}
+			fmt.Printf("  %s\n", p)
+		}
+		fmt.Printf("  %s\n", f.Advice)
+	}
+	if len(diagnosis.Findings) == 0 {
+		if diagnosis.Committed != nil && diagnosis.Committed.Equal(diagnosis.Directory) {
+			fmt.Println("\nNo problems found: the directory matches the committed tree.")
+		} else {
+			fmt.Println("\nNo problems found.")
+		}
+	}
+	return nil
+}
+
+// matches names which of the computed SWHIDs expected is: "directory",
+// "committed", both, or "neither", in which case the archive most likely
+// has another commit or the SWHID is of another object type.
+func matches(diagnosis *swhid.Diagnosis, expected *swhid.Identifier) string {
+	var found []string
+	if expected.CoreSWHID() == diagnosis.Directory.CoreSWHID() {
+		found = append(found, "directory")
+	}
+	if diagnosis.Committed != nil && expected.CoreSWHID() == diagnosis.Committed.CoreSWHID() {
+		found = append(found, "committed")
+	}
+	if len(found) == 0 {
+		return "neither"
+	}
+	return strings.Join(found, " and ")
+}
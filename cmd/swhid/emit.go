@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/emit"
+)
+
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// openEmitter returns an emitter for a --emit target, an http(s) webhook
+// URL.
+func openEmitter(target string) (emit.Emitter, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid emit target %q: %w", target, err)
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		return &emit.Webhook{URL: target}, nil
+	default:
+		return nil, fmt.Errorf("unsupported emit target %q (expected an http(s):// URL)", target)
+	}
+}
+
+// emitTree sends an event for every object of root to the --emit targets.
+func emitTree(root *swhid.Node, dir string) error {
+	if len(emitFlags) == 0 {
+		return nil
+	}
+
+	var emitters []emit.Emitter
+	for _, target := range emitFlags {
+		em, err := openEmitter(target)
+		if err != nil {
+			return err
+		}
+		emitters = append(emitters, em)
+	}
+	em := emit.Multi(emitters...)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	source, err := filepath.Abs(dir)
+	if err != nil {
+		source = dir
+	}
+	if err := emit.Tree(ctx, em, root, source); err != nil {
+		em.Close(ctx)
+		return err
+	}
+	return em.Close(ctx)
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// evalUnsupported lists the commands eval cannot run: those streaming
+// NDJSON, which --json runs directly, and eval itself.
+var evalUnsupported = map[string]bool{
+	"eval":     true,
+	"help":     true,
+	"history":  true,
+	"manifest": true,
+}
+
+// runEval runs the command a JSON request on stdin describes and writes
+// its JSON document to stdout on one line. The request is an object:
+//
+//	{"command": "directory", "args": ["src"], "exclude": ["*.log"]}
+//
+// "command" is the command, followed by its subcommand if any ("oci
+// verify"); "args" its arguments, as an array or, for clients that only
+// send strings such as Terraform's external data source, a string holding
+// a JSON array or a single argument; "stdin" a string the command reads as
+// its standard input; and "flat" true to get the response as an object of
+// strings, nested keys joined with dots. Every other key is a flag, its
+// value a string, number, boolean or, for repeatable flags, an array.
+// Errors are written as with --json.
+func runEval(args []string) error {
+	if len(args) > 0 {
+		return usageError{errors.New("eval reads its request from stdin and takes no arguments")}
+	}
+	jsonFlag = true
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read stdin: %w", err)
+	}
+	var request map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&request); err != nil {
+		return usageError{fmt.Errorf("invalid request: %w", err)}
+	}
+
+	command, _ := request["command"].(string)
+	words := strings.Fields(command)
+	if len(words) == 0 {
+		return usageError{errors.New(`invalid request: "command" required`)}
+	}
+	if evalUnsupported[words[0]] {
+		return usageError{fmt.Errorf("eval cannot run %s; run it with --json", words[0])}
+	}
+	positional, err := evalArgs(request["args"])
+	if err != nil {
+		return usageError{err}
+	}
+	flat, err := evalBool(request["flat"])
+	if err != nil {
+		return usageError{fmt.Errorf(`invalid request: "flat": %w`, err)}
+	}
+	if input, ok := request["stdin"]; ok {
+		s, ok := input.(string)
+		if !ok {
+			return usageError{errors.New(`invalid request: "stdin" must be a string`)}
+		}
+		restore, err := replaceStdin(s)
+		if err != nil {
+			return err
+		}
+		defer restore()
+	}
+
+	argv := words[1:]
+	keys := make([]string, 0, len(request))
+	for k := range request {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		switch k {
+		case "command", "args", "stdin", "flat":
+			continue
+		}
+		values, err := evalFlagValues(request[k])
+		if err != nil {
+			return usageError{fmt.Errorf("invalid request: %q: %w", k, err)}
+		}
+		for _, v := range values {
+			argv = append(argv, "--"+k+"="+v)
+		}
+	}
+	argv = append(append(argv, "--json", "--"), positional...)
+
+	var documents []interface{}
+	documentSink = func(v interface{}) error {
+		documents = append(documents, v)
+		return nil
+	}
+	err = run(words[0], argv)
+	documentSink = nil
+	if err != nil {
+		return err
+	}
+
+	var response interface{}
+	switch len(documents) {
+	case 0:
+		response = map[string]interface{}{"schema": jsonSchema}
+	case 1:
+		response = documents[0]
+	default:
+		response = map[string]interface{}{"schema": jsonSchema, "documents": documents}
+	}
+	if flat {
+		if response, err = flatten(response); err != nil {
+			return err
+		}
+	}
+	return json.NewEncoder(os.Stdout).Encode(response)
+}
+
+// evalArgs returns the arguments of a request's "args" value.
+func evalArgs(v interface{}) ([]string, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if !strings.HasPrefix(strings.TrimSpace(v), "[") {
+			return []string{v}, nil
+		}
+		var args []string
+		if err := json.Unmarshal([]byte(v), &args); err != nil {
+			return nil, fmt.Errorf(`invalid request: "args": %w`, err)
+		}
+		return args, nil
+	case []interface{}:
+		args := make([]string, len(v))
+		for i, arg := range v {
+			s, ok := arg.(string)
+			if !ok {
+				return nil, fmt.Errorf(`invalid request: "args" must hold strings, not %v`, arg)
+			}
+			args[i] = s
+		}
+		return args, nil
+	}
+	return nil, errors.New(`invalid request: "args" must be an array or a string`)
+}
+
+// evalFlagValues returns the values a request gives a flag.
+func evalFlagValues(v interface{}) ([]string, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case json.Number:
+		return []string{v.String()}, nil
+	case bool:
+		return []string{strconv.FormatBool(v)}, nil
+	case []interface{}:
+		var values []string
+		for _, elem := range v {
+			if _, ok := elem.([]interface{}); ok {
+				return nil, errors.New("nested arrays are not flag values")
+			}
+			more, err := evalFlagValues(elem)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, more...)
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("%v is not a flag value", v)
+}
+
+// evalBool reads a boolean given as JSON or as a string.
+func evalBool(v interface{}) (bool, error) {
+	switch v := v.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(v)
+	}
+	return false, fmt.Errorf("%v is not a boolean", v)
+}
+
+// replaceStdin makes os.Stdin read s, from a temporary file so that
+// commands can stat and seek it, and returns a function restoring it.
+func replaceStdin(s string) (func(), error) {
+	f, err := os.CreateTemp("", "swhid-eval-*")
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(f.Name())
+	if _, err := io.WriteString(f, s); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	stdin := os.Stdin
+	os.Stdin = f
+	return func() {
+		os.Stdin = stdin
+		f.Close()
+	}, nil
+}
+
+// flatten returns v, encoded as JSON, as an object of strings: nested
+// objects and arrays are spread over keys joined with dots, such as
+// "qualifiers.origin" and "results.0.swhid", numbers and booleans are
+// written as in JSON, and null is empty.
+func flatten(v interface{}) (map[string]string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]string)
+	var walk func(prefix string, v interface{})
+	walk = func(prefix string, v interface{}) {
+		join := func(key string) string {
+			if prefix == "" {
+				return key
+			}
+			return prefix + "." + key
+		}
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for k, elem := range v {
+				walk(join(k), elem)
+			}
+		case []interface{}:
+			for i, elem := range v {
+				walk(join(strconv.Itoa(i)), elem)
+			}
+		case string:
+			out[prefix] = v
+		case json.Number:
+			out[prefix] = v.String()
+		case bool:
+			out[prefix] = strconv.FormatBool(v)
+		case nil:
+			out[prefix] = ""
+		}
+	}
+	walk("", doc)
+	return out, nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/objects"
+)
+
+const helloSWHID = "swh:1:cnt:ce013625030ba8dba906f756967f9e9ca394464a"
+
+// runEvalRequest runs eval with request on stdin and returns its response
+// decoded.
+func runEvalRequest(t *testing.T, request string) (map[string]interface{}, error) {
+	t.Helper()
+	restore, err := replaceStdin(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restore()
+	out, err := runCommand(t, "eval")
+	if err != nil {
+		return nil, err
+	}
+	var response map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &response); err != nil {
+		t.Fatalf("eval wrote %q: %v", out, err)
+	}
+	return response, nil
+}
+
+func TestEvalFlags(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "debug.log"), []byte("noise\n"), 0644)
+	want := swhid.FromDirectory([]objects.DirectoryEntry{
+		{Name: "hello.txt", Type: objects.EntryTypeFile, Target: objects.ComputeContentHash([]byte("hello\n"))},
+	})
+	dirArgs, _ := json.Marshal([]string{dir})
+
+	tests := []struct {
+		name    string
+		request string
+		want    string
+	}{
+		{"stdin and repeated flag",
+			`{"command": "content", "stdin": "hello\n", "qualifier": ["origin=https://example.com/r", "path=/hello.txt"]}`,
+			helloSWHID + ";origin=https://example.com/r;path=/hello.txt"},
+		{"boolean flag",
+			`{"command": "parse", "args": [" SWH:1:CNT:CE013625030BA8DBA906F756967F9E9CA394464A. "], "lenient": true}`,
+			helloSWHID},
+		{"boolean flag as string",
+			`{"command": "parse", "args": [" SWH:1:CNT:CE013625030BA8DBA906F756967F9E9CA394464A. "], "lenient": "true"}`,
+			helloSWHID},
+		{"number flag",
+			`{"command": "directory", "args": ` + string(dirArgs) + `, "jobs": 2, "exclude": "*.log"}`,
+			want.String()},
+		{"args as a string holding an array",
+			`{"command": "directory", "args": ` + jsonString(t, string(dirArgs)) + `, "exclude": ["*.log"]}`,
+			want.String()},
+		{"args as a single string",
+			`{"command": "parse", "args": "` + helloSWHID + `"}`,
+			helloSWHID},
+	}
+	for _, tt := range tests {
+		response, err := runEvalRequest(t, tt.request)
+		if err != nil {
+			t.Errorf("%s: eval error = %v", tt.name, err)
+			continue
+		}
+		if response["swhid"] != tt.want {
+			t.Errorf("%s: swhid = %v, want %v", tt.name, response["swhid"], tt.want)
+		}
+		if response["schema"] != jsonSchema {
+			t.Errorf("%s: schema = %v, want %v", tt.name, response["schema"], jsonSchema)
+		}
+	}
+}
+
+func TestEvalArgsAreNotFlags(t *testing.T) {
+	// An argument that looks like a flag is still an argument
+	_, err := runEvalRequest(t, `{"command": "parse", "args": ["--lenient"]}`)
+	if code := errorCode(err); code != "invalid_swhid" {
+		t.Errorf("eval parse --lenient: error = %v, code %q; want invalid_swhid", err, code)
+	}
+}
+
+func TestEvalFlat(t *testing.T) {
+	response, err := runEvalRequest(t, `{"command": "content", "stdin": "hello\n", "qualifier": "origin=https://example.com/r", "flat": "true"}`)
+	if err != nil {
+		t.Fatalf("eval error = %v", err)
+	}
+	want := map[string]interface{}{
+		"schema":            jsonSchema,
+		"swhid":             helloSWHID + ";origin=https://example.com/r",
+		"core":              helloSWHID,
+		"object_type":       "cnt",
+		"object_hash":       "ce013625030ba8dba906f756967f9e9ca394464a",
+		"qualifiers.origin": "https://example.com/r",
+	}
+	for k, v := range want {
+		if response[k] != v {
+			t.Errorf("%s = %v, want %v", k, response[k], v)
+		}
+	}
+	for k, v := range response {
+		if _, ok := v.(string); !ok {
+			t.Errorf("%s = %v, want a string", k, v)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		request string
+		code    string
+	}{
+		{`not json`, "usage"},
+		{`{"args": ["x"]}`, "usage"},
+		{`{"command": "history", "args": ["."]}`, "usage"},
+		{`{"command": "parse", "args": 3}`, "usage"},
+		{`{"command": "parse", "args": [3]}`, "usage"},
+		{`{"command": "parse", "args": "[3"}`, "usage"},
+		{`{"command": "parse", "args": ["` + helloSWHID + `"], "flat": 1}`, "usage"},
+		{`{"command": "parse", "args": ["` + helloSWHID + `"], "stdin": 1}`, "usage"},
+		{`{"command": "parse", "args": ["` + helloSWHID + `"], "lenient": [[true]]}`, "usage"},
+		{`{"command": "parse", "args": ["` + helloSWHID + `"], "no-such-flag": "x"}`, "usage"},
+		{`{"command": "parse", "args": ["swh:1:rvs:ce013625030ba8dba906f756967f9e9ca394464a"]}`, "invalid_swhid"},
+		{`{"command": "extract", "args": [` + jsonString(t, filepath.Join(t.TempDir(), "missing.txt")) + `]}`, "not_found"},
+	}
+	for _, tt := range tests {
+		_, err := runEvalRequest(t, tt.request)
+		if err == nil {
+			t.Errorf("eval %s succeeded, want a %s error", tt.request, tt.code)
+			continue
+		}
+		if code := errorCode(err); code != tt.code {
+			t.Errorf("eval %s: error = %v, code %q, want %q", tt.request, err, code, tt.code)
+		}
+	}
+}
+
+// jsonString returns s encoded as a JSON string.
+func jsonString(t *testing.T, s string) string {
+	t.Helper()
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
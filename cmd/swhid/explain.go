@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/objects"
+)
+
+// runExplainRevision prints the revision SWHID of a commit followed by the
+// payload it was hashed from, one escaped line at a time, marking the
+// lines of extra headers with a "+".
+func runExplainRevision(repoPath, ref string, opts swhid.GitOptions) error {
+	session, err := swhid.OpenRepoWithOptions(repoPath, opts)
+	if err != nil {
+		return err
+	}
+	meta, err := session.RevisionMetadata(ref)
+	if err != nil {
+		return err
+	}
+
+	explanation := objects.ExplainRevision(meta)
+	id := applyQualifiers(swhid.FromRevisionMetadata(meta))
+
+	if formatFlag == "json" {
+		lines := make([]map[string]interface{}, len(explanation.Lines))
+		for i, line := range explanation.Lines {
+			lines[i] = map[string]interface{}{
+				"key":   line.Key,
+				"extra": line.Extra,
+				"text":  line.Text,
+			}
+		}
+		return writeJSON(map[string]interface{}{
+			"swhid":  id.String(),
+			"header": explanation.Header,
+			"lines":  lines,
+		})
+	}
+
+	fmt.Println(id)
+	fmt.Printf("  %s\n", explanation.Header)
+	extra := false
+	for _, line := range explanation.Lines {
+		marker := " "
+		if line.Extra {
+			marker, extra = "+", true
+		}
+		fmt.Printf("%s %s\n", marker, line.Text)
+	}
+	if extra {
+		fmt.Println("(+ marks headers other than tree, parent, author and committer)")
+	}
+	return nil
+}
+
+// runExplainRelease prints the release SWHID of an annotated tag with the
+// fields it was computed from, then the payload it was hashed from as
+// runExplainRevision does.
+func runExplainRelease(repoPath, tagName string, opts swhid.GitOptions) error {
+	session, err := swhid.OpenRepoWithOptions(repoPath, opts)
+	if err != nil {
+		return err
+	}
+	meta, err := session.ReleaseMetadata(tagName)
+	if err != nil {
+		return err
+	}
+
+	explanation := objects.ExplainRelease(meta)
+	id := applyQualifiers(swhid.FromReleaseMetadata(meta))
+	target, err := swhid.NewIdentifier(swhid.ObjectType(meta.Target.Type), meta.Target.Hash, nil)
+	if err != nil {
+		return err
+	}
+	message, signature, format := meta.Signature()
+
+	if formatFlag == "json" {
+		doc := map[string]interface{}{
+			"swhid":       id.String(),
+			"name":        meta.Name,
+			"target":      target.String(),
+			"target_type": meta.Target.GitType(),
+			"message":     message,
+			"signed":      signature != "",
+			"header":      explanation.Header,
+		}
+		if meta.Author != "" {
+			doc["tagger"] = meta.Author
+			doc["tagger_date"] = taggerTime(meta).Format(time.RFC3339)
+		}
+		if signature != "" {
+			doc["signature_format"] = format
+			doc["signature"] = signature
+		}
+		if len(meta.ExtraHeaders) > 0 {
+			doc["extra_headers"] = meta.ExtraHeaders
+		}
+		lines := make([]map[string]interface{}, len(explanation.Lines))
+		for i, line := range explanation.Lines {
+			lines[i] = map[string]interface{}{
+				"key":   line.Key,
+				"extra": line.Extra,
+				"text":  line.Text,
+			}
+		}
+		doc["lines"] = lines
+		return writeJSON(doc)
+	}
+
+	fmt.Println(id)
+	fmt.Printf("  name:      %s\n", meta.Name)
+	fmt.Printf("  target:    %s (%s)\n", target, meta.Target.GitType())
+	if meta.Author != "" {
+		fmt.Printf("  tagger:    %s\n", meta.Author)
+		fmt.Printf("  date:      %s\n", taggerTime(meta).Format("2006-01-02 15:04:05 -0700"))
+	} else {
+		fmt.Println("  tagger:    (none)")
+	}
+	if signature != "" {
+		fmt.Printf("  signature: %s\n", format)
+	} else {
+		fmt.Println("  signature: (unsigned)")
+	}
+	for _, header := range meta.ExtraHeaders {
+		fmt.Printf("  header:    %s\n", header[0])
+	}
+	fmt.Println("  message:")
+	for _, line := range strings.SplitAfter(message, "\n") {
+		if line != "" {
+			fmt.Printf("    %s\n", objects.EscapePayload(strings.TrimSuffix(line, "\n")))
+		}
+	}
+
+	fmt.Printf("  %s\n", explanation.Header)
+	for _, line := range explanation.Lines {
+		marker := " "
+		if line.Extra {
+			marker = "+"
+		}
+		fmt.Printf("%s %s\n", marker, line.Text)
+	}
+	return nil
+}
+
+// taggerTime returns the time a tag was made, in the tagger's time zone.
+func taggerTime(meta objects.ReleaseMetadata) time.Time {
+	t := time.Unix(meta.AuthorTimestamp, 0).UTC()
+	tz := meta.AuthorTimezone
+	if len(tz) != 5 || (tz[0] != '+' && tz[0] != '-') {
+		return t
+	}
+	hours, err1 := strconv.Atoi(tz[1:3])
+	minutes, err2 := strconv.Atoi(tz[3:5])
+	if err1 != nil || err2 != nil {
+		return t
+	}
+	offset := hours*3600 + minutes*60
+	if tz[0] == '-' {
+		offset = -offset
+	}
+	return t.In(time.FixedZone(tz, offset))
+}
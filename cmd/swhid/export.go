@@ -0,0 +1,192 @@
+package main
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/release"
+)
+
+// runExport archives a directory to the --output file with its SWHID
+// recorded in the archive, or checks such an archive with "export verify".
+func runExport(args []string) error {
+	if len(args) > 0 && args[0] == "verify" {
+		return runExportVerify(args[1:])
+	}
+	if len(args) < 1 {
+		return fmt.Errorf("directory path required")
+	}
+	if outputFlag == "" || outputFlag == "-" {
+		return fmt.Errorf("archive file required (-o NAME.tar.gz or NAME.zip)")
+	}
+	format, err := archiveFormat(outputFlag)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(outputFlag)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	opts := release.Options{Prefix: prefixFlag, Tree: treeOptions()}
+	var w io.Writer = f
+	var gz *gzip.Writer
+	if format == "tar.gz" {
+		gz = gzip.NewWriter(f)
+		w = gz
+	}
+	write := release.WriteTar
+	if format == "zip" {
+		write = release.WriteZip
+	}
+	id, err := write(w, args[0], opts)
+	if err == nil && gz != nil {
+		err = gz.Close()
+	}
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		os.Remove(outputFlag)
+		return err
+	}
+
+	// -o names the archive, so the report goes to stdout.
+	archive := outputFlag
+	outputFlag = ""
+	if formatFlag == "json" {
+		doc := map[string]interface{}{"path": archive, "swhid": id.CoreSWHID()}
+		if prefixFlag != "" {
+			doc["prefix"] = prefixFlag
+		}
+		return writeJSON(doc)
+	}
+	fmt.Printf("%s  %s\n", id, archive)
+	return nil
+}
+
+// archiveFormat returns the archive format a file name asks for: "zip",
+// "tar" or "tar.gz".
+func archiveFormat(name string) (string, error) {
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return "zip", nil
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return "tar.gz", nil
+	case strings.HasSuffix(name, ".tar"):
+		return "tar", nil
+	}
+	return "", fmt.Errorf("%s: unknown archive format (use .tar, .tar.gz, .tgz or .zip)", name)
+}
+
+// hashArchive returns the directory SWHID of the tree in the .tar, .tar.gz
+// or .zip file at path, normalized as the named preset says.
+func hashArchive(path, presetName string) (*swhid.Identifier, error) {
+	format, err := archiveFormat(path)
+	if err != nil {
+		return nil, err
+	}
+	preset, err := release.LookupPreset(presetName)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch format {
+	case "zip":
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		return release.HashZip(f, info.Size(), preset)
+	case "tar.gz":
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		return release.HashTar(gz, preset)
+	}
+	return release.HashTar(f, preset)
+}
+
+// runExportVerify checks an archive against the SWHID recorded in it.
+func runExportVerify(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("archive path required")
+	}
+	format, err := archiveFormat(args[0])
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var res *release.Result
+	switch format {
+	case "zip":
+		info, statErr := f.Stat()
+		if statErr != nil {
+			return statErr
+		}
+		res, err = release.ReadZip(f, info.Size())
+	case "tar.gz":
+		gz, gzErr := gzip.NewReader(f)
+		if gzErr != nil {
+			return gzErr
+		}
+		res, err = release.ReadTar(gz)
+	default:
+		res, err = release.ReadTar(f)
+	}
+	if res == nil {
+		return err
+	}
+
+	if formatFlag == "json" {
+		doc := map[string]interface{}{
+			"path":     args[0],
+			"computed": res.Computed.CoreSWHID(),
+			"ok":       res.Match(),
+		}
+		if res.Embedded != nil {
+			doc["swhid"] = res.Embedded.CoreSWHID()
+		}
+		if res.Prefix != "" {
+			doc["prefix"] = res.Prefix
+		}
+		if werr := writeJSON(doc); werr != nil {
+			return werr
+		}
+	} else {
+		status := "OK"
+		switch {
+		case errors.Is(err, release.ErrNoSWHID):
+			status = "NO SWHID"
+		case err != nil:
+			status = "MISMATCH"
+		}
+		fmt.Printf("Status:   %s\n", status)
+		if res.Embedded != nil {
+			fmt.Printf("Recorded: %s\n", res.Embedded)
+		}
+		fmt.Printf("Computed: %s\n", res.Computed)
+		if res.Prefix != "" {
+			fmt.Printf("Prefix:   %s\n", res.Prefix)
+		}
+	}
+	return err
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andrew/swhid-go"
+)
+
+func TestExportOutputAfterDirectory(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello\n"), 0644)
+	want, _ := swhid.FromDirectoryPath(dir)
+
+	for _, name := range []string{"release.tar.gz", "release.zip"} {
+		archive := filepath.Join(t.TempDir(), name)
+		stdout, err := runCommand(t, "export", dir, "-o", archive, "--prefix", "project-1.0")
+		if err != nil {
+			t.Fatalf("export %s error = %v", name, err)
+		}
+		if !strings.HasPrefix(stdout, want.String()+"  ") {
+			t.Errorf("export %s wrote %q, want %v first", name, stdout, want)
+		}
+		if _, err := os.Stat(archive); err != nil {
+			t.Fatalf("export -o %s wrote no archive: %v", name, err)
+		}
+		if _, err := runCommand(t, "export", "verify", archive); err != nil {
+			t.Errorf("export verify %s error = %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/andrew/swhid-go/extract"
+)
+
+// runExtract lists the SWHIDs found in documents, plain text, PDF or DOCX,
+// repairing those mangled by typesetting and reporting those that cannot
+// be repaired.
+func runExtract(args []string) error {
+	if len(args) < 1 {
+		args = []string{"-"}
+	}
+
+	type found struct {
+		path string
+		extract.Match
+	}
+	var all []found
+	for _, path := range args {
+		var matches []extract.Match
+		var err error
+		if path == "-" {
+			matches, err = extract.Reader(os.Stdin)
+		} else {
+			matches, err = extract.File(path)
+		}
+		if err != nil {
+			return err
+		}
+		for _, m := range matches {
+			all = append(all, found{path, m})
+		}
+	}
+
+	invalid := 0
+	for _, f := range all {
+		if f.Err != nil {
+			invalid++
+		}
+	}
+
+	if formatFlag == "json" {
+		list := []map[string]interface{}{}
+		for _, f := range all {
+			item := map[string]interface{}{
+				"path": f.path,
+				"line": f.Line,
+				"text": f.Raw,
+			}
+			if f.ID != nil {
+				item["swhid"] = f.ID.String()
+			}
+			if len(f.Fixes) > 0 {
+				item["fixes"] = f.Fixes
+			}
+			if f.Err != nil {
+				item["error"] = f.Err.Error()
+			}
+			list = append(list, item)
+		}
+		if err := writeJSON(map[string]interface{}{"total": len(all), "invalid": invalid, "results": list}); err != nil {
+			return err
+		}
+	} else {
+		for _, f := range all {
+			if f.Err != nil {
+				fmt.Fprintf(os.Stderr, "%s:%d: %q: %v\n", f.path, f.Line, f.Raw, f.Err)
+				continue
+			}
+			if len(f.Fixes) > 0 {
+				fixes := make([]string, len(f.Fixes))
+				for i, fix := range f.Fixes {
+					fixes[i] = string(fix)
+				}
+				fmt.Printf("%s:%d: %s (%s)\n", f.path, f.Line, f.ID, strings.Join(fixes, ", "))
+			} else {
+				fmt.Printf("%s:%d: %s\n", f.path, f.Line, f.ID)
+			}
+		}
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d SWHIDs found are invalid", invalid, len(all))
+	}
+	return nil
+}
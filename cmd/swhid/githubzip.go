@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/andrew/swhid-go/release"
+)
+
+// zipMarkers label difference kinds in text output, in the style of git
+// status: A for what only the zip has, D for what only the commit has.
+var zipMarkers = map[string]string{
+	release.ZipModeDiffers:    "T",
+	release.ZipContentDiffers: "M",
+	release.ZipMissing:        "D",
+	release.ZipExtra:          "A",
+}
+
+// runGitHubZip hashes the tree in a GitHub "Download ZIP" archive and,
+// given a clone, checks it against the commit it was made from.
+func runGitHubZip(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("zip file required")
+	}
+	opts := release.GitHubZipOptions{}
+	if len(args) > 1 {
+		opts.Repo = args[1]
+	}
+	if len(args) > 2 {
+		opts.Rev = args[2]
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	z, err := release.HashGitHubZip(f, info.Size(), opts)
+	if err != nil {
+		return err
+	}
+
+	if formatFlag == "json" {
+		doc := map[string]interface{}{
+			"path":   args[0],
+			"prefix": z.Prefix,
+			"swhid":  z.ID.CoreSWHID(),
+		}
+		if z.Commit != "" {
+			doc["commit"] = z.Commit
+		}
+		if z.Tree != nil {
+			differences := make([]map[string]interface{}, len(z.Differences))
+			for i, d := range z.Differences {
+				differences[i] = map[string]interface{}{"path": d.Path, "kind": d.Kind}
+			}
+			doc["tree"] = z.Tree.CoreSWHID()
+			doc["matches"] = z.Match()
+			doc["restored"] = z.Restored
+			doc["differences"] = differences
+		}
+		return writeJSON(doc)
+	}
+
+	fmt.Printf("SWHID:  %s\n", z.ID)
+	if z.Commit != "" {
+		fmt.Printf("Commit: %s\n", z.Commit)
+	}
+	if z.Tree == nil {
+		return nil
+	}
+	fmt.Printf("Tree:   %s\n", z.Tree)
+	if z.Restored > 0 {
+		fmt.Printf("Restored the modes of %d entries the zip lost.\n", z.Restored)
+	}
+	if z.Match() {
+		fmt.Println("The zip matches the commit's tree.")
+		return nil
+	}
+	for _, d := range z.Differences {
+		fmt.Printf("%s %s\n", zipMarkers[d.Kind], d.Path)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/andrew/swhid-go"
+)
+
+// runGitoid converts between SWHIDs and gitoid (OmniBOR) URIs. Without an
+// argument it prints the sha1 and sha256 blob gitoids of stdin.
+func runGitoid(args []string) error {
+	if len(args) < 1 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		sha1, _ := swhid.GitoidFromContent(data, swhid.GitoidSHA1)
+		sha256, _ := swhid.GitoidFromContent(data, swhid.GitoidSHA256)
+		if formatFlag == "json" {
+			return writeJSON(map[string]interface{}{
+				"swhid":  swhid.FromContent(data).String(),
+				"sha1":   sha1.String(),
+				"sha256": sha256.String(),
+			})
+		}
+		fmt.Println(sha1)
+		fmt.Println(sha256)
+		return nil
+	}
+
+	if strings.HasPrefix(args[0], "gitoid:") {
+		g, err := swhid.ParseGitoid(args[0])
+		if err != nil {
+			return err
+		}
+		id, err := g.SWHID()
+		if err != nil {
+			return err
+		}
+		outputIdentifier(applyQualifiers(id))
+		return nil
+	}
+
+	id, err := swhid.Parse(args[0])
+	if err != nil {
+		return err
+	}
+	g, err := id.Gitoid()
+	if err != nil {
+		return err
+	}
+	if formatFlag == "json" {
+		return writeJSON(map[string]interface{}{
+			"swhid":  id.CoreSWHID(),
+			"gitoid": g.String(),
+		})
+	}
+	fmt.Println(g)
+	return nil
+}
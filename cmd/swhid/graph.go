@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/dataset"
+)
+
+// runGraph writes graph.nodes.csv and graph.edges.csv for a repository in
+// the swh-graph dataset layout.
+func runGraph(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("repository path required")
+	}
+
+	outDir := "."
+	if len(args) > 1 {
+		outDir = args[1]
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+
+	nodesPath := filepath.Join(outDir, "graph.nodes.csv")
+	edgesPath := filepath.Join(outDir, "graph.edges.csv")
+
+	nodes, err := os.Create(nodesPath)
+	if err != nil {
+		return err
+	}
+	defer nodes.Close()
+
+	edges, err := os.Create(edgesPath)
+	if err != nil {
+		return err
+	}
+	defer edges.Close()
+
+	if err := dataset.ExportWithOptions(args[0], swhid.GitOptions{ReadOnlyFS: readOnlyFlag}, nodes, edges); err != nil {
+		return err
+	}
+
+	if formatFlag == "json" {
+		return writeJSON(map[string]interface{}{"nodes": nodesPath, "edges": edgesPath})
+	}
+	fmt.Printf("Wrote %s and %s\n", nodesPath, edgesPath)
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/andrew/swhid-go"
+)
+
+// runHistory writes one JSON object per commit reachable from the
+// repository's references (NDJSON), regardless of --format. With
+// --graph-only only the fields a commit-graph file holds are written, and
+// they are read from the commit-graph when there is one; otherwise every
+// commit is decoded.
+func runHistory(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("repository path required")
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+	if graphOnlyFlag {
+		return swhid.WalkRevisionGraph(args[0], func(r swhid.RevisionNode) error {
+			return encoder.Encode(map[string]interface{}{
+				"schema":              jsonSchema,
+				"swhid":               r.ID.String(),
+				"directory":           r.Directory.String(),
+				"parents":             identifierStrings(r.Parents),
+				"committer_timestamp": r.CommitterTimestamp,
+			})
+		})
+	}
+	return swhid.WalkHistory(args[0], func(r swhid.RevisionRecord) error {
+		return encoder.Encode(map[string]interface{}{
+			"schema":              jsonSchema,
+			"swhid":               r.ID.String(),
+			"directory":           r.Directory.String(),
+			"parents":             identifierStrings(r.Parents),
+			"author":              r.Author,
+			"author_timestamp":    r.AuthorTimestamp,
+			"author_timezone":     r.AuthorTimezone,
+			"committer":           r.Committer,
+			"committer_timestamp": r.CommitterTimestamp,
+			"committer_timezone":  r.CommitterTimezone,
+		})
+	})
+}
+
+func identifierStrings(ids []*swhid.Identifier) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = id.String()
+	}
+	return out
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/andrew/swhid-go"
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// hookMarker identifies hook scripts written by "swhid hook install".
+const hookMarker = "# installed by swhid hook install"
+
+var hookNames = []string{"prepare-commit-msg", "pre-push"}
+
+func runHook(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("hook subcommand required (install, run)")
+	}
+
+	switch args[0] {
+	case "install":
+		return runHookInstall(args[1:])
+	case "run":
+		if len(args) < 2 {
+			return fmt.Errorf("hook name required")
+		}
+		switch args[1] {
+		case "prepare-commit-msg":
+			return runPrepareCommitMsg(args[2:])
+		case "pre-push":
+			return runPrePush()
+		default:
+			return fmt.Errorf("unknown hook: %s", args[1])
+		}
+	default:
+		return fmt.Errorf("unknown hook subcommand: %s", args[0])
+	}
+}
+
+func runHookInstall(args []string) error {
+	repoPath := "."
+	if len(args) > 0 {
+		repoPath = args[0]
+	}
+
+	hooksDir, err := gitHooksDir(repoPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return err
+	}
+
+	installed := []string{}
+	for _, name := range hookNames {
+		path := filepath.Join(hooksDir, name)
+		if existing, err := os.ReadFile(path); err == nil && !strings.Contains(string(existing), hookMarker) && !forceFlag {
+			return fmt.Errorf("%s already exists; use --force to overwrite", path)
+		}
+
+		script := fmt.Sprintf("#!/bin/sh\n%s\nexec swhid hook run %s \"$@\"\n", hookMarker, name)
+		if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+			return err
+		}
+		installed = append(installed, path)
+	}
+
+	if formatFlag == "json" {
+		return writeJSON(map[string]interface{}{"installed": installed})
+	}
+	for _, path := range installed {
+		fmt.Printf("Installed %s\n", path)
+	}
+	return nil
+}
+
+// gitHooksDir returns the directory Git runs the hooks of the repository at
+// repoPath from, as git rev-parse --git-path hooks does: core.hooksPath
+// from any config file, relative to the top of the worktree, or else the
+// hooks directory of the common Git directory, which linked worktrees
+// share and which a submodule's .git file leads to.
+func gitHooksDir(repoPath string) (string, error) {
+	repo, err := git.PlainOpenWithOptions(repoPath, &git.PlainOpenOptions{DetectDotGit: true, EnableDotGitCommonDir: true})
+	if err != nil {
+		return "", fmt.Errorf("not a git repository: %s", repoPath)
+	}
+	storage, ok := repo.Storer.(*filesystem.Storage)
+	if !ok {
+		return "", fmt.Errorf("not a git repository: %s", repoPath)
+	}
+	gitDir := storage.Filesystem().Root()
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		common := strings.TrimSpace(string(data))
+		if !filepath.IsAbs(common) {
+			common = filepath.Join(gitDir, common)
+		}
+		gitDir = common
+	}
+
+	gitConfig, err := repo.ConfigScoped(gitconfig.SystemScope)
+	if err != nil {
+		return "", err
+	}
+	hooksPath := gitConfig.Raw.Section("core").Option("hooksPath")
+	if hooksPath == "" {
+		return filepath.Join(gitDir, "hooks"), nil
+	}
+	if rest, ok := strings.CutPrefix(hooksPath, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		hooksPath = filepath.Join(home, rest)
+	}
+	if filepath.IsAbs(hooksPath) {
+		return filepath.Clean(hooksPath), nil
+	}
+	// Hooks run from the top of the worktree, or the Git directory of a
+	// bare repository
+	top := gitDir
+	if wt, err := repo.Worktree(); err == nil {
+		top = wt.Filesystem.Root()
+	}
+	return filepath.Join(top, hooksPath), nil
+}
+
+// runPrepareCommitMsg appends a trailer with the directory SWHID of the
+// staged tree to the commit message file.
+func runPrepareCommitMsg(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("commit message file required")
+	}
+	msgFile := args[0]
+
+	// git commit -a and git commit PATHS stage into a temporary index and
+	// name it in GIT_INDEX_FILE; that is the tree being committed.
+	var id *swhid.Identifier
+	var err error
+	if indexFile := os.Getenv("GIT_INDEX_FILE"); indexFile != "" {
+		id, err = swhid.FromGitIndexFile(indexFile)
+	} else {
+		id, err = swhid.FromGitIndex(".")
+	}
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(msgFile)
+	if err != nil {
+		return err
+	}
+
+	trailer := cfg.Hooks.Trailer + ": " + id.CoreSWHID()
+	if strings.Contains(string(content), trailer) {
+		return nil
+	}
+
+	// Replace any stale trailer left over from an amended commit.
+	var lines []string
+	for _, line := range strings.Split(strings.TrimRight(string(content), "\n"), "\n") {
+		if !strings.HasPrefix(line, cfg.Hooks.Trailer+": ") {
+			lines = append(lines, line)
+		}
+	}
+	message := strings.TrimRight(strings.Join(lines, "\n"), "\n")
+	message += "\n\n" + trailer + "\n"
+
+	return os.WriteFile(msgFile, []byte(message), 0644)
+}
+
+// runPrePush checks every pushed tag listed in the releases file against
+// the SWHID recorded for it, refusing the push on a mismatch.
+func runPrePush() error {
+	recorded, err := readReleasesFile(cfg.Hooks.ReleasesFile)
+	if err != nil {
+		return err
+	}
+	if len(recorded) == 0 {
+		return nil
+	}
+
+	var failed []string
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		// <local ref> <local sha> <remote ref> <remote sha>
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 1 || !strings.HasPrefix(fields[0], "refs/tags/") {
+			continue
+		}
+
+		tag := strings.TrimPrefix(fields[0], "refs/tags/")
+		want, ok := recorded[tag]
+		if !ok {
+			continue
+		}
+
+		got, err := swhid.FromRelease(".", tag)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", tag, err))
+			continue
+		}
+		if got.CoreSWHID() != want {
+			failed = append(failed, fmt.Sprintf("%s: recorded %s, computed %s", tag, want, got.CoreSWHID()))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("release SWHID mismatch:\n  %s", strings.Join(failed, "\n  "))
+	}
+	return nil
+}
+
+// readReleasesFile reads "<tag> <swhid>" lines. A missing file yields no records.
+func readReleasesFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	recorded := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s: invalid line %q (expected TAG SWHID)", path, line)
+		}
+		id, err := swhid.Parse(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		recorded[fields[0]] = id.CoreSWHID()
+	}
+	return recorded, scanner.Err()
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andrew/swhid-go"
+	"github.com/go-git/go-git/v5"
+)
+
+// isolateGitConfig keeps the user's and the system's Git config, and any
+// core.hooksPath in them, out of a test.
+func isolateGitConfig(t *testing.T) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+}
+
+func writeFiles(t *testing.T, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHookInstallForceAfterRepo(t *testing.T) {
+	isolateGitConfig(t)
+	repo := newTestRepo(t)
+	hook := filepath.Join(repo, ".git", "hooks", "pre-push")
+	writeFiles(t, map[string]string{hook: "#!/bin/sh\nexit 0\n"})
+
+	if _, err := runCommand(t, "hook", "install", repo); err == nil {
+		t.Fatal("hook install overwrote a hook it did not write")
+	}
+	if _, err := runCommand(t, "hook", "install", repo, "--force"); err != nil {
+		t.Fatalf("hook install --force error = %v", err)
+	}
+	data, _ := os.ReadFile(hook)
+	if !strings.Contains(string(data), hookMarker) {
+		t.Errorf("hook install REPO --force left %q", data)
+	}
+}
+
+func TestGitHooksDir(t *testing.T) {
+	isolateGitConfig(t)
+
+	repo := newTestRepo(t)
+	gitDir := filepath.Join(repo, ".git")
+
+	// A linked worktree runs the hooks of the repository it belongs to
+	wtGitDir := filepath.Join(gitDir, "worktrees", "wt")
+	wt := filepath.Join(t.TempDir(), "wt")
+	head, _ := os.ReadFile(filepath.Join(gitDir, "refs", "heads", "master"))
+	writeFiles(t, map[string]string{
+		filepath.Join(wtGitDir, "HEAD"):      string(head),
+		filepath.Join(wtGitDir, "commondir"): "../..\n",
+		filepath.Join(wt, ".git"):            "gitdir: " + wtGitDir + "\n",
+	})
+
+	// A submodule's .git file leads to its Git directory in the superproject
+	modGitDir := filepath.Join(gitDir, "modules", "sub")
+	if _, err := git.PlainInit(modGitDir, true); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(repo, "sub")
+	writeFiles(t, map[string]string{filepath.Join(sub, ".git"): "gitdir: ../.git/modules/sub\n"})
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{repo, filepath.Join(gitDir, "hooks")},
+		{sub, filepath.Join(modGitDir, "hooks")},
+		{wt, filepath.Join(gitDir, "hooks")},
+	}
+	for _, tt := range tests {
+		got, err := gitHooksDir(tt.path)
+		if err != nil {
+			t.Errorf("gitHooksDir(%s) error = %v", tt.path, err)
+			continue
+		}
+		if filepath.Clean(got) != tt.want {
+			t.Errorf("gitHooksDir(%s) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+
+	if _, err := gitHooksDir(t.TempDir()); err == nil {
+		t.Error("gitHooksDir() of a directory outside any repository succeeded")
+	}
+}
+
+func TestGitHooksDirHooksPath(t *testing.T) {
+	isolateGitConfig(t)
+	repo := newTestRepo(t)
+	r, err := git.PlainOpen(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gitConfig, err := r.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gitConfig.Raw.Section("core").SetOption("hooksPath", ".githooks")
+	if err := r.SetConfig(gitConfig); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := runCommand(t, "hook", "install", repo); err != nil {
+		t.Fatalf("hook install error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, ".githooks", "prepare-commit-msg")); err != nil {
+		t.Errorf("hook install ignored core.hooksPath: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, ".git", "hooks", "prepare-commit-msg")); err == nil {
+		t.Error("hook install wrote to .git/hooks despite core.hooksPath")
+	}
+}
+
+func TestPrepareCommitMsgIndexFile(t *testing.T) {
+	repo := newTestRepo(t)
+
+	// The temporary index of git commit -a, staging a change to hello.txt
+	// that the repository's own index does not have
+	r, err := git.PlainOpen(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	headIndex, err := r.Storer.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, map[string]string{filepath.Join(repo, "hello.txt"): "changed\n"})
+	wt, _ := r.Worktree()
+	if _, err := wt.Add("hello.txt"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(repo, ".git", "index"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nextIndex := filepath.Join(repo, ".git", "next-index-1.lock")
+	writeFiles(t, map[string]string{nextIndex: string(data)})
+	if err := r.Storer.SetIndex(headIndex); err != nil {
+		t.Fatal(err)
+	}
+	want, err := swhid.FromGitIndexFile(nextIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := filepath.Join(t.TempDir(), "COMMIT_EDITMSG")
+	writeFiles(t, map[string]string{msg: "Change hello\n"})
+	t.Setenv("GIT_INDEX_FILE", nextIndex)
+	if _, err := runCommand(t, "hook", "run", "prepare-commit-msg", msg); err != nil {
+		t.Fatalf("hook run prepare-commit-msg error = %v", err)
+	}
+	got, _ := os.ReadFile(msg)
+	if wantMsg := "Change hello\n\nSource-SWHID: " + want.CoreSWHID() + "\n"; string(got) != wantMsg {
+		t.Errorf("commit message = %q, want %q", got, wantMsg)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/andrew/swhid-go/inventory"
+)
+
+// runIndex writes or refreshes a SQLite inventory of a directory, rehashing
+// only files changed since the database was last written.
+func runIndex(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("directory path required")
+	}
+
+	stats, err := inventory.UpdateWithOptions(dbFlag, args[0], inventory.UpdateOptions{
+		Tree:     treeOptions(),
+		Paranoid: paranoidFlag,
+	})
+	if err != nil {
+		return err
+	}
+	if err := emitTree(stats.Tree, args[0]); err != nil {
+		return err
+	}
+
+	if formatFlag == "json" {
+		return writeJSON(map[string]interface{}{
+			"swhid":   stats.Root.String(),
+			"db":      dbFlag,
+			"objects": stats.Objects,
+			"hashed":  stats.Hashed,
+			"reused":  stats.Reused,
+			"removed": stats.Removed,
+			"changes": map[string]interface{}{
+				"added":    nonNil(stats.Changes.Added),
+				"removed":  nonNil(stats.Changes.Removed),
+				"modified": nonNil(stats.Changes.Modified),
+			},
+		})
+	}
+
+	fmt.Println(stats.Root)
+	fmt.Printf("Indexed %d objects in %s (%d files hashed, %d unchanged, %d removed)\n",
+		stats.Objects, dbFlag, stats.Hashed, stats.Reused, stats.Removed)
+	if changesFlag {
+		printChanges("A", stats.Changes.Added)
+		printChanges("M", stats.Changes.Modified)
+		printChanges("D", stats.Changes.Removed)
+	}
+	return nil
+}
+
+// printChanges lists changed paths with a git status style marker; the
+// root directory is shown as ".".
+func printChanges(marker string, paths []string) {
+	for _, p := range paths {
+		if p == "" {
+			p = "."
+		}
+		fmt.Printf("%s %s\n", marker, p)
+	}
+}
+
+// nonNil makes empty lists encode as [] instead of null.
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
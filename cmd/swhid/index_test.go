@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/inventory"
+)
+
+func TestIndexDBAfterPath(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db := filepath.Join(t.TempDir(), "files.sqlite")
+
+	if _, err := runCommand(t, "index", dir, "--db", db); err != nil {
+		t.Fatalf("index error = %v", err)
+	}
+	inv, err := inventory.Read(db)
+	if err != nil {
+		t.Fatalf("inventory.Read() error = %v", err)
+	}
+	want, _ := swhid.FromDirectoryPath(dir)
+	if inv.Meta["swhid"] != want.CoreSWHID() || len(inv.Records) != 2 {
+		t.Errorf("inventory = %+v, want %v and 2 records", inv, want)
+	}
+}
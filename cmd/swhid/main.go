@@ -1,21 +1,105 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/archive"
+	"github.com/andrew/swhid-go/attest"
+	"github.com/andrew/swhid-go/objects"
 )
 
 var (
-	formatFlag     string
-	qualifierFlags qualifierList
+	cfg               *config
+	formatFlag        string
+	jsonFlag          bool
+	qualifierFlags    qualifierList
+	parseURLFlag      bool
+	provenanceFlag    bool
+	ldflagsFlag       bool
+	stagedFlag        bool
+	treeFlag          string
+	forceFlag         bool
+	replaceFlag       bool
+	graftsFlag        bool
+	refsFlag          string
+	headFlag          string
+	remoteFlag        string
+	depthFlag         int
+	progressFlag      bool
+	checkpointFlag    string
+	specFlag          string
+	lenientFlag       bool
+	outputFlag        string
+	dbFlag            string
+	emitFlags         stringList
+	includeFlags      stringList
+	excludeFlags      stringList
+	followFlag        bool
+	noHardLinks       bool
+	vcsDirsFlag       string
+	modeFlags         modeList
+	readOnlyFlag      bool
+	noGitFlag         bool
+	signCommand       string
+	signFlag          bool
+	keyFlag           string
+	identityTokenFlag string
+	fulcioURLFlag     string
+	rekorURLFlag      string
+	rekorKeyFlag      string
+	certChainFlag     string
+	certIdentityFlag  string
+	certIssuerFlag    string
+	paranoidFlag      bool
+	changesFlag       bool
+	graphOnlyFlag     bool
+	atFlag            string
+	refsFileFlag      string
+	refspecFlags      stringList
+	branchesFromFlag  string
+	forgeURLFlag      string
+	archiveFlag       bool
+	noCloneFlag       bool
+	skipForksFlag     bool
+	skipArchivedFlag  bool
+	intervalFlag      time.Duration
+	batchFlag         string
+	jobsFlag          int
+	explainFlag       bool
+	alsoVersionFlag   int
+	prefixFlag        string
+	analyzeFlag       bool
+	estimateFlag      bool
+	mimeFlag          bool
+	licensesFlag      bool
+	skipOSJunkFlag    bool
+	warningsFlag      bool
+	presetFlag        string
 )
 
+// subcommands lists the subcommands of commands that have them; flags may
+// follow a subcommand name.
+var subcommands = map[string]map[string]bool{
+	"attest": {"verify": true},
+	"export": {"verify": true},
+	"auth":   {"login": true, "status": true, "logout": true},
+	"hook":   {"install": true, "run": true},
+	"oci":    {"annotate": true, "attach": true, "verify": true},
+	"sums":   {"create": true, "verify": true},
+}
+
 type qualifierList map[string]string
 
 func (q *qualifierList) String() string {
@@ -31,8 +115,29 @@ func (q *qualifierList) Set(value string) error {
 	return nil
 }
 
+// modeList collects --mode PATTERN=MODE flags, MODE being octal.
+type modeList map[string]os.FileMode
+
+func (m *modeList) String() string {
+	return fmt.Sprintf("%v", *m)
+}
+
+func (m *modeList) Set(value string) error {
+	pattern, mode, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("invalid mode format: %s (expected PATTERN=MODE)", value)
+	}
+	n, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return fmt.Errorf("invalid mode %q: expected octal such as 0755", mode)
+	}
+	(*m)[pattern] = os.FileMode(n)
+	return nil
+}
+
 func init() {
 	qualifierFlags = make(qualifierList)
+	modeFlags = make(modeList)
 }
 
 func main() {
@@ -43,26 +148,135 @@ func main() {
 
 	command := os.Args[1]
 
-	// Parse flags after command
-	fs := flag.NewFlagSet(command, flag.ExitOnError)
-	fs.StringVar(&formatFlag, "f", "text", "Output format (text, json)")
-	fs.StringVar(&formatFlag, "format", "text", "Output format (text, json)")
+	var err error
+	cfg, err = loadConfig()
+	if err == nil {
+		err = run(command, os.Args[2:])
+	}
+
+	if err != nil {
+		if jsonFlag {
+			writeError(err)
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			var parseErr *swhid.ParseError
+			if errors.As(err, &parseErr) {
+				for _, suggestion := range parseErr.Suggestions {
+					fmt.Fprintf(os.Stderr, "Hint: %s\n", suggestion)
+				}
+			}
+		}
+		var usage usageError
+		if errors.As(err, &usage) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+// run parses the flags in argv, which follow command and its subcommand
+// name, if any, and runs the command.
+func run(command string, argv []string) error {
+	// Flag errors are reported like any other, as JSON with --json.
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.BoolVar(&jsonFlag, "json", false, "Write JSON on one line, and errors as JSON on stderr")
+	fs.StringVar(&formatFlag, "f", cfg.Format, "Output format (text, json)")
+	fs.StringVar(&formatFlag, "format", cfg.Format, "Output format (text, json)")
 	fs.Var(&qualifierFlags, "q", "Add qualifier (KEY=VALUE)")
 	fs.Var(&qualifierFlags, "qualifier", "Add qualifier (KEY=VALUE)")
+	fs.BoolVar(&parseURLFlag, "parse", false, "Convert an archive, ni: or magnet: URL back into a SWHID (url command)")
+	fs.BoolVar(&stagedFlag, "staged", false, "Hash the Git index instead of the worktree (directory command)")
+	fs.StringVar(&treeFlag, "tree", "", "Take the tree REV names in the repository instead of hashing the worktree (directory command)")
+	fs.BoolVar(&forceFlag, "force", false, "Overwrite existing hooks (hook install command)")
+	fs.BoolVar(&replaceFlag, "replace-refs", false, "Apply refs/replace/* substitutions (revision, release commands)")
+	fs.BoolVar(&graftsFlag, "grafts", false, "Apply parents from .git/info/grafts (revision command)")
+	fs.StringVar(&refsFlag, "refs", "loader", "References in a snapshot: loader, all, heads (snapshot command)")
+	fs.StringVar(&headFlag, "head", "", "Ref or object the snapshot's HEAD points to (snapshot command)")
+	fs.StringVar(&remoteFlag, "remote", "", "Clone URL and compute its snapshot (snapshot command)")
+	fs.IntVar(&depthFlag, "depth", 1, "Commits to fetch per ref with --remote, 0 for full history (snapshot command)")
+	fs.BoolVar(&progressFlag, "progress", false, "Report resolved refs on stderr (snapshot command)")
+	fs.StringVar(&checkpointFlag, "checkpoint", "", "Save and resume snapshot state in FILE (snapshot command)")
+	fs.StringVar(&atFlag, "at", "", "Snapshot the references as they were at DATE, from the reflogs (snapshot command)")
+	fs.StringVar(&refsFileFlag, "refs-file", "", "Snapshot the references listed in FILE instead of the current ones (snapshot command)")
+	fs.Var(&refspecFlags, "refspec", "List only the refs fetched with SPEC, under their names in the origin (snapshot command)")
+	fs.StringVar(&branchesFromFlag, "branches-from", "", "Add the archive's branches of a snapshot SWHID or an origin's latest snapshot for refs not fetched (snapshot command)")
+	fs.StringVar(&batchFlag, "batch", "", "Snapshot every repository listed in FILE, one per line, - for stdin (snapshot command)")
+	fs.IntVar(&jobsFlag, "jobs", cfg.Concurrency, "Repositories to snapshot at once with --batch, 0 for one per CPU, to identify at once with crawl, or files to hash at once, largest first (snapshot, crawl, directory commands)")
+	fs.StringVar(&forgeURLFlag, "forge-url", "", "API of a GitHub Enterprise server or GitLab instance (crawl command)")
+	fs.BoolVar(&archiveFlag, "archive", false, "Take snapshots from the archive's latest visits where it has them (crawl command)")
+	fs.BoolVar(&noCloneFlag, "no-clone", false, "Do not clone repositories the archive has no snapshot of (crawl command)")
+	fs.BoolVar(&skipForksFlag, "skip-forks", false, "Leave out forks (crawl command)")
+	fs.BoolVar(&skipArchivedFlag, "skip-archived", false, "Leave out repositories archived on the forge (crawl command)")
+	fs.DurationVar(&intervalFlag, "interval", 0, "Least time between two requests to the forge or the archive (crawl command)")
+	fs.BoolVar(&explainFlag, "explain", false, "Show what went into the hash: branches in manifest order, or the commit or tag payload (snapshot, revision and release commands)")
+	fs.BoolVar(&estimateFlag, "estimate", false, "Count the files and bytes that would be hashed, without hashing (directory command)")
+	fs.BoolVar(&analyzeFlag, "analyze", false, "Report encoding, line endings and the SWHIDs of normalized forms (content command)")
+	fs.IntVar(&alsoVersionFlag, "also-version", 0, "Also print each SWHID in SWHID version N where it can be derived (describe command)")
+	fs.StringVar(&presetFlag, "preset", "exact", "Normalize a .tar, .tar.gz or .zip as made by: exact, gnu-tar, setuptools-sdist, github-archive (directory command)")
+	fs.StringVar(&prefixFlag, "prefix", "", "Directory to archive the files under, such as project-1.0 (export command)")
+	fs.StringVar(&specFlag, "spec", "", "Validate strictly against SWHID spec 1.0 or 1.1 (parse command)")
+	fs.BoolVar(&lenientFlag, "lenient", false, "Repair case, whitespace and punctuation before parsing (parse command)")
+	fs.StringVar(&outputFlag, "o", "", "Write to FILE (manifest, verify, attest, sums, selftest, export commands)")
+	fs.StringVar(&outputFlag, "output", "", "Write to FILE (manifest, verify, attest, sums, selftest, export commands)")
+	fs.StringVar(&dbFlag, "db", "inventory.sqlite", "SQLite inventory to create or update (index command)")
+	fs.BoolVar(&paranoidFlag, "paranoid", false, "Rehash every file instead of trusting size and mtime (index command)")
+	fs.BoolVar(&changesFlag, "changes", false, "List added, modified and removed paths (index command)")
+	fs.BoolVar(&graphOnlyFlag, "graph-only", false, "Only output identifiers, parents and commit dates, read from the commit-graph (history command)")
+	fs.Var(&includeFlags, "include", "Only hash paths matching PATTERN (directory, manifest, index, doctor, repro commands)")
+	fs.Var(&excludeFlags, "exclude", "Skip paths matching PATTERN (directory, manifest, index, doctor, repro commands)")
+	fs.BoolVar(&skipOSJunkFlag, "skip-os-junk", false, "Skip .DS_Store, ._* and other macOS and Windows metadata files (directory, manifest, index, doctor, repro commands)")
+	fs.BoolVar(&followFlag, "follow-symlinks", false, "Hash symlink targets instead of links (directory, manifest, index commands)")
+	fs.BoolVar(&noHardLinks, "no-hardlink-reuse", false, "Read every hard link separately (directory, manifest, index commands)")
+	fs.Var(&modeFlags, "mode", "Hash files matching PATTERN with octal MODE, as in '**/*.sh=0755' (directory, manifest, index, doctor, repro commands)")
+	fs.StringVar(&vcsDirsFlag, "vcs-dirs", ".git", "Comma-separated version control directories to skip, \"all\" for .git,.hg,.svn,.bzr or \"\" for none (directory, manifest, index, doctor, repro commands)")
+	fs.BoolVar(&warningsFlag, "warnings", false, "Report special files skipped, LFS pointers and other surprises on stderr (directory, manifest, index, doctor, repro commands)")
+	fs.BoolVar(&mimeFlag, "mime", false, "Record the media type of every file (manifest command)")
+	fs.BoolVar(&licensesFlag, "licenses", false, "Record the licenses found in every file (manifest command)")
+	fs.BoolVar(&noGitFlag, "no-git", false, "Take modes and contents from the filesystem only, not from the index of an enclosing Git repository (directory, manifest, index, doctor, repro commands)")
+	fs.BoolVar(&readOnlyFlag, "read-only", false, "Fail instead of writing anything to the repository or tree being hashed")
+	fs.Var(&emitFlags, "emit", "Send an event per object to an http(s) webhook URL (manifest, index commands)")
+	fs.StringVar(&signCommand, "sign-command", "", "Sign the report with CMD, reading the message on stdin (verify command)")
+	fs.BoolVar(&signFlag, "sign", false, "Sign the statement into a Sigstore bundle (attest command)")
+	fs.StringVar(&keyFlag, "key", "", "Private key to sign with, or public key to verify with (attest command)")
+	fs.StringVar(&identityTokenFlag, "identity-token", "", "OIDC token for keyless signing (attest command)")
+	fs.StringVar(&fulcioURLFlag, "fulcio-url", attest.DefaultFulcioURL, "Fulcio instance for keyless signing (attest command)")
+	fs.StringVar(&rekorURLFlag, "rekor-url", attest.DefaultRekorURL, "Rekor instance for keyless signing (attest command)")
+	fs.StringVar(&rekorKeyFlag, "rekor-key", "", "Rekor public key checking log entries (attest verify command)")
+	fs.StringVar(&certChainFlag, "certificate-chain", "", "Fulcio root and intermediate certificates (attest verify command)")
+	fs.StringVar(&certIdentityFlag, "certificate-identity", "", "Signer email or URI required for keyless bundles (attest verify command)")
+	fs.StringVar(&certIssuerFlag, "certificate-oidc-issuer", "", "OIDC issuer required for keyless bundles (attest verify command)")
+	fs.BoolVar(&provenanceFlag, "provenance", false, "Show SWHIDs embedded at build time (version command)")
+	fs.BoolVar(&ldflagsFlag, "ldflags", false, "Print linker flags embedding the SWHIDs of a repository's HEAD and its tree (version command)")
 
-	// Skip the command name when parsing
-	if len(os.Args) > 2 {
-		fs.Parse(os.Args[2:])
+	// Skip the command name, and a subcommand name such as "verify" in
+	// "swhid attest verify --key k.pub bundle.json", when parsing
+	rest := argv
+	var sub []string
+	if len(rest) > 0 && subcommands[command][rest[0]] {
+		sub, rest = rest[:1], rest[1:]
+	}
+	positional, err := parseInterspersed(fs, rest)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			showHelp()
+			return nil
+		}
+		return usageError{err}
+	}
+	if jsonFlag {
+		formatFlag = "json"
 	}
 
-	args := fs.Args()
+	args := append(sub, positional...)
 
-	var err error
 	switch command {
 	case "parse":
 		err = runParse(args)
 	case "content":
-		err = runContent()
+		err = runContent(args)
+	case "describe":
+		err = runDescribe(args)
 	case "directory":
 		err = runDirectory(args)
 	case "revision":
@@ -71,15 +285,87 @@ func main() {
 		err = runRelease(args)
 	case "snapshot":
 		err = runSnapshot(args)
+	case "crawl":
+		err = runCrawl(args)
+	case "url":
+		err = runURL(args)
+	case "gitoid":
+		err = runGitoid(args)
+	case "cite":
+		err = runCite(args)
+	case "visits":
+		err = runVisits(args)
+	case "auth":
+		err = runAuth(args)
+	case "version":
+		err = runVersion(args)
+	case "hook":
+		err = runHook(args)
+	case "history":
+		err = runHistory(args)
+	case "graph":
+		err = runGraph(args)
+	case "manifest":
+		err = runManifest(args)
+	case "index":
+		err = runIndex(args)
+	case "verify":
+		err = runVerify(args)
+	case "doctor":
+		err = runDoctor(args)
+	case "reconcile":
+		err = runReconcile(args)
+	case "repro":
+		err = runRepro(args)
+	case "github-zip":
+		err = runGitHubZip(args)
+	case "selftest":
+		err = runSelftest(args)
+	case "attest":
+		err = runAttest(args)
+	case "sums":
+		err = runSums(args)
+	case "export":
+		err = runExport(args)
+	case "extract":
+		err = runExtract(args)
+	case "oci":
+		err = runOCI(args)
+	case "eval":
+		err = runEval(args)
+	case "run":
+		err = runPipeline(args)
 	case "help", "-h", "--help":
 		showHelp()
 	default:
+		if jsonFlag {
+			return usageError{fmt.Errorf("unknown command %q", command)}
+		}
 		showHelp()
 	}
+	return err
+}
 
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+// parseInterspersed parses the flags in args wherever they appear, so that
+// "swhid revision . HEAD --json" reads --json rather than taking it for an
+// argument, and returns the remaining arguments in order. Everything after
+// "--" is an argument.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		consumed := args[:len(args)-fs.NArg()]
+		args = fs.Args()
+		if len(consumed) > 0 && consumed[len(consumed)-1] == "--" {
+			return append(positional, args...), nil
+		}
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
 	}
 }
 
@@ -88,7 +374,24 @@ func runParse(args []string) error {
 		return fmt.Errorf("SWHID string required")
 	}
 
-	id, err := swhid.Parse(args[0])
+	var id *swhid.Identifier
+	var err error
+	switch {
+	case lenientFlag:
+		var fixes []swhid.LenientFix
+		id, fixes, err = swhid.ParseLenient(args[0])
+		for _, fix := range fixes {
+			fmt.Fprintf(os.Stderr, "Fixed: %s\n", fix)
+		}
+	case specFlag == "":
+		id, err = swhid.Parse(args[0])
+	case specFlag == "1.0":
+		id, err = swhid.ParseStrict(args[0], swhid.SpecV1_0)
+	case specFlag == "1.1":
+		id, err = swhid.ParseStrict(args[0], swhid.SpecV1_1)
+	default:
+		return fmt.Errorf("unknown spec version %q (expected 1.0 or 1.1)", specFlag)
+	}
 	if err != nil {
 		return err
 	}
@@ -97,12 +400,44 @@ func runParse(args []string) error {
 	return nil
 }
 
-func runContent() error {
+func runContent(args []string) error {
+	if analyzeFlag && len(args) > 0 {
+		return fmt.Errorf("--analyze reads content from stdin")
+	}
+	if len(args) > 0 {
+		if len(args) < 3 {
+			return fmt.Errorf("repository path, ref and file path required")
+		}
+		id, err := swhid.FromGitBlob(args[0], args[1], args[2])
+		if err != nil {
+			return err
+		}
+		outputIdentifier(applyQualifiers(id))
+		return nil
+	}
+
+	// A large file redirected to stdin is streamed rather than read into
+	// memory, from wherever stdin is positioned in it.
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode().IsRegular() && !analyzeFlag {
+		if offset, err := os.Stdin.Seek(0, io.SeekCurrent); err == nil && info.Size()-offset >= swhid.StreamThreshold {
+			id, err := swhid.FromContentReader(os.Stdin, info.Size()-offset)
+			if err != nil {
+				return fmt.Errorf("failed to read stdin: %w", err)
+			}
+			outputIdentifier(applyQualifiers(id))
+			return nil
+		}
+	}
+
 	data, err := io.ReadAll(os.Stdin)
 	if err != nil {
 		return fmt.Errorf("failed to read stdin: %w", err)
 	}
 
+	if analyzeFlag {
+		return runAnalyzeContent(data)
+	}
+
 	id := swhid.FromContent(data)
 	id = applyQualifiers(id)
 	outputIdentifier(id)
@@ -116,20 +451,64 @@ func runDirectory(args []string) error {
 
 	path := args[0]
 
+	if treeFlag != "" {
+		id, err := swhid.FromGitTree(path, treeFlag)
+		if err != nil {
+			return err
+		}
+		id = applyQualifiers(id)
+		outputIdentifier(id)
+		return nil
+	}
+
+	if stagedFlag {
+		id, err := swhid.FromGitIndex(path)
+		if err != nil {
+			return err
+		}
+		id = applyQualifiers(id)
+		outputIdentifier(id)
+		return nil
+	}
+
 	info, err := os.Stat(path)
 	if err != nil {
 		return fmt.Errorf("path does not exist: %s", path)
 	}
 	if !info.IsDir() {
-		return fmt.Errorf("path is not a directory: %s", path)
+		if _, err := archiveFormat(path); err != nil {
+			return fmt.Errorf("path is not a directory or archive: %s", path)
+		}
+		id, err := hashArchive(path, presetFlag)
+		if err != nil {
+			return err
+		}
+		outputIdentifier(applyQualifiers(id))
+		return nil
 	}
 
-	id, err := swhid.FromDirectoryPath(path)
+	if estimateFlag {
+		estimate, err := swhid.EstimateWork(path, treeOptions())
+		if err != nil {
+			return err
+		}
+		if formatFlag == "json" {
+			return writeJSON(map[string]interface{}{
+				"files":  estimate.Files,
+				"bytes":  estimate.Bytes,
+				"cached": estimate.Cached,
+			})
+		}
+		fmt.Printf("%d files, %d bytes to hash\n", estimate.Files, estimate.Bytes)
+		return nil
+	}
+
+	node, err := swhid.TreeFromDirectoryPathWithOptions(path, treeOptions())
 	if err != nil {
 		return err
 	}
 
-	id = applyQualifiers(id)
+	id := applyQualifiers(node.ID)
 	outputIdentifier(id)
 	return nil
 }
@@ -145,7 +524,15 @@ func runRevision(args []string) error {
 		ref = args[1]
 	}
 
-	id, err := swhid.FromRevision(repoPath, ref)
+	opts, err := gitOptions()
+	if err != nil {
+		return err
+	}
+	if explainFlag {
+		return runExplainRevision(repoPath, ref, opts)
+	}
+
+	id, err := swhid.FromRevisionWithOptions(repoPath, ref, opts)
 	if err != nil {
 		return err
 	}
@@ -163,7 +550,16 @@ func runRelease(args []string) error {
 	repoPath := args[0]
 	tagName := args[1]
 
-	id, err := swhid.FromRelease(repoPath, tagName)
+	opts, err := gitOptions()
+	if err != nil {
+		return err
+	}
+
+	if explainFlag {
+		return runExplainRelease(repoPath, tagName, opts)
+	}
+
+	id, err := swhid.FromReleaseWithOptions(repoPath, tagName, opts)
 	if err != nil {
 		return err
 	}
@@ -174,13 +570,44 @@ func runRelease(args []string) error {
 }
 
 func runSnapshot(args []string) error {
+	if explainFlag && (remoteFlag != "" || batchFlag != "") {
+		return fmt.Errorf("--explain cannot be combined with --remote or --batch")
+	}
+	if remoteFlag != "" {
+		return runRemoteSnapshot(remoteFlag)
+	}
+	if batchFlag != "" {
+		return runBatchSnapshot(batchFlag)
+	}
+
 	if len(args) < 1 {
 		return fmt.Errorf("repository path required")
 	}
 
 	repoPath := args[0]
 
-	id, err := swhid.FromSnapshot(repoPath)
+	opts, err := gitOptions()
+	if err != nil {
+		return err
+	}
+	if explainFlag {
+		return runExplainSnapshot(repoPath, opts)
+	}
+
+	var id *swhid.Identifier
+	if info, statErr := os.Stat(repoPath); statErr == nil && info.Mode().IsRegular() {
+		session, err := swhid.OpenBundleWithOptions(repoPath, opts)
+		if err != nil {
+			return err
+		}
+		id, err = session.Snapshot()
+	} else if atFlag != "" || refsFileFlag != "" {
+		id, err = historicalSnapshot(repoPath, opts)
+	} else if progressFlag || checkpointFlag != "" {
+		id, err = runStreamSnapshot(repoPath, opts)
+	} else {
+		id, err = swhid.FromSnapshotWithOptions(repoPath, opts)
+	}
 	if err != nil {
 		return err
 	}
@@ -190,12 +617,179 @@ func runSnapshot(args []string) error {
 	return nil
 }
 
+func runRemoteSnapshot(url string) error {
+	opts, err := gitOptions()
+	if err != nil {
+		return err
+	}
+
+	// Cancel the clone on interrupt so its temporary directory is removed.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	result, err := swhid.CloneAndSnapshot(ctx, url, swhid.CloneOptions{
+		GitOptions: opts,
+		Depth:      depthFlag,
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, ok := qualifierFlags["origin"]; !ok {
+		qualifierFlags["origin"] = url
+	}
+	outputIdentifier(applyQualifiers(result.Snapshot))
+	if result.Head != nil && formatFlag != "json" {
+		fmt.Printf("HEAD:  %s\n", result.Head)
+	}
+	return nil
+}
+
+// treeOptions returns the options for hashing a directory: the configured
+// exclude patterns plus the --include, --exclude, --skip-os-junk,
+// --follow-symlinks, --no-hardlink-reuse, --no-git, --read-only and
+// --warnings flags.
+func treeOptions() swhid.TreeOptions {
+	opts := swhid.TreeOptions{
+		Include:         includeFlags,
+		Exclude:         append(append([]string(nil), cfg.Exclude...), excludeFlags...),
+		SkipOSJunk:      skipOSJunkFlag,
+		FollowSymlinks:  followFlag,
+		NoHardLinkReuse: noHardLinks,
+		Modes:           modeFlags,
+		VCSDirs:         vcsDirs(),
+		NoGitIndex:      noGitFlag,
+		ReadOnlyFS:      readOnlyFlag,
+		Concurrency:     jobsFlag,
+		DetectMIME:      mimeFlag,
+	}
+	if warningsFlag {
+		opts.Warn = writeWarning
+	}
+	return opts
+}
+
+// vcsDirs returns the names given with --vcs-dirs; the empty list, not
+// nil, when there are none, so that nothing is skipped.
+func vcsDirs() []string {
+	if vcsDirsFlag == "all" {
+		return swhid.AllVCSDirs
+	}
+	dirs := []string{}
+	for _, name := range strings.Split(vcsDirsFlag, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			dirs = append(dirs, name)
+		}
+	}
+	return dirs
+}
+
+func gitOptions() (swhid.GitOptions, error) {
+	refs, err := swhid.ParseRefPolicy(refsFlag)
+	if err != nil {
+		return swhid.GitOptions{}, err
+	}
+
+	var branches []objects.Branch
+	if branchesFromFlag != "" {
+		if branches, err = archiveBranches(branchesFromFlag); err != nil {
+			return swhid.GitOptions{}, err
+		}
+	}
+
+	return swhid.GitOptions{
+		ReplaceRefs: replaceFlag,
+		Grafts:      graftsFlag,
+		Refs:        refs,
+		Refspecs:    refspecFlags,
+		Branches:    branches,
+		Head:        headFlag,
+		ReadOnlyFS:  readOnlyFlag,
+	}, nil
+}
+
+// archiveBranches fetches the branches of a snapshot from the archive:
+// the snapshot named by a SWHID, or the latest one of an origin URL.
+func archiveBranches(source string) ([]objects.Branch, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	token, _ := apiToken()
+	client := &archive.Client{Token: token}
+	snapshot, err := swhid.Parse(source)
+	if err != nil {
+		if snapshot, err = client.LatestSnapshot(ctx, source); err != nil {
+			return nil, err
+		}
+	}
+	return client.SnapshotBranches(ctx, snapshot)
+}
+
+func runURL(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("SWHID or URL required")
+	}
+
+	if parseURLFlag {
+		parse := swhid.ParseArchiveURL
+		switch {
+		case strings.HasPrefix(args[0], "ni:"):
+			parse = swhid.ParseNamedInformationURI
+		case strings.HasPrefix(args[0], "magnet:"):
+			parse = swhid.ParseMagnetURI
+		case strings.HasPrefix(args[0], swhid.Scheme+":") || !strings.Contains(args[0], "://"):
+			parse = swhid.ParseURLForm
+		}
+		id, err := parse(args[0])
+		if err != nil {
+			return err
+		}
+		outputIdentifier(id)
+		return nil
+	}
+
+	id, err := swhid.Parse(args[0])
+	if err != nil {
+		return err
+	}
+
+	switch formatFlag {
+	case "json":
+		data := map[string]interface{}{
+			"swhid":   id.String(),
+			"resolve": id.ResolveURL(),
+			"browse":  id.QualifiedBrowseURL(),
+			"api":     id.APIURL(),
+			"ni":      id.NamedInformationURI(),
+			"magnet":  id.MagnetURI(),
+		}
+		if vault := id.VaultURL(); vault != "" {
+			data["vault"] = vault
+		}
+		return writeJSON(data)
+	default:
+		fmt.Printf("SWHID:   %s\n", id.String())
+		fmt.Printf("Resolve: %s\n", id.ResolveURL())
+		fmt.Printf("Browse:  %s\n", id.QualifiedBrowseURL())
+		fmt.Printf("API:     %s\n", id.APIURL())
+		fmt.Printf("NI:      %s\n", id.NamedInformationURI())
+		fmt.Printf("Magnet:  %s\n", id.MagnetURI())
+		if vault := id.VaultURL(); vault != "" {
+			fmt.Printf("Vault:   %s\n", vault)
+		}
+	}
+	return nil
+}
+
 func applyQualifiers(id *swhid.Identifier) *swhid.Identifier {
-	if len(qualifierFlags) == 0 {
+	if len(qualifierFlags) == 0 && cfg.Origin == "" {
 		return id
 	}
 
 	quals := make(map[string]string)
+	if cfg.Origin != "" {
+		quals["origin"] = cfg.Origin
+	}
 	for k, v := range qualifierFlags {
 		quals[k] = v
 	}
@@ -219,24 +813,43 @@ func outputText(id *swhid.Identifier) {
 
 	if len(id.Qualifiers) > 0 {
 		fmt.Println("Qualifiers:")
-		for key, value := range id.Qualifiers {
-			fmt.Printf("  %s: %s\n", key, value)
+		for _, key := range qualifierKeys(id) {
+			fmt.Printf("  %s: %s\n", key, id.Qualifiers[key])
 		}
 	}
 }
 
+// qualifierKeys returns the keys of id's qualifiers in the order String
+// writes them: those of the specification in its order, then the rest
+// sorted.
+func qualifierKeys(id *swhid.Identifier) []string {
+	var keys, others []string
+	for _, key := range swhid.CanonicalQualifierOrder() {
+		if _, ok := id.Qualifiers[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	for key := range id.Qualifiers {
+		if !slices.Contains(keys, key) {
+			others = append(others, key)
+		}
+	}
+	sort.Strings(others)
+	return append(keys, others...)
+}
+
 func outputJSON(id *swhid.Identifier) {
-	data := map[string]interface{}{
+	qualifiers := id.Qualifiers
+	if qualifiers == nil {
+		qualifiers = map[string]string{}
+	}
+	writeJSON(map[string]interface{}{
 		"swhid":       id.String(),
 		"core":        id.CoreSWHID(),
 		"object_type": id.ObjectType,
 		"object_hash": id.ObjectHash,
-		"qualifiers":  id.Qualifiers,
-	}
-
-	encoder := json.NewEncoder(os.Stdout)
-	encoder.SetIndent("", "  ")
-	encoder.Encode(data)
+		"qualifiers":  qualifiers,
+	})
 }
 
 func showHelp() {
@@ -245,16 +858,237 @@ func showHelp() {
 Usage:
   swhid parse <swhid>                   Parse and validate a SWHID
   swhid content [options]               Generate SWHID for content from stdin
+  swhid content <repo> <ref> <path>     Generate SWHID for a file at a revision from
+                                        its blob id, without reading the file
+  swhid content --analyze               Report the encoding and line endings of content
+                                        from stdin and the SWHIDs it would have after
+                                        normalizing them
+  swhid describe [path]                 Show every SWHID of a file, directory or repository:
+                                        content or directory, HEAD revision, snapshot,
+                                        origin and the fully qualified SWHID
   swhid directory <path> [options]      Generate SWHID for directory
+  swhid directory --staged <repo>       Generate SWHID for the staged Git index
+  swhid directory --tree <rev> <repo>   Generate SWHID for a committed tree, such as
+                                        HEAD~3:src or stash@{0}^3
+  swhid directory --estimate <path>     Count the files and bytes hashing would read
+  swhid directory [--preset P] <archive>
+                                        Generate SWHID for the tree in a .tar, .tar.gz
+                                        or .zip, normalized for its producer
   swhid revision <repo> [ref] [options] Generate SWHID for git revision/commit
+  swhid revision --explain <repo> [ref] Show the commit payload hashed, byte for byte
   swhid release <repo> <tag> [options]  Generate SWHID for git release/tag
+  swhid release --explain <repo> <tag>  Show the tag's target, tagger, message and
+                                        signature, and the payload hashed
   swhid snapshot <repo> [options]       Generate SWHID for git snapshot
+  swhid snapshot <file.bundle>          Generate SWHID for a git bundle's snapshot
+  swhid snapshot --remote <url>         Clone a remote and generate its snapshot SWHID
+  swhid snapshot --at <date> <repo>     Generate the snapshot SWHID as of a past date
+  swhid snapshot --batch <file>         Generate snapshot SWHIDs for every repository listed
+  swhid snapshot --explain <repo>       List the branches that went into the snapshot SWHID
+  swhid crawl <github|gitlab> <namespace>
+                                        Snapshot SWHIDs of every repository of an
+                                        organization, user or group, from the archive
+                                        or shallow clones
+  swhid history <repo>                  Stream NDJSON of every commit's SWHIDs
+  swhid history --graph-only <repo>     Stream only SWHIDs, parents and commit dates
+  swhid graph <repo> [outdir]           Export nodes/edges CSV in swh-graph layout
+  swhid manifest <path> [-o FILE]       List path, type, SWHID and size of every object
+  swhid index <path> [--db FILE]        Create or update a SQLite inventory of a directory
+  swhid verify <swhid> <path> [ref]     Recompute a SWHID and report whether it matches
+  swhid doctor <path> [swhid]           Explain why a directory SWHID may differ from the
+                                        archive's (uncommitted, ignored or excluded files,
+                                        CRLF, LFS, submodules, shallow clones)
+  swhid github-zip <zip> [repo [rev]]   Generate SWHID for the tree in a GitHub "Download
+                                        ZIP" and, with a clone, check it against the commit,
+                                        listing type (T), content (M), missing (D) and extra
+                                        (A) entries
+  swhid reconcile <path> <dir-swhid>    List entries added (A), changed (M) or removed (D)
+                                        locally relative to an archived directory
+  swhid repro <pathA> <pathB>           Compare two checkouts or build outputs and report
+                                        where they diverge (mode, line endings, line order,
+                                        content, missing entries)
+  swhid attest <path>... [--sign]       Write an in-toto statement of paths' SWHIDs, signed
+                                        with --key or keyless through Sigstore
+  swhid attest verify <bundle> [path]   Check a signed statement and that paths still match
+  swhid sums create <path>... [-o FILE] Write "<swhid>  <path>" lines, like sha256sum
+  swhid sums verify [SWHIDSUMS]         Check the paths listed in a SWHIDSUMS file
+  swhid export <dir> -o FILE            Archive a directory as .tar, .tar.gz or .zip with
+                                        its SWHID recorded in the archive
+  swhid export verify <archive>         Check an archive against the SWHID recorded in it
+  swhid oci annotate <image> [dir]      Add the SWHIDs of an image's source directory
+                                        to its manifest annotations
+  swhid oci attach <image> [dir]        Push the source as a referrer artifact of an image,
+                                        annotated with its SWHIDs
+  swhid oci verify <image>              Recompute the SWHIDs of an image's attached source
+  swhid extract [file]...               List the SWHIDs in text, PDF or DOCX documents,
+                                        repairing broken ones and reporting invalid ones
+  swhid eval < request.json             Run the command a JSON request names and write its
+                                        JSON result on one line, for tools shelling out
+  swhid run <pipeline.yaml>             Run a provenance pipeline: compute, verify, qualify
+                                        and publish the SWHIDs of its inputs, and write its
+                                        manifest and attestation
+  swhid url <swhid> [options]           Print archive URLs for a SWHID
+  swhid url --parse <url>               Convert an archive, ni: or magnet: URL, or a
+                                        SWHID copied from an address bar, into a SWHID
+  swhid cite <swhid>                    Show archive metadata and DOIs recorded for an object
+  swhid cite <doi>                      Find archived snapshots of origins citing a DOI
+  swhid visits <origin-url>             List archive visits of an origin and their snapshots
+  swhid gitoid <swhid|gitoid>           Convert between SWHIDs and gitoid/OmniBOR URIs
+  swhid gitoid < file                   Print the sha1 and sha256 blob gitoids of stdin
+  swhid auth login [token]              Store a SWH API token in the system keyring
+  swhid auth status                     Show which API token is in use
+  swhid auth logout                     Remove the stored API token
+  swhid hook install [repo] [--force]   Install git hooks recording and checking SWHIDs
+  swhid version [--provenance]          Show version and embedded source SWHIDs
+  swhid selftest [dir]                  Check known-answer vectors and the filesystem of
+                                        dir (default: temp dir), writing a JSON report
+  swhid version --ldflags [repo]        Print -ldflags embedding HEAD's SWHIDs
+
+Options may come before or after the arguments; arguments after -- are
+never read as options.
 
 Options:
   -f, --format FORMAT              Output format (text, json)
+      --json                       Machine mode: JSON output on one line for every
+                                   command, and errors as a JSON object on stderr
+                                   with "error", "code" and "suggestions"
   -q, --qualifier KEY=VALUE        Add qualifier to generated SWHID
+      --staged                     Hash what is staged in the Git index
+      --tree REV                   Take the tree REV names (any git rev-parse tree-ish)
+      --parse                      Treat the url argument as an archive, ni: or magnet: URL
+      --spec VERSION               Validate qualifiers strictly against spec 1.0 or 1.1
+      --lenient                    Accept pasted SWHIDs with stray case, spaces or punctuation
+      --force                      Overwrite hooks not written by swhid
+      --replace-refs               Hash refs/replace/* substitutes instead of the true objects
+      --grafts                     Hash commits with parents from .git/info/grafts
+      --refs POLICY                References in a snapshot: loader (default; every ref
+                                   the SWH git loader archives, including refs/notes/*,
+                                   but not refs/pull/*/merge), all, or heads (HEAD,
+                                   refs/heads/*, refs/tags/* only)
+      --head REF                   Point the snapshot's HEAD at REF (a ref name or
+                                   commit hash) instead of the repository's HEAD
+      --remote URL                 Snapshot a remote via a temporary bare clone
+      --progress                   Report snapshot progress on stderr
+      --batch FILE                 Snapshot each repository or bundle listed in FILE
+                                   (one path per line, - for stdin)
+      --jobs N                     Repositories snapshotted at once with --batch
+                                   (default one per CPU), or identified at once by
+                                   crawl (default 4); concurrency in the config
+                                   file or SWHID_CONCURRENCY sets the default
+      --forge-url URL              API base of a GitHub Enterprise server or a GitLab
+                                   instance for crawl (tokens are read from
+                                   GITHUB_TOKEN and GITLAB_TOKEN)
+      --archive                    With crawl, take each repository's snapshot from
+                                   the archive's latest visit when there is one
+      --no-clone                   With crawl, leave repositories the archive lacks
+                                   unidentified instead of cloning them
+      --skip-forks                 Leave forks out of a crawl
+      --skip-archived              Leave repositories archived on the forge out of a crawl
+      --interval DURATION          Least time between requests to the forge, and to
+                                   the archive, in a crawl (rate limit responses are
+                                   always waited out)
+      --explain                    Show what went into the hash: every branch of a
+                                   snapshot in manifest order with its target, the
+                                   escaped commit payload of a revision, or the fields
+                                   and payload of a release
+      --checkpoint FILE            Save snapshot state to FILE and resume from it
+      --at DATE                    Snapshot the refs as of DATE (RFC 3339, YYYY-MM-DD or
+                                   unix seconds), reconstructed from the reflogs
+      --refs-file FILE             Snapshot the refs recorded in FILE (git show-ref,
+                                   for-each-ref, ls-remote --symref or packed-refs
+                                   output) instead of the current ones
+      --refspec SPEC               List only the refs fetched with SPEC, named as in the
+                                   origin: with +refs/heads/*:refs/remotes/origin/*,
+                                   refs/remotes/origin/main is refs/heads/main
+      --branches-from SWHID|URL    Add the branches of an archived snapshot, or of an
+                                   origin's latest one, for refs not fetched locally
+      --depth N                    Commits fetched per ref with --remote (default 1,
+                                   0 for full history)
+  -o, --output FILE                Write the NDJSON manifest to FILE (default stdout),
+                                   or the verify report, attest output, SWHIDSUMS
+                                   lines or selftest report
+      --db FILE                    SQLite inventory for the index command
+                                   (default inventory.sqlite)
+      --paranoid                   Rehash every file on index, instead of reusing the
+                                   SWHIDs of files with unchanged size and mtime
+      --changes                    List paths added (A), modified (M) and removed (D)
+                                   since the previous index run
+      --graph-only                 Limit history output to SWHIDs, parents and commit
+                                   dates, read from .git/objects/info/commit-graph
+                                   when present instead of decoding every commit
+      --include PATTERN            Only hash entries matching PATTERN and what is below
+                                   them; repeatable
+      --exclude PATTERN            Skip entries matching PATTERN without reading them;
+                                   repeatable, added to the configured exclude list.
+                                   Patterns without a slash match names at any depth
+                                   ("*.log"), others match paths from the root
+      --skip-os-junk               Skip .DS_Store, AppleDouble ._* files, __MACOSX,
+                                   Thumbs.db and other operating system metadata, so
+                                   trees copied through Finder or USB sticks hash the same
+      --warnings                   Report on stderr what hashing a directory did
+                                   without failing: special files skipped, Git LFS
+                                   pointers hashed as is, untracked files of a
+                                   checkout taking their mode from disk, dangling
+                                   links kept with --follow-symlinks
+      --preset NAME                Normalize an archive given to directory as its
+                                   producer made it: exact (default), gnu-tar,
+                                   setuptools-sdist (drops the top-level directory,
+                                   PKG-INFO and *.egg-info) or github-archive (drops
+                                   the repo-ref/ directory), to match the tree's SWHID
+      --follow-symlinks            Hash what symlinks point to instead of the links;
+                                   symlink loops are reported as errors
+      --no-hardlink-reuse          Read and hash every hard link to a file; by default
+                                   a file with several links is hashed once
+      --mime                       Add each file's media type, detected from its first
+                                   bytes while it is hashed, to manifest NDJSON lines
+      --licenses                   Add the licenses found in each file (SPDX tags and
+                                   common license texts), scanned while it is hashed,
+                                   to manifest NDJSON lines
+      --no-git                     Take executable bits from the filesystem only, even
+                                   for files the index of an enclosing Git repository
+                                   tracks, and read every file rather than trust the
+                                   index for unchanged ones; for vendored trees inside
+                                   a repository
+      --read-only                  Open repositories so that any write to them, even a
+                                   lock file, fails; for read-only mounted archives
+      --emit URL                   Send an event per object computed by manifest or
+                                   index to an http(s) webhook (NDJSON batches);
+                                   repeatable
+      --sign-command CMD           Sign the verify report with CMD, which reads the
+                                   message on stdin and writes the signature to stdout;
+                                   the report becomes a DSSE envelope
+      --sign                       Sign the attest statement into a Sigstore bundle: with
+                                   --key FILE (PEM or cosign key; COSIGN_PASSWORD
+                                   decrypts it), or keyless with a Fulcio certificate
+                                   for --identity-token (or SIGSTORE_ID_TOKEN), recorded
+                                   in Rekor (--fulcio-url, --rekor-url)
+      --key FILE                   Public key that attest verify checks bundles with
+      --certificate-chain FILE     Fulcio root (and intermediate) certificates, and
+      --rekor-key FILE             the Rekor public key, for keyless bundles
+      --certificate-identity ID    Email or URI the signing certificate must name
+      --certificate-oidc-issuer URL  OIDC issuer the signing certificate must name
+      --provenance                 Show source SWHIDs embedded in this binary
+      --ldflags                    Print linker flags for embedding source SWHIDs
   -h, --help                       Show this help
 
+Configuration:
+  Defaults are read from ~/.config/swhid/config.toml (or $SWHID_CONFIG):
+
+    api_token = "..."
+    origin = "https://github.com/example/repo"
+    exclude = ["node_modules", "*.log"]
+    format = "json"
+    concurrency = 8
+
+    [hooks]
+    trailer = "Source-SWHID"              # commit trailer added by prepare-commit-msg
+    releases_file = ".swhid-releases"     # "TAG SWHID" lines checked by pre-push
+
+  Environment variables override the file: SWHID_API_TOKEN, SWHID_ORIGIN,
+  SWHID_EXCLUDE (comma-separated), SWHID_FORMAT, SWHID_CONCURRENCY.
+  Command-line flags override both. Without an api_token setting, the
+  token stored by "swhid auth login" in the system keyring is used.
+
 Examples:
   # Parse a SWHID
   swhid parse swh:1:cnt:94a9ed024d3859793618152ea559a168bbcbb5e2
@@ -262,13 +1096,48 @@ Examples:
   # Generate SWHID from file content
   cat file.txt | swhid content
 
+  # Why a file hashes differently from the archived copy: its encoding,
+  # line endings and the SWHIDs of its normalized forms
+  swhid content --analyze < file.txt
+
+  # All SWHIDs of a committed file, including the one qualified with its
+  # origin, anchor revision and path
+  swhid describe src/main.go
+
+  # Snapshot, HEAD revision and root directory of a repository
+  swhid describe /path/to/repo
+
+  # Print each SWHID in a later SWHID version too, where it can be derived
+  swhid describe --also-version 2 src/main.go
+
   # Generate SWHID from directory
   swhid directory /path/to/dir
 
+  # Hash up to 8 files at once, largest first
+  swhid directory --jobs 8 /path/to/dir
+
+  # How much hashing a directory would read, without reading it
+  swhid directory --estimate /path/to/dir
+
+  # Executable bits from disk even inside a Git checkout, ignoring its index
+  swhid directory --no-git vendor/lib
+
+  # SWHID of the tree a GitHub tarball was made from, as for the tagged commit
+  swhid directory --preset github-archive project-1.0.tar.gz
+
+  # Why a GitHub "Download ZIP" hashes differently from the commit it was
+  # made from: its SWHID, checked against the commit GitHub recorded in it
+  swhid github-zip repo-main.zip /path/to/repo
+
+  # Directory SWHID of a committed tree, without checking it out
+  swhid directory --tree v1.0.0:src /path/to/repo
+
   # Generate SWHID from git commit
   swhid revision /path/to/repo
   swhid revision /path/to/repo main
   swhid revision /path/to/repo abc123
+  swhid revision /path/to/repo 'HEAD~3^2'
+  swhid revision /path/to/repo 'stash@{0}'
 
   # Generate SWHID from git tag
   swhid release /path/to/repo v1.0.0
@@ -276,12 +1145,64 @@ Examples:
   # Generate SWHID from git snapshot
   swhid snapshot /path/to/repo
 
+  # Snapshot of a CI checkout that fetched only main, completed with the
+  # archive's branches for the rest of the origin
+  swhid snapshot --head refs/heads/main --refspec '+refs/heads/main:refs/remotes/origin/main' \
+    --branches-from https://github.com/example/repo .
+
+  # See which branches, in which order, make up the snapshot SWHID
+  swhid snapshot --explain /path/to/repo
+
+  # See the exact commit payload behind a revision SWHID, extra headers marked
+  swhid revision --explain /path/to/repo HEAD
+
+  # What a tag points to, who made it, and whether it is signed
+  swhid release --explain /path/to/repo v1.0.0
+
+  # Check a release tarball's tree against its published SWHID
+  swhid verify -f json swh:1:dir:d198bc9d7a6bcf6db04f476d29314f157507d505 ./release
+
+  # Find out why a checkout's SWHID differs from the archived one
+  swhid doctor /path/to/repo swh:1:dir:d198bc9d7a6bcf6db04f476d29314f157507d505
+
+  # Audit which files of a directory are missing from or differ in its archived copy
+  swhid reconcile ./release swh:1:dir:d198bc9d7a6bcf6db04f476d29314f157507d505
+
+  # Find why two builds of the same source are not bit-for-bit identical
+  swhid repro ./build-a ./build-b
+
+  # Record a container image's source: annotate the image before it is
+  # published, or attach the source to an already published one
+  swhid oci annotate oci:build/image:latest .
+  swhid oci attach ghcr.io/example/app:1.0 .
+  swhid oci verify ghcr.io/example/app:1.0
+
+  # Find the snapshots an origin was archived under
+  swhid visits https://github.com/example/repo
+
+  # Inventory an organization's repositories, from the archive where it has
+  # them, cloning the rest
+  swhid crawl --archive --skip-forks -f json -o inventory.json github example
+
+  # Show archive URLs for a SWHID, and go back from a URL
+  swhid url swh:1:dir:d198bc9d7a6bcf6db04f476d29314f157507d505
+  swhid url --parse https://archive.softwareheritage.org/browse/directory/d198bc9d7a6bcf6db04f476d29314f157507d505/
+
   # Generate SWHID with qualifiers
   cat file.txt | swhid content -q origin=https://github.com/example/repo
 
   # Output as JSON
   swhid parse swh:1:cnt:94a9ed024d3859793618152ea559a168bbcbb5e2 -f json
 
+  # One-line JSON for scripts, and the same through a JSON request, with
+  # the result flattened to strings as Terraform's external data source
+  # expects
+  swhid directory --json ./src
+  echo '{"command":"directory","args":"[\"./src\"]","exclude":"*.log","flat":"true"}' | swhid eval
+
+  # A recurring provenance job described in YAML instead of a script
+  swhid run provenance.yaml
+
 For more information, visit: https://www.swhid.org/
 `)
 }
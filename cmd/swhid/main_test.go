@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// runCommand runs command with argv as main does, with the defaults of a
+// missing config file and the repeatable flags of earlier runs cleared,
+// and returns what it wrote to stdout.
+func runCommand(t *testing.T, command string, argv ...string) (string, error) {
+	t.Helper()
+	t.Setenv("SWHID_CONFIG", filepath.Join(t.TempDir(), "config.toml"))
+	var err error
+	if cfg, err = loadConfig(); err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	qualifierFlags = make(qualifierList)
+	modeFlags = make(modeList)
+	emitFlags, includeFlags, excludeFlags, refspecFlags = nil, nil, nil, nil
+
+	out, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	stdout := os.Stdout
+	os.Stdout = out
+	runErr := run(command, argv)
+	os.Stdout = stdout
+
+	if _, err := out.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data), runErr
+}
+
+// newTestRepo creates a repository with one commit of hello.txt.
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("PlainInit() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Add("hello.txt"); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	sig := &object.Signature{Name: "Test", Email: "test@example.com", When: time.Unix(1000000000, 0).UTC()}
+	if _, err := wt.Commit("Initial commit\n", &git.CommitOptions{Author: sig, Committer: sig}); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	return dir
+}
+
+func TestParseInterspersed(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+		json bool
+		out  string
+	}{
+		{[]string{"--json", ".", "HEAD"}, []string{".", "HEAD"}, true, ""},
+		{[]string{".", "HEAD", "--json"}, []string{".", "HEAD"}, true, ""},
+		{[]string{".", "-o", "out.txt", "HEAD"}, []string{".", "HEAD"}, false, "out.txt"},
+		{[]string{"-", "--json"}, []string{"-"}, true, ""},
+		{[]string{".", "--", "--json", "-o"}, []string{".", "--json", "-o"}, false, ""},
+		{[]string{"--json", "--", "-o"}, []string{"-o"}, true, ""},
+	}
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		jsonSet := fs.Bool("json", false, "")
+		out := fs.String("o", "", "")
+		got, err := parseInterspersed(fs, tt.args)
+		if err != nil {
+			t.Errorf("parseInterspersed(%q) error = %v", tt.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) || *jsonSet != tt.json || *out != tt.out {
+			t.Errorf("parseInterspersed(%q) = %q, json %v, o %q; want %q, json %v, o %q",
+				tt.args, got, *jsonSet, *out, tt.want, tt.json, tt.out)
+		}
+	}
+}
+
+func TestParseInterspersedUnknownFlag(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if _, err := parseInterspersed(fs, []string{".", "--nope"}); err == nil {
+		t.Error("parseInterspersed() accepted an unknown flag after an argument")
+	}
+}
+
+func TestRunFlagsAfterArguments(t *testing.T) {
+	repo := newTestRepo(t)
+
+	before, err := runCommand(t, "revision", "--json", repo, "HEAD")
+	if err != nil {
+		t.Fatalf("revision --json error = %v", err)
+	}
+	after, err := runCommand(t, "revision", repo, "HEAD", "--json")
+	if err != nil {
+		t.Fatalf("revision ... --json error = %v", err)
+	}
+	if after != before {
+		t.Errorf("revision with --json last = %q, want %q", after, before)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(after), &doc); err != nil || strings.Count(after, "\n") != 1 {
+		t.Fatalf("revision ... --json wrote %q, want one line of JSON", after)
+	}
+	if doc["object_type"] != "rev" {
+		t.Errorf("object_type = %v, want rev", doc["object_type"])
+	}
+}
+
+func TestRunUnknownFlagAfterArguments(t *testing.T) {
+	repo := newTestRepo(t)
+	_, err := runCommand(t, "revision", repo, "HEAD", "--jsno")
+	var usage usageError
+	if !errors.As(err, &usage) {
+		t.Errorf("revision with an unknown flag last: error = %v, want a usage error", err)
+	}
+}
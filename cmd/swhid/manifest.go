@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/license"
+	"github.com/andrew/swhid-go/manifest"
+)
+
+// runManifest writes the path, type, SWHID and size of every object under
+// a directory as NDJSON. With --licenses, files are scanned for licenses
+// as they are hashed.
+func runManifest(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("directory path required")
+	}
+
+	info, err := os.Stat(args[0])
+	if err != nil {
+		return fmt.Errorf("path does not exist: %s", args[0])
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("path is not a directory: %s", args[0])
+	}
+
+	opts := treeOptions()
+	var scanner *license.Scanner
+	if licensesFlag {
+		scanner = license.NewScanner()
+		opts.Inspect = scanner.Inspect
+	}
+	root, err := swhid.TreeFromDirectoryPathWithOptions(args[0], opts)
+	if err != nil {
+		return err
+	}
+
+	if outputFlag == "" || outputFlag == "-" {
+		out := bufio.NewWriter(os.Stdout)
+		if err := manifest.Write(root, withLicenses(manifest.NewNDJSONWriter(out), scanner)); err != nil {
+			return err
+		}
+		if err := out.Flush(); err != nil {
+			return err
+		}
+		return emitTree(root, args[0])
+	}
+
+	f, err := os.Create(outputFlag)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	out := bufio.NewWriter(f)
+	if err := manifest.Write(root, withLicenses(manifest.NewNDJSONWriter(out), scanner)); err != nil {
+		return err
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return emitTree(root, args[0])
+}
+
+// licensedWriter fills in the licenses a scanner found before passing
+// entries on.
+type licensedWriter struct {
+	manifest.Writer
+	scanner *license.Scanner
+}
+
+func (w licensedWriter) Write(e manifest.Entry) error {
+	e.Licenses = w.scanner.Licenses(e.Path)
+	return w.Writer.Write(e)
+}
+
+// withLicenses adds the licenses scanner found to the entries written to
+// w, or returns w unchanged without a scanner.
+func withLicenses(w manifest.Writer, scanner *license.Scanner) manifest.Writer {
+	if scanner == nil {
+		return w
+	}
+	return licensedWriter{w, scanner}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andrew/swhid-go"
+)
+
+func TestManifestOutputAfterPath(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello\n"), 0644)
+	out := filepath.Join(t.TempDir(), "manifest.ndjson")
+
+	stdout, err := runCommand(t, "manifest", dir, "-o", out)
+	if err != nil {
+		t.Fatalf("manifest error = %v", err)
+	}
+	if stdout != "" {
+		t.Errorf("manifest -o wrote %q to stdout", stdout)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("manifest -o wrote no file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("manifest wrote %q, want 2 lines", data)
+	}
+	var root map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &root); err != nil {
+		t.Fatalf("manifest line %q: %v", lines[0], err)
+	}
+	want, _ := swhid.FromDirectoryPath(dir)
+	if root["path"] != "" || root["swhid"] != want.CoreSWHID() {
+		t.Errorf("manifest root = %v, want %v", root, want)
+	}
+}
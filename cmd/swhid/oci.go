@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/oci"
+	"github.com/andrew/swhid-go/release"
+)
+
+// runOCI attaches the SWHIDs of a source directory to a container image,
+// as manifest annotations with "oci annotate" or as a source referrer with
+// "oci attach", or checks the attached source with "oci verify".
+func runOCI(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("subcommand required (annotate, attach or verify)")
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	switch args[0] {
+	case "annotate":
+		return runOCIAnnotate(ctx, args[1:])
+	case "attach":
+		return runOCIAttach(ctx, args[1:])
+	case "verify":
+		return runOCIVerify(ctx, args[1:])
+	}
+	return fmt.Errorf("unknown oci subcommand %q (expected annotate, attach or verify)", args[0])
+}
+
+// ociArgs returns the image reference and source directory of annotate
+// and attach.
+func ociArgs(args []string) (store oci.Store, ref, dir string, err error) {
+	if len(args) < 1 {
+		return nil, "", "", fmt.Errorf("image reference required")
+	}
+	dir = "."
+	if len(args) > 1 {
+		dir = args[1]
+	}
+	store, ref, err = oci.ParseReference(args[0])
+	return store, ref, dir, err
+}
+
+// sourceProvenance returns the revision and origin to record with a
+// directory's SWHID: HEAD and the origin remote, when dir is committed
+// there unchanged, and the configured or -q origin in any case.
+func sourceProvenance(ctx context.Context, dir string, id *swhid.Identifier) (*swhid.Identifier, string, error) {
+	d, err := swhid.Describe(ctx, dir, treeOptions())
+	if err != nil {
+		return nil, "", err
+	}
+	var rev *swhid.Identifier
+	origin := ""
+	if d.Qualified != nil && d.Directory.CoreSWHID() == id.CoreSWHID() {
+		rev, origin = d.Revision, d.Origin
+	}
+	if cfg.Origin != "" {
+		origin = cfg.Origin
+	}
+	if o, ok := qualifierFlags["origin"]; ok {
+		origin = o
+	}
+	return rev, origin, nil
+}
+
+func runOCIAnnotate(ctx context.Context, args []string) error {
+	store, ref, dir, err := ociArgs(args)
+	if err != nil {
+		return err
+	}
+	id, err := pathSWHID(dir)
+	if err != nil {
+		return err
+	}
+	if id.ObjectType != swhid.ObjectTypeDirectory {
+		return fmt.Errorf("%s: not a directory", dir)
+	}
+	rev, origin, err := sourceProvenance(ctx, dir, id)
+	if err != nil {
+		return err
+	}
+
+	annotations := oci.Annotations(id, rev, origin)
+	desc, err := oci.Annotate(ctx, store, ref, annotations)
+	if err != nil {
+		return err
+	}
+	if formatFlag == "json" {
+		return writeJSON(map[string]interface{}{"image": args[0], "digest": desc.Digest, "annotations": annotations})
+	}
+	fmt.Printf("Image:     %s\n", desc.Digest)
+	printAnnotations(annotations)
+	return nil
+}
+
+func runOCIAttach(ctx context.Context, args []string) error {
+	store, ref, dir, err := ociArgs(args)
+	if err != nil {
+		return err
+	}
+	// The provenance check needs the SWHID before the source is packed.
+	id, err := pathSWHID(dir)
+	if err != nil {
+		return err
+	}
+	rev, origin, err := sourceProvenance(ctx, dir, id)
+	if err != nil {
+		return err
+	}
+
+	src := oci.Source{Dir: dir, Opts: release.Options{Tree: treeOptions()}, Revision: rev, Origin: origin}
+	desc, _, err := oci.AttachSource(ctx, store, ref, src)
+	if err != nil {
+		return err
+	}
+	if formatFlag == "json" {
+		return writeJSON(map[string]interface{}{"image": args[0], "artifact": desc.Digest, "annotations": desc.Annotations})
+	}
+	fmt.Printf("Artifact:  %s\n", desc.Digest)
+	printAnnotations(desc.Annotations)
+	return nil
+}
+
+func printAnnotations(annotations map[string]string) {
+	for _, f := range []struct{ key, label string }{
+		{oci.AnnotationDirectory, "Directory:"},
+		{oci.AnnotationRevision, "Revision: "},
+		{oci.AnnotationOrigin, "Origin:   "},
+	} {
+		if v, ok := annotations[f.key]; ok {
+			fmt.Printf("%s %s\n", f.label, v)
+		}
+	}
+}
+
+func runOCIVerify(ctx context.Context, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("image reference required")
+	}
+	store, ref, err := oci.ParseReference(args[0])
+	if err != nil {
+		return err
+	}
+	v, err := oci.Verify(ctx, store, ref)
+	if v == nil {
+		return err
+	}
+
+	if formatFlag == "json" {
+		doc := map[string]interface{}{"image": args[0], "digest": v.Image.Digest, "ok": err == nil}
+		if v.Annotated != nil {
+			doc["annotated"] = v.Annotated.CoreSWHID()
+		}
+		sources := []map[string]interface{}{}
+		for _, s := range v.Sources {
+			source := map[string]interface{}{"artifact": s.Artifact.Digest, "computed": s.Computed.CoreSWHID()}
+			if s.Recorded != nil {
+				source["recorded"] = s.Recorded.CoreSWHID()
+			}
+			sources = append(sources, source)
+		}
+		doc["sources"] = sources
+		if werr := writeJSON(doc); werr != nil {
+			return werr
+		}
+		return err
+	}
+
+	status := "OK"
+	switch {
+	case errors.Is(err, oci.ErrNoSource):
+		status = "NO SOURCE"
+	case err != nil:
+		status = "MISMATCH"
+	}
+	fmt.Printf("Status:    %s\n", status)
+	fmt.Printf("Image:     %s\n", v.Image.Digest)
+	if v.Annotated != nil {
+		fmt.Printf("Annotated: %s\n", v.Annotated)
+	}
+	for _, s := range v.Sources {
+		fmt.Printf("Artifact:  %s\n", s.Artifact.Digest)
+		if s.Recorded != nil {
+			fmt.Printf("Recorded:  %s\n", s.Recorded)
+		}
+		fmt.Printf("Computed:  %s\n", s.Computed)
+	}
+	return err
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/oci"
+)
+
+// jsonSchema names the layout of the JSON the CLI writes with --format json:
+// every document, and every line of NDJSON streams such as history's, is
+// an object holding it under "schema". Under one schema fields are only
+// added, never renamed, removed or given another meaning, so parsers should
+// ignore fields they do not know; anything else moves to swhid-cli/2.
+// Object keys are written sorted and lists in a fixed order, so the same
+// input always gives the same bytes.
+const jsonSchema = "swhid-cli/1"
+
+// documentSink, when set, receives the documents writeDocument would
+// write, as eval collects them for its response.
+var documentSink func(v interface{}) error
+
+// writeJSON writes v, a map or a struct that encodes as a JSON object, with
+// the schema added, as indented JSON to the --output file or stdout.
+func writeJSON(v interface{}) error {
+	doc, err := withSchema(v)
+	if err != nil {
+		return err
+	}
+	return writeDocument(doc)
+}
+
+// writeDocument writes v unchanged as indented JSON, or on one line with
+// --json, to the --output file or stdout. It is for documents whose format
+// is defined elsewhere and that carry their own type, such as in-toto
+// statements, Sigstore bundles and verification results.
+func writeDocument(v interface{}) error {
+	if documentSink != nil && outputFlag == "" {
+		return documentSink(v)
+	}
+	var out io.Writer = os.Stdout
+	if outputFlag != "" {
+		f, err := os.Create(outputFlag)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	encoder := json.NewEncoder(out)
+	if !jsonFlag {
+		encoder.SetIndent("", "  ")
+	}
+	return encoder.Encode(v)
+}
+
+// withSchema returns v as a map with "schema" set to jsonSchema. Encoding
+// a map sorts its keys, so structs lose their field order here too.
+func withSchema(v interface{}) (map[string]interface{}, error) {
+	if m, ok := v.(map[string]interface{}); ok {
+		doc := make(map[string]interface{}, len(m)+1)
+		for k, value := range m {
+			doc[k] = value
+		}
+		doc["schema"] = jsonSchema
+		return doc, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("JSON output must be an object, not %T", v)
+	}
+	doc := make(map[string]interface{}, len(fields)+1)
+	for k, value := range fields {
+		doc[k] = value
+	}
+	doc["schema"] = jsonSchema
+	return doc, nil
+}
+
+// warningMu keeps warnings from parallel hashing workers on lines of their
+// own.
+var warningMu sync.Mutex
+
+// writeWarning reports w on stderr, as "Warning: path: message" or, for
+// --json, a JSON document on one line holding "warning", "kind" and "path".
+func writeWarning(w swhid.Warning) {
+	warningMu.Lock()
+	defer warningMu.Unlock()
+	if jsonFlag {
+		json.NewEncoder(os.Stderr).Encode(map[string]interface{}{"schema": jsonSchema, "warning": w.Message, "kind": w.Kind, "path": w.Path})
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+}
+
+// writeError writes err to stderr as a JSON document on one line, for
+// --json: "error" holds the message, "code" one of usage, invalid_swhid,
+// not_found or error, and "suggestions" the fixes a parse error offers.
+func writeError(err error) {
+	doc := map[string]interface{}{"schema": jsonSchema, "error": err.Error(), "code": errorCode(err)}
+	var parseErr *swhid.ParseError
+	if errors.As(err, &parseErr) && len(parseErr.Suggestions) > 0 {
+		doc["suggestions"] = parseErr.Suggestions
+	}
+	json.NewEncoder(os.Stderr).Encode(doc)
+}
+
+// errorCode classifies err for writeError.
+func errorCode(err error) string {
+	var parseErr *swhid.ParseError
+	var usage usageError
+	switch {
+	case errors.As(err, &usage):
+		return "usage"
+	case errors.As(err, &parseErr):
+		return "invalid_swhid"
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, oci.ErrNotFound):
+		return "not_found"
+	}
+	return "error"
+}
+
+// usageError is an invalid flag or request.
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string { return e.err.Error() }
+func (e usageError) Unwrap() error { return e.err }
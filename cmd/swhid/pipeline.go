@@ -0,0 +1,617 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/attest"
+	"github.com/andrew/swhid-go/emit"
+	"github.com/andrew/swhid-go/manifest"
+	"gopkg.in/yaml.v3"
+)
+
+// pipeline is a provenance job read from YAML by the run command:
+//
+//	inputs:
+//	  - path: ./src
+//	    expect: swh:1:dir:...
+//	  - url: https://example.org/project-1.0.tar.gz
+//	    preset: github-archive
+//	  - repo: https://github.com/example/project
+//	steps:
+//	  - compute
+//	  - verify
+//	  - qualify:
+//	      origin: https://github.com/example/project
+//	  - publish:
+//	      to: [https://provenance.example.org/swhids]
+//	outputs:
+//	  manifest: out/manifest.ndjson
+//	  attestation: out/attestation.json
+//
+// Relative paths, of inputs and outputs alike, are taken from the
+// directory holding the file.
+type pipeline struct {
+	Inputs  []pipelineInput `yaml:"inputs"`
+	Steps   []pipelineStep  `yaml:"steps"`
+	Outputs pipelineOutputs `yaml:"outputs"`
+}
+
+// pipelineInput is one object a pipeline identifies: a file, directory or
+// archive at Path, a file or archive downloaded from URL, or a repository,
+// local or remote, at Repo.
+type pipelineInput struct {
+	Name string `yaml:"name"` // defaults to the path, URL or repository
+	Path string `yaml:"path"`
+	URL  string `yaml:"url"`
+	Repo string `yaml:"repo"`
+
+	// Rev hashes a revision of Repo instead of its snapshot; only HEAD
+	// for remote repositories.
+	Rev string `yaml:"rev"`
+
+	// Preset normalizes archives as --preset does; Exclude adds to the
+	// exclude patterns of directories.
+	Preset  string   `yaml:"preset"`
+	Exclude []string `yaml:"exclude"`
+
+	// Expect is the SWHID the verify step checks the input against.
+	Expect string `yaml:"expect"`
+}
+
+// Kinds of pipelineStep.
+const (
+	stepCompute = "compute" // compute the SWHID of every input
+	stepVerify  = "verify"  // fail unless inputs have the SWHIDs they expect
+	stepQualify = "qualify" // add qualifiers to the SWHIDs
+	stepPublish = "publish" // send the SWHIDs to webhooks, as --emit does
+)
+
+// pipelineStep is one step, written as its kind alone ("- compute") or as
+// a mapping from its kind to its settings ("- publish: {to: [...]}").
+type pipelineStep struct {
+	Kind string `yaml:"-"`
+
+	// Qualifiers, the settings of qualify, are added to every SWHID;
+	// remote inputs also get their URL as origin unless one is given.
+	Qualifiers map[string]string `yaml:"-"`
+
+	// To, for publish, are --emit targets: http(s) webhook URLs.
+	To []string `yaml:"to"`
+}
+
+func (s *pipelineStep) UnmarshalYAML(n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		s.Kind = n.Value
+		return nil
+	case yaml.MappingNode:
+		if len(n.Content) != 2 {
+			return fmt.Errorf("line %d: a step is a mapping with one key, its kind", n.Line)
+		}
+		s.Kind = n.Content[0].Value
+		if s.Kind == stepQualify {
+			return n.Content[1].Decode(&s.Qualifiers)
+		}
+		type settings pipelineStep
+		return n.Content[1].Decode((*settings)(s))
+	}
+	return fmt.Errorf("line %d: a step is a kind or a mapping from a kind to its settings", n.Line)
+}
+
+// pipelineOutputs are the files a pipeline writes once its steps are done.
+type pipelineOutputs struct {
+	// Manifest lists every object of the directory inputs as the manifest
+	// command does, paths starting with the input's name when there are
+	// several, as NDJSON.
+	Manifest string `yaml:"manifest"`
+
+	// Attestation is an in-toto statement of the inputs' SWHIDs, signed as
+	// attest --sign does, with --key or keyless, when Sign is set.
+	Attestation string `yaml:"attestation"`
+	Sign        bool   `yaml:"sign"`
+
+	// Report is the JSON document run writes, also written to this file.
+	Report string `yaml:"report"`
+}
+
+// pipelineResult is what the steps found out about an input.
+type pipelineResult struct {
+	input  pipelineInput
+	source string // where it was read from, for publish
+	remote bool   // read from a URL
+	id     *swhid.Identifier
+	tree   *swhid.Node // for directories, for the manifest
+}
+
+// loadPipeline reads and checks the pipeline in file, resolving its
+// relative paths.
+func loadPipeline(file string) (*pipeline, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var p pipeline
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&p); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = errors.New("empty pipeline")
+		}
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if err := p.check(filepath.Dir(file)); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return &p, nil
+}
+
+// check validates p and makes its relative paths relative to dir.
+func (p *pipeline) check(dir string) error {
+	if len(p.Inputs) == 0 {
+		return errors.New("no inputs")
+	}
+	resolve := func(name string) string {
+		if name == "" || filepath.IsAbs(name) {
+			return name
+		}
+		return filepath.Join(dir, filepath.FromSlash(name))
+	}
+
+	names := make(map[string]bool)
+	expected := false
+	for i := range p.Inputs {
+		in := &p.Inputs[i]
+		sources := 0
+		for _, s := range []string{in.Path, in.URL, in.Repo} {
+			if s != "" {
+				sources++
+			}
+		}
+		if sources != 1 {
+			return fmt.Errorf("input %d: give one of path, url and repo", i+1)
+		}
+		if in.Name == "" {
+			in.Name = in.Path + in.URL + in.Repo
+		}
+		if names[in.Name] {
+			return fmt.Errorf("input %d: name %q used twice", i+1, in.Name)
+		}
+		names[in.Name] = true
+		if in.Rev != "" && in.Repo == "" {
+			return fmt.Errorf("input %q: rev needs a repo", in.Name)
+		}
+		if in.Expect != "" {
+			if _, err := swhid.Parse(in.Expect); err != nil {
+				return fmt.Errorf("input %q: expect: %w", in.Name, err)
+			}
+			expected = true
+		}
+		in.Path = resolve(in.Path)
+		if in.Repo != "" && !isRemoteRepo(in.Repo) {
+			in.Repo = resolve(in.Repo)
+		}
+	}
+
+	computed := false
+	for i, step := range p.Steps {
+		switch step.Kind {
+		case stepCompute:
+			computed = true
+			continue
+		case stepVerify:
+			if !expected {
+				return fmt.Errorf("step %d: verify, but no input has an expect SWHID", i+1)
+			}
+		case stepQualify:
+			if len(step.Qualifiers) == 0 {
+				return fmt.Errorf("step %d: qualify needs qualifiers", i+1)
+			}
+		case stepPublish:
+			if len(step.To) == 0 {
+				return fmt.Errorf("step %d: publish needs targets in to", i+1)
+			}
+			for _, target := range step.To {
+				if _, err := openEmitter(target); err != nil {
+					return fmt.Errorf("step %d: %w", i+1, err)
+				}
+			}
+		default:
+			return fmt.Errorf("step %d: unknown step %q (use compute, verify, qualify or publish)", i+1, step.Kind)
+		}
+		if !computed {
+			return fmt.Errorf("step %d: %s needs compute before it", i+1, step.Kind)
+		}
+	}
+	if !computed {
+		return errors.New("no compute step")
+	}
+	o := &p.Outputs
+	if o.Sign && o.Attestation == "" {
+		return errors.New("sign needs an attestation output")
+	}
+	o.Manifest, o.Attestation, o.Report = resolve(o.Manifest), resolve(o.Attestation), resolve(o.Report)
+	return nil
+}
+
+// isRemoteRepo reports whether repo is a URL to clone rather than a path.
+func isRemoteRepo(repo string) bool {
+	return strings.Contains(repo, "://") || strings.HasPrefix(repo, "git@")
+}
+
+// runPipeline runs the steps of the pipeline in a YAML file in order,
+// stopping at the first that fails, then writes its outputs and reports
+// the SWHID of every input.
+func runPipeline(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("pipeline file required")
+	}
+	p, err := loadPipeline(args[0])
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	results := make([]*pipelineResult, len(p.Inputs))
+	for i, in := range p.Inputs {
+		results[i] = &pipelineResult{input: in, source: in.Path + in.URL + in.Repo, remote: in.URL != "" || isRemoteRepo(in.Repo)}
+	}
+	for _, step := range p.Steps {
+		switch step.Kind {
+		case stepCompute:
+			for _, r := range results {
+				if err := r.compute(ctx); err != nil {
+					return fmt.Errorf("compute %s: %w", r.input.Name, err)
+				}
+			}
+		case stepVerify:
+			var mismatches []string
+			for _, r := range results {
+				if r.input.Expect == "" {
+					continue
+				}
+				want, _ := swhid.Parse(r.input.Expect)
+				if want.CoreSWHID() != r.id.CoreSWHID() {
+					mismatches = append(mismatches, fmt.Sprintf("%s: expected %s, computed %s", r.input.Name, want.CoreSWHID(), r.id.CoreSWHID()))
+				}
+			}
+			if len(mismatches) > 0 {
+				return fmt.Errorf("SWHID mismatch: %s", strings.Join(mismatches, "; "))
+			}
+		case stepQualify:
+			for _, r := range results {
+				if err := r.qualify(step.Qualifiers); err != nil {
+					return fmt.Errorf("qualify %s: %w", r.input.Name, err)
+				}
+			}
+		case stepPublish:
+			if err := publishResults(ctx, results, step.To); err != nil {
+				return err
+			}
+		}
+	}
+	if err := p.Outputs.write(results); err != nil {
+		return err
+	}
+	return reportResults(results, p.Outputs.Report)
+}
+
+// compute sets the SWHID of r's input.
+func (r *pipelineResult) compute(ctx context.Context) error {
+	in := r.input
+	switch {
+	case in.Path != "":
+		return r.computePath(ctx, in.Path)
+	case in.URL != "":
+		file, err := download(ctx, in.URL)
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(filepath.Dir(file))
+		return r.computePath(ctx, file)
+	case isRemoteRepo(in.Repo):
+		if in.Rev != "" && in.Rev != "HEAD" {
+			return fmt.Errorf("rev %s of a remote repository; only HEAD can be hashed without a clone", in.Rev)
+		}
+		opts, err := gitOptions()
+		if err != nil {
+			return err
+		}
+		remote, err := swhid.CloneAndSnapshot(ctx, in.Repo, swhid.CloneOptions{GitOptions: opts, Depth: depthFlag})
+		if err != nil {
+			return err
+		}
+		r.id = remote.Snapshot
+		if in.Rev != "" {
+			if r.id = remote.Head; r.id == nil {
+				return errors.New("HEAD does not resolve to a commit")
+			}
+		}
+		return nil
+	}
+	opts, err := gitOptions()
+	if err != nil {
+		return err
+	}
+	if in.Rev != "" {
+		r.id, err = swhid.FromRevisionWithOptions(in.Repo, in.Rev, opts)
+	} else {
+		r.id, err = swhid.FromSnapshotWithOptions(in.Repo, opts)
+	}
+	return err
+}
+
+// computePath hashes the file, directory or archive at path.
+func (r *pipelineResult) computePath(ctx context.Context, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		opts := treeOptions()
+		opts.Exclude = append(opts.Exclude, r.input.Exclude...)
+		r.tree, err = swhid.TreeFromDirectoryPathContext(ctx, path, opts)
+		if err == nil {
+			r.id = r.tree.ID
+		}
+		return err
+	}
+	if _, err := archiveFormat(path); err == nil {
+		preset := r.input.Preset
+		if preset == "" {
+			preset = presetFlag
+		}
+		r.id, err = hashArchive(path, preset)
+		return err
+	}
+	if r.input.Preset != "" {
+		return fmt.Errorf("preset %s given for %s, which is not an archive", r.input.Preset, path)
+	}
+	r.id, err = swhid.FromFile(path)
+	return err
+}
+
+// qualify adds qualifiers to r's SWHID, and the input's URL as origin for
+// remote inputs, checking the result.
+func (r *pipelineResult) qualify(qualifiers map[string]string) error {
+	quals := make(map[string]string, len(r.id.Qualifiers)+len(qualifiers)+1)
+	for k, v := range r.id.Qualifiers {
+		quals[k] = v
+	}
+	if r.remote && qualifiers[swhid.QualifierOrigin] == "" {
+		quals[swhid.QualifierOrigin] = r.source
+	}
+	for k, v := range qualifiers {
+		quals[k] = v
+	}
+	id, err := swhid.Parse(r.id.WithQualifiers(quals).String())
+	if err != nil {
+		return err
+	}
+	r.id = id
+	return nil
+}
+
+// download fetches u into a new temporary directory, keeping the name the
+// URL ends in so that archives are recognized, and returns the file's path.
+func download(ctx context.Context, u string) (string, error) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "", err
+	}
+	name := path.Base(parsed.Path)
+	if name == "/" || name == "." {
+		name = "download"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+
+	dir, err := os.MkdirTemp("", "swhid-run-*")
+	if err != nil {
+		return "", err
+	}
+	file := filepath.Join(dir, name)
+	f, err := os.Create(file)
+	if err == nil {
+		_, err = io.Copy(f, resp.Body)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return file, nil
+}
+
+// objectTypeNames name object types in published events.
+var objectTypeNames = map[swhid.ObjectType]string{
+	swhid.ObjectTypeContent:   "file",
+	swhid.ObjectTypeDirectory: "directory",
+	swhid.ObjectTypeRevision:  "revision",
+	swhid.ObjectTypeRelease:   "release",
+	swhid.ObjectTypeSnapshot:  "snapshot",
+}
+
+// publishResults sends an event for every input to targets: its name as
+// path, its object type, its core SWHID and where it was read from.
+func publishResults(ctx context.Context, results []*pipelineResult, targets []string) error {
+	var emitters []emit.Emitter
+	for _, target := range targets {
+		em, err := openEmitter(target)
+		if err != nil {
+			return err
+		}
+		emitters = append(emitters, em)
+	}
+	em := emit.Multi(emitters...)
+	for _, r := range results {
+		e := emit.Event{
+			Entry:  manifest.Entry{Path: r.input.Name, Type: objectTypeNames[r.id.ObjectType], SWHID: r.id},
+			Source: r.source,
+		}
+		if r.tree != nil {
+			e.Type, e.Size = manifest.TypeName(r.tree.Type), r.tree.Size
+		}
+		if err := em.Emit(ctx, e); err != nil {
+			em.Close(ctx)
+			return fmt.Errorf("publish: %w", err)
+		}
+	}
+	if err := em.Close(ctx); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+	return nil
+}
+
+// write writes the manifest and attestation outputs of results.
+func (o pipelineOutputs) write(results []*pipelineResult) error {
+	if o.Manifest != "" {
+		if err := writePipelineManifest(o.Manifest, results); err != nil {
+			return err
+		}
+	}
+	if o.Attestation == "" {
+		return nil
+	}
+	var subjects []attest.Subject
+	for _, r := range results {
+		subjects = append(subjects, attest.Subject{Name: r.input.Name, SWHID: r.id})
+	}
+	st := attest.NewStatement(cliVersion(), subjects...)
+	var doc interface{} = st
+	if o.Sign {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		bundle, err := signStatement(ctx, st)
+		if err != nil {
+			return err
+		}
+		doc = bundle
+	}
+	return writeDocumentFile(o.Attestation, doc)
+}
+
+// writePipelineManifest writes the objects of the directory inputs among
+// results to file, under the inputs' names when there are several.
+func writePipelineManifest(file string, results []*pipelineResult) error {
+	var trees []*pipelineResult
+	for _, r := range results {
+		if r.tree != nil {
+			trees = append(trees, r)
+		}
+	}
+	if len(trees) == 0 {
+		return errors.New("manifest output, but no input is a directory")
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	out := bufio.NewWriter(f)
+	w := manifest.NewNDJSONWriter(out)
+	for _, r := range trees {
+		err := manifest.Walk(r.tree, func(e manifest.Entry) error {
+			if len(trees) > 1 {
+				e.Path = path.Join(r.input.Name, e.Path)
+			}
+			return w.Write(e)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// writeDocumentFile writes v to file as indented JSON, creating the
+// directory it is in.
+func writeDocumentFile(file string, v interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// reportResults writes the SWHID of every input, as "SWHID  name" lines or
+// a JSON document, and the JSON document to report, if set.
+func reportResults(results []*pipelineResult, report string) error {
+	list := make([]map[string]interface{}, len(results))
+	for i, r := range results {
+		list[i] = map[string]interface{}{"name": r.input.Name, "source": r.source}
+		if r.id != nil {
+			list[i]["swhid"] = r.id.String()
+		}
+		if r.input.Expect != "" {
+			list[i]["expect"] = r.input.Expect
+		}
+	}
+	doc := map[string]interface{}{"inputs": list}
+	if report != "" {
+		withSchema, err := withSchema(doc)
+		if err != nil {
+			return err
+		}
+		if err := writeDocumentFile(report, withSchema); err != nil {
+			return err
+		}
+	}
+	if formatFlag == "json" {
+		return writeJSON(doc)
+	}
+	for _, r := range results {
+		if r.id != nil {
+			fmt.Printf("%s  %s\n", r.id, r.input.Name)
+		}
+	}
+	return nil
+}
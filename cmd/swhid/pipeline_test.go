@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/attest"
+)
+
+func TestLoadPipeline(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "pipeline.yaml")
+	writeFiles(t, map[string]string{file: `inputs:
+  - path: src
+  - name: abs
+    path: /srv/data
+  - repo: .
+    rev: HEAD
+  - repo: https://github.com/example/project
+steps:
+  - compute
+  - qualify:
+      origin: https://example.org/project
+  - publish:
+      to: [https://example.org/hook]
+outputs:
+  manifest: out/manifest.ndjson
+  report: out/report.json
+`})
+
+	p, err := loadPipeline(file)
+	if err != nil {
+		t.Fatalf("loadPipeline() error = %v", err)
+	}
+
+	inputs := []struct{ name, path, repo string }{
+		{"src", filepath.Join(dir, "src"), ""},
+		{"abs", "/srv/data", ""},
+		{".", "", dir},
+		{"https://github.com/example/project", "", "https://github.com/example/project"},
+	}
+	if len(p.Inputs) != len(inputs) {
+		t.Fatalf("loadPipeline() read %d inputs, want %d", len(p.Inputs), len(inputs))
+	}
+	for i, want := range inputs {
+		in := p.Inputs[i]
+		if in.Name != want.name || in.Path != want.path || in.Repo != want.repo {
+			t.Errorf("input %d = name %q, path %q, repo %q; want %q, %q, %q", i+1, in.Name, in.Path, in.Repo, want.name, want.path, want.repo)
+		}
+	}
+
+	kinds := []string{stepCompute, stepQualify, stepPublish}
+	if len(p.Steps) != len(kinds) {
+		t.Fatalf("loadPipeline() read %d steps, want %d", len(p.Steps), len(kinds))
+	}
+	for i, kind := range kinds {
+		if p.Steps[i].Kind != kind {
+			t.Errorf("step %d = %q, want %q", i+1, p.Steps[i].Kind, kind)
+		}
+	}
+	if p.Steps[1].Qualifiers["origin"] != "https://example.org/project" {
+		t.Errorf("qualify step = %v", p.Steps[1].Qualifiers)
+	}
+	if len(p.Steps[2].To) != 1 || p.Steps[2].To[0] != "https://example.org/hook" {
+		t.Errorf("publish step to = %v", p.Steps[2].To)
+	}
+
+	if want := filepath.Join(dir, "out", "manifest.ndjson"); p.Outputs.Manifest != want {
+		t.Errorf("manifest output = %q, want %q", p.Outputs.Manifest, want)
+	}
+	if p.Outputs.Attestation != "" {
+		t.Errorf("attestation output = %q, want none", p.Outputs.Attestation)
+	}
+}
+
+func TestLoadPipelineErrors(t *testing.T) {
+	tests := []struct {
+		name, yaml, want string
+	}{
+		{"empty", "", "empty pipeline"},
+		{"unknown field", "inputs: [{path: src}]\nsteps: [compute]\nextra: 1\n", "extra"},
+		{"no inputs", "steps: [compute]\n", "no inputs"},
+		{"two sources", "inputs: [{path: src, url: https://example.org/a.tar}]\nsteps: [compute]\n", "give one of path, url and repo"},
+		{"no source", "inputs: [{name: src}]\nsteps: [compute]\n", "give one of path, url and repo"},
+		{"duplicate name", "inputs: [{path: src}, {path: src}]\nsteps: [compute]\n", `name "src" used twice`},
+		{"rev without repo", "inputs: [{path: src, rev: HEAD}]\nsteps: [compute]\n", "rev needs a repo"},
+		{"bad expect", "inputs: [{path: src, expect: swh:1:dir:nope}]\nsteps: [compute]\n", "expect"},
+		{"no compute", "inputs: [{path: src}]\nsteps: []\n", "no compute step"},
+		{"verify first", "inputs: [{path: src, expect: " + helloSWHID + "}]\nsteps: [verify, compute]\n", "verify needs compute before it"},
+		{"verify without expect", "inputs: [{path: src}]\nsteps: [compute, verify]\n", "no input has an expect SWHID"},
+		{"empty qualify", "inputs: [{path: src}]\nsteps: [compute, qualify: {}]\n", "qualify needs qualifiers"},
+		{"publish without targets", "inputs: [{path: src}]\nsteps: [compute, publish: {}]\n", "publish needs targets"},
+		{"publish bad target", "inputs: [{path: src}]\nsteps: [compute, publish: {to: [ftp://example.org]}]\n", "unsupported emit target"},
+		{"publish to kafka", "inputs: [{path: src}]\nsteps: [compute, publish: {to: [kafka://broker:9092/swhids]}]\n", "unsupported emit target"},
+		{"unknown step", "inputs: [{path: src}]\nsteps: [compute, upload]\n", `unknown step "upload"`},
+		{"step with two keys", "inputs: [{path: src}]\nsteps: [compute, {qualify: {origin: x}, publish: {}}]\n", "a step is a mapping with one key"},
+		{"sign without attestation", "inputs: [{path: src}]\nsteps: [compute]\noutputs: {sign: true}\n", "sign needs an attestation output"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "pipeline.yaml")
+			writeFiles(t, map[string]string{file: tt.yaml})
+			_, err := loadPipeline(file)
+			if err == nil {
+				t.Fatalf("loadPipeline() accepted %q", tt.yaml)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("loadPipeline() error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunPipeline(t *testing.T) {
+	var mu sync.Mutex
+	var published []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		decoder := json.NewDecoder(r.Body)
+		for {
+			var event map[string]interface{}
+			if err := decoder.Decode(&event); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Errorf("webhook received invalid NDJSON: %v", err)
+				break
+			}
+			published = append(published, event)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	writeFiles(t, map[string]string{
+		filepath.Join(src, "hello.txt"):     "hello\n",
+		filepath.Join(src, "lib", "lib.go"): "package lib\n",
+		filepath.Join(dir, "notes.txt"):     "hello\n",
+		filepath.Join(dir, "pipeline.yaml"): `inputs:
+  - name: src
+    path: src
+  - path: notes.txt
+    expect: ` + helloSWHID + `
+steps:
+  - compute
+  - verify
+  - qualify:
+      origin: https://example.org/project
+  - publish:
+      to: [` + server.URL + `]
+outputs:
+  manifest: out/manifest.ndjson
+  attestation: out/attestation.json
+  report: out/report.json
+`,
+	})
+	srcID, err := swhid.FromDirectoryPath(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runCommand(t, "run", filepath.Join(dir, "pipeline.yaml"), "--json")
+	if err != nil {
+		t.Fatalf("run error = %v", err)
+	}
+
+	var doc struct {
+		Inputs []map[string]string `json:"inputs"`
+	}
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("run --json wrote %q: %v", out, err)
+	}
+	wantSWHIDs := map[string]string{
+		"src":       srcID.CoreSWHID() + ";origin=https://example.org/project",
+		"notes.txt": helloSWHID + ";origin=https://example.org/project",
+	}
+	if len(doc.Inputs) != len(wantSWHIDs) {
+		t.Fatalf("run reported %d inputs, want %d", len(doc.Inputs), len(wantSWHIDs))
+	}
+	for _, in := range doc.Inputs {
+		if in["swhid"] != wantSWHIDs[in["name"]] {
+			t.Errorf("run reported %s as %s, want %s", in["name"], in["swhid"], wantSWHIDs[in["name"]])
+		}
+	}
+
+	// Only the directory input is in the manifest, under its own paths
+	manifest, err := os.ReadFile(filepath.Join(dir, "out", "manifest.ndjson"))
+	if err != nil {
+		t.Fatalf("run wrote no manifest: %v", err)
+	}
+	var paths []string
+	for _, line := range strings.Split(strings.TrimSuffix(string(manifest), "\n"), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("manifest line %q: %v", line, err)
+		}
+		paths = append(paths, entry["path"].(string))
+	}
+	if got := strings.Join(paths, ","); got != ",hello.txt,lib,lib/lib.go" {
+		t.Errorf("manifest paths = %s", got)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "out", "attestation.json"))
+	if err != nil {
+		t.Fatalf("run wrote no attestation: %v", err)
+	}
+	var st attest.Statement
+	if err := json.Unmarshal(data, &st); err != nil {
+		t.Fatalf("attestation %s: %v", data, err)
+	}
+	if len(st.Subjects) != 2 || st.Subjects[0].Name != "src" || st.Subjects[0].SWHID.CoreSWHID() != srcID.CoreSWHID() {
+		t.Errorf("attestation subjects = %+v", st.Subjects)
+	}
+
+	report, err := os.ReadFile(filepath.Join(dir, "out", "report.json"))
+	if err != nil {
+		t.Fatalf("run wrote no report: %v", err)
+	}
+	var reportDoc map[string]interface{}
+	if err := json.Unmarshal(report, &reportDoc); err != nil || reportDoc["schema"] != jsonSchema {
+		t.Errorf("report = %s", report)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(published) != 2 || published[0]["path"] != "src" || published[0]["type"] != "directory" || published[1]["type"] != "file" {
+		t.Errorf("published %v", published)
+	}
+}
+
+func TestRunPipelineVerifyMismatch(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, map[string]string{
+		filepath.Join(dir, "notes.txt"): "changed\n",
+		filepath.Join(dir, "pipeline.yaml"): `inputs:
+  - path: notes.txt
+    expect: ` + helloSWHID + `
+steps: [compute, verify]
+outputs:
+  report: out/report.json
+`,
+	})
+	_, err := runCommand(t, "run", filepath.Join(dir, "pipeline.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "SWHID mismatch") {
+		t.Errorf("run error = %v, want a SWHID mismatch", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out", "report.json")); err == nil {
+		t.Error("run wrote outputs after a failed step")
+	}
+}
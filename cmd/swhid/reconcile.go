@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/archive"
+	"github.com/andrew/swhid-go/manifest"
+)
+
+// reconcileMarkers label difference kinds in text output, in the style of
+// git status: entries the archive lacks are additions, entries only the
+// archive has are deletions.
+var reconcileMarkers = map[string]string{
+	archive.NotArchived:    "A",
+	archive.HashMismatch:   "M",
+	archive.MissingLocally: "D",
+}
+
+// runReconcile compares a local directory with its archived copy and lists
+// the entries on which they differ.
+func runReconcile(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("directory path and directory SWHID required")
+	}
+	id, err := swhid.Parse(args[1])
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	token, _ := apiToken()
+	client := &archive.Client{Token: token}
+	r, err := client.ReconcileWithArchive(ctx, args[0], id)
+	if err != nil {
+		return err
+	}
+
+	if formatFlag == "json" {
+		differences := make([]map[string]interface{}, len(r.Differences))
+		for i, d := range r.Differences {
+			entry := map[string]interface{}{"path": d.Path, "kind": d.Kind}
+			if d.Local != nil {
+				entry["local"] = d.Local.String()
+				entry["local_type"] = manifest.TypeName(d.LocalType)
+			}
+			if d.Archived != nil {
+				entry["archived"] = d.Archived.String()
+				entry["archived_type"] = manifest.TypeName(d.ArchivedType)
+			}
+			differences[i] = entry
+		}
+		return writeJSON(map[string]interface{}{
+			"path":        args[0],
+			"local":       r.Local.String(),
+			"archived":    r.Archived.String(),
+			"matches":     len(r.Differences) == 0,
+			"differences": differences,
+		})
+	}
+
+	fmt.Printf("Local:    %s\n", r.Local)
+	fmt.Printf("Archived: %s\n", r.Archived)
+	if len(r.Differences) == 0 {
+		fmt.Println("The archived copy matches the directory.")
+		return nil
+	}
+	for _, d := range r.Differences {
+		fmt.Printf("%s %s\n", reconcileMarkers[d.Kind], d.Path)
+	}
+	return nil
+}
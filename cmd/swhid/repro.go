@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/manifest"
+)
+
+// runRepro compares two directories that should hash the same and lists
+// the entries on which they first diverge. Like verify, it fails when they
+// differ, so it can gate reproducible builds.
+func runRepro(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("two directory paths required")
+	}
+
+	report, err := swhid.CompareDirectories(args[0], args[1], treeOptions())
+	if err != nil {
+		return err
+	}
+
+	if formatFlag == "json" {
+		differences := make([]map[string]interface{}, len(report.Differences))
+		for i, d := range report.Differences {
+			entry := map[string]interface{}{"path": d.Path, "kind": d.Kind, "detail": d.Detail}
+			if d.A != nil {
+				entry["a"] = d.A.ID.String()
+				entry["a_type"] = manifest.TypeName(d.A.Type)
+			}
+			if d.B != nil {
+				entry["b"] = d.B.ID.String()
+				entry["b_type"] = manifest.TypeName(d.B.Type)
+			}
+			differences[i] = entry
+		}
+		if err := writeJSON(map[string]interface{}{
+			"a":           map[string]string{"path": args[0], "swhid": report.A.String()},
+			"b":           map[string]string{"path": args[1], "swhid": report.B.String()},
+			"identical":   len(report.Differences) == 0,
+			"differences": differences,
+		}); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("A: %s  %s\n", report.A, args[0])
+		fmt.Printf("B: %s  %s\n", report.B, args[1])
+		if len(report.Differences) == 0 {
+			fmt.Println("The trees are identical.")
+			return nil
+		}
+		for _, d := range report.Differences {
+			fmt.Printf("%-8s %s: %s\n", d.Kind, d.Path, d.Detail)
+		}
+	}
+
+	if len(report.Differences) > 0 {
+		return fmt.Errorf("trees differ in %d entries", len(report.Differences))
+	}
+	return nil
+}
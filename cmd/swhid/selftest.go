@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/andrew/swhid-go/selftest"
+)
+
+// runSelftest recomputes the embedded known-answer vectors, probes the
+// filesystem of an optional directory (the temporary directory by default)
+// and writes the JSON report. It fails when any check fails, so deployments
+// can gate on the exit status.
+func runSelftest(args []string) error {
+	dir := ""
+	if len(args) > 0 {
+		dir = args[0]
+	}
+
+	report, err := selftest.Run(dir)
+	if err != nil {
+		return err
+	}
+	report.Version = cliVersion()
+	if err := writeJSON(report); err != nil {
+		return err
+	}
+
+	if !report.Passed {
+		return fmt.Errorf("self-test failed")
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/objects"
+)
+
+// runStreamSnapshot computes a snapshot reference by reference, reporting
+// progress on stderr and, with --checkpoint, saving its state to a file it
+// resumes from if a previous run was interrupted. The file is removed once
+// the snapshot is complete.
+func runStreamSnapshot(repoPath string, opts swhid.GitOptions) (*swhid.Identifier, error) {
+	session, err := swhid.OpenRepoWithOptions(repoPath, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	streamOpts := swhid.SnapshotOptions{GitOptions: opts}
+
+	if progressFlag {
+		streamOpts.Progress = func(p swhid.SnapshotProgress) {
+			if p.Done%1000 == 0 || p.Done == p.Total {
+				fmt.Fprintf(os.Stderr, "\rResolved %d/%d refs (%d resumed)", p.Done, p.Total, p.Resumed)
+				if p.Done == p.Total {
+					fmt.Fprintln(os.Stderr)
+				}
+			}
+		}
+	}
+
+	if checkpointFlag != "" {
+		state, err := readCheckpoint(checkpointFlag)
+		if err != nil {
+			return nil, err
+		}
+		streamOpts.Resume = state
+		streamOpts.Checkpoint = func(state *swhid.SnapshotState) error {
+			return writeCheckpoint(checkpointFlag, state)
+		}
+	}
+
+	id, err := swhid.FromSnapshotStream(session.Repository(), streamOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	if checkpointFlag != "" {
+		if err := os.Remove(checkpointFlag); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return id, nil
+}
+
+func readCheckpoint(path string) (*swhid.SnapshotState, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state swhid.SnapshotState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %w", path, err)
+	}
+	return &state, nil
+}
+
+// writeCheckpoint replaces the checkpoint file atomically so an interrupted
+// write never leaves a truncated state behind.
+func writeCheckpoint(path string, state *swhid.SnapshotState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// historicalSnapshot computes a past snapshot of a repository, from the
+// refs recorded in --refs-file or from the reflogs as of --at.
+func historicalSnapshot(repoPath string, opts swhid.GitOptions) (*swhid.Identifier, error) {
+	branches, err := historicalBranches(repoPath, opts)
+	if err != nil {
+		return nil, err
+	}
+	return swhid.FromSnapshotBranches(branches), nil
+}
+
+// historicalBranches returns the branches of the past snapshot
+// historicalSnapshot computes.
+func historicalBranches(repoPath string, opts swhid.GitOptions) ([]objects.Branch, error) {
+	if refsFileFlag == "" {
+		at, err := parseDate(atFlag)
+		if err != nil {
+			return nil, err
+		}
+		return swhid.SnapshotBranchesAt(repoPath, at, opts)
+	}
+	if atFlag != "" {
+		return nil, fmt.Errorf("--at and --refs-file cannot be combined")
+	}
+
+	f, err := os.Open(refsFileFlag)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	refs, err := swhid.ReadRefSnapshot(f)
+	if err != nil {
+		return nil, err
+	}
+	session, err := swhid.OpenRepoWithOptions(repoPath, opts)
+	if err != nil {
+		return nil, err
+	}
+	return swhid.SnapshotBranchesFromRefs(session.Repository(), refs, opts)
+}
+
+// runExplainSnapshot prints the snapshot SWHID of a repository or bundle
+// followed by every branch that went into it, in the order the manifest
+// lists them, with the target type and target hashed for each.
+func runExplainSnapshot(repoPath string, opts swhid.GitOptions) error {
+	var branches []objects.Branch
+	var err error
+	if info, statErr := os.Stat(repoPath); statErr == nil && info.Mode().IsRegular() {
+		var session *swhid.RepoSession
+		if session, err = swhid.OpenBundleWithOptions(repoPath, opts); err == nil {
+			branches, err = session.SnapshotBranches()
+		}
+	} else if atFlag != "" || refsFileFlag != "" {
+		branches, err = historicalBranches(repoPath, opts)
+	} else {
+		branches, err = swhid.SnapshotBranchesWithOptions(repoPath, opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	id := applyQualifiers(swhid.FromSnapshotBranches(branches))
+	branches = append([]objects.Branch(nil), branches...)
+	objects.SortBranches(branches)
+
+	if formatFlag == "json" {
+		list := make([]map[string]interface{}, len(branches))
+		for i, b := range branches {
+			list[i] = map[string]interface{}{
+				"name":        b.Name,
+				"target_type": b.TargetType,
+				"target":      nil,
+			}
+			if b.Target != "" {
+				list[i]["target"] = b.Target
+			}
+			if !utf8.ValidString(b.Name) {
+				list[i]["name_base64"] = []byte(b.Name)
+			}
+		}
+		return writeJSON(map[string]interface{}{
+			"swhid":    id.String(),
+			"branches": list,
+		})
+	}
+
+	fmt.Println(id)
+	for i, b := range branches {
+		target := b.Target
+		if target == "" {
+			target = "-"
+		} else if b.TargetType == objects.BranchTargetAlias {
+			target = displayName(target)
+		}
+		fmt.Printf("%4d  %-9s %-40s  %s\n", i+1, b.TargetType, target, displayName(b.Name))
+	}
+	return nil
+}
+
+// displayName returns a branch name as it is when it prints safely, and
+// quoted with escapes when it is not valid UTF-8 or holds control
+// characters.
+func displayName(name string) string {
+	if !utf8.ValidString(name) || strings.ContainsFunc(name, unicode.IsControl) {
+		return strconv.Quote(name)
+	}
+	return name
+}
+
+// parseDate accepts an RFC 3339 timestamp, a YYYY-MM-DD date (the start
+// of that day in UTC) or unix seconds.
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	if seconds, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q (expected RFC 3339, YYYY-MM-DD or unix seconds)", s)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/andrew/swhid-go/sums"
+)
+
+func runSums(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("sums subcommand required (create, verify)")
+	}
+
+	switch args[0] {
+	case "create":
+		return runSumsCreate(args[1:])
+	case "verify":
+		return runSumsVerify(args[1:])
+	default:
+		return fmt.Errorf("unknown sums subcommand: %s", args[0])
+	}
+}
+
+// runSumsCreate writes a SWHIDSUMS line for each path to the --output file
+// or stdout.
+func runSumsCreate(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("file or directory path required")
+	}
+
+	entries, err := sums.Create(args, treeOptions())
+	if err != nil {
+		return err
+	}
+
+	if formatFlag == "json" && (outputFlag == "" || outputFlag == "-") {
+		list := []map[string]interface{}{}
+		for _, e := range entries {
+			list = append(list, map[string]interface{}{"path": e.Path, "swhid": e.SWHID.String()})
+		}
+		return writeJSON(map[string]interface{}{"results": list})
+	}
+	if outputFlag == "" || outputFlag == "-" {
+		return sums.Write(os.Stdout, entries)
+	}
+	f, err := os.Create(outputFlag)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := sums.Write(f, entries); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// runSumsVerify checks every line of a SWHIDSUMS file, reporting like
+// sha256sum --check.
+func runSumsVerify(args []string) error {
+	path := sums.DefaultFilename
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	entries, err := sums.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	results := sums.Verify(entries, treeOptions())
+
+	mismatched, unreadable := 0, 0
+	for _, r := range results {
+		switch {
+		case r.Err == nil:
+		case errors.Is(r.Err, sums.ErrMismatch):
+			mismatched++
+		default:
+			unreadable++
+		}
+	}
+
+	if formatFlag == "json" {
+		var list []map[string]interface{}
+		for _, r := range results {
+			item := map[string]interface{}{
+				"path":  r.Path,
+				"swhid": r.SWHID.CoreSWHID(),
+				"ok":    r.Err == nil,
+			}
+			if r.Actual != nil {
+				item["actual"] = r.Actual.CoreSWHID()
+			}
+			if r.Err != nil && !errors.Is(r.Err, sums.ErrMismatch) {
+				item["error"] = r.Err.Error()
+			}
+			list = append(list, item)
+		}
+		if err := writeJSON(map[string]interface{}{"results": list}); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range results {
+			switch {
+			case r.Err == nil:
+				fmt.Printf("%s: OK\n", r.Path)
+			case errors.Is(r.Err, sums.ErrMismatch):
+				fmt.Printf("%s: FAILED\n", r.Path)
+			default:
+				fmt.Fprintf(os.Stderr, "swhid: %v\n", r.Err)
+				fmt.Printf("%s: FAILED open or read\n", r.Path)
+			}
+		}
+	}
+
+	switch {
+	case mismatched > 0 && unreadable > 0:
+		return fmt.Errorf("%d of %d SWHIDs did not match and %d paths could not be read", mismatched, len(results), unreadable)
+	case mismatched > 0:
+		return fmt.Errorf("%d of %d SWHIDs did not match", mismatched, len(results))
+	case unreadable > 0:
+		return fmt.Errorf("%d of %d paths could not be read", unreadable, len(results))
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrew/swhid-go/sums"
+)
+
+func TestSumsCreateOutputAfterPaths(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	os.WriteFile(a, []byte("hello\n"), 0644)
+	os.WriteFile(b, []byte("world\n"), 0644)
+	out := filepath.Join(t.TempDir(), sums.DefaultFilename)
+
+	stdout, err := runCommand(t, "sums", "create", a, b, "-o", out)
+	if err != nil {
+		t.Fatalf("sums create error = %v", err)
+	}
+	if stdout != "" {
+		t.Errorf("sums create -o wrote %q to stdout", stdout)
+	}
+	entries, err := sums.ReadFile(out)
+	if err != nil {
+		t.Fatalf("sums.ReadFile() error = %v", err)
+	}
+	if len(entries) != 2 || entries[0].Path != a || entries[1].Path != b ||
+		entries[0].SWHID.String() != helloSWHID {
+		t.Errorf("sums create -o wrote %+v", entries)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/attest"
+)
+
+// runVerify recomputes the SWHID of a file, directory or repository object
+// and reports whether it matches the expected one. The report is a
+// VerificationResult, or a DSSE envelope of one with --sign-command.
+func runVerify(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("SWHID and path required")
+	}
+
+	target, err := swhid.Parse(args[0])
+	if err != nil {
+		return err
+	}
+	path := args[1]
+	ref := ""
+	if len(args) > 2 {
+		ref = args[2]
+	}
+
+	recomputed, method, err := recompute(target, path, ref)
+	if err != nil {
+		return err
+	}
+	result := attest.NewResult(target, recomputed, method, path, cliVersion())
+
+	var report interface{} = result
+	if signCommand != "" {
+		env, err := attest.Sign(result, &commandSigner{command: signCommand})
+		if err != nil {
+			return err
+		}
+		report = env
+	}
+
+	if formatFlag == "json" || signCommand != "" || outputFlag != "" {
+		if err := writeDocument(report); err != nil {
+			return err
+		}
+	} else {
+		status := "OK"
+		if !result.Match {
+			status = "MISMATCH"
+		}
+		fmt.Printf("Status:     %s\n", status)
+		fmt.Printf("Target:     %s\n", result.Target)
+		fmt.Printf("Recomputed: %s\n", result.Recomputed)
+		fmt.Printf("Method:     %s\n", result.Method)
+		fmt.Printf("Subject:    %s\n", result.Subject)
+		fmt.Printf("Time:       %s\n", result.Timestamp.Format(time.RFC3339))
+	}
+
+	if !result.Match {
+		return fmt.Errorf("SWHID mismatch: expected %s, computed %s", target.CoreSWHID(), recomputed.CoreSWHID())
+	}
+	return nil
+}
+
+// recompute computes the SWHID of the object at path that target is
+// expected to identify. ref selects the commit or tag in a repository; a
+// revision defaults to the target's own hash.
+func recompute(target *swhid.Identifier, path, ref string) (*swhid.Identifier, string, error) {
+	switch target.ObjectType {
+	case swhid.ObjectTypeContent:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, "", err
+		}
+		return swhid.FromContent(data), attest.MethodFile, nil
+
+	case swhid.ObjectTypeDirectory:
+		node, err := swhid.TreeFromDirectoryPathWithOptions(path, treeOptions())
+		if err != nil {
+			return nil, "", err
+		}
+		return node.ID, attest.MethodDirectory, nil
+	}
+
+	opts, err := gitOptions()
+	if err != nil {
+		return nil, "", err
+	}
+	switch target.ObjectType {
+	case swhid.ObjectTypeRevision:
+		if ref == "" {
+			ref = target.ObjectHash
+		}
+		id, err := swhid.FromRevisionWithOptions(path, ref, opts)
+		return id, attest.MethodGitRevision, err
+	case swhid.ObjectTypeRelease:
+		if ref == "" {
+			return nil, "", fmt.Errorf("tag name required to verify a release")
+		}
+		id, err := swhid.FromReleaseWithOptions(path, ref, opts)
+		return id, attest.MethodGitRelease, err
+	default:
+		id, err := swhid.FromSnapshotWithOptions(path, opts)
+		return id, attest.MethodGitSnapshot, err
+	}
+}
+
+// commandSigner signs by running a shell command with the message on
+// standard input and reading the raw signature from standard output, so
+// any signing tool can be plugged in, for instance
+//
+//	openssl dgst -sha256 -sign key.pem
+type commandSigner struct {
+	command string
+}
+
+func (s *commandSigner) KeyID() string { return "" }
+
+func (s *commandSigner) Sign(message []byte) ([]byte, error) {
+	cmd := exec.Command("sh", "-c", s.command)
+	cmd.Stdin = bytes.NewReader(message)
+	cmd.Stderr = os.Stderr
+	sig, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("sign command failed: %w", err)
+	}
+	return sig, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/buildinfo"
+)
+
+// version is set with -ldflags "-X main.version=...". It falls back to the
+// module version recorded by the Go toolchain.
+var version string
+
+func cliVersion() string {
+	if version != "" {
+		return version
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok && bi.Main.Version != "" {
+		return bi.Main.Version
+	}
+	return "(devel)"
+}
+
+func runVersion(args []string) error {
+	if ldflagsFlag {
+		path := "."
+		if len(args) > 0 {
+			path = args[0]
+		}
+		flags, err := buildinfo.LDFlags(path)
+		if err != nil {
+			return err
+		}
+		if formatFlag == "json" {
+			return writeJSON(map[string]interface{}{"ldflags": flags})
+		}
+		fmt.Println(flags)
+		return nil
+	}
+
+	if !provenanceFlag {
+		if formatFlag == "json" {
+			return writeJSON(map[string]interface{}{"version": cliVersion()})
+		}
+		fmt.Printf("swhid %s\n", cliVersion())
+		return nil
+	}
+
+	info, err := buildinfo.Provenance()
+	if err != nil {
+		return err
+	}
+
+	switch formatFlag {
+	case "json":
+		data := map[string]interface{}{
+			"version":  cliVersion(),
+			"modified": info.Modified,
+		}
+		if info.Directory != nil {
+			data["directory"] = info.Directory.String()
+		}
+		if info.Revision != nil {
+			data["revision"] = info.Revision.String()
+		}
+		if info.Origin != "" {
+			data["origin"] = info.Origin
+		}
+		return writeJSON(data)
+	default:
+		fmt.Printf("Version:   %s\n", cliVersion())
+		fmt.Printf("Directory: %s\n", identifierOrUnknown(info.Directory))
+		fmt.Printf("Revision:  %s\n", identifierOrUnknown(info.Revision))
+		if info.Origin != "" {
+			fmt.Printf("Origin:    %s\n", info.Origin)
+		}
+		if info.Modified {
+			fmt.Println("Modified:  true (built from a dirty worktree)")
+		}
+	}
+	return nil
+}
+
+func identifierOrUnknown(id *swhid.Identifier) string {
+	if id == nil {
+		return "unknown"
+	}
+	return id.String()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/andrew/swhid-go/archive"
+)
+
+// runVisits lists the archive's visits of an origin, most recent first,
+// with the snapshot each one recorded.
+func runVisits(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("origin URL required")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	token, _ := apiToken()
+	client := &archive.Client{Token: token}
+	visits, err := client.Visits(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	if formatFlag == "json" {
+		list := make([]map[string]interface{}, len(visits))
+		for i, v := range visits {
+			list[i] = map[string]interface{}{
+				"visit":    v.Visit,
+				"date":     v.Date.Format(time.RFC3339),
+				"type":     v.Type,
+				"status":   v.Status,
+				"snapshot": nil,
+			}
+			if v.Snapshot != nil {
+				list[i]["snapshot"] = v.Snapshot.String()
+			}
+		}
+		return writeJSON(map[string]interface{}{
+			"origin": args[0],
+			"visits": list,
+		})
+	}
+
+	for _, v := range visits {
+		snapshot := "-"
+		if v.Snapshot != nil {
+			snapshot = v.Snapshot.CoreSWHID()
+		}
+		fmt.Printf("%s  %-8s %s\n", v.Date.UTC().Format(time.RFC3339), v.Status, snapshot)
+	}
+	return nil
+}
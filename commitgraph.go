@@ -0,0 +1,200 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	commitgraph "github.com/go-git/go-git/v5/plumbing/format/commitgraph/v2"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// RevisionNode is the part of a commit needed to walk history: its
+// identifier, root directory, parents and committer date. Unlike
+// RevisionRecord it can be filled from a commit-graph file without
+// decoding the commit object.
+type RevisionNode struct {
+	ID                 *Identifier
+	Directory          *Identifier
+	Parents            []*Identifier
+	CommitterTimestamp int64
+}
+
+// RevisionGraph answers parent, tree and date lookups for the commits of a
+// repository. It reads them from the repository's commit-graph file or
+// chain (written by git commit-graph write or gc) when there is one, and
+// decodes the commit object for commits the graph does not cover.
+type RevisionGraph struct {
+	repo  *git.Repository
+	index commitgraph.Index
+}
+
+// OpenRevisionGraph returns a RevisionGraph for repo. A missing or
+// unreadable commit-graph is not an error; lookups then fall back to the
+// object store. Close releases the commit-graph file.
+func OpenRevisionGraph(repo *git.Repository) *RevisionGraph {
+	g := &RevisionGraph{repo: repo}
+	if fs, ok := repo.Storer.(*filesystem.Storage); ok {
+		if index, err := commitgraph.OpenChainOrFileIndex(fs.Filesystem()); err == nil {
+			g.index = index
+		}
+	}
+	return g
+}
+
+// HasCommitGraph reports whether lookups are backed by a commit-graph.
+func (g *RevisionGraph) HasCommitGraph() bool {
+	return g.index != nil
+}
+
+// Revision returns the node for the commit with the given hash.
+func (g *RevisionGraph) Revision(hash plumbing.Hash) (RevisionNode, error) {
+	if g.index != nil {
+		if i, err := g.index.GetIndexByHash(hash); err == nil {
+			data, err := g.index.GetCommitDataByIndex(i)
+			if err != nil {
+				return RevisionNode{}, fmt.Errorf("failed to read commit-graph entry for %s: %w", hash, err)
+			}
+			return newRevisionNode(hash, data.TreeHash, data.ParentHashes, data.When.Unix()), nil
+		}
+	}
+
+	commit, err := g.repo.CommitObject(hash)
+	if err != nil {
+		return RevisionNode{}, err
+	}
+	return newRevisionNode(commit.Hash, commit.TreeHash, commit.ParentHashes, commit.Committer.When.Unix()), nil
+}
+
+// Close releases the commit-graph, if one was opened.
+func (g *RevisionGraph) Close() error {
+	if g.index == nil {
+		return nil
+	}
+	return g.index.Close()
+}
+
+// WalkRevisionGraph calls fn for every commit reachable from HEAD or any
+// reference of the repository at repoPath, each commit once, like
+// WalkHistory but without decoding commits that are in the repository's
+// commit-graph. Use it when only identifiers, parents and dates are needed.
+// Without a commit-graph it decodes every commit, as WalkHistory does.
+func WalkRevisionGraph(repoPath string, fn func(RevisionNode) error) error {
+	repo, err := openRepository(repoPath, false, false)
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	return WalkRevisionGraphRepo(repo, fn)
+}
+
+// WalkRevisionGraphRepo is like WalkRevisionGraph for an already open
+// repository.
+func WalkRevisionGraphRepo(repo *git.Repository, fn func(RevisionNode) error) error {
+	g := OpenRevisionGraph(repo)
+	defer g.Close()
+
+	tips, err := commitTips(repo)
+	if err != nil {
+		return fmt.Errorf("failed to walk history: %w", err)
+	}
+
+	seen := make(map[plumbing.Hash]bool)
+	stack := make([]plumbing.Hash, 0, len(tips))
+	for i := len(tips) - 1; i >= 0; i-- {
+		stack = append(stack, tips[i])
+	}
+
+	for len(stack) > 0 {
+		hash := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+
+		node, err := g.Revision(hash)
+		if err != nil {
+			return fmt.Errorf("failed to read revision %s: %w", hash, err)
+		}
+		if err := fn(node); err != nil {
+			return err
+		}
+		for i := len(node.Parents) - 1; i >= 0; i-- {
+			parent := plumbing.NewHash(node.Parents[i].ObjectHash)
+			if !seen[parent] {
+				stack = append(stack, parent)
+			}
+		}
+	}
+	return nil
+}
+
+// commitTips returns HEAD followed by the commits that references point at,
+// without duplicates. Annotated tags, including tags of tags, are peeled to
+// the commit they name; references to trees and blobs are skipped.
+func commitTips(repo *git.Repository) ([]plumbing.Hash, error) {
+	var tips []plumbing.Hash
+	seen := make(map[plumbing.Hash]bool)
+	add := func(h plumbing.Hash) {
+		for !seen[h] {
+			seen[h] = true
+			obj, err := repo.Storer.EncodedObject(plumbing.AnyObject, h)
+			if err != nil {
+				return
+			}
+			switch obj.Type() {
+			case plumbing.CommitObject:
+				tips = append(tips, h)
+				return
+			case plumbing.TagObject:
+				tag, err := object.DecodeTag(repo.Storer, obj)
+				if err != nil {
+					return
+				}
+				h = tag.Target
+			default:
+				return
+			}
+		}
+	}
+
+	if head, err := repo.Head(); err == nil {
+		add(head.Hash())
+	} else if err != plumbing.ErrReferenceNotFound {
+		return nil, err
+	}
+
+	refs, err := repo.References()
+	if err != nil {
+		return nil, err
+	}
+	defer refs.Close()
+
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference {
+			add(ref.Hash())
+		}
+		return nil
+	})
+	return tips, err
+}
+
+func newRevisionNode(hash, tree plumbing.Hash, parents []plumbing.Hash, committed int64) RevisionNode {
+	id, _ := NewIdentifier(ObjectTypeRevision, hash.String(), nil)
+	dir, _ := NewIdentifier(ObjectTypeDirectory, tree.String(), nil)
+	node := RevisionNode{
+		ID:                 id,
+		Directory:          dir,
+		Parents:            make([]*Identifier, 0, len(parents)),
+		CommitterTimestamp: committed,
+	}
+	for _, parent := range parents {
+		parentID, _ := NewIdentifier(ObjectTypeRevision, parent.String(), nil)
+		node.Parents = append(node.Parents, parentID)
+	}
+	return node
+}
@@ -0,0 +1,132 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	commitgraph "github.com/go-git/go-git/v5/plumbing/format/commitgraph/v2"
+)
+
+// writeCommitGraph writes a commit-graph file covering the given commits,
+// with When overridden so tests can tell graph reads from object reads.
+func writeCommitGraph(t *testing.T, repoPath string, when time.Time, commits map[plumbing.Hash]*commitgraph.CommitData) {
+	t.Helper()
+
+	index := commitgraph.NewMemoryIndex()
+	for hash, data := range commits {
+		data.When = when
+		data.Generation = 1
+		index.Add(hash, data)
+	}
+
+	path := filepath.Join(repoPath, ".git", "objects", "info", "commit-graph")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer f.Close()
+	if err := commitgraph.NewEncoder(f).Encode(index); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+}
+
+func TestWalkRevisionGraph(t *testing.T) {
+	repoPath, repo, first := newTestRepo(t)
+
+	if err := os.WriteFile(filepath.Join(repoPath, "second.txt"), []byte("second\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	second := commitAll(t, repo, "Second commit\n")
+
+	// The graph only covers the first commit, as if it was written before
+	// the second; the second must be decoded from the object store.
+	firstCommit, _ := repo.CommitObject(first)
+	graphTime := time.Unix(1234567890, 0)
+	writeCommitGraph(t, repoPath, graphTime, map[plumbing.Hash]*commitgraph.CommitData{
+		first: {TreeHash: firstCommit.TreeHash},
+	})
+
+	var nodes []RevisionNode
+	err := WalkRevisionGraph(repoPath, func(n RevisionNode) error {
+		nodes = append(nodes, n)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkRevisionGraph() error = %v", err)
+	}
+	if len(nodes) != 2 {
+		t.Fatalf("WalkRevisionGraph() visited %d commits, want 2", len(nodes))
+	}
+
+	var records []RevisionRecord
+	if err := WalkHistory(repoPath, func(r RevisionRecord) error {
+		records = append(records, r)
+		return nil
+	}); err != nil {
+		t.Fatalf("WalkHistory() error = %v", err)
+	}
+	want := map[string]RevisionRecord{}
+	for _, r := range records {
+		want[r.ID.ObjectHash] = r
+	}
+
+	for _, n := range nodes {
+		r, ok := want[n.ID.ObjectHash]
+		if !ok {
+			t.Fatalf("unexpected commit %v", n.ID)
+		}
+		if !n.Directory.Equal(r.Directory) {
+			t.Errorf("%s Directory = %v, want %v", n.ID.ObjectHash, n.Directory, r.Directory)
+		}
+		if len(n.Parents) != len(r.Parents) {
+			t.Fatalf("%s Parents = %v, want %v", n.ID.ObjectHash, n.Parents, r.Parents)
+		}
+		for i := range n.Parents {
+			if !n.Parents[i].Equal(r.Parents[i]) {
+				t.Errorf("%s Parents[%d] = %v, want %v", n.ID.ObjectHash, i, n.Parents[i], r.Parents[i])
+			}
+		}
+
+		wantTime := r.CommitterTimestamp
+		if n.ID.ObjectHash == first.String() {
+			wantTime = graphTime.Unix()
+		}
+		if n.CommitterTimestamp != wantTime {
+			t.Errorf("%s CommitterTimestamp = %d, want %d", n.ID.ObjectHash, n.CommitterTimestamp, wantTime)
+		}
+	}
+
+	if nodes[0].ID.ObjectHash != second.String() {
+		t.Errorf("first node = %v, want HEAD %s", nodes[0].ID, second)
+	}
+}
+
+func TestRevisionGraphWithoutCommitGraph(t *testing.T) {
+	_, repo, first := newTestRepo(t)
+
+	g := OpenRevisionGraph(repo)
+	defer g.Close()
+	if g.HasCommitGraph() {
+		t.Error("HasCommitGraph() = true, want false")
+	}
+
+	node, err := g.Revision(first)
+	if err != nil {
+		t.Fatalf("Revision() error = %v", err)
+	}
+	if node.ID.ObjectHash != first.String() || len(node.Parents) != 0 {
+		t.Errorf("Revision() = %+v", node)
+	}
+
+	if _, err := g.Revision(plumbing.NewHash("0123456789abcdef0123456789abcdef01234567")); err == nil {
+		t.Error("Revision() expected error for missing commit")
+	}
+}
@@ -0,0 +1,77 @@
+package swhid
+
+import (
+	"sort"
+
+	"github.com/andrew/swhid-go/objects"
+)
+
+// SubtreeMatch is a directory that appears, identically, in two trees.
+type SubtreeMatch struct {
+	ID     *Identifier
+	PathsA []string // where the subtree occurs in the first tree
+	PathsB []string // where the subtree occurs in the second tree
+	Size   int64    // content bytes in one copy of the subtree
+	Files  int      // non-directory entries in one copy of the subtree
+}
+
+// CommonSubtrees returns the directories shared by trees a and b, such as
+// vendored code or the unchanged parts of a fork. Each shared directory is
+// reported at its highest occurrence only: directories inside a match are
+// not listed again. Empty directories are ignored. Results are sorted by
+// size, largest first.
+func CommonSubtrees(a, b *Node) []SubtreeMatch {
+	inB := make(map[string][]string)
+	b.Walk(func(n *Node) bool {
+		if isNonEmptyDir(n) {
+			inB[n.ID.ObjectHash] = append(inB[n.ID.ObjectHash], n.Path)
+		}
+		return true
+	})
+
+	matches := make(map[string]*SubtreeMatch)
+	var order []string
+	a.Walk(func(n *Node) bool {
+		if !isNonEmptyDir(n) {
+			return true
+		}
+		if _, ok := inB[n.ID.ObjectHash]; !ok {
+			return true
+		}
+		m, ok := matches[n.ID.ObjectHash]
+		if !ok {
+			m = &SubtreeMatch{ID: n.ID, Size: n.Size, Files: n.Files()}
+			matches[n.ID.ObjectHash] = m
+			order = append(order, n.ID.ObjectHash)
+		}
+		m.PathsA = append(m.PathsA, n.Path)
+		return false
+	})
+
+	b.Walk(func(n *Node) bool {
+		if m, ok := matches[n.ID.ObjectHash]; ok && n.Type == objects.EntryTypeDirectory {
+			m.PathsB = append(m.PathsB, n.Path)
+			return false
+		}
+		return true
+	})
+
+	result := make([]SubtreeMatch, 0, len(order))
+	for _, hash := range order {
+		m := matches[hash]
+		// Only nested inside other matches in b: report every occurrence.
+		if len(m.PathsB) == 0 {
+			m.PathsB = inB[hash]
+		}
+		result = append(result, *m)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Size > result[j].Size
+	})
+	return result
+}
+
+func isNonEmptyDir(n *Node) bool {
+	return n.Type == objects.EntryTypeDirectory && len(n.Children) > 0
+}
@@ -0,0 +1,65 @@
+package swhid
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestCommonSubtrees(t *testing.T) {
+	a := fstest.MapFS{
+		"main.go":                     {Data: []byte("package main\n")},
+		"vendor/lib/lib.go":           {Data: []byte("package lib\n")},
+		"vendor/lib/internal/util.go": {Data: []byte("package internal\n")},
+		"vendor/modules.txt":          {Data: []byte("# lib\n")},
+		"docs/readme.txt":             {Data: []byte("docs a\n")},
+	}
+	b := fstest.MapFS{
+		"cmd/tool.go":                      {Data: []byte("package main\n\nfunc main() {}\n")},
+		"third_party/lib/lib.go":           {Data: []byte("package lib\n")},
+		"third_party/lib/internal/util.go": {Data: []byte("package internal\n")},
+		"docs/readme.txt":                  {Data: []byte("docs b\n")},
+	}
+
+	treeA, err := TreeFromFS(a)
+	if err != nil {
+		t.Fatalf("TreeFromFS() error = %v", err)
+	}
+	treeB, err := TreeFromFS(b)
+	if err != nil {
+		t.Fatalf("TreeFromFS() error = %v", err)
+	}
+
+	matches := CommonSubtrees(treeA, treeB)
+	if len(matches) != 1 {
+		t.Fatalf("CommonSubtrees() returned %d matches, want 1: %+v", len(matches), matches)
+	}
+
+	m := matches[0]
+	if len(m.PathsA) != 1 || m.PathsA[0] != "vendor/lib" {
+		t.Errorf("PathsA = %v, want [vendor/lib]", m.PathsA)
+	}
+	if len(m.PathsB) != 1 || m.PathsB[0] != "third_party/lib" {
+		t.Errorf("PathsB = %v, want [third_party/lib]", m.PathsB)
+	}
+	if m.Files != 2 {
+		t.Errorf("Files = %d, want 2", m.Files)
+	}
+	if want := int64(len("package lib\n") + len("package internal\n")); m.Size != want {
+		t.Errorf("Size = %d, want %d", m.Size, want)
+	}
+}
+
+func TestCommonSubtreesIdentical(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/file.txt": {Data: []byte("x\n")},
+	}
+	tree, err := TreeFromFS(fsys)
+	if err != nil {
+		t.Fatalf("TreeFromFS() error = %v", err)
+	}
+
+	matches := CommonSubtrees(tree, tree)
+	if len(matches) != 1 || matches[0].PathsA[0] != "" || matches[0].PathsB[0] != "" {
+		t.Errorf("CommonSubtrees() = %+v, want a single root match", matches)
+	}
+}
@@ -0,0 +1,77 @@
+// Package dataset exports a local Git repository in the edge-list layout
+// used by the Software Heritage graph dataset (swh-graph), so local objects
+// can be merged with the public graph.
+//
+// Two CSV files are produced. The nodes file holds one SWHID per line, sorted.
+// The edges file holds one edge per line as "<src> <dst>", followed for
+// labelled edges by the base64-encoded label and, for directory entries, the
+// entry permissions:
+//
+//	swh:1:snp:... swh:1:rev:... cmVmcy9oZWFkcy9tYWlu
+//	swh:1:rev:... swh:1:dir:...
+//	swh:1:rev:... swh:1:rev:...
+//	swh:1:dir:... swh:1:cnt:... UkVBRE1F 33188
+//
+// The swh-graph tooling expects the files zstd-compressed as
+// graph.nodes.csv.zst and graph.edges.csv.zst; compress them with zstd
+// before loading.
+package dataset
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/graph"
+)
+
+// Export writes the nodes and edges of the repository at repoPath: its
+// snapshot, and every release, revision, directory and content reachable
+// from it.
+func Export(repoPath string, nodes, edges io.Writer) error {
+	return ExportWithOptions(repoPath, swhid.GitOptions{}, nodes, edges)
+}
+
+// ExportWithOptions is Export opening the repository as
+// graph.FromRepositoryWithOptions does.
+func ExportWithOptions(repoPath string, opts swhid.GitOptions, nodes, edges io.Writer) error {
+	g, err := graph.FromRepositoryWithOptions(repoPath, opts)
+	if err != nil {
+		return err
+	}
+	return WriteGraph(g, nodes, edges)
+}
+
+// WriteGraph writes the nodes and edges of an in-memory graph.
+func WriteGraph(g *graph.Graph, nodes, edges io.Writer) error {
+	nw := bufio.NewWriter(nodes)
+	ew := bufio.NewWriter(edges)
+
+	for _, n := range g.Nodes() {
+		fmt.Fprintln(nw, n.ID.CoreSWHID())
+
+		for _, e := range n.Edges {
+			src, dst := e.From.ID.CoreSWHID(), e.To.ID.CoreSWHID()
+			switch {
+			case e.Label == "":
+				fmt.Fprintf(ew, "%s %s\n", src, dst)
+			case e.Perms == "":
+				fmt.Fprintf(ew, "%s %s %s\n", src, dst, base64.StdEncoding.EncodeToString([]byte(e.Label)))
+			default:
+				perms, err := strconv.ParseUint(e.Perms, 8, 32)
+				if err != nil {
+					return fmt.Errorf("invalid permissions %q on %s: %w", e.Perms, src, err)
+				}
+				fmt.Fprintf(ew, "%s %s %s %d\n", src, dst, base64.StdEncoding.EncodeToString([]byte(e.Label)), perms)
+			}
+		}
+	}
+
+	if err := nw.Flush(); err != nil {
+		return err
+	}
+	return ew.Flush()
+}
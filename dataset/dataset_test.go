@@ -0,0 +1,77 @@
+package dataset
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+
+	tmpDir, err := os.MkdirTemp("", "swhid-dataset-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+
+	repo, err := git.PlainInit(tmpDir, false)
+	if err != nil {
+		t.Fatalf("Failed to init repository: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "hello.txt"), []byte("hello\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+	if _, err := wt.Add("hello.txt"); err != nil {
+		t.Fatalf("Failed to stage file: %v", err)
+	}
+	sig := &object.Signature{Name: "Test", Email: "test@example.com", When: time.Unix(1000000000, 0).UTC()}
+	if _, err := wt.Commit("Initial commit\n", &git.CommitOptions{Author: sig, Committer: sig}); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	return tmpDir
+}
+
+func TestExport(t *testing.T) {
+	repoPath := newTestRepo(t)
+
+	var nodes, edges bytes.Buffer
+	if err := Export(repoPath, &nodes, &edges); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	nodeLines := strings.Split(strings.TrimSpace(nodes.String()), "\n")
+	counts := map[string]int{}
+	for _, n := range nodeLines {
+		counts[n[6:9]]++
+	}
+	for objType, want := range map[string]int{"snp": 1, "rev": 1, "dir": 1, "cnt": 1} {
+		if counts[objType] != want {
+			t.Errorf("%s nodes = %d, want %d (nodes: %v)", objType, counts[objType], want, nodeLines)
+		}
+	}
+
+	wantEdge := "swh:1:cnt:ce013625030ba8dba906f756967f9e9ca394464a " +
+		base64.StdEncoding.EncodeToString([]byte("hello.txt")) + " 33188"
+	if !strings.Contains(edges.String(), wantEdge) {
+		t.Errorf("edges missing directory entry edge %q:\n%s", wantEdge, edges.String())
+	}
+
+	mainLabel := base64.StdEncoding.EncodeToString([]byte("refs/heads/master"))
+	if !strings.Contains(edges.String(), mainLabel) {
+		t.Errorf("edges missing snapshot branch label %q:\n%s", mainLabel, edges.String())
+	}
+}
@@ -0,0 +1,319 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ErrModified is returned by DescribeFile for a file whose contents on disk
+// differ from the committed version, which the archive cannot have.
+var ErrModified = errors.New("file has uncommitted changes")
+
+// DescribeFile returns the fully qualified SWHID of a file as committed at
+// HEAD: its content SWHID with the origin taken from the repository's
+// "origin" remote (or its only remote), the HEAD revision as anchor, and
+// the file's path from the repository root. filePath is absolute or
+// relative to repoPath, which may be any directory inside the worktree.
+// The origin qualifier is left out when the repository has no remote.
+func DescribeFile(ctx context.Context, repoPath, filePath string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	repo, err := openRepository(repoPath, true, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to open repository: %w", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return "", fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	if !filepath.IsAbs(filePath) {
+		filePath = filepath.Join(repoPath, filePath)
+	}
+	rel, err := worktreePath(wt.Filesystem.Root(), filePath)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := FromGitBlobRepo(repo, "HEAD", rel)
+	if err != nil {
+		return "", err
+	}
+	if onDisk, err := fileContent(filePath); err != nil {
+		return "", err
+	} else if !FromContent(onDisk).Equal(content) {
+		return "", fmt.Errorf("%w: %s", ErrModified, rel)
+	}
+
+	anchor, err := FromRevisionRepo(repo, "HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	qualifiers := map[string]string{
+		QualifierAnchor: anchor.CoreSWHID(),
+		QualifierPath:   "/" + rel,
+	}
+	if origin, err := remoteOrigin(repo); err != nil {
+		return "", err
+	} else if origin != "" {
+		qualifiers[QualifierOrigin] = origin
+	}
+
+	id, err := NewIdentifier(ObjectTypeContent, content.ObjectHash, qualifiers)
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// Kinds of path told apart by Describe.
+const (
+	DescribedFile       = "file"       // a file or symbolic link
+	DescribedDirectory  = "directory"  // a directory, possibly inside a repository
+	DescribedRepository = "repository" // the top of a worktree, or a bare repository
+)
+
+// Description is every SWHID that applies to a path, as found by Describe.
+// Identifiers that do not apply are nil.
+type Description struct {
+	Path string // the path described, made absolute
+	Kind string
+
+	Content   *Identifier // a file's contents as on disk
+	Directory *Identifier // a directory as on disk, or a repository's tree at HEAD
+	Revision  *Identifier // HEAD of the enclosing repository
+	Snapshot  *Identifier // a repository's references
+	Origin    string      // the repository's origin remote; see NormalizeOriginURL
+
+	// Qualified is Content or Directory with origin, anchor and path
+	// qualifiers. It is set only when the path is committed at HEAD
+	// unchanged, since the archive cannot have anything else.
+	Qualified *Identifier
+}
+
+// Describe computes every identifier that applies to path at once: the
+// content SWHID of a file, the directory SWHID of a directory, and, inside
+// a Git repository, the HEAD revision and origin they are anchored to. For
+// the top of a worktree it adds the snapshot, and the directory is the
+// tree of HEAD rather than the files on disk, so that untracked files do
+// not leak into it. opts applies to directories hashed from disk.
+func Describe(ctx context.Context, path string, opts TreeOptions) (*Description, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Lstat(abs)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Description{Path: abs, Kind: DescribedFile}
+	dir := filepath.Dir(abs)
+	if info.IsDir() {
+		d.Kind = DescribedDirectory
+		dir = abs
+	} else {
+		content, err := fileContent(abs)
+		if err != nil {
+			return nil, err
+		}
+		d.Content = FromContent(content)
+	}
+
+	repo := discoverGitRepo(dir, opts.ReadOnlyFS)
+	rel, inWorktree := "", false
+	if repo != nil {
+		if wt, err := repo.Worktree(); err == nil {
+			rel, err = repoRelativePath(wt.Filesystem.Root(), abs)
+			if err != nil {
+				return nil, err
+			}
+			inWorktree = true
+		} else if !errors.Is(err, git.ErrIsBareRepository) || d.Kind == DescribedFile {
+			repo = nil
+		}
+	}
+	if repo != nil && d.Kind == DescribedDirectory && rel == "" {
+		d.Kind = DescribedRepository
+	}
+
+	var commit *object.Commit
+	if repo != nil {
+		commit, err = resolveCommitIn(repo, "HEAD")
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			commit = nil // no commits yet
+		} else if err != nil {
+			return nil, err
+		}
+		if commit != nil {
+			d.Revision = FromRevisionMetadata(revisionMetadata(repo, commit))
+		}
+		if d.Origin, err = remoteOrigin(repo); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case d.Kind == DescribedRepository:
+		if d.Snapshot, err = FromSnapshotRepo(repo); err != nil {
+			return nil, err
+		}
+		if commit != nil {
+			d.Directory, _ = NewIdentifier(ObjectTypeDirectory, commit.TreeHash.String(), nil)
+		} else if inWorktree {
+			d.Directory, err = treeID(ctx, abs, opts)
+		}
+	case d.Kind == DescribedDirectory:
+		d.Directory, err = treeID(ctx, abs, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if commit != nil && inWorktree {
+		d.Qualified, err = d.qualify(commit, rel)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// qualify returns the described content or directory with origin, anchor
+// and path qualifiers, or nil when it is not what HEAD has at rel.
+func (d *Description) qualify(commit *object.Commit, rel string) (*Identifier, error) {
+	id := d.Content
+	if id == nil {
+		id = d.Directory
+	}
+
+	committed := commit.TreeHash
+	if rel != "" {
+		tree, err := commit.Tree()
+		if err != nil {
+			return nil, err
+		}
+		entry, err := tree.FindEntry(rel)
+		if errors.Is(err, object.ErrEntryNotFound) || errors.Is(err, object.ErrDirectoryNotFound) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		committed = entry.Hash
+	}
+	if committed.String() != id.ObjectHash {
+		return nil, nil
+	}
+
+	qualifiers := map[string]string{QualifierAnchor: d.Revision.CoreSWHID()}
+	if rel != "" {
+		qualifiers[QualifierPath] = "/" + rel
+	}
+	if d.Origin != "" {
+		qualifiers[QualifierOrigin] = d.Origin
+	}
+	return NewIdentifier(id.ObjectType, id.ObjectHash, qualifiers)
+}
+
+func treeID(ctx context.Context, path string, opts TreeOptions) (*Identifier, error) {
+	node, err := TreeFromDirectoryPathContext(ctx, path, opts)
+	if err != nil {
+		return nil, err
+	}
+	return node.ID, nil
+}
+
+// repoRelativePath is like worktreePath but returns "" for the worktree
+// root itself.
+func repoRelativePath(root, path string) (string, error) {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	dir, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return "", err
+	}
+	if filepath.Join(dir, filepath.Base(path)) == realRoot {
+		return "", nil
+	}
+	return worktreePath(root, path)
+}
+
+// worktreePath returns the slash-separated path of file relative to root,
+// resolving symbolic links in the directories of both so that paths
+// through a linked temporary directory still compare equal.
+func worktreePath(root, file string) (string, error) {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	dir, err := filepath.EvalSymlinks(filepath.Dir(file))
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(realRoot, filepath.Join(dir, filepath.Base(file)))
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is not a file inside the worktree %s", file, root)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// fileContent returns what Git hashes for a file: its bytes, or the target
+// of a symbolic link.
+func fileContent(path string) ([]byte, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(target), nil
+	}
+	return os.ReadFile(path)
+}
+
+// remoteOrigin returns the URL of the "origin" remote, or of the only
+// remote, normalized to the form the archive lists origins under; it is
+// empty when there is no such remote.
+func remoteOrigin(repo *git.Repository) (string, error) {
+	remote, err := repo.Remote("origin")
+	if errors.Is(err, git.ErrRemoteNotFound) {
+		remotes, err := repo.Remotes()
+		if err != nil {
+			return "", err
+		}
+		if len(remotes) != 1 {
+			return "", nil
+		}
+		remote = remotes[0]
+	} else if err != nil {
+		return "", err
+	}
+
+	urls := remote.Config().URLs
+	if len(urls) == 0 {
+		return "", nil
+	}
+	return NormalizeOriginURL(urls[0]), nil
+}
@@ -0,0 +1,142 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5/config"
+)
+
+func TestDescribeFile(t *testing.T) {
+	dir, repo, _ := newTestRepo(t)
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	head := commitAll(t, repo, "Add main\n")
+	anchor, _ := FromRevision(dir, head.String())
+
+	got, err := DescribeFile(context.Background(), dir, "src/main.go")
+	if err != nil {
+		t.Fatalf("DescribeFile() error = %v", err)
+	}
+	want := FromContent([]byte("package main\n")).CoreSWHID() + ";anchor=" + anchor.CoreSWHID() + ";path=/src/main.go"
+	if got != want {
+		t.Errorf("DescribeFile() = %s, want %s", got, want)
+	}
+
+	if _, err := repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{"git@github.com:example/repo.git"}}); err != nil {
+		t.Fatalf("CreateRemote() error = %v", err)
+	}
+	got, err = DescribeFile(context.Background(), filepath.Join(dir, "src"), filepath.Join(dir, "src", "main.go"))
+	if err != nil {
+		t.Fatalf("DescribeFile() error = %v", err)
+	}
+	want = FromContent([]byte("package main\n")).CoreSWHID() + ";origin=https://github.com/example/repo;anchor=" + anchor.CoreSWHID() + ";path=/src/main.go"
+	if got != want {
+		t.Errorf("DescribeFile() = %s, want %s", got, want)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("edited\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := DescribeFile(context.Background(), dir, "hello.txt"); !errors.Is(err, ErrModified) {
+		t.Errorf("DescribeFile() of a modified file error = %v, want ErrModified", err)
+	}
+	if _, err := DescribeFile(context.Background(), dir, "src"); !errors.Is(err, ErrNotBlob) {
+		t.Errorf("DescribeFile() of a directory error = %v, want ErrNotBlob", err)
+	}
+	if _, err := DescribeFile(context.Background(), dir, "../outside.txt"); err == nil {
+		t.Error("DescribeFile() of a path outside the worktree succeeded")
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	ctx := context.Background()
+	dir, repo, _ := newTestRepo(t)
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	head := commitAll(t, repo, "Add main\n")
+	if _, err := repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{"git@github.com:example/repo.git"}}); err != nil {
+		t.Fatalf("CreateRemote() error = %v", err)
+	}
+	anchor, _ := FromRevision(dir, head.String())
+	snapshot, _ := FromSnapshot(dir)
+	origin := "https://github.com/example/repo"
+
+	file, err := Describe(ctx, filepath.Join(dir, "src", "main.go"), TreeOptions{})
+	if err != nil {
+		t.Fatalf("Describe() error = %v", err)
+	}
+	content := FromContent([]byte("package main\n"))
+	if file.Kind != DescribedFile || !file.Content.Equal(content) || !file.Revision.Equal(anchor) || file.Origin != origin {
+		t.Errorf("Describe(file) = %+v", file)
+	}
+	if file.Directory != nil || file.Snapshot != nil {
+		t.Errorf("Describe(file) = %+v, want no directory or snapshot", file)
+	}
+	want := content.CoreSWHID() + ";origin=" + origin + ";anchor=" + anchor.CoreSWHID() + ";path=/src/main.go"
+	if file.Qualified == nil || file.Qualified.String() != want {
+		t.Errorf("Describe(file).Qualified = %v, want %s", file.Qualified, want)
+	}
+
+	sub, err := Describe(ctx, filepath.Join(dir, "src"), TreeOptions{})
+	if err != nil {
+		t.Fatalf("Describe() error = %v", err)
+	}
+	srcID, _ := TreeFromDirectoryPathWithOptions(filepath.Join(dir, "src"), TreeOptions{})
+	if sub.Kind != DescribedDirectory || !sub.Directory.Equal(srcID.ID) || !sub.Revision.Equal(anchor) || sub.Snapshot != nil {
+		t.Errorf("Describe(dir) = %+v", sub)
+	}
+	if sub.Qualified == nil || sub.Qualified.Qualifiers[QualifierPath] != "/src" {
+		t.Errorf("Describe(dir).Qualified = %v", sub.Qualified)
+	}
+
+	// Untracked files change the directory on disk but not the tree at HEAD.
+	if err := os.WriteFile(filepath.Join(dir, "build.log"), []byte("untracked\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	top, err := Describe(ctx, dir, TreeOptions{})
+	if err != nil {
+		t.Fatalf("Describe() error = %v", err)
+	}
+	commit, _ := repo.CommitObject(head)
+	if top.Kind != DescribedRepository || !top.Snapshot.Equal(snapshot) || !top.Revision.Equal(anchor) || top.Directory.ObjectHash != commit.TreeHash.String() {
+		t.Errorf("Describe(repo) = %+v", top)
+	}
+	if top.Qualified == nil || top.Qualified.Qualifiers[QualifierPath] != "" {
+		t.Errorf("Describe(repo).Qualified = %v, want no path", top.Qualified)
+	}
+
+	// Modified or untracked files are described but cannot be qualified.
+	modified, err := Describe(ctx, filepath.Join(dir, "build.log"), TreeOptions{})
+	if err != nil {
+		t.Fatalf("Describe() error = %v", err)
+	}
+	if modified.Content == nil || modified.Revision == nil || modified.Qualified != nil {
+		t.Errorf("Describe(untracked) = %+v, want content and revision without Qualified", modified)
+	}
+
+	plain := t.TempDir()
+	if err := os.WriteFile(filepath.Join(plain, "a.txt"), []byte("a\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	outside, err := Describe(ctx, plain, TreeOptions{})
+	if err != nil {
+		t.Fatalf("Describe() error = %v", err)
+	}
+	if outside.Kind != DescribedDirectory || outside.Directory == nil || outside.Revision != nil || outside.Qualified != nil {
+		t.Errorf("Describe(outside a repository) = %+v", outside)
+	}
+}
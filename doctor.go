@@ -0,0 +1,360 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/andrew/swhid-go/objects"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Checks run by Diagnose, in the order findings are reported.
+const (
+	CheckShallow        = "shallow"
+	CheckExcluded       = "excluded"
+	CheckIgnored        = "ignored"
+	CheckDirty          = "dirty"
+	CheckCRLF           = "crlf"
+	CheckLFS            = "lfs"
+	CheckSubmodule      = "submodule"
+	CheckEmptyDirectory = "empty-directory"
+	CheckSymlink        = "symlink"
+	CheckMode           = "mode"
+)
+
+// Finding is one likely reason why the SWHID computed for a directory on
+// disk differs from the one the archive has for the same code.
+type Finding struct {
+	Check   string   // one of the Check constants
+	Summary string   // what was found
+	Advice  string   // how to get the archived SWHID
+	Paths   []string // affected paths relative to the diagnosed directory, if any
+}
+
+// Diagnosis is the result of Diagnose.
+type Diagnosis struct {
+	// Directory is the SWHID of the directory as hashed from disk with the
+	// options given to Diagnose.
+	Directory *Identifier
+
+	// Committed is the SWHID of the same directory in the HEAD commit of
+	// the enclosing Git repository, which is what the archive has once
+	// that commit is archived. It is nil outside a repository or when the
+	// directory is not committed.
+	Committed *Identifier
+
+	Findings []Finding
+}
+
+var checkText = map[string][2]string{
+	CheckShallow: {
+		"The repository is a shallow clone.",
+		"Commits beyond the shallow boundary are missing, so history, graph and snapshot results cover only part of the repository, and clones made with --depth usually have a single branch. Run git fetch --unshallow and fetch every branch before computing snapshot SWHIDs.",
+	},
+	CheckExcluded: {
+		"Committed files are left out by exclude or include patterns.",
+		"The archive hashes every committed file. Drop the patterns from --exclude and --include, the exclude setting of the configuration file and SWHID_EXCLUDE.",
+	},
+	CheckIgnored: {
+		"Files ignored by Git are present on disk and hashed.",
+		"Build output and other files matched by .gitignore are never archived. Hash a clean checkout, pass --exclude for them, or hash the commit with swhid revision.",
+	},
+	CheckDirty: {
+		"The worktree has uncommitted changes.",
+		"The archive only has committed content. Commit or stash the changes, or hash the commit with swhid revision.",
+	},
+	CheckCRLF: {
+		"Files have CRLF line endings on disk but LF in the repository.",
+		"core.autocrlf or an eol setting in .gitattributes converted them on checkout. Hash the commit with swhid revision, or check out again with core.autocrlf=false.",
+	},
+	CheckLFS: {
+		"Git LFS files are checked out with their real content.",
+		"The repository, and so the archive, stores the LFS pointer files instead. Hash the commit with swhid revision, or a checkout made with GIT_LFS_SKIP_SMUDGE=1.",
+	},
+	CheckSubmodule: {
+		"Submodules are hashed as directories.",
+		"Git and the archive record a submodule as a reference to a commit, not as its files. Hash the commit with swhid revision.",
+	},
+	CheckEmptyDirectory: {
+		"Empty directories on disk are hashed.",
+		"Git cannot record empty directories, so trees archived from Git have none. Remove them, or hash the commit with swhid revision.",
+	},
+	CheckSymlink: {
+		"Symbolic links are checked out as plain files.",
+		"With core.symlinks=false, as on Windows without symlink support, links become files holding their target. Enable symlinks and check out again, or hash the commit with swhid revision.",
+	},
+	CheckMode: {
+		"The executable bit differs from the commit.",
+		"The mode recorded in the Git index decides which files are executable. Commit or revert the mode change.",
+	},
+}
+
+var checkOrder = []string{
+	CheckShallow, CheckExcluded, CheckIgnored, CheckDirty, CheckCRLF, CheckLFS,
+	CheckSubmodule, CheckEmptyDirectory, CheckSymlink, CheckMode,
+}
+
+// Diagnose hashes the directory at dirPath with opts, as the directory
+// command does, and looks for reasons the result may differ from the
+// SWHID the archive has for the same code: uncommitted, ignored or
+// excluded files, files converted on checkout (line endings, LFS,
+// symlinks), submodules and empty directories, and shallow clones. The
+// directory is compared with its counterpart in the HEAD commit of the
+// enclosing Git repository; outside a repository the diagnosis has no
+// findings.
+func Diagnose(dirPath string, opts TreeOptions) (*Diagnosis, error) {
+	node, err := TreeFromDirectoryPathWithOptions(dirPath, opts)
+	if err != nil {
+		return nil, err
+	}
+	diagnosis := &Diagnosis{Directory: node.ID}
+
+	repo, err := openRepository(dirPath, true, opts.ReadOnlyFS)
+	if err != nil {
+		return diagnosis, nil
+	}
+
+	d := &diagnoser{repo: repo, dir: dirPath, findings: make(map[string]*Finding)}
+	if shallow, err := repo.Storer.Shallow(); err == nil && len(shallow) > 0 {
+		d.add(CheckShallow, "")
+	}
+	if d.filter, err = newPathFilter(opts.Include, opts.excludes()); err != nil {
+		return nil, err
+	}
+
+	if err := d.compareHead(node); err != nil {
+		return nil, err
+	}
+	diagnosis.Committed = d.committed
+
+	for _, check := range checkOrder {
+		if f, ok := d.findings[check]; ok {
+			diagnosis.Findings = append(diagnosis.Findings, *f)
+		}
+	}
+	return diagnosis, nil
+}
+
+type diagnoser struct {
+	repo      *git.Repository
+	dir       string
+	prefix    []string // path of dir from the worktree root
+	filter    *pathFilter
+	ignore    gitignore.Matcher
+	committed *Identifier
+	findings  map[string]*Finding
+}
+
+func (d *diagnoser) add(check, relPath string) {
+	f, ok := d.findings[check]
+	if !ok {
+		text := checkText[check]
+		f = &Finding{Check: check, Summary: text[0], Advice: text[1]}
+		d.findings[check] = f
+	}
+	if relPath != "" {
+		f.Paths = append(f.Paths, relPath)
+	}
+}
+
+// compareHead locates the directory in the HEAD commit and compares it
+// with node. A bare repository, an unborn HEAD or a directory that is not
+// committed leaves nothing to compare with.
+func (d *diagnoser) compareHead(node *Node) error {
+	wt, err := d.repo.Worktree()
+	if err != nil {
+		return nil
+	}
+	root, err := filepath.EvalSymlinks(wt.Filesystem.Root())
+	if err != nil {
+		return err
+	}
+	abs, err := filepath.EvalSymlinks(d.dir)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return err
+	}
+	rel = filepath.ToSlash(rel)
+	if rel != "." {
+		d.prefix = strings.Split(rel, "/")
+	}
+
+	patterns, _ := gitignore.ReadPatterns(wt.Filesystem, nil)
+	d.ignore = gitignore.NewMatcher(patterns)
+
+	head, err := d.repo.Head()
+	if err != nil {
+		return nil
+	}
+	commit, err := d.repo.CommitObject(head.Hash())
+	if err != nil {
+		return fmt.Errorf("failed to read HEAD commit: %w", err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return fmt.Errorf("failed to get tree: %w", err)
+	}
+	if rel != "." {
+		if tree, err = tree.Tree(rel); err != nil {
+			if d.ignore.Match(d.prefix, true) {
+				d.add(CheckIgnored, "")
+			} else {
+				d.add(CheckDirty, "")
+			}
+			return nil
+		}
+	}
+
+	d.committed, _ = NewIdentifier(ObjectTypeDirectory, tree.Hash.String(), nil)
+	if node.ID.ObjectHash == tree.Hash.String() {
+		return nil
+	}
+	return d.compare(node, tree)
+}
+
+// compare classifies the differences between a directory on disk and the
+// committed tree at the same path.
+func (d *diagnoser) compare(node *Node, tree *object.Tree) error {
+	committed := make(map[string]object.TreeEntry, len(tree.Entries))
+	for _, e := range tree.Entries {
+		committed[e.Name] = e
+	}
+
+	for _, child := range node.Children {
+		entry, ok := committed[child.Name]
+		if !ok {
+			d.onlyOnDisk(child)
+			continue
+		}
+		delete(committed, child.Name)
+		if err := d.compareEntry(child, entry); err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range tree.Entries {
+		if _, ok := committed[entry.Name]; !ok {
+			continue
+		}
+		relPath := path.Join(node.Path, entry.Name)
+		switch {
+		case d.filter.excluded(relPath) || !d.filter.included(relPath):
+			d.add(CheckExcluded, relPath)
+		case entry.Mode == filemode.Submodule:
+			d.add(CheckSubmodule, relPath)
+		default:
+			d.add(CheckDirty, relPath)
+		}
+	}
+	return nil
+}
+
+func (d *diagnoser) compareEntry(child *Node, entry object.TreeEntry) error {
+	hash := entry.Hash.String()
+	switch entry.Mode {
+	case filemode.Submodule:
+		if child.Type != objects.EntryTypeRevision {
+			d.add(CheckSubmodule, child.Path)
+		} else if child.ID.ObjectHash != hash {
+			d.add(CheckDirty, child.Path)
+		}
+
+	case filemode.Dir:
+		if child.Type != objects.EntryTypeDirectory {
+			d.add(CheckDirty, child.Path)
+		} else if child.ID.ObjectHash != hash {
+			subtree, err := d.repo.TreeObject(entry.Hash)
+			if err != nil {
+				return fmt.Errorf("failed to read tree %s: %w", entry.Hash, err)
+			}
+			return d.compare(child, subtree)
+		}
+
+	case filemode.Symlink:
+		switch {
+		case child.Type == objects.EntryTypeSymlink && child.ID.ObjectHash == hash:
+		case child.Type != objects.EntryTypeSymlink && child.ID.ObjectHash == hash:
+			d.add(CheckSymlink, child.Path)
+		default:
+			d.add(CheckDirty, child.Path)
+		}
+
+	default:
+		if child.Type != objects.EntryTypeFile && child.Type != objects.EntryTypeExecutable {
+			d.add(CheckDirty, child.Path)
+			return nil
+		}
+		if child.ID.ObjectHash != hash {
+			return d.compareContent(child, entry)
+		}
+		if (child.Type == objects.EntryTypeExecutable) != (entry.Mode == filemode.Executable) {
+			d.add(CheckMode, child.Path)
+		}
+	}
+	return nil
+}
+
+// compareContent tells files converted on checkout, by Git LFS or line
+// ending conversion, from files that were edited.
+func (d *diagnoser) compareContent(child *Node, entry object.TreeEntry) error {
+	data, err := os.ReadFile(filepath.Join(d.dir, filepath.FromSlash(child.Path)))
+	if err != nil {
+		return err
+	}
+
+	blob, err := d.repo.BlobObject(entry.Hash)
+	if err != nil {
+		return fmt.Errorf("failed to read blob %s: %w", entry.Hash, err)
+	}
+	if blob.Size <= maxLFSPointerSize {
+		r, err := blob.Reader()
+		if err != nil {
+			return fmt.Errorf("failed to read blob %s: %w", entry.Hash, err)
+		}
+		committed, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read blob %s: %w", entry.Hash, err)
+		}
+		if bytes.HasPrefix(committed, lfsPointerPrefix) && !bytes.HasPrefix(data, lfsPointerPrefix) {
+			d.add(CheckLFS, child.Path)
+			return nil
+		}
+	}
+
+	if bytes.Contains(data, []byte("\r\n")) {
+		if FromContent(bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))).ObjectHash == entry.Hash.String() {
+			d.add(CheckCRLF, child.Path)
+			return nil
+		}
+	}
+	d.add(CheckDirty, child.Path)
+	return nil
+}
+
+// onlyOnDisk classifies an entry that is not in the commit: ignored by
+// Git, an empty directory, or simply not committed yet.
+func (d *diagnoser) onlyOnDisk(child *Node) {
+	parts := append(append([]string(nil), d.prefix...), strings.Split(child.Path, "/")...)
+	switch {
+	case d.ignore.Match(parts, child.Type == objects.EntryTypeDirectory):
+		d.add(CheckIgnored, child.Path)
+	case child.Type == objects.EntryTypeDirectory && child.Files() == 0:
+		d.add(CheckEmptyDirectory, child.Path)
+	default:
+		d.add(CheckDirty, child.Path)
+	}
+}
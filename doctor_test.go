@@ -0,0 +1,120 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiagnose(t *testing.T) {
+	dir, repo, _ := newTestRepo(t)
+
+	write := func(name, content string) {
+		t.Helper()
+		full := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	write(".gitignore", "build/\n")
+	write("crlf.txt", "a\nb\n")
+	write("big.bin", "version https://git-lfs.github.com/spec/v1\noid sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824\nsize 5\n")
+	write("debug.log", "log\n")
+	if err := os.Symlink("hello.txt", filepath.Join(dir, "link")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	commitAll(t, repo, "Add files\n")
+
+	diagnosis, err := Diagnose(dir, TreeOptions{})
+	if err != nil {
+		t.Fatalf("Diagnose() error = %v", err)
+	}
+	if len(diagnosis.Findings) != 0 {
+		t.Errorf("Diagnose() of a clean checkout found %+v", diagnosis.Findings)
+	}
+	if diagnosis.Committed == nil || !diagnosis.Committed.Equal(diagnosis.Directory) {
+		t.Errorf("Committed = %v, want %v", diagnosis.Committed, diagnosis.Directory)
+	}
+
+	write("hello.txt", "changed\n")
+	write("new.txt", "new\n")
+	write("build/out.o", "object\n")
+	write("crlf.txt", "a\r\nb\r\n")
+	write("big.bin", "hello")
+	if err := os.Mkdir(filepath.Join(dir, "empty"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	os.Remove(filepath.Join(dir, "link"))
+	write("link", "hello.txt")
+
+	diagnosis, err = Diagnose(dir, TreeOptions{Exclude: []string{"*.log"}})
+	if err != nil {
+		t.Fatalf("Diagnose() error = %v", err)
+	}
+	if diagnosis.Committed.Equal(diagnosis.Directory) {
+		t.Errorf("Directory = Committed = %v, want them to differ", diagnosis.Directory)
+	}
+
+	got := make(map[string][]string)
+	var order []string
+	for _, f := range diagnosis.Findings {
+		got[f.Check] = f.Paths
+		order = append(order, f.Check)
+		if f.Summary == "" || f.Advice == "" {
+			t.Errorf("finding %s has no summary or advice", f.Check)
+		}
+	}
+	want := map[string][]string{
+		CheckExcluded:       {"debug.log"},
+		CheckIgnored:        {"build"},
+		CheckDirty:          {"hello.txt", "new.txt"},
+		CheckCRLF:           {"crlf.txt"},
+		CheckLFS:            {"big.bin"},
+		CheckEmptyDirectory: {"empty"},
+		CheckSymlink:        {"link"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findings = %v, want %v", got, want)
+	}
+	wantOrder := []string{CheckExcluded, CheckIgnored, CheckDirty, CheckCRLF, CheckLFS, CheckEmptyDirectory, CheckSymlink}
+	if !reflect.DeepEqual(order, wantOrder) {
+		t.Errorf("finding order = %v, want %v", order, wantOrder)
+	}
+
+	head, _ := repo.Head()
+	if err := os.WriteFile(filepath.Join(dir, ".git", "shallow"), []byte(head.Hash().String()+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write shallow file: %v", err)
+	}
+	diagnosis, err = Diagnose(filepath.Join(dir, "build"), TreeOptions{})
+	if err != nil {
+		t.Fatalf("Diagnose() error = %v", err)
+	}
+	if len(diagnosis.Findings) != 2 || diagnosis.Findings[0].Check != CheckShallow || diagnosis.Findings[1].Check != CheckIgnored {
+		t.Errorf("Diagnose() of an ignored subdirectory = %+v, want shallow and ignored findings", diagnosis.Findings)
+	}
+	if diagnosis.Committed != nil {
+		t.Errorf("Committed = %v, want nil", diagnosis.Committed)
+	}
+}
+
+func TestDiagnoseOutsideRepository(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	diagnosis, err := Diagnose(dir, TreeOptions{})
+	if err != nil {
+		t.Fatalf("Diagnose() error = %v", err)
+	}
+	if diagnosis.Directory == nil || diagnosis.Committed != nil || len(diagnosis.Findings) != 0 {
+		t.Errorf("Diagnose() = %+v, want only the directory SWHID", diagnosis)
+	}
+}
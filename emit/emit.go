@@ -0,0 +1,80 @@
+// Package emit streams events for computed SWHIDs to external systems, so
+// pipelines can consume manifest and inventory results as they are
+// produced. Emitters buffer events and deliver them in batches; Close
+// delivers what remains.
+package emit
+
+import (
+	"context"
+	"errors"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/manifest"
+)
+
+// Event reports the SWHID computed for one object of a tree.
+type Event struct {
+	manifest.Entry
+	Root   *swhid.Identifier // SWHID of the tree the object belongs to
+	Source string            // where the tree was read from, such as a directory path
+}
+
+// Emitter delivers events. Implementations are not safe for concurrent use.
+type Emitter interface {
+	Emit(ctx context.Context, e Event) error
+	// Close delivers any buffered events and releases resources.
+	Close(ctx context.Context) error
+}
+
+// fields returns the JSON representation of an event.
+func (e Event) fields() map[string]interface{} {
+	fields := map[string]interface{}{
+		"path":  e.Path,
+		"type":  e.Type,
+		"swhid": e.SWHID.CoreSWHID(),
+		"size":  e.Size,
+	}
+	if e.Root != nil {
+		fields["root"] = e.Root.CoreSWHID()
+	}
+	if e.Source != "" {
+		fields["source"] = e.Source
+	}
+	return fields
+}
+
+// Tree emits an event for root and every node below it, in depth-first
+// tree order.
+func Tree(ctx context.Context, em Emitter, root *swhid.Node, source string) error {
+	return manifest.Walk(root, func(entry manifest.Entry) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return em.Emit(ctx, Event{Entry: entry, Root: root.ID, Source: source})
+	})
+}
+
+type multi []Emitter
+
+// Multi returns an Emitter that sends every event to each of emitters.
+func Multi(emitters ...Emitter) Emitter {
+	return multi(emitters)
+}
+
+func (m multi) Emit(ctx context.Context, e Event) error {
+	for _, em := range m {
+		if err := em.Emit(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes every emitter, even after one fails, and returns all errors.
+func (m multi) Close(ctx context.Context) error {
+	var errs []error
+	for _, em := range m {
+		errs = append(errs, em.Close(ctx))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,81 @@
+package emit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrew/swhid-go"
+)
+
+// recorder keeps the events it receives.
+type recorder struct {
+	events []Event
+	closed bool
+	err    error
+}
+
+func (r *recorder) Emit(ctx context.Context, e Event) error {
+	r.events = append(r.events, e)
+	return r.err
+}
+
+func (r *recorder) Close(ctx context.Context) error {
+	r.closed = true
+	return r.err
+}
+
+func testTree(t *testing.T) *swhid.Node {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	root, err := swhid.TreeFromDirectoryPath(dir)
+	if err != nil {
+		t.Fatalf("TreeFromDirectoryPath() error = %v", err)
+	}
+	return root
+}
+
+func TestTree(t *testing.T) {
+	root := testTree(t)
+	a, b := &recorder{}, &recorder{}
+	em := Multi(a, b)
+
+	if err := Tree(context.Background(), em, root, "/src"); err != nil {
+		t.Fatalf("Tree() error = %v", err)
+	}
+	if err := em.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	for _, r := range []*recorder{a, b} {
+		if len(r.events) != 3 || !r.closed {
+			t.Fatalf("recorder got %d events, closed %v; want 3, true", len(r.events), r.closed)
+		}
+		last := r.events[2]
+		if last.Path != "src/main.go" || last.Type != "file" || last.Source != "/src" || !last.Root.Equal(root.ID) {
+			t.Errorf("last event = %+v", last)
+		}
+	}
+}
+
+func TestMultiCloseErrors(t *testing.T) {
+	failing := &recorder{err: errors.New("unreachable")}
+	ok := &recorder{}
+
+	if err := Multi(failing, ok).Close(context.Background()); err == nil {
+		t.Error("Close() expected error")
+	}
+	if !ok.closed {
+		t.Error("Close() skipped the emitters after a failure")
+	}
+}
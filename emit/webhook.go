@@ -0,0 +1,85 @@
+package emit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// DefaultWebhookBatchSize is the number of events per request when
+// Webhook.BatchSize is zero.
+const DefaultWebhookBatchSize = 100
+
+// Webhook posts events to an HTTP endpoint as NDJSON, one JSON object per
+// event with the keys path, type, swhid, size, root and source. Any 2xx
+// response acknowledges the whole batch.
+type Webhook struct {
+	URL       string
+	Client    *http.Client // defaults to http.DefaultClient
+	Header    http.Header  // added to every request, e.g. Authorization
+	BatchSize int          // defaults to DefaultWebhookBatchSize
+
+	buf     bytes.Buffer
+	pending int
+}
+
+// Emit buffers e and posts the batch once it is full.
+func (w *Webhook) Emit(ctx context.Context, e Event) error {
+	enc := json.NewEncoder(&w.buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(e.fields()); err != nil {
+		return err
+	}
+	w.pending++
+
+	size := w.BatchSize
+	if size <= 0 {
+		size = DefaultWebhookBatchSize
+	}
+	if w.pending >= size {
+		return w.flush(ctx)
+	}
+	return nil
+}
+
+// Close posts any buffered events.
+func (w *Webhook) Close(ctx context.Context) error {
+	return w.flush(ctx)
+}
+
+func (w *Webhook) flush(ctx context.Context) error {
+	if w.pending == 0 {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(w.buf.Bytes()))
+	if err != nil {
+		return err
+	}
+	for key, values := range w.Header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook: POST %s: %s", w.URL, resp.Status)
+	}
+
+	w.buf.Reset()
+	w.pending = 0
+	return nil
+}
@@ -0,0 +1,69 @@
+package emit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhook(t *testing.T) {
+	var batches [][]map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/x-ndjson" || r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("request headers = %v", r.Header)
+		}
+		var batch []map[string]interface{}
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var event map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+				t.Errorf("invalid event line: %v", err)
+			}
+			batch = append(batch, event)
+		}
+		batches = append(batches, batch)
+	}))
+	defer server.Close()
+
+	root := testTree(t)
+	w := &Webhook{
+		URL:       server.URL,
+		Header:    http.Header{"Authorization": {"Bearer secret"}},
+		BatchSize: 2,
+	}
+	if err := Tree(context.Background(), w, root, "/src"); err != nil {
+		t.Fatalf("Tree() error = %v", err)
+	}
+	if len(batches) != 1 {
+		t.Fatalf("posted %d batches before Close, want 1", len(batches))
+	}
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if len(batches) != 2 || len(batches[0]) != 2 || len(batches[1]) != 1 {
+		t.Fatalf("batches = %v", batches)
+	}
+	first := batches[0][0]
+	if first["swhid"] != root.ID.CoreSWHID() || first["root"] != root.ID.CoreSWHID() || first["type"] != "directory" || first["source"] != "/src" {
+		t.Errorf("first event = %v", first)
+	}
+}
+
+func TestWebhookError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	w := &Webhook{URL: server.URL}
+	if err := Tree(context.Background(), w, testTree(t), ""); err != nil {
+		t.Fatalf("Tree() error = %v", err)
+	}
+	if err := w.Close(context.Background()); err == nil {
+		t.Error("Close() expected error for 503 response")
+	}
+}
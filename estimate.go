@@ -0,0 +1,35 @@
+package swhid
+
+import "context"
+
+// WorkEstimate is how much hashing a directory takes, as EstimateWork
+// reports it.
+type WorkEstimate struct {
+	Files  int   // regular files that would be read and hashed
+	Bytes  int64 // their total size
+	Cached int   // files TreeOptions.Cached already has SWHIDs for
+}
+
+// EstimateWork walks the directory at path as hashing it with opts would,
+// applying the same filters and skipping the same entries, but reads no
+// file. Only the first of several hard links to a file counts, unless
+// opts.NoHardLinkReuse is set. Callers can use the estimate to show
+// progress against a known total or to turn away directories too large to
+// hash.
+func EstimateWork(path string, opts TreeOptions) (*WorkEstimate, error) {
+	return EstimateWorkContext(context.Background(), path, opts)
+}
+
+// EstimateWorkContext is EstimateWork, giving up with ctx's error once ctx
+// is done.
+func EstimateWorkContext(ctx context.Context, path string, opts TreeOptions) (*WorkEstimate, error) {
+	files, cached, err := filesToHash(ctx, path, opts)
+	if err != nil {
+		return nil, err
+	}
+	estimate := &WorkEstimate{Files: len(files), Cached: len(cached)}
+	for _, f := range files {
+		estimate.Bytes += f.Size
+	}
+	return estimate, nil
+}
@@ -0,0 +1,55 @@
+package swhid
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEstimateWork(t *testing.T) {
+	dir := t.TempDir()
+	for name, size := range map[string]int{"a.txt": 10, "src/main.go": 100, "build.log": 1000, ".git/HEAD": 23} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Link(filepath.Join(dir, "src/main.go"), filepath.Join(dir, "main-link.go")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("a.txt", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts TreeOptions
+		want WorkEstimate
+	}{
+		{"default", TreeOptions{}, WorkEstimate{Files: 3, Bytes: 1110}},
+		{"excluded", TreeOptions{Exclude: []string{"*.log"}}, WorkEstimate{Files: 2, Bytes: 110}},
+		{"hard links", TreeOptions{NoHardLinkReuse: true}, WorkEstimate{Files: 4, Bytes: 1210}},
+		{"cached", TreeOptions{Cached: func(relPath string, _ os.FileInfo) *Identifier {
+			if relPath == "build.log" {
+				return FromContent(nil)
+			}
+			return nil
+		}}, WorkEstimate{Files: 2, Bytes: 110, Cached: 1}},
+	}
+	for _, tt := range tests {
+		got, err := EstimateWork(dir, tt.opts)
+		if err != nil {
+			t.Fatalf("%s: EstimateWork() error = %v", tt.name, err)
+		}
+		if *got != tt.want {
+			t.Errorf("%s: EstimateWork() = %+v, want %+v", tt.name, *got, tt.want)
+		}
+	}
+
+	if _, err := EstimateWork(filepath.Join(dir, "missing"), TreeOptions{}); err == nil {
+		t.Error("EstimateWork(missing) succeeded")
+	}
+}
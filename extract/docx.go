@@ -0,0 +1,140 @@
+package extract
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxPartSize bounds the uncompressed size of each document part read, so
+// that a crafted file cannot exhaust memory.
+const maxPartSize = 64 << 20
+
+// errTooLarge is returned for document parts larger than maxPartSize.
+var errTooLarge = errors.New("document part too large")
+
+// docxParts are the parts of a DOCX file whose text is extracted, in
+// order: the body, then footnotes and endnotes, where citations often sit.
+var docxParts = []string{"word/document.xml", "word/footnotes.xml", "word/endnotes.xml"}
+
+// docxRels are the relationships of those parts, holding hyperlink targets.
+var docxRels = []string{"word/_rels/document.xml.rels", "word/_rels/footnotes.xml.rels", "word/_rels/endnotes.xml.rels"}
+
+// DOCXText returns the text of a DOCX document: a line per paragraph of
+// the body, footnotes and endnotes, followed by the targets of external
+// hyperlinks, one per line, since link text need not show where it leads.
+func DOCXText(r io.ReaderAt, size int64) (string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return "", err
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	if files[docxParts[0]] == nil {
+		return "", fmt.Errorf("not a DOCX document: no %s", docxParts[0])
+	}
+
+	var text strings.Builder
+	for _, name := range docxParts {
+		if f := files[name]; f != nil {
+			if err := readPart(f, func(d *xml.Decoder) error { return docxParagraphs(d, &text) }); err != nil {
+				return "", fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	for _, name := range docxRels {
+		if f := files[name]; f != nil {
+			if err := readPart(f, func(d *xml.Decoder) error { return docxLinks(d, &text) }); err != nil {
+				return "", fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return text.String(), nil
+}
+
+// readPart decodes a part of a zip file with fn, within maxPartSize.
+func readPart(f *zip.File, fn func(*xml.Decoder) error) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	lr := &io.LimitedReader{R: rc, N: maxPartSize + 1}
+	err = fn(xml.NewDecoder(lr))
+	if lr.N <= 0 {
+		return errTooLarge
+	}
+	return err
+}
+
+// docxParagraphs writes the text runs of a WordprocessingML part to text.
+func docxParagraphs(d *xml.Decoder, text *strings.Builder) error {
+	inText := false
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				text.WriteByte('\t')
+			case "br", "cr":
+				text.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(t)
+			}
+		}
+	}
+}
+
+// docxLinks writes the targets of the external relationships of a
+// relationships part to text.
+func docxLinks(d *xml.Decoder, text *strings.Builder) error {
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "Relationship" {
+			continue
+		}
+		var target, mode string
+		for _, attr := range start.Attr {
+			switch attr.Name.Local {
+			case "Target":
+				target = attr.Value
+			case "TargetMode":
+				mode = attr.Value
+			}
+		}
+		if mode == "External" {
+			text.WriteString(target)
+			text.WriteByte('\n')
+		}
+	}
+}
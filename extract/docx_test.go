@@ -0,0 +1,64 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func buildDOCX(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return b.Bytes()
+}
+
+func TestDOCXText(t *testing.T) {
+	const ns = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	data := buildDOCX(t, map[string]string{
+		// Word splits text into runs wherever formatting or editing
+		// history changes, often inside a SWHID.
+		"word/document.xml": `<?xml version="1.0"?><w:document ` + ns + `><w:body>
+<w:p><w:r><w:t>Software: </w:t></w:r><w:r><w:t>swh:1:dir:d198bc9d7a6b</w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t>cf6db04f476d29314f157507d505</w:t></w:r></w:p>
+<w:p><w:hyperlink r:id="rId5"><w:r><w:t>the archived snapshot</w:t></w:r></w:hyperlink></w:p>
+</w:body></w:document>`,
+		"word/footnotes.xml": `<?xml version="1.0"?><w:footnotes ` + ns + `><w:footnote><w:p><w:r><w:t>See swh:1:cnt:94a9ed024d38597936</w:t></w:r></w:p></w:footnote></w:footnotes>`,
+		"word/_rels/document.xml.rels": `<?xml version="1.0"?><Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+<Relationship Id="rId5" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="https://archive.softwareheritage.org/swh:1:snp:c7c108084bc0bf3d81436bf980b46e98bd338453" TargetMode="External"/>
+</Relationships>`,
+	})
+
+	path := filepath.Join(t.TempDir(), "paper.docx")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	matches, err := File(path)
+	if err != nil {
+		t.Fatalf("File() error = %v", err)
+	}
+	if len(matches) != 3 {
+		t.Fatalf("File() found %d matches, want 3: %+v", len(matches), matches)
+	}
+	if matches[0].Err != nil || matches[0].ID.String() != testSWHID {
+		t.Errorf("body match = %+v, want %s", matches[0], testSWHID)
+	}
+	if matches[1].Err == nil {
+		t.Errorf("truncated footnote match = %+v, want an error", matches[1])
+	}
+	if want := "swh:1:snp:c7c108084bc0bf3d81436bf980b46e98bd338453"; matches[2].Err != nil || matches[2].ID.String() != want {
+		t.Errorf("hyperlink match = %+v, want %s", matches[2], want)
+	}
+}
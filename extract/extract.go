@@ -0,0 +1,170 @@
+// Package extract finds SWHIDs in documents: plain text, and the text
+// layers of PDF and DOCX files, where research papers usually carry them.
+//
+// Text taken from documents breaks identifiers apart. Typesetting wraps a
+// long hash over two lines, often with a hyphen, inserts soft hyphens and
+// zero-width spaces, and copying from a PDF may change the case of
+// letters or leave punctuation attached. Text joins such pieces back
+// together and repairs what ParseLenient repairs, and returns what cannot
+// be repaired, such as a hash that lost digits, as a Match with an error,
+// so that broken citations are reported rather than skipped.
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/andrew/swhid-go"
+)
+
+// FixLineBreak is reported for identifiers that were split over lines,
+// with or without a hyphen, had spaces around their colons, or were
+// interrupted by soft hyphens or zero-width characters.
+const FixLineBreak swhid.LenientFix = "joined text broken across lines or spaced out"
+
+// Match is an identifier found in a document.
+type Match struct {
+	Raw   string             // the text as found
+	Line  int                // 1-based line of the text it starts on
+	ID    *swhid.Identifier  // the repaired identifier; nil if Err is set
+	Fixes []swhid.LenientFix // the repairs that were needed, if any
+	Err   error              // why the text is not a valid SWHID
+}
+
+// prefixRegex matches the start of a SWHID, allowing the line breaks and
+// spaces typesetting puts around its colons.
+var prefixRegex = regexp.MustCompile(`(?i)swh\s{0,3}:\s{0,3}1\s{0,3}:\s{0,3}(cnt|dir|rev|rel|snp)\s{0,3}:`)
+
+// Text returns every SWHID in text, in order of appearance.
+func Text(text string) []Match {
+	var matches []Match
+	for _, loc := range prefixRegex.FindAllStringSubmatchIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		objectType := strings.ToLower(text[loc[2]:loc[3]])
+		hash, end := scanHash(text, end)
+		candidate := "swh:1:" + objectType + ":" + hash
+		if len(hash) == swhid.ObjectIDLen && end < len(text) && text[end] == ';' {
+			qualEnd := end + strings.IndexFunc(text[end:], unicode.IsSpace)
+			if qualEnd < end {
+				qualEnd = len(text)
+			}
+			candidate += text[end:qualEnd]
+			end = qualEnd
+		} else if trailing := strings.TrimLeft(text[end:], "0123456789abcdefABCDEF"); len(trailing) < len(text)-end {
+			// Hex digits beyond the 40th are part of a damaged hash.
+			extra := len(text) - end - len(trailing)
+			candidate += text[end : end+extra]
+			end += extra
+		}
+
+		m := Match{Raw: text[start:end], Line: 1 + strings.Count(text[:start], "\n")}
+		if strings.ContainsFunc(m.Raw, func(r rune) bool { return unicode.IsSpace(r) || isInvisible(r) }) {
+			m.Fixes = append(m.Fixes, FixLineBreak)
+		}
+		if core, _, _ := strings.Cut(m.Raw, ";"); strings.ToLower(core) != core {
+			m.Fixes = append(m.Fixes, swhid.FixCase)
+		}
+		id, fixes, err := swhid.ParseLenient(candidate)
+		m.Fixes = append(m.Fixes, fixes...)
+		m.ID, m.Err = id, err
+		matches = append(matches, m)
+	}
+	return matches
+}
+
+// scanHash collects the hex digits of a hash starting at text[i], up to
+// 40, skipping the breaks typesetting leaves inside one. It returns the
+// lowercased digits and where they end.
+func scanHash(text string, i int) (string, int) {
+	var hash strings.Builder
+	end := i
+	for i < len(text) && hash.Len() < swhid.ObjectIDLen {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case isHex(r):
+			hash.WriteRune(unicode.ToLower(r))
+			i += size
+			end = i
+		case isInvisible(r):
+			i += size
+		case isHyphen(r) || unicode.IsSpace(r):
+			// A hyphen or spaces continue the hash only if they wrap the
+			// line.
+			j := i
+			if isHyphen(r) {
+				j += size
+			}
+			for j < len(text) {
+				r, size := utf8.DecodeRuneInString(text[j:])
+				if !unicode.IsSpace(r) {
+					break
+				}
+				j += size
+			}
+			if !strings.Contains(text[i:j], "\n") {
+				return hash.String(), end
+			}
+			i = j
+		default:
+			return hash.String(), end
+		}
+	}
+	return hash.String(), end
+}
+
+func isHex(r rune) bool {
+	return '0' <= r && r <= '9' || 'a' <= r && r <= 'f' || 'A' <= r && r <= 'F'
+}
+
+// isInvisible reports soft hyphens and zero-width characters.
+func isInvisible(r rune) bool {
+	return r == '\u00ad' || r == '\u200b' || r == '\u200c' || r == '\u200d' || r == '\ufeff'
+}
+
+func isHyphen(r rune) bool {
+	return r == '-' || r == '\u2010' || r == '\u2011'
+}
+
+// File returns the SWHIDs in the file at path: in its text layer for PDF
+// and DOCX files, told apart by their content, or in its text otherwise.
+func File(path string) ([]Match, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	text, err := DocumentText(data, filepath.Ext(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return Text(text), nil
+}
+
+// DocumentText returns the text of a document: the text layer of a PDF or
+// DOCX file, or data itself for anything else. ext, the file name's
+// extension, tells DOCX files apart from other zip files.
+func DocumentText(data []byte, ext string) (string, error) {
+	r := bytes.NewReader(data)
+	switch {
+	case bytes.HasPrefix(data, []byte("%PDF-")):
+		return PDFText(r, int64(len(data)))
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")) && strings.EqualFold(ext, ".docx"):
+		return DOCXText(r, int64(len(data)))
+	}
+	return string(data), nil
+}
+
+// Reader returns the SWHIDs in the plain text read from r.
+func Reader(r io.Reader) ([]Match, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Text(string(data)), nil
+}
@@ -0,0 +1,69 @@
+package extract
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/andrew/swhid-go"
+)
+
+const testSWHID = "swh:1:dir:d198bc9d7a6bcf6db04f476d29314f157507d505"
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		want  string
+		fixes []swhid.LenientFix
+	}{
+		{"plain", "The code is archived as " + testSWHID + " in the archive.", testSWHID, nil},
+		{"sentence end", "archived as " + testSWHID + ".", testSWHID, nil},
+		{"hyphenated line break", "archived as swh:1:dir:d198bc9d7a6bcf6db04f476d2931-\n4f157507d505 in", testSWHID, []swhid.LenientFix{FixLineBreak}},
+		{"line break", "swh:1:dir:d198bc9d7a6bcf6db04f476d2931\n  4f157507d505", testSWHID, []swhid.LenientFix{FixLineBreak}},
+		{"break after colon", "(swh:1:dir:\nd198bc9d7a6bcf6db04f476d29314f157507d505)", testSWHID, []swhid.LenientFix{FixLineBreak}},
+		{"soft hyphen", "swh:1:dir:d198bc9d7a6bcf6db04f476d2931\u00ad4f157507d505", testSWHID, []swhid.LenientFix{FixLineBreak}},
+		{"upper case", "SWH:1:DIR:D198BC9D7A6BCF6DB04F476D29314F157507D505", testSWHID, []swhid.LenientFix{swhid.FixCase}},
+		{"qualifiers", testSWHID + ";origin=https://github.com/example/repo;path=/src/,", testSWHID + ";origin=https://github.com/example/repo;path=/src/", []swhid.LenientFix{swhid.FixTrailingPunctuation}},
+		{"archive URL", "https://archive.softwareheritage.org/" + testSWHID, testSWHID, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := Text(tt.text)
+			if len(matches) != 1 {
+				t.Fatalf("Text() found %d matches, want 1: %+v", len(matches), matches)
+			}
+			m := matches[0]
+			if m.Err != nil {
+				t.Fatalf("Text() error = %v", m.Err)
+			}
+			if m.ID.String() != tt.want {
+				t.Errorf("Text() = %s, want %s", m.ID, tt.want)
+			}
+			if !slices.Equal(m.Fixes, tt.fixes) {
+				t.Errorf("Text() fixes = %q, want %q", m.Fixes, tt.fixes)
+			}
+		})
+	}
+}
+
+func TestTextBroken(t *testing.T) {
+	text := "First swh:1:cnt:94a9ed024d3859793618152ea559a168bbcbb5e2,\n" +
+		"then a truncated swh:1:rev:309cf2674ee7a0749978cf8265ab91a60aea0f and\n" +
+		"one with a digit too many swh:1:rel:22ece559cc7cc2364edc5e5593d63ae8bd229f9f0.\n"
+	matches := Text(text)
+	if len(matches) != 3 {
+		t.Fatalf("Text() found %d matches, want 3: %+v", len(matches), matches)
+	}
+	if matches[0].Err != nil || matches[0].Line != 1 {
+		t.Errorf("Text() first match = %+v", matches[0])
+	}
+	for _, m := range matches[1:] {
+		if m.ID != nil || !errors.Is(m.Err, swhid.ErrInvalidObjectHash) {
+			t.Errorf("Text() match %q = %v, %v, want ErrInvalidObjectHash", m.Raw, m.ID, m.Err)
+		}
+	}
+	if matches[1].Line != 2 || matches[2].Line != 3 {
+		t.Errorf("Text() lines = %d, %d, want 2, 3", matches[1].Line, matches[2].Line)
+	}
+}
@@ -0,0 +1,840 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// ErrEncryptedPDF is returned for encrypted PDF files, whose text cannot
+// be read without decrypting them.
+var ErrEncryptedPDF = errors.New("encrypted PDF")
+
+// PDF values. Numbers are float64, booleans bool, null nil and arrays
+// []any.
+type (
+	pdfName    string
+	pdfString  string // the raw bytes of a literal or hex string
+	pdfKeyword string // an operator, or "obj", "R", "stream" and the like
+	pdfDict    map[pdfName]any
+	pdfRef     struct{ num, gen int }
+	pdfStream  struct {
+		dict pdfDict
+		raw  []byte
+	}
+)
+
+// maxFormDepth bounds how deeply form XObjects may draw one another.
+const maxFormDepth = 8
+
+// PDFText returns the text layer of a PDF document, page by page, followed
+// on each page by the URIs its links lead to. It reads text shown with
+// simple fonts and with fonts that map their codes to Unicode, as TeX and
+// word processors write them; text drawn as outlines or images, as in
+// scanned documents, has no text layer to read. Damaged files are read as
+// far as possible.
+func PDFText(r io.ReaderAt, size int64) (string, error) {
+	data, err := io.ReadAll(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return "", err
+	}
+	doc := parsePDF(data)
+	if doc.encrypted {
+		return "", ErrEncryptedPDF
+	}
+
+	var text strings.Builder
+	for _, page := range doc.pages() {
+		doc.pageText(page, &text)
+	}
+	return text.String(), nil
+}
+
+// pdfDoc is the objects of a PDF file, found by scanning it rather than
+// through its cross-reference table, which is often damaged.
+type pdfDoc struct {
+	objects   map[int]any
+	trailer   pdfDict
+	encrypted bool
+	fonts     map[pdfRef]*pdfFont
+}
+
+var objRegex = regexp.MustCompile(`(?m)(\d+)\s+(\d+)\s+obj\b`)
+
+func parsePDF(data []byte) *pdfDoc {
+	doc := &pdfDoc{objects: make(map[int]any), trailer: pdfDict{}, fonts: make(map[pdfRef]*pdfFont)}
+
+	// Later definitions of an object, from incremental updates, replace
+	// earlier ones.
+	for _, loc := range objRegex.FindAllSubmatchIndex(data, -1) {
+		num, _ := strconv.Atoi(string(data[loc[2]:loc[3]]))
+		l := &pdfLexer{data: data, pos: loc[1], refs: true}
+		v, err := l.value()
+		if err != nil {
+			continue
+		}
+		if dict, ok := v.(pdfDict); ok {
+			if tok, _ := l.peek(); tok == pdfKeyword("stream") {
+				l.next()
+				v = &pdfStream{dict: dict, raw: l.streamData(dict)}
+			}
+		}
+		doc.objects[num] = v
+	}
+
+	// Objects compressed into object streams, and the trailers, which are
+	// either after the trailer keyword or the dictionaries of
+	// cross-reference streams.
+	var nums []int
+	for num := range doc.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		s, ok := doc.objects[num].(*pdfStream)
+		if !ok {
+			continue
+		}
+		switch s.dict["Type"] {
+		case pdfName("ObjStm"):
+			doc.readObjectStream(s)
+		case pdfName("XRef"):
+			doc.addTrailer(s.dict)
+		}
+	}
+	for i := 0; ; {
+		j := bytes.Index(data[i:], []byte("trailer"))
+		if j < 0 {
+			break
+		}
+		l := &pdfLexer{data: data, pos: i + j + len("trailer"), refs: true}
+		if v, err := l.value(); err == nil {
+			if dict, ok := v.(pdfDict); ok {
+				doc.addTrailer(dict)
+			}
+		}
+		i += j + len("trailer")
+	}
+	return doc
+}
+
+func (d *pdfDoc) addTrailer(dict pdfDict) {
+	for k, v := range dict {
+		d.trailer[k] = v
+	}
+	if _, ok := dict["Encrypt"]; ok {
+		d.encrypted = true
+	}
+}
+
+// readObjectStream adds the objects of an object stream that are not
+// defined directly in the file.
+func (d *pdfDoc) readObjectStream(s *pdfStream) {
+	data, err := d.decode(s)
+	if err != nil {
+		return
+	}
+	n, _ := d.resolve(s.dict["N"]).(float64)
+	first, _ := d.resolve(s.dict["First"]).(float64)
+	header := &pdfLexer{data: data}
+	for i := 0; i < int(n); i++ {
+		numTok, err1 := header.next()
+		offTok, err2 := header.next()
+		num, ok1 := numTok.(float64)
+		off, ok2 := offTok.(float64)
+		if err1 != nil || err2 != nil || !ok1 || !ok2 {
+			return
+		}
+		if _, defined := d.objects[int(num)]; defined {
+			continue
+		}
+		pos := int(first) + int(off)
+		if pos < 0 || pos >= len(data) {
+			continue
+		}
+		l := &pdfLexer{data: data, pos: pos, refs: true}
+		if v, err := l.value(); err == nil {
+			d.objects[int(num)] = v
+		}
+	}
+}
+
+// resolve follows references to the object they name.
+func (d *pdfDoc) resolve(v any) any {
+	for i := 0; i < 32; i++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = d.objects[ref.num]
+	}
+	return nil
+}
+
+func (d *pdfDoc) dict(v any) pdfDict {
+	switch v := d.resolve(v).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+// decode returns the decoded data of a stream. Only FlateDecode, by far
+// the most common filter for text and fonts, is supported.
+func (d *pdfDoc) decode(s *pdfStream) ([]byte, error) {
+	var filters []any
+	switch f := d.resolve(s.dict["Filter"]).(type) {
+	case nil:
+	case pdfName:
+		filters = []any{f}
+	case []any:
+		filters = f
+	}
+	data := s.raw
+	for _, f := range filters {
+		switch d.resolve(f) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			zr, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			lr := &io.LimitedReader{R: zr, N: maxPartSize + 1}
+			out, err := io.ReadAll(lr)
+			// Truncated streams are common; keep what could be read.
+			if len(out) == 0 && err != nil {
+				return nil, err
+			}
+			if lr.N <= 0 {
+				return nil, errTooLarge
+			}
+			data = out
+		default:
+			return nil, fmt.Errorf("unsupported PDF filter %v", f)
+		}
+	}
+	return data, nil
+}
+
+// pages returns the page dictionaries in order, with the resources they
+// inherit filled in. Without a usable page tree, every page object is
+// returned in object number order.
+func (d *pdfDoc) pages() []pdfDict {
+	var pages []pdfDict
+	seen := make(map[int]bool)
+	var walk func(node any, resources any, depth int)
+	walk = func(node any, resources any, depth int) {
+		if ref, ok := node.(pdfRef); ok {
+			if seen[ref.num] {
+				return
+			}
+			seen[ref.num] = true
+		}
+		dict := d.dict(node)
+		if dict == nil || depth > 64 {
+			return
+		}
+		if r, ok := dict["Resources"]; ok {
+			resources = r
+		}
+		if kids, ok := d.resolve(dict["Kids"]).([]any); ok {
+			for _, kid := range kids {
+				walk(kid, resources, depth+1)
+			}
+			return
+		}
+		page := pdfDict{}
+		for k, v := range dict {
+			page[k] = v
+		}
+		page["Resources"] = resources
+		pages = append(pages, page)
+	}
+	if root := d.dict(d.trailer["Root"]); root != nil {
+		walk(root["Pages"], nil, 0)
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+
+	var nums []int
+	for num, v := range d.objects {
+		if dict := d.dict(v); dict != nil && dict["Type"] == pdfName("Page") {
+			nums = append(nums, num)
+		}
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		pages = append(pages, d.dict(d.objects[num]))
+	}
+	return pages
+}
+
+// pageText writes the text of a page, then the URIs of its links.
+func (d *pdfDoc) pageText(page pdfDict, text *strings.Builder) {
+	var content []byte
+	switch c := d.resolve(page["Contents"]).(type) {
+	case *pdfStream:
+		content, _ = d.decode(c)
+	case []any:
+		for _, part := range c {
+			if s, ok := d.resolve(part).(*pdfStream); ok {
+				data, _ := d.decode(s)
+				content = append(append(content, data...), '\n')
+			}
+		}
+	}
+	d.contentText(content, d.dict(page["Resources"]), text, 0)
+	text.WriteByte('\n')
+
+	annots, _ := d.resolve(page["Annots"]).([]any)
+	for _, a := range annots {
+		action := d.dict(d.dict(a)["A"])
+		if uri, ok := d.resolve(action["URI"]).(pdfString); ok {
+			text.WriteString(decodeTextString(uri))
+			text.WriteByte('\n')
+		}
+	}
+}
+
+// contentText writes the text a content stream shows.
+func (d *pdfDoc) contentText(content []byte, resources pdfDict, text *strings.Builder, depth int) {
+	l := &pdfLexer{data: content}
+	var (
+		operands []any
+		font     *pdfFont
+		lastY    float64
+	)
+	number := func(i int) float64 {
+		if i < len(operands) {
+			f, _ := operands[i].(float64)
+			return f
+		}
+		return 0
+	}
+	newline := func() {
+		if text.Len() > 0 && !strings.HasSuffix(text.String(), "\n") {
+			text.WriteByte('\n')
+		}
+	}
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return
+		}
+		op, ok := tok.(pdfKeyword)
+		if !ok || op == "[" || op == "<<" {
+			v, err := l.complete(tok)
+			if err != nil {
+				return
+			}
+			operands = append(operands, v)
+			continue
+		}
+
+		switch op {
+		case "Tf":
+			if name, ok := firstOperand(operands).(pdfName); ok {
+				fonts := d.dict(resources["Font"])
+				font = d.font(fonts[name])
+			}
+		case "Tj":
+			if s, ok := lastOperand(operands).(pdfString); ok {
+				text.WriteString(font.decode(s))
+			}
+		case "'", "\"":
+			newline()
+			if s, ok := lastOperand(operands).(pdfString); ok {
+				text.WriteString(font.decode(s))
+			}
+		case "TJ":
+			items, _ := lastOperand(operands).([]any)
+			for _, item := range items {
+				switch item := item.(type) {
+				case pdfString:
+					text.WriteString(font.decode(item))
+				case float64:
+					// A large negative adjustment is a word space.
+					if item < -250 {
+						text.WriteByte(' ')
+					}
+				}
+			}
+		case "Td", "TD":
+			if number(1) != 0 {
+				newline()
+			}
+		case "T*":
+			newline()
+		case "Tm":
+			if y := number(5); y != lastY {
+				newline()
+				lastY = y
+			}
+		case "Do":
+			name, _ := firstOperand(operands).(pdfName)
+			form, ok := d.resolve(d.dict(resources["XObject"])[name]).(*pdfStream)
+			if ok && form.dict["Subtype"] == pdfName("Form") && depth < maxFormDepth {
+				data, _ := d.decode(form)
+				formResources := d.dict(form.dict["Resources"])
+				if formResources == nil {
+					formResources = resources
+				}
+				d.contentText(data, formResources, text, depth+1)
+			}
+		case "BI":
+			// Inline image data is binary; skip to its end.
+			i := bytes.Index(l.data[l.pos:], []byte("EI"))
+			if i < 0 {
+				return
+			}
+			l.pos += i + 2
+		}
+		operands = operands[:0]
+	}
+}
+
+func firstOperand(operands []any) any {
+	if len(operands) == 0 {
+		return nil
+	}
+	return operands[0]
+}
+
+func lastOperand(operands []any) any {
+	if len(operands) == 0 {
+		return nil
+	}
+	return operands[len(operands)-1]
+}
+
+// pdfFont maps the codes of a font's strings to text through its
+// ToUnicode CMap.
+type pdfFont struct {
+	width int // bytes per code
+	codes map[uint32]string
+}
+
+func (d *pdfDoc) font(v any) *pdfFont {
+	ref, isRef := v.(pdfRef)
+	if f, ok := d.fonts[ref]; isRef && ok {
+		return f
+	}
+	var f *pdfFont
+	dict := d.dict(v)
+	if s, ok := d.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+		if data, err := d.decode(s); err == nil {
+			f = parseCMap(data)
+		}
+	}
+	if f == nil && dict["Subtype"] == pdfName("Type0") {
+		f = &pdfFont{width: 2}
+	}
+	if isRef {
+		d.fonts[ref] = f
+	}
+	return f
+}
+
+// decode returns the text of a string shown in the font. Without a
+// ToUnicode map, single-byte codes are taken as Latin-1, which covers the
+// ASCII an identifier is made of.
+func (f *pdfFont) decode(s pdfString) string {
+	if f == nil {
+		return latin1(string(s))
+	}
+	var b strings.Builder
+	for i := 0; i+f.width <= len(s); i += f.width {
+		var code uint32
+		for _, c := range []byte(s[i : i+f.width]) {
+			code = code<<8 | uint32(c)
+		}
+		if t, ok := f.codes[code]; ok {
+			b.WriteString(t)
+		} else if f.width == 1 {
+			b.WriteRune(rune(code))
+		}
+	}
+	return b.String()
+}
+
+func latin1(s string) string {
+	runes := make([]rune, len(s))
+	for i := 0; i < len(s); i++ {
+		runes[i] = rune(s[i])
+	}
+	return string(runes)
+}
+
+// parseCMap reads the code space and the bfchar and bfrange mappings of a
+// ToUnicode CMap.
+func parseCMap(data []byte) *pdfFont {
+	f := &pdfFont{width: 1, codes: make(map[uint32]string)}
+	l := &pdfLexer{data: data}
+	var operands []any
+	code := func(v any) (uint32, bool) {
+		s, ok := v.(pdfString)
+		if !ok || len(s) == 0 || len(s) > 4 {
+			return 0, false
+		}
+		var c uint32
+		for _, b := range []byte(s) {
+			c = c<<8 | uint32(b)
+		}
+		return c, true
+	}
+	for {
+		tok, err := l.next()
+		if err != nil {
+			break
+		}
+		op, ok := tok.(pdfKeyword)
+		if !ok || op == "[" || op == "<<" {
+			v, err := l.complete(tok)
+			if err != nil {
+				break
+			}
+			operands = append(operands, v)
+			continue
+		}
+		switch op {
+		case "endcodespacerange":
+			if s, ok := firstOperand(operands).(pdfString); ok && len(s) > 0 {
+				f.width = len(s)
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := code(operands[i])
+				dst, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 {
+					f.codes[src] = utf16String(dst)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := code(operands[i])
+				hi, ok2 := code(operands[i+1])
+				if !ok1 || !ok2 || hi < lo || hi-lo > 0xffff {
+					continue
+				}
+				switch dst := operands[i+2].(type) {
+				case pdfString:
+					base := []rune(utf16String(dst))
+					if len(base) == 0 {
+						continue
+					}
+					for c := lo; c <= hi; c++ {
+						last := base[len(base)-1] + rune(c-lo)
+						f.codes[c] = string(base[:len(base)-1]) + string(last)
+					}
+				case []any:
+					for j, item := range dst {
+						if s, ok := item.(pdfString); ok && lo+uint32(j) <= hi {
+							f.codes[lo+uint32(j)] = utf16String(s)
+						}
+					}
+				}
+			}
+		}
+		if strings.HasPrefix(string(op), "begin") || strings.HasPrefix(string(op), "end") {
+			operands = operands[:0]
+		}
+	}
+	return f
+}
+
+func utf16String(s pdfString) string {
+	units := make([]uint16, len(s)/2)
+	for i := range units {
+		units[i] = uint16(s[2*i])<<8 | uint16(s[2*i+1])
+	}
+	return string(utf16.Decode(units))
+}
+
+// decodeTextString decodes a PDF text string, UTF-16BE with a byte order
+// mark or else PDFDocEncoding, taken as Latin-1.
+func decodeTextString(s pdfString) string {
+	if strings.HasPrefix(string(s), "\xfe\xff") {
+		return utf16String(s[2:])
+	}
+	return latin1(string(s))
+}
+
+// pdfLexer reads the tokens and objects of PDF syntax, from the file or
+// from a content stream.
+type pdfLexer struct {
+	data []byte
+	pos  int
+	refs bool // read "num gen R" as references, outside content streams
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case isPDFSpace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (l *pdfLexer) peek() (any, error) {
+	pos := l.pos
+	tok, err := l.next()
+	l.pos = pos
+	return tok, err
+}
+
+// next returns the next token: a number, name, string, or keyword, the
+// keywords including the delimiters "<<", ">>", "[" and "]".
+func (l *pdfLexer) next() (any, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.EOF
+	}
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		l.pos++
+		start := l.pos
+		for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+			l.pos++
+		}
+		return pdfName(unescapeName(string(l.data[start:l.pos]))), nil
+	case c == '(':
+		return l.literalString(), nil
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<',
+		c == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>':
+		l.pos += 2
+		return pdfKeyword(l.data[l.pos-2 : l.pos]), nil
+	case c == '<':
+		return l.hexString(), nil
+	case c == '[' || c == ']' || c == '{' || c == '}' || c == ')' || c == '>':
+		l.pos++
+		return pdfKeyword(c), nil
+	}
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	if f, err := strconv.ParseFloat(word, 64); err == nil && (c == '-' || c == '+' || c == '.' || '0' <= c && c <= '9') {
+		return f, nil
+	}
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	return pdfKeyword(word), nil
+}
+
+// value reads the next object.
+func (l *pdfLexer) value() (any, error) {
+	tok, err := l.next()
+	if err != nil {
+		return nil, err
+	}
+	return l.complete(tok)
+}
+
+// complete reads the rest of the object tok starts: the entries of a
+// dictionary or array, or, for references, the generation and R.
+func (l *pdfLexer) complete(tok any) (any, error) {
+	switch tok {
+	case pdfKeyword("<<"):
+		dict := pdfDict{}
+		for {
+			key, err := l.next()
+			if err != nil {
+				return nil, err
+			}
+			if key == pdfKeyword(">>") {
+				return dict, nil
+			}
+			name, ok := key.(pdfName)
+			if !ok {
+				return nil, fmt.Errorf("PDF dictionary key %v is not a name", key)
+			}
+			v, err := l.value()
+			if err != nil {
+				return nil, err
+			}
+			dict[name] = v
+		}
+	case pdfKeyword("["):
+		var array []any
+		for {
+			tok, err := l.next()
+			if err != nil {
+				return nil, err
+			}
+			if tok == pdfKeyword("]") {
+				return array, nil
+			}
+			v, err := l.complete(tok)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, v)
+		}
+	}
+	if num, ok := tok.(float64); ok && l.refs {
+		pos := l.pos
+		gen, err1 := l.next()
+		r, err2 := l.next()
+		if g, ok := gen.(float64); ok && err1 == nil && err2 == nil && r == pdfKeyword("R") {
+			return pdfRef{int(num), int(g)}, nil
+		}
+		l.pos = pos
+	}
+	return tok, nil
+}
+
+func (l *pdfLexer) literalString() pdfString {
+	l.pos++ // (
+	var b []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return pdfString(b)
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return pdfString(b)
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if '0' <= e && e <= '7' {
+					n := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && '0' <= l.data[l.pos] && l.data[l.pos] <= '7'; i++ {
+						n = n*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(n)
+				} else {
+					c = e
+				}
+			}
+		}
+		b = append(b, c)
+	}
+	return pdfString(b)
+}
+
+func (l *pdfLexer) hexString() pdfString {
+	l.pos++ // <
+	end := bytes.IndexByte(l.data[l.pos:], '>')
+	if end < 0 {
+		end = len(l.data) - l.pos
+	}
+	digits := make([]byte, 0, end)
+	for _, c := range l.data[l.pos : l.pos+end] {
+		if !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	l.pos += end + 1
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	b, _ := hex.DecodeString(string(digits))
+	return pdfString(b)
+}
+
+// streamData returns the raw data of the stream whose stream keyword was
+// just read, by its Length when that is direct and right, or up to the
+// endstream keyword otherwise.
+func (l *pdfLexer) streamData(dict pdfDict) []byte {
+	start := l.pos
+	if start < len(l.data) && l.data[start] == '\r' {
+		start++
+	}
+	if start < len(l.data) && l.data[start] == '\n' {
+		start++
+	}
+	if n, ok := dict["Length"].(float64); ok && n >= 0 && start+int(n) <= len(l.data) {
+		end := start + int(n)
+		rest := bytes.TrimLeft(l.data[end:min(end+16, len(l.data))], "\r\n \t")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			l.pos = end
+			return l.data[start:end]
+		}
+	}
+	end := bytes.Index(l.data[start:], []byte("endstream"))
+	if end < 0 {
+		l.pos = len(l.data)
+		return l.data[start:]
+	}
+	l.pos = start + end
+	return bytes.TrimRight(l.data[start:start+end], "\r\n")
+}
+
+func unescapeName(s string) string {
+	if !strings.Contains(s, "#") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '#' && i+2 < len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,125 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF assembles a PDF file from the bodies of objects 1, 2, ...,
+// with a cross-reference table and a trailer naming object 1 as the
+// catalog.
+func buildPDF(objects ...string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+func stream(dict string, data []byte) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func flate(data string) []byte {
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	w.Write([]byte(data))
+	w.Close()
+	return b.Bytes()
+}
+
+func TestPDFText(t *testing.T) {
+	// Page 1 uses a simple font and wraps the SWHID over two lines; page
+	// 2 uses a two-byte font mapped to Unicode through its ToUnicode CMap
+	// and links to an archive URL.
+	page1 := `BT /F1 10 Tf 72 720 Td (The code is archived as swh:1:dir:d198bc9d7a6bcf6db04f476d2931-) Tj
+0 -12 Td [(4f157507d505) -333 (\(see the archive\).)] TJ ET`
+	var codes strings.Builder
+	for _, r := range "swh:1:cnt:94a9ed024d3859793618152ea559a168bbcbb5e2" {
+		fmt.Fprintf(&codes, "%04X", 0x100+r)
+	}
+	page2 := "BT /F2 10 Tf 1 0 0 1 72 720 Tm <" + codes.String() + "> Tj ET"
+	cmap := `/CIDInit /ProcSet findresource begin 12 dict begin begincmap
+1 begincodespacerange <0000> <FFFF> endcodespacerange
+1 beginbfrange <0100> <017F> <0000> endbfrange
+endcmap end end`
+
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /Resources << /Font << /F1 5 0 R /F2 6 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 7 0 R >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 8 0 R /Annots [<< /Type /Annot /Subtype /Link /A << /S /URI /URI (https://archive.softwareheritage.org/swh:1:rev:309cf2674ee7a0749978cf8265ab91a60aea0f7d) >> >>] >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		"<< /Type /Font /Subtype /Type0 /BaseFont /Custom /Encoding /Identity-H /ToUnicode 9 0 R >>",
+		stream("", []byte(page1)),
+		stream("/Filter /FlateDecode", flate(page2)),
+		stream("/Filter /FlateDecode", flate(cmap)),
+	)
+
+	text, err := PDFText(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("PDFText() error = %v", err)
+	}
+	var got []string
+	for _, m := range Text(text) {
+		if m.Err != nil {
+			t.Fatalf("Text() error = %v in %q", m.Err, text)
+		}
+		got = append(got, m.ID.String())
+	}
+	want := []string{
+		"swh:1:dir:d198bc9d7a6bcf6db04f476d29314f157507d505",
+		"swh:1:cnt:94a9ed024d3859793618152ea559a168bbcbb5e2",
+		"swh:1:rev:309cf2674ee7a0749978cf8265ab91a60aea0f7d",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("SWHIDs in PDF = %q, want %q\ntext: %q", got, want, text)
+	}
+}
+
+func TestPDFObjectStream(t *testing.T) {
+	// The page tree lives in a compressed object stream, as PDF 1.5 and
+	// later writers store it.
+	pages, page := "<< /Type /Pages /Kids [4 0 R] /Count 1 >> ", "<< /Type /Page /Parent 2 0 R /Contents 5 0 R >>"
+	objs := pages + page
+	header := fmt.Sprintf("2 0 4 %d ", len(pages))
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"null",
+		stream(fmt.Sprintf("/Type /ObjStm /N 2 /First %d /Filter /FlateDecode", len(header)), flate(header+objs)),
+		"null",
+		stream("", []byte("BT 72 720 Td ("+testSWHID+") Tj ET")),
+	)
+	// Objects 2 and 4 are only placeholders in the file; drop them so the
+	// object stream defines them.
+	data = bytes.Replace(data, []byte("2 0 obj\nnull\nendobj\n"), nil, 1)
+	data = bytes.Replace(data, []byte("4 0 obj\nnull\nendobj\n"), nil, 1)
+
+	text, err := PDFText(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("PDFText() error = %v", err)
+	}
+	if m := Text(text); len(m) != 1 || m[0].Err != nil || m[0].ID.String() != testSWHID {
+		t.Errorf("Text(PDFText()) = %+v, text %q", m, text)
+	}
+}
+
+func TestPDFEncrypted(t *testing.T) {
+	data := bytes.Replace(buildPDF("<< /Type /Catalog >>"), []byte("/Root 1 0 R"), []byte("/Root 1 0 R /Encrypt 2 0 R"), 1)
+	if _, err := PDFText(bytes.NewReader(data), int64(len(data))); !errors.Is(err, ErrEncryptedPDF) {
+		t.Errorf("PDFText() error = %v, want ErrEncryptedPDF", err)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package swhid
+
+import "os"
+
+// hardLinkID reports no identity: hard links are only recognised on Unix.
+func hardLinkID(info os.FileInfo) (fileID, bool) {
+	return fileID{}, false
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package swhid
+
+import (
+	"os"
+	"syscall"
+)
+
+// hardLinkID returns the device and inode of a regular file that has more
+// than one link, so its content can be hashed once for all of them.
+func hardLinkID(info os.FileInfo) (fileID, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || uint64(st.Nlink) < 2 {
+		return fileID{}, false
+	}
+	return fileID{dev: uint64(st.Dev), ino: uint64(st.Ino)}, true
+}
@@ -1,25 +1,202 @@
 package swhid
 
 import (
+	"context"
+	"errors"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
-	"sort"
+	"slices"
 
 	"github.com/andrew/swhid-go/objects"
-	"github.com/go-git/go-git/v5"
 )
 
-// FromDirectoryPath computes the SWHID for a directory on the filesystem.
-// It recursively hashes all files and subdirectories.
-// If the directory is within a Git repository, it uses the Git index for file permissions.
-func FromDirectoryPath(path string) (*Identifier, error) {
-	return FromDirectoryPathWithOptions(path, nil, nil)
+// TreeOptions adjusts how TreeFromDirectoryPathWithOptions reads a
+// directory.
+type TreeOptions struct {
+	// Cached is consulted before a regular file is read, with its path
+	// relative to the root and its file info. When it returns a non-nil
+	// identifier, that is used as the file's content SWHID and the file is
+	// not read. This lets callers skip files unchanged since an earlier run.
+	Cached func(relPath string, info os.FileInfo) *Identifier
+
+	// Include, when non-empty, limits hashing to entries matching one of
+	// its patterns and everything below them. Directories are only
+	// descended into if they match or could contain a match, and ones left
+	// empty are dropped. Exclude skips matching entries and everything
+	// below them without reading them. Patterns use path.Match syntax; one
+	// without a slash matches entry names at any depth ("*.log"), one with
+	// a slash matches paths from the root ("src/testdata").
+	//
+	// The root SWHID of a filtered tree identifies the filtered tree, not
+	// the directory on disk.
+	Include []string
+	Exclude []string
+
+	// SkipOSJunk adds OSJunk to Exclude, leaving out the metadata files
+	// operating systems and file managers scatter through trees, such as
+	// macOS AppleDouble "._*" files and .DS_Store, so that a tree copied
+	// through Finder or onto a USB stick hashes as it did before.
+	SkipOSJunk bool
+
+	// FollowSymlinks hashes what symbolic links point to, as files or
+	// directories, instead of the links themselves. Links whose target
+	// does not exist are still hashed as links. Software Heritage does not
+	// follow links, so the result differs from the archive's SWHID for
+	// trees containing them.
+	FollowSymlinks bool
+
+	// NoHardLinkReuse reads and hashes every hard link to a file
+	// separately. By default, on Unix, a file with several links is hashed
+	// once per traversal and the result reused for its other links, found
+	// by device and inode.
+	NoHardLinkReuse bool
+
+	// MaxDepth bounds how deeply directories may nest below the root; 0
+	// means DefaultMaxDepth and a negative value means no limit.
+	MaxDepth int
+
+	// Modes sets the mode regular files are hashed with, by their path
+	// from the root, overriding the Git index and the filesystem. Keys are
+	// paths ("bin/run") or patterns as for Include ("*.sh",
+	// "scripts/**/*.py", or "configure" for that name at any depth); a path
+	// wins over patterns, and a longer pattern over a shorter one. Only the
+	// executable bits matter.
+	Modes map[string]os.FileMode
+
+	// ModeFunc, when set, decides the mode of every regular file instead
+	// of Modes, the Git index and the filesystem. It is called with the
+	// file's path from the root and the file info read from disk, so
+	// returning info.Mode() keeps the filesystem's mode. Build systems that
+	// know the intended modes can use it to hash trees checked out where
+	// modes are lost.
+	ModeFunc func(relPath string, info os.FileInfo) os.FileMode
+
+	// VCSDirs names the version control metadata entries skipped at any
+	// depth, whether directories or files such as the .git file of a
+	// submodule checkout. Nil means DefaultVCSDirs, as Software Heritage
+	// archives trees; AllVCSDirs adds Mercurial, Subversion and Bazaar. A
+	// non-nil empty slice skips nothing, to hash a repository as data.
+	VCSDirs []string
+
+	// ReadOnlyFS opens the enclosing Git repository, consulted for
+	// executable bits, through filesystems that refuse writes; see
+	// GitOptions.ReadOnlyFS. Hashing the tree itself only ever reads.
+	ReadOnlyFS bool
+
+	// NoGitIndex leaves the index of an enclosing Git repository out of
+	// hashing. By default the repository holding the directory is looked
+	// for and the executable bits of the files its index tracks taken from
+	// there, as a checkout on a filesystem without modes would lose them;
+	// with NoGitIndex they come from Modes, ModeFunc and the filesystem
+	// alone, as for vendored trees the index does not describe. Tracked
+	// files whose size and modification time are those the index recorded
+	// when they were staged are not read either, unless Inspect or the
+	// Git LFS pointer check of Warn needs their contents: their SWHIDs
+	// are the blobs the index names, which git status trusts the same way.
+	// NoGitIndex reads every file.
+	NoGitIndex bool
+
+	// Concurrency, when above 1, hashes up to that many files at once. The
+	// tree is then walked twice, first to list the files to hash and then,
+	// once they are hashed, to build the tree from the results. Scheduler
+	// decides the order files are handed to workers in; nil means
+	// LargestFirst.
+	Concurrency int
+	Scheduler   Scheduler
+
+	// DetectMIME records the media type of regular files in their nodes'
+	// MIMEType, detected from their first bytes by the magic package while
+	// they are read for hashing. Files whose SWHID comes from Cached, the Git
+	// index or an earlier hard link are only read as far as detection needs.
+	DetectMIME bool
+
+	// Inspect, when set, is called before a regular file is read for
+	// hashing, with its path from the root and its file info. The contents
+	// are copied to the writer it returns as they are hashed, so that
+	// license scanners and the like see every file without reading it a
+	// second time; returning nil skips the file. A writer that is also an
+	// io.Closer is closed once the file has been read. An error from Write
+	// or Close stops the traversal. Files whose SWHID comes from Cached or
+	// an earlier hard link are not read and not inspected. With
+	// Concurrency above 1, Inspect is called from several goroutines at
+	// once.
+	Inspect func(relPath string, info os.FileInfo) io.Writer
+
+	// Warn, when set, is told of what hashing did that the caller may not
+	// expect without stopping: special files left out, files of a Git
+	// checkout missing from its index whose executable bit comes from the
+	// filesystem, Git LFS pointers hashed in place of the files they stand
+	// for, and dangling links kept with FollowSymlinks. With Concurrency
+	// above 1, Warn is called from several goroutines at once.
+	Warn func(Warning)
 }
 
-// FromDirectoryPathWithOptions computes the SWHID with custom options.
-// gitRepo can be provided to use Git index for permissions.
-// permissions can be provided as a map of path -> mode for explicit permissions.
-func FromDirectoryPathWithOptions(path string, gitRepo *git.Repository, permissions map[string]os.FileMode) (*Identifier, error) {
+// excludes returns the exclude patterns in effect: Exclude, plus OSJunk
+// with SkipOSJunk.
+func (opts TreeOptions) excludes() []string {
+	if !opts.SkipOSJunk {
+		return opts.Exclude
+	}
+	return append(slices.Clip(opts.Exclude), OSJunk...)
+}
+
+// DefaultMaxDepth is the directory nesting limit used when
+// TreeOptions.MaxDepth is zero.
+const DefaultMaxDepth = 1024
+
+// DefaultVCSDirs are the entries skipped when TreeOptions.VCSDirs is nil.
+var DefaultVCSDirs = []string{".git"}
+
+// AllVCSDirs are the metadata directories of the version control systems
+// Software Heritage loads: Git, Mercurial, Subversion and Bazaar.
+var AllVCSDirs = []string{".git", ".hg", ".svn", ".bzr"}
+
+// OSJunk are the exclude patterns TreeOptions.SkipOSJunk adds: macOS
+// Finder and Spotlight metadata, AppleDouble files and the __MACOSX
+// directories of zip files made by the Archive Utility, and Windows
+// thumbnail caches and folder settings.
+var OSJunk = []string{
+	".DS_Store", "._*", ".AppleDouble", ".LSOverride", "Icon\r",
+	".Spotlight-V100", ".Trashes", ".fseventsd", ".TemporaryItems",
+	".DocumentRevisions-V100", "__MACOSX",
+	"Thumbs.db", "ehthumbs.db", "desktop.ini", "$RECYCLE.BIN",
+}
+
+// ErrSymlinkLoop is returned when following symbolic links leads back into
+// a directory that contains the link.
+var ErrSymlinkLoop = errors.New("symbolic link loop")
+
+// ErrTreeTooDeep is returned when directories nest deeper than the
+// configured maximum depth.
+var ErrTreeTooDeep = errors.New("directory tree too deep")
+
+// TreeFromDirectoryPath hashes a directory like FromDirectoryPath and
+// returns the whole Merkle tree rather than just the root SWHID.
+func TreeFromDirectoryPath(path string) (*Node, error) {
+	return TreeFromDirectoryPathWithOptions(path, TreeOptions{})
+}
+
+// TreeFromDirectoryPathWithOptions is TreeFromDirectoryPath with options.
+func TreeFromDirectoryPathWithOptions(path string, opts TreeOptions) (*Node, error) {
+	return TreeFromDirectoryPathContext(context.Background(), path, opts)
+}
+
+// TreeFromDirectoryPathContext is TreeFromDirectoryPathWithOptions, giving
+// up with ctx's error once ctx is done. Cancellation is checked before each
+// directory entry.
+func TreeFromDirectoryPathContext(ctx context.Context, path string, opts TreeOptions) (*Node, error) {
+	if opts.Concurrency > 1 {
+		return treeParallel(ctx, path, opts)
+	}
+	return buildTree(ctx, path, opts, nil)
+}
+
+// buildTree hashes the directory at path one file at a time. With
+// opts.DetectMIME, mimeTypes gives the media types already detected for
+// files by their path from the root.
+func buildTree(ctx context.Context, path string, opts TreeOptions, mimeTypes map[string]string) (*Node, error) {
 	info, err := os.Stat(path)
 	if err != nil {
 		return nil, err
@@ -28,65 +205,147 @@ func FromDirectoryPathWithOptions(path string, gitRepo *git.Repository, permissi
 		return nil, &os.PathError{Op: "swhid", Path: path, Err: os.ErrInvalid}
 	}
 
-	// Try to discover Git repo if not provided
-	if gitRepo == nil {
-		gitRepo = discoverGitRepo(path)
+	filter, err := newPathFilter(opts.Include, opts.excludes())
+	if err != nil {
+		return nil, err
 	}
 
-	entries, err := buildEntries(path, gitRepo, permissions)
+	modes, err := newModeTable(opts.Modes)
 	if err != nil {
 		return nil, err
 	}
 
-	return FromDirectory(entries), nil
-}
-
-func discoverGitRepo(path string) *git.Repository {
-	// Walk up the directory tree looking for .git
-	absPath, err := filepath.Abs(path)
+	b := &treeBuilder{ctx: ctx, opts: opts, filter: filter, modes: modes, mimeTypes: mimeTypes}
+	if !opts.NoGitIndex {
+		b.indexModes, b.indexBlobs = discoverIndex(path, opts.ReadOnlyFS)
+	}
+	node, err := b.build(path, info, filter.included(""))
 	if err != nil {
-		return nil
+		return nil, err
+	}
+	if node == nil {
+		node = newDirectoryNode("", filepath.Base(path), nil)
+		node.ModTime = info.ModTime()
 	}
+	return node, nil
+}
 
-	for {
-		repo, err := git.PlainOpen(absPath)
-		if err == nil {
-			return repo
-		}
+// indexModes returns the mode a Git index records for the file at
+// fullPath, if the file is tracked.
+type indexModes func(fullPath string) (os.FileMode, bool)
+
+// indexBlobs returns the blob a Git index records for the regular file at
+// fullPath, whose file info is info, if the file is tracked and has not
+// changed since it was staged, as far as its file info tells.
+type indexBlobs func(fullPath string, info os.FileInfo) (*Identifier, bool)
+
+// treeBuilder hashes a directory on the filesystem into a Merkle tree.
+type treeBuilder struct {
+	ctx         context.Context
+	indexModes  indexModes
+	indexBlobs  indexBlobs
+	permissions map[string]os.FileMode
+	opts        TreeOptions
+	filter      *pathFilter
+	modes       *modeTable
+	hardLinks   map[fileID]*Node
+	mimeTypes   map[string]string
+}
 
-		parent := filepath.Dir(absPath)
-		if parent == absPath {
-			break
-		}
-		absPath = parent
-	}
+// fileID identifies a file by device and inode.
+type fileID struct {
+	dev, ino uint64
+}
 
-	return nil
+// dirFrame is a directory on the traversal stack. Its entries are handled
+// one at a time; a subdirectory is pushed as a new frame and its node is
+// added to children once the frame is popped.
+type dirFrame struct {
+	parent   *dirFrame
+	dirPath  string
+	relPath  string
+	info     os.FileInfo
+	depth    int
+	included bool
+	entries  []os.DirEntry
+	next     int
+	children []*Node
 }
 
-func buildEntries(dirPath string, gitRepo *git.Repository, permissions map[string]os.FileMode) ([]objects.DirectoryEntry, error) {
-	dirEntries, err := os.ReadDir(dirPath)
+// build hashes the directory at dirPath, whose file info is info. When
+// included is false, directories are only searched for entries matching
+// an include pattern, and ones holding none are dropped; build returns nil
+// if the whole tree is dropped.
+//
+// The traversal keeps its own stack rather than recursing, so deep trees
+// cannot exhaust the goroutine stack.
+func (b *treeBuilder) build(dirPath string, info os.FileInfo, included bool) (*Node, error) {
+	root, err := b.push(nil, dirPath, "", info, included)
 	if err != nil {
 		return nil, err
 	}
 
-	var entries []objects.DirectoryEntry
+	stack := []*dirFrame{root}
+	for {
+		f := stack[len(stack)-1]
+		if f.next == len(f.entries) {
+			stack = stack[:len(stack)-1]
+			var node *Node
+			if f.included || len(f.children) > 0 {
+				node = newDirectoryNode(f.relPath, filepath.Base(f.dirPath), f.children)
+				node.ModTime = f.info.ModTime()
+			}
+			if f.parent == nil {
+				return node, nil
+			}
+			if node != nil {
+				f.parent.children = append(f.parent.children, node)
+			}
+			continue
+		}
+
+		if err := b.ctx.Err(); err != nil {
+			return nil, err
+		}
+		de := f.entries[f.next]
+		f.next++
 
-	for _, de := range dirEntries {
 		name := de.Name()
 
-		// Skip .git directory
-		if name == ".git" {
+		// Skip version control metadata
+		if b.vcsDir(name) {
+			continue
+		}
+
+		childPath := path.Join(f.relPath, name)
+		if b.filter.excluded(childPath) {
+			continue
+		}
+		childIncluded := f.included || b.filter.included(childPath)
+		mayBeDir := de.IsDir() || (b.opts.FollowSymlinks && de.Type()&os.ModeSymlink != 0)
+		if !childIncluded && !(mayBeDir && b.filter.mayContain(childPath)) {
 			continue
 		}
 
-		fullPath := filepath.Join(dirPath, name)
+		fullPath := filepath.Join(f.dirPath, name)
 		info, err := de.Info()
 		if err != nil {
 			return nil, err
 		}
 
-		var entry objects.DirectoryEntry
+		if info.Mode()&os.ModeSymlink != 0 && b.opts.FollowSymlinks {
+			// Dangling links are kept as symlinks
+			if target, err := os.Stat(fullPath); err == nil {
+				info = target
+			} else if childIncluded {
+				b.opts.warn(childPath, WarnDanglingSymlink, "target missing, hashed as a link")
+			}
+		}
+		if !childIncluded && !info.IsDir() {
+			continue
+		}
+
+		var child *Node
 
 		// Check if it's a symlink
 		if info.Mode()&os.ModeSymlink != 0 {
@@ -94,123 +353,160 @@ func buildEntries(dirPath string, gitRepo *git.Repository, permissions map[strin
 			if err != nil {
 				return nil, err
 			}
-			targetHash := objects.ComputeContentHash([]byte(target))
-			entry = objects.DirectoryEntry{
-				Name:   name,
-				Type:   objects.EntryTypeSymlink,
-				Target: targetHash,
-			}
+			child = newContentNode(f.relPath, name, objects.EntryTypeSymlink, []byte(target))
 		} else if info.IsDir() {
-			// Recurse into subdirectory
-			subID, err := FromDirectoryPathWithOptions(fullPath, gitRepo, permissions)
+			// Descend into subdirectory
+			frame, err := b.push(f, fullPath, childPath, info, childIncluded)
 			if err != nil {
 				return nil, err
 			}
-			entry = objects.DirectoryEntry{
-				Name:   name,
-				Type:   objects.EntryTypeDirectory,
-				Target: subID.ObjectHash,
-			}
+			stack = append(stack, frame)
+			continue
+		} else if !info.Mode().IsRegular() {
+			// FIFOs, sockets and devices have no place in a Git tree
+			b.opts.warn(childPath, WarnSpecialFile, "%s skipped", specialFileKind(info.Mode()))
+			continue
 		} else {
 			// Regular file
-			content, err := os.ReadFile(fullPath)
-			if err != nil {
-				return nil, err
-			}
-			targetHash := objects.ComputeContentHash(content)
-
 			entryType := objects.EntryTypeFile
-			if isExecutable(fullPath, info, gitRepo, permissions) {
+			if b.isExecutable(fullPath, childPath, info) {
 				entryType = objects.EntryTypeExecutable
 			}
 
-			entry = objects.DirectoryEntry{
-				Name:   name,
-				Type:   entryType,
-				Target: targetHash,
+			var id *Identifier
+			if b.indexBlobs != nil && !b.opts.needsContent(info.Size()) {
+				id, _ = b.indexBlobs(fullPath, info)
+			}
+			if id == nil && b.opts.Cached != nil {
+				id = b.opts.Cached(childPath, info)
+			}
+			link, linked := hardLinkID(info)
+			linked = linked && !b.opts.NoHardLinkReuse
+			mimeType := ""
+			if id == nil && linked {
+				if n := b.hardLinks[link]; n != nil {
+					id, mimeType = n.ID, n.MIMEType
+				}
+			}
+			if id != nil {
+				if b.opts.DetectMIME && mimeType == "" {
+					mimeType = b.mimeTypes[childPath]
+					if mimeType == "" {
+						if mimeType, err = fileMIMEType(fullPath, info.Size()); err != nil {
+							return nil, err
+						}
+					}
+				}
+				child = &Node{Name: name, Path: childPath, Type: entryType, ID: id, Size: info.Size(), MIMEType: mimeType}
+			} else {
+				file, err := os.Open(fullPath)
+				if err != nil {
+					return nil, err
+				}
+				r, done := tap(file, childPath, info, b.opts)
+				child, err = newStreamedNode(f.relPath, name, entryType, r, info.Size())
+				file.Close()
+				mimeType, err := done(err)
+				if err != nil {
+					return nil, err
+				}
+				child.MIMEType = mimeType
+			}
+			if linked {
+				if b.hardLinks == nil {
+					b.hardLinks = make(map[fileID]*Node)
+				}
+				b.hardLinks[link] = child
 			}
 		}
 
-		entries = append(entries, entry)
+		child.ModTime = info.ModTime()
+		f.children = append(f.children, child)
 	}
+}
 
-	// Sort for deterministic output
-	sort.Slice(entries, func(i, j int) bool {
-		return entries[i].SortKey() < entries[j].SortKey()
-	})
+// vcsDir reports whether name is version control metadata to skip.
+func (b *treeBuilder) vcsDir(name string) bool {
+	dirs := b.opts.VCSDirs
+	if dirs == nil {
+		dirs = DefaultVCSDirs
+	}
+	return slices.Contains(dirs, name)
+}
+
+// push reads a directory into a new frame below parent, refusing to enter
+// a directory that is already being traversed or that is too deep.
+func (b *treeBuilder) push(parent *dirFrame, dirPath, relPath string, info os.FileInfo, included bool) (*dirFrame, error) {
+	depth := 0
+	if parent != nil {
+		depth = parent.depth + 1
+	}
+
+	maxDepth := b.opts.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = DefaultMaxDepth
+	}
+	if maxDepth > 0 && depth > maxDepth {
+		return nil, &os.PathError{Op: "swhid", Path: dirPath, Err: ErrTreeTooDeep}
+	}
 
-	return entries, nil
+	for a := parent; a != nil; a = a.parent {
+		if os.SameFile(a.info, info) {
+			return nil, &os.PathError{Op: "swhid", Path: dirPath, Err: ErrSymlinkLoop}
+		}
+	}
+
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dirFrame{
+		parent:   parent,
+		dirPath:  dirPath,
+		relPath:  relPath,
+		info:     info,
+		depth:    depth,
+		included: included,
+		entries:  entries,
+	}, nil
 }
 
-func isExecutable(fullPath string, info os.FileInfo, gitRepo *git.Repository, permissions map[string]os.FileMode) bool {
-	// Check explicit permissions map first
-	if permissions != nil {
-		if mode, ok := permissions[fullPath]; ok {
+// isExecutable decides whether the regular file at fullPath, relPath from
+// the root, is hashed as executable.
+func (b *treeBuilder) isExecutable(fullPath, relPath string, info os.FileInfo) bool {
+	if b.opts.ModeFunc != nil {
+		return b.opts.ModeFunc(relPath, info)&0111 != 0
+	}
+	if mode, ok := b.modes.lookup(relPath); ok {
+		return mode&0111 != 0
+	}
+
+	// Check explicit permissions map next
+	if b.permissions != nil {
+		if mode, ok := b.permissions[fullPath]; ok {
+			return mode&0111 != 0
+		}
+		if mode, ok := b.permissions[relPath]; ok {
 			return mode&0111 != 0
 		}
 		// Try with resolved path
 		absPath, err := filepath.Abs(fullPath)
 		if err == nil {
-			if mode, ok := permissions[absPath]; ok {
+			if mode, ok := b.permissions[absPath]; ok {
 				return mode&0111 != 0
 			}
 		}
 	}
 
-	// Check Git index for tracked files
-	if gitRepo != nil {
-		relPath := relativePathInRepo(fullPath, gitRepo)
-		if relPath != "" {
-			// Try to get mode from index
-			idx, err := gitRepo.Storer.Index()
-			if err == nil {
-				for _, entry := range idx.Entries {
-					if entry.Name == relPath {
-						return entry.Mode&0111 != 0
-					}
-				}
-			}
+	// Check the Git index for tracked files
+	if b.indexModes != nil {
+		if mode, ok := b.indexModes(fullPath); ok {
+			return mode&0111 != 0
 		}
+		b.opts.warn(relPath, WarnModeFallback, "not in the Git index, executable bit taken from the filesystem")
 	}
 
 	// Fall back to filesystem
 	return info.Mode()&0111 != 0
 }
-
-func relativePathInRepo(fullPath string, gitRepo *git.Repository) string {
-	worktree, err := gitRepo.Worktree()
-	if err != nil {
-		return ""
-	}
-
-	repoRoot := worktree.Filesystem.Root()
-	absPath, err := filepath.Abs(fullPath)
-	if err != nil {
-		return ""
-	}
-
-	// Resolve symlinks
-	absPath, _ = filepath.EvalSymlinks(absPath)
-	repoRoot, _ = filepath.EvalSymlinks(repoRoot)
-
-	// Normalize separators
-	absPath = filepath.ToSlash(absPath)
-	repoRoot = filepath.ToSlash(repoRoot)
-
-	if !hasPrefix(absPath, repoRoot) {
-		return ""
-	}
-
-	rel := absPath[len(repoRoot):]
-	if len(rel) > 0 && rel[0] == '/' {
-		rel = rel[1:]
-	}
-	return rel
-}
-
-func hasPrefix(s, prefix string) bool {
-	if len(s) < len(prefix) {
-		return false
-	}
-	return s[:len(prefix)] == prefix
-}
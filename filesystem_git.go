@@ -0,0 +1,226 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// FromDirectoryPath computes the SWHID for a directory on the filesystem.
+// It recursively hashes all files and subdirectories.
+// If the directory is within a Git repository, it uses the Git index for file permissions,
+// and takes the SWHIDs of files unchanged since they were staged from the index
+// instead of reading them.
+func FromDirectoryPath(path string) (*Identifier, error) {
+	return FromDirectoryPathWithOptions(path, nil, nil)
+}
+
+// FromDirectoryPathWithOptions computes the SWHID with custom options.
+// gitRepo can be provided to use Git index for permissions.
+// permissions can be provided as a map of path -> mode for explicit permissions,
+// keyed by the path as joined onto path, the absolute path, or the slash-separated
+// path relative to the directory. TreeOptions.Modes also takes patterns.
+func FromDirectoryPathWithOptions(path string, gitRepo *git.Repository, permissions map[string]os.FileMode) (*Identifier, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &os.PathError{Op: "swhid", Path: path, Err: os.ErrInvalid}
+	}
+
+	// Try to discover Git repo if not provided
+	if gitRepo == nil {
+		gitRepo = discoverGitRepo(path, false)
+	}
+
+	idx := newGitIndex(gitRepo)
+	b := &treeBuilder{ctx: context.Background(), indexModes: idx.modes(), indexBlobs: idx.blobs(), permissions: permissions}
+	node, err := b.build(path, info, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return node.ID, nil
+}
+
+// discoverIndex returns lookups into the index of the Git repository
+// enclosing path, or nils outside a repository.
+func discoverIndex(path string, readOnly bool) (indexModes, indexBlobs) {
+	idx := newGitIndex(discoverGitRepo(path, readOnly))
+	return idx.modes(), idx.blobs()
+}
+
+func discoverGitRepo(path string, readOnly bool) *git.Repository {
+	// Walk up the directory tree looking for .git
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+
+	for {
+		repo, err := openRepository(absPath, false, readOnly)
+		if err == nil {
+			return repo
+		}
+
+		parent := filepath.Dir(absPath)
+		if parent == absPath {
+			break
+		}
+		absPath = parent
+	}
+
+	return nil
+}
+
+// gitIndex looks files up in the index of a repository's worktree, read
+// once, when first needed. It serves one traversal at a time.
+type gitIndex struct {
+	repo    *git.Repository
+	once    sync.Once
+	entries map[string]*index.Entry
+	written time.Time // when the index file was last written, if known
+
+	// The last file looked up, as its mode and then its blob are
+	lastPath  string
+	lastEntry *index.Entry
+}
+
+// newGitIndex returns the index of gitRepo, or nil for a nil repository.
+func newGitIndex(gitRepo *git.Repository) *gitIndex {
+	if gitRepo == nil {
+		return nil
+	}
+	return &gitIndex{repo: gitRepo}
+}
+
+// entry returns the index entry for the file at fullPath, if it is tracked.
+func (x *gitIndex) entry(fullPath string) *index.Entry {
+	x.once.Do(x.load)
+	if fullPath == x.lastPath {
+		return x.lastEntry
+	}
+	var e *index.Entry
+	if relPath := relativePathInRepo(fullPath, x.repo); relPath != "" {
+		e = x.entries[relPath]
+	}
+	x.lastPath, x.lastEntry = fullPath, e
+	return e
+}
+
+func (x *gitIndex) load() {
+	idx, err := x.repo.Storer.Index()
+	if err != nil {
+		return
+	}
+	x.entries = make(map[string]*index.Entry, len(idx.Entries))
+	for _, e := range idx.Entries {
+		// Of the stages of a conflicted path, the first
+		if _, ok := x.entries[e.Name]; !ok {
+			x.entries[e.Name] = e
+		}
+	}
+	if storage, ok := x.repo.Storer.(*filesystem.Storage); ok {
+		if info, err := storage.Filesystem().Stat("index"); err == nil {
+			x.written = info.ModTime()
+		}
+	}
+}
+
+// modes looks files' modes up in the index; it returns nil for a nil
+// index.
+func (x *gitIndex) modes() indexModes {
+	if x == nil {
+		return nil
+	}
+	return func(fullPath string) (os.FileMode, bool) {
+		e := x.entry(fullPath)
+		if e == nil {
+			return 0, false
+		}
+		mode, err := e.Mode.ToOSFileMode()
+		return mode, err == nil
+	}
+}
+
+// blobs looks the blobs of unchanged files up in the index, as git status
+// does to tell which files it need not read; it returns nil for a nil
+// index. A file is taken as unchanged when its size and modification time
+// are those recorded when it was staged, unless it was modified no earlier
+// than the index was written, in which case, as Git says, it is racily
+// clean and may have changed within the timestamp's resolution. The blob
+// must also be as large as the file: files checked out through a clean
+// filter, such as Git LFS or line ending conversion, are not stored as they
+// are on disk.
+func (x *gitIndex) blobs() indexBlobs {
+	if x == nil {
+		return nil
+	}
+	return func(fullPath string, info os.FileInfo) (*Identifier, bool) {
+		e := x.entry(fullPath)
+		switch {
+		// Stage 0 is merged; go-git's index.Merged is 1, a conflict stage
+		case e == nil || e.Stage != 0 || e.IntentToAdd || e.SkipWorktree:
+			return nil, false
+		case e.Mode != filemode.Regular && e.Mode != filemode.Executable:
+			return nil, false
+		case e.Size != uint32(info.Size()) || !e.ModifiedAt.Equal(info.ModTime()):
+			return nil, false
+		case x.written.IsZero() || !info.ModTime().Before(x.written):
+			return nil, false
+		}
+		if size, err := x.repo.Storer.EncodedObjectSize(e.Hash); err != nil || size != info.Size() {
+			return nil, false
+		}
+		id, err := NewIdentifier(ObjectTypeContent, e.Hash.String(), nil)
+		return id, err == nil
+	}
+}
+
+func relativePathInRepo(fullPath string, gitRepo *git.Repository) string {
+	worktree, err := gitRepo.Worktree()
+	if err != nil {
+		return ""
+	}
+
+	repoRoot := worktree.Filesystem.Root()
+	absPath, err := filepath.Abs(fullPath)
+	if err != nil {
+		return ""
+	}
+
+	// Resolve symlinks
+	absPath, _ = filepath.EvalSymlinks(absPath)
+	repoRoot, _ = filepath.EvalSymlinks(repoRoot)
+
+	// Normalize separators
+	absPath = filepath.ToSlash(absPath)
+	repoRoot = filepath.ToSlash(repoRoot)
+
+	if !hasPrefix(absPath, repoRoot) {
+		return ""
+	}
+
+	rel := absPath[len(repoRoot):]
+	if len(rel) > 0 && rel[0] == '/' {
+		rel = rel[1:]
+	}
+	return rel
+}
+
+func hasPrefix(s, prefix string) bool {
+	if len(s) < len(prefix) {
+		return false
+	}
+	return s[:len(prefix)] == prefix
+}
@@ -0,0 +1,113 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andrew/swhid-go/objects"
+)
+
+func TestTreeFromDirectoryPathNoGitIndex(t *testing.T) {
+	dir, _, _ := newTestRepo(t)
+	// Committed as a plain file, executable on disk
+	if err := os.Chmod(filepath.Join(dir, "hello.txt"), 0755); err != nil {
+		t.Fatalf("Failed to chmod: %v", err)
+	}
+
+	for _, tt := range []struct {
+		noGitIndex bool
+		want       objects.EntryType
+	}{
+		{false, objects.EntryTypeFile},
+		{true, objects.EntryTypeExecutable},
+	} {
+		var warnings []Warning
+		node, err := TreeFromDirectoryPathWithOptions(dir, TreeOptions{
+			NoGitIndex: tt.noGitIndex,
+			Warn:       func(w Warning) { warnings = append(warnings, w) },
+		})
+		if err != nil {
+			t.Fatalf("TreeFromDirectoryPathWithOptions() error = %v", err)
+		}
+		if got := node.Children[0].Type; got != tt.want {
+			t.Errorf("NoGitIndex %v: hello.txt has type %v, want %v", tt.noGitIndex, got, tt.want)
+		}
+		if len(warnings) != 0 {
+			t.Errorf("NoGitIndex %v: warnings = %v, want none", tt.noGitIndex, warnings)
+		}
+	}
+}
+
+func TestTreeFromDirectoryPathIndexBlobs(t *testing.T) {
+	dir, repo, _ := newTestRepo(t)
+	file := filepath.Join(dir, "staged.txt")
+	staged := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.WriteFile(file, []byte("staged\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.Chtimes(file, staged, staged); err != nil {
+		t.Fatalf("Failed to set times: %v", err)
+	}
+	commitAll(t, repo, "Add staged.txt\n")
+
+	// Same size and modification time: the index is trusted, as by git
+	// status, so the edit goes unseen
+	if err := os.WriteFile(file, []byte("edited\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.Chtimes(file, staged, staged); err != nil {
+		t.Fatalf("Failed to set times: %v", err)
+	}
+	stagedID := FromContent([]byte("staged\n"))
+	editedID := FromContent([]byte("edited\n"))
+
+	contentID := func(opts TreeOptions) *Identifier {
+		t.Helper()
+		node, err := TreeFromDirectoryPathWithOptions(dir, opts)
+		if err != nil {
+			t.Fatalf("TreeFromDirectoryPathWithOptions() error = %v", err)
+		}
+		for _, child := range node.Children {
+			if child.Name == "staged.txt" {
+				return child.ID
+			}
+		}
+		t.Fatal("staged.txt missing from the tree")
+		return nil
+	}
+	for _, concurrency := range []int{0, 2} {
+		if got := contentID(TreeOptions{Concurrency: concurrency}); !got.Equal(stagedID) {
+			t.Errorf("Concurrency %d: staged.txt = %v, want %v from the index", concurrency, got, stagedID)
+		}
+	}
+	if got := contentID(TreeOptions{NoGitIndex: true}); !got.Equal(editedID) {
+		t.Errorf("NoGitIndex: staged.txt = %v, want %v", got, editedID)
+	}
+	inspected := TreeOptions{Inspect: func(string, os.FileInfo) io.Writer { return io.Discard }}
+	if got := contentID(inspected); !got.Equal(editedID) {
+		t.Errorf("Inspect: staged.txt = %v, want %v", got, editedID)
+	}
+	files, _, err := filesToHash(context.Background(), dir, TreeOptions{})
+	if err != nil {
+		t.Fatalf("filesToHash() error = %v", err)
+	}
+	for _, f := range files {
+		if f.Path == "staged.txt" {
+			t.Error("filesToHash() lists staged.txt, whose SWHID the index gives")
+		}
+	}
+
+	// Another modification time: the file is read
+	if err := os.Chtimes(file, staged, staged.Add(time.Second)); err != nil {
+		t.Fatalf("Failed to set times: %v", err)
+	}
+	if got := contentID(TreeOptions{}); !got.Equal(editedID) {
+		t.Errorf("after touch: staged.txt = %v, want %v", got, editedID)
+	}
+}
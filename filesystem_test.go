@@ -1,9 +1,15 @@
 package swhid
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 	"testing"
+
+	"github.com/andrew/swhid-go/objects"
 )
 
 func TestFromDirectoryPath(t *testing.T) {
@@ -110,3 +116,424 @@ func TestFromDirectoryPathFile(t *testing.T) {
 		t.Error("FromDirectoryPath() expected error for file path")
 	}
 }
+
+func TestTreeFromDirectoryPathCached(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "hello.txt"), []byte("hello\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	want, err := TreeFromDirectoryPath(tmpDir)
+	if err != nil {
+		t.Fatalf("TreeFromDirectoryPath() error = %v", err)
+	}
+	if want.ModTime.IsZero() || want.Children[0].ModTime.IsZero() {
+		t.Error("TreeFromDirectoryPath() left ModTime unset")
+	}
+
+	// A cache hit is trusted without reading the file
+	stale := FromContent([]byte("stale\n"))
+	var asked []string
+	got, err := TreeFromDirectoryPathWithOptions(tmpDir, TreeOptions{
+		Cached: func(relPath string, info os.FileInfo) *Identifier {
+			asked = append(asked, relPath)
+			return stale
+		},
+	})
+	if err != nil {
+		t.Fatalf("TreeFromDirectoryPathWithOptions() error = %v", err)
+	}
+	if len(asked) != 1 || asked[0] != "hello.txt" {
+		t.Errorf("Cached called with %v, want [hello.txt]", asked)
+	}
+	if !got.Children[0].ID.Equal(stale) || got.Children[0].Size != 6 {
+		t.Errorf("cached child = %v size %d, want %v size 6", got.Children[0].ID, got.Children[0].Size, stale)
+	}
+	if got.ID.Equal(want.ID) {
+		t.Error("root SWHID ignored the cached content SWHID")
+	}
+
+	// A miss falls back to hashing
+	got, err = TreeFromDirectoryPathWithOptions(tmpDir, TreeOptions{
+		Cached: func(string, os.FileInfo) *Identifier { return nil },
+	})
+	if err != nil {
+		t.Fatalf("TreeFromDirectoryPathWithOptions() error = %v", err)
+	}
+	if !got.ID.Equal(want.ID) {
+		t.Errorf("TreeFromDirectoryPathWithOptions() = %v, want %v", got.ID, want.ID)
+	}
+}
+
+func TestTreeFromDirectoryPathFilters(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"app.log":          "log\n",
+		"docs/readme.md":   "# docs\n",
+		"src/main.go":      "package main\n",
+		"src/debug.log":    "debug\n",
+		"src/lib/util.go":  "package lib\n",
+		"testdata/big.bin": "data\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	// expected hashes a directory holding only the named files
+	expected := func(names ...string) *Identifier {
+		dir := t.TempDir()
+		for _, name := range names {
+			p := filepath.Join(dir, filepath.FromSlash(name))
+			os.MkdirAll(filepath.Dir(p), 0755)
+			os.WriteFile(p, []byte(files[name]), 0644)
+		}
+		id, err := FromDirectoryPath(dir)
+		if err != nil {
+			t.Fatalf("FromDirectoryPath() error = %v", err)
+		}
+		return id
+	}
+
+	tests := []struct {
+		name string
+		opts TreeOptions
+		want []string
+	}{
+		{"include dir", TreeOptions{Include: []string{"src"}}, []string{"src/main.go", "src/debug.log", "src/lib/util.go"}},
+		{"include nested glob", TreeOptions{Include: []string{"src/lib/*.go"}}, []string{"src/lib/util.go"}},
+		{"include name glob", TreeOptions{Include: []string{"*.md"}}, []string{"docs/readme.md"}},
+		{"exclude", TreeOptions{Exclude: []string{"*.log", "testdata"}}, []string{"docs/readme.md", "src/main.go", "src/lib/util.go"}},
+		{"include and exclude", TreeOptions{Include: []string{"./src/"}, Exclude: []string{"src/lib"}}, []string{"src/main.go", "src/debug.log"}},
+		{"include nothing", TreeOptions{Include: []string{"missing"}}, nil},
+		{"include double star", TreeOptions{Include: []string{"src/**/*.go"}}, []string{"src/main.go", "src/lib/util.go"}},
+		{"exclude double star", TreeOptions{Exclude: []string{"**/lib", "**/*.log"}}, []string{"docs/readme.md", "src/main.go", "testdata/big.bin"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var read []string
+			opts := tt.opts
+			opts.Cached = func(relPath string, info os.FileInfo) *Identifier {
+				read = append(read, relPath)
+				return nil
+			}
+
+			node, err := TreeFromDirectoryPathWithOptions(tmpDir, opts)
+			if err != nil {
+				t.Fatalf("TreeFromDirectoryPathWithOptions() error = %v", err)
+			}
+			if want := expected(tt.want...); !node.ID.Equal(want) {
+				t.Errorf("TreeFromDirectoryPathWithOptions() = %v, want %v", node.ID, want)
+			}
+			if len(read) != len(tt.want) {
+				t.Errorf("read files %v, want only %v", read, tt.want)
+			}
+		})
+	}
+
+	if _, err := TreeFromDirectoryPathWithOptions(tmpDir, TreeOptions{Exclude: []string{"["}}); err == nil {
+		t.Error("TreeFromDirectoryPathWithOptions() expected error for invalid pattern")
+	}
+}
+
+func TestTreeFromDirectoryPathFollowSymlinks(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmpDir, "real"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "real", "hello.txt"), []byte("hello\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	links := map[string]string{
+		"linked":   "real",
+		"file":     "real/hello.txt",
+		"dangling": "missing",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(tmpDir, name)); err != nil {
+			t.Skipf("symlinks unsupported: %v", err)
+		}
+	}
+
+	node, err := TreeFromDirectoryPathWithOptions(tmpDir, TreeOptions{FollowSymlinks: true})
+	if err != nil {
+		t.Fatalf("TreeFromDirectoryPathWithOptions() error = %v", err)
+	}
+	types := make(map[string]objects.EntryType)
+	for _, child := range node.Children {
+		types[child.Name] = child.Type
+	}
+	want := map[string]objects.EntryType{
+		"dangling": objects.EntryTypeSymlink,
+		"file":     objects.EntryTypeFile,
+		"linked":   objects.EntryTypeDirectory,
+		"real":     objects.EntryTypeDirectory,
+	}
+	for name, typ := range want {
+		if types[name] != typ {
+			t.Errorf("%s type = %v, want %v", name, types[name], typ)
+		}
+	}
+
+	// Without following, links are hashed as links
+	plain, err := TreeFromDirectoryPath(tmpDir)
+	if err != nil {
+		t.Fatalf("TreeFromDirectoryPath() error = %v", err)
+	}
+	if plain.ID.Equal(node.ID) {
+		t.Error("FollowSymlinks did not change the tree")
+	}
+}
+
+func TestTreeFromDirectoryPathSymlinkLoop(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmpDir, "a", "b"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.Symlink("../..", filepath.Join(tmpDir, "a", "b", "up")); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+
+	if _, err := TreeFromDirectoryPath(tmpDir); err != nil {
+		t.Fatalf("TreeFromDirectoryPath() error = %v, want the loop hashed as a link", err)
+	}
+	_, err := TreeFromDirectoryPathWithOptions(tmpDir, TreeOptions{FollowSymlinks: true})
+	if !errors.Is(err, ErrSymlinkLoop) {
+		t.Errorf("TreeFromDirectoryPathWithOptions() error = %v, want ErrSymlinkLoop", err)
+	}
+
+	// Two links to the same directory are not a loop
+	other := t.TempDir()
+	os.MkdirAll(filepath.Join(other, "shared"), 0755)
+	os.Symlink("shared", filepath.Join(other, "one"))
+	os.Symlink("shared", filepath.Join(other, "two"))
+	if _, err := TreeFromDirectoryPathWithOptions(other, TreeOptions{FollowSymlinks: true}); err != nil {
+		t.Errorf("TreeFromDirectoryPathWithOptions() error = %v for repeated links", err)
+	}
+}
+
+func TestTreeFromDirectoryPathDepth(t *testing.T) {
+	tmpDir := t.TempDir()
+	deep := tmpDir
+	for i := 0; i < 5; i++ {
+		deep = filepath.Join(deep, "d")
+	}
+	if err := os.MkdirAll(deep, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(deep, "f"), []byte("deep\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	if _, err := TreeFromDirectoryPathWithOptions(tmpDir, TreeOptions{MaxDepth: 5}); err != nil {
+		t.Errorf("MaxDepth 5 error = %v", err)
+	}
+	_, err := TreeFromDirectoryPathWithOptions(tmpDir, TreeOptions{MaxDepth: 4})
+	if !errors.Is(err, ErrTreeTooDeep) {
+		t.Errorf("MaxDepth 4 error = %v, want ErrTreeTooDeep", err)
+	}
+	if _, err := TreeFromDirectoryPathWithOptions(tmpDir, TreeOptions{MaxDepth: -1}); err != nil {
+		t.Errorf("unlimited depth error = %v", err)
+	}
+}
+
+func TestTreeFromDirectoryPathVCSDirs(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"hello.txt", ".git/config", ".hg/requires", "sub/.svn/entries", "sub/main.c"} {
+		p := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(name+"\n"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	paths := func(opts TreeOptions) []string {
+		t.Helper()
+		node, err := TreeFromDirectoryPathWithOptions(tmpDir, opts)
+		if err != nil {
+			t.Fatalf("TreeFromDirectoryPathWithOptions() error = %v", err)
+		}
+		var got []string
+		node.Walk(func(n *Node) bool {
+			if n.Type != objects.EntryTypeDirectory {
+				got = append(got, n.Path)
+			}
+			return true
+		})
+		sort.Strings(got)
+		return got
+	}
+
+	tests := []struct {
+		name    string
+		vcsDirs []string
+		want    []string
+	}{
+		{"default", nil, []string{".hg/requires", "hello.txt", "sub/.svn/entries", "sub/main.c"}},
+		{"all", AllVCSDirs, []string{"hello.txt", "sub/main.c"}},
+		{"none", []string{}, []string{".git/config", ".hg/requires", "hello.txt", "sub/.svn/entries", "sub/main.c"}},
+	}
+	for _, tt := range tests {
+		if got := paths(TreeOptions{VCSDirs: tt.vcsDirs}); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: paths = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTreeFromDirectoryPathSkipOSJunk(t *testing.T) {
+	clean := t.TempDir()
+	copied := t.TempDir()
+	for _, name := range []string{"hello.txt", "src/main.c"} {
+		for _, dir := range []string{clean, copied} {
+			p := filepath.Join(dir, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+				t.Fatalf("Failed to create dir: %v", err)
+			}
+			if err := os.WriteFile(p, []byte(name+"\n"), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+		}
+	}
+	for _, name := range []string{".DS_Store", "._hello.txt", "src/._main.c", "src/Thumbs.db", "__MACOSX/src/._main.c"} {
+		p := filepath.Join(copied, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(p, []byte("\x00\x05\x16\x07"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	want, err := FromDirectoryPath(clean)
+	if err != nil {
+		t.Fatalf("FromDirectoryPath() error = %v", err)
+	}
+	junky, err := FromDirectoryPath(copied)
+	if err != nil {
+		t.Fatalf("FromDirectoryPath() error = %v", err)
+	}
+	if junky.Equal(want) {
+		t.Fatalf("OS junk did not change the SWHID")
+	}
+	for _, opts := range []TreeOptions{{SkipOSJunk: true}, {SkipOSJunk: true, Concurrency: 2}} {
+		node, err := TreeFromDirectoryPathWithOptions(copied, opts)
+		if err != nil {
+			t.Fatalf("TreeFromDirectoryPathWithOptions() error = %v", err)
+		}
+		if !node.ID.Equal(want) {
+			t.Errorf("SkipOSJunk SWHID = %v, want %v", node.ID, want)
+		}
+	}
+}
+
+func TestTreeFromDirectoryPathModes(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"build.sh", "bin/tool", "scripts/ci/test.sh", "scripts/lib.sh", "README", "docs/README"} {
+		p := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(name+"\n"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	executables := func(opts TreeOptions) []string {
+		t.Helper()
+		node, err := TreeFromDirectoryPathWithOptions(tmpDir, opts)
+		if err != nil {
+			t.Fatalf("TreeFromDirectoryPathWithOptions() error = %v", err)
+		}
+		var got []string
+		node.Walk(func(n *Node) bool {
+			if n.Type == objects.EntryTypeExecutable {
+				got = append(got, n.Path)
+			}
+			return true
+		})
+		sort.Strings(got)
+		return got
+	}
+
+	tests := []struct {
+		name string
+		opts TreeOptions
+		want []string
+	}{
+		{"none", TreeOptions{}, nil},
+		{"path", TreeOptions{Modes: map[string]os.FileMode{"bin/tool": 0755}}, []string{"bin/tool"}},
+		{"name at any depth", TreeOptions{Modes: map[string]os.FileMode{"README": 0755}}, []string{"README", "docs/README"}},
+		{"pattern", TreeOptions{Modes: map[string]os.FileMode{"**/*.sh": 0755}}, []string{"build.sh", "scripts/ci/test.sh", "scripts/lib.sh"}},
+		{"longer pattern wins", TreeOptions{Modes: map[string]os.FileMode{"*.sh": 0755, "scripts/*.sh": 0644}}, []string{"build.sh", "scripts/ci/test.sh"}},
+		{"path wins", TreeOptions{Modes: map[string]os.FileMode{"*.sh": 0755, "./build.sh": 0644}}, []string{"scripts/ci/test.sh", "scripts/lib.sh"}},
+		{"func", TreeOptions{
+			Modes: map[string]os.FileMode{"*": 0755},
+			ModeFunc: func(relPath string, info os.FileInfo) os.FileMode {
+				if strings.HasPrefix(relPath, "bin/") {
+					return 0755
+				}
+				return info.Mode()
+			},
+		}, []string{"bin/tool"}},
+	}
+	for _, tt := range tests {
+		if got := executables(tt.opts); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: executables = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := TreeFromDirectoryPathWithOptions(tmpDir, TreeOptions{Modes: map[string]os.FileMode{"[": 0755}}); err == nil {
+		t.Error("TreeFromDirectoryPathWithOptions() expected error for invalid pattern")
+	}
+}
+
+func TestTreeFromDirectoryPathHardLinks(t *testing.T) {
+	tmpDir := t.TempDir()
+	first := filepath.Join(tmpDir, "a.txt")
+	if err := os.WriteFile(first, []byte("shared\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	for _, name := range []string{"b.txt", "c.txt"} {
+		if err := os.Link(first, filepath.Join(tmpDir, name)); err != nil {
+			t.Skipf("hard links unsupported: %v", err)
+		}
+	}
+
+	node, err := TreeFromDirectoryPath(tmpDir)
+	if err != nil {
+		t.Fatalf("TreeFromDirectoryPath() error = %v", err)
+	}
+	want := FromContent([]byte("shared\n"))
+	for _, child := range node.Children {
+		if !child.ID.Equal(want) || child.Size != 7 {
+			t.Errorf("%s = %v size %d, want %v size 7", child.Name, child.ID, child.Size, want)
+		}
+	}
+	info, err := os.Lstat(first)
+	if err != nil {
+		t.Fatalf("Lstat() error = %v", err)
+	}
+	// Reused results share one Identifier
+	if _, ok := hardLinkID(info); ok && node.Children[0].ID != node.Children[2].ID {
+		t.Error("hard links were hashed separately")
+	}
+
+	separate, err := TreeFromDirectoryPathWithOptions(tmpDir, TreeOptions{NoHardLinkReuse: true})
+	if err != nil {
+		t.Fatalf("TreeFromDirectoryPathWithOptions() error = %v", err)
+	}
+	if !separate.ID.Equal(node.ID) {
+		t.Errorf("NoHardLinkReuse root = %v, want %v", separate.ID, node.ID)
+	}
+	if separate.Children[0].ID == separate.Children[2].ID {
+		t.Error("NoHardLinkReuse reused a hard link's SWHID")
+	}
+}
@@ -0,0 +1,20 @@
+//go:build js || wasip1
+
+package swhid
+
+// FromDirectoryPath computes the SWHID for a directory on the filesystem.
+// It recursively hashes all files and subdirectories. Without Git support
+// on this platform, executable bits come from the filesystem alone.
+func FromDirectoryPath(path string) (*Identifier, error) {
+	node, err := TreeFromDirectoryPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return node.ID, nil
+}
+
+// discoverIndex returns nils: Git repositories are not read on this
+// platform.
+func discoverIndex(path string, readOnly bool) (indexModes, indexBlobs) {
+	return nil, nil
+}
@@ -0,0 +1,99 @@
+package swhid
+
+import (
+	"path"
+	"strings"
+
+	"github.com/andrew/swhid-go/internal/pathmatch"
+)
+
+// pathFilter decides which entries of a directory are hashed, with patterns
+// matched as package pathmatch describes.
+type pathFilter struct {
+	include []string
+	exclude []string
+}
+
+func newPathFilter(include, exclude []string) (*pathFilter, error) {
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil, nil
+	}
+
+	clean := func(patterns []string) ([]string, error) {
+		var out []string
+		for _, p := range patterns {
+			p, err := pathmatch.Clean(p)
+			if err != nil {
+				return nil, err
+			}
+			if p != "" {
+				out = append(out, p)
+			}
+		}
+		return out, nil
+	}
+
+	f := &pathFilter{}
+	var err error
+	if f.include, err = clean(include); err != nil {
+		return nil, err
+	}
+	if f.exclude, err = clean(exclude); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// excluded reports whether the entry at relPath is skipped, along with
+// everything below it.
+func (f *pathFilter) excluded(relPath string) bool {
+	if f == nil {
+		return false
+	}
+	for _, p := range f.exclude {
+		if pathmatch.Match(p, relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// included reports whether the entry at relPath is kept along with
+// everything below it that is not excluded.
+func (f *pathFilter) included(relPath string) bool {
+	if f == nil || len(f.include) == 0 {
+		return true
+	}
+	for _, p := range f.include {
+		if pathmatch.Match(p, relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// mayContain reports whether a directory that is not itself included could
+// hold included entries, so it must be descended into.
+func (f *pathFilter) mayContain(dir string) bool {
+	parts := strings.Split(dir, "/")
+	for _, p := range f.include {
+		if !strings.Contains(p, "/") || strings.Contains(p, "**") {
+			return true
+		}
+		pp := strings.Split(p, "/")
+		if len(pp) <= len(parts) {
+			continue
+		}
+		match := true
+		for i, part := range parts {
+			if ok, _ := path.Match(pp[i], part); !ok {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package forge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/archive"
+)
+
+// DefaultConcurrency is how many repositories Crawl identifies at once
+// unless told otherwise.
+const DefaultConcurrency = 4
+
+// Where a CrawlResult's snapshot comes from.
+const (
+	SourceArchive = "archive" // the archive's latest visit of the origin
+	SourceClone   = "clone"   // a clone of the repository
+)
+
+// CrawlOptions controls Crawl.
+type CrawlOptions struct {
+	// Archive, if set, is asked for the latest snapshot of each origin,
+	// and repositories the archive holds are not cloned.
+	Archive *archive.Client
+
+	// NoClone leaves the repositories without an archived snapshot
+	// unidentified, with an error, instead of cloning them.
+	NoClone bool
+
+	// Clone configures the clones. A Depth of 1 is enough for snapshot
+	// and HEAD SWHIDs and fetches far less than the full history.
+	Clone swhid.CloneOptions
+
+	// SkipForks and SkipArchived leave out forks and repositories the
+	// forge has made read-only.
+	SkipForks    bool
+	SkipArchived bool
+
+	// Concurrency is how many repositories are identified at once;
+	// DefaultConcurrency when 0.
+	Concurrency int
+
+	// Progress, if set, is called with each result as it is done. Calls
+	// are never concurrent.
+	Progress func(CrawlResult)
+}
+
+// CrawlResult identifies one repository of a namespace, or tells why it
+// could not be.
+type CrawlResult struct {
+	Repository
+	Snapshot *swhid.Identifier // qualified with the origin
+	Head     *swhid.Identifier // the revision HEAD resolves to, only known from clones
+	Source   string            // SourceArchive or SourceClone
+	Err      error
+	Duration time.Duration
+}
+
+// Crawl lists the repositories of a namespace and identifies each one by
+// its snapshot: the archive's, if opts.Archive holds the origin, or one
+// computed from a clone. Results are in the order the forge listed the
+// repositories. Only a failure to list the namespace is returned as an
+// error; a repository that fails fails its own result. Once ctx is done
+// no further repository is started and the rest fail with ctx's error.
+func Crawl(ctx context.Context, lister Lister, namespace string, opts CrawlOptions) ([]CrawlResult, error) {
+	listed, err := lister.Repositories(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	var repos []Repository
+	for _, r := range listed {
+		if (opts.SkipForks && r.Fork) || (opts.SkipArchived && r.Archived) {
+			continue
+		}
+		repos = append(repos, r)
+	}
+
+	workers := opts.Concurrency
+	if workers <= 0 {
+		workers = DefaultConcurrency
+	}
+	workers = min(workers, len(repos))
+
+	results := make([]CrawlResult, len(repos))
+	indexes := make(chan int)
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = identify(ctx, repos[i], opts)
+				if opts.Progress != nil {
+					mu.Lock()
+					opts.Progress(results[i])
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	next := 0
+feed:
+	for ; next < len(repos) && ctx.Err() == nil; next++ {
+		select {
+		case indexes <- next:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	for i := next; i < len(repos); i++ {
+		results[i] = CrawlResult{Repository: repos[i], Err: ctx.Err()}
+	}
+	return results, nil
+}
+
+// identify finds the snapshot of one repository for Crawl.
+func identify(ctx context.Context, repo Repository, opts CrawlOptions) (result CrawlResult) {
+	result.Repository = repo
+	start := time.Now()
+	defer func() { result.Duration = time.Since(start) }()
+
+	if opts.Archive != nil {
+		snapshot, err := opts.Archive.LatestSnapshot(ctx, repo.Origin)
+		if err == nil {
+			result.Snapshot, result.Source = snapshot, SourceArchive
+			return result
+		}
+		if !errors.Is(err, archive.ErrNotFound) || opts.NoClone {
+			result.Err = err
+			return result
+		}
+	}
+	if opts.NoClone {
+		result.Err = fmt.Errorf("forge: %s: not cloned and no archive to ask", repo.Name)
+		return result
+	}
+
+	remote, err := swhid.CloneAndSnapshot(ctx, repo.CloneURL, opts.Clone)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Snapshot = remote.Snapshot.WithQualifiers(map[string]string{swhid.QualifierOrigin: repo.Origin})
+	result.Head, result.Source = remote.Head, SourceClone
+	return result
+}
@@ -0,0 +1,101 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/archive"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+type staticLister []Repository
+
+func (l staticLister) Repositories(ctx context.Context, namespace string) ([]Repository, error) {
+	return l, nil
+}
+
+func TestCrawl(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("Failed to init repository: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("hello\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	wt, _ := repo.Worktree()
+	if _, err := wt.Add("README"); err != nil {
+		t.Fatalf("Failed to stage: %v", err)
+	}
+	sig := &object.Signature{Name: "Test", Email: "test@example.com", When: time.Unix(1000000000, 0)}
+	if _, err := wt.Commit("Initial\n", &git.CommitOptions{Author: sig, Committer: sig}); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	local, err := swhid.FromSnapshot(dir)
+	if err != nil {
+		t.Fatalf("FromSnapshot() error = %v", err)
+	}
+
+	archived := strings.Repeat("a", 40)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/1/origin/https://forge.example/org/archived/visit/latest/" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"origin": "https://forge.example/org/archived", "visit": 3, "date": "2024-01-02T03:04:05+00:00",
+			"type": "git", "status": "full", "snapshot": archived,
+		})
+	}))
+	defer server.Close()
+
+	lister := staticLister{
+		{Name: "org/archived", Origin: "https://forge.example/org/archived", CloneURL: "/nonexistent"},
+		{Name: "org/new", Origin: "https://forge.example/org/new", CloneURL: dir},
+		{Name: "org/fork", Origin: "https://forge.example/org/fork", CloneURL: dir, Fork: true},
+	}
+	progress := 0
+	results, err := Crawl(context.Background(), lister, "org", CrawlOptions{
+		Archive:   &archive.Client{BaseURL: server.URL},
+		Clone:     swhid.CloneOptions{Depth: 1, InMemory: true},
+		SkipForks: true,
+		Progress:  func(CrawlResult) { progress++ },
+	})
+	if err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+	if len(results) != 2 || progress != 2 {
+		t.Fatalf("Crawl() = %d results with %d progress calls, want 2", len(results), progress)
+	}
+
+	if r := results[0]; r.Err != nil || r.Source != SourceArchive || r.Snapshot.ObjectHash != archived {
+		t.Errorf("results[0] = %+v, want the archived snapshot", r)
+	}
+	r := results[1]
+	if r.Err != nil || r.Source != SourceClone || r.Snapshot.ObjectHash != local.ObjectHash || r.Head == nil {
+		t.Errorf("results[1] = %+v, want snapshot %s from a clone", r, local)
+	}
+	if origin := r.Snapshot.Qualifiers[swhid.QualifierOrigin]; origin != "https://forge.example/org/new" {
+		t.Errorf("results[1] origin = %q", origin)
+	}
+
+	results, err = Crawl(context.Background(), lister, "org", CrawlOptions{
+		Archive: &archive.Client{BaseURL: server.URL},
+		NoClone: true,
+	})
+	if err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+	if len(results) != 3 || results[0].Err != nil || results[1].Err == nil || results[1].Snapshot != nil {
+		t.Errorf("Crawl(NoClone) = %+v", results)
+	}
+}
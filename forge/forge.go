@@ -0,0 +1,99 @@
+// Package forge enumerates the repositories of a namespace on a forge, a
+// GitHub organization or user or a GitLab group with its subgroups, and
+// identifies each one by its snapshot SWHID, taken from the archive's
+// latest visit or computed from a clone:
+//
+//	lister := &forge.GitHub{Token: os.Getenv("GITHUB_TOKEN")}
+//	results, err := forge.Crawl(ctx, lister, "example", forge.CrawlOptions{
+//		Clone: swhid.CloneOptions{Depth: 1},
+//	})
+//
+// Forge APIs and the archive limit how many requests a client may make.
+// RateLimit is an http.RoundTripper that spaces requests out and waits
+// for the limit to reset when a server reports it is exhausted; the
+// listers and archive.Client accept it through their HTTPClient.
+package forge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrNotFound is returned by listers for a namespace the forge does not
+// have, or does not show to the token used.
+var ErrNotFound = errors.New("namespace not found")
+
+// Repository is one repository of a forge namespace.
+type Repository struct {
+	Name     string // full name on the forge, such as "example/repo" or "group/sub/repo"
+	Origin   string // the repository's web URL, normalized as the archive lists origins
+	CloneURL string // HTTPS URL to clone from
+	Fork     bool
+	Archived bool // read-only on the forge
+}
+
+// Lister enumerates the repositories of a forge namespace.
+type Lister interface {
+	Repositories(ctx context.Context, namespace string) ([]Repository, error)
+}
+
+// getPages fetches a paginated listing, following the Link headers both
+// GitHub and GitLab send, and hands each page's body to page. A 404 on
+// the first page is ErrNotFound.
+func getPages(ctx context.Context, client *http.Client, u string, header http.Header, page func([]byte) error) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	for u != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to fetch %s: %w", u, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to fetch %s: %w", u, err)
+		}
+		switch {
+		case resp.StatusCode == http.StatusNotFound:
+			return fmt.Errorf("forge: %s: %w", u, ErrNotFound)
+		case resp.StatusCode != http.StatusOK:
+			return fmt.Errorf("failed to fetch %s: %s", u, resp.Status)
+		}
+
+		if err := page(body); err != nil {
+			return fmt.Errorf("forge: invalid response for %s: %w", u, err)
+		}
+		u = nextLink(resp.Header.Get("Link"))
+	}
+	return nil
+}
+
+// nextLink returns the rel="next" target of an RFC 8288 Link header, or
+// "" on the last page.
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		target, params, ok := strings.Cut(link, ";")
+		if !ok {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(target), "<>")
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,114 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGitHubRepositories(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		switch r.URL.Path {
+		case "/orgs/example/repos":
+			if r.URL.Query().Get("page") == "" {
+				w.Header().Set("Link", `<`+server.URL+`/orgs/example/repos?page=2>; rel="next", <`+server.URL+`/orgs/example/repos?page=2>; rel="last"`)
+				json.NewEncoder(w).Encode([]map[string]interface{}{
+					{"full_name": "example/one", "html_url": "https://github.com/example/one", "clone_url": "https://github.com/example/one.git"},
+				})
+				return
+			}
+			json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"full_name": "example/two", "html_url": "https://github.com/example/two", "clone_url": "https://github.com/example/two.git", "fork": true, "archived": true},
+			})
+		case "/users/someone/repos":
+			json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"full_name": "someone/dotfiles", "html_url": "https://github.com/someone/dotfiles"},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	g := &GitHub{BaseURL: server.URL, Token: "secret"}
+	repos, err := g.Repositories(context.Background(), "example")
+	if err != nil {
+		t.Fatalf("Repositories() error = %v", err)
+	}
+	if len(repos) != 2 {
+		t.Fatalf("Repositories() = %+v, want 2 repositories", repos)
+	}
+	if r := repos[0]; r.Name != "example/one" || r.Origin != "https://github.com/example/one" || r.CloneURL != "https://github.com/example/one.git" || r.Fork {
+		t.Errorf("repos[0] = %+v", r)
+	}
+	if r := repos[1]; r.Name != "example/two" || !r.Fork || !r.Archived {
+		t.Errorf("repos[1] = %+v", r)
+	}
+
+	repos, err = g.Repositories(context.Background(), "someone")
+	if err != nil {
+		t.Fatalf("Repositories() of a user error = %v", err)
+	}
+	if len(repos) != 1 || repos[0].Name != "someone/dotfiles" {
+		t.Errorf("Repositories() of a user = %+v", repos)
+	}
+
+	if _, err := g.Repositories(context.Background(), "nobody"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Repositories() of an unknown namespace error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestGitLabRepositories(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("PRIVATE-TOKEN"); got != "secret" {
+			t.Errorf("PRIVATE-TOKEN = %q", got)
+		}
+		if r.URL.EscapedPath() != "/api/v4/groups/group%2Fsub/projects" || r.URL.Query().Get("include_subgroups") != "true" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`[
+			{"path_with_namespace": "group/sub/app", "web_url": "https://gitlab.com/group/sub/app",
+			 "http_url_to_repo": "https://gitlab.com/group/sub/app.git", "archived": false},
+			{"path_with_namespace": "group/sub/deeper/fork", "web_url": "https://gitlab.com/group/sub/deeper/fork",
+			 "http_url_to_repo": "https://gitlab.com/group/sub/deeper/fork.git", "forked_from_project": {"id": 1}, "archived": true}
+		]`))
+	}))
+	defer server.Close()
+
+	g := &GitLab{BaseURL: server.URL, Token: "secret"}
+	repos, err := g.Repositories(context.Background(), "group/sub")
+	if err != nil {
+		t.Fatalf("Repositories() error = %v", err)
+	}
+	if len(repos) != 2 {
+		t.Fatalf("Repositories() = %+v, want 2 repositories", repos)
+	}
+	if r := repos[0]; r.Name != "group/sub/app" || r.Origin != "https://gitlab.com/group/sub/app" || r.Fork || r.Archived {
+		t.Errorf("repos[0] = %+v", r)
+	}
+	if r := repos[1]; !r.Fork || !r.Archived {
+		t.Errorf("repos[1] = %+v", r)
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	tests := []struct{ header, want string }{
+		{"", ""},
+		{`<https://api.example/x?page=3>; rel="next", <https://api.example/x?page=9>; rel="last"`, "https://api.example/x?page=3"},
+		{`<https://api.example/x?page=1>; rel="prev"`, ""},
+		{`<https://api.example/x?page=2>; type="json"; rel="next"`, "https://api.example/x?page=2"},
+	}
+	for _, tt := range tests {
+		if got := nextLink(tt.header); got != tt.want {
+			t.Errorf("nextLink(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/andrew/swhid-go"
+)
+
+// DefaultGitHubURL is the API of github.com.
+const DefaultGitHubURL = "https://api.github.com"
+
+// GitHub lists the repositories of a GitHub organization or user.
+type GitHub struct {
+	BaseURL    string       // defaults to DefaultGitHubURL; GitHub Enterprise serves it under /api/v3
+	Token      string       // sent as a bearer token, if set; without one only public repositories are listed
+	HTTPClient *http.Client // defaults to http.DefaultClient
+}
+
+type githubRepo struct {
+	FullName string `json:"full_name"`
+	HTMLURL  string `json:"html_url"`
+	CloneURL string `json:"clone_url"`
+	Fork     bool   `json:"fork"`
+	Archived bool   `json:"archived"`
+}
+
+// Repositories lists every repository of the organization named
+// namespace, or of the user if there is no such organization.
+func (g *GitHub) Repositories(ctx context.Context, namespace string) ([]Repository, error) {
+	base := DefaultGitHubURL
+	if g.BaseURL != "" {
+		base = strings.TrimSuffix(g.BaseURL, "/")
+	}
+	header := http.Header{"Accept": {"application/vnd.github+json"}}
+	if g.Token != "" {
+		header.Set("Authorization", "Bearer "+g.Token)
+	}
+
+	var repos []Repository
+	page := func(body []byte) error {
+		var list []githubRepo
+		if err := json.Unmarshal(body, &list); err != nil {
+			return err
+		}
+		for _, r := range list {
+			repos = append(repos, Repository{
+				Name:     r.FullName,
+				Origin:   swhid.NormalizeOriginURL(r.HTMLURL),
+				CloneURL: r.CloneURL,
+				Fork:     r.Fork,
+				Archived: r.Archived,
+			})
+		}
+		return nil
+	}
+
+	name := url.PathEscape(namespace)
+	err := getPages(ctx, g.HTTPClient, base+"/orgs/"+name+"/repos?type=all&per_page=100", header, page)
+	if errors.Is(err, ErrNotFound) {
+		err = getPages(ctx, g.HTTPClient, base+"/users/"+name+"/repos?type=owner&per_page=100", header, page)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
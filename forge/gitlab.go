@@ -0,0 +1,74 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/andrew/swhid-go"
+)
+
+// DefaultGitLabURL is gitlab.com.
+const DefaultGitLabURL = "https://gitlab.com"
+
+// GitLab lists the projects of a GitLab group, subgroups included, or of
+// a user.
+type GitLab struct {
+	BaseURL    string       // the instance, defaults to DefaultGitLabURL
+	Token      string       // a personal, group or project access token, if set
+	HTTPClient *http.Client // defaults to http.DefaultClient
+}
+
+type gitlabProject struct {
+	PathWithNamespace string          `json:"path_with_namespace"`
+	WebURL            string          `json:"web_url"`
+	HTTPURLToRepo     string          `json:"http_url_to_repo"`
+	ForkedFromProject json.RawMessage `json:"forked_from_project"`
+	Archived          bool            `json:"archived"`
+}
+
+// Repositories lists every project of the group whose full path is
+// namespace, such as "group/subgroup", and of its subgroups; or of the
+// user of that name if there is no such group.
+func (g *GitLab) Repositories(ctx context.Context, namespace string) ([]Repository, error) {
+	base := DefaultGitLabURL
+	if g.BaseURL != "" {
+		base = strings.TrimSuffix(g.BaseURL, "/")
+	}
+	header := http.Header{}
+	if g.Token != "" {
+		header.Set("PRIVATE-TOKEN", g.Token)
+	}
+
+	var repos []Repository
+	page := func(body []byte) error {
+		var list []gitlabProject
+		if err := json.Unmarshal(body, &list); err != nil {
+			return err
+		}
+		for _, p := range list {
+			repos = append(repos, Repository{
+				Name:     p.PathWithNamespace,
+				Origin:   swhid.NormalizeOriginURL(p.WebURL),
+				CloneURL: p.HTTPURLToRepo,
+				Fork:     len(p.ForkedFromProject) > 0 && string(p.ForkedFromProject) != "null",
+				Archived: p.Archived,
+			})
+		}
+		return nil
+	}
+
+	// Groups are addressed by their URL-encoded full path.
+	name := url.PathEscape(namespace)
+	err := getPages(ctx, g.HTTPClient, base+"/api/v4/groups/"+name+"/projects?include_subgroups=true&per_page=100", header, page)
+	if errors.Is(err, ErrNotFound) {
+		err = getPages(ctx, g.HTTPClient, base+"/api/v4/users/"+name+"/projects?per_page=100", header, page)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
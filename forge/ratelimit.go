@@ -0,0 +1,170 @@
+package forge
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Defaults for RateLimit.
+const (
+	DefaultMaxRetries = 3
+	DefaultMaxWait    = 15 * time.Minute
+)
+
+// RateLimit is an http.RoundTripper that keeps a client within a server's
+// rate limit. It spaces requests at least Interval apart and, when a
+// response says the limit is used up, holds back further requests until
+// the time the server gives for it to reset. A request refused for going
+// over the limit, with status 429 or a 403 carrying an exhausted
+// X-RateLimit-Remaining, is retried once that time has come.
+//
+// It understands Retry-After and the reset headers of GitHub
+// (X-RateLimit-Reset), GitLab (RateLimit-Reset) and the Software Heritage
+// API (X-RateLimit-Reset). Share one RateLimit between the clients of a
+// server so that they are paced together; it is safe for concurrent use.
+type RateLimit struct {
+	// Interval is the least time between the start of two requests.
+	Interval time.Duration
+
+	// MaxRetries is how many times a refused request is retried;
+	// DefaultMaxRetries when 0, none when negative.
+	MaxRetries int
+
+	// MaxWait is the longest RateLimit waits for a limit to reset;
+	// DefaultMaxWait when 0. A longer wait fails the request instead.
+	MaxWait time.Duration
+
+	// Transport sends the requests; http.DefaultTransport when nil.
+	Transport http.RoundTripper
+
+	mu   sync.Mutex
+	next time.Time // no request starts before then
+}
+
+// RoundTrip implements http.RoundTripper. Requests with a body other than
+// one GetBody can replay are not retried.
+func (r *RateLimit) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	retries := r.MaxRetries
+	if retries == 0 {
+		retries = DefaultMaxRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := r.wait(req); err != nil {
+			return nil, err
+		}
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+
+		reset, limited := r.observe(resp)
+		if !limited || attempt >= retries || (req.Body != nil && req.GetBody == nil) {
+			return resp, nil
+		}
+		if wait := time.Until(reset); wait > r.maxWait() {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+func (r *RateLimit) maxWait() time.Duration {
+	if r.MaxWait == 0 {
+		return DefaultMaxWait
+	}
+	return r.MaxWait
+}
+
+// wait blocks until the next request may start, or req's context ends.
+func (r *RateLimit) wait(req *http.Request) error {
+	r.mu.Lock()
+	now := time.Now()
+	start := r.next
+	if start.Before(now) {
+		start = now
+	}
+	r.next = start.Add(r.Interval)
+	r.mu.Unlock()
+
+	delay := time.Until(start)
+	if delay <= 0 {
+		return nil
+	}
+	if delay > r.maxWait() {
+		return fmt.Errorf("forge: rate limit of %s resets in %s", req.URL.Host, delay.Round(time.Second))
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+// observe reads the rate limit state a response reports. It returns when
+// the limit resets and whether the request was refused for exceeding it,
+// and holds back later requests until then if the limit is used up.
+func (r *RateLimit) observe(resp *http.Response) (time.Time, bool) {
+	exhausted := remaining(resp.Header) == "0"
+	refused := resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode == http.StatusForbidden && exhausted)
+	if !refused && !exhausted {
+		return time.Time{}, false
+	}
+
+	reset, ok := resetTime(resp.Header, time.Now())
+	if !ok {
+		reset = time.Now().Add(time.Minute)
+	}
+	r.mu.Lock()
+	if reset.After(r.next) {
+		r.next = reset
+	}
+	r.mu.Unlock()
+	return reset, refused
+}
+
+func remaining(h http.Header) string {
+	if v := h.Get("X-RateLimit-Remaining"); v != "" {
+		return v
+	}
+	return h.Get("RateLimit-Remaining")
+}
+
+// resetTime returns when a rate limit resets, from Retry-After (seconds or
+// an HTTP date) or a reset header holding a Unix time.
+func resetTime(h http.Header, now time.Time) (time.Time, bool) {
+	if v := h.Get("Retry-After"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil {
+			return now.Add(time.Duration(seconds) * time.Second), true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return t, true
+		}
+	}
+	for _, name := range []string{"X-RateLimit-Reset", "RateLimit-Reset"} {
+		if unix, err := strconv.ParseInt(h.Get(name), 10, 64); err == nil {
+			return time.Unix(unix, 0), true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,104 @@
+package forge
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimitRetries(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch requests {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Unix()-1, 10))
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &RateLimit{}}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || requests != 3 {
+		t.Errorf("status %d after %d requests, want 200 after 3", resp.StatusCode, requests)
+	}
+}
+
+func TestRateLimitGivesUp(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	limit := &RateLimit{MaxWait: time.Minute}
+	client := &http.Client{Transport: limit}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || requests != 1 {
+		t.Errorf("status %d after %d requests, want 429 after 1", resp.StatusCode, requests)
+	}
+
+	// The limit is known to be exhausted for an hour: later requests fail
+	// without reaching the server.
+	if _, err := client.Get(server.URL); err == nil || requests != 1 {
+		t.Errorf("Get() during the reset error = %v after %d requests", err, requests)
+	}
+}
+
+func TestRateLimitInterval(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &RateLimit{Interval: 20 * time.Millisecond}}
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		resp.Body.Close()
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("4 requests took %s, want at least 60ms", elapsed)
+	}
+}
+
+func TestResetTime(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		header http.Header
+		want   time.Time
+		ok     bool
+	}{
+		{http.Header{"Retry-After": {"30"}}, now.Add(30 * time.Second), true},
+		{http.Header{"Retry-After": {"Wed, 15 Nov 2023 00:00:00 GMT"}}, time.Date(2023, 11, 15, 0, 0, 0, 0, time.UTC), true},
+		{http.Header{"X-Ratelimit-Reset": {"1700000100"}}, time.Unix(1700000100, 0), true},
+		{http.Header{"Ratelimit-Reset": {"1700000200"}}, time.Unix(1700000200, 0), true},
+		{http.Header{}, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := resetTime(tt.header, now)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("resetTime(%v) = %v, %v, want %v, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
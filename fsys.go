@@ -0,0 +1,86 @@
+package swhid
+
+import (
+	"io/fs"
+	"path"
+
+	"github.com/andrew/swhid-go/objects"
+)
+
+// FromFS computes the SWHID for the root directory of fsys, such as an
+// embed.FS, a zip.Reader or an fs.Sub of either. Executable bits come from
+// the file modes fsys reports, and symlinks are hashed by their target when
+// fsys implements fs.ReadLinkFS. As with FromDirectoryPath, .git
+// directories are skipped.
+func FromFS(fsys fs.FS) (*Identifier, error) {
+	node, err := TreeFromFS(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return node.ID, nil
+}
+
+// TreeFromFS hashes fsys like FromFS and returns the whole Merkle tree
+// rather than just the root SWHID.
+func TreeFromFS(fsys fs.FS) (*Node, error) {
+	return buildFSNode(fsys, ".", "")
+}
+
+func buildFSNode(fsys fs.FS, dir, relPath string) (*Node, error) {
+	dirEntries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var children []*Node
+	for _, de := range dirEntries {
+		name := de.Name()
+		if name == ".git" {
+			continue
+		}
+
+		fullPath := path.Join(dir, name)
+		info, err := de.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		var child *Node
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			target, err := fs.ReadLink(fsys, fullPath)
+			if err != nil {
+				return nil, err
+			}
+			child = newContentNode(relPath, name, objects.EntryTypeSymlink, []byte(target))
+		case info.IsDir():
+			child, err = buildFSNode(fsys, fullPath, path.Join(relPath, name))
+			if err != nil {
+				return nil, err
+			}
+		default:
+			entryType := objects.EntryTypeFile
+			if info.Mode()&0111 != 0 {
+				entryType = objects.EntryTypeExecutable
+			}
+			file, err := fsys.Open(fullPath)
+			if err != nil {
+				return nil, err
+			}
+			child, err = newStreamedNode(relPath, name, entryType, file, info.Size())
+			file.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		child.ModTime = info.ModTime()
+		children = append(children, child)
+	}
+
+	name := ""
+	if relPath != "" {
+		name = path.Base(relPath)
+	}
+	return newDirectoryNode(relPath, name, children), nil
+}
@@ -0,0 +1,57 @@
+package swhid
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"hello.txt": {Data: []byte("hello\n"), Mode: 0644},
+	}
+
+	id, err := FromFS(fsys)
+	if err != nil {
+		t.Fatalf("FromFS() error = %v", err)
+	}
+
+	// Same tree as TestFromDirectoryPath
+	wantHash := "aaa96ced2d9a1c8e72c56b253a0e2fe78393feb7"
+	if id.ObjectHash != wantHash {
+		t.Errorf("FromFS() hash = %v, want %v", id.ObjectHash, wantHash)
+	}
+}
+
+func TestFromFSMatchesDirectoryPath(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "swhid-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := os.MkdirAll(filepath.Join(tmpDir, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create subdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "sub", "run.sh"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.Symlink("sub/run.sh", filepath.Join(tmpDir, "link")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	want, err := FromDirectoryPath(tmpDir)
+	if err != nil {
+		t.Fatalf("FromDirectoryPath() error = %v", err)
+	}
+
+	got, err := FromFS(os.DirFS(tmpDir))
+	if err != nil {
+		t.Fatalf("FromFS() error = %v", err)
+	}
+
+	if got.ObjectHash != want.ObjectHash {
+		t.Errorf("FromFS() hash = %v, want %v", got.ObjectHash, want.ObjectHash)
+	}
+}
@@ -1,11 +1,11 @@
+//go:build !js && !wasip1
+
 package swhid
 
 import (
 	"bufio"
 	"bytes"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/andrew/swhid-go/objects"
@@ -14,27 +14,190 @@ import (
 	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
-// FromRevision computes the SWHID for a Git revision (commit).
+// GitOptions controls how a repository is read when computing revision,
+// release and snapshot SWHIDs. The zero value reproduces what the Software
+// Heritage git loader archives: the true object graph, ignoring objects
+// substituted through refs/replace/ and parents listed in .git/info/grafts,
+// and the references selected by RefsLoader.
+type GitOptions struct {
+	// ReplaceRefs substitutes objects that have a refs/replace/<hash>
+	// reference with their replacement, as git does unless
+	// GIT_NO_REPLACE_OBJECTS is set. Snapshots always list refs/replace/*
+	// as ordinary branches pointing at the replacement objects.
+	ReplaceRefs bool
+
+	// Grafts overrides commit parents with those listed in the
+	// repository's info/grafts file.
+	Grafts bool
+
+	// Refs selects which references a snapshot lists.
+	Refs RefPolicy
+
+	// Refspecs, for repositories that fetched only some of the origin's
+	// references, maybe under other names, as CI systems do, are the
+	// refspecs that were fetched with: a snapshot lists only the local
+	// references they wrote, under the names they have in the origin, so
+	// "+refs/heads/*:refs/remotes/origin/*" lists refs/remotes/origin/main
+	// as refs/heads/main. A refspec without a colon keeps the name.
+	Refspecs []string
+
+	// Branches are branches known from elsewhere, such as the archive's
+	// latest snapshot of the origin or a forge's API, for the references
+	// the repository did not fetch. They are named as in the origin, and a
+	// reference the repository has replaces the branch of the same name.
+	Branches []objects.Branch
+
+	// Head, if set, replaces the repository's HEAD in a snapshot: a
+	// reference name such as "refs/heads/main" makes HEAD an alias to it,
+	// a 40-hex object name makes it point at that object. Use it when the
+	// repository has no meaningful HEAD, as with bare mirrors and bundles.
+	Head string
+
+	// ReadOnlyFS opens the repository through filesystems that refuse
+	// writes, so that nothing, not even a lock file, is created in it; an
+	// attempted write fails with a *WriteError matching ErrReadOnlyFS.
+	// Computing SWHIDs never needs to write, so this only makes that
+	// guarantee enforced, as required for read-only mounts.
+	ReadOnlyFS bool
+}
+
+// RefPolicy selects the references included in a snapshot.
+type RefPolicy int
+
+const (
+	// RefsLoader lists what the SWH git loader archives: every reference,
+	// including refs/notes/*, refs/replace/* and refs/remotes/*, except the
+	// auto-merge refs GitHub publishes as refs/pull/<n>/merge.
+	RefsLoader RefPolicy = iota
+
+	// RefsAll lists every reference in the repository.
+	RefsAll
+
+	// RefsBranchesAndTags lists only HEAD, refs/heads/* and refs/tags/*.
+	RefsBranchesAndTags
+)
+
+func (p RefPolicy) String() string {
+	switch p {
+	case RefsLoader:
+		return "loader"
+	case RefsAll:
+		return "all"
+	case RefsBranchesAndTags:
+		return "heads"
+	default:
+		return fmt.Sprintf("RefPolicy(%d)", int(p))
+	}
+}
+
+// ParseRefPolicy returns the policy named by s ("loader", "all" or "heads").
+func ParseRefPolicy(s string) (RefPolicy, error) {
+	for _, p := range []RefPolicy{RefsLoader, RefsAll, RefsBranchesAndTags} {
+		if p.String() == s {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown ref policy %q (expected loader, all or heads)", s)
+}
+
+// includes reports whether the reference named name belongs in a snapshot.
+func (p RefPolicy) includes(name string) bool {
+	switch p {
+	case RefsAll:
+		return true
+	case RefsBranchesAndTags:
+		return name == "HEAD" || strings.HasPrefix(name, "refs/heads/") || strings.HasPrefix(name, "refs/tags/")
+	default:
+		return !(strings.HasPrefix(name, "refs/pull/") && strings.HasSuffix(name, "/merge"))
+	}
+}
+
+// FromRevision computes the SWHID for a Git revision (commit). ref is any
+// revision expression git rev-parse accepts, such as "main", an abbreviated
+// hash, "HEAD~3^2", "stash@{0}" or ":/fix typo"; see FromGitTree. An empty
+// ref means HEAD.
 func FromRevision(repoPath, ref string) (*Identifier, error) {
-	repo, err := git.PlainOpen(repoPath)
+	repo, commit, err := resolveCommit(repoPath, ref, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return FromRevisionMetadata(revisionMetadata(repo, commit)), nil
+}
+
+// FromRevisionRepo is like FromRevision for an already open repository,
+// which may use any storage, including memory.
+func FromRevisionRepo(repo *git.Repository, ref string) (*Identifier, error) {
+	commit, err := resolveCommitIn(repo, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return FromRevisionMetadata(revisionMetadata(repo, commit)), nil
+}
+
+// FromGitTree computes the directory SWHID of a tree in a Git repository,
+// named by any tree-ish expression git rev-parse accepts: a commit or tag,
+// which is peeled to its tree, "HEAD~3^{tree}", "stash@{0}^3" for the
+// untracked files of a stash, or "v1.0:src" for a subdirectory. Only the
+// tree's object name is read, so nothing is rehashed. Expressions that
+// cannot be resolved are a *RevisionError.
+func FromGitTree(repoPath, treeish string) (*Identifier, error) {
+	repo, err := openRepository(repoPath, false, false)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open repository: %w", err)
 	}
 
+	return FromGitTreeRepo(repo, treeish)
+}
+
+// FromGitTreeRepo is like FromGitTree for an already open repository.
+func FromGitTreeRepo(repo *git.Repository, treeish string) (*Identifier, error) {
+	if treeish == "" {
+		treeish = "HEAD"
+	}
+
+	hash, err := resolveRevisionTo(repo, treeish, plumbing.TreeObject)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewIdentifier(ObjectTypeDirectory, hash.String(), nil)
+}
+
+func resolveCommit(repoPath, ref string, readOnly bool) (*git.Repository, *object.Commit, error) {
+	repo, err := openRepository(repoPath, false, readOnly)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	commit, err := resolveCommitIn(repo, ref)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return repo, commit, nil
+}
+
+func resolveCommitIn(repo *git.Repository, ref string) (*object.Commit, error) {
 	if ref == "" {
 		ref = "HEAD"
 	}
 
-	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	hash, err := resolveRevisionTo(repo, ref, plumbing.CommitObject)
 	if err != nil {
-		return nil, fmt.Errorf("failed to resolve reference %s: %w", ref, err)
+		return nil, err
 	}
 
-	commit, err := repo.CommitObject(*hash)
+	commit, err := repo.CommitObject(hash)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get commit: %w", err)
 	}
 
+	return commit, nil
+}
+
+func revisionMetadata(repo *git.Repository, commit *object.Commit) objects.RevisionMetadata {
 	meta := objects.RevisionMetadata{
 		Directory:          commit.TreeHash.String(),
 		Author:             formatPerson(commit.Author),
@@ -57,16 +220,30 @@ func FromRevision(repoPath, ref string) (*Identifier, error) {
 		meta.ExtraHeaders = extraHeaders
 	}
 
-	return FromRevisionMetadata(meta), nil
+	return meta
 }
 
 // FromRelease computes the SWHID for a Git release (annotated tag).
 func FromRelease(repoPath, tagName string) (*Identifier, error) {
-	repo, err := git.PlainOpen(repoPath)
+	repo, err := openRepository(repoPath, false, false)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open repository: %w", err)
 	}
 
+	return FromReleaseRepo(repo, tagName)
+}
+
+// FromReleaseRepo is like FromRelease for an already open repository.
+func FromReleaseRepo(repo *git.Repository, tagName string) (*Identifier, error) {
+	tagObj, err := resolveTag(repo, tagName)
+	if err != nil {
+		return nil, err
+	}
+
+	return FromReleaseMetadata(releaseMetadata(repo, tagObj)), nil
+}
+
+func resolveTag(repo *git.Repository, tagName string) (*object.Tag, error) {
 	refName := plumbing.NewTagReferenceName(tagName)
 	ref, err := repo.Reference(refName, true)
 	if err != nil {
@@ -80,6 +257,10 @@ func FromRelease(repoPath, tagName string) (*Identifier, error) {
 		return nil, fmt.Errorf("lightweight tags are not supported for release SWHIDs")
 	}
 
+	return tagObj, nil
+}
+
+func releaseMetadata(repo *git.Repository, tagObj *object.Tag) objects.ReleaseMetadata {
 	// Determine target type
 	targetType := objects.TargetTypeRevision
 	if _, err := repo.CommitObject(tagObj.Target); err == nil {
@@ -98,7 +279,9 @@ func FromRelease(repoPath, tagName string) (*Identifier, error) {
 			Hash: tagObj.Target.String(),
 			Type: targetType,
 		},
-		Message: tagObj.Message,
+		// go-git splits off the signature git tag -s appends to the
+		// message; the tag hash covers both.
+		Message: tagObj.Message + tagObj.PGPSignature,
 	}
 
 	if !tagObj.Tagger.When.IsZero() {
@@ -113,33 +296,77 @@ func FromRelease(repoPath, tagName string) (*Identifier, error) {
 		meta.ExtraHeaders = extraHeaders
 	}
 
-	return FromReleaseMetadata(meta), nil
+	return meta
 }
 
 // FromSnapshot computes the SWHID for a Git repository snapshot.
 func FromSnapshot(repoPath string) (*Identifier, error) {
-	repo, err := git.PlainOpen(repoPath)
+	return FromSnapshotWithOptions(repoPath, GitOptions{})
+}
+
+// FromSnapshotWithOptions computes the snapshot SWHID of the references
+// selected by opts.Refs.
+func FromSnapshotWithOptions(repoPath string, opts GitOptions) (*Identifier, error) {
+	branches, err := SnapshotBranchesWithOptions(repoPath, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return FromSnapshotBranches(branches), nil
+}
+
+// FromSnapshotRepo is like FromSnapshot for an already open repository.
+func FromSnapshotRepo(repo *git.Repository) (*Identifier, error) {
+	branches, err := SnapshotBranchesRepo(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	return FromSnapshotBranches(branches), nil
+}
+
+// SnapshotBranches returns the branches FromSnapshot hashes for the
+// repository at repoPath: HEAD as an alias plus every reference the SWH
+// git loader would archive.
+func SnapshotBranches(repoPath string) ([]objects.Branch, error) {
+	return SnapshotBranchesWithOptions(repoPath, GitOptions{})
+}
+
+// SnapshotBranchesWithOptions returns the branches FromSnapshotWithOptions
+// hashes.
+func SnapshotBranchesWithOptions(repoPath string, opts GitOptions) ([]objects.Branch, error) {
+	repo, err := openRepository(repoPath, false, opts.ReadOnlyFS)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open repository: %w", err)
 	}
 
+	return snapshotBranchesIn(repo, opts)
+}
+
+// SnapshotBranchesRepo is like SnapshotBranches for an already open repository.
+func SnapshotBranchesRepo(repo *git.Repository) ([]objects.Branch, error) {
+	return snapshotBranchesIn(repo, GitOptions{})
+}
+
+func snapshotBranchesIn(repo *git.Repository, opts GitOptions) ([]objects.Branch, error) {
+	return snapshotBranches(repo, opts, func(hash plumbing.Hash) (objects.BranchTargetType, string) {
+		return resolveRefTarget(repo, hash)
+	})
+}
+
+func snapshotBranches(repo *git.Repository, opts GitOptions, resolve func(plumbing.Hash) (objects.BranchTargetType, string)) ([]objects.Branch, error) {
+	refMap, err := newRefMapper(opts)
+	if err != nil {
+		return nil, err
+	}
 	var branches []objects.Branch
 
-	// Check for HEAD first
-	gitDir := filepath.Join(repoPath, ".git")
-	if info, err := os.Stat(gitDir); err == nil && info.IsDir() {
-		headPath := filepath.Join(gitDir, "HEAD")
-		if content, err := os.ReadFile(headPath); err == nil {
-			headContent := strings.TrimSpace(string(content))
-			if strings.HasPrefix(headContent, "ref:") {
-				targetRef := strings.TrimSpace(strings.TrimPrefix(headContent, "ref:"))
-				branches = append(branches, objects.Branch{
-					Name:       "HEAD",
-					TargetType: objects.BranchTargetAlias,
-					Target:     targetRef,
-				})
-			}
-		}
+	head, err := snapshotHead(repo, opts, resolve)
+	if err != nil {
+		return nil, err
+	}
+	if head != nil {
+		branches = append(branches, *head)
 	}
 
 	// Iterate all references
@@ -149,24 +376,11 @@ func FromSnapshot(repoPath string) (*Identifier, error) {
 	}
 
 	err = refs.ForEach(func(ref *plumbing.Reference) error {
-		refName := ref.Name().String()
-
-		if ref.Type() == plumbing.SymbolicReference {
-			// Symbolic reference (alias)
-			branches = append(branches, objects.Branch{
-				Name:       refName,
-				TargetType: objects.BranchTargetAlias,
-				Target:     ref.Target().String(),
-			})
-		} else {
-			// Direct reference
-			targetType, targetHash := resolveRefTarget(repo, ref.Hash())
-			branches = append(branches, objects.Branch{
-				Name:       refName,
-				TargetType: targetType,
-				Target:     targetHash,
-			})
+		if ref.Name() == plumbing.HEAD || !refMap.includes(ref.Name().String()) {
+			return nil
 		}
+
+		branches = append(branches, snapshotBranch(ref, resolve))
 		return nil
 	})
 
@@ -174,7 +388,64 @@ func FromSnapshot(repoPath string) (*Identifier, error) {
 		return nil, fmt.Errorf("failed to iterate references: %w", err)
 	}
 
-	return FromSnapshotBranches(branches), nil
+	return refMap.finish(branches), nil
+}
+
+// snapshotBranch converts a reference into a snapshot branch: symbolic
+// references become aliases, direct ones point at their resolved object.
+func snapshotBranch(ref *plumbing.Reference, resolve func(plumbing.Hash) (objects.BranchTargetType, string)) objects.Branch {
+	if ref.Type() == plumbing.SymbolicReference {
+		return objects.Branch{
+			Name:       ref.Name().String(),
+			TargetType: objects.BranchTargetAlias,
+			Target:     ref.Target().String(),
+		}
+	}
+
+	targetType, target := resolve(ref.Hash())
+	return objects.Branch{Name: ref.Name().String(), TargetType: targetType, Target: target}
+}
+
+// snapshotHead returns the snapshot's HEAD branch: opts.Head if set,
+// otherwise HEAD as stored in the repository, read through go-git's
+// reference storage so worktrees, bare repositories and packed refs are
+// handled. A symbolic HEAD becomes an alias and a detached HEAD points at
+// its object. Repositories without a HEAD yield no branch.
+func snapshotHead(repo *git.Repository, opts GitOptions, resolve func(plumbing.Hash) (objects.BranchTargetType, string)) (*objects.Branch, error) {
+	var ref *plumbing.Reference
+	switch {
+	case opts.Head == "":
+		var err error
+		ref, err = repo.Storer.Reference(plumbing.HEAD)
+		if err == plumbing.ErrReferenceNotFound {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read HEAD: %w", err)
+		}
+	default:
+		ref = headOverride(opts.Head)
+	}
+
+	if ref.Type() == plumbing.SymbolicReference {
+		return &objects.Branch{
+			Name:       "HEAD",
+			TargetType: objects.BranchTargetAlias,
+			Target:     ref.Target().String(),
+		}, nil
+	}
+
+	targetType, target := resolve(ref.Hash())
+	return &objects.Branch{Name: "HEAD", TargetType: targetType, Target: target}, nil
+}
+
+// headOverride returns the HEAD reference described by GitOptions.Head: a
+// direct reference for an object name, an alias for a reference name.
+func headOverride(head string) *plumbing.Reference {
+	if plumbing.IsHash(head) {
+		return plumbing.NewHashReference(plumbing.HEAD, plumbing.NewHash(head))
+	}
+	return plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.ReferenceName(head))
 }
 
 func resolveRefTarget(repo *git.Repository, hash plumbing.Hash) (objects.BranchTargetType, string) {
@@ -218,43 +489,42 @@ func formatTimezone(t interface{ Zone() (string, int) }) string {
 	return fmt.Sprintf("%s%02d%02d", sign, hours, minutes)
 }
 
-func extractCommitExtraHeaders(repo *git.Repository, commit *object.Commit) [][2]string {
-	// Get raw commit data
-	obj, err := repo.Storer.EncodedObject(plumbing.CommitObject, commit.Hash)
+func readRawObject(repo *git.Repository, objType plumbing.ObjectType, hash plumbing.Hash) ([]byte, error) {
+	obj, err := repo.Storer.EncodedObject(objType, hash)
 	if err != nil {
-		return nil
+		return nil, err
 	}
 
 	reader, err := obj.Reader()
 	if err != nil {
-		return nil
+		return nil, err
 	}
 	defer reader.Close()
 
 	var buf bytes.Buffer
-	buf.ReadFrom(reader)
-	rawData := buf.String()
-
-	return parseExtraHeaders(rawData, []string{"tree", "parent", "author", "committer"})
+	if _, err := buf.ReadFrom(reader); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
-func extractTagExtraHeaders(repo *git.Repository, tag *object.Tag) [][2]string {
-	obj, err := repo.Storer.EncodedObject(plumbing.TagObject, tag.Hash)
+func extractCommitExtraHeaders(repo *git.Repository, commit *object.Commit) [][2]string {
+	// Get raw commit data
+	rawData, err := readRawObject(repo, plumbing.CommitObject, commit.Hash)
 	if err != nil {
 		return nil
 	}
 
-	reader, err := obj.Reader()
+	return parseExtraHeaders(string(rawData), []string{"tree", "parent", "author", "committer"})
+}
+
+func extractTagExtraHeaders(repo *git.Repository, tag *object.Tag) [][2]string {
+	rawData, err := readRawObject(repo, plumbing.TagObject, tag.Hash)
 	if err != nil {
 		return nil
 	}
-	defer reader.Close()
-
-	var buf bytes.Buffer
-	buf.ReadFrom(reader)
-	rawData := buf.String()
 
-	return parseExtraHeaders(rawData, []string{"object", "type", "tag", "tagger"})
+	return parseExtraHeaders(string(rawData), []string{"object", "type", "tag", "tagger"})
 }
 
 func parseExtraHeaders(rawData string, standardHeaders []string) [][2]string {
@@ -0,0 +1,413 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andrew/swhid-go/objects"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// newTestRepo creates a Git repository with a single commit on the default
+// branch and returns its path, the repository and the commit hash.
+func newTestRepo(t *testing.T) (string, *git.Repository, plumbing.Hash) {
+	t.Helper()
+
+	tmpDir, err := os.MkdirTemp("", "swhid-git-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+
+	repo, err := git.PlainInit(tmpDir, false)
+	if err != nil {
+		t.Fatalf("Failed to init repository: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "hello.txt"), []byte("hello\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	hash := commitAll(t, repo, "Initial commit\n")
+	return tmpDir, repo, hash
+}
+
+// commitAll stages every file in the worktree and commits it with a fixed signature.
+func commitAll(t *testing.T, repo *git.Repository, message string) plumbing.Hash {
+	t.Helper()
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+	if err := wt.AddGlob("."); err != nil {
+		t.Fatalf("Failed to stage files: %v", err)
+	}
+
+	sig := &object.Signature{
+		Name:  "Test",
+		Email: "test@example.com",
+		When:  time.Unix(1000000000, 0).In(time.FixedZone("", 2*3600)),
+	}
+	hash, err := wt.Commit(message, &git.CommitOptions{Author: sig, Committer: sig})
+	if err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	return hash
+}
+
+func TestFromRevision(t *testing.T) {
+	repoPath, _, hash := newTestRepo(t)
+
+	id, err := FromRevision(repoPath, "HEAD")
+	if err != nil {
+		t.Fatalf("FromRevision() error = %v", err)
+	}
+
+	if id.ObjectType != ObjectTypeRevision {
+		t.Errorf("FromRevision() type = %v, want %v", id.ObjectType, ObjectTypeRevision)
+	}
+
+	if id.ObjectHash != hash.String() {
+		t.Errorf("FromRevision() hash = %v, want %v", id.ObjectHash, hash.String())
+	}
+}
+
+func TestSnapshotRefPolicy(t *testing.T) {
+	repoPath, repo, hash := newTestRepo(t)
+
+	for _, name := range []string{
+		"refs/notes/commits",
+		"refs/pull/1/head",
+		"refs/pull/1/merge",
+		"refs/remotes/origin/master",
+		"refs/tags/v1",
+	} {
+		if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.ReferenceName(name), hash)); err != nil {
+			t.Fatalf("Failed to set reference: %v", err)
+		}
+	}
+
+	tests := []struct {
+		refs     RefPolicy
+		included []string
+		excluded []string
+	}{
+		{
+			refs:     RefsLoader,
+			included: []string{"HEAD", "refs/heads/master", "refs/tags/v1", "refs/notes/commits", "refs/pull/1/head", "refs/remotes/origin/master"},
+			excluded: []string{"refs/pull/1/merge"},
+		},
+		{
+			refs:     RefsAll,
+			included: []string{"HEAD", "refs/heads/master", "refs/tags/v1", "refs/notes/commits", "refs/pull/1/head", "refs/pull/1/merge", "refs/remotes/origin/master"},
+		},
+		{
+			refs:     RefsBranchesAndTags,
+			included: []string{"HEAD", "refs/heads/master", "refs/tags/v1"},
+			excluded: []string{"refs/notes/commits", "refs/pull/1/head", "refs/pull/1/merge", "refs/remotes/origin/master"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.refs.String(), func(t *testing.T) {
+			branches, err := SnapshotBranchesWithOptions(repoPath, GitOptions{Refs: tt.refs})
+			if err != nil {
+				t.Fatalf("SnapshotBranchesWithOptions() error = %v", err)
+			}
+
+			names := make(map[string]bool)
+			for _, b := range branches {
+				names[b.Name] = true
+			}
+			for _, name := range tt.included {
+				if !names[name] {
+					t.Errorf("missing %s", name)
+				}
+			}
+			for _, name := range tt.excluded {
+				if names[name] {
+					t.Errorf("unexpected %s", name)
+				}
+			}
+		})
+	}
+
+	def, _ := FromSnapshot(repoPath)
+	loader, _ := FromSnapshotWithOptions(repoPath, GitOptions{Refs: RefsLoader})
+	if !def.Equal(loader) {
+		t.Errorf("FromSnapshot() = %v, want loader policy %v", def, loader)
+	}
+}
+
+func TestParseRefPolicy(t *testing.T) {
+	for _, p := range []RefPolicy{RefsLoader, RefsAll, RefsBranchesAndTags} {
+		got, err := ParseRefPolicy(p.String())
+		if err != nil || got != p {
+			t.Errorf("ParseRefPolicy(%q) = %v, %v", p.String(), got, err)
+		}
+	}
+	if _, err := ParseRefPolicy("notes"); err == nil {
+		t.Error("ParseRefPolicy() expected error for unknown policy")
+	}
+}
+
+func TestSnapshotHead(t *testing.T) {
+	repoPath, repo, hash := newTestRepo(t)
+
+	want := FromSnapshotBranches([]objects.Branch{
+		{Name: "HEAD", TargetType: objects.BranchTargetAlias, Target: "refs/heads/master"},
+		{Name: "refs/heads/master", TargetType: objects.BranchTargetRevision, Target: hash.String()},
+	})
+
+	got, err := FromSnapshot(repoPath)
+	if err != nil {
+		t.Fatalf("FromSnapshot() error = %v", err)
+	}
+	if !got.Equal(want) {
+		t.Errorf("FromSnapshot() = %v, want %v", got, want)
+	}
+
+	// A .git file pointing elsewhere, as in linked worktrees and submodules.
+	gitDir := filepath.Join(t.TempDir(), "repo.git")
+	if err := os.Rename(filepath.Join(repoPath, ".git"), gitDir); err != nil {
+		t.Fatalf("Failed to move git dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoPath, ".git"), []byte("gitdir: "+gitDir+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .git file: %v", err)
+	}
+	got, err = FromSnapshot(repoPath)
+	if err != nil {
+		t.Fatalf("FromSnapshot() error = %v", err)
+	}
+	if !got.Equal(want) {
+		t.Errorf("FromSnapshot() with .git file = %v, want %v", got, want)
+	}
+
+	// A bare repository.
+	got, err = FromSnapshot(gitDir)
+	if err != nil {
+		t.Fatalf("FromSnapshot() error = %v", err)
+	}
+	if !got.Equal(want) {
+		t.Errorf("FromSnapshot() of bare repository = %v, want %v", got, want)
+	}
+
+	// A detached HEAD points at the commit itself.
+	repo, err = git.PlainOpen(gitDir)
+	if err != nil {
+		t.Fatalf("Failed to open bare repository: %v", err)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.HEAD, hash)); err != nil {
+		t.Fatalf("Failed to detach HEAD: %v", err)
+	}
+	branches, err := SnapshotBranches(gitDir)
+	if err != nil {
+		t.Fatalf("SnapshotBranches() error = %v", err)
+	}
+	heads := 0
+	for _, b := range branches {
+		if b.Name != "HEAD" {
+			continue
+		}
+		heads++
+		if b.TargetType != objects.BranchTargetRevision || b.Target != hash.String() {
+			t.Errorf("detached HEAD = %s %s, want revision %s", b.TargetType, b.Target, hash)
+		}
+	}
+	if heads != 1 {
+		t.Errorf("SnapshotBranches() listed HEAD %d times, want 1", heads)
+	}
+}
+
+func TestSnapshotDetachedHead(t *testing.T) {
+	repoPath, repo, first := newTestRepo(t)
+	if err := os.WriteFile(filepath.Join(repoPath, "more.txt"), []byte("more\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	second := commitAll(t, repo, "Second commit\n")
+	branch, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// wantSnapshot is the snapshot the loader records for a repository
+	// whose HEAD is detached at head: HEAD pointing at the commit itself,
+	// next to the branches.
+	wantSnapshot := func(head plumbing.Hash, branches ...objects.Branch) *Identifier {
+		return FromSnapshotBranches(append([]objects.Branch{
+			{Name: "HEAD", TargetType: objects.BranchTargetRevision, Target: head.String()},
+		}, branches...))
+	}
+	mainBranch := objects.Branch{Name: branch.Name().String(), TargetType: objects.BranchTargetRevision, Target: second.String()}
+
+	// A CI checkout: HEAD detached at an older commit of the worktree
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.Checkout(&git.CheckoutOptions{Hash: first}); err != nil {
+		t.Fatalf("Failed to detach HEAD: %v", err)
+	}
+	got, err := FromSnapshot(repoPath)
+	if err != nil {
+		t.Fatalf("FromSnapshot() error = %v", err)
+	}
+	if want := wantSnapshot(first, mainBranch); !got.Equal(want) {
+		t.Errorf("FromSnapshot() of detached checkout = %v, want %v", got, want)
+	}
+	streamed, err := FromSnapshotStream(repo, SnapshotOptions{})
+	if err != nil {
+		t.Fatalf("FromSnapshotStream() error = %v", err)
+	}
+	if !streamed.Equal(got) {
+		t.Errorf("FromSnapshotStream() = %v, want %v", streamed, got)
+	}
+
+	// A linked worktree, as git worktree add --detach leaves it: its own
+	// HEAD, detached, and the branches and objects of the main repository
+	wtGitDir := filepath.Join(repoPath, ".git", "worktrees", "ci")
+	wtPath := filepath.Join(t.TempDir(), "ci")
+	files := map[string]string{
+		filepath.Join(wtGitDir, "HEAD"):      second.String() + "\n",
+		filepath.Join(wtGitDir, "commondir"): "../..\n",
+		filepath.Join(wtGitDir, "gitdir"):    filepath.Join(wtPath, ".git") + "\n",
+		filepath.Join(wtPath, ".git"):        "gitdir: " + wtGitDir + "\n",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, readOnly := range []bool{false, true} {
+		got, err := FromSnapshotWithOptions(wtPath, GitOptions{ReadOnlyFS: readOnly})
+		if err != nil {
+			t.Fatalf("FromSnapshotWithOptions() of linked worktree error = %v", err)
+		}
+		if want := wantSnapshot(second, mainBranch); !got.Equal(want) {
+			t.Errorf("FromSnapshotWithOptions(ReadOnlyFS: %v) of linked worktree = %v, want %v", readOnly, got, want)
+		}
+	}
+	rev, err := FromRevision(wtPath, "HEAD")
+	if err != nil {
+		t.Fatalf("FromRevision() of linked worktree error = %v", err)
+	}
+	if rev.ObjectHash != second.String() {
+		t.Errorf("FromRevision() of linked worktree = %v, want %v", rev.ObjectHash, second)
+	}
+}
+
+func TestSnapshotExplicitHead(t *testing.T) {
+	repoPath, _, hash := newTestRepo(t)
+
+	tests := []struct {
+		head       string
+		targetType objects.BranchTargetType
+		target     string
+	}{
+		{head: "refs/heads/main", targetType: objects.BranchTargetAlias, target: "refs/heads/main"},
+		{head: hash.String(), targetType: objects.BranchTargetRevision, target: hash.String()},
+	}
+
+	for _, tt := range tests {
+		branches, err := SnapshotBranchesWithOptions(repoPath, GitOptions{Head: tt.head})
+		if err != nil {
+			t.Fatalf("SnapshotBranchesWithOptions() error = %v", err)
+		}
+		if len(branches) == 0 || branches[0].Name != "HEAD" {
+			t.Fatalf("SnapshotBranchesWithOptions(%q) has no HEAD: %v", tt.head, branches)
+		}
+		if branches[0].TargetType != tt.targetType || branches[0].Target != tt.target {
+			t.Errorf("HEAD = %s %s, want %s %s", branches[0].TargetType, branches[0].Target, tt.targetType, tt.target)
+		}
+	}
+}
+
+func TestFromRepoInMemory(t *testing.T) {
+	repo, err := git.Init(memory.NewStorage(), memfs.New())
+	if err != nil {
+		t.Fatalf("Failed to init repository: %v", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+	f, err := wt.Filesystem.Create("hello.txt")
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	f.Write([]byte("hello\n"))
+	f.Close()
+	hash := commitAll(t, repo, "Initial commit\n")
+
+	sig := &object.Signature{Name: "Test", Email: "test@example.com", When: time.Unix(1000000000, 0).UTC()}
+	tag, err := repo.CreateTag("v1.0.0", hash, &git.CreateTagOptions{Tagger: sig, Message: "v1.0.0\n"})
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	rev, err := FromRevisionRepo(repo, "HEAD")
+	if err != nil {
+		t.Fatalf("FromRevisionRepo() error = %v", err)
+	}
+	if rev.ObjectHash != hash.String() {
+		t.Errorf("FromRevisionRepo() = %v, want %v", rev.ObjectHash, hash)
+	}
+
+	verbatim, report, err := FromRevisionVerbatimRepo(repo, "HEAD")
+	if err != nil {
+		t.Fatalf("FromRevisionVerbatimRepo() error = %v", err)
+	}
+	if !verbatim.Equal(rev) || !report.Match() {
+		t.Errorf("FromRevisionVerbatimRepo() = %v, match %v", verbatim, report.Match())
+	}
+
+	rel, err := FromReleaseRepo(repo, "v1.0.0")
+	if err != nil {
+		t.Fatalf("FromReleaseRepo() error = %v", err)
+	}
+	if rel.ObjectHash != tag.Hash().String() {
+		t.Errorf("FromReleaseRepo() = %v, want %v", rel.ObjectHash, tag.Hash())
+	}
+
+	snp, err := FromSnapshotRepo(repo)
+	if err != nil {
+		t.Fatalf("FromSnapshotRepo() error = %v", err)
+	}
+	want := FromSnapshotBranches([]objects.Branch{
+		{Name: "HEAD", TargetType: objects.BranchTargetAlias, Target: "refs/heads/master"},
+		{Name: "refs/heads/master", TargetType: objects.BranchTargetRevision, Target: hash.String()},
+		{Name: "refs/tags/v1.0.0", TargetType: objects.BranchTargetRelease, Target: tag.Hash().String()},
+	})
+	if !snp.Equal(want) {
+		t.Errorf("FromSnapshotRepo() = %v, want %v", snp, want)
+	}
+
+	dir, err := FromGitIndexRepo(repo)
+	if err != nil {
+		t.Fatalf("FromGitIndexRepo() error = %v", err)
+	}
+	commit, _ := repo.CommitObject(hash)
+	if dir.ObjectHash != commit.TreeHash.String() {
+		t.Errorf("FromGitIndexRepo() = %v, want %v", dir.ObjectHash, commit.TreeHash)
+	}
+
+	count := 0
+	if err := WalkHistoryRepo(repo, func(RevisionRecord) error { count++; return nil }); err != nil {
+		t.Fatalf("WalkHistoryRepo() error = %v", err)
+	}
+	if count != 1 {
+		t.Errorf("WalkHistoryRepo() visited %d revisions, want 1", count)
+	}
+}
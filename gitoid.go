@@ -0,0 +1,117 @@
+package swhid
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/andrew/swhid-go/objects"
+)
+
+// Gitoid hash algorithms.
+const (
+	GitoidSHA1   = "sha1"
+	GitoidSHA256 = "sha256"
+)
+
+var (
+	// ErrNotGitoid is returned when a string is not a gitoid URI.
+	ErrNotGitoid = errors.New("not a gitoid URI")
+
+	// ErrGitoidAlgorithm is returned when converting a SHA-256 gitoid to a
+	// SWHID. It names the same object but with a different hash, which
+	// cannot be derived from the SHA-1 one; recompute it from the content
+	// with GitoidFromContent instead.
+	ErrGitoidAlgorithm = errors.New("only sha1 gitoids map to SWHIDs")
+
+	// ErrNoGitoid is returned for SWHIDs of object types git does not
+	// have, namely snapshots.
+	ErrNoGitoid = errors.New("object type has no gitoid")
+)
+
+// Gitoid is a git object identifier URI, gitoid:<type>:<algorithm>:<hash>,
+// as used by OmniBOR artifact IDs. A sha1 gitoid hashes an object exactly
+// as git and SWHIDs do, so blob, tree, commit and tag gitoids convert to
+// cnt, dir, rev and rel SWHIDs and back.
+type Gitoid struct {
+	Type      string // blob, tree, commit or tag
+	Algorithm string // GitoidSHA1 or GitoidSHA256
+	Hash      string // lowercase hex
+}
+
+var gitoidRegex = regexp.MustCompile(`^gitoid:(blob|tree|commit|tag):(sha1|sha256):([0-9a-f]+)$`)
+
+// gitoidTypes maps git object types to SWHID object types.
+var gitoidTypes = map[string]ObjectType{
+	"blob":   ObjectTypeContent,
+	"tree":   ObjectTypeDirectory,
+	"commit": ObjectTypeRevision,
+	"tag":    ObjectTypeRelease,
+}
+
+// ParseGitoid parses a gitoid URI such as
+// "gitoid:blob:sha1:261eeb9e9f8b2b4b0d119366dda99c6fd7d35c64".
+func ParseGitoid(s string) (*Gitoid, error) {
+	m := gitoidRegex.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotGitoid, s)
+	}
+
+	g := &Gitoid{Type: m[1], Algorithm: m[2], Hash: m[3]}
+	if want := hashLength(g.Algorithm); len(g.Hash) != want {
+		return nil, fmt.Errorf("%w: %s hash must be %d hex characters, got %d", ErrNotGitoid, g.Algorithm, want, len(g.Hash))
+	}
+	return g, nil
+}
+
+// String returns the gitoid URI.
+func (g *Gitoid) String() string {
+	return "gitoid:" + g.Type + ":" + g.Algorithm + ":" + g.Hash
+}
+
+// SWHID returns the core SWHID of the object the gitoid names. SHA-256
+// gitoids fail with ErrGitoidAlgorithm.
+func (g *Gitoid) SWHID() (*Identifier, error) {
+	if g.Algorithm != GitoidSHA1 {
+		return nil, fmt.Errorf("%w: %s", ErrGitoidAlgorithm, g)
+	}
+	return NewIdentifier(gitoidTypes[g.Type], g.Hash, nil)
+}
+
+// Gitoid returns the sha1 gitoid of the object the SWHID names; qualifiers
+// are dropped. Snapshots fail with ErrNoGitoid.
+func (id *Identifier) Gitoid() (*Gitoid, error) {
+	for gitType, objectType := range gitoidTypes {
+		if objectType == id.ObjectType {
+			return &Gitoid{Type: gitType, Algorithm: GitoidSHA1, Hash: id.ObjectHash}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNoGitoid, id.ObjectType)
+}
+
+// GitoidFromContent computes the blob gitoid of data with the given
+// algorithm, GitoidSHA1 or GitoidSHA256. Use it to get the SHA-256 gitoid
+// of content whose SWHID is known.
+func GitoidFromContent(data []byte, algorithm string) (*Gitoid, error) {
+	switch algorithm {
+	case GitoidSHA1:
+		return &Gitoid{Type: "blob", Algorithm: GitoidSHA1, Hash: objects.ComputeContentHash(data)}, nil
+	case GitoidSHA256:
+		h := sha256.New()
+		fmt.Fprintf(h, "blob %d\x00", len(data))
+		h.Write(data)
+		return &Gitoid{Type: "blob", Algorithm: GitoidSHA256, Hash: hex.EncodeToString(h.Sum(nil))}, nil
+	default:
+		return nil, fmt.Errorf("unknown gitoid algorithm %q (expected sha1 or sha256)", algorithm)
+	}
+}
+
+func hashLength(algorithm string) int {
+	if algorithm == GitoidSHA256 {
+		return 64
+	}
+	return 40
+}
@@ -0,0 +1,94 @@
+package swhid
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGitoidRoundTrip(t *testing.T) {
+	tests := []struct {
+		swhid  string
+		gitoid string
+	}{
+		{"swh:1:cnt:e69de29bb2d1d6434b8b29ae775ad8c2e48c5391", "gitoid:blob:sha1:e69de29bb2d1d6434b8b29ae775ad8c2e48c5391"},
+		{"swh:1:dir:4b825dc642cb6eb9a060e54bf8d69288fbee4904", "gitoid:tree:sha1:4b825dc642cb6eb9a060e54bf8d69288fbee4904"},
+		{"swh:1:rev:309cf2674ee7a0749978cf8265ab91a60aea0f7d", "gitoid:commit:sha1:309cf2674ee7a0749978cf8265ab91a60aea0f7d"},
+		{"swh:1:rel:22ece559cc7cc2364edc5e5593d63ae8bd229f9f", "gitoid:tag:sha1:22ece559cc7cc2364edc5e5593d63ae8bd229f9f"},
+	}
+
+	for _, tt := range tests {
+		id, err := Parse(tt.swhid)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.swhid, err)
+		}
+		g, err := id.Gitoid()
+		if err != nil {
+			t.Fatalf("Gitoid() error = %v", err)
+		}
+		if g.String() != tt.gitoid {
+			t.Errorf("Gitoid() = %v, want %v", g, tt.gitoid)
+		}
+
+		parsed, err := ParseGitoid(tt.gitoid)
+		if err != nil {
+			t.Fatalf("ParseGitoid(%q) error = %v", tt.gitoid, err)
+		}
+		back, err := parsed.SWHID()
+		if err != nil {
+			t.Fatalf("SWHID() error = %v", err)
+		}
+		if back.String() != tt.swhid {
+			t.Errorf("SWHID() = %v, want %v", back, tt.swhid)
+		}
+	}
+}
+
+func TestGitoidErrors(t *testing.T) {
+	snp, _ := Parse("swh:1:snp:c7c108084bc0bf3d81436bf980b46e98bd338453")
+	if _, err := snp.Gitoid(); !errors.Is(err, ErrNoGitoid) {
+		t.Errorf("snapshot Gitoid() error = %v, want ErrNoGitoid", err)
+	}
+
+	g, err := ParseGitoid("gitoid:blob:sha256:473a0f4c3be8a93681a267e3b1e9a7dcda1185436fe141f7749120a303721813")
+	if err != nil {
+		t.Fatalf("ParseGitoid() error = %v", err)
+	}
+	if _, err := g.SWHID(); !errors.Is(err, ErrGitoidAlgorithm) {
+		t.Errorf("sha256 SWHID() error = %v, want ErrGitoidAlgorithm", err)
+	}
+
+	for _, s := range []string{
+		"gitoid:blob:sha1:e69de29b",
+		"gitoid:blob:sha256:e69de29bb2d1d6434b8b29ae775ad8c2e48c5391",
+		"gitoid:snapshot:sha1:e69de29bb2d1d6434b8b29ae775ad8c2e48c5391",
+		"swh:1:cnt:e69de29bb2d1d6434b8b29ae775ad8c2e48c5391",
+	} {
+		if _, err := ParseGitoid(s); !errors.Is(err, ErrNotGitoid) {
+			t.Errorf("ParseGitoid(%q) error = %v, want ErrNotGitoid", s, err)
+		}
+	}
+}
+
+func TestGitoidFromContent(t *testing.T) {
+	g, err := GitoidFromContent(nil, GitoidSHA256)
+	if err != nil {
+		t.Fatalf("GitoidFromContent() error = %v", err)
+	}
+	// The empty blob in a SHA-256 git repository.
+	if want := "gitoid:blob:sha256:473a0f4c3be8a93681a267e3b1e9a7dcda1185436fe141f7749120a303721813"; g.String() != want {
+		t.Errorf("GitoidFromContent(sha256) = %v, want %v", g, want)
+	}
+
+	g, err = GitoidFromContent([]byte("hello\n"), GitoidSHA1)
+	if err != nil {
+		t.Fatalf("GitoidFromContent() error = %v", err)
+	}
+	id, _ := g.SWHID()
+	if !id.Equal(FromContent([]byte("hello\n"))) {
+		t.Errorf("GitoidFromContent(sha1) = %v, want %v", id, FromContent([]byte("hello\n")))
+	}
+
+	if _, err := GitoidFromContent(nil, "md5"); err == nil {
+		t.Error("GitoidFromContent() expected error for unknown algorithm")
+	}
+}
@@ -0,0 +1,231 @@
+package gomod
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/internal/archivetree"
+	"github.com/andrew/swhid-go/objects"
+)
+
+// ErrLimitExceeded is returned when a module zip is larger, or holds more
+// or bigger files, than the resolver's Limits allow.
+var ErrLimitExceeded = errors.New("module zip exceeds extraction limit")
+
+// ErrUnsafePath is returned for a module zip member outside the module
+// directory, with a non-canonical or duplicate name, or that is not a
+// regular file or directory.
+var ErrUnsafePath = errors.New("unsafe path in module zip")
+
+// Limits bounds what hashing a module zip may cost. The zip comes from the
+// network and its headers are not trusted: sizes are counted while
+// decompressing. Zero fields take their value from DefaultLimits.
+type Limits struct {
+	// MaxMemory is the largest zip, or uncompressed file within it, held in
+	// memory; anything larger is spooled to a temporary file.
+	MaxMemory int64
+
+	// MaxTotalBytes caps both the zip itself and the uncompressed size of
+	// all its files together.
+	MaxTotalBytes int64
+
+	// MaxMembers caps the number of entries in the zip.
+	MaxMembers int
+
+	// TempDir is where spooled data is written; os.TempDir() when empty.
+	TempDir string
+}
+
+// DefaultLimits match the limits the go command places on module zips.
+var DefaultLimits = Limits{
+	MaxMemory:     16 << 20,
+	MaxTotalBytes: 500 << 20,
+	MaxMembers:    100000,
+}
+
+func (l Limits) withDefaults() Limits {
+	if l.MaxMemory <= 0 {
+		l.MaxMemory = DefaultLimits.MaxMemory
+	}
+	if l.MaxTotalBytes <= 0 {
+		l.MaxTotalBytes = DefaultLimits.MaxTotalBytes
+	}
+	if l.MaxMembers <= 0 {
+		l.MaxMembers = DefaultLimits.MaxMembers
+	}
+	return l
+}
+
+// spool holds written bytes in memory up to max and moves them to a
+// temporary file once they grow past it.
+type spool struct {
+	max  int64
+	dir  string
+	buf  bytes.Buffer
+	file *os.File
+	size int64
+}
+
+func (s *spool) Write(p []byte) (int, error) {
+	if s.file == nil && s.size+int64(len(p)) > s.max {
+		f, err := os.CreateTemp(s.dir, "swhid-gomod-*")
+		if err != nil {
+			return 0, err
+		}
+		s.file = f
+		if _, err := f.Write(s.buf.Bytes()); err != nil {
+			return 0, err
+		}
+		s.buf = bytes.Buffer{}
+	}
+
+	var n int
+	var err error
+	if s.file != nil {
+		n, err = s.file.Write(p)
+	} else {
+		n, err = s.buf.Write(p)
+	}
+	s.size += int64(n)
+	return n, err
+}
+
+// readerAt returns the spooled bytes for random access.
+func (s *spool) readerAt() io.ReaderAt {
+	if s.file != nil {
+		return s.file
+	}
+	return bytes.NewReader(s.buf.Bytes())
+}
+
+// reader returns the spooled bytes from the start.
+func (s *spool) reader() (io.Reader, error) {
+	if s.file != nil {
+		if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return s.file, nil
+	}
+	return bytes.NewReader(s.buf.Bytes()), nil
+}
+
+// Close removes the temporary file, if there is one.
+func (s *spool) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	s.file.Close()
+	return os.Remove(s.file.Name())
+}
+
+// copyLimited copies r to w, failing with ErrLimitExceeded once more than
+// *budget bytes have been copied, and deducts what it copied from *budget.
+func copyLimited(w io.Writer, r io.Reader, budget *int64) error {
+	n, err := io.Copy(w, io.LimitReader(r, *budget+1))
+	if err != nil {
+		return err
+	}
+	if n > *budget {
+		return ErrLimitExceeded
+	}
+	*budget -= n
+	return nil
+}
+
+// hashModuleZip computes the directory SWHID of the module directory of a
+// module zip, the same one swhid.FromFS gives for fs.Sub(zr, prefix), while
+// holding to limits. Every member must lie under prefix.
+func hashModuleZip(zr *zip.Reader, prefix string, limits Limits) (*swhid.Identifier, error) {
+	if len(zr.File) > limits.MaxMembers {
+		return nil, fmt.Errorf("%w: %d entries, limit %d", ErrLimitExceeded, len(zr.File), limits.MaxMembers)
+	}
+
+	root := archivetree.New()
+	seen := make(map[string]bool, len(zr.File))
+	budget := limits.MaxTotalBytes
+	for _, f := range zr.File {
+		rel, ok := strings.CutPrefix(f.Name, prefix+"/")
+		isDir := strings.HasSuffix(rel, "/")
+		rel = strings.TrimSuffix(rel, "/")
+		if !ok || rel == "" || !fs.ValidPath(rel) || strings.Contains(rel, `\`) || seen[rel] {
+			return nil, fmt.Errorf("%w: %q", ErrUnsafePath, f.Name)
+		}
+		seen[rel] = true
+
+		names := strings.Split(rel, "/")
+		if skipped(names) {
+			continue
+		}
+		mode := f.Mode()
+		if isDir || mode.IsDir() {
+			if !root.Mkdir(rel) {
+				return nil, fmt.Errorf("%w: %q", ErrUnsafePath, f.Name)
+			}
+			continue
+		}
+		if !mode.IsRegular() {
+			return nil, fmt.Errorf("%w: %q is not a regular file", ErrUnsafePath, f.Name)
+		}
+
+		hash, size, err := hashMember(f, limits, &budget)
+		if err != nil {
+			return nil, err
+		}
+		entryType := objects.EntryTypeFile
+		if mode&0111 != 0 {
+			entryType = objects.EntryTypeExecutable
+		}
+		if !root.Add(rel, objects.DirectoryEntry{Type: entryType, Target: hash, Size: size}) {
+			return nil, fmt.Errorf("%w: %q", ErrUnsafePath, f.Name)
+		}
+	}
+	return root.Identifier(), nil
+}
+
+// skipped reports whether a member lies in a .git directory, which
+// swhid.FromFS leaves out.
+func skipped(names []string) bool {
+	for _, name := range names {
+		if name == ".git" {
+			return true
+		}
+	}
+	return false
+}
+
+// hashMember decompresses one zip member through a spool, charging its
+// size to budget, and returns its blob hash and size.
+func hashMember(f *zip.File, limits Limits, budget *int64) (string, int64, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	s := &spool{max: limits.MaxMemory, dir: limits.TempDir}
+	defer s.Close()
+	if err := copyLimited(s, rc, budget); err != nil {
+		if errors.Is(err, ErrLimitExceeded) {
+			return "", 0, fmt.Errorf("%w: more than %d uncompressed bytes", ErrLimitExceeded, limits.MaxTotalBytes)
+		}
+		return "", 0, fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+
+	r, err := s.reader()
+	if err != nil {
+		return "", 0, err
+	}
+	h := objects.NewContentHash(s.size)
+	if _, err := io.Copy(h, r); err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), s.size, nil
+}
@@ -0,0 +1,325 @@
+// Package gomod maps the dependencies of a Go module or binary to SWHIDs.
+//
+// Module lists are read from a go.mod file or from the build information
+// embedded in a compiled binary. Each module version is then fetched from
+// the module proxy: its zip is hashed into a directory SWHID, and the origin
+// metadata the proxy records (repository URL and commit hash) yields the
+// upstream repository and revision SWHID.
+package gomod
+
+import (
+	"archive/zip"
+	"bufio"
+	"debug/buildinfo"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/andrew/swhid-go"
+)
+
+// DefaultProxyURL is the module proxy used when Resolver.ProxyURL is empty.
+const DefaultProxyURL = "https://proxy.golang.org"
+
+// Module identifies a module version.
+type Module struct {
+	Path    string
+	Version string
+}
+
+func (m Module) String() string {
+	return m.Path + "@" + m.Version
+}
+
+// ReadBinary returns the dependency modules recorded in a Go binary.
+// Replaced modules are reported as their replacement; local directory
+// replacements, which have no version, are skipped.
+func ReadBinary(path string) ([]Module, error) {
+	info, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read build info: %w", err)
+	}
+
+	var modules []Module
+	for _, dep := range info.Deps {
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		if dep.Version == "" {
+			continue
+		}
+		modules = append(modules, Module{Path: dep.Path, Version: dep.Version})
+	}
+	return modules, nil
+}
+
+// ReadGoMod returns the modules listed in the require directives of a
+// go.mod file.
+func ReadGoMod(path string) ([]Module, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var modules []Module
+	inBlock := false
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.Index(line, "//"); idx != -1 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch {
+		case inBlock && fields[0] == ")":
+			inBlock = false
+			continue
+		case inBlock:
+		case fields[0] == "require" && len(fields) == 2 && fields[1] == "(":
+			inBlock = true
+			continue
+		case fields[0] == "require":
+			fields = fields[1:]
+		default:
+			continue
+		}
+
+		if len(fields) < 2 {
+			continue
+		}
+		modules = append(modules, Module{
+			Path:    strings.Trim(fields[0], `"`),
+			Version: fields[1],
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return modules, nil
+}
+
+// EscapePath applies the module proxy case encoding, replacing each upper
+// case letter with '!' followed by its lower case form.
+func EscapePath(p string) string {
+	var b strings.Builder
+	for _, r := range p {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			b.WriteRune(unicode.ToLower(r))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Dependency is the provenance of one module version.
+type Dependency struct {
+	Module     Module
+	Repository string            // upstream repository URL, if known
+	Ref        string            // tag or branch the version was resolved from, if known
+	Directory  *swhid.Identifier // SWHID of the module zip contents
+	Revision   *swhid.Identifier // SWHID of the upstream commit, if known
+	Err        error             // set when the module could not be resolved
+}
+
+// Resolver fetches module versions from a module proxy.
+type Resolver struct {
+	Client   *http.Client // defaults to http.DefaultClient
+	ProxyURL string       // defaults to DefaultProxyURL
+	Limits   Limits       // bounds on fetching and hashing module zips
+}
+
+// Report resolves every module, collecting per-module errors in
+// Dependency.Err rather than stopping at the first failure.
+func (r *Resolver) Report(modules []Module) []Dependency {
+	deps := make([]Dependency, len(modules))
+	for i, m := range modules {
+		deps[i] = r.Resolve(m)
+	}
+	return deps
+}
+
+// Resolve computes the provenance of a single module version.
+//
+// The directory SWHID identifies the module zip, which matches the upstream
+// repository tree only when the module is at the repository root and the
+// tree has no nested modules, vendor directory or executable files.
+func (r *Resolver) Resolve(m Module) Dependency {
+	dep := Dependency{Module: m}
+
+	if origin, err := r.origin(m); err == nil && origin != nil {
+		dep.Repository = origin.URL
+		dep.Ref = origin.Ref
+		if origin.VCS == "git" && origin.Hash != "" {
+			dep.Revision, _ = swhid.NewIdentifier(swhid.ObjectTypeRevision, origin.Hash, nil)
+		}
+	}
+	if dep.Repository == "" {
+		dep.Repository = r.repositoryURL(m.Path)
+	}
+
+	dir, err := r.directory(m)
+	if err != nil {
+		dep.Err = err
+		return dep
+	}
+	dep.Directory = dir
+
+	return dep
+}
+
+type proxyOrigin struct {
+	VCS  string
+	URL  string
+	Ref  string
+	Hash string
+}
+
+func (r *Resolver) origin(m Module) (*proxyOrigin, error) {
+	body, err := r.fetch(m, ".info")
+	if err != nil {
+		return nil, err
+	}
+
+	var info struct {
+		Origin *proxyOrigin
+	}
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, err
+	}
+	return info.Origin, nil
+}
+
+// directory hashes the module zip within r.Limits, spooling the zip to a
+// temporary file if it is larger than MaxMemory.
+func (r *Resolver) directory(m Module) (*swhid.Identifier, error) {
+	limits := r.Limits.withDefaults()
+
+	body, u, err := r.open(m, ".zip")
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	s := &spool{max: limits.MaxMemory, dir: limits.TempDir}
+	defer s.Close()
+	budget := limits.MaxTotalBytes
+	if err := copyLimited(s, body, &budget); err != nil {
+		if errors.Is(err, ErrLimitExceeded) {
+			return nil, fmt.Errorf("%w: %s is larger than %d bytes", ErrLimitExceeded, m, limits.MaxTotalBytes)
+		}
+		return nil, fmt.Errorf("failed to fetch %s: %w", u, err)
+	}
+
+	zr, err := zip.NewReader(s.readerAt(), s.size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid module zip for %s: %w", m, err)
+	}
+	id, err := hashModuleZip(zr, m.String(), limits)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", m, err)
+	}
+	return id, nil
+}
+
+func (r *Resolver) fetch(m Module, suffix string) ([]byte, error) {
+	body, _, err := r.open(m, suffix)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// open starts fetching a file of a module version from the proxy and
+// returns its body and URL.
+func (r *Resolver) open(m Module, suffix string) (io.ReadCloser, string, error) {
+	proxy := r.ProxyURL
+	if proxy == "" {
+		proxy = DefaultProxyURL
+	}
+	u := strings.TrimSuffix(proxy, "/") + "/" + EscapePath(m.Path) + "/@v/" + EscapePath(m.Version) + suffix
+
+	resp, err := r.client().Get(u)
+	if err != nil {
+		return nil, u, fmt.Errorf("failed to fetch %s: %w", u, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, u, fmt.Errorf("failed to fetch %s: %s", u, resp.Status)
+	}
+	return resp.Body, u, nil
+}
+
+func (r *Resolver) client() *http.Client {
+	if r.Client != nil {
+		return r.Client
+	}
+	return http.DefaultClient
+}
+
+// ErrNoRepository is returned by RepositoryURL when a module path cannot be
+// mapped to a repository.
+var ErrNoRepository = errors.New("repository not found")
+
+var knownHosts = map[string]bool{
+	"github.com":    true,
+	"gitlab.com":    true,
+	"bitbucket.org": true,
+	"codeberg.org":  true,
+}
+
+var goImportRegex = regexp.MustCompile(`<meta\s+name="go-import"\s+content="([^"]+)"`)
+
+// RepositoryURL maps a module path to its repository URL. Well-known forges
+// are mapped directly; other paths are resolved with a ?go-get=1 request.
+func (r *Resolver) RepositoryURL(modulePath string) (string, error) {
+	parts := strings.Split(modulePath, "/")
+	if knownHosts[parts[0]] && len(parts) >= 3 {
+		return "https://" + strings.Join(parts[:3], "/"), nil
+	}
+
+	resp, err := r.client().Get("https://" + modulePath + "?go-get=1")
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrNoRepository, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrNoRepository, err)
+	}
+
+	for _, match := range goImportRegex.FindAllSubmatch(body, -1) {
+		fields := strings.Fields(string(match[1]))
+		if len(fields) != 3 || fields[1] == "mod" {
+			continue
+		}
+		if modulePath == fields[0] || strings.HasPrefix(modulePath, fields[0]+"/") {
+			return fields[2], nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrNoRepository, modulePath)
+}
+
+func (r *Resolver) repositoryURL(modulePath string) string {
+	u, err := r.RepositoryURL(modulePath)
+	if err != nil {
+		return ""
+	}
+	return u
+}
@@ -0,0 +1,251 @@
+package gomod
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andrew/swhid-go"
+)
+
+func TestReadGoMod(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "swhid-gomod-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	goMod := `module example.com/app
+
+go 1.22
+
+require github.com/example/one v1.2.3
+
+require (
+	github.com/example/two v0.1.0 // indirect
+	"example.com/three" v2.0.0+incompatible
+)
+`
+	path := filepath.Join(tmpDir, "go.mod")
+	if err := os.WriteFile(path, []byte(goMod), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+
+	modules, err := ReadGoMod(path)
+	if err != nil {
+		t.Fatalf("ReadGoMod() error = %v", err)
+	}
+
+	want := []Module{
+		{Path: "github.com/example/one", Version: "v1.2.3"},
+		{Path: "github.com/example/two", Version: "v0.1.0"},
+		{Path: "example.com/three", Version: "v2.0.0+incompatible"},
+	}
+	if len(modules) != len(want) {
+		t.Fatalf("ReadGoMod() returned %d modules, want %d", len(modules), len(want))
+	}
+	for i := range want {
+		if modules[i] != want[i] {
+			t.Errorf("module %d = %v, want %v", i, modules[i], want[i])
+		}
+	}
+}
+
+func TestEscapePath(t *testing.T) {
+	if got := EscapePath("github.com/BurntSushi/toml"); got != "github.com/!burnt!sushi/toml" {
+		t.Errorf("EscapePath() = %v", got)
+	}
+}
+
+func TestRepositoryURLKnownHost(t *testing.T) {
+	r := &Resolver{}
+	got, err := r.RepositoryURL("github.com/go-git/go-git/v5")
+	if err != nil {
+		t.Fatalf("RepositoryURL() error = %v", err)
+	}
+	if got != "https://github.com/go-git/go-git" {
+		t.Errorf("RepositoryURL() = %v", got)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("github.com/example/one@v1.2.3/hello.txt")
+	if err != nil {
+		t.Fatalf("Failed to create zip entry: %v", err)
+	}
+	w.Write([]byte("hello\n"))
+	zw.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/github.com/example/one/@v/v1.2.3.info", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Version":"v1.2.3","Origin":{"VCS":"git","URL":"https://github.com/example/one","Ref":"refs/tags/v1.2.3","Hash":"309cf2674ee7a0749978cf8265ab91a60aea0f7d"}}`))
+	})
+	mux.HandleFunc("/github.com/example/one/@v/v1.2.3.zip", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(buf.Bytes())
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	r := &Resolver{Client: server.Client(), ProxyURL: server.URL}
+	deps := r.Report([]Module{{Path: "github.com/example/one", Version: "v1.2.3"}})
+	dep := deps[0]
+
+	if dep.Err != nil {
+		t.Fatalf("Resolve() error = %v", dep.Err)
+	}
+	if dep.Repository != "https://github.com/example/one" {
+		t.Errorf("Repository = %v", dep.Repository)
+	}
+	if dep.Ref != "refs/tags/v1.2.3" {
+		t.Errorf("Ref = %v", dep.Ref)
+	}
+	if got := dep.Revision.String(); got != "swh:1:rev:309cf2674ee7a0749978cf8265ab91a60aea0f7d" {
+		t.Errorf("Revision = %v", got)
+	}
+	// Same single-file tree as the root package tests
+	if got := dep.Directory.String(); got != "swh:1:dir:aaa96ced2d9a1c8e72c56b253a0e2fe78393feb7" {
+		t.Errorf("Directory = %v", got)
+	}
+}
+
+// serveZip serves zip as the module zip of github.com/example/one@v1.2.3.
+func serveZip(t *testing.T, zip []byte) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/github.com/example/one/@v/v1.2.3.zip", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(zip)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+type zipMember struct {
+	name string
+	mode fs.FileMode
+	data string
+}
+
+func buildZip(t *testing.T, members []zipMember) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, m := range members {
+		hdr := &zip.FileHeader{Name: m.name, Method: zip.Deflate}
+		hdr.SetMode(m.mode)
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatalf("Failed to create zip entry: %v", err)
+		}
+		w.Write([]byte(m.data))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to write zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestResolveSpooled(t *testing.T) {
+	data := buildZip(t, []zipMember{
+		{"github.com/example/one@v1.2.3/go.mod", 0644, "module github.com/example/one\n"},
+		{"github.com/example/one@v1.2.3/run.sh", 0755, "#!/bin/sh\necho hello\n"},
+		{"github.com/example/one@v1.2.3/sub/a.go", 0644, strings.Repeat("package sub\n", 100)},
+		{"github.com/example/one@v1.2.3/empty/", fs.ModeDir | 0755, ""},
+	})
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("zip.NewReader() error = %v", err)
+	}
+	sub, _ := fs.Sub(zr, "github.com/example/one@v1.2.3")
+	want, err := swhid.FromFS(sub)
+	if err != nil {
+		t.Fatalf("FromFS() error = %v", err)
+	}
+
+	server := serveZip(t, data)
+	tempDir := t.TempDir()
+	for _, maxMemory := range []int64{4, 1 << 20} {
+		r := &Resolver{Client: server.Client(), ProxyURL: server.URL, Limits: Limits{MaxMemory: maxMemory, TempDir: tempDir}}
+		dep := r.Resolve(Module{Path: "github.com/example/one", Version: "v1.2.3"})
+		if dep.Err != nil {
+			t.Fatalf("MaxMemory %d: Resolve() error = %v", maxMemory, dep.Err)
+		}
+		if dep.Directory.String() != want.String() {
+			t.Errorf("MaxMemory %d: Directory = %v, want %v", maxMemory, dep.Directory, want)
+		}
+	}
+	if left, _ := os.ReadDir(tempDir); len(left) != 0 {
+		t.Errorf("temporary files left behind: %v", left)
+	}
+}
+
+func TestResolveLimits(t *testing.T) {
+	const prefix = "github.com/example/one@v1.2.3/"
+	tests := []struct {
+		name    string
+		members []zipMember
+		limits  Limits
+		want    error
+	}{
+		{"traversal", []zipMember{{prefix + "../../etc/passwd", 0644, "x"}}, Limits{}, ErrUnsafePath},
+		{"outside module", []zipMember{{"github.com/example/other@v1.0.0/a.go", 0644, "x"}}, Limits{}, ErrUnsafePath},
+		{"absolute", []zipMember{{prefix + "/a.go", 0644, "x"}}, Limits{}, ErrUnsafePath},
+		{"backslash", []zipMember{{prefix + `sub\a.go`, 0644, "x"}}, Limits{}, ErrUnsafePath},
+		{"duplicate", []zipMember{{prefix + "a.go", 0644, "x"}, {prefix + "a.go", 0644, "y"}}, Limits{}, ErrUnsafePath},
+		{"file and directory", []zipMember{{prefix + "a", 0644, "x"}, {prefix + "a/b", 0644, "y"}}, Limits{}, ErrUnsafePath},
+		{"symlink", []zipMember{{prefix + "link", fs.ModeSymlink | 0777, "/etc/passwd"}}, Limits{}, ErrUnsafePath},
+		{"members", []zipMember{{prefix + "a", 0644, "x"}, {prefix + "b", 0644, "y"}}, Limits{MaxMembers: 1}, ErrLimitExceeded},
+		{"uncompressed bytes", []zipMember{{prefix + "a", 0644, strings.Repeat("x", 4096)}}, Limits{MaxTotalBytes: 1024}, ErrLimitExceeded},
+		{"zip bytes", []zipMember{{prefix + "a", 0644, "x"}}, Limits{MaxTotalBytes: 10}, ErrLimitExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := serveZip(t, buildZip(t, tt.members))
+			r := &Resolver{Client: server.Client(), ProxyURL: server.URL, Limits: tt.limits}
+			dep := r.Resolve(Module{Path: "github.com/example/one", Version: "v1.2.3"})
+			if !errors.Is(dep.Err, tt.want) {
+				t.Errorf("Resolve() error = %v, want %v", dep.Err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveMissing(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	r := &Resolver{Client: server.Client(), ProxyURL: server.URL}
+	dep := r.Resolve(Module{Path: "github.com/example/missing", Version: "v1.0.0"})
+	if dep.Err == nil {
+		t.Error("Resolve() expected error for missing module")
+	}
+	if dep.Repository != "https://github.com/example/missing" {
+		t.Errorf("Repository = %v", dep.Repository)
+	}
+}
+
+func TestReadBinary(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skipf("cannot locate test binary: %v", err)
+	}
+
+	modules, err := ReadBinary(exe)
+	if err != nil {
+		t.Fatalf("ReadBinary() error = %v", err)
+	}
+	for _, m := range modules {
+		if m.Path == "" || m.Version == "" {
+			t.Errorf("ReadBinary() returned incomplete module %+v", m)
+		}
+	}
+}
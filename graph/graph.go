@@ -0,0 +1,351 @@
+// Package graph models Software Heritage objects and the typed edges
+// between them: snapshots point at revisions and releases, releases at their
+// targets, revisions at their directory and parents, and directories at
+// their entries.
+//
+// A Graph is built from a Git repository with FromRepository and can be
+// traversed with Walk, Ancestors and ReachableContents.
+package graph
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/objects"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+)
+
+// EdgeType identifies the relation an edge represents.
+type EdgeType int
+
+const (
+	SnapshotBranch    EdgeType = iota // snp → target of a branch; Label is the branch name
+	ReleaseTarget                     // rel → its target object
+	RevisionDirectory                 // rev → its root directory
+	RevisionParent                    // rev → a parent revision
+	DirectoryEntry                    // dir → entry; Label is the entry name, Perms its mode
+)
+
+func (t EdgeType) String() string {
+	switch t {
+	case SnapshotBranch:
+		return "snapshot-branch"
+	case ReleaseTarget:
+		return "release-target"
+	case RevisionDirectory:
+		return "revision-directory"
+	case RevisionParent:
+		return "revision-parent"
+	case DirectoryEntry:
+		return "directory-entry"
+	default:
+		return "unknown"
+	}
+}
+
+// Edge is a typed, optionally labelled link between two nodes.
+type Edge struct {
+	Type  EdgeType
+	From  *Node
+	To    *Node
+	Label string // branch or entry name; empty for unlabelled edges
+	Perms string // entry mode in octal for DirectoryEntry edges, e.g. "100644"
+}
+
+// Node is an object in the graph.
+type Node struct {
+	ID    *swhid.Identifier
+	Edges []*Edge // outgoing edges in insertion order
+
+	// Size is the length of a content, or the total length of the contents
+	// in a directory's subtree with an object that appears under several
+	// names counted for each of them. It is zero for other objects.
+	Size int64
+
+	// External is set for objects that are referenced but not stored in the
+	// repository, such as submodule commits.
+	External bool
+}
+
+// Graph is an in-memory object graph.
+type Graph struct {
+	nodes map[string]*Node
+
+	// Snapshot is the snapshot node the graph was built from, if any.
+	Snapshot *Node
+}
+
+// New returns an empty graph.
+func New() *Graph {
+	return &Graph{nodes: make(map[string]*Node)}
+}
+
+// Node returns the node for a SWHID (qualifiers are ignored), or nil.
+func (g *Graph) Node(id *swhid.Identifier) *Node {
+	return g.nodes[id.CoreSWHID()]
+}
+
+// Len returns the number of nodes.
+func (g *Graph) Len() int {
+	return len(g.nodes)
+}
+
+// Nodes returns all nodes sorted by SWHID.
+func (g *Graph) Nodes() []*Node {
+	nodes := make([]*Node, 0, len(g.nodes))
+	for _, n := range g.nodes {
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].ID.CoreSWHID() < nodes[j].ID.CoreSWHID()
+	})
+	return nodes
+}
+
+// Add returns the node for id, creating it if needed. The boolean reports
+// whether the node was created.
+func (g *Graph) Add(id *swhid.Identifier) (*Node, bool) {
+	key := id.CoreSWHID()
+	if n, ok := g.nodes[key]; ok {
+		return n, false
+	}
+	n := &Node{ID: id.WithQualifiers(nil)}
+	g.nodes[key] = n
+	return n, true
+}
+
+// Link adds an edge between two nodes.
+func (g *Graph) Link(edgeType EdgeType, from, to *Node, label, perms string) *Edge {
+	e := &Edge{Type: edgeType, From: from, To: to, Label: label, Perms: perms}
+	from.Edges = append(from.Edges, e)
+	return e
+}
+
+// Walk visits the nodes reachable from start, each once, in depth-first
+// order. Outgoing edges of a node are not followed when fn returns false.
+func (g *Graph) Walk(start *Node, fn func(*Node) bool) {
+	seen := map[*Node]bool{start: true}
+	stack := []*Node{start}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !fn(n) {
+			continue
+		}
+		for i := len(n.Edges) - 1; i >= 0; i-- {
+			to := n.Edges[i].To
+			if !seen[to] {
+				seen[to] = true
+				stack = append(stack, to)
+			}
+		}
+	}
+}
+
+// Ancestors returns the revisions reachable from rev through parent edges,
+// excluding rev itself.
+func (g *Graph) Ancestors(rev *Node) []*Node {
+	var ancestors []*Node
+	seen := map[*Node]bool{rev: true}
+	queue := []*Node{rev}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, e := range n.Edges {
+			if e.Type != RevisionParent || seen[e.To] {
+				continue
+			}
+			seen[e.To] = true
+			ancestors = append(ancestors, e.To)
+			queue = append(queue, e.To)
+		}
+	}
+	return ancestors
+}
+
+// ReachableContents returns every content node reachable from start,
+// sorted by SWHID.
+func (g *Graph) ReachableContents(start *Node) []*Node {
+	var contents []*Node
+	g.Walk(start, func(n *Node) bool {
+		if n.ID.ObjectType == swhid.ObjectTypeContent {
+			contents = append(contents, n)
+		}
+		return true
+	})
+	sort.Slice(contents, func(i, j int) bool {
+		return contents[i].ID.ObjectHash < contents[j].ID.ObjectHash
+	})
+	return contents
+}
+
+// FromRepository builds the graph of the repository at repoPath: its
+// snapshot and every release, revision, directory and content reachable
+// from it.
+func FromRepository(repoPath string) (*Graph, error) {
+	return FromRepositoryWithOptions(repoPath, swhid.GitOptions{})
+}
+
+// FromRepositoryWithOptions is FromRepository opening the repository as
+// swhid.OpenRepoWithOptions does, with the objects it borrows through
+// alternates and, with opts.ReadOnlyFS, through filesystems that refuse
+// writes.
+func FromRepositoryWithOptions(repoPath string, opts swhid.GitOptions) (*Graph, error) {
+	session, err := swhid.OpenRepoWithOptions(repoPath, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return FromRepo(session.Repository())
+}
+
+// FromRepo is like FromRepository for an already open repository.
+func FromRepo(repo *git.Repository) (*Graph, error) {
+	branches, err := swhid.SnapshotBranchesRepo(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &builder{repo: repo, revisions: swhid.OpenRevisionGraph(repo), graph: New()}
+	defer b.revisions.Close()
+	snp, _ := b.graph.Add(swhid.FromSnapshotBranches(branches))
+	b.graph.Snapshot = snp
+
+	snapshot := objects.NewSnapshot(branches)
+	for _, branch := range branches {
+		target, err := snapshot.Resolve(branch.Name)
+		if err != nil || target.TargetType == objects.BranchTargetDangling {
+			continue
+		}
+		id, err := swhid.NewIdentifier(branchObjectTypes[target.TargetType], target.Target, nil)
+		if err != nil {
+			return nil, err
+		}
+		b.graph.Link(SnapshotBranch, snp, b.visit(id), branch.Name, "")
+	}
+
+	for len(b.pending) > 0 {
+		n := b.pending[len(b.pending)-1]
+		b.pending = b.pending[:len(b.pending)-1]
+		if err := b.expand(n); err != nil {
+			return nil, err
+		}
+	}
+
+	sized := make(map[*Node]bool)
+	for _, n := range b.graph.nodes {
+		if n.ID.ObjectType == swhid.ObjectTypeDirectory {
+			directorySize(n, sized)
+		}
+	}
+
+	return b.graph, nil
+}
+
+type builder struct {
+	repo      *git.Repository
+	revisions *swhid.RevisionGraph // commit-graph backed parent lookups
+	graph     *Graph
+	pending   []*Node // nodes whose outgoing edges are not added yet
+}
+
+// visit returns the node for id, queueing new nodes for expansion.
+func (b *builder) visit(id *swhid.Identifier) *Node {
+	n, created := b.graph.Add(id)
+	if created {
+		b.pending = append(b.pending, n)
+	}
+	return n
+}
+
+func (b *builder) expand(n *Node) error {
+	hash := plumbing.NewHash(n.ID.ObjectHash)
+
+	switch n.ID.ObjectType {
+	case swhid.ObjectTypeRelease:
+		tag, err := b.repo.TagObject(hash)
+		if err != nil {
+			return fmt.Errorf("failed to read release %s: %w", hash, err)
+		}
+		b.graph.Link(ReleaseTarget, n, b.visit(gitObjectID(tag.TargetType, tag.Target)), "", "")
+
+	case swhid.ObjectTypeRevision:
+		rev, err := b.revisions.Revision(hash)
+		if err != nil {
+			n.External = true
+			return nil
+		}
+		b.graph.Link(RevisionDirectory, n, b.visit(rev.Directory), "", "")
+		for _, parent := range rev.Parents {
+			b.graph.Link(RevisionParent, n, b.visit(parent), "", "")
+		}
+
+	case swhid.ObjectTypeContent:
+		obj, err := b.repo.Storer.EncodedObject(plumbing.BlobObject, hash)
+		if err != nil {
+			n.External = true
+			return nil
+		}
+		n.Size = obj.Size()
+
+	case swhid.ObjectTypeDirectory:
+		tree, err := b.repo.TreeObject(hash)
+		if err != nil {
+			return fmt.Errorf("failed to read directory %s: %w", hash, err)
+		}
+		for _, entry := range tree.Entries {
+			objType := plumbing.BlobObject
+			switch entry.Mode {
+			case filemode.Dir:
+				objType = plumbing.TreeObject
+			case filemode.Submodule:
+				objType = plumbing.CommitObject
+			}
+			b.graph.Link(DirectoryEntry, n, b.visit(gitObjectID(objType, entry.Hash)), entry.Name, fmt.Sprintf("%o", uint32(entry.Mode)))
+		}
+	}
+
+	return nil
+}
+
+// directorySize sums the sizes of a directory's entries, computing those of
+// subdirectories first. Directories shared between trees are computed once.
+func directorySize(n *Node, sized map[*Node]bool) int64 {
+	if sized[n] {
+		return n.Size
+	}
+	var size int64
+	for _, e := range n.Edges {
+		if e.To.ID.ObjectType == swhid.ObjectTypeDirectory {
+			size += directorySize(e.To, sized)
+		} else {
+			size += e.To.Size
+		}
+	}
+	n.Size = size
+	sized[n] = true
+	return size
+}
+
+var branchObjectTypes = map[objects.BranchTargetType]swhid.ObjectType{
+	objects.BranchTargetContent:   swhid.ObjectTypeContent,
+	objects.BranchTargetDirectory: swhid.ObjectTypeDirectory,
+	objects.BranchTargetRevision:  swhid.ObjectTypeRevision,
+	objects.BranchTargetRelease:   swhid.ObjectTypeRelease,
+	objects.BranchTargetSnapshot:  swhid.ObjectTypeSnapshot,
+}
+
+var gitObjectTypes = map[plumbing.ObjectType]swhid.ObjectType{
+	plumbing.BlobObject:   swhid.ObjectTypeContent,
+	plumbing.TreeObject:   swhid.ObjectTypeDirectory,
+	plumbing.CommitObject: swhid.ObjectTypeRevision,
+	plumbing.TagObject:    swhid.ObjectTypeRelease,
+}
+
+func gitObjectID(t plumbing.ObjectType, hash plumbing.Hash) *swhid.Identifier {
+	id, _ := swhid.NewIdentifier(gitObjectTypes[t], hash.String(), nil)
+	return id
+}
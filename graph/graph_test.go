@@ -0,0 +1,210 @@
+package graph
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andrew/swhid-go"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func commitFile(t *testing.T, repo *git.Repository, dir, name, content string) plumbing.Hash {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+	if _, err := wt.Add(name); err != nil {
+		t.Fatalf("Failed to stage file: %v", err)
+	}
+	sig := &object.Signature{Name: "Test", Email: "test@example.com", When: time.Unix(1000000000, 0).UTC()}
+	hash, err := wt.Commit("Add "+name+"\n", &git.CommitOptions{Author: sig, Committer: sig})
+	if err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	return hash
+}
+
+func TestFromRepository(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "swhid-graph-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	repo, err := git.PlainInit(tmpDir, false)
+	if err != nil {
+		t.Fatalf("Failed to init repository: %v", err)
+	}
+
+	first := commitFile(t, repo, tmpDir, "hello.txt", "hello\n")
+	second := commitFile(t, repo, tmpDir, "src/main.go", "package main\n")
+
+	sig := &object.Signature{Name: "Test", Email: "test@example.com", When: time.Unix(1000000000, 0).UTC()}
+	tag, err := repo.CreateTag("v1.0.0", second, &git.CreateTagOptions{Tagger: sig, Message: "v1.0.0\n"})
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	g, err := FromRepository(tmpDir)
+	if err != nil {
+		t.Fatalf("FromRepository() error = %v", err)
+	}
+
+	if g.Snapshot == nil {
+		t.Fatal("Snapshot node not set")
+	}
+
+	relID, _ := swhid.NewIdentifier(swhid.ObjectTypeRelease, tag.Hash().String(), nil)
+	rel := g.Node(relID)
+	if rel == nil {
+		t.Fatalf("release %s not in graph", relID)
+	}
+	if len(rel.Edges) != 1 || rel.Edges[0].Type != ReleaseTarget || rel.Edges[0].To.ID.ObjectHash != second.String() {
+		t.Errorf("release edges = %+v, want one ReleaseTarget to %s", rel.Edges, second)
+	}
+
+	revID, _ := swhid.NewIdentifier(swhid.ObjectTypeRevision, second.String(), nil)
+	rev := g.Node(revID)
+	ancestors := g.Ancestors(rev)
+	if len(ancestors) != 1 || ancestors[0].ID.ObjectHash != first.String() {
+		t.Errorf("Ancestors() = %v, want [%s]", ancestors, first)
+	}
+
+	contents := g.ReachableContents(rev)
+	if len(contents) != 2 {
+		t.Errorf("ReachableContents() returned %d contents, want 2", len(contents))
+	}
+
+	var entryNames []string
+	for _, e := range rev.Edges {
+		if e.Type != RevisionDirectory {
+			continue
+		}
+		for _, entry := range e.To.Edges {
+			if entry.Type != DirectoryEntry {
+				t.Errorf("directory edge type = %v, want %v", entry.Type, DirectoryEntry)
+			}
+			entryNames = append(entryNames, entry.Label+":"+entry.Perms)
+		}
+	}
+	if len(entryNames) != 2 || entryNames[0] != "hello.txt:100644" || entryNames[1] != "src:40000" {
+		t.Errorf("root directory entries = %v", entryNames)
+	}
+}
+
+func TestFromRepositoryWithOptionsAlternates(t *testing.T) {
+	baseDir := t.TempDir()
+	base, err := git.PlainInit(baseDir, false)
+	if err != nil {
+		t.Fatalf("Failed to init repository: %v", err)
+	}
+	head := commitFile(t, base, baseDir, "hello.txt", "hello\n")
+
+	// A bare repository borrowing every object from base through a
+	// relative alternates path, as git clone --shared sets up
+	sharedDir := t.TempDir()
+	shared, err := git.PlainInit(sharedDir, true)
+	if err != nil {
+		t.Fatalf("Failed to init shared repository: %v", err)
+	}
+	rel, err := filepath.Rel(filepath.Join(sharedDir, "objects"), filepath.Join(baseDir, ".git", "objects"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sharedDir, "objects", "info", "alternates"), []byte(filepath.ToSlash(rel)+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write alternates: %v", err)
+	}
+	if err := shared.Storer.SetReference(plumbing.NewHashReference("refs/heads/master", head)); err != nil {
+		t.Fatalf("Failed to set reference: %v", err)
+	}
+
+	want, err := FromRepository(baseDir)
+	if err != nil {
+		t.Fatalf("FromRepository() error = %v", err)
+	}
+	for _, readOnly := range []bool{false, true} {
+		g, err := FromRepositoryWithOptions(sharedDir, swhid.GitOptions{ReadOnlyFS: readOnly})
+		if err != nil {
+			t.Fatalf("FromRepositoryWithOptions(ReadOnlyFS: %v) error = %v", readOnly, err)
+		}
+		if len(g.Nodes()) != len(want.Nodes()) {
+			t.Errorf("FromRepositoryWithOptions(ReadOnlyFS: %v) has %d nodes, want %d", readOnly, len(g.Nodes()), len(want.Nodes()))
+		}
+	}
+}
+
+func TestNodeSizes(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("Failed to init repository: %v", err)
+	}
+
+	commitFile(t, repo, dir, "a/x.txt", "hello\n")
+	commitFile(t, repo, dir, "b/x.txt", "hello\n")
+	commitFile(t, repo, dir, "top.txt", "0123456789")
+
+	g, err := FromRepo(repo)
+	if err != nil {
+		t.Fatalf("FromRepo() error = %v", err)
+	}
+
+	head, _ := repo.Head()
+	commit, _ := repo.CommitObject(head.Hash())
+	root := g.Node(mustParse(t, "swh:1:dir:"+commit.TreeHash.String()))
+	if root == nil {
+		t.Fatal("root directory not in graph")
+	}
+	// The two x.txt files are the same object but both count.
+	if root.Size != 22 {
+		t.Errorf("root Size = %d, want 22", root.Size)
+	}
+
+	cnt := g.Node(swhid.FromContent([]byte("hello\n")))
+	if cnt == nil || cnt.Size != 6 {
+		t.Errorf("content node = %+v, want Size 6", cnt)
+	}
+	if g.Snapshot.Size != 0 {
+		t.Errorf("snapshot Size = %d, want 0", g.Snapshot.Size)
+	}
+}
+
+func TestWalkSkipsChildren(t *testing.T) {
+	g := New()
+	a, _ := g.Add(mustParse(t, "swh:1:dir:d198bc9d7a6bcf6db04f476d29314f157507d505"))
+	b, _ := g.Add(mustParse(t, "swh:1:dir:4b825dc642cb6eb9a060e54bf8d69288fbee4904"))
+	c, _ := g.Add(mustParse(t, "swh:1:cnt:ce013625030ba8dba906f756967f9e9ca394464a"))
+	g.Link(DirectoryEntry, a, b, "sub", "40000")
+	g.Link(DirectoryEntry, b, c, "file", "100644")
+
+	var visited []*Node
+	g.Walk(a, func(n *Node) bool {
+		visited = append(visited, n)
+		return n != b
+	})
+	if len(visited) != 2 {
+		t.Errorf("Walk() visited %d nodes, want 2", len(visited))
+	}
+}
+
+func mustParse(t *testing.T, s string) *swhid.Identifier {
+	t.Helper()
+	id, err := swhid.Parse(s)
+	if err != nil {
+		t.Fatalf("Parse(%q) error = %v", s, err)
+	}
+	return id
+}
@@ -16,6 +16,16 @@ func FromDirectory(entries []objects.DirectoryEntry) *Identifier {
 	return id
 }
 
+// FromDirectoryStrict computes the SWHID for a directory after validating
+// its entries. The error is an *objects.EntryError naming the bad entry.
+func FromDirectoryStrict(entries []objects.DirectoryEntry) (*Identifier, error) {
+	hash, err := objects.ComputeDirectoryHashStrict(entries)
+	if err != nil {
+		return nil, err
+	}
+	return NewIdentifier(ObjectTypeDirectory, hash, nil)
+}
+
 // FromRevisionMetadata computes the SWHID for a revision with the given metadata.
 func FromRevisionMetadata(meta objects.RevisionMetadata) *Identifier {
 	hash := objects.ComputeRevisionHash(meta)
@@ -36,3 +46,24 @@ func FromSnapshotBranches(branches []objects.Branch) *Identifier {
 	id, _ := NewIdentifier(ObjectTypeSnapshot, hash, nil)
 	return id
 }
+
+// FromSnapshotBranchesStrict computes the SWHID for a snapshot after
+// validating its branches. The error is an *objects.BranchError.
+func FromSnapshotBranchesStrict(branches []objects.Branch) (*Identifier, error) {
+	hash, err := objects.ComputeSnapshotHashStrict(branches)
+	if err != nil {
+		return nil, err
+	}
+	return NewIdentifier(ObjectTypeSnapshot, hash, nil)
+}
+
+// FromRawObject computes the SWHID of a raw headered object, such as the
+// uncompressed content of a Git loose object. The type is taken from the
+// header; see objects.IdentifyRaw.
+func FromRawObject(data []byte) (*Identifier, error) {
+	t, hash, err := objects.IdentifyRaw(data)
+	if err != nil {
+		return nil, err
+	}
+	return NewIdentifier(ObjectType(t), hash, nil)
+}
@@ -105,3 +105,17 @@ func TestFromSnapshotBranches(t *testing.T) {
 		t.Errorf("FromSnapshotBranches() hash length = %d, want 40", len(id.ObjectHash))
 	}
 }
+
+func TestFromRawObject(t *testing.T) {
+	id, err := FromRawObject([]byte("blob 6\x00hello\n"))
+	if err != nil {
+		t.Fatalf("FromRawObject() error = %v", err)
+	}
+	if !id.Equal(FromContent([]byte("hello\n"))) {
+		t.Errorf("FromRawObject() = %v, want %v", id, FromContent([]byte("hello\n")))
+	}
+
+	if _, err := FromRawObject([]byte("blob 7\x00hello\n")); err == nil {
+		t.Error("FromRawObject() accepted a size mismatch")
+	}
+}
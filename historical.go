@@ -0,0 +1,389 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andrew/swhid-go/objects"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// FromSnapshotAt computes the snapshot SWHID of the repository at repoPath
+// as it was at the given time, such as the date of a Software Heritage
+// visit. See SnapshotBranchesAt for how past references are recovered.
+func FromSnapshotAt(repoPath string, at time.Time, opts GitOptions) (*Identifier, error) {
+	branches, err := SnapshotBranchesAt(repoPath, at, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return FromSnapshotBranches(branches), nil
+}
+
+// FromSnapshotRefs computes the snapshot SWHID of a recorded set of
+// references, resolving their targets in the repository at repoPath. See
+// SnapshotBranchesFromRefs.
+func FromSnapshotRefs(repoPath string, refs []*plumbing.Reference, opts GitOptions) (*Identifier, error) {
+	repo, err := openRepository(repoPath, false, opts.ReadOnlyFS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	branches, err := SnapshotBranchesFromRefs(repo, refs, opts)
+	if err != nil {
+		return nil, err
+	}
+	return FromSnapshotBranches(branches), nil
+}
+
+// SnapshotBranchesAt returns the branches the repository at repoPath had
+// at the given time, reconstructed from its reflogs: each reference takes
+// the last value its reflog recorded at or before that time and is left
+// out if it was created later or had been deleted. References without a
+// reflog, which git does not keep for tags by default, keep their current
+// value unless it points at a commit or tag dated after the given time.
+// HEAD follows the last checkout recorded in its reflog. Reflogs expire
+// (90 days by default), so older dates need a ref snapshot file; see
+// ReadRefSnapshot.
+func SnapshotBranchesAt(repoPath string, at time.Time, opts GitOptions) ([]objects.Branch, error) {
+	repo, err := openRepository(repoPath, false, opts.ReadOnlyFS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	return SnapshotBranchesAtRepo(repo, at, opts)
+}
+
+// SnapshotBranchesAtRepo is like SnapshotBranchesAt for an already open
+// repository.
+func SnapshotBranchesAtRepo(repo *git.Repository, at time.Time, opts GitOptions) ([]objects.Branch, error) {
+	refs, err := refsAt(repo, at)
+	if err != nil {
+		return nil, err
+	}
+
+	return SnapshotBranchesFromRefs(repo, refs, opts)
+}
+
+// SnapshotBranchesFromRefs returns the snapshot branches for a recorded
+// set of references, such as one read with ReadRefSnapshot, resolving
+// their targets in repo. A HEAD entry becomes the snapshot's HEAD unless
+// opts.Head overrides it; opts.Refs and opts.Refspecs select the other
+// references, and opts.Branches adds to them. Objects missing from repo
+// are listed as revisions.
+func SnapshotBranchesFromRefs(repo *git.Repository, refs []*plumbing.Reference, opts GitOptions) ([]objects.Branch, error) {
+	resolve := func(hash plumbing.Hash) (objects.BranchTargetType, string) {
+		return resolveRefTarget(repo, hash)
+	}
+
+	refMap, err := newRefMapper(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var head *plumbing.Reference
+	var branches []objects.Branch
+	for _, ref := range refs {
+		if ref.Name() == plumbing.HEAD {
+			head = ref
+			continue
+		}
+		if refMap.includes(ref.Name().String()) {
+			branches = append(branches, snapshotBranch(ref, resolve))
+		}
+	}
+
+	if opts.Head != "" {
+		head = headOverride(opts.Head)
+	}
+	if head != nil {
+		branches = append([]objects.Branch{snapshotBranch(head, resolve)}, branches...)
+	}
+	return refMap.finish(branches), nil
+}
+
+// ReadRefSnapshot parses a list of references recorded at some point, for
+// example when a mirror was fetched. It accepts the output of git
+// show-ref, git for-each-ref and git ls-remote --symref, as well as
+// packed-refs files: one "<hash> <name>" or "<hash> <type>\t<name>" line
+// per reference, and "ref: <target>\t<name>" for symbolic references.
+// Blank lines, comments and peeled "^<hash>" lines are skipped. When a
+// name is listed both ways, as ls-remote --symref does for HEAD, the
+// symbolic entry wins.
+func ReadRefSnapshot(r io.Reader) ([]*plumbing.Reference, error) {
+	var refs []*plumbing.Reference
+	index := make(map[plumbing.ReferenceName]int)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, "^") {
+			continue
+		}
+
+		var ref *plumbing.Reference
+		fields := strings.Fields(text)
+		switch {
+		case fields[0] == "ref:" && len(fields) == 3:
+			ref = plumbing.NewSymbolicReference(plumbing.ReferenceName(fields[2]), plumbing.ReferenceName(fields[1]))
+		case plumbing.IsHash(fields[0]) && (len(fields) == 2 || len(fields) == 3):
+			ref = plumbing.NewHashReference(plumbing.ReferenceName(fields[len(fields)-1]), plumbing.NewHash(fields[0]))
+		default:
+			return nil, fmt.Errorf("ref snapshot line %d: expected \"<hash> <name>\" or \"ref: <target> <name>\"", line)
+		}
+
+		if i, ok := index[ref.Name()]; ok {
+			if refs[i].Type() != plumbing.SymbolicReference {
+				refs[i] = ref
+			}
+			continue
+		}
+		index[ref.Name()] = len(refs)
+		refs = append(refs, ref)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ref snapshot: %w", err)
+	}
+
+	return refs, nil
+}
+
+// reflogEntry is one line of a reflog: the values a reference changed
+// from and to, when, and why.
+type reflogEntry struct {
+	Old     plumbing.Hash
+	New     plumbing.Hash
+	When    time.Time
+	Message string
+}
+
+// refsAt reconstructs the repository's references at the given time.
+func refsAt(repo *git.Repository, at time.Time) ([]*plumbing.Reference, error) {
+	storage, ok := repo.Storer.(*filesystem.Storage)
+	if !ok {
+		return nil, fmt.Errorf("reflogs are only available for repositories on disk")
+	}
+	fs := storage.Filesystem()
+
+	readLog := func(name string) ([]reflogEntry, bool, error) {
+		f, err := fs.Open(path.Join("logs", name))
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to open reflog of %s: %w", name, err)
+		}
+		defer f.Close()
+
+		entries, err := parseReflog(f, at)
+		if err != nil {
+			return nil, false, fmt.Errorf("reflog of %s: %w", name, err)
+		}
+		return entries, true, nil
+	}
+
+	// Current references, plus any reflog whose reference is gone.
+	names := make(map[plumbing.ReferenceName]*plumbing.Reference)
+	var order []plumbing.ReferenceName
+	iter, err := repo.References()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get references: %w", err)
+	}
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Name() != plumbing.HEAD {
+			names[ref.Name()] = ref
+			order = append(order, ref.Name())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to iterate references: %w", err)
+	}
+	logged, err := reflogNames(fs, "logs/refs")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range logged {
+		if _, ok := names[name]; !ok {
+			names[name] = nil
+			order = append(order, name)
+		}
+	}
+
+	var refs []*plumbing.Reference
+	existing := make(map[plumbing.ReferenceName]bool)
+	for _, name := range order {
+		current := names[name]
+		entries, hasLog, err := readLog(name.String())
+		if err != nil {
+			return nil, err
+		}
+
+		var ref *plumbing.Reference
+		switch {
+		case hasLog && (len(entries) == 0 || entries[len(entries)-1].New.IsZero()):
+			continue
+		case current != nil && current.Type() == plumbing.SymbolicReference:
+			// The reflog of a symbolic reference records what it resolved
+			// to; the reference itself is kept as an alias.
+			ref = current
+		case hasLog:
+			ref = plumbing.NewHashReference(name, entries[len(entries)-1].New)
+		case objectTime(repo, current.Hash()).After(at):
+			continue
+		default:
+			ref = current
+		}
+		refs = append(refs, ref)
+		existing[name] = true
+	}
+
+	head, err := headAt(repo, at, existing, readLog)
+	if err != nil {
+		return nil, err
+	}
+	if head != nil {
+		refs = append([]*plumbing.Reference{head}, refs...)
+	}
+	return refs, nil
+}
+
+// headAt works out HEAD at the given time: an alias to the branch of the
+// last checkout recorded before it, or the commit HEAD's reflog recorded
+// when it was detached. Without checkouts in the reflog the current
+// symbolic HEAD is kept.
+func headAt(repo *git.Repository, at time.Time, existing map[plumbing.ReferenceName]bool, readLog func(string) ([]reflogEntry, bool, error)) (*plumbing.Reference, error) {
+	current, err := repo.Storer.Reference(plumbing.HEAD)
+	if err != nil && err != plumbing.ErrReferenceNotFound {
+		return nil, fmt.Errorf("failed to read HEAD: %w", err)
+	}
+
+	entries, _, err := readLog("HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		const prefix = "checkout: moving from "
+		if !strings.HasPrefix(entries[i].Message, prefix) {
+			continue
+		}
+		_, to, ok := strings.Cut(strings.TrimPrefix(entries[i].Message, prefix), " to ")
+		if !ok {
+			continue
+		}
+		branch := plumbing.NewBranchReferenceName(to)
+		if existing[branch] {
+			return plumbing.NewSymbolicReference(plumbing.HEAD, branch), nil
+		}
+		return detachedHead(entries), nil
+	}
+
+	if current != nil && current.Type() == plumbing.SymbolicReference {
+		return current, nil
+	}
+	return detachedHead(entries), nil
+}
+
+func detachedHead(entries []reflogEntry) *plumbing.Reference {
+	if len(entries) == 0 || entries[len(entries)-1].New.IsZero() {
+		return nil
+	}
+	return plumbing.NewHashReference(plumbing.HEAD, entries[len(entries)-1].New)
+}
+
+// parseReflog reads reflog lines of the form
+//
+//	<old> <new> <name> <<email>> <unix time> <zone>\t<message>
+//
+// and returns the entries made at or before the given time, or all of them
+// for the zero time, oldest first.
+func parseReflog(r io.Reader, at time.Time) ([]reflogEntry, error) {
+	var entries []reflogEntry
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if text == "" {
+			continue
+		}
+
+		header, message, _ := strings.Cut(text, "\t")
+		fields := strings.Fields(header)
+		end := strings.LastIndexByte(header, '>')
+		if len(fields) < 2 || !plumbing.IsHash(fields[0]) || !plumbing.IsHash(fields[1]) || end < 0 {
+			return nil, fmt.Errorf("line %d: malformed entry", line)
+		}
+		stamp := strings.Fields(header[end+1:])
+		if len(stamp) < 1 {
+			return nil, fmt.Errorf("line %d: missing timestamp", line)
+		}
+		seconds, err := strconv.ParseInt(stamp[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid timestamp %q", line, stamp[0])
+		}
+
+		when := time.Unix(seconds, 0)
+		if !at.IsZero() && when.After(at) {
+			continue
+		}
+		entries = append(entries, reflogEntry{Old: plumbing.NewHash(fields[0]), New: plumbing.NewHash(fields[1]), When: when, Message: message})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// reflogNames lists the references that have a reflog under dir.
+func reflogNames(fs interface {
+	ReadDir(string) ([]os.FileInfo, error)
+}, dir string) ([]plumbing.ReferenceName, error) {
+	infos, err := fs.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reflogs: %w", err)
+	}
+
+	var names []plumbing.ReferenceName
+	for _, info := range infos {
+		p := path.Join(dir, info.Name())
+		if info.IsDir() {
+			sub, err := reflogNames(fs, p)
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, sub...)
+			continue
+		}
+		names = append(names, plumbing.ReferenceName(strings.TrimPrefix(p, "logs/")))
+	}
+	return names, nil
+}
+
+// objectTime returns the committer date of a commit or the tagger date of
+// a tag, and the zero time for other or missing objects.
+func objectTime(repo *git.Repository, hash plumbing.Hash) time.Time {
+	if commit, err := repo.CommitObject(hash); err == nil {
+		return commit.Committer.When
+	}
+	if tag, err := repo.TagObject(hash); err == nil {
+		return tag.Tagger.When
+	}
+	return time.Time{}
+}
@@ -0,0 +1,164 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrew/swhid-go/objects"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// writeReflog writes a reflog for name with one entry per update; each
+// update is a new hash and the unix time it was made.
+func writeReflog(t *testing.T, repoPath, name string, updates ...interface{}) {
+	t.Helper()
+
+	var b strings.Builder
+	old := plumbing.ZeroHash
+	for i := 0; i < len(updates); i += 3 {
+		hash, when, message := updates[i].(plumbing.Hash), updates[i+1].(int64), updates[i+2].(string)
+		fmt.Fprintf(&b, "%s %s Test <test@example.com> %d +0000\t%s\n", old, hash, when, message)
+		old = hash
+	}
+
+	path := filepath.Join(repoPath, ".git", "logs", filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+}
+
+func branchMap(branches []objects.Branch) map[string]objects.Branch {
+	m := make(map[string]objects.Branch, len(branches))
+	for _, b := range branches {
+		m[b.Name] = b
+	}
+	return m
+}
+
+func TestSnapshotBranchesAt(t *testing.T) {
+	repoPath, repo, first := newTestRepo(t)
+	if err := os.WriteFile(filepath.Join(repoPath, "second.txt"), []byte("second\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	second := commitAll(t, repo, "Second commit\n")
+
+	// A lightweight tag without a reflog, at a commit dated 1000000000.
+	if err := repo.Storer.SetReference(plumbing.NewHashReference("refs/tags/v1", second)); err != nil {
+		t.Fatalf("SetReference() error = %v", err)
+	}
+
+	const base = int64(1000000000)
+	writeReflog(t, repoPath, "refs/heads/master",
+		first, base+10, "commit (initial): Initial commit",
+		second, base+100, "commit: Second commit")
+	writeReflog(t, repoPath, "refs/heads/feature",
+		first, base+50, "branch: Created from master",
+		plumbing.ZeroHash, base+150, "branch: deleted")
+	writeReflog(t, repoPath, "HEAD",
+		first, base+10, "commit (initial): Initial commit",
+		first, base+60, "checkout: moving from master to feature",
+		first, base+70, "checkout: moving from feature to master",
+		second, base+100, "commit: Second commit")
+
+	tests := []struct {
+		at   int64
+		want map[string]string // branch name to target; "->" prefix for aliases
+	}{
+		{base - 1, map[string]string{"HEAD": "->refs/heads/master"}},
+		{base + 20, map[string]string{"HEAD": "->refs/heads/master", "refs/heads/master": first.String(), "refs/tags/v1": second.String()}},
+		{base + 65, map[string]string{"HEAD": "->refs/heads/feature", "refs/heads/master": first.String(), "refs/heads/feature": first.String(), "refs/tags/v1": second.String()}},
+		{base + 200, map[string]string{"HEAD": "->refs/heads/master", "refs/heads/master": second.String(), "refs/tags/v1": second.String()}},
+	}
+
+	for _, tt := range tests {
+		branches, err := SnapshotBranchesAt(repoPath, time.Unix(tt.at, 0), GitOptions{})
+		if err != nil {
+			t.Fatalf("SnapshotBranchesAt(%d) error = %v", tt.at, err)
+		}
+
+		got := branchMap(branches)
+		if len(got) != len(tt.want) {
+			t.Errorf("SnapshotBranchesAt(%d) = %v, want %v", tt.at, branches, tt.want)
+			continue
+		}
+		for name, target := range tt.want {
+			b, ok := got[name]
+			switch {
+			case !ok:
+				t.Errorf("SnapshotBranchesAt(%d) missing %s", tt.at, name)
+			case strings.HasPrefix(target, "->"):
+				if b.TargetType != objects.BranchTargetAlias || b.Target != target[2:] {
+					t.Errorf("SnapshotBranchesAt(%d) %s = %+v, want alias to %s", tt.at, name, b, target[2:])
+				}
+			case b.Target != target:
+				t.Errorf("SnapshotBranchesAt(%d) %s = %s, want %s", tt.at, name, b.Target, target)
+			}
+		}
+	}
+
+	// After the last update the reconstruction matches the live snapshot.
+	id, err := FromSnapshotAt(repoPath, time.Unix(base+200, 0), GitOptions{})
+	if err != nil {
+		t.Fatalf("FromSnapshotAt() error = %v", err)
+	}
+	want, err := FromSnapshot(repoPath)
+	if err != nil {
+		t.Fatalf("FromSnapshot() error = %v", err)
+	}
+	if !id.Equal(want) {
+		t.Errorf("FromSnapshotAt() = %v, want %v", id, want)
+	}
+}
+
+func TestReadRefSnapshot(t *testing.T) {
+	repoPath, repo, first := newTestRepo(t)
+
+	// git ls-remote --symref output, with a packed-refs style peeled line.
+	input := "# recorded at visit\n" +
+		"ref: refs/heads/master\tHEAD\n" +
+		first.String() + "\tHEAD\n" +
+		first.String() + "\trefs/heads/master\n" +
+		"^" + first.String() + "\n"
+
+	refs, err := ReadRefSnapshot(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadRefSnapshot() error = %v", err)
+	}
+	if len(refs) != 2 {
+		t.Fatalf("ReadRefSnapshot() = %v, want 2 references", refs)
+	}
+	if refs[0].Type() != plumbing.SymbolicReference || refs[0].Target() != "refs/heads/master" {
+		t.Errorf("HEAD = %v, want symbolic reference to refs/heads/master", refs[0])
+	}
+
+	branches, err := SnapshotBranchesFromRefs(repo, refs, GitOptions{})
+	if err != nil {
+		t.Fatalf("SnapshotBranchesFromRefs() error = %v", err)
+	}
+	want, err := FromSnapshot(repoPath)
+	if err != nil {
+		t.Fatalf("FromSnapshot() error = %v", err)
+	}
+	if id := FromSnapshotBranches(branches); !id.Equal(want) {
+		t.Errorf("snapshot = %v, want %v", id, want)
+	}
+
+	// git for-each-ref output includes the object type.
+	refs, err = ReadRefSnapshot(strings.NewReader(first.String() + " commit\trefs/heads/main\n"))
+	if err != nil || len(refs) != 1 || refs[0].Name() != "refs/heads/main" {
+		t.Errorf("ReadRefSnapshot(for-each-ref) = %v, %v", refs, err)
+	}
+
+	if _, err := ReadRefSnapshot(strings.NewReader("not a ref line\n")); err == nil {
+		t.Error("ReadRefSnapshot() expected error for malformed line")
+	}
+}
@@ -0,0 +1,107 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// RevisionRecord describes one commit of a repository's history.
+type RevisionRecord struct {
+	ID                 *Identifier
+	Directory          *Identifier
+	Parents            []*Identifier
+	Author             string
+	AuthorTimestamp    int64
+	AuthorTimezone     string
+	Committer          string
+	CommitterTimestamp int64
+	CommitterTimezone  string
+	Message            string
+}
+
+// WalkHistory calls fn for every commit reachable from HEAD or any
+// reference of the repository at repoPath, each commit once, as it walks.
+// Annotated tags are followed to the commits they name. Identifiers are
+// taken from the commit and tree IDs, which equal the SWHIDs for SHA-1
+// repositories. Every commit is decoded from the object store, since the
+// author, committer and message are not in the commit-graph; use
+// WalkRevisionGraph when identifiers, parents and dates are enough. The
+// walk stops at the first error returned by fn.
+func WalkHistory(repoPath string, fn func(RevisionRecord) error) error {
+	repo, err := openRepository(repoPath, false, false)
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	return WalkHistoryRepo(repo, fn)
+}
+
+// WalkHistoryRepo is like WalkHistory for an already open repository.
+func WalkHistoryRepo(repo *git.Repository, fn func(RevisionRecord) error) error {
+	tips, err := commitTips(repo)
+	if err != nil {
+		return fmt.Errorf("failed to walk history: %w", err)
+	}
+
+	seen := make(map[plumbing.Hash]bool)
+	stack := make([]plumbing.Hash, 0, len(tips))
+	for i := len(tips) - 1; i >= 0; i-- {
+		stack = append(stack, tips[i])
+	}
+
+	for len(stack) > 0 {
+		hash := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+
+		commit, err := repo.CommitObject(hash)
+		if err == plumbing.ErrObjectNotFound {
+			// Parents missing from a shallow clone end the walk there;
+			// commitTips only returns commits that exist.
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read revision %s: %w", hash, err)
+		}
+		if err := fn(newRevisionRecord(commit)); err != nil {
+			return err
+		}
+		for i := len(commit.ParentHashes) - 1; i >= 0; i-- {
+			if parent := commit.ParentHashes[i]; !seen[parent] {
+				stack = append(stack, parent)
+			}
+		}
+	}
+	return nil
+}
+
+func newRevisionRecord(commit *object.Commit) RevisionRecord {
+	id, _ := NewIdentifier(ObjectTypeRevision, commit.Hash.String(), nil)
+	dir, _ := NewIdentifier(ObjectTypeDirectory, commit.TreeHash.String(), nil)
+
+	record := RevisionRecord{
+		ID:                 id,
+		Directory:          dir,
+		Parents:            []*Identifier{},
+		Author:             formatPerson(commit.Author),
+		AuthorTimestamp:    commit.Author.When.Unix(),
+		AuthorTimezone:     formatTimezone(commit.Author.When),
+		Committer:          formatPerson(commit.Committer),
+		CommitterTimestamp: commit.Committer.When.Unix(),
+		CommitterTimezone:  formatTimezone(commit.Committer.When),
+		Message:            commit.Message,
+	}
+	for _, parent := range commit.ParentHashes {
+		parentID, _ := NewIdentifier(ObjectTypeRevision, parent.String(), nil)
+		record.Parents = append(record.Parents, parentID)
+	}
+	return record
+}
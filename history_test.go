@@ -0,0 +1,133 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestWalkHistory(t *testing.T) {
+	repoPath, repo, first := newTestRepo(t)
+
+	if err := os.WriteFile(filepath.Join(repoPath, "second.txt"), []byte("second\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	second := commitAll(t, repo, "Second commit\n")
+
+	// A side branch pointing at the first commit must not produce duplicates.
+	if err := repo.Storer.SetReference(plumbing.NewHashReference("refs/heads/side", first)); err != nil {
+		t.Fatalf("SetReference() error = %v", err)
+	}
+
+	var records []RevisionRecord
+	err := WalkHistory(repoPath, func(r RevisionRecord) error {
+		records = append(records, r)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkHistory() error = %v", err)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("WalkHistory() visited %d commits, want 2", len(records))
+	}
+
+	byHash := map[string]RevisionRecord{}
+	for _, r := range records {
+		byHash[r.ID.ObjectHash] = r
+	}
+
+	r, ok := byHash[second.String()]
+	if !ok {
+		t.Fatalf("second commit %s not visited", second)
+	}
+	if len(r.Parents) != 1 || r.Parents[0].ObjectHash != first.String() {
+		t.Errorf("Parents = %v, want [%s]", r.Parents, first)
+	}
+
+	commit, _ := repo.CommitObject(second)
+	if r.Directory.ObjectHash != commit.TreeHash.String() {
+		t.Errorf("Directory = %v, want %v", r.Directory, commit.TreeHash)
+	}
+
+	// Identifiers must agree with the re-serializing FromRevision.
+	id, err := FromRevision(repoPath, second.String())
+	if err != nil {
+		t.Fatalf("FromRevision() error = %v", err)
+	}
+	if !id.Equal(r.ID) {
+		t.Errorf("ID = %v, want %v", r.ID, id)
+	}
+	if r.AuthorTimezone != "+0200" {
+		t.Errorf("AuthorTimezone = %v, want +0200", r.AuthorTimezone)
+	}
+}
+
+func TestWalkHistoryAnnotatedTag(t *testing.T) {
+	repoPath, repo, first := newTestRepo(t)
+
+	if err := os.WriteFile(filepath.Join(repoPath, "tagged.txt"), []byte("tagged\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	tagged := commitAll(t, repo, "Tagged commit\n")
+
+	// The tagged commit is only reachable through the annotated tag once
+	// the branch is moved back.
+	sig := &object.Signature{Name: "Test", Email: "test@example.com", When: time.Unix(1000000100, 0)}
+	if _, err := repo.CreateTag("v1", tagged, &git.CreateTagOptions{Tagger: sig, Message: "v1\n"}); err != nil {
+		t.Fatalf("CreateTag() error = %v", err)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference("refs/heads/master", first)); err != nil {
+		t.Fatalf("SetReference() error = %v", err)
+	}
+
+	visited := map[string]int{}
+	err := WalkHistory(repoPath, func(r RevisionRecord) error {
+		visited[r.ID.ObjectHash]++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkHistory() error = %v", err)
+	}
+	if len(visited) != 2 || visited[tagged.String()] != 1 || visited[first.String()] != 1 {
+		t.Errorf("WalkHistory() visited %v, want %s and %s once each", visited, tagged, first)
+	}
+}
+
+func TestWalkHistoryStops(t *testing.T) {
+	repoPath, repo, _ := newTestRepo(t)
+	if err := os.WriteFile(filepath.Join(repoPath, "second.txt"), []byte("second\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	second := commitAll(t, repo, "Second commit\n")
+
+	// Records are handed to fn as the walk reaches them, so an error from
+	// the first stops it before the parent is read.
+	stop := errors.New("stop")
+	var visited []string
+	err := WalkHistory(repoPath, func(r RevisionRecord) error {
+		visited = append(visited, r.ID.ObjectHash)
+		return stop
+	})
+	if err != stop {
+		t.Errorf("WalkHistory() error = %v, want %v", err, stop)
+	}
+	if len(visited) != 1 || visited[0] != second.String() {
+		t.Errorf("WalkHistory() visited %v, want only %s", visited, second)
+	}
+}
+
+func TestWalkHistoryNotRepo(t *testing.T) {
+	err := WalkHistory(os.TempDir(), func(RevisionRecord) error { return nil })
+	if err == nil {
+		t.Error("WalkHistory() expected error for non-repository")
+	}
+}
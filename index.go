@@ -0,0 +1,141 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/andrew/swhid-go/objects"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+)
+
+// ErrIndexConflict is returned when the Git index has unmerged entries.
+var ErrIndexConflict = errors.New("index has unresolved conflicts")
+
+// FromGitIndex computes the directory SWHID of what is currently staged in
+// the Git index of the repository at repoPath. This is the tree the next
+// commit would record, independent of unstaged worktree changes; files
+// only marked with git add -N are not part of it.
+func FromGitIndex(repoPath string) (*Identifier, error) {
+	repo, err := openRepository(repoPath, false, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	return FromGitIndexRepo(repo)
+}
+
+// FromGitIndexRepo is like FromGitIndex for an already open repository.
+func FromGitIndexRepo(repo *git.Repository) (*Identifier, error) {
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+	return fromIndex(idx)
+}
+
+// FromGitIndexFile is like FromGitIndex for the index file at indexPath,
+// such as the temporary index git commit -a and git commit PATHS name in
+// GIT_INDEX_FILE while their hooks run.
+func FromGitIndexFile(indexPath string) (*Identifier, error) {
+	f, err := os.Open(indexPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+	defer f.Close()
+
+	idx := &index.Index{}
+	if err := index.NewDecoder(bufio.NewReader(f)).Decode(idx); err != nil {
+		return nil, fmt.Errorf("failed to read index %s: %w", indexPath, err)
+	}
+	return fromIndex(idx)
+}
+
+func fromIndex(idx *index.Index) (*Identifier, error) {
+	root := newTreeNode()
+	for _, entry := range idx.Entries {
+		// Fully merged entries have stage 0; conflicts use stages 1-3.
+		if entry.Stage != 0 {
+			return nil, fmt.Errorf("%w: %s", ErrIndexConflict, entry.Name)
+		}
+		// Placeholders from git add -N; git leaves them out of the tree
+		if entry.IntentToAdd {
+			continue
+		}
+		root.add(strings.Split(entry.Name, "/"), objects.DirectoryEntry{
+			Type:   entryTypeForMode(entry.Mode),
+			Target: entry.Hash.String(),
+		})
+	}
+
+	return FromDirectory(root.entries()), nil
+}
+
+func entryTypeForMode(mode filemode.FileMode) objects.EntryType {
+	switch mode {
+	case filemode.Executable:
+		return objects.EntryTypeExecutable
+	case filemode.Symlink:
+		return objects.EntryTypeSymlink
+	case filemode.Submodule:
+		return objects.EntryTypeRevision
+	case filemode.Dir:
+		return objects.EntryTypeDirectory
+	default:
+		return objects.EntryTypeFile
+	}
+}
+
+// treeNode builds nested directory entries from a flat list of slash-separated paths.
+type treeNode struct {
+	files    map[string]objects.DirectoryEntry
+	children map[string]*treeNode
+}
+
+func newTreeNode() *treeNode {
+	return &treeNode{
+		files:    make(map[string]objects.DirectoryEntry),
+		children: make(map[string]*treeNode),
+	}
+}
+
+func (n *treeNode) add(parts []string, entry objects.DirectoryEntry) {
+	if len(parts) == 1 {
+		entry.Name = parts[0]
+		n.files[parts[0]] = entry
+		return
+	}
+
+	child, ok := n.children[parts[0]]
+	if !ok {
+		child = newTreeNode()
+		n.children[parts[0]] = child
+	}
+	child.add(parts[1:], entry)
+}
+
+func (n *treeNode) entries() []objects.DirectoryEntry {
+	entries := make([]objects.DirectoryEntry, 0, len(n.files)+len(n.children))
+	for _, entry := range n.files {
+		entries = append(entries, entry)
+	}
+	for name, child := range n.children {
+		entries = append(entries, objects.DirectoryEntry{
+			Name:   name,
+			Type:   objects.EntryTypeDirectory,
+			Target: objects.ComputeDirectoryHash(child.entries()),
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].SortKey() < entries[j].SortKey()
+	})
+	return entries
+}
@@ -0,0 +1,158 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrew/swhid-go/objects"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+)
+
+func TestFromGitIndex(t *testing.T) {
+	repoPath, repo, hash := newTestRepo(t)
+
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		t.Fatalf("CommitObject() error = %v", err)
+	}
+
+	id, err := FromGitIndex(repoPath)
+	if err != nil {
+		t.Fatalf("FromGitIndex() error = %v", err)
+	}
+	if id.ObjectHash != commit.TreeHash.String() {
+		t.Errorf("FromGitIndex() hash = %v, want HEAD tree %v", id.ObjectHash, commit.TreeHash)
+	}
+}
+
+func TestFromGitIndexStaged(t *testing.T) {
+	repoPath, repo, _ := newTestRepo(t)
+
+	if err := os.MkdirAll(filepath.Join(repoPath, "src"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoPath, "src", "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Worktree() error = %v", err)
+	}
+	if _, err := wt.Add("src/main.go"); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	// An unstaged change must not affect the result.
+	if err := os.WriteFile(filepath.Join(repoPath, "hello.txt"), []byte("changed\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	id, err := FromGitIndex(repoPath)
+	if err != nil {
+		t.Fatalf("FromGitIndex() error = %v", err)
+	}
+
+	src := FromDirectory([]objects.DirectoryEntry{
+		{Name: "main.go", Type: objects.EntryTypeFile, Target: objects.ComputeContentHash([]byte("package main\n"))},
+	})
+	want := FromDirectory([]objects.DirectoryEntry{
+		{Name: "hello.txt", Type: objects.EntryTypeFile, Target: objects.ComputeContentHash([]byte("hello\n"))},
+		{Name: "src", Type: objects.EntryTypeDirectory, Target: src.ObjectHash},
+	})
+	if id.ObjectHash != want.ObjectHash {
+		t.Errorf("FromGitIndex() hash = %v, want %v", id.ObjectHash, want.ObjectHash)
+	}
+}
+
+func TestFromGitIndexIntentToAdd(t *testing.T) {
+	repoPath, repo, hash := newTestRepo(t)
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		t.Fatalf("CommitObject() error = %v", err)
+	}
+
+	// What git add -N new.txt records: an empty blob marked intent-to-add
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	idx.Version = 3
+	idx.Entries = append(idx.Entries, &index.Entry{
+		Name:        "new.txt",
+		Mode:        filemode.Regular,
+		Hash:        plumbing.NewHash(objects.ComputeContentHash(nil)),
+		IntentToAdd: true,
+	})
+	if err := repo.Storer.SetIndex(idx); err != nil {
+		t.Fatalf("SetIndex() error = %v", err)
+	}
+
+	id, err := FromGitIndex(repoPath)
+	if err != nil {
+		t.Fatalf("FromGitIndex() error = %v", err)
+	}
+	if id.ObjectHash != commit.TreeHash.String() {
+		t.Errorf("FromGitIndex() hash = %v, want HEAD tree %v without new.txt", id.ObjectHash, commit.TreeHash)
+	}
+}
+
+func TestFromGitIndexFile(t *testing.T) {
+	repoPath, repo, hash := newTestRepo(t)
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		t.Fatalf("CommitObject() error = %v", err)
+	}
+	headIndex, err := repo.Storer.Index()
+	if err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+
+	// Stage a file, keep that index aside as git commit -a does, and put
+	// the repository's own index back
+	if err := os.WriteFile(filepath.Join(repoPath, "new.txt"), []byte("new\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Worktree() error = %v", err)
+	}
+	if _, err := wt.Add("new.txt"); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(repoPath, ".git", "index"))
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	nextIndex := filepath.Join(t.TempDir(), "next-index.lock")
+	if err := os.WriteFile(nextIndex, data, 0644); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+	if err := repo.Storer.SetIndex(headIndex); err != nil {
+		t.Fatalf("SetIndex() error = %v", err)
+	}
+
+	id, err := FromGitIndexFile(nextIndex)
+	if err != nil {
+		t.Fatalf("FromGitIndexFile() error = %v", err)
+	}
+	want := FromDirectory([]objects.DirectoryEntry{
+		{Name: "hello.txt", Type: objects.EntryTypeFile, Target: objects.ComputeContentHash([]byte("hello\n"))},
+		{Name: "new.txt", Type: objects.EntryTypeFile, Target: objects.ComputeContentHash([]byte("new\n"))},
+	})
+	if id.ObjectHash != want.ObjectHash {
+		t.Errorf("FromGitIndexFile() hash = %v, want %v", id.ObjectHash, want.ObjectHash)
+	}
+	if id, _ := FromGitIndex(repoPath); id.ObjectHash != commit.TreeHash.String() {
+		t.Errorf("FromGitIndex() hash = %v, want HEAD tree %v", id.ObjectHash, commit.TreeHash)
+	}
+
+	if _, err := FromGitIndexFile(filepath.Join(repoPath, "hello.txt")); err == nil {
+		t.Error("FromGitIndexFile() read a file that is not an index")
+	}
+}
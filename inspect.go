@@ -0,0 +1,62 @@
+package swhid
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// tap wraps r, the contents of the regular file at relPath about to be
+// hashed, so that the same read also feeds media type detection and
+// opts.Inspect as opts ask, and files small enough to be Git LFS pointers
+// are checked for being one when opts.Warn is set. done must be called once r has been read, with
+// the error reading it, and returns the file's media type, if detected, and
+// the first error of the read and of closing the inspecting writer.
+func tap(r io.Reader, relPath string, info os.FileInfo, opts TreeOptions) (tapped io.Reader, done func(error) (string, error)) {
+	var (
+		writers []io.Writer
+		head    *headBuffer
+		inspect io.Writer
+	)
+	if opts.DetectMIME || (opts.Warn != nil && info.Size() <= maxLFSPointerSize) {
+		head = &headBuffer{}
+		writers = append(writers, head)
+	}
+	if opts.Inspect != nil {
+		if inspect = opts.Inspect(relPath, info); inspect != nil {
+			writers = append(writers, inspect)
+		}
+	}
+	switch len(writers) {
+	case 0:
+		tapped = r
+	case 1:
+		tapped = io.TeeReader(r, writers[0])
+	default:
+		tapped = io.TeeReader(r, io.MultiWriter(writers...))
+	}
+	return tapped, func(err error) (string, error) {
+		if c, ok := inspect.(io.Closer); ok {
+			if cerr := c.Close(); err == nil {
+				err = cerr
+			}
+		}
+		if err != nil || head == nil {
+			return "", err
+		}
+		if opts.Warn != nil && bytes.HasPrefix(head.b, lfsPointerPrefix) {
+			opts.warn(relPath, WarnLFSPointer, "Git LFS pointer hashed as is, not the file it stands for")
+		}
+		if !opts.DetectMIME {
+			return "", nil
+		}
+		return head.detect(info.Size()), nil
+	}
+}
+
+// needsContent reports whether a regular file of the given size must be
+// read even when its SWHID is known without reading it, for opts.Inspect
+// or for the Git LFS pointer check opts.Warn asks for.
+func (opts TreeOptions) needsContent(size int64) bool {
+	return opts.Inspect != nil || (opts.Warn != nil && size <= maxLFSPointerSize)
+}
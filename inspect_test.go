@@ -0,0 +1,119 @@
+package swhid
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// recorder collects what TreeOptions.Inspect passes it.
+type recorder struct {
+	mu       sync.Mutex
+	contents map[string][]byte
+	closed   map[string]bool
+}
+
+type recordedFile struct {
+	r    *recorder
+	path string
+	buf  bytes.Buffer
+}
+
+func (f *recordedFile) Write(p []byte) (int, error) { return f.buf.Write(p) }
+
+func (f *recordedFile) Close() error {
+	f.r.mu.Lock()
+	defer f.r.mu.Unlock()
+	f.r.contents[f.path] = f.buf.Bytes()
+	f.r.closed[f.path] = true
+	return nil
+}
+
+func (r *recorder) inspect(relPath string, info os.FileInfo) io.Writer {
+	if relPath == "skip" {
+		return nil
+	}
+	return &recordedFile{r: r, path: relPath}
+}
+
+func TestTreeInspect(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"a.txt":        []byte("a\n"),
+		"skip":         []byte("skipped\n"),
+		"cached":       []byte("cached\n"),
+		"src/big.data": bytes.Repeat([]byte{2}, StreamThreshold+1),
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Link(filepath.Join(dir, "a.txt"), filepath.Join(dir, "src", "b.txt")); err != nil {
+		t.Fatal(err)
+	}
+	want, err := TreeFromDirectoryPath(dir)
+	if err != nil {
+		t.Fatalf("TreeFromDirectoryPath() error = %v", err)
+	}
+	cached := func(relPath string, info os.FileInfo) *Identifier {
+		if relPath == "cached" {
+			return FromContent(files["cached"])
+		}
+		return nil
+	}
+
+	for _, concurrency := range []int{0, 4} {
+		r := &recorder{contents: map[string][]byte{}, closed: map[string]bool{}}
+		root, err := TreeFromDirectoryPathWithOptions(dir, TreeOptions{Inspect: r.inspect, Cached: cached, Concurrency: concurrency})
+		if err != nil {
+			t.Fatalf("concurrency %d: error = %v", concurrency, err)
+		}
+		if !root.ID.Equal(want.ID) {
+			t.Errorf("concurrency %d: SWHID = %v, want %v", concurrency, root.ID, want.ID)
+		}
+		// Files not read for hashing, or skipped, are not inspected
+		if len(r.contents) != 2 {
+			t.Errorf("concurrency %d: inspected %d files, want 2", concurrency, len(r.contents))
+		}
+		for _, name := range []string{"a.txt", "src/big.data"} {
+			if !bytes.Equal(r.contents[name], files[name]) {
+				t.Errorf("concurrency %d: %s inspected %d bytes, want its %d", concurrency, name, len(r.contents[name]), len(files[name]))
+			}
+			if !r.closed[name] {
+				t.Errorf("concurrency %d: %s writer not closed", concurrency, name)
+			}
+		}
+	}
+}
+
+type failingWriter struct{ err error }
+
+func (w failingWriter) Write(p []byte) (int, error) { return 0, w.err }
+
+func TestTreeInspectError(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fail := errors.New("scanner failed")
+	for _, concurrency := range []int{0, 2} {
+		_, err := TreeFromDirectoryPathWithOptions(dir, TreeOptions{
+			Concurrency: concurrency,
+			Inspect: func(string, os.FileInfo) io.Writer {
+				return failingWriter{fail}
+			},
+		})
+		if !errors.Is(err, fail) {
+			t.Errorf("concurrency %d: error = %v, want %v", concurrency, err, fail)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package swhid
+
+import (
+	"encoding/hex"
+	"sync"
+)
+
+// internChunk is how many identifiers a Pool allocates at a time.
+const internChunk = 1024
+
+// internKey is a core SWHID in binary: an object type index followed by the
+// 20-byte object hash.
+type internKey [1 + ObjectIDLen/2]byte
+
+var internTypes = []ObjectType{ObjectTypeContent, ObjectTypeDirectory, ObjectTypeRevision, ObjectTypeRelease, ObjectTypeSnapshot}
+
+// Pool interns core identifiers, so that a program holding the same SWHID
+// many times, such as the nodes and edges of a large graph, keeps one
+// shared copy of it. Identifiers are keyed by their 21-byte binary form and
+// allocated in chunks, which takes far less memory than an Identifier per
+// occurrence. A Pool is safe for concurrent use.
+type Pool struct {
+	mu   sync.RWMutex
+	ids  map[internKey]*Identifier
+	free []Identifier // unused tail of the current chunk
+}
+
+// NewPool returns an empty pool.
+func NewPool() *Pool {
+	return &Pool{ids: make(map[internKey]*Identifier)}
+}
+
+var defaultPool = NewPool()
+
+// Intern returns the shared identifier for the core SWHID of id from a
+// process-wide pool, as Pool.Intern does. That pool is never emptied, so
+// analyses that finish with their identifiers should use a Pool of their
+// own and drop it.
+func Intern(id *Identifier) *Identifier {
+	return defaultPool.Intern(id)
+}
+
+// Intern returns the pool's identifier for the core SWHID of id, adding it
+// on first use. Qualifiers are not part of an interned identifier, and its
+// hash is in lower case. Interned identifiers are shared and must not be
+// modified; WithQualifiers returns a copy that may be. An id whose type or
+// hash is invalid is returned as it is.
+func (p *Pool) Intern(id *Identifier) *Identifier {
+	key, ok := makeInternKey(id)
+	if !ok {
+		return id
+	}
+
+	p.mu.RLock()
+	interned, ok := p.ids[key]
+	p.mu.RUnlock()
+	if ok {
+		return interned
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if interned, ok := p.ids[key]; ok {
+		return interned
+	}
+	if len(p.free) == 0 {
+		p.free = make([]Identifier, internChunk)
+	}
+	interned = &p.free[0]
+	p.free = p.free[1:]
+	*interned = Identifier{
+		Scheme:     Scheme,
+		Version:    SchemeVersion,
+		ObjectType: internTypes[key[0]],
+		ObjectHash: hex.EncodeToString(key[1:]),
+	}
+	p.ids[key] = interned
+	return interned
+}
+
+// Len returns the number of identifiers in the pool.
+func (p *Pool) Len() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.ids)
+}
+
+func makeInternKey(id *Identifier) (internKey, bool) {
+	var key internKey
+	if id == nil || len(id.ObjectHash) != ObjectIDLen {
+		return key, false
+	}
+	typeIndex := -1
+	for i, t := range internTypes {
+		if id.ObjectType == t {
+			typeIndex = i
+			break
+		}
+	}
+	if typeIndex < 0 {
+		return key, false
+	}
+	key[0] = byte(typeIndex)
+	for i := 1; i < len(key); i++ {
+		hi, lo := unhex(id.ObjectHash[2*i-2]), unhex(id.ObjectHash[2*i-1])
+		if hi < 0 || lo < 0 {
+			return key, false
+		}
+		key[i] = byte(hi<<4 | lo)
+	}
+	return key, true
+}
+
+// unhex returns the value of a hex digit, or -1.
+func unhex(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0')
+	case c >= 'a' && c <= 'f':
+		return int(c - 'a' + 10)
+	case c >= 'A' && c <= 'F':
+		return int(c - 'A' + 10)
+	}
+	return -1
+}
@@ -0,0 +1,70 @@
+package swhid
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestIntern(t *testing.T) {
+	p := NewPool()
+	a, _ := Parse("swh:1:cnt:94a9ed024d3859793618152ea559a168bbcbb5e2;origin=https://example.com")
+	b := &Identifier{Scheme: Scheme, Version: SchemeVersion, ObjectType: ObjectTypeContent, ObjectHash: "94A9ED024D3859793618152EA559A168BBCBB5E2"}
+	dir, _ := Parse("swh:1:dir:94a9ed024d3859793618152ea559a168bbcbb5e2")
+
+	ia, ib := p.Intern(a), p.Intern(b)
+	if ia != ib {
+		t.Error("Intern() returned different identifiers for the same core SWHID")
+	}
+	if got := ia.String(); got != "swh:1:cnt:94a9ed024d3859793618152ea559a168bbcbb5e2" {
+		t.Errorf("Intern() = %v", got)
+	}
+	if p.Intern(dir) == ia {
+		t.Error("Intern() shared an identifier across object types")
+	}
+	if p.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", p.Len())
+	}
+	if a.Qualifiers["origin"] == "" {
+		t.Error("Intern() modified its argument")
+	}
+
+	invalid := &Identifier{Scheme: Scheme, Version: SchemeVersion, ObjectType: ObjectTypeContent, ObjectHash: "xyz"}
+	if p.Intern(invalid) != invalid || p.Intern(nil) != nil {
+		t.Error("Intern() should return invalid identifiers unchanged")
+	}
+	if p.Len() != 2 {
+		t.Errorf("Len() = %d after invalid identifiers, want 2", p.Len())
+	}
+}
+
+func TestInternConcurrent(t *testing.T) {
+	p := NewPool()
+	ids := make([]*Identifier, 3000)
+	for i := range ids {
+		ids[i] = FromContent([]byte{byte(i), byte(i >> 8)})
+	}
+
+	results := make([][]*Identifier, 8)
+	var wg sync.WaitGroup
+	for w := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, id := range ids {
+				results[w] = append(results[w], p.Intern(id))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if p.Len() != len(ids) {
+		t.Fatalf("Len() = %d, want %d", p.Len(), len(ids))
+	}
+	for i, id := range ids {
+		for w := range results {
+			if results[w][i] != results[0][i] || !results[w][i].Equal(id) {
+				t.Fatalf("worker %d got %v for %v", w, results[w][i], id)
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Package archivetree builds the directory tree of an archive from its
+// members, for the packages that hash tar and zip archives without
+// extracting them.
+package archivetree
+
+import (
+	"strings"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/objects"
+)
+
+// Tree is a directory of an archive being read. Files and directories are
+// added by their slash-separated path below it, which callers check first.
+type Tree struct {
+	files map[string]objects.DirectoryEntry
+	dirs  map[string]*Tree
+}
+
+// New returns an empty tree.
+func New() *Tree {
+	return &Tree{files: make(map[string]objects.DirectoryEntry), dirs: make(map[string]*Tree)}
+}
+
+// Mkdir records the directory at rel, creating its parents as needed. It
+// returns false if rel or one of its parents is a file.
+func (t *Tree) Mkdir(rel string) bool {
+	_, ok := t.mkdir(strings.Split(rel, "/"))
+	return ok
+}
+
+// Add records the file, executable or symlink entry at rel, creating its
+// parents as needed; entry.Name is set from rel. It returns false if rel is
+// a directory or one of its parents is a file.
+func (t *Tree) Add(rel string, entry objects.DirectoryEntry) bool {
+	names := strings.Split(rel, "/")
+	dir, ok := t.mkdir(names[:len(names)-1])
+	if !ok {
+		return false
+	}
+	name := names[len(names)-1]
+	if _, isDir := dir.dirs[name]; isDir {
+		return false
+	}
+	entry.Name = name
+	dir.files[name] = entry
+	return true
+}
+
+// mkdir returns the directory at the names below t, creating it as
+// needed, or false if one of the names is a file.
+func (t *Tree) mkdir(names []string) (*Tree, bool) {
+	for _, name := range names {
+		if _, ok := t.files[name]; ok {
+			return nil, false
+		}
+		sub, ok := t.dirs[name]
+		if !ok {
+			sub = New()
+			t.dirs[name] = sub
+		}
+		t = sub
+	}
+	return t, true
+}
+
+// Identifier returns the directory SWHID of the tree.
+func (t *Tree) Identifier() *swhid.Identifier {
+	entries := make([]objects.DirectoryEntry, 0, len(t.files)+len(t.dirs))
+	for _, e := range t.files {
+		entries = append(entries, e)
+	}
+	for name, sub := range t.dirs {
+		entries = append(entries, objects.DirectoryEntry{Name: name, Type: objects.EntryTypeDirectory, Target: sub.Identifier().ObjectHash})
+	}
+	return swhid.FromDirectory(entries)
+}
@@ -0,0 +1,36 @@
+package archivetree
+
+import (
+	"testing"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/objects"
+)
+
+func TestTree(t *testing.T) {
+	hello := objects.ComputeContentHash([]byte("hello\n"))
+	tree := New()
+	if !tree.Add("src/lib/hello.txt", objects.DirectoryEntry{Type: objects.EntryTypeFile, Target: hello}) {
+		t.Fatal("Add() refused a file in new directories")
+	}
+	if !tree.Mkdir("src/empty") || !tree.Mkdir("src/lib") {
+		t.Fatal("Mkdir() refused a directory")
+	}
+	if tree.Add("src/lib", objects.DirectoryEntry{Type: objects.EntryTypeFile, Target: hello}) {
+		t.Error("Add() replaced a directory with a file")
+	}
+	if tree.Mkdir("src/lib/hello.txt/sub") || tree.Add("src/lib/hello.txt/x", objects.DirectoryEntry{Type: objects.EntryTypeFile, Target: hello}) {
+		t.Error("a file was used as a directory")
+	}
+
+	lib := swhid.FromDirectory([]objects.DirectoryEntry{{Name: "hello.txt", Type: objects.EntryTypeFile, Target: hello}})
+	empty := swhid.FromDirectory(nil)
+	src := swhid.FromDirectory([]objects.DirectoryEntry{
+		{Name: "empty", Type: objects.EntryTypeDirectory, Target: empty.ObjectHash},
+		{Name: "lib", Type: objects.EntryTypeDirectory, Target: lib.ObjectHash},
+	})
+	want := swhid.FromDirectory([]objects.DirectoryEntry{{Name: "src", Type: objects.EntryTypeDirectory, Target: src.ObjectHash}})
+	if got := tree.Identifier(); got.String() != want.String() {
+		t.Errorf("Identifier() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,60 @@
+// Package pathmatch matches slash-separated paths against the patterns of
+// TreeOptions.Include and Exclude, for the packages that select files the
+// same way. Patterns use path.Match syntax. A pattern without a slash
+// matches a name at any depth, like "node_modules" or "*.log"; one with a
+// slash matches the path from the root, like "src/vendor", where a "**"
+// element matches any number of directories, as in "**/testdata" or
+// "src/**/*.sh".
+package pathmatch
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Clean strips a leading "./" and surrounding slashes from a pattern and
+// checks its syntax.
+func Clean(p string) (string, error) {
+	p = strings.Trim(strings.TrimPrefix(p, "./"), "/")
+	if _, err := path.Match(p, ""); err != nil {
+		return "", fmt.Errorf("invalid path pattern %q: %w", p, err)
+	}
+	return p, nil
+}
+
+// Match reports whether the path relPath matches pattern, which Clean has
+// checked.
+func Match(pattern, relPath string) bool {
+	if !strings.Contains(pattern, "/") {
+		relPath = path.Base(relPath)
+	}
+	if strings.Contains(pattern, "**") {
+		return matchElements(strings.Split(pattern, "/"), strings.Split(relPath, "/"))
+	}
+	ok, _ := path.Match(pattern, relPath)
+	return ok
+}
+
+// matchElements matches a path element by element, with "**" matching any
+// number of elements.
+func matchElements(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(parts); i++ {
+				if matchElements(pattern[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
@@ -0,0 +1,36 @@
+package pathmatch
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"*.log", "a.log", true},
+		{"*.log", "logs/deep/a.log", true},
+		{"node_modules", "web/node_modules", true},
+		{"src/vendor", "src/vendor", true},
+		{"src/vendor", "lib/src/vendor", false},
+		{"**/testdata", "testdata", true},
+		{"**/testdata", "a/b/testdata", true},
+		{"src/**/*.sh", "src/run.sh", true},
+		{"src/**/*.sh", "src/a/b/run.sh", true},
+		{"src/**/*.sh", "lib/run.sh", false},
+		{"**/*.egg-info", "pkg.egg-info", true},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestClean(t *testing.T) {
+	if got, err := Clean("./src/vendor/"); err != nil || got != "src/vendor" {
+		t.Errorf("Clean(./src/vendor/) = %q, %v", got, err)
+	}
+	if _, err := Clean("["); err == nil {
+		t.Error("Clean([) accepted an invalid pattern")
+	}
+}
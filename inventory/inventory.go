@@ -0,0 +1,421 @@
+// Package inventory keeps a SQLite database listing every object in a
+// directory tree, so provenance can be queried locally with the sqlite3
+// shell or any SQLite client:
+//
+//	sqlite3 inventory.sqlite "SELECT path FROM objects WHERE type = 'executable'"
+//
+// The meta table holds key/value pairs: "schema" (SchemaVersion), "root"
+// (the absolute path indexed), "swhid" (the root directory's SWHID) and
+// "indexed_at" (when hashing started, in nanoseconds). The objects table
+// has one row per object:
+//
+//	path   slash-separated path from the tree root, "" for the root
+//	type   file, executable, directory, symlink or submodule
+//	swhid  core SWHID of the object, indexed
+//	size   content bytes at or below the object
+//	mtime  modification time in nanoseconds since the Unix epoch
+//	seq    position of the object in depth-first tree order
+//
+// Update replaces the rows in one transaction on every run, reusing the
+// SWHIDs of files whose size and modification time are unchanged, and
+// reports what changed since the previous run. UpdateInventory does the
+// same in memory.
+//
+// The database is accessed through github.com/mattn/go-sqlite3, so this
+// package needs cgo.
+package inventory
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/manifest"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SchemaVersion identifies the table layout written by this package. It is
+// stored under the "schema" key of the meta table.
+const SchemaVersion = "swhid-inventory/1"
+
+// ErrNotInventory is returned when a file exists but is not an inventory
+// database. Update refuses to overwrite such files.
+var ErrNotInventory = errors.New("inventory: not an inventory database")
+
+const schemaSQL = `
+CREATE TABLE IF NOT EXISTS meta (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS objects (
+	path  TEXT PRIMARY KEY,
+	type  TEXT NOT NULL,
+	swhid TEXT NOT NULL,
+	size  INTEGER NOT NULL,
+	mtime INTEGER NOT NULL,
+	seq   INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS objects_swhid ON objects (swhid);
+`
+
+// Record is one row of the objects table.
+type Record struct {
+	manifest.Entry
+	ModTime time.Time
+}
+
+// Stats summarizes an Update.
+type Stats struct {
+	Root    *swhid.Identifier
+	Tree    *swhid.Node // the hashed directory
+	Objects int         // rows written
+	Hashed  int         // files read and hashed
+	Reused  int         // files whose SWHID was taken from the previous inventory
+	Removed int         // rows of the previous inventory no longer present
+	Changes Changes
+}
+
+// Inventory is the content of an inventory database.
+type Inventory struct {
+	Meta    map[string]string
+	Records []Record
+}
+
+// open opens the database at path. Without create the file must already
+// exist and is opened read-only.
+func open(path string, create bool) (*sql.DB, error) {
+	mode := "rwc"
+	if !create {
+		if _, err := os.Stat(path); err != nil {
+			return nil, err
+		}
+		mode = "ro"
+	}
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath() + "?mode=" + mode
+	return sql.Open("sqlite3", dsn)
+}
+
+// notDatabase reports whether err is SQLite refusing a file that is not
+// a database. The driver's error type only exists in cgo builds, so the
+// messages of SQLITE_NOTADB and SQLITE_CORRUPT are matched instead.
+func notDatabase(err error) bool {
+	return err != nil && (strings.Contains(err.Error(), "file is not a database") ||
+		strings.Contains(err.Error(), "database disk image is malformed"))
+}
+
+// tables returns the number of tables in db, and whether one is meta.
+func tables(db *sql.DB) (n int, meta bool, err error) {
+	err = db.QueryRow("SELECT count(*), count(CASE name WHEN 'meta' THEN 1 END) FROM sqlite_master WHERE type = 'table'").Scan(&n, &meta)
+	if notDatabase(err) {
+		return 0, false, ErrNotInventory
+	}
+	return n, meta, err
+}
+
+// readMeta reads and checks the meta table of db.
+func readMeta(db *sql.DB) (map[string]string, error) {
+	if _, ok, err := tables(db); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, ErrNotInventory
+	}
+	rows, err := db.Query("SELECT key, value FROM meta")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	meta := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		meta[key] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(meta["schema"], "swhid-inventory/") {
+		return nil, ErrNotInventory
+	}
+	if v := meta["schema"]; v != SchemaVersion {
+		return nil, fmt.Errorf("inventory: unsupported schema version %q", v)
+	}
+	return meta, nil
+}
+
+// Read loads the inventory database at path.
+func Read(path string) (*Inventory, error) {
+	inv := &Inventory{}
+	meta, err := Walk(path, func(rec Record) error {
+		inv.Records = append(inv.Records, rec)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	inv.Meta = meta
+	return inv, nil
+}
+
+// Walk reads the inventory database at path, calling fn with each record
+// in tree order without holding them all in memory, and returns the meta
+// table. It stops at the first error fn returns.
+func Walk(path string, fn func(Record) error) (map[string]string, error) {
+	db, err := open(path, false)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	meta, err := readMeta(db)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query("SELECT path, type, swhid, size, mtime FROM objects ORDER BY seq")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var rec Record
+		var id string
+		var mtime int64
+		if err := rows.Scan(&rec.Path, &rec.Type, &id, &rec.Size, &mtime); err != nil {
+			return nil, fmt.Errorf("inventory: malformed record: %w", err)
+		}
+		if rec.SWHID, err = swhid.Parse(id); err != nil {
+			return nil, fmt.Errorf("inventory: %s: %w", rec.Path, err)
+		}
+		rec.ModTime = time.Unix(0, mtime)
+		if err := fn(rec); err != nil {
+			return nil, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// Export writes the records of the inventory database at path to w as
+// manifest entries, one at a time, and closes w. With a
+// manifest.NewNDJSONWriter over a compressor or a network connection the
+// inventory streams out without being loaded whole.
+func Export(path string, w manifest.Writer) error {
+	if _, err := Walk(path, func(rec Record) error { return w.Write(rec.Entry) }); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// Write replaces the contents of the inventory database at path with an
+// inventory of the tree at root, in one transaction, creating the file if
+// needed. It refuses an existing database that is not an inventory.
+// Tables and indexes added by hand are kept.
+func Write(path string, root *swhid.Node, meta map[string]string) error {
+	db, err := open(path, true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := readMeta(db); errors.Is(err, ErrNotInventory) {
+		// Only a new, empty database may become an inventory
+		if n, _, err := tables(db); err != nil {
+			return err
+		} else if n > 0 {
+			return ErrNotInventory
+		}
+	} else if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(schemaSQL); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM meta; DELETE FROM objects"); err != nil {
+		return err
+	}
+
+	values := map[string]string{"schema": SchemaVersion}
+	for _, key := range []string{"root", "swhid", "indexed_at"} {
+		if v, ok := meta[key]; ok {
+			values[key] = v
+		}
+	}
+	for key, value := range values {
+		if _, err := tx.Exec("INSERT INTO meta (key, value) VALUES (?, ?)", key, value); err != nil {
+			return err
+		}
+	}
+
+	insert, err := tx.Prepare("INSERT INTO objects (path, type, swhid, size, mtime, seq) VALUES (?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+	var seq int64
+	var walkErr error
+	root.Walk(func(n *swhid.Node) bool {
+		_, walkErr = insert.Exec(n.Path, manifest.TypeName(n.Type), n.ID.CoreSWHID(), n.Size, n.ModTime.UnixNano(), seq)
+		seq++
+		return walkErr == nil
+	})
+	if walkErr != nil {
+		return walkErr
+	}
+	return tx.Commit()
+}
+
+// UpdateOptions controls how a directory is re-indexed.
+type UpdateOptions struct {
+	Tree swhid.TreeOptions // how to read the directory; Cached is replaced
+
+	// Paranoid rehashes every file instead of trusting unchanged sizes and
+	// modification times, so Changes also reports files rewritten with
+	// their old size and timestamp.
+	Paranoid bool
+}
+
+// Changes lists the differences between an inventory and the previous one.
+type Changes struct {
+	Added    []string // paths not in the previous inventory
+	Removed  []string // paths of the previous inventory no longer present
+	Modified []string // non-directory paths whose SWHID or type changed
+}
+
+// Update hashes the directory dir and writes its inventory to path. If
+// path already holds an inventory of the same directory, regular files
+// whose size and modification time match their record are not read again;
+// their recorded SWHID is reused. Files modified after the previous run
+// started are always rehashed, since a same-timestamp change could have
+// gone unnoticed.
+func Update(path, dir string) (*Stats, error) {
+	return UpdateWithOptions(path, dir, UpdateOptions{})
+}
+
+// UpdateWithOptions is Update with options for reading the directory, such
+// as include and exclude filters.
+func UpdateWithOptions(path, dir string, opts UpdateOptions) (*Stats, error) {
+	prev, err := Read(path)
+	switch {
+	case err == nil:
+	case errors.Is(err, os.ErrNotExist):
+		prev = nil
+	default:
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	inv, stats, err := UpdateInventory(dir, prev, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := Write(path, stats.Tree, inv.Meta); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// UpdateInventory hashes the directory dir, reusing the SWHIDs prev
+// recorded for files whose size and modification time are unchanged, as
+// Update does, and returns the new inventory. prev may be nil, and is only
+// reused if it is an inventory of the same directory. The returned Stats
+// report what changed since prev.
+func UpdateInventory(dir string, prev *Inventory, opts UpdateOptions) (*Inventory, *Stats, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if prev == nil {
+		prev = &Inventory{}
+	}
+
+	reusable := make(map[string]Record)
+	if !opts.Paranoid && prev.Meta["root"] == absDir {
+		indexedAt, _ := strconv.ParseInt(prev.Meta["indexed_at"], 10, 64)
+		for _, rec := range prev.Records {
+			if rec.ModTime.UnixNano() < indexedAt {
+				reusable[rec.Path] = rec
+			}
+		}
+	}
+
+	stats := &Stats{}
+	started := time.Now()
+	treeOpts := opts.Tree
+	treeOpts.Cached = func(relPath string, info os.FileInfo) *swhid.Identifier {
+		rec, ok := reusable[relPath]
+		if !ok || (rec.Type != "file" && rec.Type != "executable") ||
+			rec.Size != info.Size() || !rec.ModTime.Equal(info.ModTime()) {
+			stats.Hashed++
+			return nil
+		}
+		stats.Reused++
+		return rec.SWHID
+	}
+	root, err := swhid.TreeFromDirectoryPathWithOptions(absDir, treeOpts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	inv := &Inventory{Meta: map[string]string{
+		"schema":     SchemaVersion,
+		"root":       absDir,
+		"swhid":      root.ID.CoreSWHID(),
+		"indexed_at": strconv.FormatInt(started.UnixNano(), 10),
+	}}
+	root.Walk(func(n *swhid.Node) bool {
+		inv.Records = append(inv.Records, Record{
+			Entry:   manifest.Entry{Path: n.Path, Type: manifest.TypeName(n.Type), SWHID: n.ID, Size: n.Size},
+			ModTime: n.ModTime,
+		})
+		return true
+	})
+
+	stats.Root, stats.Tree = root.ID, root
+	stats.Objects = len(inv.Records)
+	stats.Changes = diff(prev.Records, inv.Records)
+	stats.Removed = len(stats.Changes.Removed)
+	return inv, stats, nil
+}
+
+// diff compares the records of two inventories.
+func diff(before, after []Record) Changes {
+	var c Changes
+	old := make(map[string]Record, len(before))
+	for _, rec := range before {
+		old[rec.Path] = rec
+	}
+	present := make(map[string]bool, len(after))
+	for _, rec := range after {
+		present[rec.Path] = true
+		prev, ok := old[rec.Path]
+		switch {
+		case !ok:
+			c.Added = append(c.Added, rec.Path)
+		case rec.Type == "directory" && prev.Type == "directory":
+		case rec.Type != prev.Type || rec.SWHID.CoreSWHID() != prev.SWHID.CoreSWHID():
+			c.Modified = append(c.Modified, rec.Path)
+		}
+	}
+	for _, rec := range before {
+		if !present[rec.Path] {
+			c.Removed = append(c.Removed, rec.Path)
+		}
+	}
+	return c
+}
@@ -0,0 +1,310 @@
+package inventory
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/manifest"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	// Keep modification times clearly before the run that indexes them
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatalf("Failed to set times: %v", err)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	dir := t.TempDir()
+	db := filepath.Join(t.TempDir(), "inventory.sqlite")
+	writeFile(t, filepath.Join(dir, "README"), "hello\n")
+	writeFile(t, filepath.Join(dir, "src", "main.go"), "package main\n")
+	writeFile(t, filepath.Join(dir, "src", "old.go"), "package old\n")
+
+	stats, err := Update(db, dir)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if stats.Objects != 5 || stats.Hashed != 3 || stats.Reused != 0 || stats.Removed != 0 {
+		t.Errorf("first Update() = %+v", stats)
+	}
+	want, err := swhid.FromDirectoryPath(dir)
+	if err != nil {
+		t.Fatalf("FromDirectoryPath() error = %v", err)
+	}
+	if !stats.Root.Equal(want) {
+		t.Errorf("Update() root = %v, want %v", stats.Root, want)
+	}
+
+	inv, err := Read(db)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if inv.Meta["swhid"] != want.CoreSWHID() || inv.Meta["schema"] != SchemaVersion {
+		t.Errorf("Read() meta = %v", inv.Meta)
+	}
+	var paths []string
+	for _, rec := range inv.Records {
+		paths = append(paths, rec.Path)
+	}
+	if got := strings.Join(paths, ","); got != ",README,src,src/main.go,src/old.go" {
+		t.Errorf("Read() paths = %s", got)
+	}
+	readme := inv.Records[1]
+	if readme.Type != "file" || readme.Size != 6 || !readme.SWHID.Equal(swhid.FromContent([]byte("hello\n"))) {
+		t.Errorf("README record = %+v", readme)
+	}
+	info, _ := os.Stat(filepath.Join(dir, "README"))
+	if !readme.ModTime.Equal(info.ModTime()) {
+		t.Errorf("README mtime = %v, want %v", readme.ModTime, info.ModTime())
+	}
+
+	// Other SQLite clients can query the objects table directly
+	conn, err := sql.Open("sqlite3", db)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	var path string
+	err = conn.QueryRow("SELECT path FROM objects WHERE swhid = ?", readme.SWHID.CoreSWHID()).Scan(&path)
+	conn.Close()
+	if err != nil || path != "README" {
+		t.Errorf("query by SWHID = %q, %v; want README", path, err)
+	}
+
+	// Change one file, remove another: only the changed file is read again
+	writeFile(t, filepath.Join(dir, "src", "main.go"), "package main // changed\n")
+	if err := os.Remove(filepath.Join(dir, "src", "old.go")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+
+	stats, err = Update(db, dir)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if stats.Objects != 4 || stats.Hashed != 1 || stats.Reused != 1 || stats.Removed != 1 {
+		t.Errorf("second Update() = %+v", stats)
+	}
+	want, _ = swhid.FromDirectoryPath(dir)
+	if !stats.Root.Equal(want) {
+		t.Errorf("Update() root = %v, want %v", stats.Root, want)
+	}
+	if inv, err := Read(db); err != nil || len(inv.Records) != 4 || inv.Meta["swhid"] != want.CoreSWHID() {
+		t.Errorf("Read() after second Update() = %+v, %v", inv, err)
+	}
+}
+
+func TestUpdateRecentlyModified(t *testing.T) {
+	dir := t.TempDir()
+	db := filepath.Join(t.TempDir(), "inventory.sqlite")
+	path := filepath.Join(dir, "racy.txt")
+	if err := os.WriteFile(path, []byte("one\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatalf("Failed to set times: %v", err)
+	}
+
+	if _, err := Update(db, dir); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	stats, err := Update(db, dir)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if stats.Reused != 0 || stats.Hashed != 1 {
+		t.Errorf("Update() = %+v, want the file modified after the last run rehashed", stats)
+	}
+}
+
+func TestUpdateOtherRoot(t *testing.T) {
+	db := filepath.Join(t.TempDir(), "inventory.sqlite")
+	a, b := t.TempDir(), t.TempDir()
+	writeFile(t, filepath.Join(a, "f"), "a")
+	writeFile(t, filepath.Join(b, "f"), "b")
+	os.Chtimes(filepath.Join(b, "f"), time.Now().Add(-time.Hour), time.Now().Add(-time.Hour))
+
+	if _, err := Update(db, a); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	stats, err := Update(db, b)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if stats.Reused != 0 {
+		t.Errorf("Update() reused %d SWHIDs from another directory's inventory", stats.Reused)
+	}
+}
+
+// createDB creates a SQLite database at path and runs stmts in it.
+func createDB(t *testing.T, path string, stmts ...string) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to run %q: %v", stmt, err)
+		}
+	}
+}
+
+func TestUpdateRefusesOtherFiles(t *testing.T) {
+	other := filepath.Join(t.TempDir(), "notes.db")
+	createDB(t, other, "CREATE TABLE notes (body TEXT)", "INSERT INTO notes VALUES ('keep me')")
+	sqlite, err := os.ReadFile(other)
+	if err != nil {
+		t.Fatalf("Failed to read database: %v", err)
+	}
+
+	for _, content := range []string{
+		"not an inventory\n",
+		"SQLite format 3\x00",
+		`{"schema":"swhid-inventory/1"}` + "\n",
+		string(sqlite),
+	} {
+		db := filepath.Join(t.TempDir(), "notes.db")
+		if err := os.WriteFile(db, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+
+		if _, err := Update(db, t.TempDir()); !errors.Is(err, ErrNotInventory) {
+			t.Errorf("Update() over %.20q error = %v, want ErrNotInventory", content, err)
+		}
+		data, _ := os.ReadFile(db)
+		if string(data) != content {
+			t.Errorf("Update() overwrote %.20q, which is not an inventory", content)
+		}
+	}
+}
+
+func TestReadFormat(t *testing.T) {
+	db := filepath.Join(t.TempDir(), "inventory.sqlite")
+	createDB(t, db, schemaSQL,
+		`INSERT INTO meta VALUES ('schema', 'swhid-inventory/1'), ('root', '/src'), ('indexed_at', '2000000000000000000'),
+			('swhid', 'swh:1:dir:d198bc9d7a6bcf6db04f476d29314f157507d505')`,
+		`INSERT INTO objects VALUES
+			('hello.txt', 'file', 'swh:1:cnt:ce013625030ba8dba906f756967f9e9ca394464a', 6, 1000000000000000000, 1),
+			('', 'directory', 'swh:1:dir:d198bc9d7a6bcf6db04f476d29314f157507d505', 6, 1000000000000000000, 0)`,
+	)
+	inv, err := Read(db)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if inv.Meta["root"] != "/src" || inv.Meta["indexed_at"] != "2000000000000000000" || len(inv.Records) != 2 {
+		t.Fatalf("Read() = %+v", inv)
+	}
+	hello := inv.Records[1]
+	if hello.Path != "hello.txt" || hello.Type != "file" || hello.Size != 6 ||
+		hello.SWHID.String() != "swh:1:cnt:ce013625030ba8dba906f756967f9e9ca394464a" ||
+		!hello.ModTime.Equal(time.Unix(1000000000, 0)) {
+		t.Errorf("Read() record = %+v", hello)
+	}
+
+	createDB(t, db, "UPDATE meta SET value = 'swhid-inventory/99' WHERE key = 'schema'")
+	if _, err := Read(db); err == nil || errors.Is(err, ErrNotInventory) {
+		t.Errorf("Read() of a later schema error = %v, want an unsupported version", err)
+	}
+}
+
+func TestUpdateInventory(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "README"), "hello\n")
+	writeFile(t, filepath.Join(dir, "src", "main.go"), "package main\n")
+	writeFile(t, filepath.Join(dir, "src", "old.go"), "package old\n")
+
+	prev, _, err := UpdateInventory(dir, nil, UpdateOptions{})
+	if err != nil {
+		t.Fatalf("UpdateInventory() error = %v", err)
+	}
+
+	// Rewrite README with the same size and timestamp, which only a
+	// paranoid update notices
+	info, _ := os.Stat(filepath.Join(dir, "README"))
+	writeFile(t, filepath.Join(dir, "README"), "HELLO\n")
+	os.Chtimes(filepath.Join(dir, "README"), info.ModTime(), info.ModTime())
+	writeFile(t, filepath.Join(dir, "src", "main.go"), "package main // changed\n")
+	writeFile(t, filepath.Join(dir, "src", "new.go"), "package new\n")
+	os.Remove(filepath.Join(dir, "src", "old.go"))
+
+	inv, stats, err := UpdateInventory(dir, prev, UpdateOptions{})
+	if err != nil {
+		t.Fatalf("UpdateInventory() error = %v", err)
+	}
+	if stats.Hashed != 2 || stats.Reused != 1 {
+		t.Errorf("UpdateInventory() = %+v", stats)
+	}
+	c := stats.Changes
+	if strings.Join(c.Added, ",") != "src/new.go" || strings.Join(c.Removed, ",") != "src/old.go" ||
+		strings.Join(c.Modified, ",") != "src/main.go" {
+		t.Errorf("UpdateInventory() changes = %+v", c)
+	}
+	if len(inv.Records) != stats.Objects || inv.Meta["swhid"] != stats.Root.CoreSWHID() {
+		t.Errorf("UpdateInventory() inventory = %+v", inv)
+	}
+
+	_, stats, err = UpdateInventory(dir, prev, UpdateOptions{Paranoid: true})
+	if err != nil {
+		t.Fatalf("UpdateInventory() error = %v", err)
+	}
+	if stats.Hashed != 3 || stats.Reused != 0 || strings.Join(stats.Changes.Modified, ",") != "README,src/main.go" {
+		t.Errorf("paranoid UpdateInventory() = %+v", stats)
+	}
+	want, _ := swhid.FromDirectoryPath(dir)
+	if !stats.Root.Equal(want) {
+		t.Errorf("paranoid UpdateInventory() root = %v, want %v", stats.Root, want)
+	}
+}
+
+func TestExport(t *testing.T) {
+	dir := t.TempDir()
+	db := filepath.Join(t.TempDir(), "inventory.sqlite")
+	writeFile(t, filepath.Join(dir, "README"), "hello\n")
+	writeFile(t, filepath.Join(dir, "src", "main.go"), "package main\n")
+
+	stats, err := Update(db, dir)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := Export(db, manifest.NewNDJSONWriter(&buf)); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != stats.Objects {
+		t.Fatalf("Export() wrote %d lines, want %d", len(lines), stats.Objects)
+	}
+	if !strings.Contains(lines[0], stats.Root.CoreSWHID()) {
+		t.Errorf("Export() first line = %s, want the root %v", lines[0], stats.Root)
+	}
+
+	var seen int
+	stop := errors.New("stop")
+	_, err = Walk(db, func(rec Record) error {
+		if seen++; seen == 2 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || seen != 2 {
+		t.Errorf("Walk() error = %v after %d rows, want %v after 2", err, seen, stop)
+	}
+}
@@ -0,0 +1,72 @@
+package swhid
+
+import (
+	"strings"
+	"unicode"
+)
+
+// LenientFix names a correction ParseLenient made to its input.
+type LenientFix string
+
+const (
+	FixWhitespace          LenientFix = "trimmed surrounding whitespace"
+	FixURLPrefix           LenientFix = "removed swh:// prefix"
+	FixTrailingPunctuation LenientFix = "removed trailing punctuation"
+	FixCase                LenientFix = "lowercased scheme, object type and hash"
+)
+
+// trailingPunctuation is what copy-pasting from prose tends to leave after
+// an identifier: sentence punctuation, closing brackets and quotes.
+const trailingPunctuation = ".,;:!?)]}>'\"”’»"
+
+// ParseLenient parses a SWHID that may have been mangled by copy-paste
+// from PDFs, emails or web pages. It trims surrounding whitespace
+// (including non-breaking and zero-width spaces), drops a swh:// prefix and
+// trailing punctuation, and lowercases the scheme, object type and hash,
+// then parses the result like Parse. The returned fixes list the
+// corrections that were needed, in the order they were applied; none means
+// the input was already canonical.
+//
+// Trailing punctuation is removed even after qualifiers, so a path or
+// origin that genuinely ends in one of .,;:!?)]}>'" loses it; use Parse
+// for input that did not pass through prose.
+func ParseLenient(s string) (*Identifier, []LenientFix, error) {
+	var fixes []LenientFix
+
+	if trimmed := strings.TrimFunc(s, isLenientSpace); trimmed != s {
+		fixes = append(fixes, FixWhitespace)
+		s = trimmed
+	}
+
+	if len(s) >= 6 && strings.EqualFold(s[:6], "swh://") {
+		fixes = append(fixes, FixURLPrefix)
+		s = s[6:]
+		if len(s) < 4 || !strings.EqualFold(s[:4], "swh:") {
+			s = "swh:" + s
+		}
+	}
+
+	if trimmed := strings.TrimRight(s, trailingPunctuation); trimmed != s {
+		fixes = append(fixes, FixTrailingPunctuation)
+		s = strings.TrimFunc(trimmed, isLenientSpace)
+	}
+
+	core, qualifiers, hasQualifiers := strings.Cut(s, ";")
+	if lower := strings.ToLower(core); lower != core {
+		fixes = append(fixes, FixCase)
+		s = lower
+		if hasQualifiers {
+			s += ";" + qualifiers
+		}
+	}
+
+	id, err := Parse(s)
+	if err != nil {
+		return nil, fixes, err
+	}
+	return id, fixes, nil
+}
+
+func isLenientSpace(r rune) bool {
+	return unicode.IsSpace(r) || r == '\u200b' || r == '\ufeff'
+}
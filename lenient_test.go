@@ -0,0 +1,50 @@
+package swhid
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseLenient(t *testing.T) {
+	const canonical = "swh:1:cnt:94a9ed024d3859793618152ea559a168bbcbb5e2"
+
+	tests := []struct {
+		name      string
+		input     string
+		want      string
+		wantFixes []LenientFix
+		wantErr   bool
+	}{
+		{"canonical", canonical, canonical, nil, false},
+		{"whitespace", "  " + canonical + "\n", canonical, []LenientFix{FixWhitespace}, false},
+		{"non-breaking space", "\u00a0" + canonical + "\u200b", canonical, []LenientFix{FixWhitespace}, false},
+		{"url prefix", "swh://" + canonical, canonical, []LenientFix{FixURLPrefix}, false},
+		{"url prefix without scheme", "SWH://1:cnt:94a9ed024d3859793618152ea559a168bbcbb5e2", canonical, []LenientFix{FixURLPrefix}, false},
+		{"sentence end", canonical + ".", canonical, []LenientFix{FixTrailingPunctuation}, false},
+		{"closing quote and paren", canonical + "”).", canonical, []LenientFix{FixTrailingPunctuation}, false},
+		{"uppercase hash", "swh:1:cnt:94A9ED024D3859793618152EA559A168BBCBB5E2", canonical, []LenientFix{FixCase}, false},
+		{
+			name:      "qualifiers keep their case",
+			input:     " SWH:1:CNT:94A9ED024D3859793618152EA559A168BBCBB5E2;origin=https://github.com/Example/Repo, ",
+			want:      canonical + ";origin=https://github.com/Example/Repo",
+			wantFixes: []LenientFix{FixWhitespace, FixTrailingPunctuation, FixCase},
+		},
+		{"still invalid", "swh:1:cnt:xyz.", "", []LenientFix{FixTrailingPunctuation}, true},
+		{"empty", "   ", "", []LenientFix{FixWhitespace}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, fixes, err := ParseLenient(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLenient(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !slices.Equal(fixes, tt.wantFixes) {
+				t.Errorf("ParseLenient(%q) fixes = %v, want %v", tt.input, fixes, tt.wantFixes)
+			}
+			if err == nil && id.String() != tt.want {
+				t.Errorf("ParseLenient(%q) = %v, want %v", tt.input, id, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// Package license detects the licenses of files from their contents, for
+// SBOM pipelines that want a license next to every content SWHID. It is
+// meant as an example of swhid.TreeOptions.Inspect: a Scanner sees each
+// file as it is hashed, so a tree is read once for both.
+//
+// Detection recognizes SPDX-License-Identifier tags, whose expressions are
+// reported as written, and the texts of common licenses by their
+// distinctive sentences, reported as SPDX identifiers. It is a heuristic,
+// not a license compliance tool: modified or unusual license texts are
+// not recognized.
+package license
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ScanSize is the number of leading bytes of a file that are looked at.
+// License headers and the texts recognized fit well within it.
+const ScanSize = 64 << 10
+
+// spdxTag matches an SPDX-License-Identifier tag and the license expression
+// following it on its line, which stops at anything that cannot be part of
+// one, such as a comment closer.
+var spdxTag = regexp.MustCompile(`SPDX-License-Identifier:[ \t]*([A-Za-z0-9.+:() \t-]*)`)
+
+// text is a license recognized by sentences that all appear in its text,
+// compared lowercased with whitespace collapsed.
+type text struct {
+	id      string
+	phrases []string
+}
+
+// texts are tried in order and only the first match is reported, so
+// licenses whose texts mention others come before them.
+var texts = []text{
+	{"AGPL-3.0", []string{"gnu affero general public license", "version 3"}},
+	{"LGPL-3.0", []string{"gnu lesser general public license", "version 3"}},
+	{"LGPL-2.1", []string{"gnu lesser general public license", "version 2.1"}},
+	{"GPL-3.0", []string{"gnu general public license", "version 3"}},
+	{"GPL-2.0", []string{"gnu general public license", "version 2"}},
+	{"Apache-2.0", []string{"apache license", "version 2.0"}},
+	{"MPL-2.0", []string{"mozilla public license", "version 2.0"}},
+	{"MIT", []string{"permission is hereby granted, free of charge, to any person obtaining a copy"}},
+	{"ISC", []string{"permission to use, copy, modify, and/or distribute this software for any purpose with or without fee is hereby granted"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "neither the name of"}},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms", "this list of conditions and the following disclaimer"}},
+	{"Unlicense", []string{"this is free and unencumbered software released into the public domain"}},
+}
+
+// Detect returns the licenses found in data: the expressions of its SPDX
+// tags, in order, then the identifier of a license text it contains, each
+// once. Only the first ScanSize bytes are looked at.
+func Detect(data []byte) []string {
+	if len(data) > ScanSize {
+		data = data[:ScanSize]
+	}
+	var found []string
+	add := func(id string) {
+		for _, f := range found {
+			if f == id {
+				return
+			}
+		}
+		found = append(found, id)
+	}
+	for _, m := range spdxTag.FindAllSubmatch(data, -1) {
+		// An HTML comment closer leaves dashes behind; no identifier ends
+		// in one.
+		if expr := strings.TrimRight(string(m[1]), " \t-"); expr != "" {
+			add(expr)
+		}
+	}
+	normalized := strings.ToLower(strings.Join(strings.Fields(string(data)), " "))
+	for _, t := range texts {
+		if containsAll(normalized, t.phrases) {
+			add(t.id)
+			break
+		}
+	}
+	return found
+}
+
+func containsAll(s string, phrases []string) bool {
+	for _, p := range phrases {
+		if !strings.Contains(s, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// Scanner records the licenses of the files of a tree as it is hashed.
+// Pass its Inspect method as swhid.TreeOptions.Inspect; it is safe for
+// concurrent use, as parallel hashing needs.
+type Scanner struct {
+	mu    sync.Mutex
+	found map[string][]string
+}
+
+// NewScanner returns an empty Scanner.
+func NewScanner() *Scanner {
+	return &Scanner{found: make(map[string][]string)}
+}
+
+// Inspect returns a writer collecting the first ScanSize bytes of the file
+// at relPath and detecting its licenses when closed.
+func (s *Scanner) Inspect(relPath string, info os.FileInfo) io.Writer {
+	return &fileScan{s: s, path: relPath}
+}
+
+// Licenses returns the licenses found in the file at relPath, nil if none
+// were or it was not scanned.
+func (s *Scanner) Licenses(relPath string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.found[relPath]
+}
+
+// Files returns the licenses found by path, for the files that have any.
+func (s *Scanner) Files() map[string][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files := make(map[string][]string, len(s.found))
+	for path, ids := range s.found {
+		files[path] = ids
+	}
+	return files
+}
+
+// fileScan buffers the head of one file for its Scanner.
+type fileScan struct {
+	s    *Scanner
+	path string
+	buf  bytes.Buffer
+}
+
+func (f *fileScan) Write(p []byte) (int, error) {
+	if n := ScanSize - f.buf.Len(); n > 0 {
+		f.buf.Write(p[:min(n, len(p))])
+	}
+	return len(p), nil
+}
+
+func (f *fileScan) Close() error {
+	if ids := Detect(f.buf.Bytes()); len(ids) > 0 {
+		f.s.mu.Lock()
+		f.s.found[f.path] = ids
+		f.s.mu.Unlock()
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package license
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/andrew/swhid-go"
+)
+
+const mitText = `MIT License
+
+Copyright (c) 2024 Example
+
+Permission is hereby granted, free of charge, to any person obtaining a
+copy of this software and associated documentation files (the "Software"),
+to deal in the Software without restriction.
+`
+
+const bsd3Text = `Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice,
+   this list of conditions and the following disclaimer.
+3. Neither the name of the copyright holder nor the names of its
+   contributors may be used to endorse or promote products.
+`
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{"none", "package main\n", nil},
+		{"spdx go", "// SPDX-License-Identifier: Apache-2.0\npackage main\n", []string{"Apache-2.0"}},
+		{"spdx c comment", "/* SPDX-License-Identifier: GPL-2.0-only OR MIT */\n", []string{"GPL-2.0-only OR MIT"}},
+		{"spdx html", "<!-- SPDX-License-Identifier: CC-BY-4.0 -->\n", []string{"CC-BY-4.0"}},
+		{"spdx repeated", "# SPDX-License-Identifier: MIT\n# SPDX-License-Identifier: MIT\n", []string{"MIT"}},
+		{"spdx empty", "SPDX-License-Identifier: `\n", nil},
+		{"mit text", mitText, []string{"MIT"}},
+		{"bsd-3-clause text", bsd3Text, []string{"BSD-3-Clause"}},
+		{"lgpl before gpl", "GNU LESSER GENERAL PUBLIC LICENSE\nVersion 3, 29 June 2007\n\nThis version of the GNU General Public License\n", []string{"LGPL-3.0"}},
+		{"tag and text", "SPDX-License-Identifier: MIT\n\n" + mitText, []string{"MIT"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect([]byte(tt.data)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectScanSize(t *testing.T) {
+	data := append(bytes.Repeat([]byte("x"), ScanSize), "\nSPDX-License-Identifier: MIT\n"...)
+	if got := Detect(data); got != nil {
+		t.Errorf("Detect() = %q past ScanSize, want nil", got)
+	}
+}
+
+func TestScanner(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"LICENSE":     mitText,
+		"main.go":     "// SPDX-License-Identifier: Apache-2.0\npackage main\n",
+		"README":      "hello\n",
+		"src/util.go": "// SPDX-License-Identifier: BSD-3-Clause\npackage src\n",
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := map[string][]string{
+		"LICENSE":     {"MIT"},
+		"main.go":     {"Apache-2.0"},
+		"src/util.go": {"BSD-3-Clause"},
+	}
+
+	for _, concurrency := range []int{0, 3} {
+		s := NewScanner()
+		if _, err := swhid.TreeFromDirectoryPathWithOptions(dir, swhid.TreeOptions{Inspect: s.Inspect, Concurrency: concurrency}); err != nil {
+			t.Fatalf("TreeFromDirectoryPathWithOptions() error = %v", err)
+		}
+		if got := s.Files(); !reflect.DeepEqual(got, want) {
+			t.Errorf("concurrency %d: Files() = %q, want %q", concurrency, got, want)
+		}
+		if got := s.Licenses("README"); got != nil {
+			t.Errorf("Licenses(README) = %q, want nil", got)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+// Package magic detects the media type of file contents from their first
+// bytes, with signatures like libmagic's, so that `file --mime-type` and
+// Detect mostly agree, in pure Go and without a magic database.
+//
+// Detection only looks at the first HeadSize bytes. It knows common
+// archive, compression, executable, image, audio, video, font and document
+// formats, scripts by their #! line, and markup; other contents are
+// text/plain when they look like text and application/octet-stream
+// otherwise.
+package magic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// HeadSize is the number of leading bytes Detect looks at.
+const HeadSize = 4096
+
+// Media types without a signature of their own.
+const (
+	Empty  = "inode/x-empty"
+	Text   = "text/plain"
+	Binary = "application/octet-stream"
+)
+
+// signature is a format recognized by bytes at a fixed offset.
+type signature struct {
+	offset int
+	magic  string
+	mime   string
+}
+
+// signatures are tried in order; more specific ones come first.
+var signatures = []signature{
+	{0, "%PDF-", "application/pdf"},
+	{0, "%!PS", "application/postscript"},
+	{0, "{\\rtf", "text/rtf"},
+	{0, "\x89PNG\r\n\x1a\n", "image/png"},
+	{0, "\xff\xd8\xff", "image/jpeg"},
+	{0, "GIF87a", "image/gif"},
+	{0, "GIF89a", "image/gif"},
+	{0, "II*\x00", "image/tiff"},
+	{0, "MM\x00*", "image/tiff"},
+	{0, "\x00\x00\x01\x00", "image/vnd.microsoft.icon"},
+	{0, "8BPS", "image/vnd.adobe.photoshop"},
+	{0, "\x1f\x8b", "application/gzip"},
+	{0, "BZh", "application/x-bzip2"},
+	{0, "\xfd7zXZ\x00", "application/x-xz"},
+	{0, "\x28\xb5\x2f\xfd", "application/zstd"},
+	{0, "7z\xbc\xaf\x27\x1c", "application/x-7z-compressed"},
+	{0, "Rar!\x1a\x07", "application/x-rar"},
+	{0, "\x04\x22\x4d\x18", "application/x-lz4"},
+	{257, "ustar", "application/x-tar"},
+	{0, "!<arch>\n", "application/x-archive"},
+	{0, "\xed\xab\xee\xdb", "application/x-rpm"},
+	{0, "\x00asm", "application/wasm"},
+	{0, "MZ", "application/x-dosexec"},
+	{0, "\xfe\xed\xfa\xce", "application/x-mach-binary"},
+	{0, "\xfe\xed\xfa\xcf", "application/x-mach-binary"},
+	{0, "\xce\xfa\xed\xfe", "application/x-mach-binary"},
+	{0, "\xcf\xfa\xed\xfe", "application/x-mach-binary"},
+	{0, "SQLite format 3\x00", "application/vnd.sqlite3"},
+	{0, "OggS", "audio/ogg"},
+	{0, "fLaC", "audio/flac"},
+	{0, "ID3", "audio/mpeg"},
+	{0, "MThd", "audio/midi"},
+	{0, "wOFF", "font/woff"},
+	{0, "wOF2", "font/woff2"},
+	{0, "\x00\x01\x00\x00\x00", "font/sfnt"},
+	{0, "OTTO", "font/sfnt"},
+	{0, "-----BEGIN PGP", "application/pgp-keys"},
+}
+
+// Detect returns the media type of contents starting with head, at most
+// HeadSize bytes of them, whose full length is size. Knowing the whole
+// contents, when head holds all size bytes, lets it tell JSON from other
+// text.
+func Detect(head []byte, size int64) string {
+	if size == 0 {
+		return Empty
+	}
+	if len(head) > HeadSize {
+		head = head[:HeadSize]
+	}
+	complete := int64(len(head)) >= size
+
+	switch {
+	case bytes.HasPrefix(head, []byte("\x7fELF")):
+		return elf(head)
+	case bytes.HasPrefix(head, []byte("\xca\xfe\xba\xbe")):
+		// Java class files and universal Mach-O binaries share the magic;
+		// the next word is a class file version (45 and up) or a small
+		// count of architectures.
+		if len(head) >= 8 && binary.BigEndian.Uint32(head[4:]) >= 45 {
+			return "application/x-java-applet"
+		}
+		return "application/x-mach-binary"
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		return zip(head)
+	case bytes.HasPrefix(head, []byte("RIFF")) && len(head) >= 12:
+		switch string(head[8:12]) {
+		case "WEBP":
+			return "image/webp"
+		case "WAVE":
+			return "audio/x-wav"
+		case "AVI ":
+			return "video/x-msvideo"
+		}
+	case len(head) >= 12 && string(head[4:8]) == "ftyp":
+		return isoMedia(string(head[8:12]))
+	case bytes.HasPrefix(head, []byte("\x1a\x45\xdf\xa3")):
+		if bytes.Contains(head, []byte("webm")) {
+			return "video/webm"
+		}
+		return "video/x-matroska"
+	case len(head) >= 2 && head[0] == 0xff && head[1]&0xe0 == 0xe0 && head[1]&0x06 != 0:
+		return "audio/mpeg"
+	}
+
+	for _, s := range signatures {
+		if len(head) >= s.offset+len(s.magic) && string(head[s.offset:s.offset+len(s.magic)]) == s.magic {
+			return s.mime
+		}
+	}
+
+	if bytes.HasPrefix(head, []byte("\xfe\xff")) || bytes.HasPrefix(head, []byte("\xff\xfe")) {
+		return Text // UTF-16 with a byte order mark
+	}
+	if !isText(head) {
+		return Binary
+	}
+	text := bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+	if bytes.HasPrefix(text, []byte("#!")) {
+		return script(text)
+	}
+	if mime := markup(text); mime != "" {
+		return mime
+	}
+	if complete {
+		trimmed := bytes.TrimSpace(text)
+		if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+			return "application/json"
+		}
+	}
+	return Text
+}
+
+// elf tells ELF executables, shared objects, relocatable objects and core
+// dumps apart by the file type field.
+func elf(head []byte) string {
+	if len(head) < 18 {
+		return Binary
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if head[5] == 2 {
+		order = binary.BigEndian
+	}
+	switch order.Uint16(head[16:]) {
+	case 1:
+		return "application/x-object"
+	case 2:
+		return "application/x-executable"
+	case 3:
+		return "application/x-sharedlib"
+	case 4:
+		return "application/x-coredump"
+	}
+	return Binary
+}
+
+// zip recognizes the zip-based formats that name themselves in a first,
+// stored entry called mimetype (EPUB, OpenDocument) and Office Open XML
+// and Java archives by their first entry's name.
+func zip(head []byte) string {
+	if len(head) < 30 {
+		return "application/zip"
+	}
+	nameLen := int(binary.LittleEndian.Uint16(head[26:]))
+	extraLen := int(binary.LittleEndian.Uint16(head[28:]))
+	if 30+nameLen > len(head) {
+		return "application/zip"
+	}
+	name := string(head[30 : 30+nameLen])
+	switch {
+	case name == "mimetype" && binary.LittleEndian.Uint16(head[8:]) == 0:
+		start := 30 + nameLen + extraLen
+		size := int(binary.LittleEndian.Uint32(head[18:]))
+		if size > 0 && size < 100 && start+size <= len(head) {
+			if mime := string(head[start : start+size]); strings.HasPrefix(mime, "application/") {
+				return mime
+			}
+		}
+	case name == "[Content_Types].xml" || strings.HasPrefix(name, "word/"):
+		// The first entry does not say which Office format it is.
+		return "application/vnd.openxmlformats-officedocument"
+	case name == "META-INF/MANIFEST.MF" || name == "META-INF/":
+		return "application/java-archive"
+	}
+	return "application/zip"
+}
+
+// isoMedia maps the major brand of an ISO base media file.
+func isoMedia(brand string) string {
+	switch brand {
+	case "qt  ":
+		return "video/quicktime"
+	case "M4A ", "M4B ":
+		return "audio/x-m4a"
+	case "heic", "heix", "mif1":
+		return "image/heic"
+	case "avif":
+		return "image/avif"
+	case "3gp4", "3gp5", "3gp6":
+		return "video/3gpp"
+	}
+	return "video/mp4"
+}
+
+// interpreters maps the interpreters of #! lines to script types.
+var interpreters = map[string]string{
+	"sh":      "text/x-shellscript",
+	"bash":    "text/x-shellscript",
+	"dash":    "text/x-shellscript",
+	"zsh":     "text/x-shellscript",
+	"ksh":     "text/x-shellscript",
+	"fish":    "text/x-shellscript",
+	"python":  "text/x-script.python",
+	"perl":    "text/x-perl",
+	"ruby":    "text/x-ruby",
+	"node":    "application/javascript",
+	"php":     "text/x-php",
+	"lua":     "text/x-lua",
+	"tclsh":   "text/x-tcl",
+	"awk":     "text/x-awk",
+	"make":    "text/x-makefile",
+	"Rscript": "text/x-R",
+}
+
+// script returns the type of a script by the interpreter its #! line
+// names, directly or through env.
+func script(text []byte) string {
+	line, _, _ := bytes.Cut(text[2:], []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return Text
+	}
+	name := path.Base(fields[0])
+	if name == "env" {
+		name = ""
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") && !strings.Contains(f, "=") {
+				name = path.Base(f)
+				break
+			}
+		}
+	}
+	// python3, python3.12, perl5 and the like
+	base := strings.TrimRight(name, "0123456789.")
+	if mime, ok := interpreters[base]; ok {
+		return mime
+	}
+	if mime, ok := interpreters[name]; ok {
+		return mime
+	}
+	return "text/x-script"
+}
+
+// markup recognizes HTML, SVG and XML by their first tag.
+func markup(text []byte) string {
+	t := bytes.TrimLeft(text, " \t\r\n")
+	lower := bytes.ToLower(t[:min(len(t), 512)])
+	switch {
+	case bytes.HasPrefix(lower, []byte("<!doctype html")), bytes.HasPrefix(lower, []byte("<html")),
+		bytes.HasPrefix(lower, []byte("<head")), bytes.HasPrefix(lower, []byte("<body")):
+		return "text/html"
+	case bytes.HasPrefix(lower, []byte("<svg")):
+		return "image/svg+xml"
+	case bytes.HasPrefix(lower, []byte("<?xml")):
+		if bytes.Contains(lower, []byte("<svg")) {
+			return "image/svg+xml"
+		}
+		return "text/xml"
+	}
+	return ""
+}
+
+// isText reports whether b looks like text: no NUL bytes or control
+// characters other than the usual whitespace, backspace and escape. Bytes
+// that are not UTF-8 are allowed, as in Latin-1 text, but a cut-off rune
+// at the end is not held against it either way.
+func isText(b []byte) bool {
+	for len(b) > 0 {
+		c := b[0]
+		if c < 0x20 {
+			switch c {
+			case '\t', '\n', '\r', '\f', '\v', '\b', 0x1b:
+			default:
+				return false
+			}
+		} else if c == 0x7f {
+			return false
+		}
+		if c < utf8.RuneSelf {
+			b = b[1:]
+			continue
+		}
+		_, n := utf8.DecodeRune(b)
+		b = b[n:]
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+package magic
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tar := make([]byte, 512)
+	copy(tar, "file.txt")
+	copy(tar[257:], "ustar\x0000")
+
+	elfExec := append([]byte("\x7fELF\x02\x01\x01"), make([]byte, 11)...)
+	elfExec[16] = 2
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"empty", nil, Empty},
+		{"text", []byte("hello\n"), Text},
+		{"latin-1 text", []byte("caf\xe9\n"), Text},
+		{"binary", []byte{0, 1, 2, 3}, Binary},
+		{"shell", []byte("#!/bin/sh\necho hi\n"), "text/x-shellscript"},
+		{"env python", []byte("#!/usr/bin/env -S python3.12 -u\n"), "text/x-script.python"},
+		{"unknown interpreter", []byte("#!/opt/bin/frob\n"), "text/x-script"},
+		{"json", []byte(` {"a": [1, 2]}` + "\n"), "application/json"},
+		{"not json", []byte("{not json}\n"), Text},
+		{"html", []byte("<!DOCTYPE html>\n<html></html>\n"), "text/html"},
+		{"svg", []byte("<?xml version=\"1.0\"?>\n<svg xmlns=\"http://www.w3.org/2000/svg\"/>\n"), "image/svg+xml"},
+		{"xml", []byte("<?xml version=\"1.0\"?>\n<project/>\n"), "text/xml"},
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "image/png"},
+		{"gzip", []byte("\x1f\x8b\x08\x00"), "application/gzip"},
+		{"pdf", []byte("%PDF-1.7\n"), "application/pdf"},
+		{"tar", tar, "application/x-tar"},
+		{"zip", []byte("PK\x03\x04\x14\x00"), "application/zip"},
+		{"elf executable", elfExec, "application/x-executable"},
+		{"java class", []byte("\xca\xfe\xba\xbe\x00\x00\x00\x41"), "application/x-java-applet"},
+		{"universal mach-o", []byte("\xca\xfe\xba\xbe\x00\x00\x00\x02"), "application/x-mach-binary"},
+		{"webp", []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), "image/webp"},
+		{"mp4", []byte("\x00\x00\x00\x18ftypisom"), "video/mp4"},
+		{"wasm", []byte("\x00asm\x01\x00\x00\x00"), "application/wasm"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.data, int64(len(tt.data))); got != tt.want {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectHead(t *testing.T) {
+	// JSON is only recognized when the whole contents were seen
+	data := []byte(`{"a": 1}`)
+	if got := Detect(data, 1<<20); got != Text {
+		t.Errorf("Detect() of a truncated head = %q, want %q", got, Text)
+	}
+
+	// Bytes past HeadSize are not looked at
+	long := append(bytes.Repeat([]byte("a"), HeadSize), 0)
+	if got := Detect(long, int64(len(long))); got != Text {
+		t.Errorf("Detect() = %q, want %q", got, Text)
+	}
+}
+
+func TestDetectOpenDocument(t *testing.T) {
+	mime := "application/vnd.oasis.opendocument.text"
+	head := []byte("PK\x03\x04\x14\x00\x00\x00\x00\x00")
+	head = append(head, make([]byte, 8)...)             // time, date, crc
+	head = append(head, byte(len(mime)), 0, 0, 0)       // compressed size
+	head = append(head, byte(len(mime)), 0, 0, 0)       // uncompressed size
+	head = append(head, byte(len("mimetype")), 0, 0, 0) // name and extra lengths
+	head = append(head, "mimetype"+mime...)
+	if got := Detect(head, 1<<10); got != mime {
+		t.Errorf("Detect() = %q, want %q", got, mime)
+	}
+}
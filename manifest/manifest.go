@@ -0,0 +1,154 @@
+// Package manifest lists every object of a directory tree with its path,
+// type, SWHID and size, and writes such listings as NDJSON.
+//
+// Every line carries four fields per object:
+//
+//	path   slash-separated path from the tree root, "" for the root itself
+//	type   file, executable, directory, symlink or submodule
+//	swhid  core SWHID of the object
+//	size   content bytes at or below the object
+//
+// Lines also carry a mime field, the media type of a file, when the tree
+// was built with TreeOptions.DetectMIME, and a licenses list when the
+// caller filled in Entry.Licenses.
+package manifest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/objects"
+)
+
+// Entry is one object of a tree.
+type Entry struct {
+	Path  string
+	Type  string
+	SWHID *swhid.Identifier
+	Size  int64
+
+	// MIMEType is the media type of a file's contents, if detected.
+	MIMEType string
+
+	// Licenses are the licenses found in a file, such as by a
+	// license.Scanner; Walk leaves them empty.
+	Licenses []string
+}
+
+// Writer receives manifest entries one at a time. Close must be called to
+// flush buffered output; it does not close the underlying io.Writer.
+type Writer interface {
+	Write(Entry) error
+	Close() error
+}
+
+// TypeName returns the manifest name of a directory entry type.
+func TypeName(t objects.EntryType) string {
+	switch t {
+	case objects.EntryTypeExecutable:
+		return "executable"
+	case objects.EntryTypeDirectory:
+		return "directory"
+	case objects.EntryTypeSymlink:
+		return "symlink"
+	case objects.EntryTypeRevision:
+		return "submodule"
+	default:
+		return "file"
+	}
+}
+
+// Walk calls fn with an entry for root and every node below it, in
+// depth-first tree order, stopping at the first error fn returns.
+func Walk(root *swhid.Node, fn func(Entry) error) error {
+	var err error
+	root.Walk(func(n *swhid.Node) bool {
+		if err != nil {
+			return false
+		}
+		err = fn(Entry{Path: n.Path, Type: TypeName(n.Type), SWHID: n.ID, Size: n.Size, MIMEType: n.MIMEType})
+		return err == nil
+	})
+	return err
+}
+
+// Write writes every entry of the tree at root to w and closes w.
+func Write(root *swhid.Node, w Writer) error {
+	if err := Walk(root, w.Write); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// WriterFunc is a Writer that calls the function with each entry, for
+// callers that consume entries as they are produced rather than collect
+// them. Close does nothing.
+type WriterFunc func(Entry) error
+
+func (f WriterFunc) Write(e Entry) error { return f(e) }
+
+func (f WriterFunc) Close() error { return nil }
+
+type chanWriter struct {
+	ctx context.Context
+	ch  chan<- Entry
+}
+
+// NewChanWriter returns a Writer that sends each entry on ch, blocking
+// until it is received, so a slow consumer holds back the walk instead of
+// entries piling up in memory. Write fails with ctx's error once ctx is
+// done. Close closes ch.
+func NewChanWriter(ctx context.Context, ch chan<- Entry) Writer {
+	return &chanWriter{ctx: ctx, ch: ch}
+}
+
+func (w *chanWriter) Write(e Entry) error {
+	select {
+	case w.ch <- e:
+		return nil
+	case <-w.ctx.Done():
+		return w.ctx.Err()
+	}
+}
+
+func (w *chanWriter) Close() error {
+	close(w.ch)
+	return nil
+}
+
+type ndjsonWriter struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+// NewNDJSONWriter returns a Writer producing one JSON object per line.
+func NewNDJSONWriter(w io.Writer) Writer {
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	return &ndjsonWriter{buf: buf, enc: enc}
+}
+
+func (w *ndjsonWriter) Write(e Entry) error {
+	row := map[string]interface{}{
+		"path":  e.Path,
+		"type":  e.Type,
+		"swhid": e.SWHID.CoreSWHID(),
+		"size":  e.Size,
+	}
+	if e.MIMEType != "" {
+		row["mime"] = e.MIMEType
+	}
+	if len(e.Licenses) > 0 {
+		row["licenses"] = e.Licenses
+	}
+	return w.enc.Encode(row)
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.buf.Flush()
+}
@@ -0,0 +1,175 @@
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrew/swhid-go"
+)
+
+func testTree(t *testing.T) *swhid.Node {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("hello\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "run.sh"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	root, err := swhid.TreeFromDirectoryPath(dir)
+	if err != nil {
+		t.Fatalf("TreeFromDirectoryPath() error = %v", err)
+	}
+	return root
+}
+
+func TestWalk(t *testing.T) {
+	root := testTree(t)
+
+	var entries []Entry
+	if err := Walk(root, func(e Entry) error {
+		entries = append(entries, e)
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+
+	want := []struct {
+		path, typ string
+		size      int64
+	}{
+		{"", "directory", 16},
+		{"README", "file", 6},
+		{"src", "directory", 10},
+		{"src/run.sh", "executable", 10},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("Walk() visited %d entries, want %d", len(entries), len(want))
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.Path != w.path || e.Type != w.typ || e.Size != w.size {
+			t.Errorf("entry %d = %s %s %d, want %s %s %d", i, e.Path, e.Type, e.Size, w.path, w.typ, w.size)
+		}
+	}
+	if !entries[0].SWHID.Equal(root.ID) {
+		t.Errorf("root SWHID = %v, want %v", entries[0].SWHID, root.ID)
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	root := testTree(t)
+
+	var buf bytes.Buffer
+	if err := Write(root, NewNDJSONWriter(&buf)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	scanner := bufio.NewScanner(&buf)
+	lines := 0
+	for scanner.Scan() {
+		var row map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			t.Fatalf("line %d: %v", lines+1, err)
+		}
+		if lines == 0 && row["swhid"] != root.ID.CoreSWHID() {
+			t.Errorf("first row swhid = %v, want %v", row["swhid"], root.ID)
+		}
+		lines++
+	}
+	if lines != 4 {
+		t.Errorf("wrote %d lines, want 4", lines)
+	}
+}
+
+func TestWriterFunc(t *testing.T) {
+	root := testTree(t)
+
+	var paths []string
+	stop := errors.New("stop")
+	err := Write(root, WriterFunc(func(e Entry) error {
+		paths = append(paths, e.Path)
+		if e.Path == "src" {
+			return stop
+		}
+		return nil
+	}))
+	if !errors.Is(err, stop) {
+		t.Fatalf("Write() error = %v, want %v", err, stop)
+	}
+	if len(paths) != 3 {
+		t.Errorf("Write() passed %q, want the entries up to src", paths)
+	}
+}
+
+func TestChanWriter(t *testing.T) {
+	root := testTree(t)
+
+	ch := make(chan Entry)
+	done := make(chan error, 1)
+	go func() { done <- Write(root, NewChanWriter(context.Background(), ch)) }()
+	var n int
+	for e := range ch {
+		if n == 0 && !e.SWHID.Equal(root.ID) {
+			t.Errorf("first entry = %v, want %v", e.SWHID, root.ID)
+		}
+		n++
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if n != 4 {
+		t.Errorf("received %d entries, want 4", n)
+	}
+
+	// Nobody receives: Write gives up once the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Write(root, NewChanWriter(ctx, make(chan Entry))); !errors.Is(err, context.Canceled) {
+		t.Errorf("Write() with canceled context error = %v", err)
+	}
+}
+
+func TestNDJSONWriterMIME(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "run.sh"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	root, err := swhid.TreeFromDirectoryPathWithOptions(dir, swhid.TreeOptions{DetectMIME: true})
+	if err != nil {
+		t.Fatalf("TreeFromDirectoryPathWithOptions() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(root, NewNDJSONWriter(&buf)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("wrote %d lines, want 2", len(lines))
+	}
+	var dirRow, fileRow map[string]interface{}
+	if err := json.Unmarshal(lines[0], &dirRow); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(lines[1], &fileRow); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := dirRow["mime"]; ok {
+		t.Errorf("directory row has mime %v", dirRow["mime"])
+	}
+	if fileRow["mime"] != "text/x-shellscript" {
+		t.Errorf("file row mime = %v, want text/x-shellscript", fileRow["mime"])
+	}
+}
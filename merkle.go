@@ -0,0 +1,97 @@
+package swhid
+
+import (
+	"path"
+	"sort"
+	"time"
+
+	"github.com/andrew/swhid-go/objects"
+)
+
+// Node is one object in a directory Merkle tree: a directory, file, symlink
+// or submodule, together with the SWHID of everything below it.
+type Node struct {
+	Name     string
+	Path     string // slash-separated path from the root, "" for the root itself
+	Type     objects.EntryType
+	ID       *Identifier
+	Size     int64     // content bytes at or below this node
+	ModTime  time.Time // modification time reported by the filesystem, if any
+	Children []*Node   // directory entries in tree order; nil for other types
+
+	// MIMEType is the media type of a file's contents, such as
+	// "text/x-shellscript", when TreeOptions.DetectMIME asked for it.
+	MIMEType string
+}
+
+// Entry returns the directory entry describing the node in its parent.
+func (n *Node) Entry() objects.DirectoryEntry {
+	return objects.DirectoryEntry{
+		Name:   n.Name,
+		Type:   n.Type,
+		Target: n.ID.ObjectHash,
+		Size:   n.Size,
+	}
+}
+
+// Walk calls fn for the node and its descendants in depth-first tree order.
+// Children of a node are skipped when fn returns false for it.
+func (n *Node) Walk(fn func(*Node) bool) {
+	if !fn(n) {
+		return
+	}
+	for _, child := range n.Children {
+		child.Walk(fn)
+	}
+}
+
+// Files returns the number of non-directory nodes at or below n.
+func (n *Node) Files() int {
+	count := 0
+	n.Walk(func(node *Node) bool {
+		if node.Type != objects.EntryTypeDirectory {
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+// newContentNode hashes a file or symlink; for symlinks data is the target.
+func newContentNode(parent, name string, entryType objects.EntryType, data []byte) *Node {
+	return &Node{
+		Name: name,
+		Path: path.Join(parent, name),
+		Type: entryType,
+		ID:   FromContent(data),
+		Size: int64(len(data)),
+	}
+}
+
+// newDirectoryNode sorts children into tree order and hashes them.
+func newDirectoryNode(dirPath, name string, children []*Node) *Node {
+	sort.Slice(children, func(i, j int) bool {
+		a, b := children[i].Entry(), children[j].Entry()
+		return a.SortKey() < b.SortKey()
+	})
+
+	entries := make([]objects.DirectoryEntry, len(children))
+	var size int64
+	for i, child := range children {
+		entries[i] = child.Entry()
+		size += child.Size
+	}
+
+	if children == nil {
+		children = []*Node{}
+	}
+
+	return &Node{
+		Name:     name,
+		Path:     dirPath,
+		Type:     objects.EntryTypeDirectory,
+		ID:       FromDirectory(entries),
+		Size:     size,
+		Children: children,
+	}
+}
@@ -0,0 +1,65 @@
+package swhid
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTreeFromDirectoryPath(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "swhid-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := os.MkdirAll(filepath.Join(tmpDir, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create subdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "hello.txt"), []byte("hello\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "sub", "file.txt"), []byte("test\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tree, err := TreeFromDirectoryPath(tmpDir)
+	if err != nil {
+		t.Fatalf("TreeFromDirectoryPath() error = %v", err)
+	}
+
+	id, err := FromDirectoryPath(tmpDir)
+	if err != nil {
+		t.Fatalf("FromDirectoryPath() error = %v", err)
+	}
+	if !tree.ID.Equal(id) {
+		t.Errorf("TreeFromDirectoryPath() root = %v, want %v", tree.ID, id)
+	}
+
+	if tree.Size != 11 {
+		t.Errorf("Size = %d, want 11", tree.Size)
+	}
+	if tree.Files() != 2 {
+		t.Errorf("Files() = %d, want 2", tree.Files())
+	}
+	for i, want := range []int64{6, 5} {
+		if got := tree.Children[i].Entry().Size; got != want {
+			t.Errorf("%s Entry().Size = %d, want %d", tree.Children[i].Name, got, want)
+		}
+	}
+
+	var paths []string
+	tree.Walk(func(n *Node) bool {
+		paths = append(paths, n.Path)
+		return true
+	})
+	want := []string{"", "hello.txt", "sub", "sub/file.txt"}
+	if len(paths) != len(want) {
+		t.Fatalf("Walk() visited %v, want %v", paths, want)
+	}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Errorf("Walk() path %d = %q, want %q", i, paths[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package swhid
+
+import (
+	"io"
+	"os"
+
+	"github.com/andrew/swhid-go/magic"
+)
+
+// headBuffer keeps the first magic.HeadSize bytes written to it, so that
+// the media type of a file can be detected from the same read that hashes
+// it.
+type headBuffer struct {
+	b []byte
+}
+
+func (h *headBuffer) Write(p []byte) (int, error) {
+	if n := magic.HeadSize - len(h.b); n > 0 {
+		h.b = append(h.b, p[:min(n, len(p))]...)
+	}
+	return len(p), nil
+}
+
+// detect returns the media type of the size bytes whose head h kept.
+func (h *headBuffer) detect(size int64) string {
+	return magic.Detect(h.b, size)
+}
+
+// fileMIMEType detects the media type of the file at path, of size bytes,
+// for files whose SWHID is known without reading them.
+func fileMIMEType(path string, size int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	head := make([]byte, min(size, magic.HeadSize))
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return magic.Detect(head[:n], size), nil
+}
@@ -0,0 +1,87 @@
+package swhid
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTreeDetectMIME(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"README":      []byte("hello\n"),
+		"run.sh":      []byte("#!/bin/sh\necho hi\n"),
+		"data.json":   []byte(`{"a": 1}`),
+		"big.bin":     append([]byte("\x1f\x8b\x08\x00"), bytes.Repeat([]byte{0}, StreamThreshold)...),
+		"empty":       nil,
+		"src/page.ht": []byte("<html><body></body></html>\n"),
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Link(filepath.Join(dir, "run.sh"), filepath.Join(dir, "src", "run-link.sh")); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"README":          "text/plain",
+		"run.sh":          "text/x-shellscript",
+		"data.json":       "application/json",
+		"big.bin":         "application/gzip",
+		"empty":           "inode/x-empty",
+		"src/page.ht":     "text/html",
+		"src/run-link.sh": "text/x-shellscript",
+	}
+
+	plain, err := TreeFromDirectoryPathWithOptions(dir, TreeOptions{})
+	if err != nil {
+		t.Fatalf("TreeFromDirectoryPathWithOptions() error = %v", err)
+	}
+	cached := func(relPath string, info os.FileInfo) *Identifier {
+		if relPath == "README" {
+			return FromContent(files["README"])
+		}
+		return nil
+	}
+	for _, opts := range []TreeOptions{
+		{DetectMIME: true},
+		{DetectMIME: true, Concurrency: 4},
+		{DetectMIME: true, Cached: cached},
+	} {
+		root, err := TreeFromDirectoryPathWithOptions(dir, opts)
+		if err != nil {
+			t.Fatalf("TreeFromDirectoryPathWithOptions(%+v) error = %v", opts, err)
+		}
+		if !root.ID.Equal(plain.ID) {
+			t.Errorf("DetectMIME changed the SWHID: %v, want %v", root.ID, plain.ID)
+		}
+		got := map[string]string{}
+		root.Walk(func(n *Node) bool {
+			if n.MIMEType != "" {
+				got[n.Path] = n.MIMEType
+			}
+			return true
+		})
+		for path, mime := range want {
+			if got[path] != mime {
+				t.Errorf("concurrency %d: %s MIMEType = %q, want %q", opts.Concurrency, path, got[path], mime)
+			}
+		}
+		if len(got) != len(want) {
+			t.Errorf("concurrency %d: MIME types for %v, want only files", opts.Concurrency, got)
+		}
+	}
+
+	plain.Walk(func(n *Node) bool {
+		if n.MIMEType != "" {
+			t.Errorf("%s MIMEType = %q without DetectMIME", n.Path, n.MIMEType)
+		}
+		return true
+	})
+}
@@ -0,0 +1,68 @@
+package swhid
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/andrew/swhid-go/internal/pathmatch"
+)
+
+// modeTable holds TreeOptions.Modes, split into exact paths, looked up
+// directly, and patterns, tried from the most specific. A key without a
+// slash is a pattern even without wildcards, since like an Include pattern
+// it matches a file of that name at any depth.
+type modeTable struct {
+	exact    map[string]os.FileMode
+	patterns []modePattern
+}
+
+type modePattern struct {
+	pattern string
+	mode    os.FileMode
+}
+
+func newModeTable(modes map[string]os.FileMode) (*modeTable, error) {
+	if len(modes) == 0 {
+		return nil, nil
+	}
+
+	t := &modeTable{exact: make(map[string]os.FileMode)}
+	for key, mode := range modes {
+		p, err := pathmatch.Clean(key)
+		if err != nil {
+			return nil, err
+		}
+		if strings.ContainsAny(p, `*?[\`) || !strings.Contains(p, "/") {
+			t.patterns = append(t.patterns, modePattern{p, mode})
+		} else {
+			t.exact[p] = mode
+		}
+	}
+
+	// Longer patterns name fewer files: "src/*.sh" wins over "*.sh".
+	sort.Slice(t.patterns, func(i, j int) bool {
+		a, b := t.patterns[i].pattern, t.patterns[j].pattern
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+		return a < b
+	})
+	return t, nil
+}
+
+// lookup returns the mode set for the file at relPath, if any.
+func (t *modeTable) lookup(relPath string) (os.FileMode, bool) {
+	if t == nil {
+		return 0, false
+	}
+	if mode, ok := t.exact[relPath]; ok {
+		return mode, true
+	}
+	for _, p := range t.patterns {
+		if pathmatch.Match(p.pattern, relPath) {
+			return p.mode, true
+		}
+	}
+	return 0, false
+}
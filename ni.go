@@ -0,0 +1,92 @@
+package swhid
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// NamedInformationAlgorithm is the RFC 6920 hash name used for SWHID
+// hashes, which are SHA-1 digests of the Git serialization of an object.
+const NamedInformationAlgorithm = "sha-1"
+
+// ErrNotNamedInformation is returned when a URI cannot be mapped to a SWHID.
+var ErrNotNamedInformation = errors.New("not a SWHID named-information or magnet URI")
+
+// NamedInformationURI returns the RFC 6920 named-information URI for the
+// object, "ni:///sha-1;<base64url hash>?type=<type>". The digest is the
+// SWHID hash, not a hash of the raw file bytes, so the object type travels
+// in the type query parameter; qualifiers are dropped.
+func (id *Identifier) NamedInformationURI() string {
+	raw, _ := hex.DecodeString(id.ObjectHash)
+	return "ni:///" + NamedInformationAlgorithm + ";" + base64.RawURLEncoding.EncodeToString(raw) + "?type=" + string(id.ObjectType)
+}
+
+// ParseNamedInformationURI converts a named-information URI produced by
+// NamedInformationURI back into a core SWHID. An authority, as in
+// ni://example.com/sha-1;..., is ignored. URIs with another algorithm or
+// without a type parameter are rejected, since neither says which SWHID
+// they correspond to.
+func ParseNamedInformationURI(uri string) (*Identifier, error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "ni" {
+		return nil, fmt.Errorf("%w: %s", ErrNotNamedInformation, uri)
+	}
+
+	algorithm, value, ok := strings.Cut(strings.TrimPrefix(u.Path, "/"), ";")
+	if !ok {
+		return nil, fmt.Errorf("%w: missing \"<algorithm>;<value>\" in %s", ErrNotNamedInformation, uri)
+	}
+	if algorithm != NamedInformationAlgorithm {
+		return nil, fmt.Errorf("%w: algorithm %s is not sha-1", ErrNotNamedInformation, algorithm)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(raw) != 20 {
+		return nil, fmt.Errorf("%w: invalid sha-1 value %q", ErrNotNamedInformation, value)
+	}
+
+	objectType := u.Query().Get("type")
+	if objectType == "" {
+		return nil, fmt.Errorf("%w: missing type parameter in %s", ErrNotNamedInformation, uri)
+	}
+	return NewIdentifier(ObjectType(objectType), hex.EncodeToString(raw), nil)
+}
+
+// MagnetURI returns a magnet link naming the object by its core SWHID as
+// exact topic, "magnet:?xt=urn:swh:1:<type>:<hash>".
+func (id *Identifier) MagnetURI() string {
+	return "magnet:?xt=urn:" + id.CoreSWHID()
+}
+
+// ParseMagnetURI returns the core SWHID in the first urn:swh: exact topic
+// (xt, or xt.1, xt.2 and so on) of a magnet link.
+func ParseMagnetURI(uri string) (*Identifier, error) {
+	if !strings.HasPrefix(uri, "magnet:?") {
+		return nil, fmt.Errorf("%w: %s", ErrNotNamedInformation, uri)
+	}
+	query, err := url.ParseQuery(strings.TrimPrefix(uri, "magnet:?"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotNamedInformation, err)
+	}
+
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		if key == "xt" || strings.HasPrefix(key, "xt.") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, v := range query[key] {
+			if strings.HasPrefix(v, "urn:"+Scheme+":") {
+				return Parse(strings.TrimPrefix(v, "urn:"))
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: no urn:swh: exact topic in %s", ErrNotNamedInformation, uri)
+}
@@ -0,0 +1,63 @@
+package swhid
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNamedInformationURI(t *testing.T) {
+	id, _ := Parse("swh:1:cnt:ce013625030ba8dba906f756967f9e9ca394464a;origin=https://example.com")
+
+	uri := id.NamedInformationURI()
+	if want := "ni:///sha-1;zgE2JQMLqNupBvdWln-enKOURko?type=cnt"; uri != want {
+		t.Errorf("NamedInformationURI() = %v, want %v", uri, want)
+	}
+
+	for _, s := range []string{uri, "ni://example.com/sha-1;zgE2JQMLqNupBvdWln-enKOURko?type=cnt"} {
+		parsed, err := ParseNamedInformationURI(s)
+		if err != nil {
+			t.Fatalf("ParseNamedInformationURI(%q) error = %v", s, err)
+		}
+		if parsed.String() != id.CoreSWHID() {
+			t.Errorf("ParseNamedInformationURI(%q) = %v, want %v", s, parsed, id.CoreSWHID())
+		}
+	}
+
+	for _, s := range []string{
+		"ni:///sha-1;zgE2JQMLqNupBvdWln-enKOURko",
+		"ni:///sha-256;f4OxZX_x_FO5LcGBSKHWXfwtSx-j1ncoSt3SABJtkGk?type=cnt",
+		"ni:///sha-1;zgE2JQ?type=cnt",
+		"ni:///sha-1;zgE2JQMLqNupBvdWln-enKOURko?type=xyz",
+		"https://example.com/sha-1;zgE2JQMLqNupBvdWln-enKOURko?type=cnt",
+	} {
+		if _, err := ParseNamedInformationURI(s); err == nil {
+			t.Errorf("ParseNamedInformationURI(%q) expected error", s)
+		}
+	}
+}
+
+func TestMagnetURI(t *testing.T) {
+	id, _ := Parse("swh:1:dir:d198bc9d7a6bcf6db04f476d29314f157507d505")
+
+	uri := id.MagnetURI()
+	if want := "magnet:?xt=urn:swh:1:dir:d198bc9d7a6bcf6db04f476d29314f157507d505"; uri != want {
+		t.Errorf("MagnetURI() = %v, want %v", uri, want)
+	}
+
+	for _, s := range []string{
+		uri,
+		"magnet:?dn=src&xt.1=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a&xt.2=urn:swh:1:dir:d198bc9d7a6bcf6db04f476d29314f157507d505",
+	} {
+		parsed, err := ParseMagnetURI(s)
+		if err != nil {
+			t.Fatalf("ParseMagnetURI(%q) error = %v", s, err)
+		}
+		if !parsed.Equal(id) {
+			t.Errorf("ParseMagnetURI(%q) = %v, want %v", s, parsed, id)
+		}
+	}
+
+	if _, err := ParseMagnetURI("magnet:?xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a"); !errors.Is(err, ErrNotNamedInformation) {
+		t.Errorf("ParseMagnetURI() error = %v, want ErrNotNamedInformation", err)
+	}
+}
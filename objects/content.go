@@ -4,14 +4,22 @@ import (
 	"crypto/sha1"
 	"encoding/hex"
 	"fmt"
+	"hash"
 )
 
 // ComputeContentHash computes the Git blob hash for file content.
 // The hash is computed using Git's blob format: "blob <size>\0<content>"
 func ComputeContentHash(data []byte) string {
-	header := fmt.Sprintf("blob %d\x00", len(data))
-	h := sha1.New()
-	h.Write([]byte(header))
+	h := NewContentHash(int64(len(data)))
 	h.Write(data)
 	return hex.EncodeToString(h.Sum(nil))
 }
+
+// NewContentHash returns a SHA-1 hash with the blob header for content of
+// the given size already written, for hashing content that is not held in
+// memory. Exactly size bytes must be written to it before calling Sum.
+func NewContentHash(size int64) hash.Hash {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", size)
+	return h
+}
@@ -1,12 +1,39 @@
 package objects
 
 import (
+	"bytes"
 	"crypto/sha1"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"sort"
+	"strings"
 )
 
+// Directory entry validation errors.
+var (
+	ErrEmptyEntryName     = errors.New("empty entry name")
+	ErrInvalidEntryName   = errors.New("entry name contains '/' or NUL")
+	ErrDuplicateEntryName = errors.New("duplicate entry name")
+	ErrInvalidEntryTarget = errors.New("entry target must be 40 hex digits")
+)
+
+// EntryError reports which directory entry is malformed.
+type EntryError struct {
+	Index int    // position of the entry in the slice passed by the caller
+	Name  string // entry name as given
+	Err   error
+}
+
+func (e *EntryError) Error() string {
+	return fmt.Sprintf("directory entry %d (%q): %v", e.Index, e.Name, e.Err)
+}
+
+func (e *EntryError) Unwrap() error {
+	return e.Err
+}
+
 // EntryType represents the type of a directory entry.
 type EntryType int
 
@@ -24,6 +51,12 @@ type DirectoryEntry struct {
 	Type   EntryType
 	Target string // 40-char hex hash
 	Perms  string // optional, uses default if empty
+
+	// Size is the number of content bytes at or below the entry when the
+	// caller knows it: the file length, the symlink target length, or the
+	// total over a directory's subtree. It is not part of the Git tree
+	// format, so it does not affect the hash and is zero after parsing.
+	Size int64
 }
 
 // DefaultPerms returns the default Git permissions for an entry type.
@@ -61,38 +94,287 @@ func (e *DirectoryEntry) SortKey() string {
 	return e.Name
 }
 
-// ComputeDirectoryHash computes the Git tree hash for a directory.
+// ComputeDirectoryHash computes the Git tree hash for a directory. The
+// entries are written into the hash one at a time rather than serialized
+// into one buffer first, and sorted through an index only if they are not
+// already in tree order, so the slice is neither copied nor modified.
 func ComputeDirectoryHash(entries []DirectoryEntry) string {
-	serialized := serializeEntries(entries)
-	header := fmt.Sprintf("tree %d\x00", len(serialized))
+	return computeDirectoryHash(entries, treeOrder(entries))
+}
+
+// ComputeDirectoryHashSorted is like ComputeDirectoryHash for entries the
+// caller already has in tree order, such as those returned by
+// ParseDirectory or sorted with SortEntries; it skips the order check.
+// Entries in any other order give a wrong hash.
+func ComputeDirectoryHashSorted(entries []DirectoryEntry) string {
+	return computeDirectoryHash(entries, nil)
+}
 
+func computeDirectoryHash(entries []DirectoryEntry, order []int) string {
 	h := sha1.New()
-	h.Write([]byte(header))
-	h.Write(serialized)
+	fmt.Fprintf(h, "tree %d\x00", payloadSize(entries))
+	writeEntries(h, entries, order)
 	return hex.EncodeToString(h.Sum(nil))
 }
 
-func serializeEntries(entries []DirectoryEntry) []byte {
-	// Sort entries by sort key
-	sorted := make([]DirectoryEntry, len(entries))
-	copy(sorted, entries)
-	sort.Slice(sorted, func(i, j int) bool {
-		return sorted[i].SortKey() < sorted[j].SortKey()
+// ComputeDirectoryHashStrict is like ComputeDirectoryHash but validates the
+// entries first, returning an *EntryError for the first malformed one.
+func ComputeDirectoryHashStrict(entries []DirectoryEntry) (string, error) {
+	if err := ValidateEntries(entries); err != nil {
+		return "", err
+	}
+	return ComputeDirectoryHash(entries), nil
+}
+
+// ValidateEntries checks that every entry has a non-empty name without '/'
+// or NUL, that names are unique, and that targets are 40-char hex hashes.
+func ValidateEntries(entries []DirectoryEntry) error {
+	seen := make(map[string]int, len(entries))
+	for i, entry := range entries {
+		var err error
+		switch {
+		case entry.Name == "":
+			err = ErrEmptyEntryName
+		case strings.ContainsAny(entry.Name, "/\x00"):
+			err = ErrInvalidEntryName
+		case !isHexHash(entry.Target):
+			err = ErrInvalidEntryTarget
+		}
+		if err == nil {
+			if first, ok := seen[entry.Name]; ok {
+				err = fmt.Errorf("%w: also at index %d", ErrDuplicateEntryName, first)
+			}
+		}
+		if err != nil {
+			return &EntryError{Index: i, Name: entry.Name, Err: err}
+		}
+		seen[entry.Name] = i
+	}
+	return nil
+}
+
+func isHexHash(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if fromHex(s[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// fromHex returns the value of a hex digit, or -1.
+func fromHex(c byte) int {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0')
+	case 'a' <= c && c <= 'f':
+		return int(c - 'a' + 10)
+	case 'A' <= c && c <= 'F':
+		return int(c - 'A' + 10)
+	default:
+		return -1
+	}
+}
+
+// appendTarget appends the binary form of a hex target to dst.
+func appendTarget(dst []byte, target string) []byte {
+	if !isHexHash(target) {
+		b, _ := hex.DecodeString(target)
+		return append(dst, b...)
+	}
+	for i := 0; i < len(target); i += 2 {
+		dst = append(dst, byte(fromHex(target[i])<<4|fromHex(target[i+1])))
+	}
+	return dst
+}
+
+// SerializeDirectory returns the Git tree object payload for a directory,
+// without the "tree <size>\0" header.
+func SerializeDirectory(entries []DirectoryEntry) []byte {
+	var buf bytes.Buffer
+	buf.Grow(payloadSize(entries))
+	writeEntries(&buf, entries, treeOrder(entries))
+	return buf.Bytes()
+}
+
+// WriteDirectory writes the payload SerializeDirectory returns to w, entry
+// by entry, and returns the number of bytes written.
+func WriteDirectory(w io.Writer, entries []DirectoryEntry) (int64, error) {
+	return writeEntries(w, entries, treeOrder(entries))
+}
+
+// SortEntries sorts entries in place into Git tree order: by name, with
+// directories compared as if their name ended in '/'.
+func SortEntries(entries []DirectoryEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return entryLess(&entries[i], &entries[j])
 	})
+}
+
+// EntriesSorted reports whether entries are in Git tree order.
+func EntriesSorted(entries []DirectoryEntry) bool {
+	for i := 1; i < len(entries); i++ {
+		if entryLess(&entries[i], &entries[i-1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ErrMalformedTree is returned by ParseDirectory for data that is not a
+// Git tree payload.
+var ErrMalformedTree = errors.New("malformed tree object")
+
+// ParseDirectory decodes a Git tree object payload, as produced by
+// SerializeDirectory, into its entries. Every entry keeps the mode it was
+// stored with in Perms.
+func ParseDirectory(data []byte) ([]DirectoryEntry, error) {
+	var entries []DirectoryEntry
+	for len(data) > 0 {
+		sp := bytes.IndexByte(data, ' ')
+		if sp <= 0 {
+			return nil, ErrMalformedTree
+		}
+		perms := string(data[:sp])
+		data = data[sp+1:]
+
+		nul := bytes.IndexByte(data, 0)
+		if nul <= 0 || len(data) < nul+1+20 {
+			return nil, ErrMalformedTree
+		}
+		name := string(data[:nul])
+		target := hex.EncodeToString(data[nul+1 : nul+21])
+		data = data[nul+21:]
+
+		var typ EntryType
+		switch perms {
+		case "40000":
+			typ = EntryTypeDirectory
+		case "100755":
+			typ = EntryTypeExecutable
+		case "120000":
+			typ = EntryTypeSymlink
+		case "160000":
+			typ = EntryTypeRevision
+		default:
+			typ = EntryTypeFile
+		}
+		entries = append(entries, DirectoryEntry{Name: name, Type: typ, Target: target, Perms: perms})
+	}
+	return entries, nil
+}
+
+// treeOrder returns the indexes of entries in tree order, or nil if the
+// entries are already sorted.
+func treeOrder(entries []DirectoryEntry) []int {
+	if EntriesSorted(entries) {
+		return nil
+	}
+	order := make([]int, len(entries))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return entryLess(&entries[order[i]], &entries[order[j]])
+	})
+	return order
+}
+
+// entryLess compares the sort keys of two entries (see SortKey) without
+// building them.
+func entryLess(a, b *DirectoryEntry) bool {
+	n := len(a.Name)
+	if len(b.Name) < n {
+		n = len(b.Name)
+	}
+	if a.Name[:n] != b.Name[:n] {
+		return a.Name[:n] < b.Name[:n]
+	}
+	for i := n; ; i++ {
+		ca, cb := sortKeyByte(a, i), sortKeyByte(b, i)
+		if ca != cb {
+			return ca < cb
+		}
+		if ca < 0 {
+			return false
+		}
+	}
+}
 
-	var result []byte
-	for _, entry := range sorted {
-		// Format: "<perms> <name>\0<binary_hash>"
-		perms := entry.Permissions()
-		result = append(result, []byte(perms)...)
-		result = append(result, ' ')
-		result = append(result, []byte(entry.Name)...)
-		result = append(result, 0)
+// sortKeyByte returns byte i of e's sort key, or -1 past its end.
+func sortKeyByte(e *DirectoryEntry, i int) int {
+	switch {
+	case i < len(e.Name):
+		return int(e.Name[i])
+	case i == len(e.Name) && e.Type == EntryTypeDirectory:
+		return '/'
+	default:
+		return -1
+	}
+}
 
-		// Convert hex hash to binary
-		hashBytes, _ := hex.DecodeString(entry.Target)
-		result = append(result, hashBytes...)
+// payloadSize returns the length of the serialized tree payload.
+func payloadSize(entries []DirectoryEntry) int {
+	size := 0
+	for i := range entries {
+		e := &entries[i]
+		size += len(e.Permissions()) + 1 + len(e.Name) + 1 + targetSize(e.Target)
 	}
+	return size
+}
 
-	return result
+// targetSize is the number of bytes hex.DecodeString yields for target,
+// which for malformed targets is what decodes before the first bad digit.
+func targetSize(target string) int {
+	if isHexHash(target) {
+		return 20
+	}
+	b, _ := hex.DecodeString(target)
+	return len(b)
+}
+
+// writeChunkSize is how much serialized payload writeEntries gathers
+// before each write, so hashing a huge directory neither holds its whole
+// payload nor pays for one small write per entry.
+const writeChunkSize = 32 << 10
+
+// writeEntries writes "<perms> <name>\0<binary hash>" for each entry, in
+// the given order or in slice order if order is nil.
+func writeEntries(w io.Writer, entries []DirectoryEntry, order []int) (int64, error) {
+	var written int64
+	buf := make([]byte, 0, writeChunkSize+512)
+	flush := func() error {
+		n, err := w.Write(buf)
+		written += int64(n)
+		buf = buf[:0]
+		return err
+	}
+
+	for i := range entries {
+		e := &entries[i]
+		if order != nil {
+			e = &entries[order[i]]
+		}
+
+		buf = append(buf, e.Permissions()...)
+		buf = append(buf, ' ')
+		buf = append(buf, e.Name...)
+		buf = append(buf, 0)
+		buf = appendTarget(buf, e.Target)
+
+		if len(buf) >= writeChunkSize {
+			if err := flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+	if len(buf) > 0 {
+		if err := flush(); err != nil {
+			return written, err
+		}
+	}
+	return written, nil
 }
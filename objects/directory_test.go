@@ -1,6 +1,10 @@
 package objects
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -115,3 +119,179 @@ func TestDirectoryEntrySorting(t *testing.T) {
 		t.Errorf("Hash should be deterministic regardless of input order: %v != %v", hash1, hash2)
 	}
 }
+
+func TestComputeDirectoryHashStrict(t *testing.T) {
+	valid := "ce013625030ba8dba906f756967f9e9ca394464a"
+
+	tests := []struct {
+		name      string
+		entries   []DirectoryEntry
+		wantErr   error
+		wantIndex int
+	}{
+		{
+			name:    "valid",
+			entries: []DirectoryEntry{{Name: "hello.txt", Type: EntryTypeFile, Target: valid}},
+		},
+		{
+			name:      "empty name",
+			entries:   []DirectoryEntry{{Name: "", Type: EntryTypeFile, Target: valid}},
+			wantErr:   ErrEmptyEntryName,
+			wantIndex: 0,
+		},
+		{
+			name:      "slash in name",
+			entries:   []DirectoryEntry{{Name: "a", Target: valid}, {Name: "a/b", Target: valid}},
+			wantErr:   ErrInvalidEntryName,
+			wantIndex: 1,
+		},
+		{
+			name:      "NUL in name",
+			entries:   []DirectoryEntry{{Name: "a\x00b", Target: valid}},
+			wantErr:   ErrInvalidEntryName,
+			wantIndex: 0,
+		},
+		{
+			name:      "duplicate name",
+			entries:   []DirectoryEntry{{Name: "a", Target: valid}, {Name: "b", Target: valid}, {Name: "a", Type: EntryTypeDirectory, Target: valid}},
+			wantErr:   ErrDuplicateEntryName,
+			wantIndex: 2,
+		},
+		{
+			name:      "short target",
+			entries:   []DirectoryEntry{{Name: "a", Target: "ce0136"}},
+			wantErr:   ErrInvalidEntryTarget,
+			wantIndex: 0,
+		},
+		{
+			name:      "non-hex target",
+			entries:   []DirectoryEntry{{Name: "a", Target: "zz013625030ba8dba906f756967f9e9ca394464a"}},
+			wantErr:   ErrInvalidEntryTarget,
+			wantIndex: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := ComputeDirectoryHashStrict(tt.entries)
+
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("ComputeDirectoryHashStrict() unexpected error: %v", err)
+				}
+				if hash != ComputeDirectoryHash(tt.entries) {
+					t.Errorf("ComputeDirectoryHashStrict() = %v, want %v", hash, ComputeDirectoryHash(tt.entries))
+				}
+				return
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ComputeDirectoryHashStrict() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var entryErr *EntryError
+			if !errors.As(err, &entryErr) {
+				t.Fatalf("error %T is not an *EntryError", err)
+			}
+			if entryErr.Index != tt.wantIndex {
+				t.Errorf("EntryError.Index = %d, want %d", entryErr.Index, tt.wantIndex)
+			}
+		})
+	}
+}
+
+func TestParseDirectory(t *testing.T) {
+	entries := []DirectoryEntry{
+		{Name: "src", Type: EntryTypeDirectory, Target: "4b825dc642cb6eb9a060e54bf8d69288fbee4904"},
+		{Name: "run.sh", Type: EntryTypeExecutable, Target: "ce013625030ba8dba906f756967f9e9ca394464a"},
+		{Name: "link", Type: EntryTypeSymlink, Target: "ce013625030ba8dba906f756967f9e9ca394464a"},
+		{Name: "lib", Type: EntryTypeRevision, Target: "aaa96ced2d9a1c8e72c56b253a0e2fe78393feb7"},
+		{Name: "README", Type: EntryTypeFile, Target: "ce013625030ba8dba906f756967f9e9ca394464a", Perms: "100664"},
+	}
+
+	parsed, err := ParseDirectory(SerializeDirectory(entries))
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+	if len(parsed) != len(entries) {
+		t.Fatalf("ParseDirectory() returned %d entries, want %d", len(parsed), len(entries))
+	}
+	if got, want := ComputeDirectoryHash(parsed), ComputeDirectoryHash(entries); got != want {
+		t.Errorf("round trip hash = %s, want %s", got, want)
+	}
+	for _, e := range parsed {
+		if e.Name == "README" && (e.Type != EntryTypeFile || e.Perms != "100664") {
+			t.Errorf("README = %+v", e)
+		}
+		if e.Name == "lib" && e.Type != EntryTypeRevision {
+			t.Errorf("lib type = %v, want revision", e.Type)
+		}
+	}
+
+	for _, bad := range []string{"100644", "100644 name", "100644 name\x00short", " name\x00" + strings.Repeat("x", 20)} {
+		if _, err := ParseDirectory([]byte(bad)); err != ErrMalformedTree {
+			t.Errorf("ParseDirectory(%q) error = %v, want ErrMalformedTree", bad, err)
+		}
+	}
+}
+
+func TestDirectoryTreeOrder(t *testing.T) {
+	target := "ce013625030ba8dba906f756967f9e9ca394464a"
+	// "a-b" < "a.b" < "a/" (dir a) < "a0" < "ab"; the file "a" sorts first.
+	entries := []DirectoryEntry{
+		{Name: "ab", Type: EntryTypeFile, Target: target},
+		{Name: "a", Type: EntryTypeDirectory, Target: "4b825dc642cb6eb9a060e54bf8d69288fbee4904"},
+		{Name: "a0", Type: EntryTypeFile, Target: target},
+		{Name: "a.b", Type: EntryTypeFile, Target: target},
+		{Name: "a-b", Type: EntryTypeFile, Target: target},
+		{Name: "A", Type: EntryTypeFile, Target: target},
+	}
+	original := append([]DirectoryEntry(nil), entries...)
+
+	if EntriesSorted(entries) {
+		t.Fatal("EntriesSorted() = true for unsorted entries")
+	}
+	want := ComputeDirectoryHash(entries)
+	for i := range entries {
+		if entries[i] != original[i] {
+			t.Fatalf("ComputeDirectoryHash() modified its input")
+		}
+	}
+
+	sorted := append([]DirectoryEntry(nil), entries...)
+	SortEntries(sorted)
+	var names []string
+	for i, e := range sorted {
+		names = append(names, e.Name)
+		if i > 0 && sorted[i-1].SortKey() >= e.SortKey() {
+			t.Errorf("SortEntries() put %q before %q", sorted[i-1].SortKey(), e.SortKey())
+		}
+	}
+	if got := strings.Join(names, " "); got != "A a-b a.b a a0 ab" {
+		t.Errorf("SortEntries() order = %s", got)
+	}
+	if !EntriesSorted(sorted) {
+		t.Error("EntriesSorted() = false after SortEntries")
+	}
+
+	if got := ComputeDirectoryHash(sorted); got != want {
+		t.Errorf("ComputeDirectoryHash(sorted) = %s, want %s", got, want)
+	}
+	if got := ComputeDirectoryHashSorted(sorted); got != want {
+		t.Errorf("ComputeDirectoryHashSorted() = %s, want %s", got, want)
+	}
+
+	var buf bytes.Buffer
+	n, err := WriteDirectory(&buf, entries)
+	if err != nil {
+		t.Fatalf("WriteDirectory() error = %v", err)
+	}
+	serialized := SerializeDirectory(entries)
+	if !bytes.Equal(buf.Bytes(), serialized) || n != int64(len(serialized)) {
+		t.Errorf("WriteDirectory() wrote %d bytes differing from SerializeDirectory()", n)
+	}
+	raw := append([]byte(fmt.Sprintf("tree %d\x00", len(serialized))), serialized...)
+	if _, hash, err := IdentifyRaw(raw); err != nil || hash != want {
+		t.Errorf("IdentifyRaw(SerializeDirectory()) = %s, %v, want %s", hash, err, want)
+	}
+}
@@ -0,0 +1,110 @@
+package objects
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// PayloadLine is one line of an object payload as an Explanation shows it.
+type PayloadLine struct {
+	// Key is the header a line belongs to ("tree", "parent", "gpgsig",
+	// ...), continuation lines included; it is empty for the blank line
+	// ending the headers and for message lines.
+	Key string
+
+	// Extra marks the lines of headers other than tree, parent, author
+	// and committer, such as gpgsig, mergetag or encoding, or for tags
+	// other than object, type, tag and tagger.
+	Extra bool
+
+	// Text is the line's bytes, its final "\n" included, with backslashes,
+	// control characters and bytes that are not UTF-8 escaped as in Go
+	// string literals.
+	Text string
+}
+
+// Explanation shows the exact bytes an object hash is computed over.
+type Explanation struct {
+	Header string // the "commit <size>\x00" or "tag <size>\x00" prefix, escaped like PayloadLine.Text
+	Lines  []PayloadLine
+	Hash   string
+}
+
+// standardRevisionHeaders are the headers every commit has.
+var standardRevisionHeaders = map[string]bool{"tree": true, "parent": true, "author": true, "committer": true}
+
+// standardReleaseHeaders are the headers of a tag other than extra ones.
+var standardReleaseHeaders = map[string]bool{"object": true, "type": true, "tag": true, "tagger": true}
+
+// ExplainRevision splits the payload ComputeRevisionHash hashes into
+// lines, so that a revision hash that differs from Git's can be compared
+// byte by byte: a missing final newline, a stray carriage return or an
+// unexpected extra header all show in the escaped text.
+func ExplainRevision(meta RevisionMetadata) Explanation {
+	return explainPayload("commit", string(serializeRevision(meta)), standardRevisionHeaders, ComputeRevisionHash(meta))
+}
+
+// ExplainRelease splits the payload ComputeReleaseHash hashes into lines
+// as ExplainRevision does for revisions. A tag's signature is part of its
+// message, so its lines have no Key.
+func ExplainRelease(meta ReleaseMetadata) Explanation {
+	return explainPayload("tag", string(serializeRelease(meta)), standardReleaseHeaders, ComputeReleaseHash(meta))
+}
+
+func explainPayload(gitType, payload string, standard map[string]bool, hash string) Explanation {
+	e := Explanation{
+		Header: EscapePayload(fmt.Sprintf("%s %d\x00", gitType, len(payload))),
+		Hash:   hash,
+	}
+
+	inHeaders := true
+	key := ""
+	for _, line := range strings.SplitAfter(payload, "\n") {
+		if line == "" {
+			continue
+		}
+		switch {
+		case !inHeaders:
+			key = ""
+		case line == "\n":
+			inHeaders, key = false, ""
+		case !strings.HasPrefix(line, " "):
+			key, _, _ = strings.Cut(line, " ")
+		}
+		e.Lines = append(e.Lines, PayloadLine{
+			Key:   key,
+			Extra: key != "" && !standard[key],
+			Text:  EscapePayload(line),
+		})
+	}
+	return e
+}
+
+// EscapePayload escapes backslashes, control characters, including
+// newlines, and bytes that are not UTF-8 in s the way Go string literals
+// do, leaving other text as it is.
+func EscapePayload(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			fmt.Fprintf(&b, `\x%02x`, s[i])
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, r)
+		default:
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
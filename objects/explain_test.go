@@ -0,0 +1,99 @@
+package objects
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestExplainRevision(t *testing.T) {
+	meta := RevisionMetadata{
+		Directory:          "4b825dc642cb6eb9a060e54bf8d69288fbee4904",
+		Parents:            []string{"ce013625030ba8dba906f756967f9e9ca394464a"},
+		Author:             "A U Thor <author@example.com>",
+		AuthorTimestamp:    1000000000,
+		AuthorTimezone:     "+0200",
+		Committer:          "A U Thor <author@example.com>",
+		CommitterTimestamp: 1000000000,
+		CommitterTimezone:  "+0200",
+		ExtraHeaders:       [][2]string{{"gpgsig", "-----BEGIN PGP SIGNATURE-----\n\nabc\n-----END PGP SIGNATURE-----"}},
+		Message:            "Subject\r\n\nBody without final newline",
+	}
+
+	e := ExplainRevision(meta)
+	if e.Hash != ComputeRevisionHash(meta) {
+		t.Errorf("Hash = %v, want %v", e.Hash, ComputeRevisionHash(meta))
+	}
+	if want := fmt.Sprintf(`commit %d\x00`, len(SerializeRevision(meta))); e.Header != want {
+		t.Errorf("Header = %q, want %q", e.Header, want)
+	}
+
+	want := []PayloadLine{
+		{Key: "tree", Text: `tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n`},
+		{Key: "parent", Text: `parent ce013625030ba8dba906f756967f9e9ca394464a\n`},
+		{Key: "author", Text: `author A U Thor <author@example.com> 1000000000 +0200\n`},
+		{Key: "committer", Text: `committer A U Thor <author@example.com> 1000000000 +0200\n`},
+		{Key: "gpgsig", Extra: true, Text: `gpgsig -----BEGIN PGP SIGNATURE-----\n`},
+		{Key: "gpgsig", Extra: true, Text: ` \n`},
+		{Key: "gpgsig", Extra: true, Text: ` abc\n`},
+		{Key: "gpgsig", Extra: true, Text: ` -----END PGP SIGNATURE-----\n`},
+		{Text: `\n`},
+		{Text: `Subject\r\n`},
+		{Text: `\n`},
+		{Text: `Body without final newline`},
+	}
+	if !slices.Equal(e.Lines, want) {
+		t.Errorf("Lines = %+v\nwant %+v", e.Lines, want)
+	}
+}
+
+func TestEscapePayload(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain text", "plain text"},
+		{"tab\there", `tab\there`},
+		{"back\\slash", `back\\slash`},
+		{"nul\x00 del\x7f", `nul\x00 del\x7f`},
+		{"caf\xe9", `caf\xe9`},
+		{"café", "café"},
+	}
+	for _, tt := range tests {
+		if got := EscapePayload(tt.in); got != tt.want {
+			t.Errorf("EscapePayload(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExplainRelease(t *testing.T) {
+	meta := ReleaseMetadata{
+		Name:            "v1.0.0",
+		Target:          ReleaseTarget{Hash: "ce013625030ba8dba906f756967f9e9ca394464a", Type: TargetTypeRevision},
+		Author:          "A U Thor <author@example.com>",
+		AuthorTimestamp: 1000000000,
+		AuthorTimezone:  "-0130",
+		Message:         "Release\n-----BEGIN SSH SIGNATURE-----\nabc\n-----END SSH SIGNATURE-----\n",
+	}
+
+	e := ExplainRelease(meta)
+	if e.Hash != ComputeReleaseHash(meta) {
+		t.Errorf("Hash = %v, want %v", e.Hash, ComputeReleaseHash(meta))
+	}
+	if want := fmt.Sprintf(`tag %d\x00`, len(serializeRelease(meta))); e.Header != want {
+		t.Errorf("Header = %q, want %q", e.Header, want)
+	}
+	want := []PayloadLine{
+		{Key: "object", Text: `object ce013625030ba8dba906f756967f9e9ca394464a\n`},
+		{Key: "type", Text: `type commit\n`},
+		{Key: "tag", Text: `tag v1.0.0\n`},
+		{Key: "tagger", Text: `tagger A U Thor <author@example.com> 1000000000 -0130\n`},
+		{Text: `\n`},
+		{Text: `Release\n`},
+		{Text: `-----BEGIN SSH SIGNATURE-----\n`},
+		{Text: `abc\n`},
+		{Text: `-----END SSH SIGNATURE-----\n`},
+	}
+	if !slices.Equal(e.Lines, want) {
+		t.Errorf("Lines = %+v\nwant %+v", e.Lines, want)
+	}
+}
@@ -0,0 +1,135 @@
+package objects
+
+import (
+	"encoding/binary"
+	"hash"
+	"hash/fnv"
+	"sort"
+	"strings"
+)
+
+// Fingerprints are 64-bit FNV-1a hashes of an object's normalized fields.
+// They are stable across processes and much cheaper than the git hash, so
+// caches can use them as keys for "same input". Inputs that serialize to
+// the same git object get the same fingerprint: empty timezones count as
+// "+0000", default permissions as their explicit value, and directory
+// entries and snapshot branches are taken in canonical order. Being
+// non-cryptographic, distinct inputs may collide; a cache that must be
+// exact should compare the inputs on a fingerprint hit.
+
+// fingerprinter writes length-prefixed fields so adjacent fields cannot
+// run into each other.
+type fingerprinter struct {
+	h   hash.Hash64
+	buf [binary.MaxVarintLen64]byte
+}
+
+func newFingerprinter(kind string) *fingerprinter {
+	f := &fingerprinter{h: fnv.New64a()}
+	f.str(kind)
+	return f
+}
+
+func (f *fingerprinter) str(s string) {
+	f.int(int64(len(s)))
+	f.h.Write([]byte(s))
+}
+
+func (f *fingerprinter) int(n int64) {
+	f.h.Write(f.buf[:binary.PutVarint(f.buf[:], n)])
+}
+
+func (f *fingerprinter) headers(headers [][2]string) {
+	f.int(int64(len(headers)))
+	for _, header := range headers {
+		f.str(header[0])
+		f.str(header[1])
+	}
+}
+
+func (f *fingerprinter) sum() uint64 {
+	return f.h.Sum64()
+}
+
+// Fingerprint returns a fast non-cryptographic hash of the revision's
+// normalized metadata.
+func (m RevisionMetadata) Fingerprint() uint64 {
+	f := newFingerprinter("revision")
+	f.str(m.Directory)
+	f.int(int64(len(m.Parents)))
+	for _, parent := range m.Parents {
+		f.str(parent)
+	}
+	f.str(m.Author)
+	f.int(m.AuthorTimestamp)
+	f.str(timezoneOrUTC(m.AuthorTimezone))
+	f.str(m.Committer)
+	f.int(m.CommitterTimestamp)
+	f.str(timezoneOrUTC(m.CommitterTimezone))
+	f.headers(m.ExtraHeaders)
+	f.str(m.Message)
+	return f.sum()
+}
+
+// Fingerprint returns a fast non-cryptographic hash of the release's
+// normalized metadata. Timestamp and timezone are ignored without an
+// author, as they are when serializing.
+func (m ReleaseMetadata) Fingerprint() uint64 {
+	f := newFingerprinter("release")
+	f.str(m.Name)
+	f.str(m.Target.Hash)
+	f.str(m.Target.GitType())
+	f.str(m.Author)
+	if m.Author != "" {
+		f.int(m.AuthorTimestamp)
+		f.str(timezoneOrUTC(m.AuthorTimezone))
+	}
+	f.headers(m.ExtraHeaders)
+	f.str(m.Message)
+	return f.sum()
+}
+
+// DirectoryFingerprint returns a fast non-cryptographic hash of a
+// directory's entries, independent of their order.
+func DirectoryFingerprint(entries []DirectoryEntry) uint64 {
+	sorted := make([]DirectoryEntry, len(entries))
+	copy(sorted, entries)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].SortKey() < sorted[j].SortKey()
+	})
+
+	f := newFingerprinter("directory")
+	f.int(int64(len(sorted)))
+	for _, entry := range sorted {
+		f.str(entry.Permissions())
+		f.str(entry.Name)
+		f.str(strings.ToLower(entry.Target))
+	}
+	return f.sum()
+}
+
+// SnapshotFingerprint returns a fast non-cryptographic hash of a
+// snapshot's branches, independent of their order.
+func SnapshotFingerprint(branches []Branch) uint64 {
+	sorted := make([]Branch, len(branches))
+	copy(sorted, branches)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	f := newFingerprinter("snapshot")
+	f.int(int64(len(sorted)))
+	for _, branch := range sorted {
+		f.str(branch.Name)
+		f.str(string(branch.TargetType))
+		f.str(string(computeTargetIdentifier(branch)))
+	}
+	return f.sum()
+}
+
+func timezoneOrUTC(tz string) string {
+	if tz == "" {
+		return "+0000"
+	}
+	return tz
+}
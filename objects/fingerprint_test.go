@@ -0,0 +1,120 @@
+package objects
+
+import (
+	"testing"
+)
+
+func TestRevisionFingerprint(t *testing.T) {
+	meta := RevisionMetadata{
+		Directory:          "4b825dc642cb6eb9a060e54bf8d69288fbee4904",
+		Author:             "Test <test@example.com>",
+		AuthorTimestamp:    1234567890,
+		AuthorTimezone:     "+0000",
+		Committer:          "Test <test@example.com>",
+		CommitterTimestamp: 1234567890,
+		CommitterTimezone:  "+0000",
+		Message:            "Test\n",
+	}
+
+	if meta.Fingerprint() != meta.Fingerprint() {
+		t.Error("Fingerprint() not deterministic")
+	}
+
+	// Same git object, same fingerprint
+	same := meta
+	same.AuthorTimezone = ""
+	same.CommitterTimezone = ""
+	if meta.Fingerprint() != same.Fingerprint() {
+		t.Error("Empty timezone should fingerprint like +0000")
+	}
+
+	variants := []func(*RevisionMetadata){
+		func(m *RevisionMetadata) { m.Message = "Test 2\n" },
+		func(m *RevisionMetadata) { m.Parents = []string{m.Directory} },
+		func(m *RevisionMetadata) { m.AuthorTimestamp++ },
+		func(m *RevisionMetadata) { m.ExtraHeaders = [][2]string{{"encoding", "latin1"}} },
+		// Field boundaries must not shift: "ab"+"c" vs "a"+"bc"
+		func(m *RevisionMetadata) {
+			m.Author, m.Committer = "Test <test@example.com>T", "est <test@example.com>"
+		},
+	}
+	for i, change := range variants {
+		changed := meta
+		change(&changed)
+		if changed.Fingerprint() == meta.Fingerprint() {
+			t.Errorf("variant %d: Fingerprint() unchanged", i)
+		}
+	}
+}
+
+func TestReleaseFingerprint(t *testing.T) {
+	meta := ReleaseMetadata{
+		Name:    "v1.0.0",
+		Target:  ReleaseTarget{Hash: "4b825dc642cb6eb9a060e54bf8d69288fbee4904", Type: TargetTypeRevision},
+		Message: "Release\n",
+	}
+
+	// Without an author, timestamp and timezone are not serialized.
+	same := meta
+	same.AuthorTimestamp = 1234567890
+	same.AuthorTimezone = "+0200"
+	if ComputeReleaseHash(meta) != ComputeReleaseHash(same) {
+		t.Fatal("test assumption: tagger fields ignored without author")
+	}
+	if meta.Fingerprint() != same.Fingerprint() {
+		t.Error("Fingerprint() should ignore timestamp without author")
+	}
+
+	withAuthor := same
+	withAuthor.Author = "Test <test@example.com>"
+	if withAuthor.Fingerprint() == meta.Fingerprint() {
+		t.Error("Fingerprint() should change with author")
+	}
+
+	retargeted := meta
+	retargeted.Target.Type = TargetTypeDirectory
+	if retargeted.Fingerprint() == meta.Fingerprint() {
+		t.Error("Fingerprint() should change with target type")
+	}
+}
+
+func TestDirectoryFingerprint(t *testing.T) {
+	entries := []DirectoryEntry{
+		{Name: "b.txt", Type: EntryTypeFile, Target: "ce013625030ba8dba906f756967f9e9ca394464a"},
+		{Name: "a", Type: EntryTypeDirectory, Target: "4b825dc642cb6eb9a060e54bf8d69288fbee4904"},
+	}
+	reordered := []DirectoryEntry{
+		{Name: "a", Type: EntryTypeDirectory, Target: "4B825DC642CB6EB9A060E54BF8D69288FBEE4904", Perms: "40000"},
+		{Name: "b.txt", Type: EntryTypeFile, Target: "ce013625030ba8dba906f756967f9e9ca394464a"},
+	}
+
+	if ComputeDirectoryHash(entries) != ComputeDirectoryHash(reordered) {
+		t.Fatal("test assumption: both lists serialize identically")
+	}
+	if DirectoryFingerprint(entries) != DirectoryFingerprint(reordered) {
+		t.Error("DirectoryFingerprint() should not depend on order, hex case or default perms")
+	}
+
+	executable := []DirectoryEntry{entries[0], entries[1]}
+	executable[0].Type = EntryTypeExecutable
+	if DirectoryFingerprint(executable) == DirectoryFingerprint(entries) {
+		t.Error("DirectoryFingerprint() should change with permissions")
+	}
+}
+
+func TestSnapshotFingerprint(t *testing.T) {
+	branches := []Branch{
+		{Name: "HEAD", TargetType: BranchTargetAlias, Target: "refs/heads/main"},
+		{Name: "refs/heads/main", TargetType: BranchTargetRevision, Target: "4b825dc642cb6eb9a060e54bf8d69288fbee4904"},
+	}
+	reordered := []Branch{branches[1], branches[0]}
+
+	if SnapshotFingerprint(branches) != SnapshotFingerprint(reordered) {
+		t.Error("SnapshotFingerprint() should not depend on order")
+	}
+
+	dangling := []Branch{branches[0], {Name: "refs/heads/main", TargetType: BranchTargetDangling}}
+	if SnapshotFingerprint(dangling) == SnapshotFingerprint(branches) {
+		t.Error("SnapshotFingerprint() should change with target type")
+	}
+}
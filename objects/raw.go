@@ -0,0 +1,53 @@
+package objects
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrMalformedObject is returned by IdentifyRaw for data that is not a
+// headered object.
+var ErrMalformedObject = errors.New("malformed object")
+
+// rawTypes maps object header names to SWHID object types.
+var rawTypes = map[string]TargetType{
+	"blob":     TargetTypeContent,
+	"tree":     TargetTypeDirectory,
+	"commit":   TargetTypeRevision,
+	"tag":      TargetTypeRelease,
+	"snapshot": TargetTypeSnapshot,
+}
+
+// IdentifyRaw returns the SWHID object type and hash of a raw headered
+// object such as "blob 6\x00hello\n": a Git object as stored uncompressed
+// in a loose object file, or a Software Heritage snapshot manifest. The
+// header's size must match the length of the payload.
+func IdentifyRaw(data []byte) (TargetType, string, error) {
+	nul := bytes.IndexByte(data, 0)
+	if nul < 0 {
+		return "", "", fmt.Errorf("%w: no header", ErrMalformedObject)
+	}
+	kind, size, ok := bytes.Cut(data[:nul], []byte{' '})
+	if !ok {
+		return "", "", fmt.Errorf("%w: invalid header %q", ErrMalformedObject, data[:nul])
+	}
+
+	t, ok := rawTypes[string(kind)]
+	if !ok {
+		return "", "", fmt.Errorf("%w: unknown object type %q", ErrMalformedObject, kind)
+	}
+	n, err := strconv.Atoi(string(size))
+	if err != nil || n < 0 || (len(size) > 1 && size[0] == '0') {
+		return "", "", fmt.Errorf("%w: invalid size %q", ErrMalformedObject, size)
+	}
+	if n != len(data)-nul-1 {
+		return "", "", fmt.Errorf("%w: header says %d bytes, payload has %d", ErrMalformedObject, n, len(data)-nul-1)
+	}
+
+	sum := sha1.Sum(data)
+	return t, hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,58 @@
+package objects
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestIdentifyRaw(t *testing.T) {
+	meta := RevisionMetadata{
+		Directory:          "4b825dc642cb6eb9a060e54bf8d69288fbee4904",
+		Author:             "Test Author <test@example.com>",
+		AuthorTimestamp:    1234567890,
+		AuthorTimezone:     "+0000",
+		Committer:          "Test Author <test@example.com>",
+		CommitterTimestamp: 1234567890,
+		CommitterTimezone:  "+0000",
+		Message:            "Initial commit\n",
+	}
+	commit := SerializeRevision(meta)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		wantType TargetType
+		wantHash string
+	}{
+		{"blob", []byte("blob 6\x00hello\n"), TargetTypeContent, "ce013625030ba8dba906f756967f9e9ca394464a"},
+		{"empty tree", []byte("tree 0\x00"), TargetTypeDirectory, "4b825dc642cb6eb9a060e54bf8d69288fbee4904"},
+		{"commit", append([]byte(fmt.Sprintf("commit %d\x00", len(commit))), commit...), TargetTypeRevision, ComputeRevisionHash(meta)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typ, hash, err := IdentifyRaw(tt.data)
+			if err != nil {
+				t.Fatalf("IdentifyRaw() error = %v", err)
+			}
+			if typ != tt.wantType || hash != tt.wantHash {
+				t.Errorf("IdentifyRaw() = %s, %s, want %s, %s", typ, hash, tt.wantType, tt.wantHash)
+			}
+		})
+	}
+}
+
+func TestIdentifyRawErrors(t *testing.T) {
+	for _, data := range []string{
+		"hello\n",
+		"blob6\x00hello\n",
+		"blob 7\x00hello\n",
+		"blob 06\x00hello\n",
+		"blob -1\x00",
+		"object 6\x00hello\n",
+	} {
+		if _, _, err := IdentifyRaw([]byte(data)); !errors.Is(err, ErrMalformedObject) {
+			t.Errorf("IdentifyRaw(%q) error = %v, want ErrMalformedObject", data, err)
+		}
+	}
+}
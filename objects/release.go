@@ -46,13 +46,47 @@ func (t ReleaseTarget) GitType() string {
 type ReleaseMetadata struct {
 	Name            string
 	Target          ReleaseTarget
-	Author          string // "Name <email>" format, optional
-	AuthorTimestamp int64  // Unix timestamp, required if Author is set
-	AuthorTimezone  string // "+0000" format
-	Message         string
+	Author          string      // "Name <email>" format, optional
+	AuthorTimestamp int64       // Unix timestamp, required if Author is set
+	AuthorTimezone  string      // "+0000" format
+	Message         string      // including any signature; see Signature
 	ExtraHeaders    [][2]string // Additional headers like gpgsig
 }
 
+// signatureStarts are the lines a signature appended to a tag message
+// begins with, and the signature formats they mark.
+var signatureStarts = []struct{ line, format string }{
+	{"-----BEGIN PGP SIGNATURE-----", "openpgp"},
+	{"-----BEGIN PGP MESSAGE-----", "openpgp"},
+	{"-----BEGIN SSH SIGNATURE-----", "ssh"},
+	{"-----BEGIN SIGNED MESSAGE-----", "x509"},
+}
+
+// Signature splits Message into the text the tagger wrote and the
+// signature git tag -s appends to it, and names the signature's format:
+// "openpgp", "ssh" or "x509". For unsigned tags, signature and format are
+// empty. Like Git, it takes the last line starting a signature.
+func (m ReleaseMetadata) Signature() (message, signature, format string) {
+	start := -1
+	for i := 0; i < len(m.Message); {
+		line := m.Message[i:]
+		for _, s := range signatureStarts {
+			if strings.HasPrefix(line, s.line) {
+				start, format = i, s.format
+			}
+		}
+		next := strings.IndexByte(line, '\n')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	if start < 0 {
+		return m.Message, "", ""
+	}
+	return m.Message[:start], m.Message[start:], format
+}
+
 // ComputeReleaseHash computes the Git tag hash for a release.
 func ComputeReleaseHash(meta ReleaseMetadata) string {
 	serialized := serializeRelease(meta)
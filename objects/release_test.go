@@ -68,3 +68,26 @@ func TestReleaseTargetGitType(t *testing.T) {
 		}
 	}
 }
+
+func TestReleaseSignature(t *testing.T) {
+	pgp := "-----BEGIN PGP SIGNATURE-----\n\nabc\n-----END PGP SIGNATURE-----\n"
+	tests := []struct {
+		message                      string
+		wantMessage, wantSig, format string
+	}{
+		{"Release\n", "Release\n", "", ""},
+		{"", "", "", ""},
+		{"Release\n" + pgp, "Release\n", pgp, "openpgp"},
+		{pgp, "", pgp, "openpgp"},
+		{"Quoting -----BEGIN PGP SIGNATURE----- inline\n", "Quoting -----BEGIN PGP SIGNATURE----- inline\n", "", ""},
+		{"Release\n-----BEGIN SIGNED MESSAGE-----\nx\n", "Release\n", "-----BEGIN SIGNED MESSAGE-----\nx\n", "x509"},
+		// The last signature start wins, as in Git
+		{"Notes\n" + pgp + "-----BEGIN SSH SIGNATURE-----\ny\n", "Notes\n" + pgp, "-----BEGIN SSH SIGNATURE-----\ny\n", "ssh"},
+	}
+	for _, tt := range tests {
+		message, sig, format := ReleaseMetadata{Message: tt.message}.Signature()
+		if message != tt.wantMessage || sig != tt.wantSig || format != tt.format {
+			t.Errorf("Signature(%q) = %q, %q, %q, want %q, %q, %q", tt.message, message, sig, format, tt.wantMessage, tt.wantSig, tt.format)
+		}
+	}
+}
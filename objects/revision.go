@@ -32,6 +32,12 @@ func ComputeRevisionHash(meta RevisionMetadata) string {
 	return hex.EncodeToString(h.Sum(nil))
 }
 
+// SerializeRevision returns the Git commit object payload for a revision,
+// without the "commit <size>\0" header.
+func SerializeRevision(meta RevisionMetadata) []byte {
+	return serializeRevision(meta)
+}
+
 func serializeRevision(meta RevisionMetadata) []byte {
 	var lines []string
 
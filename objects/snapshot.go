@@ -3,8 +3,11 @@ package objects
 import (
 	"crypto/sha1"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"sort"
+	"unicode/utf8"
 )
 
 // BranchTargetType represents the type of target a branch points to.
@@ -20,13 +23,84 @@ const (
 	BranchTargetDangling  BranchTargetType = "dangling"
 )
 
+// Snapshot branch validation errors.
+var (
+	ErrDuplicateBranchName = errors.New("duplicate branch name")
+	ErrInvalidBranchTarget = errors.New("branch target must be 40 hex digits")
+	ErrInvalidBranchType   = errors.New("unknown branch target type")
+	ErrDanglingWithTarget  = errors.New("dangling branch must not have a target")
+	ErrAliasTargetMissing  = errors.New("alias points at a missing branch")
+	ErrAliasCycle          = errors.New("alias cycle")
+	ErrBranchNotFound      = errors.New("branch not found")
+)
+
+// BranchError reports which snapshot branch is malformed.
+type BranchError struct {
+	Name string // branch name as given
+	Err  error
+}
+
+func (e *BranchError) Error() string {
+	return fmt.Sprintf("snapshot branch %q: %v", e.Name, e.Err)
+}
+
+func (e *BranchError) Unwrap() error {
+	return e.Err
+}
+
 // Branch represents a branch in a snapshot.
+//
+// Git ref names are raw bytes and need not be valid UTF-8. Name, and Target
+// for aliases, hold those bytes unchanged (a Go string may hold any bytes),
+// and the manifest is built from them as they are. Convert to and from
+// []byte directly, never through runes. Branches marshal to JSON
+// losslessly: a name or alias target that is not valid UTF-8 is written in
+// base64 under NameBase64 or TargetBase64 as well.
 type Branch struct {
 	Name       string
 	TargetType BranchTargetType
 	Target     string // 40-char hex hash, or branch name for alias, or empty for dangling
 }
 
+// branchJSON is the JSON form of a Branch. Name and Target are kept for
+// readability even when invalid UTF-8 makes encoding/json replace bytes in
+// them; the base64 fields then hold the exact bytes.
+type branchJSON struct {
+	Name         string
+	NameBase64   []byte `json:",omitempty"`
+	TargetType   BranchTargetType
+	Target       string
+	TargetBase64 []byte `json:",omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (b Branch) MarshalJSON() ([]byte, error) {
+	j := branchJSON{Name: b.Name, TargetType: b.TargetType, Target: b.Target}
+	if !utf8.ValidString(b.Name) {
+		j.NameBase64 = []byte(b.Name)
+	}
+	if !utf8.ValidString(b.Target) {
+		j.TargetBase64 = []byte(b.Target)
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *Branch) UnmarshalJSON(data []byte) error {
+	var j branchJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*b = Branch{Name: j.Name, TargetType: j.TargetType, Target: j.Target}
+	if j.NameBase64 != nil {
+		b.Name = string(j.NameBase64)
+	}
+	if j.TargetBase64 != nil {
+		b.Target = string(j.TargetBase64)
+	}
+	return nil
+}
+
 // ComputeSnapshotHash computes the hash for a snapshot.
 func ComputeSnapshotHash(branches []Branch) string {
 	serialized := serializeBranches(branches)
@@ -38,13 +112,131 @@ func ComputeSnapshotHash(branches []Branch) string {
 	return hex.EncodeToString(h.Sum(nil))
 }
 
+// ComputeSnapshotHashStrict is like ComputeSnapshotHash but validates the
+// branches first, returning a *BranchError for the first malformed one.
+func ComputeSnapshotHashStrict(branches []Branch) (string, error) {
+	if err := ValidateBranches(branches); err != nil {
+		return "", err
+	}
+	return ComputeSnapshotHash(branches), nil
+}
+
+// ValidateBranches checks that branch names are unique, that object targets
+// are 40-char hex hashes, and that every alias resolves to an existing
+// non-alias branch without looping.
+func ValidateBranches(branches []Branch) error {
+	byName := make(map[string]Branch, len(branches))
+	for _, branch := range branches {
+		if _, ok := byName[branch.Name]; ok {
+			return &BranchError{Name: branch.Name, Err: ErrDuplicateBranchName}
+		}
+		byName[branch.Name] = branch
+
+		switch branch.TargetType {
+		case BranchTargetContent, BranchTargetDirectory, BranchTargetRevision, BranchTargetRelease, BranchTargetSnapshot:
+			if !isHexHash(branch.Target) {
+				return &BranchError{Name: branch.Name, Err: ErrInvalidBranchTarget}
+			}
+		case BranchTargetDangling:
+			if branch.Target != "" {
+				return &BranchError{Name: branch.Name, Err: ErrDanglingWithTarget}
+			}
+		case BranchTargetAlias:
+		default:
+			return &BranchError{Name: branch.Name, Err: fmt.Errorf("%w: %s", ErrInvalidBranchType, branch.TargetType)}
+		}
+	}
+
+	snapshot := NewSnapshot(branches)
+	for _, branch := range branches {
+		if branch.TargetType != BranchTargetAlias {
+			continue
+		}
+		if _, err := snapshot.Resolve(branch.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Snapshot is the branches of a snapshot, indexed by name.
+type Snapshot struct {
+	Branches []Branch
+	byName   map[string]int
+}
+
+// NewSnapshot indexes branches by name. Where a name is duplicated, the
+// first branch with it wins.
+func NewSnapshot(branches []Branch) *Snapshot {
+	s := &Snapshot{Branches: branches, byName: make(map[string]int, len(branches))}
+	for i, branch := range branches {
+		if _, ok := s.byName[branch.Name]; !ok {
+			s.byName[branch.Name] = i
+		}
+	}
+	return s
+}
+
+// Branch returns the branch called name, without following aliases.
+func (s *Snapshot) Branch(name string) (Branch, bool) {
+	i, ok := s.byName[name]
+	if !ok {
+		return Branch{}, false
+	}
+	return s.Branches[i], true
+}
+
+// Resolve returns the branch called name or, if it is an alias, the first
+// branch that is not an alias at the end of its chain: for HEAD aliased to
+// refs/heads/main, the refs/heads/main branch with its revision. The result
+// may be dangling. A missing name, an alias to a missing branch or an alias
+// cycle is a *BranchError for name.
+func (s *Snapshot) Resolve(name string) (Branch, error) {
+	current, ok := s.Branch(name)
+	if !ok {
+		return Branch{}, &BranchError{Name: name, Err: ErrBranchNotFound}
+	}
+
+	visited := map[string]bool{name: true}
+	for current.TargetType == BranchTargetAlias {
+		next, ok := s.Branch(current.Target)
+		if !ok {
+			return Branch{}, &BranchError{Name: name, Err: fmt.Errorf("%w: %s", ErrAliasTargetMissing, current.Target)}
+		}
+		if visited[next.Name] {
+			return Branch{}, &BranchError{Name: name, Err: fmt.Errorf("%w through %s", ErrAliasCycle, next.Name)}
+		}
+		visited[next.Name] = true
+		current = next
+	}
+	return current, nil
+}
+
+// DefaultBranch resolves HEAD, the branch a clone of the snapshot's origin
+// would check out.
+func (s *Snapshot) DefaultBranch() (Branch, error) {
+	return s.Resolve("HEAD")
+}
+
+// ResolveBranch resolves name among branches like Snapshot.Resolve. To
+// resolve several names, index the branches once with NewSnapshot.
+func ResolveBranch(branches []Branch, name string) (Branch, error) {
+	return NewSnapshot(branches).Resolve(name)
+}
+
+// SortBranches sorts branches in place into the order the snapshot
+// manifest lists them: by the bytes of their names.
+func SortBranches(branches []Branch) {
+	sort.Slice(branches, func(i, j int) bool {
+		return branches[i].Name < branches[j].Name
+	})
+}
+
 func serializeBranches(branches []Branch) []byte {
-	// Sort branches by name
 	sorted := make([]Branch, len(branches))
 	copy(sorted, branches)
-	sort.Slice(sorted, func(i, j int) bool {
-		return sorted[i].Name < sorted[j].Name
-	})
+	SortBranches(sorted)
 
 	var result []byte
 	for _, branch := range sorted {
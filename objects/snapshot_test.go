@@ -1,6 +1,12 @@
 package objects
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
 	"testing"
 )
 
@@ -102,3 +108,219 @@ func TestSnapshotWithDangling(t *testing.T) {
 		t.Errorf("ComputeSnapshotHash() hash length = %d, want 40", len(hash))
 	}
 }
+
+func TestComputeSnapshotHashStrict(t *testing.T) {
+	rev := "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+	tests := []struct {
+		name     string
+		branches []Branch
+		wantErr  error
+		wantName string
+	}{
+		{
+			name: "valid with alias",
+			branches: []Branch{
+				{Name: "HEAD", TargetType: BranchTargetAlias, Target: "refs/heads/main"},
+				{Name: "refs/heads/main", TargetType: BranchTargetRevision, Target: rev},
+				{Name: "refs/heads/gone", TargetType: BranchTargetDangling},
+			},
+		},
+		{
+			name: "duplicate name",
+			branches: []Branch{
+				{Name: "refs/heads/main", TargetType: BranchTargetRevision, Target: rev},
+				{Name: "refs/heads/main", TargetType: BranchTargetRevision, Target: rev},
+			},
+			wantErr:  ErrDuplicateBranchName,
+			wantName: "refs/heads/main",
+		},
+		{
+			name: "short target",
+			branches: []Branch{
+				{Name: "refs/heads/main", TargetType: BranchTargetRevision, Target: "4b825d"},
+			},
+			wantErr:  ErrInvalidBranchTarget,
+			wantName: "refs/heads/main",
+		},
+		{
+			name: "unknown type",
+			branches: []Branch{
+				{Name: "refs/heads/main", TargetType: "commit", Target: rev},
+			},
+			wantErr:  ErrInvalidBranchType,
+			wantName: "refs/heads/main",
+		},
+		{
+			name: "alias to missing branch",
+			branches: []Branch{
+				{Name: "HEAD", TargetType: BranchTargetAlias, Target: "refs/heads/main"},
+			},
+			wantErr:  ErrAliasTargetMissing,
+			wantName: "HEAD",
+		},
+		{
+			name: "alias cycle",
+			branches: []Branch{
+				{Name: "a", TargetType: BranchTargetAlias, Target: "b"},
+				{Name: "b", TargetType: BranchTargetAlias, Target: "a"},
+			},
+			wantErr:  ErrAliasCycle,
+			wantName: "a",
+		},
+		{
+			name: "self alias",
+			branches: []Branch{
+				{Name: "a", TargetType: BranchTargetAlias, Target: "a"},
+			},
+			wantErr:  ErrAliasCycle,
+			wantName: "a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := ComputeSnapshotHashStrict(tt.branches)
+
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("ComputeSnapshotHashStrict() unexpected error: %v", err)
+				}
+				if hash != ComputeSnapshotHash(tt.branches) {
+					t.Errorf("ComputeSnapshotHashStrict() = %v, want %v", hash, ComputeSnapshotHash(tt.branches))
+				}
+				return
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ComputeSnapshotHashStrict() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var branchErr *BranchError
+			if !errors.As(err, &branchErr) {
+				t.Fatalf("error %T is not a *BranchError", err)
+			}
+			if branchErr.Name != tt.wantName {
+				t.Errorf("BranchError.Name = %q, want %q", branchErr.Name, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestSnapshotNonUTF8BranchName(t *testing.T) {
+	name := "refs/heads/caf\xe9"
+	target := "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+	branches := []Branch{
+		{Name: name, TargetType: BranchTargetRevision, Target: target},
+		{Name: "HEAD", TargetType: BranchTargetAlias, Target: name},
+	}
+
+	// The manifest carries the name bytes unchanged, not U+FFFD.
+	rawTarget, _ := hex.DecodeString(target)
+	manifest := "alias HEAD\x00" + strconv.Itoa(len(name)) + ":" + name +
+		"revision " + name + "\x0020:" + string(rawTarget)
+	sum := sha1.Sum([]byte("snapshot " + strconv.Itoa(len(manifest)) + "\x00" + manifest))
+	if got, want := ComputeSnapshotHash(branches), hex.EncodeToString(sum[:]); got != want {
+		t.Errorf("ComputeSnapshotHash() = %v, want %v", got, want)
+	}
+
+	data, err := json.Marshal(branches)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var decoded []Branch
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if !slices.Equal(decoded, branches) {
+		t.Errorf("JSON round trip = %q, want %q", decoded, branches)
+	}
+}
+
+func TestBranchJSON(t *testing.T) {
+	b := Branch{Name: "refs/heads/main", TargetType: BranchTargetRevision, Target: "4b825dc642cb6eb9a060e54bf8d69288fbee4904"}
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if want := `{"Name":"refs/heads/main","TargetType":"revision","Target":"4b825dc642cb6eb9a060e54bf8d69288fbee4904"}`; string(data) != want {
+		t.Errorf("json.Marshal() = %s, want %s", data, want)
+	}
+
+	var decoded Branch
+	if err := json.Unmarshal([]byte(`{"name": "HEAD", "targetType": "dangling"}`), &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if decoded != (Branch{Name: "HEAD", TargetType: BranchTargetDangling}) {
+		t.Errorf("json.Unmarshal() = %+v", decoded)
+	}
+}
+
+func TestResolveBranch(t *testing.T) {
+	const rev = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+	branches := []Branch{
+		{Name: "HEAD", TargetType: BranchTargetAlias, Target: "refs/heads/default"},
+		{Name: "refs/heads/default", TargetType: BranchTargetAlias, Target: "refs/heads/main"},
+		{Name: "refs/heads/main", TargetType: BranchTargetRevision, Target: rev},
+		{Name: "refs/heads/gone", TargetType: BranchTargetDangling},
+		{Name: "refs/heads/broken", TargetType: BranchTargetAlias, Target: "refs/heads/missing"},
+		{Name: "refs/heads/a", TargetType: BranchTargetAlias, Target: "refs/heads/b"},
+		{Name: "refs/heads/b", TargetType: BranchTargetAlias, Target: "refs/heads/a"},
+	}
+
+	tests := []struct {
+		name    string
+		want    Branch
+		wantErr error
+	}{
+		{"HEAD", branches[2], nil},
+		{"refs/heads/main", branches[2], nil},
+		{"refs/heads/gone", branches[3], nil},
+		{"refs/heads/broken", Branch{}, ErrAliasTargetMissing},
+		{"refs/heads/a", Branch{}, ErrAliasCycle},
+		{"refs/heads/nope", Branch{}, ErrBranchNotFound},
+	}
+	for _, tt := range tests {
+		got, err := ResolveBranch(branches, tt.name)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("ResolveBranch(%q) error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		var branchErr *BranchError
+		if tt.wantErr != nil && (!errors.As(err, &branchErr) || branchErr.Name != tt.name) {
+			t.Errorf("ResolveBranch(%q) error = %v, want *BranchError for the name", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("ResolveBranch(%q) = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	def, err := NewSnapshot(branches).DefaultBranch()
+	if err != nil {
+		t.Fatalf("DefaultBranch() error = %v", err)
+	}
+	if def.Name != "refs/heads/main" || def.Target != rev {
+		t.Errorf("DefaultBranch() = %+v", def)
+	}
+
+	if _, err := NewSnapshot(branches[2:4]).DefaultBranch(); !errors.Is(err, ErrBranchNotFound) {
+		t.Errorf("DefaultBranch() without HEAD error = %v, want %v", err, ErrBranchNotFound)
+	}
+}
+
+func TestSortBranches(t *testing.T) {
+	branches := []Branch{
+		{Name: "refs/tags/v1"},
+		{Name: "refs/heads/caf\xe9"},
+		{Name: "HEAD"},
+		{Name: "refs/heads/main"},
+	}
+	SortBranches(branches)
+	var names []string
+	for _, b := range branches {
+		names = append(names, b.Name)
+	}
+	want := []string{"HEAD", "refs/heads/caf\xe9", "refs/heads/main", "refs/tags/v1"}
+	if !slices.Equal(names, want) {
+		t.Errorf("SortBranches() = %q, want %q", names, want)
+	}
+}
@@ -0,0 +1,255 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AnnotationRefName is the annotation an image layout's index.json tags
+// manifests with.
+const AnnotationRefName = "org.opencontainers.image.ref.name"
+
+// Layout is an OCI image layout directory, as written by docker buildx
+// --output type=oci,tar=false, skopeo or oras.
+type Layout struct {
+	Dir string
+}
+
+// Manifest implements Store. ref is a tag, matched against the
+// org.opencontainers.image.ref.name annotation of index.json entries, or a
+// digest; an empty ref names the only image in index.json, leaving out
+// artifacts.
+func (l *Layout) Manifest(ctx context.Context, ref string) (Descriptor, []byte, error) {
+	desc, err := l.lookup(ref)
+	if err != nil {
+		return Descriptor{}, nil, err
+	}
+	data, err := l.readBlob(desc.Digest)
+	if err != nil {
+		return Descriptor{}, nil, err
+	}
+	if desc.MediaType == "" {
+		var m struct {
+			MediaType string `json:"mediaType"`
+		}
+		if err := json.Unmarshal(data, &m); err != nil {
+			return Descriptor{}, nil, fmt.Errorf("oci: invalid manifest %s: %w", desc.Digest, err)
+		}
+		desc.MediaType = m.MediaType
+	}
+	return Descriptor{MediaType: desc.MediaType, ArtifactType: desc.ArtifactType, Digest: desc.Digest, Size: int64(len(data))}, data, nil
+}
+
+// lookup finds the index.json entry ref names. Digests not listed there
+// are looked up among the blobs.
+func (l *Layout) lookup(ref string) (Descriptor, error) {
+	index, err := l.index()
+	if err != nil {
+		return Descriptor{}, err
+	}
+	if strings.HasPrefix(ref, "sha256:") {
+		for _, m := range index.Manifests {
+			if m.Digest == ref {
+				return m, nil
+			}
+		}
+		if _, err := os.Stat(l.blobPath(ref)); err != nil {
+			return Descriptor{}, fmt.Errorf("%w: manifest %s", ErrNotFound, ref)
+		}
+		return Descriptor{Digest: ref}, nil
+	}
+
+	var found []Descriptor
+	for _, m := range index.Manifests {
+		if ref == "" && m.ArtifactType == "" || ref != "" && m.Annotations[AnnotationRefName] == ref {
+			found = append(found, m)
+		}
+	}
+	switch {
+	case len(found) == 0 && ref == "":
+		return Descriptor{}, fmt.Errorf("%w: image layout %s has no images", ErrNotFound, l.Dir)
+	case len(found) == 0:
+		return Descriptor{}, fmt.Errorf("%w: tag %s", ErrNotFound, ref)
+	case len(found) > 1 && ref == "":
+		return Descriptor{}, fmt.Errorf("oci: image layout %s has %d images; name one with a tag", l.Dir, len(found))
+	}
+	return found[0], nil
+}
+
+// PutManifest implements Store, adding or, for a tag already present,
+// replacing the index.json entry.
+func (l *Layout) PutManifest(ctx context.Context, ref string, desc Descriptor, data []byte) error {
+	if err := l.PutBlob(ctx, desc, bytes.NewReader(data)); err != nil {
+		return err
+	}
+	index, err := l.index()
+	if err != nil {
+		return err
+	}
+
+	entry := Descriptor{MediaType: desc.MediaType, ArtifactType: desc.ArtifactType, Digest: desc.Digest, Size: desc.Size}
+	var m struct {
+		Subject     *Descriptor       `json:"subject"`
+		Annotations map[string]string `json:"annotations"`
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("oci: invalid manifest: %w", err)
+	}
+	if m.Subject != nil {
+		// index.json has no subject field; the referrer is found by
+		// reading the manifest, so only its annotations are copied.
+		entry.Annotations = m.Annotations
+	}
+	if ref != "" {
+		if entry.Annotations == nil {
+			entry.Annotations = make(map[string]string)
+		}
+		entry.Annotations[AnnotationRefName] = ref
+	}
+
+	manifests := index.Manifests[:0]
+	for _, existing := range index.Manifests {
+		if existing.Digest == desc.Digest && existing.Annotations[AnnotationRefName] == ref {
+			continue
+		}
+		if ref != "" && existing.Annotations[AnnotationRefName] == ref {
+			continue
+		}
+		manifests = append(manifests, existing)
+	}
+	index.Manifests = append(manifests, entry)
+	return l.writeIndex(index)
+}
+
+// Blob implements Store.
+func (l *Layout) Blob(ctx context.Context, desc Descriptor) (io.ReadCloser, error) {
+	f, err := os.Open(l.blobPath(desc.Digest))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: blob %s", ErrNotFound, desc.Digest)
+	}
+	return f, err
+}
+
+// PutBlob implements Store. The blob is written to a temporary file and
+// renamed into place once its digest is checked.
+func (l *Layout) PutBlob(ctx context.Context, desc Descriptor, r io.Reader) error {
+	if err := l.init(); err != nil {
+		return err
+	}
+	path := l.blobPath(desc.Digest)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if got := "sha256:" + hex.EncodeToString(h.Sum(nil)); got != desc.Digest {
+		return fmt.Errorf("oci: blob digest is %s, want %s", got, desc.Digest)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Referrers implements Store by reading each manifest index.json lists.
+func (l *Layout) Referrers(ctx context.Context, subject Descriptor, artifactType string) ([]Descriptor, error) {
+	index, err := l.index()
+	if err != nil {
+		return nil, err
+	}
+	var referrers []Descriptor
+	for _, entry := range index.Manifests {
+		if entry.MediaType != MediaTypeImageManifest && entry.MediaType != "" {
+			continue
+		}
+		data, err := l.readBlob(entry.Digest)
+		if err != nil {
+			return nil, err
+		}
+		var m Manifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("oci: invalid manifest %s: %w", entry.Digest, err)
+		}
+		if m.Subject == nil || m.Subject.Digest != subject.Digest {
+			continue
+		}
+		desc := Descriptor{MediaType: MediaTypeImageManifest, ArtifactType: m.ArtifactType, Digest: entry.Digest, Size: int64(len(data)), Annotations: m.Annotations}
+		if desc.ArtifactType == "" {
+			desc.ArtifactType = m.Config.MediaType
+		}
+		if artifactType == "" || desc.ArtifactType == artifactType {
+			referrers = append(referrers, desc)
+		}
+	}
+	return referrers, nil
+}
+
+func (l *Layout) blobPath(digest string) string {
+	algorithm, hex, _ := strings.Cut(digest, ":")
+	return filepath.Join(l.Dir, "blobs", algorithm, hex)
+}
+
+func (l *Layout) readBlob(digest string) ([]byte, error) {
+	data, err := os.ReadFile(l.blobPath(digest))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: blob %s", ErrNotFound, digest)
+	}
+	return data, err
+}
+
+func (l *Layout) index() (*Index, error) {
+	data, err := os.ReadFile(filepath.Join(l.Dir, "index.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Index{SchemaVersion: 2, MediaType: MediaTypeImageIndex}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var index Index
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("oci: invalid index.json: %w", err)
+	}
+	return &index, nil
+}
+
+func (l *Layout) writeIndex(index *Index) error {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(l.Dir, "index.json"), data, 0644)
+}
+
+// init creates the oci-layout file of a new layout.
+func (l *Layout) init() error {
+	path := filepath.Join(l.Dir, "oci-layout")
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(l.Dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(`{"imageLayoutVersion":"1.0.0"}`), 0644)
+}
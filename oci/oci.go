@@ -0,0 +1,395 @@
+// Package oci attaches SWHIDs to container images, in registries and in
+// OCI image layouts, and checks them against the source the image was built
+// from.
+//
+// Identifiers are attached in either of two ways. Annotate adds them as
+// annotations of the image manifest, such as
+// org.softwareheritage.swhid.dir, which changes the image's digest and so
+// belongs in the publishing step. AttachSource leaves the image untouched
+// and pushes a referrer artifact for it: an OCI 1.1 manifest whose subject
+// is the image, carrying the same annotations and, as its only layer, a
+// gzipped tar of the source written by release.WriteTar. Verify fetches
+// such artifacts, recomputes the SWHID of each source layer and checks it
+// against what the artifact and the image record.
+package oci
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/release"
+)
+
+// Annotation keys.
+const (
+	AnnotationDirectory = "org.softwareheritage.swhid.dir"
+	AnnotationRevision  = "org.softwareheritage.swhid.rev"
+	AnnotationOrigin    = "org.softwareheritage.origin"
+)
+
+// Media types.
+const (
+	MediaTypeImageManifest  = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeImageIndex     = "application/vnd.oci.image.index.v1+json"
+	MediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeDockerList     = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeEmpty          = "application/vnd.oci.empty.v1+json"
+	MediaTypeLayerGzip      = "application/vnd.oci.image.layer.v1.tar+gzip"
+
+	// ArtifactTypeSource is the artifact type of the referrers
+	// AttachSource pushes.
+	ArtifactTypeSource = "application/vnd.softwareheritage.source.v1"
+)
+
+var (
+	// ErrNoSource is returned by Verify for images without a source
+	// artifact.
+	ErrNoSource = errors.New("oci: image has no attached source")
+
+	// ErrMismatch is returned with a Verification whose source does not
+	// hash to the SWHIDs recorded for it.
+	ErrMismatch = errors.New("oci: source does not match its SWHID")
+
+	// ErrNotFound is returned by stores for missing manifests and blobs.
+	ErrNotFound = errors.New("oci: not found")
+)
+
+// Descriptor points to a manifest or blob by digest.
+type Descriptor struct {
+	MediaType    string            `json:"mediaType"`
+	ArtifactType string            `json:"artifactType,omitempty"`
+	Digest       string            `json:"digest"`
+	Size         int64             `json:"size"`
+	Data         []byte            `json:"data,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+}
+
+// Manifest is an OCI image manifest.
+type Manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType,omitempty"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        Descriptor        `json:"config"`
+	Layers        []Descriptor      `json:"layers"`
+	Subject       *Descriptor       `json:"subject,omitempty"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// Index is an OCI image index, as listed by the referrers API and kept in
+// an image layout's index.json.
+type Index struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType,omitempty"`
+	Manifests     []Descriptor      `json:"manifests"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// Store holds images: a registry repository (Registry) or an image layout
+// directory (Layout).
+type Store interface {
+	// Manifest returns the descriptor and content of the manifest or
+	// index ref names, a tag or a digest.
+	Manifest(ctx context.Context, ref string) (Descriptor, []byte, error)
+
+	// PutManifest stores the manifest data, described by desc, under the
+	// tag ref, or only by digest when ref is empty.
+	PutManifest(ctx context.Context, ref string, desc Descriptor, data []byte) error
+
+	// Blob opens the blob desc describes.
+	Blob(ctx context.Context, desc Descriptor) (io.ReadCloser, error)
+
+	// PutBlob stores the blob read from r, which desc describes.
+	PutBlob(ctx context.Context, desc Descriptor, r io.Reader) error
+
+	// Referrers lists the manifests whose subject is subject, limited to
+	// artifactType unless it is empty.
+	Referrers(ctx context.Context, subject Descriptor, artifactType string) ([]Descriptor, error)
+}
+
+// Digest returns the sha256 digest of data.
+func Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Annotations returns the annotations recording a source directory's
+// SWHID and, when known, the revision and origin it was taken from.
+func Annotations(dir, rev *swhid.Identifier, origin string) map[string]string {
+	a := map[string]string{AnnotationDirectory: dir.CoreSWHID()}
+	if rev != nil {
+		a[AnnotationRevision] = rev.CoreSWHID()
+	}
+	if origin != "" {
+		a[AnnotationOrigin] = origin
+	}
+	return a
+}
+
+// Annotate adds annotations to the manifest or index ref names in store,
+// keeping every other field, and stores the result under ref again. It
+// returns the descriptor of the annotated manifest, whose digest differs
+// from the original's. Docker manifests have no annotations; attach a
+// source artifact to them instead.
+func Annotate(ctx context.Context, store Store, ref string, annotations map[string]string) (Descriptor, error) {
+	desc, data, err := store.Manifest(ctx, ref)
+	if err != nil {
+		return Descriptor{}, err
+	}
+	if desc.MediaType == MediaTypeDockerManifest || desc.MediaType == MediaTypeDockerList {
+		return Descriptor{}, fmt.Errorf("oci: %s manifests cannot carry annotations", desc.MediaType)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return Descriptor{}, fmt.Errorf("oci: invalid manifest: %w", err)
+	}
+	merged := make(map[string]string)
+	if raw, ok := fields["annotations"]; ok {
+		if err := json.Unmarshal(raw, &merged); err != nil {
+			return Descriptor{}, fmt.Errorf("oci: invalid manifest annotations: %w", err)
+		}
+	}
+	for k, v := range annotations {
+		merged[k] = v
+	}
+	if fields["annotations"], err = json.Marshal(merged); err != nil {
+		return Descriptor{}, err
+	}
+	if data, err = json.Marshal(fields); err != nil {
+		return Descriptor{}, err
+	}
+
+	annotated := Descriptor{MediaType: desc.MediaType, Digest: Digest(data), Size: int64(len(data))}
+	if err := store.PutManifest(ctx, ref, annotated, data); err != nil {
+		return Descriptor{}, err
+	}
+	return annotated, nil
+}
+
+// Source is the source code an image was built from.
+type Source struct {
+	Dir  string // directory holding the source
+	Opts release.Options
+
+	// Revision and Origin, if set, are recorded with the directory's
+	// SWHID.
+	Revision *swhid.Identifier
+	Origin   string
+}
+
+// AttachSource pushes a referrer artifact for the image ref names in
+// store: a manifest of type ArtifactTypeSource whose subject is the image
+// and whose only layer is a gzipped release tar of src.Dir, annotated with
+// the directory's SWHID and src's revision and origin. The image itself is
+// not modified. It returns the artifact's descriptor and the directory's
+// SWHID.
+func AttachSource(ctx context.Context, store Store, ref string, src Source) (Descriptor, *swhid.Identifier, error) {
+	subject, _, err := store.Manifest(ctx, ref)
+	if err != nil {
+		return Descriptor{}, nil, err
+	}
+
+	// The layer is spooled to disk, as its digest is needed before it is
+	// uploaded.
+	tmp, err := os.CreateTemp("", "swhid-oci-*.tar.gz")
+	if err != nil {
+		return Descriptor{}, nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	zw := gzip.NewWriter(io.MultiWriter(tmp, h))
+	dir, err := release.WriteTar(zw, src.Dir, src.Opts)
+	if err != nil {
+		return Descriptor{}, nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return Descriptor{}, nil, err
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return Descriptor{}, nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return Descriptor{}, nil, err
+	}
+
+	annotations := Annotations(dir, src.Revision, src.Origin)
+	layer := Descriptor{
+		MediaType:   MediaTypeLayerGzip,
+		Digest:      "sha256:" + hex.EncodeToString(h.Sum(nil)),
+		Size:        size,
+		Annotations: map[string]string{"org.opencontainers.image.title": "source.tar.gz"},
+	}
+	if err := store.PutBlob(ctx, layer, tmp); err != nil {
+		return Descriptor{}, nil, err
+	}
+
+	empty := []byte("{}")
+	config := Descriptor{MediaType: MediaTypeEmpty, Digest: Digest(empty), Size: int64(len(empty)), Data: empty}
+	if err := store.PutBlob(ctx, config, bytes.NewReader(empty)); err != nil {
+		return Descriptor{}, nil, err
+	}
+
+	manifest := Manifest{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeImageManifest,
+		ArtifactType:  ArtifactTypeSource,
+		Config:        config,
+		Layers:        []Descriptor{layer},
+		Subject:       &Descriptor{MediaType: subject.MediaType, Digest: subject.Digest, Size: subject.Size},
+		Annotations:   annotations,
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return Descriptor{}, nil, err
+	}
+	desc := Descriptor{
+		MediaType:    MediaTypeImageManifest,
+		ArtifactType: ArtifactTypeSource,
+		Digest:       Digest(data),
+		Size:         int64(len(data)),
+		Annotations:  annotations,
+	}
+	if err := store.PutManifest(ctx, "", desc, data); err != nil {
+		return Descriptor{}, nil, err
+	}
+	return desc, dir, nil
+}
+
+// Verification is what Verify found.
+type Verification struct {
+	Image     Descriptor
+	Annotated *swhid.Identifier // the image's directory annotation; nil if there is none
+	Sources   []SourceCheck
+}
+
+// SourceCheck is the check of one source artifact.
+type SourceCheck struct {
+	Artifact Descriptor
+	Recorded *swhid.Identifier // the artifact's directory annotation
+	Computed *swhid.Identifier // the SWHID of its source layer
+}
+
+// Match reports whether the source hashes to what the artifact, and the
+// image if annotated, record.
+func (v *Verification) Match() bool {
+	if len(v.Sources) == 0 {
+		return false
+	}
+	for _, s := range v.Sources {
+		if s.Recorded == nil || s.Computed.CoreSWHID() != s.Recorded.CoreSWHID() {
+			return false
+		}
+		if v.Annotated != nil && s.Computed.CoreSWHID() != v.Annotated.CoreSWHID() {
+			return false
+		}
+	}
+	return true
+}
+
+// Verify checks the source artifacts attached to the image ref names in
+// store: it recomputes the SWHID of each one's source layer and compares
+// it with the artifact's directory annotation and the image's, if any. The
+// Verification comes with ErrNoSource when there is no source artifact and
+// ErrMismatch when a SWHID differs; other errors come without one.
+func Verify(ctx context.Context, store Store, ref string) (*Verification, error) {
+	image, data, err := store.Manifest(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	v := &Verification{Image: image}
+
+	var annotated struct {
+		Annotations map[string]string `json:"annotations"`
+	}
+	if err := json.Unmarshal(data, &annotated); err != nil {
+		return nil, fmt.Errorf("oci: invalid manifest: %w", err)
+	}
+	if s, ok := annotated.Annotations[AnnotationDirectory]; ok {
+		if v.Annotated, err = swhid.Parse(s); err != nil {
+			return nil, fmt.Errorf("oci: invalid %s annotation: %w", AnnotationDirectory, err)
+		}
+	}
+
+	referrers, err := store.Referrers(ctx, image, ArtifactTypeSource)
+	if err != nil {
+		return nil, err
+	}
+	for _, desc := range referrers {
+		check, err := checkSource(ctx, store, desc)
+		if err != nil {
+			return nil, err
+		}
+		v.Sources = append(v.Sources, *check)
+	}
+
+	switch {
+	case len(v.Sources) == 0:
+		return v, ErrNoSource
+	case !v.Match():
+		return v, ErrMismatch
+	}
+	return v, nil
+}
+
+// checkSource recomputes the SWHID of the source layer of the artifact
+// desc describes.
+func checkSource(ctx context.Context, store Store, desc Descriptor) (*SourceCheck, error) {
+	_, data, err := store.Manifest(ctx, desc.Digest)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("oci: invalid source artifact %s: %w", desc.Digest, err)
+	}
+	if len(m.Layers) != 1 || m.Layers[0].MediaType != MediaTypeLayerGzip {
+		return nil, fmt.Errorf("oci: source artifact %s does not have one gzipped tar layer", desc.Digest)
+	}
+
+	check := &SourceCheck{Artifact: desc}
+	if s, ok := m.Annotations[AnnotationDirectory]; ok {
+		if check.Recorded, err = swhid.Parse(s); err != nil {
+			return nil, fmt.Errorf("oci: invalid %s annotation on %s: %w", AnnotationDirectory, desc.Digest, err)
+		}
+	}
+
+	blob, err := store.Blob(ctx, m.Layers[0])
+	if err != nil {
+		return nil, err
+	}
+	defer blob.Close()
+	h := sha256.New()
+	digested := io.TeeReader(blob, h)
+	zr, err := gzip.NewReader(digested)
+	if err != nil {
+		return nil, fmt.Errorf("oci: source layer %s: %w", m.Layers[0].Digest, err)
+	}
+	result, err := release.ReadTar(zr)
+	if result == nil {
+		return nil, fmt.Errorf("oci: source layer %s: %w", m.Layers[0].Digest, err)
+	}
+	// Read what the tar reader left, so the whole blob is digested.
+	if _, err := io.Copy(io.Discard, zr); err != nil {
+		return nil, fmt.Errorf("oci: source layer %s: %w", m.Layers[0].Digest, err)
+	}
+	if _, err := io.Copy(io.Discard, digested); err != nil {
+		return nil, err
+	}
+	if got := "sha256:" + hex.EncodeToString(h.Sum(nil)); got != m.Layers[0].Digest {
+		return nil, fmt.Errorf("oci: source layer digest is %s, want %s", got, m.Layers[0].Digest)
+	}
+	check.Computed = result.Computed
+	return check, nil
+}
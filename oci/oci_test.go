@@ -0,0 +1,258 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrew/swhid-go"
+)
+
+func testSource(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("hello\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	return dir
+}
+
+// pushImage stores a minimal image in store under tag.
+func pushImage(t *testing.T, store Store, tag string) Descriptor {
+	t.Helper()
+
+	ctx := context.Background()
+	config := []byte(`{"architecture":"amd64","os":"linux"}`)
+	layer := []byte("not really a layer")
+	configDesc := Descriptor{MediaType: "application/vnd.oci.image.config.v1+json", Digest: Digest(config), Size: int64(len(config))}
+	layerDesc := Descriptor{MediaType: MediaTypeLayerGzip, Digest: Digest(layer), Size: int64(len(layer))}
+	for _, blob := range []struct {
+		desc Descriptor
+		data []byte
+	}{{configDesc, config}, {layerDesc, layer}} {
+		if err := store.PutBlob(ctx, blob.desc, bytes.NewReader(blob.data)); err != nil {
+			t.Fatalf("PutBlob() error = %v", err)
+		}
+	}
+
+	data, err := json.Marshal(Manifest{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeImageManifest,
+		Config:        configDesc,
+		Layers:        []Descriptor{layerDesc},
+		Annotations:   map[string]string{"org.opencontainers.image.version": "1.0"},
+	})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	desc := Descriptor{MediaType: MediaTypeImageManifest, Digest: Digest(data), Size: int64(len(data))}
+	if err := store.PutManifest(ctx, tag, desc, data); err != nil {
+		t.Fatalf("PutManifest() error = %v", err)
+	}
+	return desc
+}
+
+func TestAnnotate(t *testing.T) {
+	ctx := context.Background()
+	store := &Layout{Dir: t.TempDir()}
+	image := pushImage(t, store, "v1")
+
+	dir, err := swhid.FromDirectoryPath(testSource(t))
+	if err != nil {
+		t.Fatalf("FromDirectoryPath() error = %v", err)
+	}
+	annotated, err := Annotate(ctx, store, "v1", Annotations(dir, nil, "https://github.com/example/project"))
+	if err != nil {
+		t.Fatalf("Annotate() error = %v", err)
+	}
+	if annotated.Digest == image.Digest {
+		t.Error("Annotate() kept the image digest")
+	}
+
+	desc, data, err := store.Manifest(ctx, "v1")
+	if err != nil {
+		t.Fatalf("Manifest() error = %v", err)
+	}
+	if desc.Digest != annotated.Digest {
+		t.Errorf("Manifest(v1) = %s, want the annotated %s", desc.Digest, annotated.Digest)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	want := map[string]string{
+		"org.opencontainers.image.version": "1.0",
+		AnnotationDirectory:                dir.CoreSWHID(),
+		AnnotationOrigin:                   "https://github.com/example/project",
+	}
+	if len(m.Annotations) != len(want) {
+		t.Errorf("annotations = %v, want %v", m.Annotations, want)
+	}
+	for k, v := range want {
+		if m.Annotations[k] != v {
+			t.Errorf("annotation %s = %q, want %q", k, m.Annotations[k], v)
+		}
+	}
+	if len(m.Layers) != 1 || m.Config.Digest != Digest([]byte(`{"architecture":"amd64","os":"linux"}`)) {
+		t.Errorf("Annotate() lost manifest fields: %s", data)
+	}
+}
+
+func TestAttachSourceAndVerify(t *testing.T) {
+	ctx := context.Background()
+	store := &Layout{Dir: t.TempDir()}
+	image := pushImage(t, store, "v1")
+	source := testSource(t)
+
+	if _, err := Verify(ctx, store, "v1"); !errors.Is(err, ErrNoSource) {
+		t.Errorf("Verify() before attaching error = %v, want ErrNoSource", err)
+	}
+
+	rev, err := swhid.Parse("swh:1:rev:0000000000000000000000000000000000000001")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	artifact, dir, err := AttachSource(ctx, store, "v1", Source{Dir: source, Revision: rev})
+	if err != nil {
+		t.Fatalf("AttachSource() error = %v", err)
+	}
+	want, err := swhid.FromDirectoryPath(source)
+	if err != nil {
+		t.Fatalf("FromDirectoryPath() error = %v", err)
+	}
+	if !dir.Equal(want) {
+		t.Errorf("AttachSource() = %v, want %v", dir, want)
+	}
+	if artifact.Annotations[AnnotationRevision] != rev.CoreSWHID() {
+		t.Errorf("artifact annotations = %v, want revision %s", artifact.Annotations, rev)
+	}
+
+	// The image is unchanged and still the only one in the layout.
+	desc, _, err := store.Manifest(ctx, "")
+	if err != nil {
+		t.Fatalf("Manifest() error = %v", err)
+	}
+	if desc.Digest != image.Digest {
+		t.Errorf("Manifest() = %s, want the image %s", desc.Digest, image.Digest)
+	}
+
+	v, err := Verify(ctx, store, "v1")
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if !v.Match() || len(v.Sources) != 1 || !v.Sources[0].Computed.Equal(want) {
+		t.Errorf("Verify() = %+v, want one matching source", v)
+	}
+
+	// An image annotated with another directory does not match its source.
+	other, err := swhid.Parse("swh:1:dir:0000000000000000000000000000000000000002")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	annotated, err := Annotate(ctx, store, "v1", Annotations(other, nil, ""))
+	if err != nil {
+		t.Fatalf("Annotate() error = %v", err)
+	}
+	if _, _, err := AttachSource(ctx, store, "v1", Source{Dir: source}); err != nil {
+		t.Fatalf("AttachSource() error = %v", err)
+	}
+	v, err = Verify(ctx, store, "v1")
+	if !errors.Is(err, ErrMismatch) {
+		t.Fatalf("Verify() error = %v, want ErrMismatch", err)
+	}
+	if v.Image.Digest != annotated.Digest || !v.Annotated.Equal(other) {
+		t.Errorf("Verify() = %+v, want the annotated image", v)
+	}
+}
+
+func TestVerifyTamperedSource(t *testing.T) {
+	ctx := context.Background()
+	store := &Layout{Dir: t.TempDir()}
+	pushImage(t, store, "latest")
+
+	artifact, _, err := AttachSource(ctx, store, "latest", Source{Dir: testSource(t)})
+	if err != nil {
+		t.Fatalf("AttachSource() error = %v", err)
+	}
+	_, data, err := store.Manifest(ctx, artifact.Digest)
+	if err != nil {
+		t.Fatalf("Manifest() error = %v", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if err := os.WriteFile(store.blobPath(m.Layers[0].Digest), []byte("tampered"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	if _, err := Verify(ctx, store, "latest"); err == nil {
+		t.Error("Verify() accepted a tampered source layer")
+	}
+}
+
+// testDigest is a well-formed manifest digest.
+const testDigest = "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		ref                    string
+		host, repository, name string
+		plainHTTP              bool
+	}{
+		{"alpine", "index.docker.io", "library/alpine", "latest", false},
+		{"example/app:1.0", "index.docker.io", "example/app", "1.0", false},
+		{"ghcr.io/example/app:v2", "ghcr.io", "example/app", "v2", false},
+		{"localhost:5000/app", "localhost:5000", "app", "latest", true},
+		{"127.0.0.1:5000/app:v1", "127.0.0.1:5000", "app", "v1", true},
+		{"ghcr.io/example/app@" + testDigest, "ghcr.io", "example/app", testDigest, false},
+	}
+	for _, tt := range tests {
+		store, name, err := ParseReference(tt.ref)
+		if err != nil {
+			t.Errorf("ParseReference(%q) error = %v", tt.ref, err)
+			continue
+		}
+		r, ok := store.(*Registry)
+		if !ok {
+			t.Errorf("ParseReference(%q) = %T, want *Registry", tt.ref, store)
+			continue
+		}
+		if r.Host != tt.host || r.Repository != tt.repository || name != tt.name || r.PlainHTTP != tt.plainHTTP {
+			t.Errorf("ParseReference(%q) = %+v %q, want %s/%s %q", tt.ref, r, name, tt.host, tt.repository, tt.name)
+		}
+	}
+
+	layouts := []struct{ ref, dir, name string }{
+		{"oci:build/image", "build/image", ""},
+		{"oci:build/image:v1", "build/image", "v1"},
+		{"oci:image@sha256:abc", "image", "sha256:abc"},
+	}
+	for _, tt := range layouts {
+		store, name, err := ParseReference(tt.ref)
+		if err != nil {
+			t.Errorf("ParseReference(%q) error = %v", tt.ref, err)
+			continue
+		}
+		if l, ok := store.(*Layout); !ok || l.Dir != tt.dir || name != tt.name {
+			t.Errorf("ParseReference(%q) = %+v %q, want layout %s %q", tt.ref, store, name, tt.dir, tt.name)
+		}
+	}
+
+	for _, ref := range []string{"oci:", "Example/App", "ghcr.io/", "ghcr.io/example/app@sha256:abc"} {
+		if _, _, err := ParseReference(ref); err == nil {
+			t.Errorf("ParseReference(%q) succeeded", ref)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// Registry is a repository in a registry speaking the OCI distribution
+// API, such as ghcr.io/owner/image.
+type Registry struct {
+	Host       string // such as ghcr.io or localhost:5000
+	Repository string // such as owner/image
+	PlainHTTP  bool   // use http rather than https
+
+	// Client sends the requests through its Transport; nil means
+	// http.DefaultClient.
+	Client *http.Client
+
+	// Keychain finds the credentials for Host; nil means
+	// authn.DefaultKeychain, which reads the Docker configuration file
+	// and runs the credential helpers it names.
+	Keychain authn.Keychain
+
+	mu     sync.Mutex
+	client *http.Client // Client, authenticating to the registry
+}
+
+// acceptManifests lists the manifest media types Manifest accepts.
+var acceptManifests = strings.Join([]string{MediaTypeImageManifest, MediaTypeImageIndex, MediaTypeDockerManifest, MediaTypeDockerList}, ", ")
+
+// Manifest implements Store.
+func (r *Registry) Manifest(ctx context.Context, ref string) (Descriptor, []byte, error) {
+	req, err := r.request(ctx, http.MethodGet, "manifests/"+ref, nil)
+	if err != nil {
+		return Descriptor{}, nil, err
+	}
+	req.Header.Set("Accept", acceptManifests)
+	resp, err := r.do(req)
+	if err != nil {
+		return Descriptor{}, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Descriptor{}, nil, responseError(resp, "manifest "+ref)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Descriptor{}, nil, err
+	}
+
+	desc := Descriptor{Digest: Digest(data), Size: int64(len(data))}
+	if strings.HasPrefix(ref, "sha256:") && desc.Digest != ref {
+		return Descriptor{}, nil, fmt.Errorf("oci: manifest digest is %s, want %s", desc.Digest, ref)
+	}
+	desc.MediaType, _, _ = mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if desc.MediaType == "" || desc.MediaType == "application/json" {
+		var m struct {
+			MediaType string `json:"mediaType"`
+		}
+		if err := json.Unmarshal(data, &m); err != nil {
+			return Descriptor{}, nil, fmt.Errorf("oci: invalid manifest %s: %w", ref, err)
+		}
+		desc.MediaType = m.MediaType
+	}
+	return desc, data, nil
+}
+
+// PutManifest implements Store. Registries without the referrers API do
+// not record the subject of a manifest; for them the manifest is also
+// added to the index tagged after its subject's digest, as the
+// distribution specification's fallback describes.
+func (r *Registry) PutManifest(ctx context.Context, ref string, desc Descriptor, data []byte) error {
+	if ref == "" {
+		ref = desc.Digest
+	}
+	req, err := r.request(ctx, http.MethodPut, "manifests/"+ref, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", desc.MediaType)
+	resp, err := r.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return responseError(resp, "manifest "+ref)
+	}
+
+	var m struct {
+		Subject *Descriptor `json:"subject"`
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("oci: invalid manifest: %w", err)
+	}
+	if m.Subject == nil || resp.Header.Get("OCI-Subject") != "" {
+		return nil
+	}
+	return r.addFallbackReferrer(ctx, *m.Subject, desc)
+}
+
+// addFallbackReferrer adds desc to the referrers index tagged after
+// subject's digest.
+func (r *Registry) addFallbackReferrer(ctx context.Context, subject, desc Descriptor) error {
+	tag := fallbackTag(subject.Digest)
+	index := &Index{SchemaVersion: 2, MediaType: MediaTypeImageIndex}
+	_, data, err := r.Manifest(ctx, tag)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, index); err != nil {
+			return fmt.Errorf("oci: invalid referrers index %s: %w", tag, err)
+		}
+	case !errors.Is(err, ErrNotFound):
+		return err
+	}
+	for _, m := range index.Manifests {
+		if m.Digest == desc.Digest {
+			return nil
+		}
+	}
+	index.Manifests = append(index.Manifests, Descriptor{
+		MediaType:    desc.MediaType,
+		ArtifactType: desc.ArtifactType,
+		Digest:       desc.Digest,
+		Size:         desc.Size,
+		Annotations:  desc.Annotations,
+	})
+	if data, err = json.Marshal(index); err != nil {
+		return err
+	}
+	return r.PutManifest(ctx, tag, Descriptor{MediaType: MediaTypeImageIndex, Digest: Digest(data), Size: int64(len(data))}, data)
+}
+
+// fallbackTag returns the tag of the referrers index for registries
+// without the referrers API: the digest with its colon replaced by a dash.
+func fallbackTag(digest string) string {
+	return strings.Replace(digest, ":", "-", 1)
+}
+
+// Blob implements Store.
+func (r *Registry) Blob(ctx context.Context, desc Descriptor) (io.ReadCloser, error) {
+	req, err := r.request(ctx, http.MethodGet, "blobs/"+desc.Digest, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, responseError(resp, "blob "+desc.Digest)
+	}
+	return resp.Body, nil
+}
+
+// PutBlob implements Store with a monolithic upload, skipped when the
+// registry already has the blob.
+func (r *Registry) PutBlob(ctx context.Context, desc Descriptor, body io.Reader) error {
+	req, err := r.request(ctx, http.MethodHead, "blobs/"+desc.Digest, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := r.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	if req, err = r.request(ctx, http.MethodPost, "blobs/uploads/", nil); err != nil {
+		return err
+	}
+	if resp, err = r.do(req); err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return responseError(resp, "upload of blob "+desc.Digest)
+	}
+	location, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return fmt.Errorf("oci: invalid upload location: %w", err)
+	}
+	query := location.Query()
+	query.Set("digest", desc.Digest)
+	location.RawQuery = query.Encode()
+
+	if req, err = http.NewRequestWithContext(ctx, http.MethodPut, location.String(), body); err != nil {
+		return err
+	}
+	req.ContentLength = desc.Size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if resp, err = r.do(req); err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return responseError(resp, "upload of blob "+desc.Digest)
+	}
+	return nil
+}
+
+// Referrers implements Store with the referrers API, falling back to the
+// index tagged after subject's digest for registries without it.
+func (r *Registry) Referrers(ctx context.Context, subject Descriptor, artifactType string) ([]Descriptor, error) {
+	path := "referrers/" + subject.Digest
+	if artifactType != "" {
+		path += "?artifactType=" + url.QueryEscape(artifactType)
+	}
+	req, err := r.request(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", MediaTypeImageIndex)
+	resp, err := r.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var data []byte
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if data, err = io.ReadAll(resp.Body); err != nil {
+			return nil, err
+		}
+	case http.StatusNotFound:
+		_, data, err = r.Manifest(ctx, fallbackTag(subject.Digest))
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, responseError(resp, "referrers of "+subject.Digest)
+	}
+
+	var index Index
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("oci: invalid referrers index: %w", err)
+	}
+	// Registries may ignore the artifactType filter.
+	var referrers []Descriptor
+	for _, m := range index.Manifests {
+		if artifactType == "" || m.ArtifactType == artifactType {
+			referrers = append(referrers, m)
+		}
+	}
+	return referrers, nil
+}
+
+func (r *Registry) request(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	scheme := "https"
+	if r.PlainHTTP {
+		scheme = "http"
+	}
+	return http.NewRequestWithContext(ctx, method, scheme+"://"+r.Host+"/v2/"+r.Repository+"/"+path, body)
+}
+
+// do sends req with the authenticating client.
+func (r *Registry) do(req *http.Request) (*http.Response, error) {
+	client, err := r.authClient(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
+
+// authClient returns Client with a transport that authenticates to the
+// registry as its challenge asks, with the credentials from Keychain,
+// setting it up on first use.
+func (r *Registry) authClient(ctx context.Context) (*http.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.client != nil {
+		return r.client, nil
+	}
+
+	var opts []name.Option
+	if r.PlainHTTP {
+		opts = append(opts, name.Insecure)
+	}
+	repo, err := name.NewRepository(r.Host+"/"+r.Repository, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("oci: %w", err)
+	}
+	keychain := r.Keychain
+	if keychain == nil {
+		keychain = authn.DefaultKeychain
+	}
+	auth, err := keychain.Resolve(repo.Registry)
+	if err != nil {
+		return nil, fmt.Errorf("oci: credentials for %s: %w", r.Host, err)
+	}
+
+	client := http.Client{}
+	if r.Client != nil {
+		client = *r.Client
+	}
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if client.Transport, err = transport.NewWithContext(ctx, repo.Registry, auth, base, []string{repo.Scope(transport.PushScope)}); err != nil {
+		return nil, fmt.Errorf("oci: authenticating to %s for %s: %w", r.Host, r.Repository, err)
+	}
+	r.client = &client
+	return r.client, nil
+}
+
+// responseError describes an unsuccessful response to a request about
+// what, with the message of the registry's error body, if any.
+func responseError(resp *http.Response, what string) error {
+	var body struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrNotFound, what)
+	}
+	msg := resp.Status
+	if json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body) == nil && len(body.Errors) > 0 {
+		msg = body.Errors[0].Code + ": " + body.Errors[0].Message
+	}
+	return fmt.Errorf("oci: %s %s: %s", resp.Request.Method, what, msg)
+}
+
+// ParseReference parses an image reference: oci:DIR, oci:DIR:TAG or
+// oci:DIR@DIGEST for an image layout, and otherwise
+// [HOST/]REPOSITORY[:TAG][@DIGEST] for a registry, Docker Hub by default.
+// It returns the store and the tag or digest naming the image in it.
+// Registry credentials come from authn.DefaultKeychain.
+func ParseReference(s string) (Store, string, error) {
+	if dir, ok := strings.CutPrefix(s, "oci:"); ok {
+		ref := ""
+		if i := strings.LastIndex(dir, "@"); i >= 0 {
+			dir, ref = dir[:i], dir[i+1:]
+		} else if i := strings.LastIndex(dir, ":"); i > 0 && !strings.ContainsAny(dir[i+1:], `/\`) {
+			dir, ref = dir[:i], dir[i+1:]
+		}
+		if dir == "" {
+			return nil, "", fmt.Errorf("oci: invalid reference %q: missing directory", s)
+		}
+		return &Layout{Dir: dir}, ref, nil
+	}
+
+	ref, err := name.ParseReference(s)
+	if err != nil {
+		return nil, "", fmt.Errorf("oci: invalid reference %q: %w", s, err)
+	}
+	repo := ref.Context()
+	return &Registry{
+		Host:       repo.RegistryStr(),
+		Repository: repo.RepositoryStr(),
+		PlainHTTP:  repo.Scheme() == "http",
+	}, ref.Identifier(), nil
+}
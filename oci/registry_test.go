@@ -0,0 +1,243 @@
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+)
+
+// testRegistry is an in-memory registry for one repository, requiring a
+// bearer token and, unless referrers is false, implementing the referrers
+// API. When credentials is set the token service requires them as
+// "user:password".
+type testRegistry struct {
+	repository  string
+	referrers   bool
+	credentials string
+
+	mu        sync.Mutex
+	manifests map[string][]byte // by tag and digest
+	types     map[string]string // media types by digest
+	blobs     map[string][]byte
+}
+
+func newTestRegistry(t *testing.T, referrers bool) (*testRegistry, *Registry) {
+	t.Helper()
+
+	tr := &testRegistry{
+		repository: "example/app",
+		referrers:  referrers,
+		manifests:  make(map[string][]byte),
+		types:      make(map[string]string),
+		blobs:      make(map[string][]byte),
+	}
+	srv := httptest.NewServer(tr)
+	t.Cleanup(srv.Close)
+	return tr, &Registry{
+		Host:       strings.TrimPrefix(srv.URL, "http://"),
+		Repository: tr.repository,
+		PlainHTTP:  true,
+		Client:     srv.Client(),
+		Keychain:   authn.NewMultiKeychain(), // anonymous
+	}
+}
+
+func (tr *testRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	if r.URL.Path == "/token" {
+		if r.URL.Query().Get("scope") != "repository:"+tr.repository+":push,pull" {
+			http.Error(w, "bad scope", http.StatusForbidden)
+			return
+		}
+		if user, password, _ := r.BasicAuth(); tr.credentials != "" && user+":"+password != tr.credentials {
+			http.Error(w, "bad credentials", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"token": "secret"})
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="http://`+r.Host+`/token",service="test"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	rest, ok := strings.CutPrefix(r.URL.Path, "/v2/"+tr.repository+"/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	kind, ref, _ := strings.Cut(rest, "/")
+	switch {
+	case kind == "manifests" && r.Method == http.MethodGet:
+		data, ok := tr.manifests[ref]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", tr.types[Digest(data)])
+		w.Write(data)
+
+	case kind == "manifests" && r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		digest := Digest(data)
+		tr.manifests[ref], tr.manifests[digest] = data, data
+		tr.types[digest] = r.Header.Get("Content-Type")
+		var m Manifest
+		json.Unmarshal(data, &m)
+		if m.Subject != nil && tr.referrers {
+			w.Header().Set("OCI-Subject", m.Subject.Digest)
+		}
+		w.WriteHeader(http.StatusCreated)
+
+	case kind == "blobs" && ref == "uploads/" && r.Method == http.MethodPost:
+		w.Header().Set("Location", "/v2/"+tr.repository+"/blobs/uploads/1?state=x")
+		w.WriteHeader(http.StatusAccepted)
+
+	case kind == "blobs" && strings.HasPrefix(ref, "uploads/") && r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		if r.URL.Query().Get("state") != "x" || Digest(data) != r.URL.Query().Get("digest") {
+			http.Error(w, "bad upload", http.StatusBadRequest)
+			return
+		}
+		tr.blobs[Digest(data)] = data
+		w.WriteHeader(http.StatusCreated)
+
+	case kind == "blobs" && (r.Method == http.MethodGet || r.Method == http.MethodHead):
+		data, ok := tr.blobs[ref]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+
+	case kind == "referrers" && tr.referrers:
+		index := Index{SchemaVersion: 2, MediaType: MediaTypeImageIndex, Manifests: []Descriptor{}}
+		for key, data := range tr.manifests {
+			var m Manifest
+			if json.Unmarshal(data, &m) != nil || m.Subject == nil || m.Subject.Digest != ref || key != Digest(data) {
+				continue
+			}
+			index.Manifests = append(index.Manifests, Descriptor{
+				MediaType:    m.MediaType,
+				ArtifactType: m.ArtifactType,
+				Digest:       key,
+				Size:         int64(len(data)),
+				Annotations:  m.Annotations,
+			})
+		}
+		w.Header().Set("Content-Type", MediaTypeImageIndex)
+		json.NewEncoder(w).Encode(index)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"errors":[{"code":"NAME_UNKNOWN","message":"not here"}]}`))
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	for _, referrers := range []bool{true, false} {
+		ctx := context.Background()
+		tr, store := newTestRegistry(t, referrers)
+		image := pushImage(t, store, "v1")
+
+		desc, _, err := store.Manifest(ctx, "v1")
+		if err != nil {
+			t.Fatalf("Manifest() error = %v", err)
+		}
+		if desc.Digest != image.Digest || desc.MediaType != MediaTypeImageManifest {
+			t.Errorf("Manifest(v1) = %+v, want %+v", desc, image)
+		}
+		if _, _, err := store.Manifest(ctx, "nosuchtag"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Manifest(nosuchtag) error = %v, want ErrNotFound", err)
+		}
+
+		artifact, _, err := AttachSource(ctx, store, "v1", Source{Dir: testSource(t), Origin: "https://github.com/example/app"})
+		if err != nil {
+			t.Fatalf("AttachSource(referrers %v) error = %v", referrers, err)
+		}
+		_, fallback := tr.manifests[fallbackTag(image.Digest)]
+		if fallback == referrers {
+			t.Errorf("referrers %v: fallback index tagged = %v", referrers, fallback)
+		}
+
+		v, err := Verify(ctx, store, "v1")
+		if err != nil {
+			t.Fatalf("Verify(referrers %v) error = %v", referrers, err)
+		}
+		if len(v.Sources) != 1 || v.Sources[0].Artifact.Digest != artifact.Digest || !v.Match() {
+			t.Errorf("Verify(referrers %v) = %+v, want the attached source", referrers, v)
+		}
+		if v.Sources[0].Artifact.Annotations[AnnotationOrigin] != "https://github.com/example/app" {
+			t.Errorf("referrer annotations = %v, want the origin", v.Sources[0].Artifact.Annotations)
+		}
+	}
+}
+
+func TestRegistryErrors(t *testing.T) {
+	_, store := newTestRegistry(t, true)
+	store.Repository = "other/app"
+	_, _, err := store.Manifest(context.Background(), "v1")
+	if err == nil || !strings.Contains(err.Error(), "authenticating to "+store.Host+" for other/app") {
+		t.Errorf("Manifest() error = %v, want a token error", err)
+	}
+}
+
+func TestRegistryKeychain(t *testing.T) {
+	tr, store := newTestRegistry(t, true)
+	tr.credentials = "user:secret"
+	if _, _, err := store.Manifest(context.Background(), "v1"); err == nil {
+		t.Fatal("Manifest() without credentials succeeded")
+	}
+
+	store.Keychain = staticKeychain{authn.FromConfig(authn.AuthConfig{Username: "user", Password: "secret"})}
+	pushImage(t, store, "v1")
+}
+
+// staticKeychain resolves every registry to the same credentials.
+type staticKeychain struct{ auth authn.Authenticator }
+
+func (k staticKeychain) Resolve(authn.Resource) (authn.Authenticator, error) {
+	return k.auth, nil
+}
+
+func TestRegistryCredentialHelper(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test credential helper is a shell script")
+	}
+	tr, store := newTestRegistry(t, true)
+	tr.credentials = "helper-user:helper-secret"
+	store.Keychain = nil // authn.DefaultKeychain
+
+	// The Docker configuration names a credential helper for the registry,
+	// which the default keychain runs as docker-credential-NAME get
+	bin, config := t.TempDir(), t.TempDir()
+	helper := "#!/bin/sh\ncat >/dev/null\necho '{\"Username\":\"helper-user\",\"Secret\":\"helper-secret\"}'\n"
+	if err := os.WriteFile(filepath.Join(bin, "docker-credential-swhidtest"), []byte(helper), 0755); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(map[string]interface{}{"credHelpers": map[string]string{store.Host: "swhidtest"}})
+	if err := os.WriteFile(filepath.Join(config, "config.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DOCKER_CONFIG", config)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	pushImage(t, store, "v1")
+	if _, _, err := store.Manifest(context.Background(), "v1"); err != nil {
+		t.Errorf("Manifest() with helper credentials error = %v", err)
+	}
+}
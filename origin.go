@@ -0,0 +1,170 @@
+package swhid
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidOrigin is returned by NormalizeOrigin for origins that are not
+// absolute URLs.
+var ErrInvalidOrigin = errors.New("invalid origin URL")
+
+// defaultPorts are the ports NormalizeOrigin drops, by scheme.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ftp":   "21",
+	"svn":   "3690",
+}
+
+// caseInsensitiveHosts are the forges that serve repository paths
+// regardless of case, so that SameOrigin folds the case of their paths.
+var caseInsensitiveHosts = []string{"github.com", "gitlab.com", "bitbucket.org"}
+
+// NormalizeOrigin rewrites an origin URL into the form the archive records
+// origins under, so that the spellings of one origin compare equal:
+//
+//   - scp-like "git@host:owner/repo.git" remotes and ssh://, git+ssh:// and
+//     git:// URLs become https:// URLs on the same host, without the port,
+//     which is the SSH or Git daemon's rather than the web server's;
+//   - the scheme and host are lowercased, a trailing dot on the host and
+//     the scheme's default port are removed;
+//   - user names, passwords and fragments are removed;
+//   - the path is re-escaped in canonical form and, for http and https
+//     URLs, loses its trailing slashes and ".git" suffix, which the forges
+//     serve the same repository with and without.
+//
+// The path's case and the query are kept. Anything but an absolute URL
+// with a host, such as a local path, is ErrInvalidOrigin; file:// URLs are
+// accepted and only have their scheme lowercased.
+func NormalizeOrigin(origin string) (string, error) {
+	s := strings.TrimSpace(origin)
+	if converted, ok := scpToURL(s); ok {
+		s = converted
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || !u.IsAbs() || u.Opaque != "" {
+		return "", fmt.Errorf("%w: %q", ErrInvalidOrigin, origin)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme == "file" {
+		return u.String(), nil
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("%w: %q has no host", ErrInvalidOrigin, origin)
+	}
+
+	host, port := strings.TrimSuffix(strings.ToLower(u.Hostname()), "."), u.Port()
+	switch u.Scheme {
+	case "ssh", "git", "git+ssh", "ssh+git":
+		u.Scheme, port = "https", ""
+	}
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+	switch {
+	case port != "":
+		u.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		u.Host = "[" + host + "]"
+	default:
+		u.Host = host
+	}
+
+	u.User = nil
+	u.Fragment, u.RawFragment = "", ""
+	path := canonicalEscapes(u.EscapedPath())
+	if u.Scheme == "http" || u.Scheme == "https" {
+		path = strings.TrimRight(strings.TrimSuffix(strings.TrimRight(path, "/"), ".git"), "/")
+	}
+	if u.Path, err = url.PathUnescape(path); err != nil {
+		return "", fmt.Errorf("%w: %q: %w", ErrInvalidOrigin, origin, err)
+	}
+	u.RawPath = path
+	return u.String(), nil
+}
+
+// canonicalEscapes decodes the percent-escapes of unreserved characters in
+// an escaped URL path and writes the others in upper case, so that paths
+// spelled differently but meaning the same compare equal. Escaped slashes
+// and other reserved characters stay escaped.
+func canonicalEscapes(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		if p[i] != '%' || i+2 >= len(p) {
+			b.WriteByte(p[i])
+			continue
+		}
+		c, err := url.PathUnescape(p[i : i+3])
+		if err != nil {
+			b.WriteByte(p[i])
+			continue
+		}
+		if ch := c[0]; 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9' || strings.IndexByte("-._~", ch) >= 0 {
+			b.WriteByte(ch)
+		} else {
+			b.WriteString(strings.ToUpper(p[i : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+// SameOrigin reports whether two origin URLs name the same origin: their
+// normalized forms, per NormalizeOrigin, are equal once http is taken as
+// https and, on forges that ignore it such as github.com, the case of the
+// path is ignored. Origins that do not normalize are compared as given.
+func SameOrigin(a, b string) bool {
+	na, errA := NormalizeOrigin(a)
+	nb, errB := NormalizeOrigin(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return originKey(na) == originKey(nb)
+}
+
+// originKey folds a normalized origin into the form SameOrigin compares.
+func originKey(origin string) string {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return origin
+	}
+	if u.Scheme == "http" {
+		u.Scheme = "https"
+	}
+	if slices.Contains(caseInsensitiveHosts, u.Host) {
+		u.Path, u.RawPath = strings.ToLower(u.Path), ""
+	}
+	return u.String()
+}
+
+// NormalizeOriginURL rewrites a Git remote URL into the form the archive
+// records origins under, as NormalizeOrigin does. Remotes that are not
+// URLs, such as local paths, are returned unchanged.
+func NormalizeOriginURL(remote string) string {
+	normalized, err := NormalizeOrigin(remote)
+	if err != nil {
+		return remote
+	}
+	return normalized
+}
+
+// scpToURL turns the scp-like "[user@]host:path" syntax Git accepts for
+// SSH remotes into an ssh:// URL.
+func scpToURL(remote string) (string, bool) {
+	if strings.Contains(remote, "://") {
+		return "", false
+	}
+	// A colon before any slash, past a possible drive letter, marks the
+	// scp-like syntax.
+	colon := strings.Index(remote, ":")
+	if colon <= 1 || strings.ContainsAny(remote[:colon], `/\`) {
+		return "", false
+	}
+	return "ssh://" + remote[:colon] + "/" + strings.TrimPrefix(remote[colon+1:], "/"), true
+}
@@ -0,0 +1,172 @@
+package swhid
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ScheduledFile is a regular file waiting to be hashed by a parallel
+// directory hash.
+type ScheduledFile struct {
+	Path string // from the root, slash-separated
+	Size int64
+}
+
+// Scheduler orders the files of a parallel directory hash in place.
+// Workers take files in the order it leaves them, each taking the next one
+// as soon as it is done with its last.
+type Scheduler func(files []ScheduledFile)
+
+// LargestFirst is the default Scheduler. It starts the largest files first
+// and leaves the small ones to fill in around them, so that workers finish
+// close together instead of one hashing a giant file alone at the end.
+// Files of the same size keep their traversal order.
+func LargestFirst(files []ScheduledFile) {
+	sort.SliceStable(files, func(i, j int) bool { return files[i].Size > files[j].Size })
+}
+
+// TraversalOrder is a Scheduler that keeps files in the order the
+// directory walk found them.
+func TraversalOrder(files []ScheduledFile) {}
+
+// placeholderID stands in for file contents while a parallel directory
+// hash walks the tree to find the files to hash.
+var placeholderID, _ = NewIdentifier(ObjectTypeContent, strings.Repeat("0", ObjectIDLen), nil)
+
+// treeParallel hashes a directory like TreeFromDirectoryPathContext with
+// up to opts.Concurrency files hashed at once. It walks the tree once to
+// list the files to hash, hashes them in the order opts.Scheduler gives,
+// and walks it again to build the tree from the results.
+func treeParallel(ctx context.Context, root string, opts TreeOptions) (*Node, error) {
+	files, hashed, err := filesToHash(ctx, root, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := opts.Scheduler
+	if schedule == nil {
+		schedule = LargestFirst
+	}
+	schedule(files)
+
+	ids, types, err := hashFiles(ctx, root, files, opts)
+	if err != nil {
+		return nil, err
+	}
+	var mimeTypes map[string]string
+	if opts.DetectMIME {
+		mimeTypes = make(map[string]string, len(files))
+	}
+	for i, f := range files {
+		hashed[f.Path] = ids[i]
+		if mimeTypes != nil {
+			mimeTypes[f.Path] = types[i]
+		}
+	}
+
+	build := opts
+	build.Concurrency = 0
+	build.Cached = func(relPath string, info os.FileInfo) *Identifier {
+		return hashed[relPath]
+	}
+	return buildTree(ctx, root, build, mimeTypes)
+}
+
+// filesToHash walks the directory at root as opts describe, without reading
+// any file, and returns the regular files that would be hashed, in
+// traversal order, and the SWHIDs opts.Cached already knows by path. Files
+// whose SWHIDs the index of an enclosing Git repository gives are in
+// neither. Only the first of several hard links to a file is listed, unless
+// opts.NoHardLinkReuse is set.
+func filesToHash(ctx context.Context, root string, opts TreeOptions) ([]ScheduledFile, map[string]*Identifier, error) {
+	var (
+		files  []ScheduledFile
+		cached = make(map[string]*Identifier)
+		links  = make(map[fileID]bool)
+	)
+	walk := opts
+	walk.Concurrency = 0
+	walk.DetectMIME = false
+	// Inspect stays set and Warn is swapped for a no-op rather than
+	// cleared, as both decide which files must be read even when a Git
+	// index gives their SWHIDs; the walk then leaves out the same files as
+	// the tree walk will. Nothing is read, and only the tree walk warns.
+	if walk.Warn != nil {
+		walk.Warn = func(Warning) {}
+	}
+	walk.Cached = func(relPath string, info os.FileInfo) *Identifier {
+		if opts.Cached != nil {
+			if id := opts.Cached(relPath, info); id != nil {
+				cached[relPath] = id
+				return id
+			}
+		}
+		// Later links to a file are left for the tree walk to reuse.
+		if link, ok := hardLinkID(info); ok && !opts.NoHardLinkReuse {
+			if links[link] {
+				return placeholderID
+			}
+			links[link] = true
+		}
+		files = append(files, ScheduledFile{Path: relPath, Size: info.Size()})
+		return placeholderID
+	}
+	if _, err := TreeFromDirectoryPathContext(ctx, root, walk); err != nil {
+		return nil, nil, err
+	}
+	return files, cached, nil
+}
+
+// hashFiles hashes files below root with opts.Concurrency workers, taking
+// them in order, and returns their SWHIDs in the same order and, with
+// opts.DetectMIME, their media types. The first error stops the workers.
+func hashFiles(ctx context.Context, root string, files []ScheduledFile, opts TreeOptions) ([]*Identifier, []string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ids := make([]*Identifier, len(files))
+	types := make([]string, len(files))
+	indexes := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for w := 0; w < opts.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				id, mimeType, err := hashFile(filepath.Join(root, filepath.FromSlash(files[i].Path)), files[i].Path, opts)
+				if err != nil {
+					once.Do(func() { firstErr = err; cancel() })
+					continue
+				}
+				ids[i], types[i] = id, mimeType
+			}
+		}()
+	}
+
+feed:
+	for i := range files {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	return ids, types, nil
+}
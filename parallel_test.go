@@ -0,0 +1,124 @@
+package swhid
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestTreeParallel(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"big.bin":          bytes.Repeat([]byte{1}, 1<<16),
+		"a.txt":            []byte("a\n"),
+		"src/main.go":      []byte("package main\n"),
+		"src/lib/util.go":  []byte("package lib\n"),
+		"src/lib/big.data": bytes.Repeat([]byte{2}, 1<<15),
+		"empty":            nil,
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Link(filepath.Join(dir, "a.txt"), filepath.Join(dir, "src/a-link.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := TreeFromDirectoryPathWithOptions(dir, TreeOptions{})
+	if err != nil {
+		t.Fatalf("TreeFromDirectoryPathWithOptions() error = %v", err)
+	}
+
+	got, err := TreeFromDirectoryPathWithOptions(dir, TreeOptions{Concurrency: 3})
+	if err != nil {
+		t.Fatalf("TreeFromDirectoryPathWithOptions(Concurrency: 3) error = %v", err)
+	}
+	if !got.ID.Equal(want.ID) {
+		t.Errorf("parallel tree = %v, want %v", got.ID, want.ID)
+	}
+
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	for name, schedule := range map[string]Scheduler{"largest first": LargestFirst, "traversal": TraversalOrder} {
+		got, err := TreeFromDirectoryPathWithOptions(dir, TreeOptions{
+			Concurrency: 3,
+			Scheduler: func(files []ScheduledFile) {
+				schedule(files)
+				mu.Lock()
+				order = order[:0]
+				for _, f := range files {
+					order = append(order, f.Path)
+				}
+				mu.Unlock()
+			},
+		})
+		if err != nil {
+			t.Fatalf("%s: TreeFromDirectoryPathWithOptions(Concurrency: 3) error = %v", name, err)
+		}
+		if !got.ID.Equal(want.ID) {
+			t.Errorf("%s: parallel tree = %v, want %v", name, got.ID, want.ID)
+		}
+		if len(order) != len(files) {
+			t.Errorf("%s: scheduled %v, want each of the %d files once", name, order, len(files))
+		}
+		if name == "largest first" && (order[0] != "big.bin" || order[1] != "src/lib/big.data") {
+			t.Errorf("%s: scheduled %v, want the largest files first", name, order)
+		}
+	}
+}
+
+func TestTreeParallelCached(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cached := FromContent([]byte("cached\n"))
+	got, err := TreeFromDirectoryPathWithOptions(dir, TreeOptions{
+		Concurrency: 2,
+		Cached:      func(string, os.FileInfo) *Identifier { return cached },
+		Scheduler: func(files []ScheduledFile) {
+			if len(files) != 0 {
+				t.Errorf("scheduled %v, want cached files skipped", files)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("TreeFromDirectoryPathWithOptions() error = %v", err)
+	}
+	if !got.Children[0].ID.Equal(cached) {
+		t.Errorf("cached file = %v, want %v", got.Children[0].ID, cached)
+	}
+}
+
+func TestTreeParallelErrors(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A file removed between listing and hashing fails the hash
+	_, err := TreeFromDirectoryPathWithOptions(dir, TreeOptions{
+		Concurrency: 2,
+		Scheduler:   func([]ScheduledFile) { os.Remove(filepath.Join(dir, "a.txt")) },
+	})
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("TreeFromDirectoryPathWithOptions() error = %v, want ErrNotExist", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := TreeFromDirectoryPathContext(ctx, dir, TreeOptions{Concurrency: 2}); !errors.Is(err, context.Canceled) {
+		t.Errorf("TreeFromDirectoryPathContext(canceled) error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,131 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"fmt"
+
+	"github.com/andrew/swhid-go/objects"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// DefaultCheckpointInterval is how many references FromSnapshotStream
+// resolves between checkpoints when SnapshotOptions.CheckpointInterval is 0.
+const DefaultCheckpointInterval = 10000
+
+// SnapshotOptions controls FromSnapshotStream.
+type SnapshotOptions struct {
+	GitOptions
+
+	// Progress, if set, is called after each reference is resolved.
+	Progress func(SnapshotProgress)
+
+	// Checkpoint, if set, is called every CheckpointInterval references
+	// with the branches resolved so far. Saving the state lets a later
+	// run continue through Resume. Returning an error aborts the snapshot.
+	Checkpoint         func(*SnapshotState) error
+	CheckpointInterval int
+
+	// Resume is a state saved by an earlier, interrupted run. Its branches
+	// are reused for references that still point at the same target, so
+	// only new and moved references are resolved again.
+	Resume *SnapshotState
+}
+
+// SnapshotProgress reports how far FromSnapshotStream has got.
+type SnapshotProgress struct {
+	Done    int    // references resolved so far
+	Total   int    // references to resolve
+	Resumed int    // of Done, how many were taken from SnapshotOptions.Resume
+	Ref     string // reference just resolved
+}
+
+// SnapshotState is the intermediate state of a snapshot computation: the
+// branches resolved so far. It round-trips through encoding/json.
+type SnapshotState struct {
+	Branches []objects.Branch
+}
+
+// FromSnapshotStream computes the snapshot SWHID of repo like
+// FromSnapshotRepo, for repositories with too many references to resolve in
+// one go: it reports progress, checkpoints its state, and can resume from a
+// checkpoint.
+func FromSnapshotStream(repo *git.Repository, opts SnapshotOptions) (*Identifier, error) {
+	resolve := func(hash plumbing.Hash) (objects.BranchTargetType, string) {
+		return resolveRefTarget(repo, hash)
+	}
+
+	refMap, err := newRefMapper(opts.GitOptions)
+	if err != nil {
+		return nil, err
+	}
+	head, err := snapshotHead(repo, opts.GitOptions, resolve)
+	if err != nil {
+		return nil, err
+	}
+
+	iter, err := repo.References()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get references: %w", err)
+	}
+	var refs []*plumbing.Reference
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Name() != plumbing.HEAD && refMap.includes(ref.Name().String()) {
+			refs = append(refs, ref)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to iterate references: %w", err)
+	}
+
+	resumed := make(map[string]objects.Branch)
+	if opts.Resume != nil {
+		for _, branch := range opts.Resume.Branches {
+			resumed[branch.Name] = branch
+		}
+	}
+
+	interval := opts.CheckpointInterval
+	if interval <= 0 {
+		interval = DefaultCheckpointInterval
+	}
+
+	state := &SnapshotState{Branches: make([]objects.Branch, 0, len(refs))}
+	progress := SnapshotProgress{Total: len(refs)}
+	for _, ref := range refs {
+		branch, ok := resumed[ref.Name().String()]
+		if ok && branchMatchesRef(branch, ref) {
+			progress.Resumed++
+		} else {
+			branch = snapshotBranch(ref, resolve)
+		}
+		state.Branches = append(state.Branches, branch)
+
+		progress.Done++
+		progress.Ref = branch.Name
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+		if opts.Checkpoint != nil && progress.Done%interval == 0 {
+			if err := opts.Checkpoint(state); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	branches := state.Branches
+	if head != nil {
+		branches = append([]objects.Branch{*head}, branches...)
+	}
+	return FromSnapshotBranches(refMap.finish(branches)), nil
+}
+
+// branchMatchesRef reports whether a checkpointed branch still describes ref.
+func branchMatchesRef(branch objects.Branch, ref *plumbing.Reference) bool {
+	if ref.Type() == plumbing.SymbolicReference {
+		return branch.TargetType == objects.BranchTargetAlias && branch.Target == ref.Target().String()
+	}
+	return branch.TargetType != objects.BranchTargetAlias && branch.Target == ref.Hash().String()
+}
@@ -0,0 +1,165 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/andrew/swhid-go/objects"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestFromSnapshotStream(t *testing.T) {
+	repoPath, repo, hash := newTestRepo(t)
+	for i := range 5 {
+		name := plumbing.NewBranchReferenceName(fmt.Sprintf("feature-%d", i))
+		if err := repo.Storer.SetReference(plumbing.NewHashReference(name, hash)); err != nil {
+			t.Fatalf("Failed to set reference: %v", err)
+		}
+	}
+
+	want, err := FromSnapshot(repoPath)
+	if err != nil {
+		t.Fatalf("FromSnapshot() error = %v", err)
+	}
+
+	var updates []SnapshotProgress
+	var checkpoints []SnapshotState
+	got, err := FromSnapshotStream(repo, SnapshotOptions{
+		Progress: func(p SnapshotProgress) { updates = append(updates, p) },
+		Checkpoint: func(s *SnapshotState) error {
+			data, err := json.Marshal(s)
+			if err != nil {
+				return err
+			}
+			var saved SnapshotState
+			if err := json.Unmarshal(data, &saved); err != nil {
+				return err
+			}
+			checkpoints = append(checkpoints, saved)
+			return nil
+		},
+		CheckpointInterval: 2,
+	})
+	if err != nil {
+		t.Fatalf("FromSnapshotStream() error = %v", err)
+	}
+	if !got.Equal(want) {
+		t.Errorf("FromSnapshotStream() = %v, want %v", got, want)
+	}
+
+	if len(updates) != 6 {
+		t.Fatalf("Progress called %d times, want 6", len(updates))
+	}
+	if last := updates[len(updates)-1]; last.Done != 6 || last.Total != 6 {
+		t.Errorf("last progress = %+v, want 6/6", last)
+	}
+	if len(checkpoints) != 3 {
+		t.Fatalf("Checkpoint called %d times, want 3", len(checkpoints))
+	}
+
+	// Resume from the second checkpoint after one of its branches moved.
+	resume := checkpoints[1]
+	moved := resume.Branches[0]
+	resume.Branches[0].Target = "0000000000000000000000000000000000000000"
+
+	var final SnapshotProgress
+	got, err = FromSnapshotStream(repo, SnapshotOptions{
+		Resume:   &resume,
+		Progress: func(p SnapshotProgress) { final = p },
+	})
+	if err != nil {
+		t.Fatalf("FromSnapshotStream() resume error = %v", err)
+	}
+	if !got.Equal(want) {
+		t.Errorf("FromSnapshotStream() resumed = %v, want %v (moved %s)", got, want, moved.Name)
+	}
+	if final.Resumed != 3 {
+		t.Errorf("Resumed = %d, want 3", final.Resumed)
+	}
+}
+
+func TestFromSnapshotStreamCheckpointError(t *testing.T) {
+	_, repo, _ := newTestRepo(t)
+
+	errStop := errors.New("stop")
+	_, err := FromSnapshotStream(repo, SnapshotOptions{
+		Checkpoint:         func(*SnapshotState) error { return errStop },
+		CheckpointInterval: 1,
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("FromSnapshotStream() error = %v, want %v", err, errStop)
+	}
+}
+
+func TestSnapshotStateJSON(t *testing.T) {
+	state := SnapshotState{Branches: []objects.Branch{
+		{Name: "refs/heads/main", TargetType: objects.BranchTargetRevision, Target: "ce013625030ba8dba906f756967f9e9ca394464a"},
+	}}
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var decoded SnapshotState
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if len(decoded.Branches) != 1 || decoded.Branches[0] != state.Branches[0] {
+		t.Errorf("round trip = %+v, want %+v", decoded, state)
+	}
+}
+
+func TestFromSnapshotStreamNonUTF8Ref(t *testing.T) {
+	_, repo, hash := newTestRepo(t)
+	name := plumbing.ReferenceName("refs/heads/caf\xe9")
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(name, hash)); err != nil {
+		t.Fatalf("Failed to set reference: %v", err)
+	}
+
+	want, err := FromSnapshotRepo(repo)
+	if err != nil {
+		t.Fatalf("FromSnapshotRepo() error = %v", err)
+	}
+
+	var saved []byte
+	_, err = FromSnapshotStream(repo, SnapshotOptions{
+		Checkpoint: func(s *SnapshotState) error {
+			saved, err = json.Marshal(s)
+			return err
+		},
+		CheckpointInterval: 1,
+	})
+	if err != nil {
+		t.Fatalf("FromSnapshotStream() error = %v", err)
+	}
+
+	var resume SnapshotState
+	if err := json.Unmarshal(saved, &resume); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	found := false
+	for _, b := range resume.Branches {
+		found = found || b.Name == string(name)
+	}
+	if !found {
+		t.Fatalf("checkpoint lost branch %q: %s", name, saved)
+	}
+
+	var final SnapshotProgress
+	got, err := FromSnapshotStream(repo, SnapshotOptions{
+		Resume:   &resume,
+		Progress: func(p SnapshotProgress) { final = p },
+	})
+	if err != nil {
+		t.Fatalf("FromSnapshotStream() resume error = %v", err)
+	}
+	if !got.Equal(want) {
+		t.Errorf("FromSnapshotStream() resumed = %v, want %v", got, want)
+	}
+	if final.Resumed != final.Total {
+		t.Errorf("Resumed = %d of %d, want all", final.Resumed, final.Total)
+	}
+}
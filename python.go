@@ -0,0 +1,149 @@
+package swhid
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// pythonQualifiers are the qualifiers swh.model's QualifiedSWHID knows, in
+// the order it writes them. It predates the bytes qualifier.
+var pythonQualifiers = SpecV1_0.QualifierOrder()
+
+// ParsePythonCompatible parses a qualified SWHID as swh.model's
+// QualifiedSWHID.from_string does, for identifiers exchanged with Python
+// services. Where Parse is lenient, it rejects what swh.model rejects:
+// qualifiers other than origin, visit, anchor, path and lines, duplicate
+// ones and ones without "=", a visit that is not a snapshot SWHID, an
+// anchor that is not a directory, revision, release or snapshot SWHID, and
+// lines that are not a number or a range of numbers. Values are decoded as
+// urllib.parse.unquote does: "+" stays a plus sign, where Parse reads a
+// space, and malformed escapes are kept as written. Lines are normalized
+// as swh.model reads them into integers, so "09" becomes "9".
+func ParsePythonCompatible(s string) (*Identifier, error) {
+	core, qualifierStr, _ := strings.Cut(s, ";")
+	id, err := Parse(core)
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range strings.Split(qualifierStr, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("%w: malformed %q", ErrInvalidQualifier, part)
+		case !slices.Contains(pythonQualifiers, key):
+			return nil, fmt.Errorf("%w: swh.model does not accept %s", ErrInvalidQualifier, key)
+		}
+		if _, dup := id.Qualifiers[key]; dup {
+			return nil, fmt.Errorf("%w: duplicate %s", ErrInvalidQualifier, key)
+		}
+
+		switch key {
+		case QualifierVisit:
+			err = checkQualifierSWHID(key, value, ObjectTypeSnapshot)
+		case QualifierAnchor:
+			err = checkQualifierSWHID(key, value, ObjectTypeSnapshot, ObjectTypeRelease, ObjectTypeRevision, ObjectTypeDirectory)
+		case QualifierLines:
+			value, err = pythonLines(value)
+		default:
+			value = unquote(value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		id.Qualifiers[key] = value
+	}
+	return id, nil
+}
+
+// StringPythonCompatible returns the SWHID as swh.model writes a
+// QualifiedSWHID with the same qualifiers, for Python services comparing
+// identifiers as strings. The path is written with just "%" and ";"
+// percent-encoded, leaving whitespace and control characters as they are;
+// bytes that are not UTF-8, which swh.model cannot write, are
+// percent-encoded. Other values are written as StringStrict writes them for
+// SWHID 1.1, so that swh.model reads an origin with a space as the same URL.
+// Qualifiers swh.model does not know, such as bytes, are an error.
+func (id *Identifier) StringPythonCompatible() (string, error) {
+	for key := range id.Qualifiers {
+		if !slices.Contains(pythonQualifiers, key) {
+			return "", fmt.Errorf("%w: swh.model does not accept %s", ErrInvalidQualifier, key)
+		}
+	}
+	var b strings.Builder
+	b.WriteString(id.CoreSWHID())
+	for _, key := range pythonQualifiers {
+		value, ok := id.Qualifiers[key]
+		if !ok {
+			continue
+		}
+		if key == QualifierPath {
+			value = encodePythonPath(value)
+		} else {
+			value = encodeStrictQualifierValue(value)
+		}
+		b.WriteString(";" + key + "=" + value)
+	}
+	return b.String(), nil
+}
+
+// pythonLines checks a lines value as swh.model parses it, a number or two
+// separated by "-", and returns it as swh.model writes it back.
+func pythonLines(value string) (string, error) {
+	from, to, isRange := strings.Cut(value, "-")
+	parts := []string{from}
+	if isRange {
+		parts = append(parts, to)
+	}
+	for i, p := range parts {
+		if p == "" || strings.Trim(p, "0123456789") != "" {
+			return "", fmt.Errorf("%w: lines must be N or N-M, got %q", ErrInvalidQualifier, value)
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return "", fmt.Errorf("%w: lines: %v", ErrInvalidQualifier, err)
+		}
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, "-"), nil
+}
+
+// unquote decodes %XX escapes like Python's urllib.parse.unquote_to_bytes,
+// keeping malformed ones as they are.
+func unquote(value string) string {
+	if !strings.Contains(value, "%") {
+		return value
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '%' && i+2 < len(value) && isHex(value[i+1:i+3]) {
+			b.WriteByte(byte(unhex(value[i+1])<<4 | unhex(value[i+2])))
+			i += 2
+			continue
+		}
+		b.WriteByte(value[i])
+	}
+	return b.String()
+}
+
+// encodePythonPath escapes a path as swh.model does, "%" and ";" only,
+// and bytes that are not UTF-8.
+func encodePythonPath(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); {
+		r, size := utf8.DecodeRuneInString(value[i:])
+		switch {
+		case r == utf8.RuneError && size == 1, r == '%', r == ';':
+			fmt.Fprintf(&b, "%%%02X", value[i])
+		default:
+			b.WriteString(value[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
@@ -0,0 +1,125 @@
+package swhid
+
+import (
+	"errors"
+	"maps"
+	"testing"
+)
+
+func TestPythonCompatibleInterop(t *testing.T) {
+	const core = "swh:1:cnt:4d99d2d18326621ccdd70f5ea66c2e2ac236ad8b"
+	const snp = "swh:1:snp:d7f1b9eb7ccb596c2622c4780febaa02549830f9"
+	const rev = "swh:1:rev:309cf2674ee7a0749978cf8265ab91a60aea0f7d"
+
+	// SWHIDs as swh.model writes them, and the qualifiers they hold
+	tests := []struct {
+		name   string
+		python string
+		want   map[string]string
+	}{
+		{
+			"all qualifiers",
+			core + ";origin=https://github.com/example/repo;visit=" + snp + ";anchor=" + rev + ";path=/src/main.c;lines=9-15",
+			map[string]string{"origin": "https://github.com/example/repo", "visit": snp, "anchor": rev, "path": "/src/main.c", "lines": "9-15"},
+		},
+		{"space in path", core + ";path=/docs/read me.txt", map[string]string{"path": "/docs/read me.txt"}},
+		{"tab in path", core + ";path=/a\tb", map[string]string{"path": "/a\tb"}},
+		{"percent and semicolon in path", core + ";path=/100%25%3Bdone", map[string]string{"path": "/100%;done"}},
+		{"plus in path", core + ";path=/c++/main.cc", map[string]string{"path": "/c++/main.cc"}},
+		{"non-ASCII path", core + ";path=/héllo/世界.txt", map[string]string{"path": "/héllo/世界.txt"}},
+		{"escaped origin", core + ";origin=https://example.org/a%20b", map[string]string{"origin": "https://example.org/a b"}},
+		{"plus in origin", core + ";origin=https://example.org/c++", map[string]string{"origin": "https://example.org/c++"}},
+		{"equals in origin", core + ";origin=https://example.org/?a=b", map[string]string{"origin": "https://example.org/?a=b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := ParsePythonCompatible(tt.python)
+			if err != nil {
+				t.Fatalf("ParsePythonCompatible() error = %v", err)
+			}
+			if !maps.Equal(id.Qualifiers, tt.want) {
+				t.Errorf("Qualifiers = %q, want %q", id.Qualifiers, tt.want)
+			}
+			got, err := id.StringPythonCompatible()
+			if err != nil {
+				t.Fatalf("StringPythonCompatible() error = %v", err)
+			}
+			if got != tt.python {
+				t.Errorf("StringPythonCompatible() = %q, want %q", got, tt.python)
+			}
+			// The strict form means the same to both
+			again, err := ParsePythonCompatible(id.String())
+			if err != nil || !again.Equal(id) {
+				t.Errorf("ParsePythonCompatible(%q) = %v, %v, want %v", id.String(), again, err, id)
+			}
+		})
+	}
+}
+
+func TestPythonCompatibleDivergences(t *testing.T) {
+	const core = "swh:1:cnt:4d99d2d18326621ccdd70f5ea66c2e2ac236ad8b"
+
+	plus := core + ";path=/c++"
+	strict, _ := Parse(plus)
+	python, _ := ParsePythonCompatible(plus)
+	if strict.Qualifiers["path"] != "/c  " || python.Qualifiers["path"] != "/c++" {
+		t.Errorf("path of %q: Parse %q, ParsePythonCompatible %q", plus, strict.Qualifiers["path"], python.Qualifiers["path"])
+	}
+
+	malformed := core + ";path=/50%zz%41"
+	strict, _ = Parse(malformed)
+	python, _ = ParsePythonCompatible(malformed)
+	if strict.Qualifiers["path"] != "/50%zz%41" || python.Qualifiers["path"] != "/50%zzA" {
+		t.Errorf("path of %q: Parse %q, ParsePythonCompatible %q", malformed, strict.Qualifiers["path"], python.Qualifiers["path"])
+	}
+
+	id, _ := NewIdentifier(ObjectTypeContent, "4d99d2d18326621ccdd70f5ea66c2e2ac236ad8b", map[string]string{"path": "/a b\xff"})
+	if got, want := id.String(), core+";path=/a b\xff"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if got, _ := id.StringPythonCompatible(); got != core+";path=/a b%FF" {
+		t.Errorf("StringPythonCompatible() = %q, want %q", got, core+";path=/a b%FF")
+	}
+}
+
+func TestParsePythonCompatibleLines(t *testing.T) {
+	const core = "swh:1:cnt:4d99d2d18326621ccdd70f5ea66c2e2ac236ad8b"
+	for input, want := range map[string]string{"9": "9", "09-015": "9-15", "0": "0"} {
+		id, err := ParsePythonCompatible(core + ";lines=" + input)
+		if err != nil {
+			t.Errorf("lines=%s: error = %v", input, err)
+			continue
+		}
+		if got := id.Qualifiers["lines"]; got != want {
+			t.Errorf("lines=%s: got %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestParsePythonCompatibleRejects(t *testing.T) {
+	const core = "swh:1:cnt:4d99d2d18326621ccdd70f5ea66c2e2ac236ad8b"
+	for _, input := range []string{
+		core + ";bytes=10-20",
+		core + ";foo=bar",
+		core + ";path=/a;path=/b",
+		core + ";path",
+		core + ";visit=" + core,
+		core + ";anchor=" + core,
+		core + ";lines=a",
+		core + ";lines=1-2-3",
+		core + ";lines=-3",
+	} {
+		if _, err := ParsePythonCompatible(input); !errors.Is(err, ErrInvalidQualifier) {
+			t.Errorf("ParsePythonCompatible(%q) error = %v, want ErrInvalidQualifier", input, err)
+		}
+	}
+	if _, err := ParsePythonCompatible("swh:1:cnt:XYZ;path=/a"); err == nil {
+		t.Error("ParsePythonCompatible() accepted an invalid core SWHID")
+	}
+
+	id, _ := NewIdentifier(ObjectTypeContent, "4d99d2d18326621ccdd70f5ea66c2e2ac236ad8b", map[string]string{"bytes": "1-2"})
+	if _, err := id.StringPythonCompatible(); !errors.Is(err, ErrInvalidQualifier) {
+		t.Errorf("StringPythonCompatible() with bytes error = %v, want ErrInvalidQualifier", err)
+	}
+}
@@ -0,0 +1,130 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// ErrReadOnlyFS is matched by the *WriteError returned when a computation
+// run with the ReadOnlyFS option attempts to write to the repository or
+// tree it reads.
+var ErrReadOnlyFS = errors.New("write attempted on read-only filesystem")
+
+// WriteError records a write refused under the ReadOnlyFS option.
+type WriteError struct {
+	Op   string // "create", "open", "rename", "remove", "mkdir", "symlink" or "tempfile"
+	Path string // relative to the filesystem the write was attempted on
+}
+
+func (e *WriteError) Error() string {
+	return fmt.Sprintf("%v: %s %s", ErrReadOnlyFS, e.Op, e.Path)
+}
+
+// Is makes errors.Is(err, ErrReadOnlyFS) match any *WriteError.
+func (e *WriteError) Is(target error) bool {
+	return target == ErrReadOnlyFS
+}
+
+// writeFlags are the os.OpenFile flags that modify the filesystem.
+const writeFlags = os.O_WRONLY | os.O_RDWR | os.O_CREATE | os.O_TRUNC | os.O_APPEND
+
+// readOnlyFS passes reads through to a billy filesystem and refuses every
+// write with a *WriteError before it reaches the disk.
+type readOnlyFS struct {
+	billy.Filesystem
+}
+
+func (fs readOnlyFS) Create(filename string) (billy.File, error) {
+	return nil, &WriteError{Op: "create", Path: filename}
+}
+
+func (fs readOnlyFS) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	if flag&writeFlags != 0 {
+		return nil, &WriteError{Op: "open", Path: filename}
+	}
+	return fs.Filesystem.OpenFile(filename, flag, perm)
+}
+
+func (fs readOnlyFS) Rename(oldpath, newpath string) error {
+	return &WriteError{Op: "rename", Path: oldpath}
+}
+
+func (fs readOnlyFS) Remove(filename string) error {
+	return &WriteError{Op: "remove", Path: filename}
+}
+
+func (fs readOnlyFS) MkdirAll(filename string, perm os.FileMode) error {
+	return &WriteError{Op: "mkdir", Path: filename}
+}
+
+func (fs readOnlyFS) Symlink(target, link string) error {
+	return &WriteError{Op: "symlink", Path: link}
+}
+
+func (fs readOnlyFS) TempFile(dir, prefix string) (billy.File, error) {
+	return nil, &WriteError{Op: "tempfile", Path: dir}
+}
+
+func (fs readOnlyFS) Chroot(path string) (billy.Filesystem, error) {
+	sub, err := fs.Filesystem.Chroot(path)
+	if err != nil {
+		return nil, err
+	}
+	return readOnlyFS{sub}, nil
+}
+
+func (fs readOnlyFS) Capabilities() billy.Capability {
+	return billy.Capabilities(fs.Filesystem) &^ (billy.WriteCapability | billy.ReadAndWriteCapability | billy.TruncateCapability | billy.LockCapability)
+}
+
+// openRepository opens a repository like git.PlainOpenWithOptions, also
+// reading objects from the repositories its alternates file names, and for
+// linked worktrees the references and objects of the repository they
+// belong to, alongside their own HEAD. With
+// readOnly set, its object store and worktree are reopened through
+// filesystems that refuse writes, so go-git cannot create lock files,
+// rewrite the index or pack objects behind the caller's back.
+func openRepository(repoPath string, detect, readOnly bool) (*git.Repository, error) {
+	repo, err := git.PlainOpenWithOptions(repoPath, &git.PlainOpenOptions{DetectDotGit: detect, EnableDotGitCommonDir: true})
+	if err != nil {
+		return nil, err
+	}
+
+	storage, ok := repo.Storer.(*filesystem.Storage)
+	if !ok {
+		return repo, nil
+	}
+	alternates, err := newAlternatesFS(storage.Filesystem())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alternates: %w", err)
+	}
+	if alternates == nil && !readOnly {
+		return repo, nil
+	}
+
+	dotGit := storage.Filesystem()
+	var worktree billy.Filesystem
+	if wt, err := repo.Worktree(); err == nil {
+		worktree = wt.Filesystem
+	} else if !errors.Is(err, git.ErrIsBareRepository) {
+		return nil, err
+	}
+	if readOnly {
+		dotGit = readOnlyFS{dotGit}
+		if worktree != nil {
+			worktree = readOnlyFS{worktree}
+		}
+		if alternates != nil {
+			alternates = readOnlyFS{alternates}
+		}
+	}
+	return git.Open(filesystem.NewStorageWithOptions(dotGit, cache.NewObjectLRUDefault(), filesystem.Options{AlternatesFS: alternates}), worktree)
+}
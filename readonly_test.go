@@ -0,0 +1,76 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestReadOnlyFS(t *testing.T) {
+	dir, _, head := newTestRepo(t)
+	before := listFiles(t, dir)
+
+	opts := GitOptions{ReadOnlyFS: true}
+	snp, err := FromSnapshotWithOptions(dir, opts)
+	if err != nil {
+		t.Fatalf("FromSnapshotWithOptions() error = %v", err)
+	}
+	if want, _ := FromSnapshot(dir); !snp.Equal(want) {
+		t.Errorf("read-only snapshot = %v, want %v", snp, want)
+	}
+	rev, err := FromRevisionWithOptions(dir, "HEAD", opts)
+	if err != nil {
+		t.Fatalf("FromRevisionWithOptions() error = %v", err)
+	}
+	if rev.ObjectHash != head.String() {
+		t.Errorf("read-only revision = %v, want %s", rev, head)
+	}
+	if _, err := TreeFromDirectoryPathWithOptions(dir, TreeOptions{ReadOnlyFS: true}); err != nil {
+		t.Fatalf("TreeFromDirectoryPathWithOptions() error = %v", err)
+	}
+
+	session, err := OpenRepoWithOptions(dir, opts)
+	if err != nil {
+		t.Fatalf("OpenRepoWithOptions() error = %v", err)
+	}
+	repo := session.Repository()
+	err = repo.Storer.SetReference(plumbing.NewHashReference("refs/heads/new", head))
+	var writeErr *WriteError
+	if !errors.Is(err, ErrReadOnlyFS) || !errors.As(err, &writeErr) {
+		t.Errorf("SetReference() error = %v, want a *WriteError", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Worktree() error = %v", err)
+	}
+	if _, err := wt.Filesystem.Create("new.txt"); !errors.Is(err, ErrReadOnlyFS) {
+		t.Errorf("Create() error = %v, want ErrReadOnlyFS", err)
+	}
+
+	after := listFiles(t, dir)
+	if len(after) != len(before) {
+		t.Errorf("read-only operations changed the repository: %d files before, %d after", len(before), len(after))
+	}
+}
+
+// listFiles returns every path below dir, including inside .git.
+func listFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var paths []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+	return paths
+}
@@ -0,0 +1,97 @@
+package swhid
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// RedactionPolicy decides which qualifiers RedactedWith keeps. The core
+// identifier and the visit, anchor, lines and bytes qualifiers, which hold
+// only hashes and numbers, are always kept.
+type RedactionPolicy struct {
+	// PublicHosts lists the hosts, subdomains included, whose origins may
+	// be logged. Origins on other hosts, and those that are not absolute
+	// URLs such as scp-like git@host:path remotes, are removed; the ones
+	// kept lose any user name, password, query and fragment, where access
+	// tokens end up.
+	PublicHosts []string
+
+	// KeepPath keeps the path qualifier, which may name internal files.
+	KeepPath bool
+
+	// KeepUnknown keeps qualifiers the specification does not define.
+	KeepUnknown bool
+}
+
+// DefaultRedactionPolicy is the policy of Redacted. It keeps origins on
+// the public forges the archive crawls, on the assumption that repository
+// names there are public, and removes paths and unknown qualifiers.
+var DefaultRedactionPolicy = RedactionPolicy{
+	PublicHosts: []string{
+		"github.com",
+		"gitlab.com",
+		"bitbucket.org",
+		"codeberg.org",
+		"sourceforge.net",
+		"salsa.debian.org",
+		"git.savannah.gnu.org",
+	},
+}
+
+// Redacted returns id with the qualifiers that may leak internal
+// information removed, following DefaultRedactionPolicy, for services
+// that log identifiers.
+func (id *Identifier) Redacted() *Identifier {
+	return id.RedactedWith(DefaultRedactionPolicy)
+}
+
+// RedactedWith returns id with the qualifiers policy does not allow
+// removed and credentials stripped from its origin.
+func (id *Identifier) RedactedWith(policy RedactionPolicy) *Identifier {
+	var qualifiers map[string]string
+	for key, value := range id.Qualifiers {
+		switch key {
+		case QualifierVisit, QualifierAnchor, QualifierLines, QualifierBytes:
+		case QualifierOrigin:
+			var ok bool
+			if value, ok = policy.redactOrigin(value); !ok {
+				continue
+			}
+		case QualifierPath:
+			if !policy.KeepPath {
+				continue
+			}
+		default:
+			if !policy.KeepUnknown {
+				continue
+			}
+		}
+		if qualifiers == nil {
+			qualifiers = make(map[string]string)
+		}
+		qualifiers[key] = value
+	}
+	return id.WithQualifiers(qualifiers)
+}
+
+// redactOrigin returns origin without credentials, query or fragment, and
+// false if it may not be logged at all.
+func (p RedactionPolicy) redactOrigin(origin string) (string, bool) {
+	u, err := url.Parse(origin)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return "", false
+	}
+	host := strings.ToLower(u.Hostname())
+	public := slices.ContainsFunc(p.PublicHosts, func(h string) bool {
+		h = strings.ToLower(h)
+		return host == h || strings.HasSuffix(host, "."+h)
+	})
+	if !public {
+		return "", false
+	}
+	u.User = nil
+	u.RawQuery, u.ForceQuery = "", false
+	u.Fragment, u.RawFragment = "", ""
+	return u.String(), true
+}
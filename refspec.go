@@ -0,0 +1,89 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/andrew/swhid-go/objects"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// refMapper selects the references of a snapshot and names them as the
+// origin does, following GitOptions.Refs, Refspecs and Branches.
+type refMapper struct {
+	policy RefPolicy
+	specs  []config.RefSpec // reversed: local references to origin names
+	extra  []objects.Branch
+}
+
+func newRefMapper(opts GitOptions) (*refMapper, error) {
+	m := &refMapper{policy: opts.Refs, extra: opts.Branches}
+	for _, s := range opts.Refspecs {
+		spec := strings.TrimPrefix(s, "+")
+		if !strings.Contains(spec, ":") {
+			spec += ":" + spec
+		}
+		if err := config.RefSpec(spec).Validate(); err != nil {
+			return nil, fmt.Errorf("invalid refspec %q: %w", s, err)
+		}
+		m.specs = append(m.specs, config.RefSpec(spec).Reverse())
+	}
+	return m, nil
+}
+
+// originName returns the name a local reference has in the origin, and
+// false for references no refspec fetched. The refs/remotes/<remote>/HEAD
+// a clone leaves is not one: it records the origin's HEAD, which is no
+// branch, and GitOptions.Head sets the snapshot's HEAD instead.
+func (m *refMapper) originName(name string) (string, bool) {
+	if len(m.specs) == 0 {
+		return name, true
+	}
+	for _, spec := range m.specs {
+		if spec.Match(plumbing.ReferenceName(name)) {
+			origin := spec.Dst(plumbing.ReferenceName(name)).String()
+			return origin, origin != "refs/heads/HEAD"
+		}
+	}
+	return name, false
+}
+
+// includes reports whether the local reference named name belongs in the
+// snapshot.
+func (m *refMapper) includes(name string) bool {
+	origin, ok := m.originName(name)
+	return ok && m.policy.includes(origin)
+}
+
+// finish renames the branches read from the repository after the origin's
+// references, alias targets included, and adds the branches known from
+// elsewhere that the repository does not have.
+func (m *refMapper) finish(branches []objects.Branch) []objects.Branch {
+	if len(m.specs) == 0 && len(m.extra) == 0 {
+		return branches
+	}
+
+	seen := make(map[string]bool, len(branches))
+	for i := range branches {
+		b := &branches[i]
+		if b.Name != "HEAD" {
+			b.Name, _ = m.originName(b.Name)
+		}
+		if b.TargetType == objects.BranchTargetAlias {
+			if target, ok := m.originName(b.Target); ok {
+				b.Target = target
+			}
+		}
+		seen[b.Name] = true
+	}
+	for _, b := range m.extra {
+		if !seen[b.Name] && m.policy.includes(b.Name) {
+			branches = append(branches, b)
+			seen[b.Name] = true
+		}
+	}
+	return branches
+}
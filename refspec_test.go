@@ -0,0 +1,101 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/andrew/swhid-go/objects"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestSnapshotRefspecs(t *testing.T) {
+	fullPath, full, hash := newTestRepo(t)
+	for _, name := range []string{"refs/heads/dev", "refs/tags/v1"} {
+		if err := full.Storer.SetReference(plumbing.NewHashReference(plumbing.ReferenceName(name), hash)); err != nil {
+			t.Fatalf("Failed to set reference: %v", err)
+		}
+	}
+	want, err := FromSnapshot(fullPath)
+	if err != nil {
+		t.Fatalf("FromSnapshot() error = %v", err)
+	}
+
+	// A CI checkout: master fetched as a remote-tracking branch, HEAD
+	// detached, the other references known only from the archive, which
+	// still has master at an older commit.
+	partialPath, partial, _ := newTestRepo(t)
+	for _, ref := range []*plumbing.Reference{
+		plumbing.NewHashReference("refs/remotes/origin/master", hash),
+		plumbing.NewHashReference("refs/pull/7/head", hash),
+		plumbing.NewHashReference(plumbing.HEAD, hash),
+	} {
+		if err := partial.Storer.SetReference(ref); err != nil {
+			t.Fatalf("Failed to set reference: %v", err)
+		}
+	}
+	if err := partial.Storer.RemoveReference("refs/heads/master"); err != nil {
+		t.Fatalf("Failed to remove reference: %v", err)
+	}
+
+	opts := GitOptions{
+		Refspecs: []string{"+refs/heads/*:refs/remotes/origin/*"},
+		Branches: []objects.Branch{
+			{Name: "refs/heads/dev", TargetType: objects.BranchTargetRevision, Target: hash.String()},
+			{Name: "refs/heads/master", TargetType: objects.BranchTargetRevision, Target: strings.Repeat("0", 40)},
+			{Name: "refs/tags/v1", TargetType: objects.BranchTargetRevision, Target: hash.String()},
+		},
+		Head: "refs/heads/master",
+	}
+	got, err := FromSnapshotWithOptions(partialPath, opts)
+	if err != nil {
+		t.Fatalf("FromSnapshotWithOptions() error = %v", err)
+	}
+	if !got.Equal(want) {
+		branches, _ := SnapshotBranchesWithOptions(partialPath, opts)
+		t.Errorf("FromSnapshotWithOptions() = %s, want %s; branches %+v", got, want, branches)
+	}
+
+	streamed, err := FromSnapshotStream(partial, SnapshotOptions{GitOptions: opts})
+	if err != nil {
+		t.Fatalf("FromSnapshotStream() error = %v", err)
+	}
+	if !streamed.Equal(want) {
+		t.Errorf("FromSnapshotStream() = %s, want %s", streamed, want)
+	}
+
+	opts.Refspecs = []string{"refs/heads/*:refs/remotes/origin/*:x"}
+	if _, err := FromSnapshotWithOptions(partialPath, opts); err == nil {
+		t.Error("FromSnapshotWithOptions() with an invalid refspec succeeded")
+	}
+}
+
+func TestSnapshotRefspecsAliases(t *testing.T) {
+	repoPath, repo, hash := newTestRepo(t)
+	for _, ref := range []*plumbing.Reference{
+		plumbing.NewHashReference("refs/remotes/origin/main", hash),
+		plumbing.NewSymbolicReference("refs/remotes/origin/HEAD", "refs/remotes/origin/main"),
+	} {
+		if err := repo.Storer.SetReference(ref); err != nil {
+			t.Fatalf("Failed to set reference: %v", err)
+		}
+	}
+
+	branches, err := SnapshotBranchesWithOptions(repoPath, GitOptions{
+		Refspecs: []string{"+refs/heads/*:refs/remotes/origin/*", "refs/heads/master"},
+	})
+	if err != nil {
+		t.Fatalf("SnapshotBranchesWithOptions() error = %v", err)
+	}
+	got := make(map[string]objects.Branch)
+	for _, b := range branches {
+		got[b.Name] = b
+	}
+	if len(got) != 3 || got["refs/heads/main"].Target != hash.String() || got["refs/heads/master"].Target != hash.String() {
+		t.Errorf("branches = %+v", branches)
+	}
+	if b := got["HEAD"]; b.TargetType != objects.BranchTargetAlias || b.Target != "refs/heads/master" {
+		t.Errorf("HEAD = %+v, want an alias to refs/heads/master", b)
+	}
+}
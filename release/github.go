@@ -0,0 +1,213 @@
+package release
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/objects"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Kinds of ZipDifference.
+const (
+	ZipModeDiffers    = "mode"    // same contents, another entry type
+	ZipContentDiffers = "content" // other contents
+	ZipMissing        = "missing" // in the commit, not in the zip, such as export-ignore files
+	ZipExtra          = "extra"   // in the zip, not in the commit
+)
+
+// ZipDifference is a path where a GitHub zip and the commit it was made
+// from disagree.
+type ZipDifference struct {
+	Path string
+	Kind string
+}
+
+// GitHubZipOptions controls HashGitHubZip.
+type GitHubZipOptions struct {
+	// Repo is a clone of the repository the zip was downloaded from. When
+	// set, the zip is checked against the commit's tree and entries whose
+	// modes the zip lost are restored from it.
+	Repo string
+
+	// Rev names the commit to check against; by default the one GitHub
+	// records in the zip comment.
+	Rev string
+}
+
+// GitHubZip is what HashGitHubZip found.
+type GitHubZip struct {
+	Prefix string            // the top-level repo-ref directory, without a slash
+	Commit string            // the commit recorded in the zip comment, if any
+	ID     *swhid.Identifier // directory SWHID of the tree below Prefix
+
+	// With GitHubZipOptions.Repo: the SWHID of the commit's tree, the
+	// number of entries whose executable bit or symlink type was restored
+	// from it, and the paths still differing, sorted.
+	Tree        *swhid.Identifier
+	Restored    int
+	Differences []ZipDifference
+}
+
+// Match reports whether the zip hashes to its commit's tree.
+func (z *GitHubZip) Match() bool {
+	return z.Tree != nil && z.ID.Equal(z.Tree)
+}
+
+// commitComment matches the commit id git archive writes as the comment of
+// the zips GitHub serves.
+var commitComment = regexp.MustCompile(`^[0-9a-f]{40}([0-9a-f]{24})?$`)
+
+// HashGitHubZip computes the directory SWHID of the logical tree in a zip
+// from GitHub's "Download ZIP" or codeload.github.com: the tree below its
+// repo-ref/ directory. Such zips, and copies repacked on other systems,
+// may lack the Unix modes of some members, which then read as plain
+// files; given a clone, those take their executable bit or symlink type
+// from the commit, submodules (empty directories in the zip) become
+// submodule entries again, and whatever still differs is listed.
+func HashGitHubZip(r io.ReaderAt, size int64, opts GitHubZipOptions) (*GitHubZip, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	a := newArchiveHasher(PresetGitHubArchive)
+	lost := make(map[string]bool)
+	for _, f := range zr.File {
+		if err := hashZipMember(a, f); err != nil {
+			return nil, err
+		}
+		// Made on a system other than Unix: no modes
+		if f.CreatorVersion>>8 != 3 && !f.Mode().IsDir() {
+			if rel, err := a.clean(f.Name); err == nil {
+				lost[rel] = true
+			}
+		}
+	}
+	top, err := a.topLevel()
+	if err != nil {
+		return nil, err
+	}
+	z := &GitHubZip{Prefix: top}
+	if c := strings.TrimSpace(zr.Comment); commitComment.MatchString(c) {
+		z.Commit = c
+	}
+
+	if opts.Repo != "" {
+		if err := z.reconcile(a, lost, opts); err != nil {
+			return nil, err
+		}
+	}
+	if z.ID, err = a.identifier(); err != nil {
+		return nil, err
+	}
+	return z, nil
+}
+
+// reconcile compares the members of a with the tree of the commit the zip
+// was made from, restoring the entry types the zip lost.
+func (z *GitHubZip) reconcile(a *archiveHasher, lost map[string]bool, opts GitHubZipOptions) error {
+	rev := opts.Rev
+	if rev == "" {
+		rev = z.Commit
+	}
+	if rev == "" {
+		return fmt.Errorf("release: the zip records no commit; name the revision to check against")
+	}
+	repo, err := git.PlainOpenWithOptions(opts.Repo, &git.PlainOpenOptions{DetectDotGit: true, EnableDotGitCommonDir: true})
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	if z.Tree, err = swhid.FromGitTreeRepo(repo, rev); err != nil {
+		return err
+	}
+	committed, err := treeEntries(repo, plumbing.NewHash(z.Tree.ObjectHash))
+	if err != nil {
+		return err
+	}
+
+	prefix := z.Prefix + "/"
+	inZip := make(map[string]bool)
+	for _, p := range a.order {
+		rel, ok := strings.CutPrefix(p, prefix)
+		if !ok {
+			continue
+		}
+		inZip[rel] = true
+		want, ok := committed[rel]
+		got := a.members[p]
+		switch {
+		case !ok:
+			if got != nil {
+				z.Differences = append(z.Differences, ZipDifference{rel, ZipExtra})
+			}
+		case want.Type == objects.EntryTypeRevision:
+			if got == nil {
+				entry := want
+				a.members[p] = &entry
+			} else {
+				z.Differences = append(z.Differences, ZipDifference{rel, ZipModeDiffers})
+			}
+		case got == nil:
+			// A directory where the commit has a file; its contents are
+			// reported as extra.
+			z.Differences = append(z.Differences, ZipDifference{rel, ZipModeDiffers})
+		case got.Target != want.Target:
+			z.Differences = append(z.Differences, ZipDifference{rel, ZipContentDiffers})
+		case got.Type != want.Type && lost[p]:
+			got.Type = want.Type
+			z.Restored++
+		case got.Type != want.Type:
+			z.Differences = append(z.Differences, ZipDifference{rel, ZipModeDiffers})
+		}
+	}
+	for rel := range committed {
+		if !inZip[rel] {
+			z.Differences = append(z.Differences, ZipDifference{rel, ZipMissing})
+		}
+	}
+	sort.Slice(z.Differences, func(i, j int) bool { return z.Differences[i].Path < z.Differences[j].Path })
+	return nil
+}
+
+// treeEntries returns the files, symlinks and submodules below the tree
+// hash, by path.
+func treeEntries(repo *git.Repository, hash plumbing.Hash) (map[string]objects.DirectoryEntry, error) {
+	tree, err := repo.TreeObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string]objects.DirectoryEntry)
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+	for {
+		name, entry, err := walker.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var t objects.EntryType
+		switch entry.Mode {
+		case filemode.Dir:
+			continue
+		case filemode.Executable:
+			t = objects.EntryTypeExecutable
+		case filemode.Symlink:
+			t = objects.EntryTypeSymlink
+		case filemode.Submodule:
+			t = objects.EntryTypeRevision
+		default:
+			t = objects.EntryTypeFile
+		}
+		entries[name] = objects.DirectoryEntry{Type: t, Target: entry.Hash.String()}
+	}
+}
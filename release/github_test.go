@@ -0,0 +1,162 @@
+package release
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andrew/swhid-go"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// newGitHubRepo commits a README, an executable and a symlink and returns
+// the clone and the commit.
+func newGitHubRepo(t *testing.T) (string, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("Failed to init repository: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "run.sh"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../README", filepath.Join(dir, "src", "link")); err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.AddGlob("."); err != nil {
+		t.Fatalf("Failed to stage files: %v", err)
+	}
+	sig := &object.Signature{Name: "Test", Email: "test@example.com", When: time.Unix(1000000000, 0).UTC()}
+	commit, err := wt.Commit("Initial commit\n", &git.CommitOptions{Author: sig, Committer: sig})
+	if err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	return dir, commit.String()
+}
+
+// zipMember is a member of a zip built for a test; unix records its mode,
+// otherwise the zip says it was made on MS-DOS and has none.
+type zipMember struct {
+	name string
+	mode os.FileMode
+	body string
+	unix bool
+}
+
+func buildZip(t *testing.T, comment string, members []zipMember) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, m := range members {
+		hdr := &zip.FileHeader{Name: m.name}
+		if m.unix {
+			hdr.SetMode(m.mode)
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(m.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.SetComment(comment); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestHashGitHubZip(t *testing.T) {
+	repo, commit := newGitHubRepo(t)
+	tree, err := swhid.FromGitTree(repo, commit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unix := buildZip(t, commit, []zipMember{
+		{"repo-main/", os.ModeDir | 0755, "", true},
+		{"repo-main/README", 0644, "hello\n", true},
+		{"repo-main/src/", os.ModeDir | 0755, "", true},
+		{"repo-main/src/link", os.ModeSymlink | 0777, "../README", true},
+		{"repo-main/src/run.sh", 0755, "#!/bin/sh\n", true},
+	})
+	z, err := HashGitHubZip(bytes.NewReader(unix), int64(len(unix)), GitHubZipOptions{})
+	if err != nil {
+		t.Fatalf("HashGitHubZip() error = %v", err)
+	}
+	if !z.ID.Equal(tree) || z.Prefix != "repo-main" || z.Commit != commit || z.Tree != nil {
+		t.Errorf("HashGitHubZip() = %+v, want %v from %s", z, tree, commit)
+	}
+
+	// Repacked without modes: only the clone can tell
+	dos := buildZip(t, commit, []zipMember{
+		{"repo-main/", 0, "", false},
+		{"repo-main/README", 0, "hello\n", false},
+		{"repo-main/src/link", 0, "../README", false},
+		{"repo-main/src/run.sh", 0, "#!/bin/sh\n", false},
+	})
+	z, err = HashGitHubZip(bytes.NewReader(dos), int64(len(dos)), GitHubZipOptions{})
+	if err != nil {
+		t.Fatalf("HashGitHubZip() error = %v", err)
+	}
+	if z.ID.Equal(tree) {
+		t.Errorf("HashGitHubZip() without modes = %v, the commit's tree", z.ID)
+	}
+	z, err = HashGitHubZip(bytes.NewReader(dos), int64(len(dos)), GitHubZipOptions{Repo: repo})
+	if err != nil {
+		t.Fatalf("HashGitHubZip() error = %v", err)
+	}
+	if !z.Match() || z.Restored != 2 || len(z.Differences) != 0 {
+		t.Errorf("HashGitHubZip() with the clone = %+v, want %v with 2 restored", z, tree)
+	}
+}
+
+func TestHashGitHubZipDifferences(t *testing.T) {
+	repo, commit := newGitHubRepo(t)
+
+	data := buildZip(t, "", []zipMember{
+		{"repo-v1/README", 0644, "changed\n", true},
+		{"repo-v1/src/run.sh", 0644, "#!/bin/sh\n", true},
+		{"repo-v1/extra", 0644, "extra\n", true},
+	})
+	if _, err := HashGitHubZip(bytes.NewReader(data), int64(len(data)), GitHubZipOptions{Repo: repo}); err == nil {
+		t.Error("HashGitHubZip() without a recorded commit or Rev succeeded")
+	}
+	z, err := HashGitHubZip(bytes.NewReader(data), int64(len(data)), GitHubZipOptions{Repo: repo, Rev: commit})
+	if err != nil {
+		t.Fatalf("HashGitHubZip() error = %v", err)
+	}
+	want := []ZipDifference{
+		{"README", ZipContentDiffers},
+		{"extra", ZipExtra},
+		{"src/link", ZipMissing},
+		{"src/run.sh", ZipModeDiffers},
+	}
+	if z.Match() || len(z.Differences) != len(want) {
+		t.Fatalf("Differences = %v, want %v", z.Differences, want)
+	}
+	for i, d := range want {
+		if z.Differences[i] != d {
+			t.Errorf("Differences[%d] = %v, want %v", i, z.Differences[i], d)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package release
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/internal/pathmatch"
+	"github.com/andrew/swhid-go/objects"
+)
+
+// Preset normalizes the members of an archive made by a known producer,
+// so that HashTar and HashZip give the directory SWHID of the tree it was
+// made from, such as the one of the tagged commit. Timestamps, owners and
+// permission bits other than the executable ones never affect a SWHID.
+type Preset struct {
+	// Name is what the preset is looked up by, as in --preset.
+	Name string
+
+	// StripTopLevel drops the directory every member sits under, such as
+	// project-1.0/. An archive without a single such directory is an
+	// error.
+	StripTopLevel bool
+
+	// ExecBits are the permission bits any of which make a regular member
+	// executable. Git looks only at the owner's, 0100.
+	ExecBits fs.FileMode
+
+	// HardLinks hashes hard link members as the file they link to, as GNU
+	// tar writes later links to a file. Otherwise they are an error.
+	HardLinks bool
+
+	// Exclude leaves out members the producer adds to the tree, matched
+	// below the top-level directory as TreeOptions.Exclude patterns are:
+	// patterns without a slash match names at any depth, others paths
+	// from the root, where a "**" element matches any number of
+	// directories.
+	Exclude []string
+}
+
+// Presets for common producers of source archives.
+var (
+	// PresetExact hashes members as they are, like ReadTar and ReadZip.
+	PresetExact = Preset{Name: "exact", ExecBits: 0111}
+
+	// PresetGNUTar is for tar archives of a checkout made with GNU tar or
+	// bsdtar, whose names may start with ./, which store files with
+	// several links once, and whose group and other execute bits follow
+	// the umask of whoever made them.
+	PresetGNUTar = Preset{Name: "gnu-tar", ExecBits: 0100, HardLinks: true}
+
+	// PresetSetuptoolsSdist is for Python source distributions, which put
+	// the tree under name-version/ and add PKG-INFO and an .egg-info
+	// directory that are not part of the source.
+	PresetSetuptoolsSdist = Preset{
+		Name:          "setuptools-sdist",
+		StripTopLevel: true,
+		ExecBits:      0100,
+		HardLinks:     true,
+		Exclude:       []string{"PKG-INFO", "*.egg-info"},
+	}
+
+	// PresetGitHubArchive is for the tarballs and zips GitHub and git
+	// archive produce for a ref, which put the tree under repo-ref/ and
+	// record the commit in a pax global header or the zip comment.
+	PresetGitHubArchive = Preset{Name: "github-archive", StripTopLevel: true, ExecBits: 0100}
+)
+
+// Presets lists the presets LookupPreset knows.
+var Presets = []Preset{PresetExact, PresetGNUTar, PresetSetuptoolsSdist, PresetGitHubArchive}
+
+// LookupPreset returns the preset called name.
+func LookupPreset(name string) (Preset, error) {
+	var names []string
+	for _, p := range Presets {
+		if p.Name == name {
+			return p, nil
+		}
+		names = append(names, p.Name)
+	}
+	return Preset{}, fmt.Errorf("release: unknown preset %q (use %s)", name, strings.Join(names, ", "))
+}
+
+// HashTar returns the directory SWHID of the tree in an uncompressed tar
+// archive, normalized as p says.
+func HashTar(r io.Reader, p Preset) (*swhid.Identifier, error) {
+	a := newArchiveHasher(p)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeXGlobalHeader:
+			continue
+		case tar.TypeDir:
+			err = a.dir(hdr.Name)
+		case tar.TypeReg:
+			err = a.file(hdr.Name, hdr.FileInfo().Mode(), hdr.Size, tr)
+		case tar.TypeSymlink:
+			err = a.file(hdr.Name, fs.ModeSymlink, int64(len(hdr.Linkname)), strings.NewReader(hdr.Linkname))
+		case tar.TypeLink:
+			err = a.link(hdr.Name, hdr.Linkname)
+		default:
+			err = fmt.Errorf("%w: %q has type %q", ErrInvalidArchive, hdr.Name, hdr.Typeflag)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return a.identifier()
+}
+
+// HashZip returns the directory SWHID of the tree in a zip archive,
+// normalized as p says. Members of zips made without Unix modes are
+// regular, non-executable files.
+func HashZip(r io.ReaderAt, size int64, p Preset) (*swhid.Identifier, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	a := newArchiveHasher(p)
+	for _, f := range zr.File {
+		if err := hashZipMember(a, f); err != nil {
+			return nil, err
+		}
+	}
+	return a.identifier()
+}
+
+func hashZipMember(a *archiveHasher, f *zip.File) error {
+	mode := f.Mode()
+	switch {
+	case mode.IsDir():
+		return a.dir(f.Name)
+	case mode.IsRegular(), mode&fs.ModeSymlink != 0:
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("release: %s: %w", f.Name, err)
+		}
+		defer rc.Close()
+		return a.file(f.Name, mode, int64(f.UncompressedSize64), rc)
+	default:
+		return fmt.Errorf("%w: %q has mode %s", ErrInvalidArchive, f.Name, mode)
+	}
+}
+
+// archiveHasher collects the members of an archive, hashed, until the
+// whole archive has been read and the top-level directory is known.
+type archiveHasher struct {
+	preset  Preset
+	members map[string]*objects.DirectoryEntry // by cleaned name; nil for directories
+	order   []string
+}
+
+func newArchiveHasher(p Preset) *archiveHasher {
+	return &archiveHasher{preset: p, members: make(map[string]*objects.DirectoryEntry)}
+}
+
+// clean returns the slash-separated path a member name stands for, without
+// a leading ./ or /, rejecting names that climb out of the archive.
+func (a *archiveHasher) clean(name string) (string, error) {
+	rel := strings.TrimLeft(strings.TrimPrefix(name, "./"), "/")
+	rel = path.Clean(strings.TrimSuffix(rel, "/"))
+	if rel == "." || rel == "" {
+		return "", nil
+	}
+	if !fs.ValidPath(rel) || strings.Contains(rel, `\`) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidArchive, name)
+	}
+	return rel, nil
+}
+
+// add records the member at name; a later member of the same name
+// replaces it, as when extracting.
+func (a *archiveHasher) add(name string, entry *objects.DirectoryEntry) error {
+	rel, err := a.clean(name)
+	if err != nil || rel == "" {
+		if err == nil && entry != nil {
+			err = fmt.Errorf("%w: %q", ErrInvalidArchive, name)
+		}
+		return err
+	}
+	if _, ok := a.members[rel]; !ok {
+		a.order = append(a.order, rel)
+	}
+	a.members[rel] = entry
+	return nil
+}
+
+func (a *archiveHasher) dir(name string) error {
+	return a.add(name, nil)
+}
+
+// file hashes a regular file or symlink member from body, which must hold
+// size bytes.
+func (a *archiveHasher) file(name string, mode fs.FileMode, size int64, body io.Reader) error {
+	entry, err := hashEntry(name, mode, a.preset.ExecBits, size, body)
+	if err != nil {
+		return err
+	}
+	return a.add(name, &entry)
+}
+
+// link records a hard link member as a copy of the earlier member it
+// links to.
+func (a *archiveHasher) link(name, target string) error {
+	if !a.preset.HardLinks {
+		return fmt.Errorf("%w: %q is a hard link", ErrInvalidArchive, name)
+	}
+	rel, err := a.clean(target)
+	if err != nil {
+		return err
+	}
+	entry := a.members[rel]
+	if entry == nil {
+		return fmt.Errorf("%w: %q links to %q, which is not an earlier file", ErrInvalidArchive, name, target)
+	}
+	copied := *entry
+	return a.add(name, &copied)
+}
+
+// identifier reads the members, normalized, into the tree below the
+// top-level directory when the preset strips it, and returns its SWHID.
+func (a *archiveHasher) identifier() (*swhid.Identifier, error) {
+	var prefix string
+	if a.preset.StripTopLevel {
+		top, err := a.topLevel()
+		if err != nil {
+			return nil, err
+		}
+		prefix = top + "/"
+	}
+	r := newReader(prefix)
+	for _, pattern := range a.preset.Exclude {
+		pattern, err := pathmatch.Clean(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("release: preset %s: %w", a.preset.Name, err)
+		}
+		r.exclude = append(r.exclude, pattern)
+	}
+	for _, p := range a.order {
+		var err error
+		if entry := a.members[p]; entry == nil {
+			err = r.dir(p)
+		} else {
+			err = r.add(p, *entry)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r.root.Identifier(), nil
+}
+
+// topLevel returns the directory every member sits under.
+func (a *archiveHasher) topLevel() (string, error) {
+	var top string
+	for _, p := range a.order {
+		first, _, _ := strings.Cut(p, "/")
+		if top == "" {
+			top = first
+		}
+		if first != top || (p == top && a.members[p] != nil) {
+			return "", fmt.Errorf("%w: members are not all under one top-level directory", ErrInvalidArchive)
+		}
+	}
+	if top == "" {
+		return "", fmt.Errorf("%w: no top-level directory in an empty archive", ErrInvalidArchive)
+	}
+	return top, nil
+}
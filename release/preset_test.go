@@ -0,0 +1,242 @@
+package release
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrew/swhid-go"
+)
+
+// archiveMember is a member of an archive built for a test.
+type archiveMember struct {
+	name string
+	mode int64
+	body string
+	link string // symlink target, or with hard, the linked member
+	hard bool
+}
+
+func buildTar(t *testing.T, comment string, members []archiveMember) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if comment != "" {
+		if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeXGlobalHeader, Name: "pax_global_header", PAXRecords: map[string]string{"comment": comment}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, m := range members {
+		hdr := &tar.Header{Name: m.name, Mode: m.mode, Typeflag: tar.TypeReg, Size: int64(len(m.body))}
+		switch {
+		case m.hard:
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeLink, m.link, 0
+		case m.link != "":
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, m.link, 0
+		case m.name[len(m.name)-1] == '/':
+			hdr.Typeflag, hdr.Size = tar.TypeDir, 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(m.body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// sourceTree is the tree the archives of these tests were made from.
+func sourceTree(t *testing.T) *swhid.Identifier {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "run.sh"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../README", filepath.Join(dir, "src", "link")); err != nil {
+		t.Fatal(err)
+	}
+	id, err := swhid.FromDirectoryPath(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestHashTarPresets(t *testing.T) {
+	want := sourceTree(t)
+
+	tests := []struct {
+		name    string
+		preset  Preset
+		comment string
+		members []archiveMember
+	}{
+		{"github-archive", PresetGitHubArchive, "0123456789abcdef0123456789abcdef01234567", []archiveMember{
+			{name: "repo-1.0/", mode: 0775},
+			{name: "repo-1.0/README", mode: 0664, body: "hello\n"},
+			{name: "repo-1.0/src/", mode: 0775},
+			{name: "repo-1.0/src/link", mode: 0777, link: "../README"},
+			{name: "repo-1.0/src/run.sh", mode: 0775, body: "#!/bin/sh\n"},
+		}},
+		{"setuptools-sdist", PresetSetuptoolsSdist, "", []archiveMember{
+			{name: "pkg-1.0/PKG-INFO", mode: 0644, body: "Metadata-Version: 2.1\n"},
+			{name: "pkg-1.0/README", mode: 0644, body: "hello\n"},
+			{name: "pkg-1.0/pkg.egg-info/", mode: 0755},
+			{name: "pkg-1.0/pkg.egg-info/SOURCES.txt", mode: 0644, body: "README\n"},
+			{name: "pkg-1.0/src/link", mode: 0777, link: "../README"},
+			{name: "pkg-1.0/src/run.sh", mode: 0744, body: "#!/bin/sh\n"},
+		}},
+		{"gnu-tar", PresetGNUTar, "", []archiveMember{
+			{name: "./", mode: 0755},
+			{name: "./README", mode: 0654, body: "hello\n"},
+			{name: "./src/", mode: 0755},
+			{name: "./src/run.sh", mode: 0700, body: "#!/bin/sh\n"},
+			{name: "./src/link", mode: 0777, link: "../README"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := buildTar(t, tt.comment, tt.members)
+			id, err := HashTar(bytes.NewReader(data), tt.preset)
+			if err != nil {
+				t.Fatalf("HashTar() error = %v", err)
+			}
+			if !id.Equal(want) {
+				t.Errorf("HashTar() = %v, want %v", id, want)
+			}
+			if exact, err := HashTar(bytes.NewReader(data), PresetExact); err == nil && exact.Equal(id) {
+				t.Errorf("HashTar() with PresetExact = %v too", exact)
+			}
+		})
+	}
+}
+
+func TestHashTarGNUTar(t *testing.T) {
+	data := buildTar(t, "", []archiveMember{
+		{name: "./a", mode: 0644, body: "a\n"},
+		{name: "./b", link: "./a", hard: true},
+		{name: "./group-exec", mode: 0654, body: "x\n"},
+	})
+	id, err := HashTar(bytes.NewReader(data), PresetGNUTar)
+	if err != nil {
+		t.Fatalf("HashTar() error = %v", err)
+	}
+	copied := buildTar(t, "", []archiveMember{
+		{name: "a", mode: 0644, body: "a\n"},
+		{name: "b", mode: 0644, body: "a\n"},
+		{name: "group-exec", mode: 0644, body: "x\n"},
+	})
+	want, err := HashTar(bytes.NewReader(copied), PresetExact)
+	if err != nil {
+		t.Fatalf("HashTar() error = %v", err)
+	}
+	if !id.Equal(want) {
+		t.Errorf("HashTar() = %v, want %v", id, want)
+	}
+
+	if _, err := HashTar(bytes.NewReader(data), PresetGitHubArchive); !errors.Is(err, ErrInvalidArchive) {
+		t.Errorf("HashTar() of hard links with PresetGitHubArchive error = %v, want ErrInvalidArchive", err)
+	}
+}
+
+func TestHashTarExclude(t *testing.T) {
+	want := sourceTree(t)
+	data := buildTar(t, "", []archiveMember{
+		{name: "pkg-1.0/README", mode: 0644, body: "hello\n"},
+		{name: "pkg-1.0/__pycache__/", mode: 0755},
+		{name: "pkg-1.0/__pycache__/a.pyc", mode: 0644, body: "x"},
+		{name: "pkg-1.0/src/link", mode: 0777, link: "../README"},
+		{name: "pkg-1.0/src/run.sh", mode: 0755, body: "#!/bin/sh\n"},
+		{name: "pkg-1.0/src/build.tmp", mode: 0644, body: "y"},
+		{name: "pkg-1.0/src/__pycache__/b.pyc", mode: 0644, body: "z"},
+	})
+	preset := Preset{Name: "custom", StripTopLevel: true, ExecBits: 0100, Exclude: []string{"./**/__pycache__/", "src/**/*.tmp"}}
+	id, err := HashTar(bytes.NewReader(data), preset)
+	if err != nil {
+		t.Fatalf("HashTar() error = %v", err)
+	}
+	if !id.Equal(want) {
+		t.Errorf("HashTar() = %v, want %v", id, want)
+	}
+
+	preset.Exclude = []string{"["}
+	if _, err := HashTar(bytes.NewReader(data), preset); err == nil {
+		t.Error("HashTar() accepted an invalid exclude pattern")
+	}
+}
+
+func TestHashTarNoTopLevel(t *testing.T) {
+	data := buildTar(t, "", []archiveMember{
+		{name: "a/x", mode: 0644, body: "x\n"},
+		{name: "b/y", mode: 0644, body: "y\n"},
+	})
+	if _, err := HashTar(bytes.NewReader(data), PresetGitHubArchive); !errors.Is(err, ErrInvalidArchive) {
+		t.Errorf("HashTar() error = %v, want ErrInvalidArchive", err)
+	}
+	data = buildTar(t, "", []archiveMember{{name: "../x", mode: 0644, body: "x\n"}})
+	if _, err := HashTar(bytes.NewReader(data), PresetExact); !errors.Is(err, ErrInvalidArchive) {
+		t.Errorf("HashTar() of ../x error = %v, want ErrInvalidArchive", err)
+	}
+}
+
+func TestHashZip(t *testing.T) {
+	want := sourceTree(t)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, m := range []archiveMember{
+		{name: "repo-main/", mode: 0755 | int64(os.ModeDir)},
+		{name: "repo-main/README", mode: 0644, body: "hello\n"},
+		{name: "repo-main/src/link", mode: 0777 | int64(os.ModeSymlink), body: "../README"},
+		{name: "repo-main/src/run.sh", mode: 0755, body: "#!/bin/sh\n"},
+	} {
+		hdr := &zip.FileHeader{Name: m.name}
+		hdr.SetMode(os.FileMode(m.mode))
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(m.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := HashZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()), PresetGitHubArchive)
+	if err != nil {
+		t.Fatalf("HashZip() error = %v", err)
+	}
+	if !id.Equal(want) {
+		t.Errorf("HashZip() = %v, want %v", id, want)
+	}
+}
+
+func TestLookupPreset(t *testing.T) {
+	for _, p := range Presets {
+		got, err := LookupPreset(p.Name)
+		if err != nil || got.Name != p.Name {
+			t.Errorf("LookupPreset(%q) = %v, %v", p.Name, got.Name, err)
+		}
+	}
+	if _, err := LookupPreset("winzip"); err == nil {
+		t.Error("LookupPreset(winzip) succeeded")
+	}
+}
@@ -0,0 +1,312 @@
+// Package release writes tar and zip archives of a directory that carry
+// the directory's SWHID, and checks such archives against it, so that a
+// release artifact can be verified on its own.
+//
+// The SWHID is recorded as two key=value records: SWHID.dir, the core
+// SWHID of the archived directory, and SWHID.prefix, the directory the
+// files are archived under, if any. Tar archives hold them in a pax global
+// header, the first entry of the archive, which tar tools that do not know
+// the keys ignore. Zip archives hold them as lines of the archive comment.
+// The SWHID is the one swhid directory gives for the directory that was
+// archived, and for the tree an extracted copy recreates below the prefix.
+//
+// HashTar and HashZip hash archives from elsewhere, normalized by a Preset
+// for the tool that made them.
+package release
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/internal/archivetree"
+	"github.com/andrew/swhid-go/internal/pathmatch"
+	"github.com/andrew/swhid-go/objects"
+)
+
+// Keys of the records holding the SWHID.
+const (
+	RecordSWHID  = "SWHID.dir"
+	RecordPrefix = "SWHID.prefix"
+)
+
+var (
+	// ErrMismatch is returned with a Result whose archived tree does not
+	// hash to the SWHID recorded in the archive.
+	ErrMismatch = errors.New("release: archive does not match its SWHID")
+
+	// ErrNoSWHID is returned with a Result for archives without a
+	// recorded SWHID.
+	ErrNoSWHID = errors.New("release: archive has no SWHID")
+
+	// ErrInvalidArchive is returned for members outside the prefix, with
+	// non-canonical or duplicate names, or of a type a SWHID directory
+	// cannot hold, such as hard links and device files.
+	ErrInvalidArchive = errors.New("release: invalid archive member")
+)
+
+// Options controls how an archive is written.
+type Options struct {
+	// Prefix is the directory the files are archived under, such as
+	// "project-1.0"; none when empty.
+	Prefix string
+
+	// ModTime is the modification time of every member. When zero the
+	// files' own times are used; set it, to a release or commit date for
+	// instance, for archives that are byte for byte reproducible.
+	ModTime time.Time
+
+	// Tree selects and reads the files, as for swhid directory.
+	Tree swhid.TreeOptions
+}
+
+// Result is what reading an archive found.
+type Result struct {
+	Embedded *swhid.Identifier // the recorded SWHID; nil if there is none
+	Computed *swhid.Identifier // the SWHID of the archived tree
+	Prefix   string            // the recorded prefix, with a trailing slash
+}
+
+// Match reports whether the archived tree hashes to the recorded SWHID.
+func (r *Result) Match() bool {
+	return r.Embedded != nil && r.Embedded.CoreSWHID() == r.Computed.CoreSWHID()
+}
+
+// err returns the error the readers give for r.
+func (r *Result) err() error {
+	switch {
+	case r.Embedded == nil:
+		return ErrNoSWHID
+	case !r.Match():
+		return fmt.Errorf("%w: recorded %s, archived tree is %s", ErrMismatch, r.Embedded, r.Computed)
+	}
+	return nil
+}
+
+// records returns the SWHID records for id and prefix.
+func records(id *swhid.Identifier, prefix string) map[string]string {
+	recs := map[string]string{RecordSWHID: id.CoreSWHID()}
+	if prefix != "" {
+		recs[RecordPrefix] = prefix
+	}
+	return recs
+}
+
+// formatComment writes records as the lines of a zip comment.
+func formatComment(recs map[string]string) string {
+	keys := make([]string, 0, len(recs))
+	for k := range recs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%s\n", k, recs[k])
+	}
+	return b.String()
+}
+
+// parseComment reads the records from a zip comment, ignoring other lines.
+func parseComment(comment string) map[string]string {
+	recs := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(comment))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if ok && (key == RecordSWHID || key == RecordPrefix) {
+			recs[key] = value
+		}
+	}
+	return recs
+}
+
+// cleanPrefix returns prefix with a single trailing slash, or "" for none.
+func cleanPrefix(prefix string) (string, error) {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return "", nil
+	}
+	if !fs.ValidPath(prefix) {
+		return "", fmt.Errorf("release: invalid prefix %q", prefix)
+	}
+	return prefix + "/", nil
+}
+
+// member is one file, directory or symlink of the tree being written.
+type member struct {
+	node *swhid.Node
+	name string      // path in the archive
+	mode fs.FileMode // permissions and type
+}
+
+// members lists the entries of the tree at root as they are archived,
+// directories with a trailing slash, root included when there is a prefix.
+func members(root *swhid.Node, prefix string) ([]member, error) {
+	var list []member
+	var err error
+	root.Walk(func(n *swhid.Node) bool {
+		name := prefix + n.Path
+		switch n.Type {
+		case objects.EntryTypeDirectory:
+			if name == "" {
+				return true
+			}
+			list = append(list, member{n, strings.TrimSuffix(name, "/") + "/", fs.ModeDir | 0755})
+		case objects.EntryTypeExecutable:
+			list = append(list, member{n, name, 0755})
+		case objects.EntryTypeSymlink:
+			list = append(list, member{n, name, fs.ModeSymlink | 0777})
+		case objects.EntryTypeFile:
+			list = append(list, member{n, name, 0644})
+		default:
+			err = fmt.Errorf("release: %s: cannot archive a submodule", n.Path)
+		}
+		return err == nil
+	})
+	return list, err
+}
+
+// hashingWriter computes the blob hash of the bytes written through it.
+type hashingWriter struct {
+	w    io.Writer
+	hash interface {
+		io.Writer
+		Sum([]byte) []byte
+	}
+}
+
+func newHashingWriter(w io.Writer, size int64) *hashingWriter {
+	return &hashingWriter{w: w, hash: objects.NewContentHash(size)}
+}
+
+func (h *hashingWriter) Write(p []byte) (int, error) {
+	h.hash.Write(p)
+	return h.w.Write(p)
+}
+
+func (h *hashingWriter) sum() string {
+	return hex.EncodeToString(h.hash.Sum(nil))
+}
+
+// reader checks the member names of an archive being read and builds its
+// tree below the prefix, leaving out members that match exclude, or lie
+// below a directory that does.
+type reader struct {
+	prefix  string
+	exclude []string
+	root    *archivetree.Tree
+	seen    map[string]bool
+}
+
+func newReader(prefix string) *reader {
+	return &reader{prefix: prefix, root: archivetree.New(), seen: make(map[string]bool)}
+}
+
+// path returns the path of the member name below the prefix, "" for the
+// prefix directory itself.
+func (r *reader) path(name string) (string, error) {
+	rel, ok := strings.CutPrefix(name, r.prefix)
+	if !ok && name+"/" == r.prefix {
+		rel, ok = "", true
+	}
+	rel = strings.TrimSuffix(rel, "/")
+	if !ok || (rel != "" && !fs.ValidPath(rel)) || strings.Contains(rel, `\`) || r.seen[rel] {
+		return "", fmt.Errorf("%w: %q", ErrInvalidArchive, name)
+	}
+	r.seen[rel] = true
+	return rel, nil
+}
+
+// excluded reports whether rel, or a directory above it, matches one of
+// r.exclude.
+func (r *reader) excluded(rel string) bool {
+	for _, pattern := range r.exclude {
+		for p := rel; p != "."; p = path.Dir(p) {
+			if pathmatch.Match(pattern, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dir records a directory member.
+func (r *reader) dir(name string) error {
+	rel, err := r.path(name)
+	if err != nil || rel == "" || r.excluded(rel) {
+		return err
+	}
+	if !r.root.Mkdir(rel) {
+		return fmt.Errorf("%w: %q", ErrInvalidArchive, name)
+	}
+	return nil
+}
+
+// file records a regular file or symlink member, hashing its content from
+// body, which must hold size bytes.
+func (r *reader) file(name string, mode fs.FileMode, size int64, body io.Reader) error {
+	entry, err := hashEntry(name, mode, 0111, size, body)
+	if err != nil {
+		return err
+	}
+	return r.add(name, entry)
+}
+
+// add records the file or symlink entry of the member name.
+func (r *reader) add(name string, entry objects.DirectoryEntry) error {
+	rel, err := r.path(name)
+	if err != nil {
+		return err
+	}
+	if rel == "" {
+		return fmt.Errorf("%w: %q", ErrInvalidArchive, name)
+	}
+	if !r.excluded(rel) && !r.root.Add(rel, entry) {
+		return fmt.Errorf("%w: %q", ErrInvalidArchive, name)
+	}
+	return nil
+}
+
+// hashEntry hashes a regular file or symlink member from body, which must
+// hold size bytes. Regular files with any of execBits set are executable.
+func hashEntry(name string, mode, execBits fs.FileMode, size int64, body io.Reader) (objects.DirectoryEntry, error) {
+	h := newHashingWriter(io.Discard, size)
+	n, err := io.Copy(h, body)
+	if err != nil {
+		return objects.DirectoryEntry{}, fmt.Errorf("release: %s: %w", name, err)
+	}
+	if n != size {
+		return objects.DirectoryEntry{}, fmt.Errorf("%w: %q holds %d bytes, its header says %d", ErrInvalidArchive, name, n, size)
+	}
+	entryType := objects.EntryTypeFile
+	switch {
+	case mode&fs.ModeSymlink != 0:
+		entryType = objects.EntryTypeSymlink
+	case mode&execBits != 0:
+		entryType = objects.EntryTypeExecutable
+	}
+	return objects.DirectoryEntry{Type: entryType, Target: h.sum(), Size: size}, nil
+}
+
+// result returns the Result for the records found and the tree read.
+func (r *reader) result(recs map[string]string) (*Result, error) {
+	res := &Result{Computed: r.root.Identifier(), Prefix: r.prefix}
+	if s, ok := recs[RecordSWHID]; ok {
+		id, err := swhid.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("release: recorded SWHID: %w", err)
+		}
+		if id.ObjectType != swhid.ObjectTypeDirectory {
+			return nil, fmt.Errorf("release: recorded SWHID %s is not a directory", id)
+		}
+		res.Embedded = id
+	}
+	return res, res.err()
+}
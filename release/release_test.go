@@ -0,0 +1,140 @@
+package release
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andrew/swhid-go"
+)
+
+func testDir(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "src", "empty"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("hello\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "run.sh"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.Symlink("../README", filepath.Join(dir, "src", "link")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	return dir
+}
+
+func TestTar(t *testing.T) {
+	dir := testDir(t)
+	want, err := swhid.FromDirectoryPath(dir)
+	if err != nil {
+		t.Fatalf("FromDirectoryPath() error = %v", err)
+	}
+
+	for _, prefix := range []string{"", "project-1.0"} {
+		var buf bytes.Buffer
+		id, err := WriteTar(&buf, dir, Options{Prefix: prefix, ModTime: time.Unix(1700000000, 0)})
+		if err != nil {
+			t.Fatalf("WriteTar(%q) error = %v", prefix, err)
+		}
+		if !id.Equal(want) {
+			t.Errorf("WriteTar(%q) = %v, want %v", prefix, id, want)
+		}
+
+		res, err := ReadTar(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("ReadTar(%q) error = %v", prefix, err)
+		}
+		if !res.Match() || !res.Computed.Equal(want) {
+			t.Errorf("ReadTar(%q) = %+v, want %v", prefix, res, want)
+		}
+	}
+}
+
+func TestZip(t *testing.T) {
+	dir := testDir(t)
+
+	var buf bytes.Buffer
+	id, err := WriteZip(&buf, dir, Options{Prefix: "project-1.0/"})
+	if err != nil {
+		t.Fatalf("WriteZip() error = %v", err)
+	}
+	res, err := ReadZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("ReadZip() error = %v", err)
+	}
+	if !res.Embedded.Equal(id) || !res.Match() || res.Prefix != "project-1.0/" {
+		t.Errorf("ReadZip() = %+v, want %v", res, id)
+	}
+}
+
+func TestReadMismatch(t *testing.T) {
+	dir := testDir(t)
+	var buf bytes.Buffer
+	if _, err := WriteZip(&buf, dir, Options{}); err != nil {
+		t.Fatalf("WriteZip() error = %v", err)
+	}
+
+	// Copy the archive with README changed and the comment kept
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("zip.NewReader() error = %v", err)
+	}
+	var tampered bytes.Buffer
+	zw := zip.NewWriter(&tampered)
+	for _, f := range zr.File {
+		if f.Name == "README" {
+			w, _ := zw.CreateHeader(&f.FileHeader)
+			w.Write([]byte("HELLO\n"))
+			continue
+		}
+		if err := zw.Copy(f); err != nil {
+			t.Fatalf("Copy() error = %v", err)
+		}
+	}
+	zw.SetComment(zr.Comment)
+	zw.Close()
+
+	res, err := ReadZip(bytes.NewReader(tampered.Bytes()), int64(tampered.Len()))
+	if !errors.Is(err, ErrMismatch) || res == nil || res.Match() {
+		t.Errorf("ReadZip() = %+v, %v, want ErrMismatch", res, err)
+	}
+}
+
+func TestReadTarInvalid(t *testing.T) {
+	archive := func(hdrs ...*tar.Header) []byte {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, hdr := range hdrs {
+			tw.WriteHeader(hdr)
+			tw.Write(make([]byte, hdr.Size))
+		}
+		tw.Close()
+		return buf.Bytes()
+	}
+
+	res, err := ReadTar(bytes.NewReader(archive(&tar.Header{Name: "README", Typeflag: tar.TypeReg, Mode: 0644, Size: 1})))
+	if !errors.Is(err, ErrNoSWHID) || res == nil || res.Computed == nil {
+		t.Errorf("ReadTar() without records = %+v, %v, want ErrNoSWHID", res, err)
+	}
+
+	for _, hdr := range []*tar.Header{
+		{Name: "../etc/passwd", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "outside/file", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "project/hard", Typeflag: tar.TypeLink, Linkname: "project/README"},
+	} {
+		global := &tar.Header{Typeflag: tar.TypeXGlobalHeader, Name: "pax_global_header",
+			PAXRecords: map[string]string{RecordSWHID: "swh:1:dir:4b825dc642cb6eb9a060e54bf8d69288fbee4904", RecordPrefix: "project"}}
+		if _, err := ReadTar(bytes.NewReader(archive(global, hdr))); !errors.Is(err, ErrInvalidArchive) {
+			t.Errorf("ReadTar(%s) error = %v, want ErrInvalidArchive", hdr.Name, err)
+		}
+	}
+}
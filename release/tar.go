@@ -0,0 +1,164 @@
+package release
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/andrew/swhid-go"
+)
+
+// WriteTar writes an uncompressed tar archive of the directory dir to w,
+// with its SWHID in a pax global header, and returns the SWHID. Wrap w in
+// a gzip.Writer or another compressor for a .tar.gz.
+func WriteTar(w io.Writer, dir string, opts Options) (*swhid.Identifier, error) {
+	prefix, err := cleanPrefix(opts.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	root, err := swhid.TreeFromDirectoryPathWithOptions(dir, opts.Tree)
+	if err != nil {
+		return nil, err
+	}
+	list, err := members(root, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	tw := tar.NewWriter(w)
+	err = tw.WriteHeader(&tar.Header{
+		Typeflag:   tar.TypeXGlobalHeader,
+		Name:       "pax_global_header",
+		PAXRecords: records(root.ID, prefix),
+		Format:     tar.FormatPAX,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range list {
+		if err := writeTarMember(tw, dir, m, opts); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return root.ID, nil
+}
+
+func writeTarMember(tw *tar.Writer, dir string, m member, opts Options) error {
+	modTime := opts.ModTime
+	if modTime.IsZero() {
+		modTime = m.node.ModTime
+	}
+	hdr := &tar.Header{
+		Name:    m.name,
+		Mode:    int64(m.mode.Perm()),
+		ModTime: modTime,
+		Format:  tar.FormatPAX,
+	}
+	path := filepath.Join(dir, filepath.FromSlash(m.node.Path))
+
+	switch {
+	case m.mode.IsDir():
+		hdr.Typeflag = tar.TypeDir
+		return tw.WriteHeader(hdr)
+	case m.mode&os.ModeSymlink != 0:
+		target, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		if swhid.FromContent([]byte(target)).ObjectHash != m.node.ID.ObjectHash {
+			return fmt.Errorf("release: %s changed while it was archived", m.node.Path)
+		}
+		hdr.Typeflag, hdr.Linkname = tar.TypeSymlink, target
+		return tw.WriteHeader(hdr)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	hdr.Typeflag, hdr.Size = tar.TypeReg, m.node.Size
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	return copyMember(tw, f, m)
+}
+
+// copyMember copies the content of m from f to w, failing if it no longer
+// hashes to the SWHID the tree was built with.
+func copyMember(w io.Writer, f io.Reader, m member) error {
+	h := newHashingWriter(w, m.node.Size)
+	if _, err := io.CopyN(h, f, m.node.Size); err != nil {
+		return fmt.Errorf("release: %s: %w", m.node.Path, err)
+	}
+	if h.sum() != m.node.ID.ObjectHash {
+		return fmt.Errorf("release: %s changed while it was archived", m.node.Path)
+	}
+	return nil
+}
+
+// ReadTar reads an uncompressed tar archive, hashes the tree below its
+// recorded prefix and checks it against the recorded SWHID. The Result
+// comes with ErrNoSWHID or ErrMismatch when they differ; other errors
+// come without one.
+func ReadTar(r io.Reader) (*Result, error) {
+	tr := tar.NewReader(r)
+	var (
+		recs map[string]string
+		rd   *reader
+	)
+	// The records come first, so the prefix is known once the first
+	// member is.
+	start := func() error {
+		prefix, err := cleanPrefix(recs[RecordPrefix])
+		rd = newReader(prefix)
+		return err
+	}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag == tar.TypeXGlobalHeader {
+			if rd != nil {
+				return nil, fmt.Errorf("%w: pax global header after the first member", ErrInvalidArchive)
+			}
+			recs = hdr.PAXRecords
+			continue
+		}
+		if rd == nil {
+			if err := start(); err != nil {
+				return nil, err
+			}
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = rd.dir(hdr.Name)
+		case tar.TypeReg:
+			err = rd.file(hdr.Name, hdr.FileInfo().Mode(), hdr.Size, tr)
+		case tar.TypeSymlink:
+			err = rd.file(hdr.Name, os.ModeSymlink, int64(len(hdr.Linkname)), strings.NewReader(hdr.Linkname))
+		default:
+			err = fmt.Errorf("%w: %q has type %q", ErrInvalidArchive, hdr.Name, hdr.Typeflag)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if rd == nil {
+		if err := start(); err != nil {
+			return nil, err
+		}
+	}
+	return rd.result(recs)
+}
@@ -0,0 +1,123 @@
+package release
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/andrew/swhid-go"
+)
+
+// WriteZip writes a zip archive of the directory dir to w, with its SWHID
+// in the archive comment, and returns the SWHID. Files are deflated.
+func WriteZip(w io.Writer, dir string, opts Options) (*swhid.Identifier, error) {
+	prefix, err := cleanPrefix(opts.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	root, err := swhid.TreeFromDirectoryPathWithOptions(dir, opts.Tree)
+	if err != nil {
+		return nil, err
+	}
+	list, err := members(root, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	zw := zip.NewWriter(w)
+	for _, m := range list {
+		if err := writeZipMember(zw, dir, m, opts); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.SetComment(formatComment(records(root.ID, prefix))); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return root.ID, nil
+}
+
+func writeZipMember(zw *zip.Writer, dir string, m member, opts Options) error {
+	modTime := opts.ModTime
+	if modTime.IsZero() {
+		modTime = m.node.ModTime
+	}
+	hdr := &zip.FileHeader{Name: m.name, Modified: modTime, Method: zip.Deflate}
+	hdr.SetMode(m.mode)
+	path := filepath.Join(dir, filepath.FromSlash(m.node.Path))
+
+	switch {
+	case m.mode.IsDir():
+		hdr.Method = zip.Store
+		_, err := zw.CreateHeader(hdr)
+		return err
+	case m.mode&os.ModeSymlink != 0:
+		target, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		if swhid.FromContent([]byte(target)).ObjectHash != m.node.ID.ObjectHash {
+			return fmt.Errorf("release: %s changed while it was archived", m.node.Path)
+		}
+		hdr.Method = zip.Store
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(fw, target)
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fw, err := zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	return copyMember(fw, f, m)
+}
+
+// ReadZip reads a zip archive, hashes the tree below its recorded prefix
+// and checks it against the recorded SWHID, like ReadTar.
+func ReadZip(r io.ReaderAt, size int64) (*Result, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	recs := parseComment(zr.Comment)
+	prefix, err := cleanPrefix(recs[RecordPrefix])
+	if err != nil {
+		return nil, err
+	}
+	rd := newReader(prefix)
+	for _, f := range zr.File {
+		if err := readZipMember(rd, f); err != nil {
+			return nil, err
+		}
+	}
+	return rd.result(recs)
+}
+
+func readZipMember(rd *reader, f *zip.File) error {
+	mode := f.Mode()
+	switch {
+	case mode.IsDir():
+		return rd.dir(f.Name)
+	case mode.IsRegular(), mode&os.ModeSymlink != 0:
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("release: %s: %w", f.Name, err)
+		}
+		defer rc.Close()
+		return rd.file(f.Name, mode, int64(f.UncompressedSize64), rc)
+	default:
+		return fmt.Errorf("%w: %q has mode %s", ErrInvalidArchive, f.Name, mode)
+	}
+}
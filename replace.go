@@ -0,0 +1,172 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/andrew/swhid-go/objects"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// maxReplaceDepth bounds chains of replace refs, matching git's limit.
+const maxReplaceDepth = 5
+
+// FromRevisionWithOptions computes the SWHID for a Git revision like
+// FromRevision, applying replace refs and grafts as opts requests.
+func FromRevisionWithOptions(repoPath, ref string, opts GitOptions) (*Identifier, error) {
+	repo, commit, err := resolveCommit(repoPath, ref, opts.ReadOnlyFS)
+	if err != nil {
+		return nil, err
+	}
+
+	return revisionWithOptions(repo, commit, opts)
+}
+
+// FromReleaseWithOptions computes the SWHID for an annotated tag like
+// FromRelease, applying replace refs to the tag object if opts requests it.
+func FromReleaseWithOptions(repoPath, tagName string, opts GitOptions) (*Identifier, error) {
+	repo, err := openRepository(repoPath, false, opts.ReadOnlyFS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	tagObj, err := resolveTag(repo, tagName)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.ReplaceRefs {
+		if tagObj, err = replaceTag(repo, tagObj); err != nil {
+			return nil, err
+		}
+	}
+
+	return FromReleaseMetadata(releaseMetadata(repo, tagObj)), nil
+}
+
+func revisionWithOptions(repo *git.Repository, commit *object.Commit, opts GitOptions) (*Identifier, error) {
+	meta, err := revisionMetadataWithOptions(repo, commit, opts)
+	if err != nil {
+		return nil, err
+	}
+	return FromRevisionMetadata(meta), nil
+}
+
+// revisionMetadataWithOptions returns the metadata revisionWithOptions
+// hashes, after replace refs and grafts if opts asks for them.
+func revisionMetadataWithOptions(repo *git.Repository, commit *object.Commit, opts GitOptions) (objects.RevisionMetadata, error) {
+	var err error
+	if opts.ReplaceRefs {
+		if commit, err = replaceCommit(repo, commit); err != nil {
+			return objects.RevisionMetadata{}, err
+		}
+	}
+
+	meta := revisionMetadata(repo, commit)
+
+	if opts.Grafts {
+		grafts, err := readGrafts(repo)
+		if err != nil {
+			return objects.RevisionMetadata{}, err
+		}
+		if parents, ok := grafts[commit.Hash]; ok {
+			meta.Parents = nil
+			for _, parent := range parents {
+				meta.Parents = append(meta.Parents, parent.String())
+			}
+		}
+	}
+
+	return meta, nil
+}
+
+// replacement follows refs/replace/<hash> chains starting at hash and
+// returns the final object hash, or hash itself if it is not replaced.
+func replacement(repo *git.Repository, hash plumbing.Hash) (plumbing.Hash, error) {
+	for range maxReplaceDepth {
+		ref, err := repo.Reference(plumbing.ReferenceName("refs/replace/"+hash.String()), true)
+		if err == plumbing.ErrReferenceNotFound {
+			return hash, nil
+		}
+		if err != nil {
+			return hash, fmt.Errorf("failed to read replace ref for %s: %w", hash, err)
+		}
+		hash = ref.Hash()
+	}
+
+	return hash, fmt.Errorf("replace depth too high for object %s", hash)
+}
+
+func replaceCommit(repo *git.Repository, commit *object.Commit) (*object.Commit, error) {
+	hash, err := replacement(repo, commit.Hash)
+	if err != nil || hash == commit.Hash {
+		return commit, err
+	}
+
+	replaced, err := repo.CommitObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("replacement %s for commit %s is not a commit: %w", hash, commit.Hash, err)
+	}
+	return replaced, nil
+}
+
+func replaceTag(repo *git.Repository, tagObj *object.Tag) (*object.Tag, error) {
+	hash, err := replacement(repo, tagObj.Hash)
+	if err != nil || hash == tagObj.Hash {
+		return tagObj, err
+	}
+
+	replaced, err := repo.TagObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("replacement %s for tag %s is not a tag: %w", hash, tagObj.Hash, err)
+	}
+	return replaced, nil
+}
+
+// readGrafts parses the repository's info/grafts file, which maps a commit
+// to the parents git should pretend it has. A missing file yields no grafts.
+func readGrafts(repo *git.Repository) (map[plumbing.Hash][]plumbing.Hash, error) {
+	storage, ok := repo.Storer.(*filesystem.Storage)
+	if !ok {
+		return nil, nil
+	}
+
+	f, err := storage.Filesystem().Open("info/grafts")
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open grafts file: %w", err)
+	}
+	defer f.Close()
+
+	grafts := make(map[plumbing.Hash][]plumbing.Hash)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		var hashes []plumbing.Hash
+		for _, field := range fields {
+			if !plumbing.IsHash(field) {
+				return nil, fmt.Errorf("grafts line %d: invalid object name %q", line, field)
+			}
+			hashes = append(hashes, plumbing.NewHash(field))
+		}
+		grafts[hashes[0]] = hashes[1:]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read grafts file: %w", err)
+	}
+
+	return grafts, nil
+}
@@ -0,0 +1,146 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrew/swhid-go/objects"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// newReplacedRepo returns a repository whose second commit is replaced by
+// a sibling commit through refs/replace/, along with both commit hashes.
+func newReplacedRepo(t *testing.T) (string, *git.Repository, plumbing.Hash, plumbing.Hash) {
+	t.Helper()
+
+	repoPath, repo, _ := newTestRepo(t)
+	if err := os.WriteFile(filepath.Join(repoPath, "hello.txt"), []byte("hello again\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	original := commitAll(t, repo, "Second commit\n")
+
+	if err := os.WriteFile(filepath.Join(repoPath, "hello.txt"), []byte("replaced\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	replacement := commitAll(t, repo, "Replacement commit\n")
+
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatalf("Failed to read HEAD: %v", err)
+	}
+	refs := []*plumbing.Reference{
+		plumbing.NewHashReference(head.Name(), original),
+		plumbing.NewHashReference(plumbing.ReferenceName("refs/replace/"+original.String()), replacement),
+	}
+	for _, ref := range refs {
+		if err := repo.Storer.SetReference(ref); err != nil {
+			t.Fatalf("Failed to set reference: %v", err)
+		}
+	}
+
+	return repoPath, repo, original, replacement
+}
+
+func TestFromRevisionWithOptionsReplaceRefs(t *testing.T) {
+	repoPath, _, original, replacement := newReplacedRepo(t)
+
+	id, err := FromRevision(repoPath, "HEAD")
+	if err != nil {
+		t.Fatalf("FromRevision() error = %v", err)
+	}
+	if id.ObjectHash != original.String() {
+		t.Errorf("FromRevision() = %v, want true object %v", id.ObjectHash, original)
+	}
+
+	id, err = FromRevisionWithOptions(repoPath, "HEAD", GitOptions{})
+	if err != nil {
+		t.Fatalf("FromRevisionWithOptions() error = %v", err)
+	}
+	if id.ObjectHash != original.String() {
+		t.Errorf("FromRevisionWithOptions(default) = %v, want %v", id.ObjectHash, original)
+	}
+
+	id, err = FromRevisionWithOptions(repoPath, "HEAD", GitOptions{ReplaceRefs: true})
+	if err != nil {
+		t.Fatalf("FromRevisionWithOptions() error = %v", err)
+	}
+	if id.ObjectHash != replacement.String() {
+		t.Errorf("FromRevisionWithOptions(ReplaceRefs) = %v, want %v", id.ObjectHash, replacement)
+	}
+
+	session, err := OpenRepoWithOptions(repoPath, GitOptions{ReplaceRefs: true})
+	if err != nil {
+		t.Fatalf("OpenRepoWithOptions() error = %v", err)
+	}
+	id, err = session.Revision("HEAD")
+	if err != nil {
+		t.Fatalf("Revision() error = %v", err)
+	}
+	if id.ObjectHash != replacement.String() {
+		t.Errorf("session Revision() = %v, want %v", id.ObjectHash, replacement)
+	}
+}
+
+func TestFromRevisionWithOptionsGrafts(t *testing.T) {
+	repoPath, _, original, _ := newReplacedRepo(t)
+
+	graftsPath := filepath.Join(repoPath, ".git", "info", "grafts")
+	if err := os.MkdirAll(filepath.Dir(graftsPath), 0755); err != nil {
+		t.Fatalf("Failed to create info dir: %v", err)
+	}
+	grafts := "# make the second commit a root\n" + original.String() + "\n"
+	if err := os.WriteFile(graftsPath, []byte(grafts), 0644); err != nil {
+		t.Fatalf("Failed to write grafts: %v", err)
+	}
+
+	id, err := FromRevisionWithOptions(repoPath, "HEAD", GitOptions{})
+	if err != nil {
+		t.Fatalf("FromRevisionWithOptions() error = %v", err)
+	}
+	if id.ObjectHash != original.String() {
+		t.Errorf("FromRevisionWithOptions(default) = %v, want %v", id.ObjectHash, original)
+	}
+
+	grafted, err := FromRevisionWithOptions(repoPath, "HEAD", GitOptions{Grafts: true})
+	if err != nil {
+		t.Fatalf("FromRevisionWithOptions() error = %v", err)
+	}
+	if grafted.ObjectHash == original.String() {
+		t.Error("FromRevisionWithOptions(Grafts) ignored the grafts file")
+	}
+
+	if err := os.WriteFile(graftsPath, []byte("not-a-hash\n"), 0644); err != nil {
+		t.Fatalf("Failed to write grafts: %v", err)
+	}
+	if _, err := FromRevisionWithOptions(repoPath, "HEAD", GitOptions{Grafts: true}); err == nil {
+		t.Error("FromRevisionWithOptions() expected error for malformed grafts")
+	}
+}
+
+func TestSnapshotReplaceRefs(t *testing.T) {
+	repoPath, _, original, replacement := newReplacedRepo(t)
+
+	branches, err := SnapshotBranches(repoPath)
+	if err != nil {
+		t.Fatalf("SnapshotBranches() error = %v", err)
+	}
+
+	name := "refs/replace/" + original.String()
+	found := false
+	for _, b := range branches {
+		if b.Name != name {
+			continue
+		}
+		found = true
+		if b.TargetType != objects.BranchTargetRevision || b.Target != replacement.String() {
+			t.Errorf("replace branch = %s %s, want revision %s", b.TargetType, b.Target, replacement)
+		}
+	}
+	if !found {
+		t.Errorf("SnapshotBranches() missing %s", name)
+	}
+}
@@ -0,0 +1,199 @@
+package swhid
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/andrew/swhid-go/objects"
+)
+
+// Difference kinds reported by CompareDirectories.
+const (
+	ReproOnlyA   = "only-a"  // present in the first tree only
+	ReproOnlyB   = "only-b"  // present in the second tree only
+	ReproType    = "type"    // a different kind of entry, such as a file and a symlink
+	ReproMode    = "mode"    // the same content, executable in one tree only
+	ReproEOL     = "eol"     // the same text once CRLF line endings are turned into LF
+	ReproOrder   = "order"   // the same lines in another order
+	ReproContent = "content" // any other change of content
+)
+
+// ReproDifference is one entry on which two trees disagree. A is nil for
+// entries only in the second tree and B for entries only in the first.
+type ReproDifference struct {
+	Path   string // slash-separated path from the compared directories
+	Kind   string
+	Detail string // what differs, in words
+	A, B   *Node
+}
+
+// ReproReport is the outcome of CompareDirectories. Trees that hash the
+// same have no differences.
+type ReproReport struct {
+	A, B        *Identifier
+	Differences []ReproDifference // sorted by path
+}
+
+// CompareDirectories hashes two directories, typically two checkouts or
+// build outputs that should be bit-for-bit identical, and when their
+// SWHIDs differ, reports the entries that cause it. Subdirectories are
+// descended into only when their hashes differ, so the report lists the
+// first divergent entries of each branch of the tree. Files differing in
+// content are told apart by a look at both versions: line ending
+// conversion, lines written in another order, or any other change.
+func CompareDirectories(pathA, pathB string, opts TreeOptions) (*ReproReport, error) {
+	a, err := TreeFromDirectoryPathWithOptions(pathA, opts)
+	if err != nil {
+		return nil, err
+	}
+	b, err := TreeFromDirectoryPathWithOptions(pathB, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &comparer{dirA: pathA, dirB: pathB, report: &ReproReport{A: a.ID, B: b.ID}}
+	if a.ID.ObjectHash != b.ID.ObjectHash {
+		if err := c.compare(a, b); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(c.report.Differences, func(i, j int) bool {
+		return c.report.Differences[i].Path < c.report.Differences[j].Path
+	})
+	return c.report, nil
+}
+
+type comparer struct {
+	dirA, dirB string
+	report     *ReproReport
+}
+
+func (c *comparer) add(kind, detail string, a, b *Node) {
+	node := a
+	if node == nil {
+		node = b
+	}
+	c.report.Differences = append(c.report.Differences, ReproDifference{Path: node.Path, Kind: kind, Detail: detail, A: a, B: b})
+}
+
+// compare compares the children of two directories with different hashes.
+func (c *comparer) compare(a, b *Node) error {
+	inB := make(map[string]*Node, len(b.Children))
+	for _, child := range b.Children {
+		inB[child.Name] = child
+	}
+
+	for _, childA := range a.Children {
+		childB, ok := inB[childA.Name]
+		if !ok {
+			c.add(ReproOnlyA, "only in "+c.dirA, childA, nil)
+			continue
+		}
+		delete(inB, childA.Name)
+		if err := c.compareEntry(childA, childB); err != nil {
+			return err
+		}
+	}
+
+	for _, childB := range b.Children {
+		if _, ok := inB[childB.Name]; ok {
+			c.add(ReproOnlyB, "only in "+c.dirB, nil, childB)
+		}
+	}
+	return nil
+}
+
+func (c *comparer) compareEntry(a, b *Node) error {
+	if a.Type == b.Type && a.ID.ObjectHash == b.ID.ObjectHash {
+		return nil
+	}
+
+	isFile := func(n *Node) bool {
+		return n.Type == objects.EntryTypeFile || n.Type == objects.EntryTypeExecutable
+	}
+	switch {
+	case a.Type == objects.EntryTypeDirectory && b.Type == objects.EntryTypeDirectory:
+		return c.compare(a, b)
+	case isFile(a) && isFile(b) && a.ID.ObjectHash == b.ID.ObjectHash:
+		if a.Type == objects.EntryTypeExecutable {
+			c.add(ReproMode, "executable in "+c.dirA+" only", a, b)
+		} else {
+			c.add(ReproMode, "executable in "+c.dirB+" only", a, b)
+		}
+	case isFile(a) && isFile(b):
+		return c.compareContent(a, b)
+	case a.Type != b.Type:
+		c.add(ReproType, fmt.Sprintf("%s in %s, %s in %s", entryKind(a.Type), c.dirA, entryKind(b.Type), c.dirB), a, b)
+	case a.Type == objects.EntryTypeSymlink:
+		c.add(ReproContent, "symbolic links to different targets", a, b)
+	default:
+		c.add(ReproContent, "different commits", a, b)
+	}
+	return nil
+}
+
+// compareContent classifies two files with different content.
+func (c *comparer) compareContent(a, b *Node) error {
+	dataA, err := os.ReadFile(filepath.Join(c.dirA, filepath.FromSlash(a.Path)))
+	if err != nil {
+		return err
+	}
+	dataB, err := os.ReadFile(filepath.Join(c.dirB, filepath.FromSlash(b.Path)))
+	if err != nil {
+		return err
+	}
+
+	// Binary files, by Git's heuristic of a NUL byte near the start, have
+	// no lines to compare.
+	if isBinary(dataA) || isBinary(dataB) {
+		c.add(ReproContent, fmt.Sprintf("binary content differs (%d and %d bytes)", len(dataA), len(dataB)), a, b)
+		return nil
+	}
+
+	lfA := bytes.ReplaceAll(dataA, []byte("\r\n"), []byte("\n"))
+	lfB := bytes.ReplaceAll(dataB, []byte("\r\n"), []byte("\n"))
+	if bytes.Equal(lfA, lfB) {
+		crlf := c.dirA
+		if len(dataB) > len(dataA) {
+			crlf = c.dirB
+		}
+		c.add(ReproEOL, "CRLF line endings in "+crlf, a, b)
+		return nil
+	}
+
+	linesA := bytes.Split(bytes.TrimSuffix(lfA, []byte("\n")), []byte("\n"))
+	linesB := bytes.Split(bytes.TrimSuffix(lfB, []byte("\n")), []byte("\n"))
+	if len(linesA) == len(linesB) {
+		slices.SortFunc(linesA, bytes.Compare)
+		slices.SortFunc(linesB, bytes.Compare)
+		if slices.EqualFunc(linesA, linesB, bytes.Equal) {
+			c.add(ReproOrder, fmt.Sprintf("the same %d lines in another order", len(linesA)), a, b)
+			return nil
+		}
+	}
+
+	c.add(ReproContent, fmt.Sprintf("content differs (%d and %d bytes)", len(dataA), len(dataB)), a, b)
+	return nil
+}
+
+// isBinary reports whether data has a NUL byte in its first 8000 bytes,
+// which is how Git decides a file is not text.
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0
+}
+
+func entryKind(t objects.EntryType) string {
+	switch t {
+	case objects.EntryTypeDirectory:
+		return "directory"
+	case objects.EntryTypeSymlink:
+		return "symbolic link"
+	case objects.EntryTypeRevision:
+		return "submodule"
+	}
+	return "file"
+}
@@ -0,0 +1,76 @@
+package swhid
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompareDirectories(t *testing.T) {
+	write := func(root, name, content string, mode os.FileMode) {
+		t.Helper()
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(content), mode); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	a, b := t.TempDir(), t.TempDir()
+	for _, dir := range []string{a, b} {
+		write(dir, "same.txt", "unchanged\n", 0644)
+		write(dir, "sub/same.txt", "unchanged\n", 0644)
+	}
+	write(a, "build.sh", "#!/bin/sh\n", 0755)
+	write(b, "build.sh", "#!/bin/sh\n", 0644)
+	write(a, "sub/notes.txt", "one\ntwo\n", 0644)
+	write(b, "sub/notes.txt", "one\r\ntwo\r\n", 0644)
+	write(a, "files.list", "a\nb\nc\n", 0644)
+	write(b, "files.list", "c\na\nb\n", 0644)
+	write(a, "version", "1.0\n", 0644)
+	write(b, "version", "1.1\n", 0644)
+	write(a, "stamp", "built\n", 0644)
+	write(b, "sub/extra/file", "new\n", 0644)
+	write(a, "data.bin", "\x00\x01", 0644)
+	write(b, "data.bin", "\x00\x02", 0644)
+
+	report, err := CompareDirectories(a, b, TreeOptions{})
+	if err != nil {
+		t.Fatalf("CompareDirectories() error = %v", err)
+	}
+	if report.A.Equal(report.B) {
+		t.Fatalf("A = B = %v, want different SWHIDs", report.A)
+	}
+
+	want := []struct{ path, kind string }{
+		{"build.sh", ReproMode},
+		{"data.bin", ReproContent},
+		{"files.list", ReproOrder},
+		{"stamp", ReproOnlyA},
+		{"sub/extra", ReproOnlyB},
+		{"sub/notes.txt", ReproEOL},
+		{"version", ReproContent},
+	}
+	if len(report.Differences) != len(want) {
+		t.Fatalf("Differences = %+v, want %d entries", report.Differences, len(want))
+	}
+	for i, w := range want {
+		d := report.Differences[i]
+		if d.Path != w.path || d.Kind != w.kind {
+			t.Errorf("Differences[%d] = %s %s, want %s %s", i, d.Path, d.Kind, w.path, w.kind)
+		}
+	}
+	if d := report.Differences[5]; d.Detail != "CRLF line endings in "+b {
+		t.Errorf("eol Detail = %q", d.Detail)
+	}
+
+	same, err := CompareDirectories(a, a, TreeOptions{})
+	if err != nil {
+		t.Fatalf("CompareDirectories() error = %v", err)
+	}
+	if len(same.Differences) != 0 || !same.A.Equal(same.B) {
+		t.Errorf("CompareDirectories(a, a) = %+v, want no differences", same)
+	}
+}
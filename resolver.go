@@ -0,0 +1,193 @@
+package swhid
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrUnresolved is returned by a Resolver that does not know an origin or
+// an anchor, or has no object at the requested path.
+var ErrUnresolved = errors.New("cannot resolve qualifier")
+
+// ErrQualifierMismatch is returned by ValidateQualifiers when the context
+// qualifiers point to a different object than the identifier itself.
+var ErrQualifierMismatch = errors.New("qualifiers do not point to the identified object")
+
+// Resolver answers the lookups needed to check and complete context
+// qualifiers. A RepoSession resolves against a local repository, an
+// archive.Client against the Software Heritage API, and a StaticResolver
+// against fixed tables; NewCachingResolver puts any of them behind an LRU
+// cache.
+type Resolver interface {
+	// LatestSnapshot returns the SWHID of the snapshot taken by the most
+	// recent visit of an origin; qualifiers on it are ignored.
+	LatestSnapshot(ctx context.Context, origin string) (*Identifier, error)
+
+	// Lookup returns the core SWHID of the object at a slash-separated path
+	// in the root directory of anchor, a snapshot, release, revision or
+	// directory. An empty path, or "/", names the root directory itself.
+	// The root directory of a snapshot is that of its HEAD branch.
+	Lookup(ctx context.Context, anchor *Identifier, path string) (*Identifier, error)
+}
+
+// ValidateQualifiers checks that the anchor and path qualifiers of id lead
+// to id itself. The visit qualifier serves as anchor when there is no
+// anchor. Identifiers without a path qualifier, or without an anchor or
+// visit to resolve it against, are left unchecked.
+func ValidateQualifiers(ctx context.Context, id *Identifier, r Resolver) error {
+	path, ok := id.Qualifiers[QualifierPath]
+	if !ok {
+		return nil
+	}
+	anchorSWHID := id.Qualifiers[QualifierAnchor]
+	if anchorSWHID == "" {
+		anchorSWHID = id.Qualifiers[QualifierVisit]
+	}
+	if anchorSWHID == "" {
+		return nil
+	}
+
+	anchor, err := Parse(anchorSWHID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidQualifier, err)
+	}
+	got, err := r.Lookup(ctx, anchor, path)
+	if err != nil {
+		return err
+	}
+	if got.CoreSWHID() != id.CoreSWHID() {
+		return fmt.Errorf("%w: %s in %s is %s", ErrQualifierMismatch, path, anchor.CoreSWHID(), got.CoreSWHID())
+	}
+	return nil
+}
+
+// HydrateQualifiers returns a copy of id with the context qualifiers it
+// lacks and r can supply: the visit of an origin is its latest snapshot.
+// Qualifiers already present are kept as they are.
+func HydrateQualifiers(ctx context.Context, id *Identifier, r Resolver) (*Identifier, error) {
+	qualifiers := make(map[string]string, len(id.Qualifiers)+1)
+	for k, v := range id.Qualifiers {
+		qualifiers[k] = v
+	}
+
+	if origin := qualifiers[QualifierOrigin]; origin != "" && qualifiers[QualifierVisit] == "" {
+		visit, err := r.LatestSnapshot(ctx, origin)
+		if err != nil {
+			return nil, err
+		}
+		qualifiers[QualifierVisit] = visit.CoreSWHID()
+	}
+	return id.WithQualifiers(qualifiers), nil
+}
+
+// StaticResolver resolves from fixed tables, for tests and for offline
+// checks against a known set of objects.
+type StaticResolver struct {
+	Snapshots map[string]*Identifier            // latest snapshot by origin URL
+	Objects   map[string]map[string]*Identifier // by anchor core SWHID, then path without leading or trailing slash
+}
+
+// LatestSnapshot implements Resolver.
+func (r StaticResolver) LatestSnapshot(ctx context.Context, origin string) (*Identifier, error) {
+	if id, ok := r.Snapshots[origin]; ok {
+		return id, nil
+	}
+	return nil, fmt.Errorf("%w: no snapshot of %s", ErrUnresolved, origin)
+}
+
+// Lookup implements Resolver.
+func (r StaticResolver) Lookup(ctx context.Context, anchor *Identifier, path string) (*Identifier, error) {
+	if id, ok := r.Objects[anchor.CoreSWHID()][strings.Trim(path, "/")]; ok {
+		return id, nil
+	}
+	return nil, fmt.Errorf("%w: %s not found in %s", ErrUnresolved, path, anchor.CoreSWHID())
+}
+
+// CachingResolver remembers the answers of another Resolver, evicting the
+// least recently used once it holds its size. Answers that ErrUnresolved
+// are remembered too; other errors, such as network failures, are not. A
+// CachingResolver is safe for concurrent use; concurrent misses on the same
+// key may each reach the underlying resolver.
+type CachingResolver struct {
+	resolver Resolver
+	size     int
+
+	mu      sync.Mutex
+	order   *list.List // of *resolverEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+type resolverEntry struct {
+	key string
+	id  *Identifier
+	err error
+}
+
+// NewCachingResolver returns a resolver that caches up to size answers of
+// r.
+func NewCachingResolver(r Resolver, size int) *CachingResolver {
+	if size < 1 {
+		size = 1
+	}
+	return &CachingResolver{
+		resolver: r,
+		size:     size,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// LatestSnapshot implements Resolver.
+func (c *CachingResolver) LatestSnapshot(ctx context.Context, origin string) (*Identifier, error) {
+	return c.cached("snp\x00"+origin, func() (*Identifier, error) {
+		return c.resolver.LatestSnapshot(ctx, origin)
+	})
+}
+
+// Lookup implements Resolver.
+func (c *CachingResolver) Lookup(ctx context.Context, anchor *Identifier, path string) (*Identifier, error) {
+	return c.cached("obj\x00"+anchor.CoreSWHID()+"\x00"+strings.Trim(path, "/"), func() (*Identifier, error) {
+		return c.resolver.Lookup(ctx, anchor, path)
+	})
+}
+
+// Len returns the number of cached answers.
+func (c *CachingResolver) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *CachingResolver) cached(key string, resolve func() (*Identifier, error)) (*Identifier, error) {
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		entry := elem.Value.(*resolverEntry)
+		c.mu.Unlock()
+		return entry.id, entry.err
+	}
+	c.mu.Unlock()
+
+	id, err := resolve()
+	if err != nil && !errors.Is(err, ErrUnresolved) {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		return id, err
+	}
+	c.entries[key] = c.order.PushFront(&resolverEntry{key: key, id: id, err: err})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*resolverEntry).key)
+	}
+	return id, err
+}
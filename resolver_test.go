@@ -0,0 +1,160 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5/config"
+)
+
+// countingResolver counts the lookups that reach it.
+type countingResolver struct {
+	Resolver
+	calls int
+	err   error
+}
+
+func (r *countingResolver) LatestSnapshot(ctx context.Context, origin string) (*Identifier, error) {
+	r.calls++
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.Resolver.LatestSnapshot(ctx, origin)
+}
+
+func (r *countingResolver) Lookup(ctx context.Context, anchor *Identifier, path string) (*Identifier, error) {
+	r.calls++
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.Resolver.Lookup(ctx, anchor, path)
+}
+
+func TestValidateQualifiers(t *testing.T) {
+	dir, repo, _ := newTestRepo(t)
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	commitAll(t, repo, "Add main\n")
+
+	session := NewRepoSession(repo, GitOptions{})
+	rev, _ := session.Revision("HEAD")
+	snp, _ := session.Snapshot()
+	srcDir, _ := session.Tree("HEAD", "src")
+	main := FromContent([]byte("package main\n"))
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		swhid   string
+		wantErr error
+	}{
+		{"anchor and path", main.CoreSWHID() + ";anchor=" + rev.CoreSWHID() + ";path=/src/main.go", nil},
+		{"directory", srcDir.CoreSWHID() + ";anchor=" + rev.CoreSWHID() + ";path=/src/", nil},
+		{"visit as anchor", main.CoreSWHID() + ";visit=" + snp.CoreSWHID() + ";path=/src/main.go", nil},
+		{"root tree anchor", main.CoreSWHID() + ";anchor=swh:1:dir:" + srcDir.ObjectHash + ";path=/main.go", nil},
+		{"no path", main.CoreSWHID() + ";anchor=" + rev.CoreSWHID(), nil},
+		{"wrong object", main.CoreSWHID() + ";anchor=" + rev.CoreSWHID() + ";path=/hello.txt", ErrQualifierMismatch},
+		{"missing path", main.CoreSWHID() + ";anchor=" + rev.CoreSWHID() + ";path=/missing.go", ErrUnresolved},
+		{"unknown anchor", main.CoreSWHID() + ";anchor=swh:1:rev:0000000000000000000000000000000000000000;path=/src/main.go", ErrUnresolved},
+		{"old snapshot", main.CoreSWHID() + ";visit=swh:1:snp:0000000000000000000000000000000000000000;path=/src/main.go", ErrUnresolved},
+	}
+	for _, tt := range tests {
+		id, err := Parse(tt.swhid)
+		if err != nil {
+			t.Fatalf("%s: Parse() error = %v", tt.name, err)
+		}
+		err = ValidateQualifiers(ctx, id, session)
+		if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: ValidateQualifiers() error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestHydrateQualifiers(t *testing.T) {
+	_, repo, _ := newTestRepo(t)
+	if _, err := repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{"git@github.com:example/repo.git"}}); err != nil {
+		t.Fatalf("CreateRemote() error = %v", err)
+	}
+	session := NewRepoSession(repo, GitOptions{})
+	snp, _ := session.Snapshot()
+	ctx := context.Background()
+
+	id, _ := Parse(FromContent([]byte("hello\n")).CoreSWHID() + ";origin=https://github.com/example/repo;path=/hello.txt")
+	got, err := HydrateQualifiers(ctx, id, session)
+	if err != nil {
+		t.Fatalf("HydrateQualifiers() error = %v", err)
+	}
+	if got.Qualifiers[QualifierVisit] != snp.CoreSWHID() || got.Qualifiers[QualifierPath] != "/hello.txt" {
+		t.Errorf("HydrateQualifiers() = %s", got)
+	}
+	if _, ok := id.Qualifiers[QualifierVisit]; ok {
+		t.Error("HydrateQualifiers() modified its argument")
+	}
+	if err := ValidateQualifiers(ctx, got, session); err != nil {
+		t.Errorf("ValidateQualifiers() of hydrated SWHID error = %v", err)
+	}
+
+	other, _ := Parse(id.CoreSWHID() + ";origin=https://example.com/other")
+	if _, err := HydrateQualifiers(ctx, other, session); !errors.Is(err, ErrUnresolved) {
+		t.Errorf("HydrateQualifiers() for another origin error = %v, want ErrUnresolved", err)
+	}
+}
+
+func TestCachingResolver(t *testing.T) {
+	anchor, _ := Parse("swh:1:rev:309cf2674ee7a0749978cf8265ab91a60aea0f7d")
+	file := FromContent([]byte("hello\n"))
+	backend := &countingResolver{Resolver: StaticResolver{
+		Snapshots: map[string]*Identifier{"https://example.com/repo": FromSnapshotBranches(nil)},
+		Objects: map[string]map[string]*Identifier{
+			anchor.CoreSWHID(): {"hello.txt": file, "a": file, "b": file},
+		},
+	}}
+	r := NewCachingResolver(backend, 2)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		got, err := r.Lookup(ctx, anchor, "/hello.txt")
+		if err != nil || !got.Equal(file) {
+			t.Fatalf("Lookup() = %v, %v", got, err)
+		}
+	}
+	if backend.calls != 1 {
+		t.Errorf("backend called %d times, want 1", backend.calls)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.Lookup(ctx, anchor, "missing"); !errors.Is(err, ErrUnresolved) {
+			t.Errorf("Lookup() error = %v, want ErrUnresolved", err)
+		}
+	}
+	if backend.calls != 2 {
+		t.Errorf("unresolved answer was not cached: %d calls", backend.calls)
+	}
+
+	// hello.txt is now the least recently used and is evicted.
+	r.LatestSnapshot(ctx, "https://example.com/repo")
+	if r.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", r.Len())
+	}
+	r.Lookup(ctx, anchor, "hello.txt")
+	if backend.calls != 4 {
+		t.Errorf("evicted answer was served from the cache: %d calls", backend.calls)
+	}
+
+	backend.err = errors.New("network down")
+	for i := 0; i < 2; i++ {
+		r.Lookup(ctx, anchor, "a")
+	}
+	if backend.calls != 6 {
+		t.Errorf("transient error was cached: %d calls", backend.calls)
+	}
+}
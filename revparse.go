@@ -0,0 +1,764 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+var (
+	// ErrUnsupportedRevision is wrapped by a *RevisionError for revision
+	// syntax git accepts but this package does not resolve, such as
+	// @{push} or approximate dates like @{yesterday}.
+	ErrUnsupportedRevision = errors.New("revision syntax not supported")
+
+	// ErrAmbiguousRevision is wrapped by a *RevisionError for an
+	// abbreviated object name shared by several objects.
+	ErrAmbiguousRevision = errors.New("ambiguous object name")
+)
+
+// RevisionError is returned when a revision expression cannot be resolved.
+// It wraps the cause: plumbing.ErrReferenceNotFound for a name that is
+// neither a reference nor an object, ErrUnsupportedRevision,
+// ErrAmbiguousRevision, or an error reading the repository.
+type RevisionError struct {
+	Rev  string // the whole expression, e.g. "main~3^2:src"
+	Part string // the part of it that failed, e.g. "^2"; empty for the whole
+	Err  error
+}
+
+func (e *RevisionError) Error() string {
+	if e.Part == "" || e.Part == e.Rev {
+		return fmt.Sprintf("cannot resolve revision %q: %v", e.Rev, e.Err)
+	}
+	return fmt.Sprintf("cannot resolve %q in revision %q: %v", e.Part, e.Rev, e.Err)
+}
+
+func (e *RevisionError) Unwrap() error {
+	return e.Err
+}
+
+// resolveRevisionTo resolves rev like resolveRevision and peels the object
+// it names, through tags and from a commit to its tree, to an object of
+// type want.
+func resolveRevisionTo(repo *git.Repository, rev string, want plumbing.ObjectType) (plumbing.Hash, error) {
+	hash, err := resolveRevision(repo, rev)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	hash, err = peel(repo, hash, want)
+	if err != nil {
+		return plumbing.ZeroHash, &RevisionError{Rev: rev, Err: err}
+	}
+	return hash, nil
+}
+
+// resolveRevision returns the object a revision expression names, with
+// the syntax of git rev-parse described in gitrevisions(7):
+//
+//   - object names, full or abbreviated, and reference names, expanded as
+//     git does ("main", "heads/main", "origin" for refs/remotes/origin/HEAD),
+//     with "@" for HEAD
+//   - reflog entries: "stash@{2}", "main@{2024-01-31}", "@{1}" for the
+//     current branch, "@{-1}" for the branch checked out before it, and
+//     "main@{upstream}"
+//   - ancestry and peeling suffixes: "~3", "^2", "^{tree}", "^{}",
+//     "^{/fix}"
+//   - ":/fix", the youngest commit reachable from any reference whose
+//     message matches a regular expression
+//   - "rev:path" for an object in a tree, and ":path" or ":2:path" for a
+//     stage of the index
+//
+// Anything else is a *RevisionError.
+func resolveRevision(repo *git.Repository, rev string) (plumbing.Hash, error) {
+	r := &revParser{repo: repo, rev: rev}
+	hash, err := r.resolve()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return hash, nil
+}
+
+type revParser struct {
+	repo *git.Repository
+	rev  string
+}
+
+func (r *revParser) fail(part string, err error) (plumbing.Hash, error) {
+	return plumbing.ZeroHash, &RevisionError{Rev: r.rev, Part: part, Err: err}
+}
+
+func (r *revParser) resolve() (plumbing.Hash, error) {
+	if r.rev == "" {
+		return r.fail("", plumbing.ErrReferenceNotFound)
+	}
+
+	if pattern, ok := strings.CutPrefix(r.rev, ":/"); ok {
+		starts, err := r.refTips()
+		if err != nil {
+			return r.fail("", err)
+		}
+		hash, err := youngestMatch(r.repo, starts, pattern)
+		if err != nil {
+			return r.fail("", err)
+		}
+		return hash, nil
+	}
+	if index, ok := strings.CutPrefix(r.rev, ":"); ok {
+		return r.indexEntry(index)
+	}
+
+	expr, treePath, hasPath := splitTreePath(r.rev)
+	hash, err := r.expression(expr)
+	if err != nil || !hasPath {
+		return hash, err
+	}
+
+	tree, err := peel(r.repo, hash, plumbing.TreeObject)
+	if err != nil {
+		return r.fail(expr, err)
+	}
+	treePath = strings.Trim(treePath, "/")
+	if treePath == "" {
+		return tree, nil
+	}
+	if strings.HasPrefix(treePath, "./") || strings.HasPrefix(treePath, "../") {
+		return r.fail(":"+treePath, fmt.Errorf("%w: paths relative to the working directory", ErrUnsupportedRevision))
+	}
+	treeObj, err := r.repo.TreeObject(tree)
+	if err != nil {
+		return r.fail(expr, err)
+	}
+	entry, err := treeObj.FindEntry(treePath)
+	if err != nil {
+		return r.fail(":"+treePath, fmt.Errorf("path %s not found in %s: %w", treePath, expr, err))
+	}
+	return entry.Hash, nil
+}
+
+// splitTreePath splits "rev:path" at the first colon outside braces, which
+// may hold dates or regular expressions.
+func splitTreePath(rev string) (expr, treePath string, ok bool) {
+	depth := 0
+	for i, c := range rev {
+		switch c {
+		case '{':
+			depth++
+		case '}':
+			if depth > 0 {
+				depth--
+			}
+		case ':':
+			if depth == 0 {
+				return rev[:i], rev[i+1:], true
+			}
+		}
+	}
+	return rev, "", false
+}
+
+// expression resolves a name, an optional reflog selector and a chain of
+// ancestry and peeling suffixes.
+func (r *revParser) expression(expr string) (plumbing.Hash, error) {
+	end := strings.IndexAny(expr, "^~")
+	if end < 0 {
+		end = len(expr)
+	}
+	name, suffixes := expr[:end], expr[end:]
+
+	var hash plumbing.Hash
+	var err error
+	if at := strings.Index(name, "@{"); at >= 0 {
+		if !strings.HasSuffix(name, "}") {
+			return r.fail(name, errors.New("invalid reflog selector"))
+		}
+		hash, err = r.reflogSelector(name[:at], name[at+2:len(name)-1])
+		if err != nil {
+			return r.fail(name, err)
+		}
+	} else {
+		if name == "@" {
+			name = "HEAD"
+		}
+		hash, err = r.name(name)
+		if err != nil {
+			return r.fail(name, err)
+		}
+	}
+
+	for suffixes != "" {
+		op := suffixes[0]
+		rest := suffixes[1:]
+
+		if op == '^' && strings.HasPrefix(rest, "{") {
+			end := closingBrace(rest)
+			if end < 0 {
+				return r.fail(suffixes, errors.New("unterminated ^{"))
+			}
+			part := suffixes[:end+2]
+			hash, err = r.peelSuffix(hash, rest[1:end])
+			if err != nil {
+				return r.fail(part, err)
+			}
+			suffixes = rest[end+1:]
+			continue
+		}
+
+		digits := len(rest) - len(strings.TrimLeft(rest, "0123456789"))
+		n := 1
+		if digits > 0 {
+			if n, err = strconv.Atoi(rest[:digits]); err != nil {
+				return r.fail(suffixes, err)
+			}
+		}
+		part := suffixes[:1+digits]
+		suffixes = rest[digits:]
+
+		switch op {
+		case '~':
+			for i := 0; i < n && err == nil; i++ {
+				hash, err = r.parent(hash, 1)
+			}
+		case '^':
+			hash, err = r.parent(hash, n)
+		default:
+			err = errors.New("invalid revision syntax")
+		}
+		if err != nil {
+			return r.fail(part, err)
+		}
+	}
+	return hash, nil
+}
+
+// closingBrace returns the index in s, which starts with "{", of the brace
+// closing it, or -1.
+func closingBrace(s string) int {
+	depth := 0
+	for i, c := range s {
+		switch c {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// name resolves an object name or a reference name. As with git, a full
+// object name wins over a reference of the same name, and a reference
+// over an abbreviated object name.
+func (r *revParser) name(name string) (plumbing.Hash, error) {
+	if len(name) == ObjectIDLen && plumbing.IsHash(name) {
+		return plumbing.NewHash(name), nil
+	}
+	if ref, err := r.reference(name); err == nil {
+		return ref.Hash(), nil
+	} else if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return plumbing.ZeroHash, err
+	}
+	if len(name) >= 4 && isHex(name) {
+		hashes := objectsWithPrefix(r.repo, name)
+		switch len(hashes) {
+		case 0:
+		case 1:
+			return hashes[0], nil
+		default:
+			return plumbing.ZeroHash, fmt.Errorf("%w: %s matches %d objects", ErrAmbiguousRevision, name, len(hashes))
+		}
+	}
+	return plumbing.ZeroHash, plumbing.ErrReferenceNotFound
+}
+
+// reference expands name with git's rules, so "main" finds
+// refs/heads/main and "origin" refs/remotes/origin/HEAD, and resolves it.
+func (r *revParser) reference(name string) (*plumbing.Reference, error) {
+	if name == "" {
+		return nil, plumbing.ErrReferenceNotFound
+	}
+	for _, rule := range plumbing.RefRevParseRules {
+		ref, err := storer.ResolveReference(r.repo.Storer, plumbing.ReferenceName(fmt.Sprintf(rule, name)))
+		if err == nil {
+			return ref, nil
+		}
+		if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return nil, err
+		}
+	}
+	return nil, plumbing.ErrReferenceNotFound
+}
+
+// objectsWithPrefix returns the objects whose name starts with the hex
+// digits of prefix.
+func objectsWithPrefix(repo *git.Repository, prefix string) []plumbing.Hash {
+	prefix = strings.ToLower(prefix)
+	var hashes []plumbing.Hash
+	match := func(h plumbing.Hash) {
+		if strings.HasPrefix(h.String(), prefix) {
+			hashes = append(hashes, h)
+		}
+	}
+
+	// Loose and packed object storage can list candidates by prefix
+	// without reading every object.
+	type prefixLister interface {
+		HashesWithPrefix(prefix []byte) ([]plumbing.Hash, error)
+	}
+	if lister, ok := repo.Storer.(prefixLister); ok {
+		even, err := hex.DecodeString(prefix[:len(prefix)&^1])
+		if err != nil {
+			return nil
+		}
+		candidates, err := lister.HashesWithPrefix(even)
+		if err != nil {
+			return nil
+		}
+		for _, h := range candidates {
+			match(h)
+		}
+		return hashes
+	}
+
+	iter, err := repo.Storer.IterEncodedObjects(plumbing.AnyObject)
+	if err != nil {
+		return nil
+	}
+	iter.ForEach(func(obj plumbing.EncodedObject) error {
+		match(obj.Hash())
+		return nil
+	})
+	return hashes
+}
+
+// reflogSelector resolves name@{selector}: an entry of the reference's
+// reflog by position or date, the upstream branch, or with no name a
+// previous checkout.
+func (r *revParser) reflogSelector(name, selector string) (plumbing.Hash, error) {
+	if name == "@" {
+		name = "HEAD"
+	}
+
+	switch strings.ToLower(selector) {
+	case "u", "upstream":
+		return r.upstream(name)
+	case "push":
+		return plumbing.ZeroHash, fmt.Errorf("%w: @{push}", ErrUnsupportedRevision)
+	}
+
+	if digits, ok := strings.CutPrefix(selector, "-"); ok {
+		n, err := strconv.Atoi(digits)
+		if err != nil || n < 1 {
+			return plumbing.ZeroHash, fmt.Errorf("invalid checkout selector @{%s}", selector)
+		}
+		if name != "" {
+			return plumbing.ZeroHash, fmt.Errorf("@{-%d} cannot follow a name", n)
+		}
+		return r.previousCheckout(n)
+	}
+
+	refName, err := r.reflogName(name)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	entries, err := readReflog(r.repo, refName)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if len(entries) == 0 {
+		return plumbing.ZeroHash, fmt.Errorf("reflog of %s is empty", refName)
+	}
+
+	if n, err := strconv.Atoi(selector); err == nil && n >= 0 {
+		// @{0} is the newest entry; one past the oldest is the value the
+		// oldest entry changed from.
+		switch {
+		case n < len(entries):
+			return entries[len(entries)-1-n].New, nil
+		case n == len(entries) && !entries[0].Old.IsZero():
+			return entries[0].Old, nil
+		default:
+			return plumbing.ZeroHash, fmt.Errorf("reflog of %s has no entry %d", refName, n)
+		}
+	}
+
+	at, err := parseReflogDate(selector)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !entries[i].When.After(at) {
+			return entries[i].New, nil
+		}
+	}
+	if !entries[0].Old.IsZero() {
+		return entries[0].Old, nil
+	}
+	return plumbing.ZeroHash, fmt.Errorf("reflog of %s only goes back to %s", refName, entries[0].When.Format(time.RFC3339))
+}
+
+// parseReflogDate accepts the dates of @{date} that are not git's
+// approximate dates: RFC 3339, "YYYY-MM-DD HH:MM:SS" and "YYYY-MM-DD" in
+// local time.
+func parseReflogDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: date %q (use RFC 3339 or YYYY-MM-DD [HH:MM:SS])", ErrUnsupportedRevision, s)
+}
+
+// reflogName returns the reference whose reflog name@{...} reads: the
+// current branch, or HEAD when detached, for an empty name, and otherwise
+// the first expansion of name that has a reflog.
+func (r *revParser) reflogName(name string) (plumbing.ReferenceName, error) {
+	if name == "" {
+		head, err := r.repo.Storer.Reference(plumbing.HEAD)
+		if err != nil {
+			return "", err
+		}
+		if head.Type() == plumbing.SymbolicReference {
+			return head.Target(), nil
+		}
+		return plumbing.HEAD, nil
+	}
+
+	fs, err := reflogFS(r.repo)
+	if err != nil {
+		return "", err
+	}
+	for _, rule := range plumbing.RefRevParseRules {
+		refName := plumbing.ReferenceName(fmt.Sprintf(rule, name))
+		if _, err := fs.Stat(path.Join("logs", refName.String())); err == nil {
+			return refName, nil
+		}
+	}
+	if ref, err := r.reference(name); err == nil {
+		return "", fmt.Errorf("no reflog for %s", ref.Name())
+	}
+	return "", plumbing.ErrReferenceNotFound
+}
+
+// upstream resolves the remote-tracking branch that branch name, or the
+// current branch for an empty name or HEAD, is configured to merge.
+func (r *revParser) upstream(name string) (plumbing.Hash, error) {
+	var branch plumbing.ReferenceName
+	if name == "" || name == "HEAD" {
+		head, err := r.repo.Storer.Reference(plumbing.HEAD)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		if head.Type() != plumbing.SymbolicReference {
+			return plumbing.ZeroHash, errors.New("HEAD does not point to a branch")
+		}
+		branch = head.Target()
+	} else {
+		branch = plumbing.NewBranchReferenceName(strings.TrimPrefix(strings.TrimPrefix(name, "refs/"), "heads/"))
+	}
+
+	cfg, err := r.repo.Config()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	b := cfg.Branches[branch.Short()]
+	if b == nil || b.Merge == "" {
+		return plumbing.ZeroHash, fmt.Errorf("no upstream configured for branch %s", branch.Short())
+	}
+
+	tracking := b.Merge
+	if b.Remote != "." {
+		remote := cfg.Remotes[b.Remote]
+		if remote == nil {
+			return plumbing.ZeroHash, fmt.Errorf("upstream remote %s of branch %s is not configured", b.Remote, branch.Short())
+		}
+		tracking = ""
+		for _, spec := range remote.Fetch {
+			if spec.Match(b.Merge) {
+				tracking = spec.Dst(b.Merge)
+				break
+			}
+		}
+		if tracking == "" {
+			return plumbing.ZeroHash, fmt.Errorf("upstream %s of branch %s is not fetched by remote %s", b.Merge, branch.Short(), b.Remote)
+		}
+	}
+
+	ref, err := storer.ResolveReference(r.repo.Storer, tracking)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("upstream %s: %w", tracking, err)
+	}
+	return ref.Hash(), nil
+}
+
+// previousCheckout resolves @{-n}, the branch or commit checked out n
+// checkouts ago, from the reflog of HEAD.
+func (r *revParser) previousCheckout(n int) (plumbing.Hash, error) {
+	entries, err := readReflog(r.repo, plumbing.HEAD)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	found := 0
+	for i := len(entries) - 1; i >= 0; i-- {
+		moved, ok := strings.CutPrefix(entries[i].Message, "checkout: moving from ")
+		if !ok {
+			continue
+		}
+		from, _, ok := strings.Cut(moved, " to ")
+		if !ok {
+			continue
+		}
+		if found++; found == n {
+			return r.name(from)
+		}
+	}
+	return plumbing.ZeroHash, fmt.Errorf("reflog of HEAD has no checkout %d", n)
+}
+
+// reflogFS returns the filesystem of a repository's git directory, where
+// reflogs are kept.
+func reflogFS(repo *git.Repository) (billy.Filesystem, error) {
+	storage, ok := repo.Storer.(*filesystem.Storage)
+	if !ok {
+		return nil, fmt.Errorf("reflogs are only available for repositories on disk")
+	}
+	return storage.Filesystem(), nil
+}
+
+// readReflog returns every entry of the reflog of name, oldest first.
+func readReflog(repo *git.Repository, name plumbing.ReferenceName) ([]reflogEntry, error) {
+	fs, err := reflogFS(repo)
+	if err != nil {
+		return nil, err
+	}
+	f, err := fs.Open(path.Join("logs", name.String()))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no reflog for %s", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open reflog of %s: %w", name, err)
+	}
+	defer f.Close()
+
+	entries, err := parseReflog(f, time.Time{})
+	if err != nil {
+		return nil, fmt.Errorf("reflog of %s: %w", name, err)
+	}
+	return entries, nil
+}
+
+// peelSuffix applies ^{...}: ^{} peels tags, ^{type} peels to an object
+// of that type, and ^{/regex} finds the youngest commit reachable from
+// hash whose message matches.
+func (r *revParser) peelSuffix(hash plumbing.Hash, inner string) (plumbing.Hash, error) {
+	if pattern, ok := strings.CutPrefix(inner, "/"); ok {
+		commit, err := peel(r.repo, hash, plumbing.CommitObject)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		return youngestMatch(r.repo, []plumbing.Hash{commit}, pattern)
+	}
+
+	switch inner {
+	case "":
+		return peel(r.repo, hash, plumbing.AnyObject)
+	case "object":
+		if _, err := r.repo.Storer.EncodedObject(plumbing.AnyObject, hash); err != nil {
+			return plumbing.ZeroHash, err
+		}
+		return hash, nil
+	}
+	want, err := plumbing.ParseObjectType(inner)
+	if err != nil || want == plumbing.OFSDeltaObject || want == plumbing.REFDeltaObject {
+		return plumbing.ZeroHash, fmt.Errorf("unknown object type %q", inner)
+	}
+	return peel(r.repo, hash, want)
+}
+
+// peel follows tags from hash, and a commit to its tree, until it reaches
+// an object of type want, or for plumbing.AnyObject the first object that
+// is not a tag.
+func peel(repo *git.Repository, hash plumbing.Hash, want plumbing.ObjectType) (plumbing.Hash, error) {
+	for {
+		obj, err := repo.Storer.EncodedObject(plumbing.AnyObject, hash)
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("object %s: %w", hash, err)
+		}
+		typ := obj.Type()
+		if typ == want || want == plumbing.AnyObject && typ != plumbing.TagObject {
+			return hash, nil
+		}
+
+		switch {
+		case typ == plumbing.TagObject:
+			tag, err := object.DecodeTag(repo.Storer, obj)
+			if err != nil {
+				return plumbing.ZeroHash, err
+			}
+			hash = tag.Target
+		case typ == plumbing.CommitObject && want == plumbing.TreeObject:
+			commit, err := object.DecodeCommit(repo.Storer, obj)
+			if err != nil {
+				return plumbing.ZeroHash, err
+			}
+			hash = commit.TreeHash
+		default:
+			return plumbing.ZeroHash, fmt.Errorf("%s is a %s, not a %s", hash.String()[:7], typ, want)
+		}
+	}
+}
+
+// parent returns the nth parent of the commit hash peels to, or the commit
+// itself for n == 0.
+func (r *revParser) parent(hash plumbing.Hash, n int) (plumbing.Hash, error) {
+	hash, err := peel(r.repo, hash, plumbing.CommitObject)
+	if err != nil || n == 0 {
+		return hash, err
+	}
+	commit, err := r.repo.CommitObject(hash)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if n > len(commit.ParentHashes) {
+		if len(commit.ParentHashes) == 0 {
+			return plumbing.ZeroHash, fmt.Errorf("commit %s has no parents", hash.String()[:7])
+		}
+		return plumbing.ZeroHash, fmt.Errorf("commit %s has no parent %d", hash.String()[:7], n)
+	}
+	return commit.ParentHashes[n-1], nil
+}
+
+// refTips returns the commits HEAD and every reference point to, for
+// :/regex.
+func (r *revParser) refTips() ([]plumbing.Hash, error) {
+	var tips []plumbing.Hash
+	if head, err := storer.ResolveReference(r.repo.Storer, plumbing.HEAD); err == nil {
+		tips = append(tips, head.Hash())
+	}
+	iter, err := r.repo.References()
+	if err != nil {
+		return nil, err
+	}
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference {
+			tips = append(tips, ref.Hash())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []plumbing.Hash
+	for _, tip := range tips {
+		if commit, err := peel(r.repo, tip, plumbing.CommitObject); err == nil {
+			commits = append(commits, commit)
+		}
+	}
+	return commits, nil
+}
+
+// youngestMatch walks the history of starts, newest committer date first as
+// git does, and returns the first commit whose message matches pattern. A
+// pattern starting with "!-" matches messages that do not match the rest,
+// and "!!" stands for a literal "!".
+func youngestMatch(repo *git.Repository, starts []plumbing.Hash, pattern string) (plumbing.Hash, error) {
+	negate := false
+	switch {
+	case strings.HasPrefix(pattern, "!-"):
+		negate, pattern = true, pattern[2:]
+	case strings.HasPrefix(pattern, "!!"):
+		pattern = pattern[1:]
+	case strings.HasPrefix(pattern, "!"):
+		return plumbing.ZeroHash, fmt.Errorf("%w: %q", ErrUnsupportedRevision, pattern)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	seen := make(map[plumbing.Hash]bool)
+	var queue []*object.Commit
+	push := func(hash plumbing.Hash) {
+		if seen[hash] {
+			return
+		}
+		seen[hash] = true
+		// Parents missing from a shallow clone end the walk there.
+		if commit, err := repo.CommitObject(hash); err == nil {
+			queue = append(queue, commit)
+		}
+	}
+	for _, hash := range starts {
+		push(hash)
+	}
+
+	for len(queue) > 0 {
+		newest := 0
+		for i, c := range queue {
+			if c.Committer.When.After(queue[newest].Committer.When) {
+				newest = i
+			}
+		}
+		commit := queue[newest]
+		queue = append(queue[:newest], queue[newest+1:]...)
+
+		if re.MatchString(commit.Message) != negate {
+			return commit.Hash, nil
+		}
+		for _, parent := range commit.ParentHashes {
+			push(parent)
+		}
+	}
+	return plumbing.ZeroHash, fmt.Errorf("no commit message matches %q", re.String())
+}
+
+// indexEntry resolves :path and :n:path, the blob staged at path in stage
+// n (0 when not given) of the index.
+func (r *revParser) indexEntry(spec string) (plumbing.Hash, error) {
+	stage := 0
+	if len(spec) >= 2 && spec[1] == ':' && '0' <= spec[0] && spec[0] <= '3' {
+		stage, spec = int(spec[0]-'0'), spec[2:]
+	}
+
+	idx, err := r.repo.Storer.Index()
+	if err != nil {
+		return r.fail("", fmt.Errorf("failed to read index: %w", err))
+	}
+	staged := false
+	for _, e := range idx.Entries {
+		if e.Name != spec {
+			continue
+		}
+		if int(e.Stage) == stage {
+			return e.Hash, nil
+		}
+		staged = true
+	}
+	if staged {
+		return r.fail("", fmt.Errorf("path %s is in the index, but not at stage %d", spec, stage))
+	}
+	return r.fail("", fmt.Errorf("path %s not in the index: %w", spec, plumbing.ErrObjectNotFound))
+}
@@ -0,0 +1,170 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestResolveRevision(t *testing.T) {
+	repoPath, repo, first := newTestRepo(t)
+	if err := os.MkdirAll(filepath.Join(repoPath, "src"), 0755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoPath, "src", "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	second := commitAll(t, repo, "Fix typo in hello\n")
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Worktree() error = %v", err)
+	}
+	sig := &object.Signature{Name: "Test", Email: "test@example.com", When: time.Unix(1000000100, 0)}
+	merge, err := wt.Commit("Merge branch 'side'\n", &git.CommitOptions{
+		Author: sig, Committer: sig, Parents: []plumbing.Hash{second, first}, AllowEmptyCommits: true,
+	})
+	if err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	tag, err := repo.CreateTag("v1", second, &git.CreateTagOptions{Tagger: sig, Message: "v1\n"})
+	if err != nil {
+		t.Fatalf("CreateTag() error = %v", err)
+	}
+
+	// A stash of two entries, the newest being the merge.
+	if err := repo.Storer.SetReference(plumbing.NewHashReference("refs/stash", merge)); err != nil {
+		t.Fatalf("SetReference() error = %v", err)
+	}
+	writeReflog(t, repoPath, "refs/stash",
+		second, int64(1000000050), "WIP on master: older",
+		merge, int64(1000000150), "WIP on master: newer")
+
+	commit, err := repo.CommitObject(second)
+	if err != nil {
+		t.Fatalf("CommitObject() error = %v", err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		t.Fatalf("Tree() error = %v", err)
+	}
+	src, err := tree.FindEntry("src")
+	if err != nil {
+		t.Fatalf("FindEntry() error = %v", err)
+	}
+
+	tests := []struct {
+		rev  string
+		want plumbing.Hash
+	}{
+		{"HEAD", merge},
+		{"@", merge},
+		{"master", merge},
+		{"heads/master", merge},
+		{merge.String(), merge},
+		{second.String()[:7], second},
+		{"HEAD^", second},
+		{"HEAD^2", first},
+		{"HEAD~2", first},
+		{"HEAD^1~1", first},
+		{"HEAD^0", merge},
+		{"v1", tag.Hash()},
+		{"v1^{}", second},
+		{"v1^{commit}", second},
+		{"v1^{tree}", tree.Hash},
+		{"v1:src", src.Hash},
+		{"v1:", tree.Hash},
+		{"HEAD^{/typo}", second},
+		{"HEAD^{/!-Merge}", second},
+		{":/Initial", first},
+		{"stash", merge},
+		{"stash@{0}", merge},
+		{"stash@{1}", second},
+		{"stash@{1}^{tree}", tree.Hash},
+		{"stash@{0}^2", first},
+		{"stash@{" + time.Unix(1000000100, 0).UTC().Format(time.RFC3339) + "}", second},
+	}
+
+	for _, tt := range tests {
+		got, err := resolveRevision(repo, tt.rev)
+		if err != nil {
+			t.Errorf("resolveRevision(%q) error = %v", tt.rev, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("resolveRevision(%q) = %s, want %s", tt.rev, got, tt.want)
+		}
+	}
+}
+
+func TestResolveRevisionErrors(t *testing.T) {
+	repoPath, repo, first := newTestRepo(t)
+	writeReflog(t, repoPath, "refs/heads/master", first, int64(1000000000), "commit (initial): Initial commit")
+
+	tests := []struct {
+		rev  string
+		part string
+		is   error
+	}{
+		{"nosuchref", "nosuchref", plumbing.ErrReferenceNotFound},
+		{"HEAD~1", "~1", nil},
+		{"HEAD^2", "^2", nil},
+		{"HEAD^{blob}", "^{blob}", nil},
+		{"HEAD:nosuchfile", ":nosuchfile", nil},
+		{"master@{5}", "master@{5}", nil},
+		{"master@{push}", "master@{push}", ErrUnsupportedRevision},
+		{"master@{yesterday}", "master@{yesterday}", ErrUnsupportedRevision},
+		{"stash@{0}", "stash@{0}", plumbing.ErrReferenceNotFound},
+		{":/no such message", "", nil},
+	}
+
+	for _, tt := range tests {
+		_, err := resolveRevision(repo, tt.rev)
+		var revErr *RevisionError
+		if !errors.As(err, &revErr) {
+			t.Errorf("resolveRevision(%q) error = %v, want *RevisionError", tt.rev, err)
+			continue
+		}
+		if revErr.Rev != tt.rev || revErr.Part != tt.part {
+			t.Errorf("resolveRevision(%q) error = %+v, want part %q", tt.rev, revErr, tt.part)
+		}
+		if tt.is != nil && !errors.Is(err, tt.is) {
+			t.Errorf("resolveRevision(%q) error = %v, want %v", tt.rev, err, tt.is)
+		}
+	}
+}
+
+func TestFromGitTree(t *testing.T) {
+	repoPath, repo, hash := newTestRepo(t)
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		t.Fatalf("CommitObject() error = %v", err)
+	}
+
+	for _, treeish := range []string{"", "HEAD", "master^{tree}", hash.String()[:8] + ":"} {
+		id, err := FromGitTree(repoPath, treeish)
+		if err != nil {
+			t.Fatalf("FromGitTree(%q) error = %v", treeish, err)
+		}
+		if id.ObjectType != ObjectTypeDirectory || id.ObjectHash != commit.TreeHash.String() {
+			t.Errorf("FromGitTree(%q) = %v, want directory %s", treeish, id, commit.TreeHash)
+		}
+	}
+
+	if _, err := FromGitTree(repoPath, "HEAD:hello.txt"); err == nil {
+		t.Error("FromGitTree(HEAD:hello.txt) succeeded for a blob")
+	}
+
+	if _, err := FromRevision(repoPath, "HEAD^{tree}"); err == nil {
+		t.Error("FromRevision(HEAD^{tree}) succeeded for a tree")
+	}
+}
@@ -0,0 +1,322 @@
+// Package selftest checks that this build of the library computes correct
+// SWHIDs on the platform it runs on. It recomputes a set of known-answer
+// vectors embedded in the binary, covering every object type and the
+// parsing rules of the specification, and probes the filesystem for
+// behaviour that changes what a directory hashes to: case folding,
+// symbolic links, executable bits and Unicode normalization of names.
+package selftest
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/objects"
+)
+
+// Check statuses. A warning marks a platform limitation that makes some
+// directories hash differently from a checkout on another system; it does
+// not fail the self-test.
+const (
+	StatusPass = "pass"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+//go:embed vectors.json
+var vectorsJSON []byte
+
+// Vector is a known-answer test: an object whose SWHID was computed by Git
+// or by the Software Heritage reference implementation, or a string that
+// the specification accepts or rejects. Exactly one of the input fields is
+// set.
+type Vector struct {
+	Name  string `json:"name"`
+	SWHID string `json:"swhid,omitempty"` // expected result for object inputs
+
+	Content  []byte                    `json:"content,omitempty"`
+	Entries  []Entry                   `json:"entries,omitempty"`
+	Revision *objects.RevisionMetadata `json:"revision,omitempty"`
+	Release  *objects.ReleaseMetadata  `json:"release,omitempty"`
+	Branches []objects.Branch          `json:"branches,omitempty"`
+
+	Parse string `json:"parse,omitempty"` // SWHID to validate against the latest spec
+	Valid bool   `json:"valid,omitempty"` // whether Parse is a valid SWHID
+}
+
+// Entry is a directory entry of a Vector, typed by manifest type name.
+type Entry struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"` // file, executable, directory, symlink or submodule
+	Target string `json:"target"`
+}
+
+var entryTypes = map[string]objects.EntryType{
+	"file":       objects.EntryTypeFile,
+	"executable": objects.EntryTypeExecutable,
+	"directory":  objects.EntryTypeDirectory,
+	"symlink":    objects.EntryTypeSymlink,
+	"submodule":  objects.EntryTypeRevision,
+}
+
+// Vectors returns the embedded known-answer vectors.
+func Vectors() []Vector {
+	var vectors []Vector
+	if err := json.Unmarshal(vectorsJSON, &vectors); err != nil {
+		panic("selftest: invalid embedded vectors: " + err.Error())
+	}
+	return vectors
+}
+
+// Result is the outcome of one vector or environment check.
+type Result struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Expected string `json:"expected,omitempty"`
+	Got      string `json:"got,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// Report is the outcome of Run.
+type Report struct {
+	Version     string   `json:"version,omitempty"` // set by the caller
+	GoVersion   string   `json:"go_version"`
+	OS          string   `json:"os"`
+	Arch        string   `json:"arch"`
+	Passed      bool     `json:"passed"`
+	Vectors     []Result `json:"vectors"`
+	Environment []Result `json:"environment"`
+}
+
+// Run checks every embedded vector and probes the filesystem in a
+// temporary directory created under dir, or under the default temporary
+// directory when dir is empty. The report passes when no check fails.
+func Run(dir string) (*Report, error) {
+	scratch, err := os.MkdirTemp(dir, "swhid-selftest-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(scratch)
+
+	report := &Report{
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		Passed:    true,
+	}
+	for _, v := range Vectors() {
+		report.Vectors = append(report.Vectors, v.Check())
+	}
+	report.Environment = Environment(scratch)
+
+	for _, r := range append(append([]Result(nil), report.Vectors...), report.Environment...) {
+		if r.Status == StatusFail {
+			report.Passed = false
+		}
+	}
+	return report, nil
+}
+
+// Check recomputes the vector and compares the result with the expected
+// one.
+func (v Vector) Check() Result {
+	r := Result{Name: v.Name, Expected: v.SWHID}
+
+	var got *swhid.Identifier
+	switch {
+	case v.Parse != "":
+		r.Expected = validity(v.Valid)
+		id, err := swhid.ParseStrict(v.Parse, swhid.SpecLatest)
+		r.Got = validity(err == nil)
+		if err != nil {
+			r.Detail = err.Error()
+		} else if id.String() != v.Parse {
+			r.Got = id.String()
+			r.Detail = "does not round-trip"
+		}
+		r.Status = status(r.Got == r.Expected)
+		return r
+
+	case v.Entries != nil:
+		entries := make([]objects.DirectoryEntry, len(v.Entries))
+		for i, e := range v.Entries {
+			t, ok := entryTypes[e.Type]
+			if !ok {
+				r.Status, r.Detail = StatusFail, fmt.Sprintf("unknown entry type %q", e.Type)
+				return r
+			}
+			entries[i] = objects.DirectoryEntry{Name: e.Name, Type: t, Target: e.Target}
+		}
+		got = swhid.FromDirectory(entries)
+	case v.Revision != nil:
+		got = swhid.FromRevisionMetadata(*v.Revision)
+	case v.Release != nil:
+		got = swhid.FromReleaseMetadata(*v.Release)
+	case v.Branches != nil:
+		got = swhid.FromSnapshotBranches(v.Branches)
+	default:
+		got = swhid.FromContent(v.Content)
+	}
+
+	r.Got = got.String()
+	r.Status = status(r.Got == r.Expected)
+	return r
+}
+
+// Environment probes the filesystem in dir, which must be an empty
+// writable directory.
+func Environment(dir string) []Result {
+	return []Result{
+		caseSensitivity(dir),
+		symlinks(dir),
+		executableBit(dir),
+		unicodeNames(dir),
+		directoryHash(dir),
+	}
+}
+
+func caseSensitivity(dir string) Result {
+	r := Result{Name: "case-sensitive filesystem"}
+	if err := os.WriteFile(filepath.Join(dir, "case"), nil, 0644); err != nil {
+		r.Status, r.Detail = StatusFail, err.Error()
+		return r
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "CASE")); err == nil {
+		r.Status = StatusWarn
+		r.Detail = "names differing only in case refer to the same file, so trees holding both cannot be checked out and hashed here"
+		return r
+	}
+	r.Status = StatusPass
+	return r
+}
+
+func symlinks(dir string) Result {
+	r := Result{Name: "symbolic links"}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink("target", link); err != nil {
+		r.Status, r.Detail = StatusWarn, "cannot create symbolic links ("+err.Error()+"); checkouts store links as plain files, which hash differently"
+		return r
+	}
+	if target, err := os.Readlink(link); err != nil || target != "target" {
+		r.Status, r.Detail = StatusWarn, "symbolic links do not keep their target"
+		return r
+	}
+	r.Status = StatusPass
+	return r
+}
+
+func executableBit(dir string) Result {
+	r := Result{Name: "executable bit"}
+	file := filepath.Join(dir, "exec")
+	if err := os.WriteFile(file, nil, 0755); err != nil {
+		r.Status, r.Detail = StatusFail, err.Error()
+		return r
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		r.Status, r.Detail = StatusFail, err.Error()
+		return r
+	}
+	if info.Mode()&0111 == 0 {
+		r.Status = StatusWarn
+		r.Detail = "the filesystem does not record executable bits; outside a Git checkout, executables hash as regular files"
+		return r
+	}
+	r.Status = StatusPass
+	return r
+}
+
+func unicodeNames(dir string) Result {
+	r := Result{Name: "unicode file names"}
+	const nfc = "café"
+	sub := filepath.Join(dir, "unicode")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		r.Status, r.Detail = StatusFail, err.Error()
+		return r
+	}
+	if err := os.WriteFile(filepath.Join(sub, nfc), nil, 0644); err != nil {
+		r.Status, r.Detail = StatusWarn, "cannot create non-ASCII file names: "+err.Error()
+		return r
+	}
+	entries, err := os.ReadDir(sub)
+	if err != nil {
+		r.Status, r.Detail = StatusFail, err.Error()
+		return r
+	}
+	if len(entries) != 1 || entries[0].Name() != nfc {
+		r.Status = StatusWarn
+		r.Detail = "file names are normalized by the filesystem, so names hash as different bytes than they were written with"
+		return r
+	}
+	r.Status = StatusPass
+	return r
+}
+
+// directoryHash writes a small tree to disk and checks that hashing it
+// from the filesystem gives the same SWHID as hashing its entries. Entry
+// types the filesystem cannot represent are left out.
+func directoryHash(dir string) Result {
+	r := Result{Name: "directory hashing"}
+	root := filepath.Join(dir, "tree")
+	fail := func(err error) Result {
+		r.Status, r.Detail = StatusFail, err.Error()
+		return r
+	}
+	if err := os.MkdirAll(filepath.Join(root, "src"), 0755); err != nil {
+		return fail(err)
+	}
+
+	hello := swhid.FromContent([]byte("hello\n")).ObjectHash
+	mainGo := []byte("package main\n")
+	if err := os.WriteFile(filepath.Join(root, "hello.txt"), []byte("hello\n"), 0644); err != nil {
+		return fail(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "src", "main.go"), mainGo, 0644); err != nil {
+		return fail(err)
+	}
+	entries := []objects.DirectoryEntry{
+		{Name: "hello.txt", Type: objects.EntryTypeFile, Target: hello},
+		{Name: "src", Type: objects.EntryTypeDirectory, Target: swhid.FromDirectory([]objects.DirectoryEntry{
+			{Name: "main.go", Type: objects.EntryTypeFile, Target: swhid.FromContent(mainGo).ObjectHash},
+		}).ObjectHash},
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "run.sh"), nil, 0755); err != nil {
+		return fail(err)
+	}
+	if info, err := os.Stat(filepath.Join(root, "run.sh")); err == nil && info.Mode()&0111 != 0 {
+		entries = append(entries, objects.DirectoryEntry{Name: "run.sh", Type: objects.EntryTypeExecutable, Target: swhid.FromContent(nil).ObjectHash})
+	} else {
+		entries = append(entries, objects.DirectoryEntry{Name: "run.sh", Type: objects.EntryTypeFile, Target: swhid.FromContent(nil).ObjectHash})
+	}
+	if err := os.Symlink("hello.txt", filepath.Join(root, "link")); err == nil {
+		entries = append(entries, objects.DirectoryEntry{Name: "link", Type: objects.EntryTypeSymlink, Target: swhid.FromContent([]byte("hello.txt")).ObjectHash})
+	}
+
+	node, err := swhid.TreeFromDirectoryPathWithOptions(root, swhid.TreeOptions{})
+	if err != nil {
+		return fail(err)
+	}
+	r.Expected = swhid.FromDirectory(entries).String()
+	r.Got = node.ID.String()
+	r.Status = status(r.Got == r.Expected)
+	return r
+}
+
+func validity(valid bool) string {
+	if valid {
+		return "valid"
+	}
+	return "invalid"
+}
+
+func status(ok bool) string {
+	if ok {
+		return StatusPass
+	}
+	return StatusFail
+}
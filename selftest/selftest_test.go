@@ -0,0 +1,61 @@
+package selftest
+
+import (
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	report, err := Run(t.TempDir())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !report.Passed {
+		t.Errorf("Run() did not pass: %+v", report)
+	}
+	if len(report.Vectors) != len(Vectors()) {
+		t.Errorf("Run() checked %d vectors, want %d", len(report.Vectors), len(Vectors()))
+	}
+	for _, r := range append(report.Vectors, report.Environment...) {
+		if r.Status == StatusFail {
+			t.Errorf("%s: got %s, want %s (%s)", r.Name, r.Got, r.Expected, r.Detail)
+		}
+	}
+}
+
+func TestVectorsCoverEveryInput(t *testing.T) {
+	kinds := map[string]int{}
+	for _, v := range Vectors() {
+		switch {
+		case v.Parse != "":
+			kinds["parse"]++
+		case v.Entries != nil:
+			kinds["dir"]++
+		case v.Revision != nil:
+			kinds["rev"]++
+		case v.Release != nil:
+			kinds["rel"]++
+		case v.Branches != nil:
+			kinds["snp"]++
+		default:
+			kinds["cnt"]++
+		}
+	}
+	for _, kind := range []string{"cnt", "dir", "rev", "rel", "snp", "parse"} {
+		if kinds[kind] == 0 {
+			t.Errorf("no %s vectors", kind)
+		}
+	}
+}
+
+func TestCheckReportsMismatch(t *testing.T) {
+	v := Vector{Name: "wrong", SWHID: "swh:1:cnt:0000000000000000000000000000000000000000", Content: []byte("hello\n")}
+	r := v.Check()
+	if r.Status != StatusFail || r.Got != "swh:1:cnt:ce013625030ba8dba906f756967f9e9ca394464a" {
+		t.Errorf("Check() = %+v, want a failure with the computed SWHID", r)
+	}
+
+	v = Vector{Name: "wrongly valid", Parse: "swh:1:cnt:94a9ed024d3859793618152ea559a168bbcbb5e2", Valid: false}
+	if r := v.Check(); r.Status != StatusFail {
+		t.Errorf("Check() = %+v, want a failure", r)
+	}
+}
@@ -0,0 +1,273 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/andrew/swhid-go/objects"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// RepoSession computes SWHIDs for a single repository. It opens the
+// repository once and caches computed identifiers, so scripts that ask for
+// many revisions, releases and snapshots do not reopen and re-scan the
+// repository on every call as FromRevision, FromRelease and FromSnapshot do.
+// A RepoSession is safe for concurrent use.
+type RepoSession struct {
+	repo *git.Repository
+	opts GitOptions
+
+	mu        sync.Mutex
+	revisions map[plumbing.Hash]*Identifier
+	releases  map[plumbing.Hash]*Identifier
+	targets   map[plumbing.Hash]objects.BranchTargetType
+}
+
+var _ RepoBackend = (*RepoSession)(nil)
+
+// OpenRepo opens the repository at repoPath for a session.
+func OpenRepo(repoPath string) (*RepoSession, error) {
+	return OpenRepoWithOptions(repoPath, GitOptions{})
+}
+
+// OpenRepoWithOptions opens a session that reads the repository as opts
+// requests.
+func OpenRepoWithOptions(repoPath string, opts GitOptions) (*RepoSession, error) {
+	repo, err := openRepository(repoPath, false, opts.ReadOnlyFS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	return NewRepoSession(repo, opts), nil
+}
+
+// NewRepoSession returns a session over an already open repository, which
+// may use any storage, including memory.
+func NewRepoSession(repo *git.Repository, opts GitOptions) *RepoSession {
+	return &RepoSession{
+		repo:      repo,
+		opts:      opts,
+		revisions: make(map[plumbing.Hash]*Identifier),
+		releases:  make(map[plumbing.Hash]*Identifier),
+		targets:   make(map[plumbing.Hash]objects.BranchTargetType),
+	}
+}
+
+// Repository returns the underlying go-git repository.
+func (s *RepoSession) Repository() *git.Repository {
+	return s.repo
+}
+
+// Revision returns the SWHID of the commit ref resolves to, like
+// FromRevisionWithOptions.
+func (s *RepoSession) Revision(ref string) (*Identifier, error) {
+	commit, err := resolveCommitIn(s.repo, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id, ok := s.revisions[commit.Hash]; ok {
+		return id, nil
+	}
+	id, err := revisionWithOptions(s.repo, commit, s.opts)
+	if err != nil {
+		return nil, err
+	}
+	s.revisions[commit.Hash] = id
+	return id, nil
+}
+
+// RevisionMetadata returns the metadata Revision hashes for the commit ref
+// resolves to, as objects.ExplainRevision takes it.
+func (s *RepoSession) RevisionMetadata(ref string) (objects.RevisionMetadata, error) {
+	commit, err := resolveCommitIn(s.repo, ref)
+	if err != nil {
+		return objects.RevisionMetadata{}, err
+	}
+	return revisionMetadataWithOptions(s.repo, commit, s.opts)
+}
+
+// Release returns the SWHID of an annotated tag, like FromReleaseWithOptions.
+func (s *RepoSession) Release(tagName string) (*Identifier, error) {
+	tagObj, err := resolveTag(s.repo, tagName)
+	if err != nil {
+		return nil, err
+	}
+	if s.opts.ReplaceRefs {
+		if tagObj, err = replaceTag(s.repo, tagObj); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id, ok := s.releases[tagObj.Hash]; ok {
+		return id, nil
+	}
+	id := FromReleaseMetadata(releaseMetadata(s.repo, tagObj))
+	s.releases[tagObj.Hash] = id
+	return id, nil
+}
+
+// ReleaseMetadata returns the metadata Release hashes for an annotated
+// tag, as objects.ExplainRelease takes it.
+func (s *RepoSession) ReleaseMetadata(tagName string) (objects.ReleaseMetadata, error) {
+	tagObj, err := resolveTag(s.repo, tagName)
+	if err != nil {
+		return objects.ReleaseMetadata{}, err
+	}
+	if s.opts.ReplaceRefs {
+		if tagObj, err = replaceTag(s.repo, tagObj); err != nil {
+			return objects.ReleaseMetadata{}, err
+		}
+	}
+	return releaseMetadata(s.repo, tagObj), nil
+}
+
+// Snapshot returns the SWHID of the repository's current references, like
+// FromSnapshotWithOptions. References are re-read on every call; object types are cached.
+func (s *RepoSession) Snapshot() (*Identifier, error) {
+	branches, err := s.SnapshotBranches()
+	if err != nil {
+		return nil, err
+	}
+	return FromSnapshotBranches(branches), nil
+}
+
+// SnapshotBranches returns the branches Snapshot hashes.
+func (s *RepoSession) SnapshotBranches() ([]objects.Branch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return snapshotBranches(s.repo, s.opts, s.resolveTarget)
+}
+
+func (s *RepoSession) resolveTarget(hash plumbing.Hash) (objects.BranchTargetType, string) {
+	if targetType, ok := s.targets[hash]; ok {
+		return targetType, hash.String()
+	}
+	targetType, target := resolveRefTarget(s.repo, hash)
+	s.targets[hash] = targetType
+	return targetType, target
+}
+
+// Tree returns the SWHID of the object at path in the tree of the commit
+// ref resolves to: a directory SWHID for the root ("" or "/") and for
+// subdirectories, a content SWHID for files and symlinks, and a revision
+// SWHID for submodules.
+func (s *RepoSession) Tree(ref, path string) (*Identifier, error) {
+	commit, err := resolveCommitIn(s.repo, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree: %w", err)
+	}
+	return treePathIdentifier(tree, path, ref)
+}
+
+// treePathIdentifier returns the SWHID of the object at path in tree, named
+// by in in errors.
+func treePathIdentifier(tree *object.Tree, path, in string) (*Identifier, error) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return NewIdentifier(ObjectTypeDirectory, tree.Hash.String(), nil)
+	}
+
+	entry, err := tree.FindEntry(path)
+	if err != nil {
+		return nil, fmt.Errorf("path %s not found in %s: %w", path, in, err)
+	}
+
+	switch entry.Mode {
+	case filemode.Dir:
+		return NewIdentifier(ObjectTypeDirectory, entry.Hash.String(), nil)
+	case filemode.Submodule:
+		return NewIdentifier(ObjectTypeRevision, entry.Hash.String(), nil)
+	default:
+		return NewIdentifier(ObjectTypeContent, entry.Hash.String(), nil)
+	}
+}
+
+// LatestSnapshot implements Resolver for the origin the repository was
+// cloned from, per its "origin" remote or only remote: the snapshot is that
+// of the repository's current references, which is what a visit of the
+// origin would record if the clone is up to date. Other origins are
+// ErrUnresolved.
+func (s *RepoSession) LatestSnapshot(ctx context.Context, origin string) (*Identifier, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	remote, err := remoteOrigin(s.repo)
+	if err != nil {
+		return nil, err
+	}
+	if remote == "" || !SameOrigin(remote, origin) {
+		return nil, fmt.Errorf("%w: %s is not the repository's origin", ErrUnresolved, origin)
+	}
+	return s.Snapshot()
+}
+
+// Lookup implements Resolver with the objects in the repository. A
+// snapshot anchor resolves only if it is the repository's current
+// snapshot. Anchors and paths missing from the repository are
+// ErrUnresolved.
+func (s *RepoSession) Lookup(ctx context.Context, anchor *Identifier, path string) (*Identifier, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	hash := plumbing.NewHash(anchor.ObjectHash)
+	switch anchor.ObjectType {
+	case ObjectTypeSnapshot:
+		current, err := s.Snapshot()
+		if err != nil {
+			return nil, err
+		}
+		if current.ObjectHash != anchor.ObjectHash {
+			return nil, fmt.Errorf("%w: %s is not the repository's current snapshot", ErrUnresolved, anchor.CoreSWHID())
+		}
+		head, err := s.repo.Head()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s has no HEAD: %v", ErrUnresolved, anchor.CoreSWHID(), err)
+		}
+		hash = head.Hash()
+	case ObjectTypeRelease, ObjectTypeRevision, ObjectTypeDirectory:
+	default:
+		return nil, fmt.Errorf("%w: anchor cannot point to a %s", ErrInvalidQualifier, anchor.ObjectType)
+	}
+
+	// Peel tags and commits down to the root tree.
+	for {
+		obj, err := s.repo.Object(plumbing.AnyObject, hash)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrUnresolved, anchor.CoreSWHID(), err)
+		}
+		switch o := obj.(type) {
+		case *object.Tag:
+			hash = o.Target
+		case *object.Commit:
+			hash = o.TreeHash
+		case *object.Tree:
+			id, err := treePathIdentifier(o, path, anchor.CoreSWHID())
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrUnresolved, err)
+			}
+			return id, nil
+		default:
+			return nil, fmt.Errorf("%w: %s does not lead to a directory", ErrUnresolved, anchor.CoreSWHID())
+		}
+	}
+}
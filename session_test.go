@@ -0,0 +1,149 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andrew/swhid-go/objects"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestRepoSession(t *testing.T) {
+	repoPath, repo, hash := newTestRepo(t)
+
+	if err := os.MkdirAll(filepath.Join(repoPath, "src"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoPath, "src", "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	head := commitAll(t, repo, "Add main\n")
+
+	sig := &object.Signature{Name: "Test", Email: "test@example.com", When: time.Unix(1000000000, 0).UTC()}
+	if _, err := repo.CreateTag("v1.0.0", hash, &git.CreateTagOptions{Tagger: sig, Message: "v1.0.0\n"}); err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	session, err := OpenRepo(repoPath)
+	if err != nil {
+		t.Fatalf("OpenRepo() error = %v", err)
+	}
+
+	rev, err := session.Revision("HEAD")
+	if err != nil {
+		t.Fatalf("Revision() error = %v", err)
+	}
+	if rev.ObjectHash != head.String() {
+		t.Errorf("Revision() = %v, want %v", rev.ObjectHash, head)
+	}
+	again, _ := session.Revision(head.String())
+	if again != rev {
+		t.Error("Revision() did not return the cached identifier")
+	}
+
+	rel, err := session.Release("v1.0.0")
+	if err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	want, _ := FromRelease(repoPath, "v1.0.0")
+	if !rel.Equal(want) {
+		t.Errorf("Release() = %v, want %v", rel, want)
+	}
+
+	snp, err := session.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	wantSnp, _ := FromSnapshot(repoPath)
+	if !snp.Equal(wantSnp) {
+		t.Errorf("Snapshot() = %v, want %v", snp, wantSnp)
+	}
+	branches, err := session.SnapshotBranches()
+	if err != nil {
+		t.Fatalf("SnapshotBranches() error = %v", err)
+	}
+	if got := FromSnapshotBranches(branches); !got.Equal(wantSnp) {
+		t.Errorf("SnapshotBranches() hash to %v, want %v", got, wantSnp)
+	}
+
+	file, err := session.Tree("HEAD", "src/main.go")
+	if err != nil {
+		t.Fatalf("Tree() error = %v", err)
+	}
+	if !file.Equal(FromContent([]byte("package main\n"))) {
+		t.Errorf("Tree(src/main.go) = %v", file)
+	}
+
+	dir, err := session.Tree("HEAD", "src")
+	if err != nil {
+		t.Fatalf("Tree() error = %v", err)
+	}
+	if dir.ObjectType != ObjectTypeDirectory {
+		t.Errorf("Tree(src) type = %v, want dir", dir.ObjectType)
+	}
+
+	root, err := session.Tree(hash.String(), "")
+	if err != nil {
+		t.Fatalf("Tree() error = %v", err)
+	}
+	commit, _ := repo.CommitObject(hash)
+	if root.ObjectHash != commit.TreeHash.String() {
+		t.Errorf("Tree(root) = %v, want %v", root.ObjectHash, commit.TreeHash)
+	}
+
+	if _, err := session.Tree("HEAD", "missing"); err == nil {
+		t.Error("Tree() expected error for missing path")
+	}
+}
+
+func TestRepoSessionSignedRelease(t *testing.T) {
+	repoPath, repo, hash := newTestRepo(t)
+
+	// go-git keeps the signature out of the tag message; the hash covers it
+	raw := "object " + hash.String() + "\ntype commit\ntag v1.0.0\ntagger Test <test@example.com> 1000000000 +0200\n\n" +
+		"Release\n-----BEGIN PGP SIGNATURE-----\n\nabc\n-----END PGP SIGNATURE-----\n"
+	obj := repo.Storer.NewEncodedObject()
+	obj.SetType(plumbing.TagObject)
+	w, err := obj.Writer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte(raw))
+	w.Close()
+	tagHash, err := repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		t.Fatalf("Failed to store tag: %v", err)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference("refs/tags/v1.0.0", tagHash)); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := OpenRepo(repoPath)
+	if err != nil {
+		t.Fatalf("OpenRepo() error = %v", err)
+	}
+	rel, err := session.Release("v1.0.0")
+	if err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if rel.ObjectHash != tagHash.String() {
+		t.Errorf("Release() = %v, want %v", rel.ObjectHash, tagHash)
+	}
+
+	meta, err := session.ReleaseMetadata("v1.0.0")
+	if err != nil {
+		t.Fatalf("ReleaseMetadata() error = %v", err)
+	}
+	if message, _, format := meta.Signature(); message != "Release\n" || format != "openpgp" {
+		t.Errorf("Signature() = %q, %q, want the message and openpgp", message, format)
+	}
+	if meta.Target.Hash != hash.String() || meta.Target.Type != objects.TargetTypeRevision || meta.AuthorTimezone != "+0200" {
+		t.Errorf("ReleaseMetadata() = %+v", meta)
+	}
+}
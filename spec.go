@@ -0,0 +1,204 @@
+package swhid
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Qualifier keys defined by the SWHID specification.
+const (
+	QualifierOrigin = "origin"
+	QualifierVisit  = "visit"
+	QualifierAnchor = "anchor"
+	QualifierPath   = "path"
+	QualifierLines  = "lines"
+	QualifierBytes  = "bytes"
+)
+
+// ErrInvalidQualifier is returned by ParseStrict and Validate for
+// qualifiers the targeted specification version does not allow.
+var ErrInvalidQualifier = errors.New("invalid qualifier")
+
+// SpecVersion selects the revision of the SWHID specification that
+// ParseStrict and Validate check against.
+type SpecVersion int
+
+const (
+	// SpecV1_0 is the original specification published with the Software
+	// Heritage archive. It defines the origin, visit, anchor, path and
+	// lines qualifiers and does not require them to be ordered.
+	SpecV1_0 SpecVersion = iota
+
+	// SpecV1_1 is the revision standardized as ISO/IEC 18670. It adds the
+	// bytes qualifier, requires qualifiers in canonical order, and requires
+	// whitespace and control characters in values to be percent-encoded.
+	SpecV1_1
+)
+
+// SpecLatest is the most recent specification version.
+const SpecLatest = SpecV1_1
+
+func (v SpecVersion) String() string {
+	switch v {
+	case SpecV1_0:
+		return "1.0"
+	case SpecV1_1:
+		return "1.1"
+	default:
+		return fmt.Sprintf("SpecVersion(%d)", int(v))
+	}
+}
+
+// QualifierOrder returns the qualifier keys defined by the specification
+// version, in canonical order.
+func (v SpecVersion) QualifierOrder() []string {
+	if v == SpecV1_0 {
+		return []string{QualifierOrigin, QualifierVisit, QualifierAnchor, QualifierPath, QualifierLines}
+	}
+	return slices.Clone(canonicalQualifierOrder)
+}
+
+// CanonicalQualifierOrder returns the qualifier keys in the order String
+// writes them, which is the canonical order of the latest specification.
+func CanonicalQualifierOrder() []string {
+	return slices.Clone(canonicalQualifierOrder)
+}
+
+var rangeRegex = regexp.MustCompile(`^[1-9][0-9]*(-[1-9][0-9]*)?$`)
+
+// ParseStrict parses a SWHID like Parse, but rejects anything the given
+// specification version does not allow: unknown, duplicate, empty or
+// (from 1.1) out-of-order qualifiers, unescaped whitespace in values, and
+// qualifier values of the wrong shape.
+func ParseStrict(swhidString string, version SpecVersion) (*Identifier, error) {
+	id, err := Parse(swhidString)
+	if err != nil {
+		return nil, err
+	}
+
+	order := version.QualifierOrder()
+	_, qualifierStr, _ := strings.Cut(swhidString, ";")
+	seen := make(map[string]bool)
+	last := -1
+	for _, part := range strings.Split(qualifierStr, ";") {
+		if qualifierStr == "" {
+			break
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || key == "" || value == "" {
+			return nil, fmt.Errorf("%w: malformed %q", ErrInvalidQualifier, part)
+		}
+
+		pos := slices.Index(order, key)
+		switch {
+		case pos == -1:
+			return nil, fmt.Errorf("%w: %s is not defined in SWHID %s", ErrInvalidQualifier, key, version)
+		case seen[key]:
+			return nil, fmt.Errorf("%w: duplicate %s", ErrInvalidQualifier, key)
+		case version >= SpecV1_1 && pos < last:
+			return nil, fmt.Errorf("%w: %s out of canonical order", ErrInvalidQualifier, key)
+		}
+		seen[key] = true
+		last = pos
+
+		if version >= SpecV1_1 && strings.ContainsFunc(value, isSpaceOrControl) {
+			return nil, fmt.Errorf("%w: %s value must percent-encode whitespace", ErrInvalidQualifier, key)
+		}
+	}
+
+	if err := id.Validate(version); err != nil {
+		return nil, err
+	}
+	return id, nil
+}
+
+// Validate checks the identifier's qualifiers against a specification
+// version: every key must be defined by it, visit must name a snapshot,
+// anchor a snapshot, release, revision or directory, and lines and bytes
+// must be a number or a range of numbers.
+func (id *Identifier) Validate(version SpecVersion) error {
+	order := version.QualifierOrder()
+	for key, value := range id.Qualifiers {
+		if !slices.Contains(order, key) {
+			return fmt.Errorf("%w: %s is not defined in SWHID %s", ErrInvalidQualifier, key, version)
+		}
+
+		switch key {
+		case QualifierVisit:
+			if err := checkQualifierSWHID(key, value, ObjectTypeSnapshot); err != nil {
+				return err
+			}
+		case QualifierAnchor:
+			if err := checkQualifierSWHID(key, value, ObjectTypeSnapshot, ObjectTypeRelease, ObjectTypeRevision, ObjectTypeDirectory); err != nil {
+				return err
+			}
+		case QualifierLines, QualifierBytes:
+			if !rangeRegex.MatchString(value) {
+				return fmt.Errorf("%w: %s must be N or N-M, got %q", ErrInvalidQualifier, key, value)
+			}
+		}
+	}
+	return nil
+}
+
+// StringStrict returns the SWHID as the given specification version
+// writes it. It fails where Validate does, writes qualifiers in the
+// version's canonical order and, from 1.1, also percent-encodes whitespace
+// and control characters in values, which String leaves as they are, so
+// that the result is accepted by ParseStrict.
+func (id *Identifier) StringStrict(version SpecVersion) (string, error) {
+	if err := id.Validate(version); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString(id.CoreSWHID())
+	for _, key := range version.QualifierOrder() {
+		value, ok := id.Qualifiers[key]
+		if !ok {
+			continue
+		}
+		if version >= SpecV1_1 {
+			value = encodeStrictQualifierValue(value)
+		} else {
+			value = encodeQualifierValue(value)
+		}
+		b.WriteString(";" + key + "=" + value)
+	}
+	return b.String(), nil
+}
+
+// encodeStrictQualifierValue percent-encodes what encodeQualifierValue
+// does plus whitespace and control characters.
+func encodeStrictQualifierValue(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c == '%' || c == ';' || isSpaceOrControl(rune(c)) {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func checkQualifierSWHID(key, value string, allowed ...ObjectType) error {
+	target, err := Parse(value)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidQualifier, key, err)
+	}
+	if len(target.Qualifiers) > 0 {
+		return fmt.Errorf("%w: %s must be a core SWHID", ErrInvalidQualifier, key)
+	}
+	if !slices.Contains(allowed, target.ObjectType) {
+		return fmt.Errorf("%w: %s cannot point to a %s", ErrInvalidQualifier, key, target.ObjectType)
+	}
+	return nil
+}
+
+func isSpaceOrControl(r rune) bool {
+	return r <= ' ' || r == 0x7f
+}
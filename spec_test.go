@@ -0,0 +1,104 @@
+package swhid
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParseStrict(t *testing.T) {
+	const core = "swh:1:cnt:4d99d2d18326621ccdd70f5ea66c2e2ac236ad8b"
+	const snp = "swh:1:snp:d7f1b9eb7ccb596c2622c4780febaa02549830f9"
+
+	tests := []struct {
+		name    string
+		input   string
+		version SpecVersion
+		wantErr bool
+	}{
+		{"core only", core, SpecV1_1, false},
+		{"canonical order", core + ";origin=https://example.org;visit=" + snp + ";lines=9-15", SpecV1_1, false},
+		{"bytes in 1.1", core + ";bytes=10-20", SpecV1_1, false},
+		{"bytes in 1.0", core + ";bytes=10-20", SpecV1_0, true},
+		{"out of order in 1.0", core + ";lines=9;origin=https://example.org", SpecV1_0, false},
+		{"out of order in 1.1", core + ";lines=9;origin=https://example.org", SpecV1_1, true},
+		{"unknown qualifier", core + ";foo=bar", SpecV1_0, true},
+		{"duplicate qualifier", core + ";lines=1;lines=2", SpecV1_0, true},
+		{"empty value", core + ";path=", SpecV1_0, true},
+		{"missing equals", core + ";path", SpecV1_0, true},
+		{"raw space in 1.0", core + ";path=/a b", SpecV1_0, false},
+		{"raw space in 1.1", core + ";path=/a b", SpecV1_1, true},
+		{"encoded space in 1.1", core + ";path=/a%20b", SpecV1_1, false},
+		{"visit not a snapshot", core + ";visit=" + core, SpecV1_1, true},
+		{"anchor content", core + ";anchor=" + core, SpecV1_1, true},
+		{"anchor revision", core + ";anchor=swh:1:rev:309cf2674ee7a0749978cf8265ab91a60aea0f7d", SpecV1_1, false},
+		{"bad lines", core + ";lines=0-3", SpecV1_1, true},
+		{"invalid core", "swh:1:cnt:XYZ", SpecV1_1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseStrict(tt.input, tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseStrict(%q, %s) error = %v, wantErr %v", tt.input, tt.version, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseStrictErrorType(t *testing.T) {
+	_, err := ParseStrict("swh:1:cnt:4d99d2d18326621ccdd70f5ea66c2e2ac236ad8b;foo=bar", SpecLatest)
+	if !errors.Is(err, ErrInvalidQualifier) {
+		t.Errorf("ParseStrict() error = %v, want ErrInvalidQualifier", err)
+	}
+}
+
+func TestQualifierOrder(t *testing.T) {
+	if got := SpecV1_0.QualifierOrder(); slices.Contains(got, QualifierBytes) {
+		t.Errorf("SpecV1_0.QualifierOrder() = %v, should not include bytes", got)
+	}
+	if got, want := SpecLatest.QualifierOrder(), CanonicalQualifierOrder(); !slices.Equal(got, want) {
+		t.Errorf("SpecLatest.QualifierOrder() = %v, want %v", got, want)
+	}
+
+	order := CanonicalQualifierOrder()
+	order[0] = "changed"
+	if CanonicalQualifierOrder()[0] != QualifierOrigin {
+		t.Error("CanonicalQualifierOrder() exposes the package's slice")
+	}
+}
+
+func TestStringStrict(t *testing.T) {
+	const core = "swh:1:cnt:4d99d2d18326621ccdd70f5ea66c2e2ac236ad8b"
+	id, _ := NewIdentifier(ObjectTypeContent, "4d99d2d18326621ccdd70f5ea66c2e2ac236ad8b", map[string]string{
+		QualifierLines:  "9-15",
+		QualifierPath:   "/a b;c\t",
+		QualifierOrigin: "https://example.org",
+	})
+
+	// String keeps writing whitespace as it is, as it did before 1.1
+	if got, want := id.String(), core+";origin=https://example.org;path=/a b%3Bc\t;lines=9-15"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	got, err := id.StringStrict(SpecV1_1)
+	if want := core + ";origin=https://example.org;path=/a%20b%3Bc%09;lines=9-15"; err != nil || got != want {
+		t.Fatalf("StringStrict(1.1) = %q, %v; want %q", got, err, want)
+	}
+	parsed, err := ParseStrict(got, SpecV1_1)
+	if err != nil {
+		t.Fatalf("ParseStrict(%q) error = %v", got, err)
+	}
+	if !parsed.Equal(id) {
+		t.Errorf("ParseStrict(%q) = %v, want %v", got, parsed.Qualifiers, id.Qualifiers)
+	}
+
+	if got, err := id.StringStrict(SpecV1_0); err != nil || got != id.String() {
+		t.Errorf("StringStrict(1.0) = %q, %v; want %q", got, err, id.String())
+	}
+
+	bytesID := id.WithQualifiers(map[string]string{QualifierBytes: "1-2"})
+	if _, err := bytesID.StringStrict(SpecV1_0); !errors.Is(err, ErrInvalidQualifier) {
+		t.Errorf("StringStrict(1.0) with bytes error = %v, want ErrInvalidQualifier", err)
+	}
+}
@@ -0,0 +1,182 @@
+package swhid
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/andrew/swhid-go/objects"
+)
+
+// StreamThreshold is the file size from which FromFile and the directory
+// hashers stream a file through FromContentReader instead of reading it
+// into memory whole.
+const StreamThreshold = 8 << 20
+
+// Read-ahead parameters of FromContentReader: how much is read at a time,
+// and how many chunks may be read ahead of the one being hashed.
+var (
+	streamChunkSize = 1 << 20
+	streamChunks    = 4
+)
+
+// ErrSizeMismatch is returned when a reader yields more or fewer bytes than
+// the size the content was declared to have.
+var ErrSizeMismatch = errors.New("content size does not match")
+
+// FromContentReader computes the content SWHID of the size bytes read from
+// r, which the blob header needs before the content. SHA-1 cannot be split
+// over several cores, so instead reading and hashing overlap: a goroutine
+// reads chunks ahead into a small pool of buffers while the calling one
+// hashes, keeping both the disk and the hash busy. Memory use stays at a
+// few megabytes whatever the size.
+//
+// It fails with ErrSizeMismatch if r ends early or holds more than size
+// bytes, as happens when a file changes while it is read.
+func FromContentReader(r io.Reader, size int64) (*Identifier, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("invalid content size %d", size)
+	}
+	h := objects.NewContentHash(size)
+
+	type chunk struct {
+		buf []byte
+		n   int
+		err error
+	}
+	free := make(chan []byte, streamChunks)
+	full := make(chan chunk, streamChunks)
+	done := make(chan struct{})
+	defer close(done)
+	for range streamChunks {
+		free <- make([]byte, streamChunkSize)
+	}
+
+	go func() {
+		defer close(full)
+		// Read one byte past size, so that content longer than declared
+		// is noticed.
+		remaining := size + 1
+		for remaining > 0 {
+			var buf []byte
+			select {
+			case buf = <-free:
+			case <-done:
+				return
+			}
+			if int64(len(buf)) > remaining {
+				buf = buf[:remaining]
+			}
+			n, err := io.ReadFull(r, buf)
+			remaining -= int64(n)
+			if err == io.ErrUnexpectedEOF {
+				err = io.EOF
+			}
+			select {
+			case full <- chunk{buf, n, err}:
+			case <-done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	var read int64
+	for c := range full {
+		read += int64(c.n)
+		if read > size {
+			return nil, fmt.Errorf("%w: more than %d bytes", ErrSizeMismatch, size)
+		}
+		h.Write(c.buf[:c.n])
+		free <- c.buf[:cap(c.buf)]
+		if c.err == io.EOF {
+			break
+		}
+		if c.err != nil {
+			return nil, c.err
+		}
+	}
+	if read != size {
+		return nil, fmt.Errorf("%w: read %d of %d bytes", ErrSizeMismatch, read, size)
+	}
+	return NewIdentifier(ObjectTypeContent, hex.EncodeToString(h.Sum(nil)), nil)
+}
+
+// FromFile computes the content SWHID of the regular file at path. Files
+// of StreamThreshold bytes or more are streamed through FromContentReader.
+// A symlink is followed and the file it points to hashed, as git
+// hash-object does; FromSymlink gives the SWHID a directory records for
+// the link itself. A dangling link is an error naming its target.
+func FromFile(path string) (*Identifier, error) {
+	id, _, err := hashFile(path, "", TreeOptions{})
+	if errors.Is(err, os.ErrNotExist) {
+		if target, lerr := os.Readlink(path); lerr == nil {
+			return nil, fmt.Errorf("%s: symlink to missing %s: %w", path, target, os.ErrNotExist)
+		}
+	}
+	return id, err
+}
+
+// FromSymlink computes the content SWHID of the symlink at path: that of
+// its target path, which is how directories, and so Software Heritage,
+// hash links. The target need not exist.
+func FromSymlink(path string) (*Identifier, error) {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return nil, err
+	}
+	return FromContent([]byte(target)), nil
+}
+
+// hashFile is FromFile for the file at relPath in a tree, also detecting
+// its media type and passing it to opts.Inspect from the same read as opts
+// ask.
+func hashFile(path, relPath string, opts TreeOptions) (*Identifier, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, "", err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, "", fmt.Errorf("%s: not a regular file", path)
+	}
+	r, done := tap(f, relPath, info, opts)
+	id, err := fromOpenFile(r, info.Size())
+	mimeType, err := done(err)
+	if err != nil {
+		return nil, "", err
+	}
+	return id, mimeType, nil
+}
+
+// fromOpenFile hashes the size bytes of f, in memory if small and streamed
+// otherwise.
+func fromOpenFile(f io.Reader, size int64) (*Identifier, error) {
+	if size >= StreamThreshold {
+		return FromContentReader(f, size)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	return FromContent(data), nil
+}
+
+// newStreamedNode is newContentNode for the size bytes read from r,
+// streamed when there are StreamThreshold or more of them.
+func newStreamedNode(parent, name string, entryType objects.EntryType, r io.Reader, size int64) (*Node, error) {
+	id, err := fromOpenFile(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return &Node{Name: name, Path: path.Join(parent, name), Type: entryType, ID: id, Size: size}, nil
+}
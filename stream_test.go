@@ -0,0 +1,138 @@
+package swhid
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// withSmallChunks makes FromContentReader read in chunks of a few bytes, so
+// that tests cover content spanning many of them.
+func withSmallChunks(t *testing.T) {
+	size, chunks := streamChunkSize, streamChunks
+	streamChunkSize, streamChunks = 7, 2
+	t.Cleanup(func() { streamChunkSize, streamChunks = size, chunks })
+}
+
+func TestFromContentReader(t *testing.T) {
+	withSmallChunks(t)
+	for _, n := range []int{0, 1, 6, 7, 8, 14, 100, 1000} {
+		data := bytes.Repeat([]byte("abcdefghijk"), n)[:n]
+		id, err := FromContentReader(iotest.HalfReader(bytes.NewReader(data)), int64(n))
+		if err != nil {
+			t.Fatalf("FromContentReader(%d bytes) error = %v", n, err)
+		}
+		if want := FromContent(data); !id.Equal(want) {
+			t.Errorf("FromContentReader(%d bytes) = %v, want %v", n, id, want)
+		}
+	}
+}
+
+func TestFromContentReaderSizeMismatch(t *testing.T) {
+	withSmallChunks(t)
+	data := []byte("hello world, hello world")
+	for _, size := range []int64{0, 10, int64(len(data)) - 1, int64(len(data)) + 1, 100} {
+		_, err := FromContentReader(bytes.NewReader(data), size)
+		if !errors.Is(err, ErrSizeMismatch) {
+			t.Errorf("FromContentReader(size %d) error = %v, want ErrSizeMismatch", size, err)
+		}
+	}
+	if _, err := FromContentReader(bytes.NewReader(data), -1); err == nil {
+		t.Error("FromContentReader(size -1) succeeded")
+	}
+}
+
+func TestFromContentReaderError(t *testing.T) {
+	withSmallChunks(t)
+	broken := errors.New("disk on fire")
+	r := io.MultiReader(bytes.NewReader(make([]byte, 20)), iotest.ErrReader(broken))
+	if _, err := FromContentReader(r, 100); !errors.Is(err, broken) {
+		t.Errorf("FromContentReader() error = %v, want %v", err, broken)
+	}
+}
+
+func TestFromFile(t *testing.T) {
+	dir := t.TempDir()
+	small := []byte("hello\n")
+	large := bytes.Repeat([]byte("0123456789abcdef"), StreamThreshold/16+1)
+	for name, data := range map[string][]byte{"small": small, "large": large} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		id, err := FromFile(path)
+		if err != nil {
+			t.Fatalf("FromFile(%s) error = %v", name, err)
+		}
+		if want := FromContent(data); !id.Equal(want) {
+			t.Errorf("FromFile(%s) = %v, want %v", name, id, want)
+		}
+	}
+
+	// Large files in a tree are streamed to the same SWHID
+	node, err := TreeFromDirectoryPathWithOptions(dir, TreeOptions{})
+	if err != nil {
+		t.Fatalf("TreeFromDirectoryPathWithOptions() error = %v", err)
+	}
+	for _, child := range node.Children {
+		if child.Name == "large" && (!child.ID.Equal(FromContent(large)) || child.Size != int64(len(large))) {
+			t.Errorf("large file node = %v, %d bytes", child.ID, child.Size)
+		}
+	}
+
+	if _, err := FromFile(dir); err == nil {
+		t.Error("FromFile(directory) succeeded")
+	}
+}
+
+func TestFromFileSymlink(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "target"), []byte("hello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	dangling := filepath.Join(dir, "dangling")
+	if err := os.Symlink("target", link); err != nil {
+		t.Skipf("Cannot create symlinks: %v", err)
+	}
+	if err := os.Symlink("missing", dangling); err != nil {
+		t.Fatal(err)
+	}
+
+	// FromFile follows the link, FromSymlink hashes it as a tree would
+	id, err := FromFile(link)
+	if err != nil {
+		t.Fatalf("FromFile(link) error = %v", err)
+	}
+	if want := FromContent([]byte("hello\n")); !id.Equal(want) {
+		t.Errorf("FromFile(link) = %v, want %v", id, want)
+	}
+	id, err = FromSymlink(link)
+	if err != nil {
+		t.Fatalf("FromSymlink(link) error = %v", err)
+	}
+	node, err := TreeFromDirectoryPath(dir)
+	if err != nil {
+		t.Fatalf("TreeFromDirectoryPath() error = %v", err)
+	}
+	for _, child := range node.Children {
+		if child.Name == "link" && !child.ID.Equal(id) {
+			t.Errorf("FromSymlink(link) = %v, tree has %v", id, child.ID)
+		}
+	}
+
+	if _, err := FromFile(dangling); !errors.Is(err, os.ErrNotExist) || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("FromFile(dangling) error = %v, want a missing target", err)
+	}
+	if id, err := FromSymlink(dangling); err != nil || !id.Equal(FromContent([]byte("missing"))) {
+		t.Errorf("FromSymlink(dangling) = %v, %v", id, err)
+	}
+	if _, err := FromSymlink(filepath.Join(dir, "target")); err == nil {
+		t.Error("FromSymlink(regular file) succeeded")
+	}
+}
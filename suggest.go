@@ -0,0 +1,156 @@
+package swhid
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseError is returned by Parse, and the functions built on it, when the
+// input is not a valid SWHID. It wraps one of the ErrEmptySWHID,
+// ErrInvalidFormat, ErrInvalidScheme, ErrInvalidVersion,
+// ErrInvalidObjectType or ErrInvalidObjectHash errors, and for the usual
+// copy-paste mistakes, such as a misspelled object type or a hash one
+// character too long, suggests how to fix the input.
+type ParseError struct {
+	Input       string
+	Err         error
+	Suggestions []string // e.g. "did you mean rev?"; empty when there is no likely fix
+}
+
+func (e *ParseError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// objectTypeNames are the full names object types are abbreviated from.
+var objectTypeNames = []struct {
+	objectType ObjectType
+	name       string
+}{
+	{ObjectTypeContent, "content"},
+	{ObjectTypeDirectory, "directory"},
+	{ObjectTypeRevision, "revision"},
+	{ObjectTypeRelease, "release"},
+	{ObjectTypeSnapshot, "snapshot"},
+}
+
+// suggestScheme suggests the scheme for a misspelling of it.
+func suggestScheme(scheme string) []string {
+	if strings.EqualFold(scheme, Scheme) || levenshtein(strings.ToLower(scheme), Scheme) <= 1 {
+		return []string{fmt.Sprintf("did you mean %s?", Scheme)}
+	}
+	return nil
+}
+
+// suggestObjectType suggests object types for a misspelled or unabbreviated
+// one: the type it spells in another case, the types whose full name it
+// abbreviates ("snap", "rvs"), or else the types nearest to it by edit
+// distance, if within two edits.
+func suggestObjectType(objectType string) []string {
+	lower := strings.ToLower(objectType)
+
+	var matches []ObjectType
+	for _, t := range objectTypeNames {
+		if lower == string(t.objectType) || lower == t.name ||
+			(len(lower) >= 2 && lower[0] == t.name[0] && isSubsequence(lower, t.name)) {
+			matches = append(matches, t.objectType)
+		}
+	}
+	if len(matches) == 0 {
+		best := 3
+		for _, t := range objectTypeNames {
+			d := levenshtein(lower, string(t.objectType))
+			if d < best {
+				best, matches = d, nil
+			}
+			if d == best && d <= 2 {
+				matches = append(matches, t.objectType)
+			}
+		}
+	}
+
+	suggestions := make([]string, len(matches))
+	for i, t := range matches {
+		suggestions[i] = fmt.Sprintf("did you mean %s?", t)
+	}
+	return suggestions
+}
+
+// suggestHash suggests fixes for a hash that is not 40 lower case hex
+// digits.
+func suggestHash(hash string) []string {
+	var suggestions []string
+	if strings.ToLower(hash) != hash && hashRegex.MatchString(strings.ToLower(hash)) {
+		return []string{"hash must be lower case; lowercase it?"}
+	}
+
+	switch n := len(hash); {
+	case n == ObjectIDLen+1 && isHex(hash[:n-1]):
+		suggestions = append(suggestions, fmt.Sprintf("hash is %d chars; remove trailing character?", n))
+	case n == ObjectIDLen+1 && isHex(hash[1:]):
+		suggestions = append(suggestions, fmt.Sprintf("hash is %d chars; remove leading character?", n))
+	case n > ObjectIDLen && isHex(hash):
+		suggestions = append(suggestions, fmt.Sprintf("hash is %d chars; it may be a longer hash, such as SHA-256, which SWHIDs do not use", n))
+	case n < ObjectIDLen && isHex(hash):
+		suggestions = append(suggestions, fmt.Sprintf("hash is %d chars; %d missing, was it truncated?", n, ObjectIDLen-n))
+	}
+
+	for i := 0; i < len(hash); i++ {
+		if fix, ok := hexLookalikes[hash[i]]; ok {
+			suggestions = append(suggestions, fmt.Sprintf("character %d is %q; did you mean %q?", i+1, hash[i], fix))
+		}
+	}
+	return suggestions
+}
+
+// hexLookalikes maps characters that are not hex digits to the digits they
+// are mistaken for when copied by hand or through OCR.
+var hexLookalikes = map[byte]byte{'o': '0', 'O': '0', 'l': '1', 'I': '1', 'i': '1', 's': '5', 'S': '5'}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if unhex(s[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// isSubsequence reports whether the bytes of s appear in t in order.
+func isSubsequence(s, t string) bool {
+	for i := 0; i < len(t) && len(s) > 0; i++ {
+		if t[i] == s[0] {
+			s = s[1:]
+		}
+	}
+	return s == ""
+}
+
+// levenshtein returns the edit distance between a and b in bytes,
+// counting the swap of two adjacent bytes as one edit, as "shw" for "swh".
+func levenshtein(a, b string) int {
+	rows := make([][]int, len(a)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(b)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+	return rows[len(a)][len(b)]
+}
@@ -0,0 +1,67 @@
+package swhid
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParseSuggestions(t *testing.T) {
+	const hash = "94a9ed024d3859793618152ea559a168bbcbb5e2"
+	tests := []struct {
+		input string
+		err   error
+		want  []string
+	}{
+		{"swh:1:rvs:" + hash, ErrInvalidObjectType, []string{"did you mean rev?"}},
+		{"swh:1:snap:" + hash, ErrInvalidObjectType, []string{"did you mean snp?"}},
+		{"swh:1:dri:" + hash, ErrInvalidObjectType, []string{"did you mean dir?"}},
+		{"swh:1:REV:" + hash, ErrInvalidObjectType, []string{"did you mean rev?"}},
+		{"swh:1:content:" + hash, ErrInvalidObjectType, []string{"did you mean cnt?"}},
+		{"swh:1:xyz:" + hash, ErrInvalidObjectType, nil},
+		{"shw:1:cnt:" + hash, ErrInvalidScheme, []string{"did you mean swh?"}},
+		{"swh:1:cnt:" + hash + "a", ErrInvalidObjectHash, []string{"hash is 41 chars; remove trailing character?"}},
+		{"swh:1:cnt:x" + hash, ErrInvalidObjectHash, []string{"hash is 41 chars; remove leading character?"}},
+		{"swh:1:cnt:" + hash[:39], ErrInvalidObjectHash, []string{"hash is 39 chars; 1 missing, was it truncated?"}},
+		{"swh:1:cnt:94A9ED024D3859793618152EA559A168BBCBB5E2", ErrInvalidObjectHash, []string{"hash must be lower case; lowercase it?"}},
+		{"swh:1:cnt:94a9ed024d38597936l8152ea559a168bbcbb5e2", ErrInvalidObjectHash, []string{`character 19 is 'l'; did you mean '1'?`}},
+		{"swh:2:cnt:" + hash, ErrInvalidVersion, nil},
+		{"", ErrEmptySWHID, nil},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.input)
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("Parse(%q) error = %v, want *ParseError", tt.input, err)
+			continue
+		}
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Parse(%q) error = %v, want %v", tt.input, err, tt.err)
+		}
+		if parseErr.Input != tt.input {
+			t.Errorf("Parse(%q) Input = %q", tt.input, parseErr.Input)
+		}
+		if !slices.Equal(parseErr.Suggestions, tt.want) {
+			t.Errorf("Parse(%q) Suggestions = %q, want %q", tt.input, parseErr.Suggestions, tt.want)
+		}
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "rev", 3},
+		{"rev", "rev", 0},
+		{"rvs", "rev", 2},
+		{"dri", "dir", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
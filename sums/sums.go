@@ -0,0 +1,218 @@
+// Package sums reads and writes SWHIDSUMS files, the SWHID counterpart of
+// SHA256SUMS: one "<swhid>  <path>" line per file or directory. Like a
+// SHA256SUMS file, a SWHIDSUMS file is plain text, so it can be published
+// next to a release and signed with any detached-signature tool; files
+// signed in place with gpg --clearsign are read as well.
+//
+// Paths containing a backslash or a newline are escaped as GNU coreutils
+// does: the line starts with a backslash, and the path has "\\" for a
+// backslash and "\n" for a newline.
+package sums
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/andrew/swhid-go"
+)
+
+// DefaultFilename is the conventional name of a SWHIDSUMS file.
+const DefaultFilename = "SWHIDSUMS"
+
+// ErrMismatch is reported for paths whose SWHID differs from the listed one.
+var ErrMismatch = errors.New("SWHID mismatch")
+
+// Entry is one line of a SWHIDSUMS file.
+type Entry struct {
+	SWHID *swhid.Identifier
+	Path  string
+}
+
+// Result is the outcome of checking one entry.
+type Result struct {
+	Entry
+	Actual *swhid.Identifier // nil if the path could not be hashed
+	Err    error             // nil if the path matches
+}
+
+// Create computes an entry for each path: a content SWHID for regular
+// files and a directory SWHID for directories.
+func Create(paths []string, opts swhid.TreeOptions) ([]Entry, error) {
+	entries := make([]Entry, 0, len(paths))
+	for _, path := range paths {
+		id, err := Compute(path, opts)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{SWHID: id, Path: path})
+	}
+	return entries, nil
+}
+
+// Compute returns the SWHID of a regular file or directory.
+func Compute(path string, opts swhid.TreeOptions) (*swhid.Identifier, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case info.IsDir():
+		node, err := swhid.TreeFromDirectoryPathWithOptions(path, opts)
+		if err != nil {
+			return nil, err
+		}
+		return node.ID, nil
+	case info.Mode().IsRegular():
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return swhid.FromContent(data), nil
+	default:
+		return nil, fmt.Errorf("%s: not a regular file or directory", path)
+	}
+}
+
+// Verify recomputes the SWHID of every entry's path, relative to the
+// working directory. A Result's Err wraps ErrMismatch for changed paths.
+func Verify(entries []Entry, opts swhid.TreeOptions) []Result {
+	results := make([]Result, len(entries))
+	for i, e := range entries {
+		results[i].Entry = e
+		actual, err := Compute(e.Path, opts)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].Actual = actual
+		if actual.CoreSWHID() != e.SWHID.CoreSWHID() {
+			results[i].Err = fmt.Errorf("%s: %w", e.Path, ErrMismatch)
+		}
+	}
+	return results
+}
+
+// Write writes entries in SWHIDSUMS format.
+func Write(w io.Writer, entries []Entry) error {
+	bw := bufio.NewWriter(w)
+	for _, e := range entries {
+		path := e.Path
+		if strings.ContainsAny(path, "\\\n") {
+			path = strings.NewReplacer("\\", "\\\\", "\n", "\\n").Replace(path)
+			bw.WriteByte('\\')
+		}
+		fmt.Fprintf(bw, "%s  %s\n", e.SWHID.CoreSWHID(), path)
+	}
+	return bw.Flush()
+}
+
+// Parse reads a SWHIDSUMS file. Blank lines and lines starting with "#"
+// are skipped. For a clearsigned file, only the signed text is read; the
+// signature itself is not checked.
+func Parse(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNo := 0
+	clearsigned := false
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		lineNo++
+
+		// A clearsigned message has armor headers up to the first blank
+		// line, dash-escapes lines starting with "-", and ends where the
+		// signature block begins.
+		if lineNo == 1 && line == "-----BEGIN PGP SIGNED MESSAGE-----" {
+			clearsigned = true
+			for scanner.Scan() && strings.TrimSpace(scanner.Text()) != "" {
+				lineNo++
+			}
+			lineNo++
+			continue
+		}
+		if clearsigned {
+			if line == "-----BEGIN PGP SIGNATURE-----" {
+				break
+			}
+			line = strings.TrimPrefix(line, "- ")
+		}
+
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entry, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func parseLine(line string) (Entry, error) {
+	escaped := strings.HasPrefix(line, "\\")
+	if escaped {
+		line = line[1:]
+	}
+
+	s, path, ok := strings.Cut(line, "  ")
+	if !ok || path == "" {
+		return Entry{}, fmt.Errorf("expected \"<swhid>  <path>\"")
+	}
+	id, err := swhid.Parse(s)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	if escaped {
+		path, err = unescape(path)
+		if err != nil {
+			return Entry{}, err
+		}
+	}
+	return Entry{SWHID: id, Path: path}, nil
+}
+
+func unescape(path string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] != '\\' {
+			b.WriteByte(path[i])
+			continue
+		}
+		i++
+		switch {
+		case i == len(path):
+			return "", fmt.Errorf("path ends in an escape")
+		case path[i] == '\\':
+			b.WriteByte('\\')
+		case path[i] == 'n':
+			b.WriteByte('\n')
+		default:
+			return "", fmt.Errorf("invalid escape \\%c in path", path[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// ReadFile parses the SWHIDSUMS file at path.
+func ReadFile(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return entries, nil
+}
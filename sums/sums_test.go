@@ -0,0 +1,112 @@
+package sums
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andrew/swhid-go"
+)
+
+func TestCreateWriteParse(t *testing.T) {
+	dir := t.TempDir()
+	weird := filepath.Join(dir, "back\\slash\nnewline")
+	os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello\n"), 0644)
+	os.WriteFile(weird, []byte("hello\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, "src"), 0755)
+	os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main\n"), 0644)
+
+	paths := []string{filepath.Join(dir, "hello.txt"), weird, filepath.Join(dir, "src")}
+	entries, err := Create(paths, swhid.TreeOptions{})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if got := entries[0].SWHID.CoreSWHID(); got != "swh:1:cnt:ce013625030ba8dba906f756967f9e9ca394464a" {
+		t.Errorf("file SWHID = %s", got)
+	}
+	if entries[2].SWHID.ObjectType != swhid.ObjectTypeDirectory {
+		t.Errorf("directory SWHID = %s", entries[2].SWHID)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, entries); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 || lines[0] != "swh:1:cnt:ce013625030ba8dba906f756967f9e9ca394464a  "+paths[0] ||
+		!strings.HasPrefix(lines[1], "\\swh:1:cnt:") || !strings.HasSuffix(lines[1], "back\\\\slash\\nnewline") {
+		t.Errorf("Write() =\n%s", buf.String())
+	}
+
+	parsed, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(parsed) != 3 {
+		t.Fatalf("Parse() returned %d entries, want 3", len(parsed))
+	}
+	for i := range entries {
+		if parsed[i].Path != entries[i].Path || !parsed[i].SWHID.Equal(entries[i].SWHID) {
+			t.Errorf("entry %d = %+v, want %+v", i, parsed[i], entries[i])
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "b")
+	os.WriteFile(a, []byte("a\n"), 0644)
+	os.WriteFile(b, []byte("b\n"), 0644)
+
+	entries, err := Create([]string{a, b, dir}, swhid.TreeOptions{})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	entries = append(entries, Entry{SWHID: entries[0].SWHID, Path: filepath.Join(dir, "missing")})
+	os.WriteFile(b, []byte("changed\n"), 0644)
+
+	results := Verify(entries, swhid.TreeOptions{})
+	if results[0].Err != nil {
+		t.Errorf("unchanged file: %v", results[0].Err)
+	}
+	if !errors.Is(results[1].Err, ErrMismatch) || results[1].Actual == nil {
+		t.Errorf("changed file: %+v", results[1])
+	}
+	if !errors.Is(results[2].Err, ErrMismatch) {
+		t.Errorf("changed directory: %+v", results[2])
+	}
+	if !errors.Is(results[3].Err, os.ErrNotExist) {
+		t.Errorf("missing file: %+v", results[3])
+	}
+}
+
+func TestParseClearsigned(t *testing.T) {
+	input := `-----BEGIN PGP SIGNED MESSAGE-----
+Hash: SHA256
+
+# release 1.0
+swh:1:cnt:ce013625030ba8dba906f756967f9e9ca394464a  hello.txt
+- -dashed.txt
+-----BEGIN PGP SIGNATURE-----
+
+iQEzBAEBCAAdFiEE
+-----END PGP SIGNATURE-----
+`
+	_, err := Parse(strings.NewReader(input))
+	if err == nil || !strings.Contains(err.Error(), "line 6") {
+		t.Errorf("Parse() error = %v, want an error on line 6", err)
+	}
+
+	input = strings.Replace(input, "- -dashed.txt", "swh:1:cnt:ce013625030ba8dba906f756967f9e9ca394464a  -dashed.txt", 1)
+	entries, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(entries) != 2 || entries[0].Path != "hello.txt" || entries[1].Path != "-dashed.txt" {
+		t.Errorf("Parse() = %+v", entries)
+	}
+}
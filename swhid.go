@@ -18,6 +18,8 @@ import (
 	"fmt"
 	"net/url"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
 )
 
@@ -49,7 +51,7 @@ var validObjectTypes = map[ObjectType]bool{
 var hashRegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
 
 // Qualifier keys in canonical order.
-var canonicalQualifierOrder = []string{"origin", "visit", "anchor", "path", "lines", "bytes"}
+var canonicalQualifierOrder = []string{QualifierOrigin, QualifierVisit, QualifierAnchor, QualifierPath, QualifierLines, QualifierBytes}
 
 // Error types
 var (
@@ -93,10 +95,14 @@ func NewIdentifier(objectType ObjectType, objectHash string, qualifiers map[stri
 	}, nil
 }
 
-// Parse parses a SWHID string into an Identifier.
+// Parse parses a SWHID string into an Identifier. Errors are *ParseError.
 func Parse(swhidString string) (*Identifier, error) {
+	parseError := func(err error, suggestions []string) error {
+		return &ParseError{Input: swhidString, Err: err, Suggestions: suggestions}
+	}
+
 	if swhidString == "" {
-		return nil, ErrEmptySWHID
+		return nil, parseError(ErrEmptySWHID, nil)
 	}
 
 	// Split core part from qualifiers
@@ -107,7 +113,7 @@ func Parse(swhidString string) (*Identifier, error) {
 	// Parse core part
 	coreParts := strings.Split(corePart, ":")
 	if len(coreParts) != 4 {
-		return nil, ErrInvalidFormat
+		return nil, parseError(ErrInvalidFormat, nil)
 	}
 
 	scheme := coreParts[0]
@@ -116,19 +122,19 @@ func Parse(swhidString string) (*Identifier, error) {
 	objectHash := coreParts[3]
 
 	if scheme != Scheme {
-		return nil, fmt.Errorf("%w: %s", ErrInvalidScheme, scheme)
+		return nil, parseError(fmt.Errorf("%w: %s", ErrInvalidScheme, scheme), suggestScheme(scheme))
 	}
 
 	if versionStr != "1" {
-		return nil, fmt.Errorf("%w: %s", ErrInvalidVersion, versionStr)
+		return nil, parseError(fmt.Errorf("%w: %s", ErrInvalidVersion, versionStr), nil)
 	}
 
 	if !validObjectTypes[objectType] {
-		return nil, fmt.Errorf("%w: %s", ErrInvalidObjectType, objectType)
+		return nil, parseError(fmt.Errorf("%w: %s", ErrInvalidObjectType, objectType), suggestObjectType(string(objectType)))
 	}
 
 	if !hashRegex.MatchString(objectHash) {
-		return nil, fmt.Errorf("%w: must be %d hex digits", ErrInvalidObjectHash, ObjectIDLen)
+		return nil, parseError(fmt.Errorf("%w: must be %d hex digits", ErrInvalidObjectHash, ObjectIDLen), suggestHash(objectHash))
 	}
 
 	// Parse qualifiers
@@ -211,19 +217,17 @@ func formatQualifiers(quals map[string]string) string {
 		}
 	}
 
-	// Add remaining qualifiers
-	for key, value := range quals {
-		isCanonical := false
-		for _, ck := range canonicalQualifierOrder {
-			if key == ck {
-				isCanonical = true
-				break
-			}
-		}
-		if !isCanonical {
-			parts = append(parts, key+"="+encodeQualifierValue(value))
+	// Add remaining qualifiers, sorted so the output is stable
+	var others []string
+	for key := range quals {
+		if !slices.Contains(canonicalQualifierOrder, key) {
+			others = append(others, key)
 		}
 	}
+	sort.Strings(others)
+	for _, key := range others {
+		parts = append(parts, key+"="+encodeQualifierValue(quals[key]))
+	}
 
 	return strings.Join(parts, ";")
 }
@@ -163,6 +163,18 @@ func TestIdentifierString(t *testing.T) {
 			},
 			want: "swh:1:cnt:94a9ed024d3859793618152ea559a168bbcbb5e2;origin=https://example.com",
 		},
+		{
+			name:       "unknown qualifiers sorted after known ones",
+			objectType: ObjectTypeContent,
+			objectHash: "94a9ed024d3859793618152ea559a168bbcbb5e2",
+			qualifiers: map[string]string{
+				"zeta":  "1",
+				"alpha": "2",
+				"path":  "/a",
+				"mid":   "3",
+			},
+			want: "swh:1:cnt:94a9ed024d3859793618152ea559a168bbcbb5e2;path=/a;alpha=2;mid=3;zeta=1",
+		},
 	}
 
 	for _, tt := range tests {
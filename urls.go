@@ -0,0 +1,279 @@
+package swhid
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ArchiveURL is the base URL of the Software Heritage archive.
+const ArchiveURL = "https://archive.softwareheritage.org"
+
+// ErrNotArchiveURL is returned when a URL cannot be mapped to a SWHID.
+var ErrNotArchiveURL = errors.New("not a Software Heritage archive URL")
+
+// archivePaths maps object types to the path segment used by the browse and API endpoints.
+var archivePaths = map[ObjectType]string{
+	ObjectTypeContent:   "content",
+	ObjectTypeDirectory: "directory",
+	ObjectTypeRevision:  "revision",
+	ObjectTypeRelease:   "release",
+	ObjectTypeSnapshot:  "snapshot",
+}
+
+// ResolveURL returns the archive URL that resolves the full SWHID,
+// qualifiers included. It is the same as URLForm.
+func (id *Identifier) ResolveURL() string {
+	return id.URLForm()
+}
+
+// URLForm returns the SWHID in the form browsers show in their address
+// bar, https://archive.softwareheritage.org/swh:1:...;origin=... On top of
+// the escaping of qualifier values in the SWHID itself, "?", "#" and
+// non-ASCII bytes are percent-encoded, so that the whole SWHID stays in the
+// URL path instead of spilling into a query string or fragment.
+func (id *Identifier) URLForm() string {
+	s := id.String()
+	var b strings.Builder
+	b.WriteString(ArchiveURL + "/")
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c == '?' || c == '#' || c >= 0x80 {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// ParseURLForm parses a SWHID copied from a browser address bar: a
+// resolver URL such as https://archive.softwareheritage.org/swh:1:...,
+// with or without its scheme, a /browse/ prefix or a trailing slash, or a
+// bare swh:1:... string. Everything after the object hash, including any
+// "?" or "#", is taken to be part of the qualifiers.
+//
+// Percent-encoded semicolons are decoded by these rules. Literal ";"
+// separate qualifiers and a %3B is a semicolon inside the qualifier value
+// it appears in. A SWHID with no literal ";" but with %3B was escaped
+// whole as one URL path segment, as url.PathEscape does: it is unescaped
+// once first, so %3B separates qualifiers and a doubly escaped %253B is a
+// semicolon in a value.
+func ParseURLForm(s string) (*Identifier, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, Scheme+":") {
+		rest := s
+		if i := strings.Index(rest, "://"); i >= 0 {
+			rest = rest[i+3:]
+		}
+		slash := strings.IndexByte(rest, '/')
+		if slash < 0 {
+			return nil, fmt.Errorf("%w: %s", ErrNotArchiveURL, s)
+		}
+		rest = rest[slash+1:]
+		rest = strings.TrimPrefix(rest, "browse/")
+		rest = strings.TrimPrefix(rest, "api/1/resolve/")
+		if !strings.HasPrefix(rest, Scheme+":") {
+			return nil, fmt.Errorf("%w: %s", ErrNotArchiveURL, s)
+		}
+		s = rest
+	}
+	return parseSWHIDPath(s)
+}
+
+// escapedSemicolon matches a percent-encoded ";".
+var escapedSemicolon = regexp.MustCompile(`%3[Bb]`)
+
+// parseSWHIDPath parses a SWHID taken from a URL path, applying the
+// semicolon rules of ParseURLForm.
+func parseSWHIDPath(p string) (*Identifier, error) {
+	if !strings.Contains(p, ";") {
+		// Escaped whole: the qualifiers are split out at the escaped
+		// semicolons before each part is unescaped, so that one escaped
+		// again inside a value stays in it.
+		parts := escapedSemicolon.Split(p, -1)
+		if len(parts) > 1 {
+			for i, part := range parts {
+				unescaped, err := url.PathUnescape(part)
+				if err != nil {
+					return nil, fmt.Errorf("%w: %v", ErrNotArchiveURL, err)
+				}
+				parts[i] = unescaped
+			}
+			p = strings.Join(parts, ";")
+		}
+	}
+	if !strings.Contains(p, ";") {
+		p = strings.TrimSuffix(p, "/")
+	}
+	return Parse(p)
+}
+
+// BrowseURL returns the web UI URL for the object.
+func (id *Identifier) BrowseURL() string {
+	return ArchiveURL + "/browse/" + archiveObjectPath(id) + "/"
+}
+
+// APIURL returns the REST API URL describing the object.
+func (id *Identifier) APIURL() string {
+	return ArchiveURL + "/api/1/" + archiveObjectPath(id) + "/"
+}
+
+// VaultURL returns the vault API URL for cooking the object into a
+// downloadable bundle, or "" for object types the vault does not support.
+func (id *Identifier) VaultURL() string {
+	switch id.ObjectType {
+	case ObjectTypeDirectory:
+		return ArchiveURL + "/api/1/vault/flat/" + id.CoreSWHID() + "/"
+	case ObjectTypeRevision, ObjectTypeSnapshot:
+		return ArchiveURL + "/api/1/vault/git-bare/" + id.CoreSWHID() + "/"
+	default:
+		return ""
+	}
+}
+
+// QualifiedBrowseURL returns the web UI URL for the object with its
+// qualifiers translated to the form the browser expects: origin, visit and
+// anchor become query parameters, path loses its leading slash and lines
+// become a #L10-L20 fragment. The bytes qualifier has no web equivalent and
+// is dropped.
+func (id *Identifier) QualifiedBrowseURL() string {
+	u := id.BrowseURL()
+
+	query := url.Values{}
+	if origin, ok := id.Qualifiers["origin"]; ok {
+		query.Set("origin_url", origin)
+	}
+	if visit, ok := id.Qualifiers["visit"]; ok {
+		if v, err := Parse(visit); err == nil {
+			query.Set("snapshot", v.ObjectHash)
+		}
+	}
+	if anchor, ok := id.Qualifiers["anchor"]; ok {
+		if a, err := Parse(anchor); err == nil {
+			query.Set(archivePaths[a.ObjectType], a.ObjectHash)
+		}
+	}
+	if p, ok := id.Qualifiers["path"]; ok {
+		query.Set("path", strings.TrimPrefix(p, "/"))
+	}
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	if lines, ok := id.Qualifiers["lines"]; ok {
+		u += "#" + LinesFragment(lines)
+	}
+	return u
+}
+
+// LinesFragment converts a lines qualifier ("10" or "10-20") into the
+// browse URL fragment form ("L10" or "L10-L20").
+func LinesFragment(lines string) string {
+	start, end, found := strings.Cut(lines, "-")
+	if !found {
+		return "L" + start
+	}
+	return "L" + start + "-L" + end
+}
+
+// ParseLinesFragment converts a browse URL fragment ("L10" or "L10-L20")
+// into a lines qualifier ("10" or "10-20"). It returns "" if the fragment is
+// not a line range.
+func ParseLinesFragment(fragment string) string {
+	fragment = strings.TrimPrefix(fragment, "#")
+	start, end, found := strings.Cut(fragment, "-")
+	if !strings.HasPrefix(start, "L") || len(start) == 1 {
+		return ""
+	}
+	if !found {
+		return start[1:]
+	}
+	if !strings.HasPrefix(end, "L") || len(end) == 1 {
+		return ""
+	}
+	return start[1:] + "-" + end[1:]
+}
+
+// browseQualifiers maps the query string and fragment of a browse URL back
+// to SWHID qualifiers.
+func browseQualifiers(u *url.URL) map[string]string {
+	quals := make(map[string]string)
+	query := u.Query()
+
+	if origin := query.Get("origin_url"); origin != "" {
+		quals["origin"] = origin
+	}
+	if snapshot := query.Get("snapshot"); hashRegex.MatchString(snapshot) {
+		quals["visit"] = fmt.Sprintf("%s:%d:%s:%s", Scheme, SchemeVersion, ObjectTypeSnapshot, snapshot)
+	}
+	for _, objectType := range []ObjectType{ObjectTypeRevision, ObjectTypeRelease, ObjectTypeDirectory} {
+		if hash := query.Get(archivePaths[objectType]); hashRegex.MatchString(hash) {
+			quals["anchor"] = fmt.Sprintf("%s:%d:%s:%s", Scheme, SchemeVersion, objectType, hash)
+			break
+		}
+	}
+	if p := query.Get("path"); p != "" {
+		quals["path"] = "/" + strings.TrimPrefix(p, "/")
+	}
+	if lines := ParseLinesFragment(u.Fragment); lines != "" {
+		quals["lines"] = lines
+	}
+	return quals
+}
+
+func archiveObjectPath(id *Identifier) string {
+	if id.ObjectType == ObjectTypeContent {
+		return "content/sha1_git:" + id.ObjectHash
+	}
+	return archivePaths[id.ObjectType] + "/" + id.ObjectHash
+}
+
+// ParseArchiveURL converts an archive.softwareheritage.org URL back into a
+// SWHID. It understands resolver URLs (/swh:1:...), browse URLs and API URLs
+// on the archive's host; URLs on other hosts are an ErrNotArchiveURL.
+// Query parameters and #L fragments on browse URLs are mapped back to
+// qualifiers, reversing QualifiedBrowseURL. SWHIDs in the path are decoded
+// by the semicolon rules of ParseURLForm.
+func ParseArchiveURL(rawURL string) (*Identifier, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotArchiveURL, err)
+	}
+	if archive, _ := url.Parse(ArchiveURL); !strings.EqualFold(u.Hostname(), archive.Hostname()) {
+		return nil, fmt.Errorf("%w: %s is not %s", ErrNotArchiveURL, u.Host, archive.Host)
+	}
+
+	p := strings.Trim(u.EscapedPath(), "/")
+	p = strings.TrimPrefix(p, "browse/")
+	p = strings.TrimPrefix(p, "api/1/resolve/")
+	p = strings.TrimPrefix(p, "api/1/vault/flat/")
+	p = strings.TrimPrefix(p, "api/1/vault/git-bare/")
+	p = strings.TrimPrefix(p, "api/1/")
+
+	if strings.HasPrefix(p, Scheme+":") {
+		return parseSWHIDPath(p)
+	}
+
+	segments := strings.Split(p, "/")
+	if len(segments) < 2 {
+		return nil, fmt.Errorf("%w: %s", ErrNotArchiveURL, rawURL)
+	}
+
+	for objectType, name := range archivePaths {
+		if segments[0] != name {
+			continue
+		}
+		hash := segments[1]
+		if objectType == ObjectTypeContent {
+			if !strings.HasPrefix(hash, "sha1_git:") {
+				return nil, fmt.Errorf("%w: content must be addressed by sha1_git", ErrNotArchiveURL)
+			}
+			hash = strings.TrimPrefix(hash, "sha1_git:")
+		}
+		return NewIdentifier(objectType, hash, browseQualifiers(u))
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrNotArchiveURL, rawURL)
+}
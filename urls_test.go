@@ -0,0 +1,243 @@
+package swhid
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIdentifierURLs(t *testing.T) {
+	dir, _ := NewIdentifier(ObjectTypeDirectory, "d198bc9d7a6bcf6db04f476d29314f157507d505", nil)
+
+	if got, want := dir.ResolveURL(), "https://archive.softwareheritage.org/swh:1:dir:d198bc9d7a6bcf6db04f476d29314f157507d505"; got != want {
+		t.Errorf("ResolveURL() = %v, want %v", got, want)
+	}
+	if got, want := dir.BrowseURL(), "https://archive.softwareheritage.org/browse/directory/d198bc9d7a6bcf6db04f476d29314f157507d505/"; got != want {
+		t.Errorf("BrowseURL() = %v, want %v", got, want)
+	}
+	if got, want := dir.APIURL(), "https://archive.softwareheritage.org/api/1/directory/d198bc9d7a6bcf6db04f476d29314f157507d505/"; got != want {
+		t.Errorf("APIURL() = %v, want %v", got, want)
+	}
+	if got, want := dir.VaultURL(), "https://archive.softwareheritage.org/api/1/vault/flat/swh:1:dir:d198bc9d7a6bcf6db04f476d29314f157507d505/"; got != want {
+		t.Errorf("VaultURL() = %v, want %v", got, want)
+	}
+
+	cnt, _ := NewIdentifier(ObjectTypeContent, "94a9ed024d3859793618152ea559a168bbcbb5e2", nil)
+	if got, want := cnt.BrowseURL(), "https://archive.softwareheritage.org/browse/content/sha1_git:94a9ed024d3859793618152ea559a168bbcbb5e2/"; got != want {
+		t.Errorf("BrowseURL() = %v, want %v", got, want)
+	}
+	if cnt.VaultURL() != "" {
+		t.Errorf("VaultURL() = %v, want empty for content", cnt.VaultURL())
+	}
+}
+
+func TestParseArchiveURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "resolver URL",
+			input: "https://archive.softwareheritage.org/swh:1:rev:309cf2674ee7a0749978cf8265ab91a60aea0f7d",
+			want:  "swh:1:rev:309cf2674ee7a0749978cf8265ab91a60aea0f7d",
+		},
+		{
+			name:  "browse SWHID URL with qualifiers",
+			input: "https://archive.softwareheritage.org/browse/swh:1:cnt:94a9ed024d3859793618152ea559a168bbcbb5e2;origin=https://example.com/",
+			want:  "swh:1:cnt:94a9ed024d3859793618152ea559a168bbcbb5e2;origin=https://example.com",
+		},
+		{
+			name:  "resolver URL with an escaped semicolon in a qualifier",
+			input: "https://archive.softwareheritage.org/swh:1:rev:309cf2674ee7a0749978cf8265ab91a60aea0f7d;origin=https://example.com/a%3Bb",
+			want:  "swh:1:rev:309cf2674ee7a0749978cf8265ab91a60aea0f7d;origin=https://example.com/a%3Bb",
+		},
+		{
+			name:  "resolver URL escaped whole, with a semicolon escaped again in a qualifier",
+			input: "https://archive.softwareheritage.org/swh:1:rev:309cf2674ee7a0749978cf8265ab91a60aea0f7d%3Borigin=https:%2F%2Fexample.com%2Fa%253Bb%3Blines=3",
+			want:  "swh:1:rev:309cf2674ee7a0749978cf8265ab91a60aea0f7d;origin=https://example.com/a%3Bb;lines=3",
+		},
+		{
+			name:  "browse content URL",
+			input: "https://archive.softwareheritage.org/browse/content/sha1_git:94a9ed024d3859793618152ea559a168bbcbb5e2/",
+			want:  "swh:1:cnt:94a9ed024d3859793618152ea559a168bbcbb5e2",
+		},
+		{
+			name:  "API snapshot URL",
+			input: "https://archive.softwareheritage.org/api/1/snapshot/c7c108084bc0bf3d81436bf980b46e98bd338453/",
+			want:  "swh:1:snp:c7c108084bc0bf3d81436bf980b46e98bd338453",
+		},
+		{
+			name:  "vault URL",
+			input: "https://archive.softwareheritage.org/api/1/vault/flat/swh:1:dir:d198bc9d7a6bcf6db04f476d29314f157507d505/",
+			want:  "swh:1:dir:d198bc9d7a6bcf6db04f476d29314f157507d505",
+		},
+		{
+			name:    "content by sha256",
+			input:   "https://archive.softwareheritage.org/browse/content/sha256:abc/",
+			wantErr: true,
+		},
+		{
+			name:    "browse URL on another host",
+			input:   "https://example.com/browse/swh:1:cnt:94a9ed024d3859793618152ea559a168bbcbb5e2",
+			wantErr: true,
+		},
+		{
+			name:    "resolver URL without a host",
+			input:   "/swh:1:cnt:94a9ed024d3859793618152ea559a168bbcbb5e2",
+			wantErr: true,
+		},
+		{
+			name:    "unrelated URL",
+			input:   "https://example.com/foo",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := ParseArchiveURL(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseArchiveURL() expected error, got %v", id)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseArchiveURL() error = %v", err)
+			}
+			if id.String() != tt.want {
+				t.Errorf("ParseArchiveURL() = %v, want %v", id.String(), tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseArchiveURL("https://example.com/foo"); !errors.Is(err, ErrNotArchiveURL) {
+		t.Errorf("ParseArchiveURL() error = %v, want ErrNotArchiveURL", err)
+	}
+}
+
+func TestURLForm(t *testing.T) {
+	id, _ := NewIdentifier(ObjectTypeContent, "94a9ed024d3859793618152ea559a168bbcbb5e2", map[string]string{
+		QualifierOrigin: "https://example.com/a;b?tab=1#top",
+		QualifierPath:   "/src/café.c",
+	})
+
+	want := "https://archive.softwareheritage.org/swh:1:cnt:94a9ed024d3859793618152ea559a168bbcbb5e2" +
+		";origin=https://example.com/a%3Bb%3Ftab=1%23top;path=/src/caf%C3%A9.c"
+	if got := id.URLForm(); got != want {
+		t.Errorf("URLForm() = %v, want %v", got, want)
+	}
+	if got := id.ResolveURL(); got != want {
+		t.Errorf("ResolveURL() = %v, want %v", got, want)
+	}
+
+	back, err := ParseURLForm(id.URLForm())
+	if err != nil {
+		t.Fatalf("ParseURLForm() error = %v", err)
+	}
+	if !back.Equal(id) {
+		t.Errorf("ParseURLForm(URLForm()) = %v, want %v", back, id)
+	}
+}
+
+func TestParseURLForm(t *testing.T) {
+	const core = "swh:1:dir:d198bc9d7a6bcf6db04f476d29314f157507d505"
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "bare SWHID", input: core, want: core},
+		{name: "bare SWHID with qualifiers", input: core + ";origin=https://example.com/repo", want: core + ";origin=https://example.com/repo"},
+		{name: "resolver URL", input: "https://archive.softwareheritage.org/" + core, want: core},
+		{name: "without scheme", input: "archive.softwareheritage.org/" + core, want: core},
+		{name: "browse prefix and trailing slash", input: "https://archive.softwareheritage.org/browse/" + core + "/", want: core},
+		{
+			name:  "escaped semicolon in a value",
+			input: "https://archive.softwareheritage.org/" + core + ";origin=https://example.com/a%3Bb;path=/src",
+			want:  core + ";origin=https://example.com/a%3Bb;path=/src",
+		},
+		{
+			name:  "whole SWHID escaped as a path segment",
+			input: "https://archive.softwareheritage.org/" + core + "%3Borigin=https:%2F%2Fexample.com%2Fa%253Bb%3Bpath=%2Fsrc",
+			want:  core + ";origin=https://example.com/a%3Bb;path=/src",
+		},
+		{
+			name:  "query string inside a qualifier",
+			input: "https://archive.softwareheritage.org/" + core + ";origin=https://example.com/?repo=a",
+			want:  core + ";origin=https://example.com/?repo=a",
+		},
+		{name: "unrelated URL", input: "https://example.com/foo", wantErr: true},
+		{name: "invalid SWHID", input: "https://archive.softwareheritage.org/swh:1:dir:abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := ParseURLForm(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseURLForm() expected error, got %v", id)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseURLForm() error = %v", err)
+			}
+			if id.String() != tt.want {
+				t.Errorf("ParseURLForm() = %v, want %v", id.String(), tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseURLForm("https://example.com/foo"); !errors.Is(err, ErrNotArchiveURL) {
+		t.Errorf("ParseURLForm() error = %v, want ErrNotArchiveURL", err)
+	}
+}
+
+func TestQualifiedBrowseURL(t *testing.T) {
+	id, _ := Parse("swh:1:cnt:94a9ed024d3859793618152ea559a168bbcbb5e2;origin=https://github.com/example/repo;visit=swh:1:snp:c7c108084bc0bf3d81436bf980b46e98bd338453;anchor=swh:1:rev:309cf2674ee7a0749978cf8265ab91a60aea0f7d;path=/src/main.go;lines=10-20")
+
+	want := "https://archive.softwareheritage.org/browse/content/sha1_git:94a9ed024d3859793618152ea559a168bbcbb5e2/" +
+		"?origin_url=https%3A%2F%2Fgithub.com%2Fexample%2Frepo&path=src%2Fmain.go" +
+		"&revision=309cf2674ee7a0749978cf8265ab91a60aea0f7d&snapshot=c7c108084bc0bf3d81436bf980b46e98bd338453#L10-L20"
+
+	got := id.QualifiedBrowseURL()
+	if got != want {
+		t.Errorf("QualifiedBrowseURL() = %v, want %v", got, want)
+	}
+
+	back, err := ParseArchiveURL(got)
+	if err != nil {
+		t.Fatalf("ParseArchiveURL() error = %v", err)
+	}
+	if !back.Equal(id) {
+		t.Errorf("ParseArchiveURL() = %v, want %v", back, id)
+	}
+}
+
+func TestLinesFragment(t *testing.T) {
+	tests := []struct {
+		lines    string
+		fragment string
+	}{
+		{"10", "L10"},
+		{"10-20", "L10-L20"},
+	}
+
+	for _, tt := range tests {
+		if got := LinesFragment(tt.lines); got != tt.fragment {
+			t.Errorf("LinesFragment(%q) = %v, want %v", tt.lines, got, tt.fragment)
+		}
+		if got := ParseLinesFragment(tt.fragment); got != tt.lines {
+			t.Errorf("ParseLinesFragment(%q) = %v, want %v", tt.fragment, got, tt.lines)
+		}
+	}
+
+	for _, bad := range []string{"", "L", "foo", "L10-20", "L10-L"} {
+		if got := ParseLinesFragment(bad); got != "" {
+			t.Errorf("ParseLinesFragment(%q) = %v, want empty", bad, got)
+		}
+	}
+}
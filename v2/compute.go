@@ -0,0 +1,209 @@
+package swhid
+
+import (
+	"context"
+	"io"
+	"os"
+
+	v1 "github.com/andrew/swhid-go"
+	"github.com/andrew/swhid-go/objects"
+)
+
+// DirectoryOptions configures FromDirectory. The zero value hashes the
+// directory as Software Heritage would archive it.
+type DirectoryOptions struct {
+	// Include and Exclude filter the entries hashed, with path.Match
+	// patterns; see the version 1 TreeOptions.
+	Include []string
+	Exclude []string
+
+	// SkipOSJunk also excludes v1.OSJunk, the metadata files of macOS and
+	// Windows file managers.
+	SkipOSJunk bool
+
+	// FollowSymlinks hashes what symbolic links point to instead of the
+	// links, which Software Heritage does not do.
+	FollowSymlinks bool
+
+	// NoHardLinkReuse hashes every hard link to a file separately.
+	NoHardLinkReuse bool
+
+	// MaxDepth bounds directory nesting; 0 means v1.DefaultMaxDepth and a
+	// negative value means no limit.
+	MaxDepth int
+
+	// Modes sets the mode of files by path or pattern, and ModeFunc
+	// decides it for every file; see the version 1 TreeOptions.
+	Modes    map[string]os.FileMode
+	ModeFunc func(relPath string, info os.FileInfo) os.FileMode
+
+	// VCSDirs names the version control metadata skipped at any depth; nil
+	// means v1.DefaultVCSDirs and an empty slice skips nothing.
+	VCSDirs []string
+
+	// UseGitIndex takes the executable bits of files tracked by the index
+	// of an enclosing Git repository from the index, as version 1 does by
+	// default, and the SWHIDs of those unchanged since they were staged
+	// too, without reading them. It is off by default: modes come from
+	// Modes, ModeFunc and the filesystem alone, so a vendored tree hashes
+	// the same inside a repository and out of it.
+	UseGitIndex bool
+
+	// ReadOnlyFS opens the enclosing Git repository, with UseGitIndex,
+	// without writing to it.
+	ReadOnlyFS bool
+
+	// Concurrency, when above 1, hashes up to that many files at once,
+	// in the order Scheduler gives; nil means v1.LargestFirst.
+	Concurrency int
+	Scheduler   v1.Scheduler
+
+	// Warn, when set, is told of special files skipped, Git LFS pointers
+	// and other things that did not stop hashing; see the version 1
+	// TreeOptions.
+	Warn func(Warning)
+}
+
+// Warning is something hashing a directory did without failing; its Kind
+// is one of the v1.Warn* constants.
+type Warning = v1.Warning
+
+// RefPolicy selects the references included in a snapshot.
+type RefPolicy = v1.RefPolicy
+
+const (
+	RefsLoader          = v1.RefsLoader
+	RefsAll             = v1.RefsAll
+	RefsBranchesAndTags = v1.RefsBranchesAndTags
+)
+
+// GitOptions configures the functions reading Git repositories. The zero
+// value computes what the Software Heritage loader would.
+type GitOptions struct {
+	// ReplaceRefs applies refs/replace/* to commits and tags.
+	ReplaceRefs bool
+
+	// Grafts applies the repository's info/grafts file.
+	Grafts bool
+
+	// Refs selects which references a snapshot lists.
+	Refs RefPolicy
+
+	// Refspecs lists in a snapshot only the references fetched with them,
+	// under their names in the origin, and Branches adds branches known
+	// from elsewhere for the others; see the version 1 GitOptions.
+	Refspecs []string
+	Branches []objects.Branch
+
+	// Head, if set, replaces the repository's HEAD in a snapshot, with a
+	// reference name or a 40-hex object name.
+	Head string
+
+	// ReadOnlyFS opens the repository through filesystems that refuse
+	// writes.
+	ReadOnlyFS bool
+}
+
+func (o GitOptions) v1() v1.GitOptions {
+	return v1.GitOptions{
+		ReplaceRefs: o.ReplaceRefs,
+		Grafts:      o.Grafts,
+		Refs:        o.Refs,
+		Refspecs:    o.Refspecs,
+		Branches:    o.Branches,
+		Head:        o.Head,
+		ReadOnlyFS:  o.ReadOnlyFS,
+	}
+}
+
+// FromContent returns the SWHID of a file's contents.
+func FromContent(data []byte) Identifier {
+	return fromV1(v1.FromContent(data))
+}
+
+// FromContentReader returns the SWHID of the size bytes read from r,
+// reading ahead while it hashes so that large files are hashed as fast as
+// they can be read, without holding them in memory.
+func FromContentReader(r io.Reader, size int64) (Identifier, error) {
+	id, err := v1.FromContentReader(r, size)
+	if err != nil {
+		return Identifier{}, &Error{Op: "content", Err: err}
+	}
+	return fromV1(id), nil
+}
+
+// FromFile returns the SWHID of the contents of the file at path, streamed
+// when large. Symlinks are followed; see the version 1 FromFile.
+func FromFile(path string) (Identifier, error) {
+	id, err := v1.FromFile(path)
+	if err != nil {
+		return Identifier{}, &Error{Op: "content", Input: path, Err: err}
+	}
+	return fromV1(id), nil
+}
+
+// FromDirectory hashes the directory at path. Cancellation is checked
+// before each entry is read.
+func FromDirectory(ctx context.Context, path string, opts DirectoryOptions) (Identifier, error) {
+	node, err := v1.TreeFromDirectoryPathContext(ctx, path, v1.TreeOptions{
+		Include:         opts.Include,
+		Exclude:         opts.Exclude,
+		SkipOSJunk:      opts.SkipOSJunk,
+		FollowSymlinks:  opts.FollowSymlinks,
+		NoHardLinkReuse: opts.NoHardLinkReuse,
+		MaxDepth:        opts.MaxDepth,
+		Modes:           opts.Modes,
+		ModeFunc:        opts.ModeFunc,
+		VCSDirs:         opts.VCSDirs,
+		NoGitIndex:      !opts.UseGitIndex,
+		ReadOnlyFS:      opts.ReadOnlyFS,
+		Concurrency:     opts.Concurrency,
+		Scheduler:       opts.Scheduler,
+		Warn:            opts.Warn,
+	})
+	if err != nil {
+		return Identifier{}, &Error{Op: "directory", Input: path, Err: err}
+	}
+	return fromV1(node.ID), nil
+}
+
+// FromRevision returns the SWHID of the commit ref names in the repository
+// at repoPath. ref may be any git rev-parse expression, such as "HEAD~3^2"
+// or "stash@{0}"; one that cannot be resolved wraps a *v1.RevisionError.
+func FromRevision(ctx context.Context, repoPath, ref string, opts GitOptions) (Identifier, error) {
+	return fromRepo(ctx, "revision", repoPath, func() (*v1.Identifier, error) {
+		return v1.FromRevisionWithOptions(repoPath, ref, opts.v1())
+	})
+}
+
+// FromRelease returns the SWHID of the annotated tag tagName in the
+// repository at repoPath.
+func FromRelease(ctx context.Context, repoPath, tagName string, opts GitOptions) (Identifier, error) {
+	return fromRepo(ctx, "release", repoPath, func() (*v1.Identifier, error) {
+		return v1.FromReleaseWithOptions(repoPath, tagName, opts.v1())
+	})
+}
+
+// FromSnapshot returns the SWHID of the snapshot of the repository at
+// repoPath.
+func FromSnapshot(ctx context.Context, repoPath string, opts GitOptions) (Identifier, error) {
+	return fromRepo(ctx, "snapshot", repoPath, func() (*v1.Identifier, error) {
+		return v1.FromSnapshotWithOptions(repoPath, opts.v1())
+	})
+}
+
+// fromRepo runs a version 1 computation on a repository. Those do not take
+// a context, so ctx is checked before it starts and once it returns.
+func fromRepo(ctx context.Context, op, repoPath string, compute func() (*v1.Identifier, error)) (Identifier, error) {
+	if err := ctx.Err(); err != nil {
+		return Identifier{}, &Error{Op: op, Input: repoPath, Err: err}
+	}
+	id, err := compute()
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return Identifier{}, &Error{Op: op, Input: repoPath, Err: err}
+	}
+	return fromV1(id), nil
+}
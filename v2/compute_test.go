@@ -0,0 +1,188 @@
+package swhid
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/andrew/swhid-go"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestFromContent(t *testing.T) {
+	if got := FromContent([]byte("Hello, World!")).String(); got != "swh:1:cnt:b45ef6fec89518d314f546fd6c3025367b721684" {
+		t.Errorf("FromContent() = %v", got)
+	}
+}
+
+func TestFromContentReader(t *testing.T) {
+	id, err := FromContentReader(strings.NewReader("Hello, World!"), 13)
+	if err != nil {
+		t.Fatalf("FromContentReader() error = %v", err)
+	}
+	if got := id.String(); got != "swh:1:cnt:b45ef6fec89518d314f546fd6c3025367b721684" {
+		t.Errorf("FromContentReader() = %v", got)
+	}
+	var e *Error
+	if _, err := FromContentReader(strings.NewReader("Hello"), 13); !errors.As(err, &e) || !errors.Is(err, ErrSizeMismatch) {
+		t.Errorf("FromContentReader(short) error = %v, want *Error wrapping ErrSizeMismatch", err)
+	}
+}
+
+func TestFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hello.txt")
+	if err := os.WriteFile(path, []byte("Hello, World!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	id, err := FromFile(path)
+	if err != nil {
+		t.Fatalf("FromFile() error = %v", err)
+	}
+	if got := id.String(); got != "swh:1:cnt:b45ef6fec89518d314f546fd6c3025367b721684" {
+		t.Errorf("FromFile() = %v", got)
+	}
+	var e *Error
+	if _, err := FromFile(path + ".missing"); !errors.As(err, &e) || e.Input != path+".missing" {
+		t.Errorf("FromFile(missing) error = %v, want *Error naming the path", err)
+	}
+}
+
+func TestFromDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "debug.log"), []byte("noise\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := FromDirectory(context.Background(), dir, DirectoryOptions{Exclude: []string{"*.log"}})
+	if err != nil {
+		t.Fatalf("FromDirectory() error = %v", err)
+	}
+
+	os.Remove(filepath.Join(dir, "debug.log"))
+	want, err := v1.FromDirectoryPath(dir)
+	if err != nil {
+		t.Fatalf("FromDirectoryPath() error = %v", err)
+	}
+	if id.String() != want.String() {
+		t.Errorf("FromDirectory() = %v, want %v", id, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = FromDirectory(ctx, dir, DirectoryOptions{})
+	var swhidErr *Error
+	if !errors.Is(err, context.Canceled) || !errors.As(err, &swhidErr) || swhidErr.Input != dir {
+		t.Errorf("FromDirectory() error = %v, want *Error wrapping context.Canceled", err)
+	}
+}
+
+func TestFromDirectoryUseGitIndex(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("PlainInit() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Add("hello.txt"); err != nil {
+		t.Fatal(err)
+	}
+	// Staged as a plain file, executable on disk
+	if err := os.Chmod(filepath.Join(dir, "hello.txt"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for _, useGitIndex := range []bool{false, true} {
+		id, err := FromDirectory(ctx, dir, DirectoryOptions{UseGitIndex: useGitIndex})
+		if err != nil {
+			t.Fatalf("FromDirectory() error = %v", err)
+		}
+		want, err := v1.TreeFromDirectoryPathWithOptions(dir, v1.TreeOptions{NoGitIndex: !useGitIndex})
+		if err != nil {
+			t.Fatalf("TreeFromDirectoryPathWithOptions() error = %v", err)
+		}
+		if id.String() != want.ID.String() {
+			t.Errorf("UseGitIndex %v: FromDirectory() = %v, want %v", useGitIndex, id, want.ID)
+		}
+	}
+	fsOnly, _ := FromDirectory(ctx, dir, DirectoryOptions{})
+	withIndex, _ := FromDirectory(ctx, dir, DirectoryOptions{UseGitIndex: true})
+	if fsOnly == withIndex {
+		t.Errorf("FromDirectory() = %v with and without the index", fsOnly)
+	}
+}
+
+func TestFromRepository(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("PlainInit() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Add("hello.txt"); err != nil {
+		t.Fatal(err)
+	}
+	sig := &object.Signature{Name: "Test", Email: "test@example.com", When: time.Unix(1000000000, 0).UTC()}
+	hash, err := wt.Commit("Initial commit\n", &git.CommitOptions{Author: sig, Committer: sig})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateTag("v1.0", hash, &git.CreateTagOptions{Tagger: sig, Message: "Release\n"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	rev, err := FromRevision(ctx, dir, "HEAD", GitOptions{})
+	if err != nil {
+		t.Fatalf("FromRevision() error = %v", err)
+	}
+	if rev.ObjectType() != ObjectTypeRevision || rev.Hash() != hash.String() {
+		t.Errorf("FromRevision() = %v, want revision %s", rev, hash)
+	}
+
+	rel, err := FromRelease(ctx, dir, "v1.0", GitOptions{})
+	if err != nil {
+		t.Fatalf("FromRelease() error = %v", err)
+	}
+	if want, _ := v1.FromRelease(dir, "v1.0"); rel.String() != want.String() {
+		t.Errorf("FromRelease() = %v, want %v", rel, want)
+	}
+
+	snp, err := FromSnapshot(ctx, dir, GitOptions{Refs: RefsBranchesAndTags})
+	if err != nil {
+		t.Fatalf("FromSnapshot() error = %v", err)
+	}
+	if snp.ObjectType() != ObjectTypeSnapshot {
+		t.Errorf("FromSnapshot() = %v", snp)
+	}
+
+	if _, err := FromRevision(ctx, dir, "missing", GitOptions{}); err == nil {
+		t.Error("FromRevision() of a missing ref succeeded")
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := FromSnapshot(canceled, dir, GitOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("FromSnapshot() error = %v, want context.Canceled", err)
+	}
+}
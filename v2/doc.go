@@ -0,0 +1,25 @@
+// Package swhid is version 2 of the swhid-go API for Software Heritage
+// Identifiers (SWHIDs).
+//
+// Version 1 (github.com/andrew/swhid-go) grew one feature at a time, and
+// its shape makes each new one a breaking change or another *WithOptions
+// variant: Identifier is a mutable struct whose exported fields callers
+// can change under each other, computations that may take minutes take no
+// context, and options are positional arguments. Version 2 settles the
+// conventions new features are added under:
+//
+//   - Identifier is an immutable, comparable value. Two identifiers are the
+//     same SWHID exactly when they are ==, and they can be map keys.
+//     Changes such as adding a qualifier return a new Identifier.
+//   - Every function that reads files or repositories takes a
+//     context.Context first and stops with its error once it is done.
+//   - Options are passed as structs, never as extra parameters, so new
+//     options are new fields.
+//   - Errors from operations are *Error, naming the operation and the path
+//     or input it failed on and wrapping the cause. The sentinel errors are
+//     those of version 1, so errors.Is works the same with either version.
+//
+// Version 2 is built on version 1, which stays supported: FromV1 and
+// Identifier.V1 convert identifiers between the two, so a program can move
+// one package at a time.
+package swhid
@@ -0,0 +1,45 @@
+package swhid
+
+import (
+	v1 "github.com/andrew/swhid-go"
+)
+
+// Sentinel errors, shared with version 1 so errors.Is matches errors from
+// either version.
+var (
+	ErrEmptySWHID        = v1.ErrEmptySWHID
+	ErrInvalidFormat     = v1.ErrInvalidFormat
+	ErrInvalidScheme     = v1.ErrInvalidScheme
+	ErrInvalidVersion    = v1.ErrInvalidVersion
+	ErrInvalidObjectType = v1.ErrInvalidObjectType
+	ErrInvalidObjectHash = v1.ErrInvalidObjectHash
+	ErrInvalidQualifier  = v1.ErrInvalidQualifier
+	ErrSymlinkLoop       = v1.ErrSymlinkLoop
+	ErrTreeTooDeep       = v1.ErrTreeTooDeep
+	ErrReadOnlyFS        = v1.ErrReadOnlyFS
+	ErrSizeMismatch      = v1.ErrSizeMismatch
+
+	ErrUnsupportedRevision = v1.ErrUnsupportedRevision
+	ErrAmbiguousRevision   = v1.ErrAmbiguousRevision
+)
+
+// Error is returned by every operation of this package that fails. It
+// names the operation and what it was applied to, and wraps the cause:
+// one of the sentinel errors, a context error, or an error from the file
+// system or repository.
+type Error struct {
+	Op    string // "parse", "new", "convert", "content", "directory", "revision", "release" or "snapshot"
+	Input string // the string parsed, or the path of the directory or repository
+	Err   error
+}
+
+func (e *Error) Error() string {
+	if e.Input == "" {
+		return "swhid: " + e.Op + ": " + e.Err.Error()
+	}
+	return "swhid: " + e.Op + " " + e.Input + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,223 @@
+package swhid
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	v1 "github.com/andrew/swhid-go"
+)
+
+// ObjectType is the type of object a SWHID identifies.
+type ObjectType = v1.ObjectType
+
+const (
+	ObjectTypeContent   = v1.ObjectTypeContent
+	ObjectTypeDirectory = v1.ObjectTypeDirectory
+	ObjectTypeRevision  = v1.ObjectTypeRevision
+	ObjectTypeRelease   = v1.ObjectTypeRelease
+	ObjectTypeSnapshot  = v1.ObjectTypeSnapshot
+)
+
+// Qualifier keys.
+const (
+	QualifierOrigin = v1.QualifierOrigin
+	QualifierVisit  = v1.QualifierVisit
+	QualifierAnchor = v1.QualifierAnchor
+	QualifierPath   = v1.QualifierPath
+	QualifierLines  = v1.QualifierLines
+	QualifierBytes  = v1.QualifierBytes
+)
+
+// Identifier is a SWHID. It is immutable and comparable: == compares the
+// object type, hash and qualifiers. The zero Identifier is not a valid
+// SWHID; see IsZero.
+type Identifier struct {
+	objectType ObjectType
+	hash       string // 40 lower case hex digits
+	qualifiers string // canonical ";key=value" suffix, encoded; empty without qualifiers
+}
+
+// New returns the core SWHID of an object.
+func New(objectType ObjectType, hash string) (Identifier, error) {
+	id, err := v1.NewIdentifier(objectType, hash, nil)
+	if err != nil {
+		return Identifier{}, &Error{Op: "new", Input: hash, Err: err}
+	}
+	return fromV1(id), nil
+}
+
+// Parse parses a SWHID. Its errors wrap a *v1.ParseError, which suggests
+// fixes for common typos.
+func Parse(s string) (Identifier, error) {
+	id, err := v1.Parse(s)
+	if err != nil {
+		return Identifier{}, &Error{Op: "parse", Input: s, Err: err}
+	}
+	return fromV1(id), nil
+}
+
+// MustParse is like Parse but panics if s is not a valid SWHID. It is
+// meant for constants in programs and tests.
+func MustParse(s string) Identifier {
+	id, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// ObjectType returns the type of the identified object.
+func (id Identifier) ObjectType() ObjectType {
+	return id.objectType
+}
+
+// Hash returns the object hash in hex.
+func (id Identifier) Hash() string {
+	return id.hash
+}
+
+// IsZero reports whether id is the zero Identifier.
+func (id Identifier) IsZero() bool {
+	return id == Identifier{}
+}
+
+// Core returns id without its qualifiers.
+func (id Identifier) Core() Identifier {
+	return Identifier{objectType: id.objectType, hash: id.hash}
+}
+
+// String returns the canonical form of the SWHID, qualifiers included.
+func (id Identifier) String() string {
+	if id.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d:%s:%s%s", v1.Scheme, v1.SchemeVersion, id.objectType, id.hash, id.qualifiers)
+}
+
+// Qualifier returns the decoded value of a qualifier and whether id has
+// it.
+func (id Identifier) Qualifier(key string) (string, bool) {
+	value, ok := id.Qualifiers()[key]
+	return value, ok
+}
+
+// Qualifiers returns a copy of id's qualifiers, decoded.
+func (id Identifier) Qualifiers() map[string]string {
+	if id.qualifiers == "" {
+		return map[string]string{}
+	}
+	return id.V1().Qualifiers
+}
+
+// WithQualifier returns id with a qualifier set to value, replacing any
+// value it had.
+func (id Identifier) WithQualifier(key, value string) Identifier {
+	qualifiers := id.Qualifiers()
+	qualifiers[key] = value
+	id.qualifiers = encodeQualifiers(qualifiers)
+	return id
+}
+
+// WithoutQualifier returns id without a qualifier.
+func (id Identifier) WithoutQualifier(key string) Identifier {
+	qualifiers := id.Qualifiers()
+	delete(qualifiers, key)
+	id.qualifiers = encodeQualifiers(qualifiers)
+	return id
+}
+
+// RedactionPolicy decides which qualifiers RedactedWith keeps; see the
+// version 1 RedactionPolicy.
+type RedactionPolicy = v1.RedactionPolicy
+
+// Redacted returns id without the qualifiers that may leak internal
+// information, following v1.DefaultRedactionPolicy, for logging.
+func (id Identifier) Redacted() Identifier {
+	return id.RedactedWith(v1.DefaultRedactionPolicy)
+}
+
+// RedactedWith returns id without the qualifiers policy does not allow and
+// with credentials stripped from its origin.
+func (id Identifier) RedactedWith(policy RedactionPolicy) Identifier {
+	if id.IsZero() {
+		return id
+	}
+	return fromV1(id.V1().RedactedWith(policy))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (id Identifier) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (id *Identifier) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
+
+// FromV1 converts a version 1 identifier, validating it, since its fields
+// may have been set to anything.
+func FromV1(id *v1.Identifier) (Identifier, error) {
+	if id == nil {
+		return Identifier{}, &Error{Op: "convert", Err: v1.ErrEmptySWHID}
+	}
+	return Parse(id.String())
+}
+
+// V1 returns id as a new version 1 identifier, which the caller may
+// modify.
+func (id Identifier) V1() *v1.Identifier {
+	if id.IsZero() {
+		return nil
+	}
+	converted, err := v1.Parse(id.String())
+	if err != nil {
+		panic("swhid: invalid identifier " + id.String())
+	}
+	return converted
+}
+
+// fromV1 converts a version 1 identifier known to be valid.
+func fromV1(id *v1.Identifier) Identifier {
+	return Identifier{
+		objectType: id.ObjectType,
+		hash:       id.ObjectHash,
+		qualifiers: encodeQualifiers(id.Qualifiers),
+	}
+}
+
+// encodeQualifiers returns the canonical suffix for qualifiers: the keys
+// of the specification in its order, then any others sorted, each value
+// encoded as version 1 encodes it.
+func encodeQualifiers(qualifiers map[string]string) string {
+	keys := make([]string, 0, len(qualifiers))
+	for key := range qualifiers {
+		keys = append(keys, key)
+	}
+	order := v1.CanonicalQualifierOrder()
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := slices.Index(order, keys[i]), slices.Index(order, keys[j])
+		switch {
+		case a >= 0 && b >= 0:
+			return a < b
+		case a >= 0 || b >= 0:
+			return a >= 0
+		}
+		return keys[i] < keys[j]
+	})
+
+	var b strings.Builder
+	for _, key := range keys {
+		// Version 1 writes a lone qualifier after the core SWHID, encoded.
+		one := &v1.Identifier{Scheme: v1.Scheme, Version: v1.SchemeVersion, Qualifiers: map[string]string{key: qualifiers[key]}}
+		b.WriteString(strings.TrimPrefix(one.String(), one.CoreSWHID()))
+	}
+	return b.String()
+}
@@ -0,0 +1,153 @@
+package swhid
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	v1 "github.com/andrew/swhid-go"
+)
+
+const helloSWHID = "swh:1:cnt:ce013625030ba8dba906f756967f9e9ca394464a"
+
+func TestParse(t *testing.T) {
+	id, err := Parse(helloSWHID + ";path=/a%3Bb;origin=https://example.com/repo")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if id.ObjectType() != ObjectTypeContent {
+		t.Errorf("ObjectType() = %v, want %v", id.ObjectType(), ObjectTypeContent)
+	}
+	if id.Hash() != "ce013625030ba8dba906f756967f9e9ca394464a" {
+		t.Errorf("Hash() = %q", id.Hash())
+	}
+
+	want := helloSWHID + ";origin=https://example.com/repo;path=/a%3Bb"
+	if id.String() != want {
+		t.Errorf("String() = %q, want %q", id.String(), want)
+	}
+	if path, ok := id.Qualifier(QualifierPath); !ok || path != "/a;b" {
+		t.Errorf("Qualifier(path) = %q, %v, want /a;b", path, ok)
+	}
+	if id.Core() != MustParse(helloSWHID) {
+		t.Errorf("Core() = %v, want %v", id.Core(), helloSWHID)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	_, err := Parse("swh:1:rvs:ce013625030ba8dba906f756967f9e9ca394464a")
+	if !errors.Is(err, ErrInvalidObjectType) || !errors.Is(err, v1.ErrInvalidObjectType) {
+		t.Fatalf("Parse() error = %v, want ErrInvalidObjectType", err)
+	}
+	var swhidErr *Error
+	if !errors.As(err, &swhidErr) || swhidErr.Op != "parse" {
+		t.Errorf("Parse() error = %#v, want *Error with Op parse", err)
+	}
+	var parseErr *v1.ParseError
+	if !errors.As(err, &parseErr) || len(parseErr.Suggestions) == 0 {
+		t.Errorf("Parse() error = %v, want suggestions", err)
+	}
+}
+
+func TestIdentifierComparable(t *testing.T) {
+	a := MustParse(helloSWHID).WithQualifier(QualifierPath, "/x").WithQualifier(QualifierOrigin, "https://example.com")
+	b := MustParse(helloSWHID + ";origin=https://example.com;path=/x")
+	if a != b {
+		t.Errorf("%v != %v", a, b)
+	}
+
+	seen := map[Identifier]bool{a: true}
+	if !seen[b] {
+		t.Error("equal identifiers are different map keys")
+	}
+
+	core := MustParse(helloSWHID)
+	withPath := core.WithQualifier(QualifierPath, "/x")
+	if core.String() != helloSWHID {
+		t.Errorf("WithQualifier() changed the receiver to %v", core)
+	}
+	if withPath.WithoutQualifier(QualifierPath) != core {
+		t.Errorf("WithoutQualifier() = %v, want %v", withPath.WithoutQualifier(QualifierPath), core)
+	}
+
+	quals := withPath.Qualifiers()
+	quals[QualifierPath] = "/changed"
+	if path, _ := withPath.Qualifier(QualifierPath); path != "/x" {
+		t.Errorf("changing Qualifiers() changed the identifier to %v", withPath)
+	}
+}
+
+func TestIdentifierZero(t *testing.T) {
+	var id Identifier
+	if !id.IsZero() || id.String() != "" || id.V1() != nil {
+		t.Errorf("zero Identifier = %q, IsZero() = %v", id, id.IsZero())
+	}
+	if MustParse(helloSWHID).IsZero() {
+		t.Error("IsZero() = true for a parsed identifier")
+	}
+}
+
+func TestIdentifierJSON(t *testing.T) {
+	in := struct{ ID Identifier }{MustParse(helloSWHID + ";lines=1-2")}
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if string(data) != `{"ID":"`+helloSWHID+`;lines=1-2"}` {
+		t.Errorf("Marshal() = %s", data)
+	}
+
+	var out struct{ ID Identifier }
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if out != in {
+		t.Errorf("Unmarshal() = %v, want %v", out.ID, in.ID)
+	}
+
+	if err := json.Unmarshal([]byte(`{"ID":"swh:1:cnt:xyz"}`), &out); !errors.Is(err, ErrInvalidObjectHash) {
+		t.Errorf("Unmarshal() error = %v, want ErrInvalidObjectHash", err)
+	}
+}
+
+func TestV1RoundTrip(t *testing.T) {
+	id := MustParse(helloSWHID + ";origin=https://example.com;visit=swh:1:snp:0000000000000000000000000000000000000000")
+
+	old := id.V1()
+	if old.String() != id.String() {
+		t.Errorf("V1() = %v, want %v", old, id)
+	}
+	old.Qualifiers[QualifierOrigin] = "changed"
+	if origin, _ := id.Qualifier(QualifierOrigin); origin != "https://example.com" {
+		t.Errorf("changing V1() changed the identifier to %v", id)
+	}
+
+	back, err := FromV1(id.V1())
+	if err != nil {
+		t.Fatalf("FromV1() error = %v", err)
+	}
+	if back != id {
+		t.Errorf("FromV1() = %v, want %v", back, id)
+	}
+
+	if _, err := FromV1(&v1.Identifier{Scheme: "swh", Version: 1, ObjectType: "cnt", ObjectHash: "bad"}); !errors.Is(err, ErrInvalidObjectHash) {
+		t.Errorf("FromV1() error = %v, want ErrInvalidObjectHash", err)
+	}
+	if _, err := FromV1(nil); !errors.Is(err, ErrEmptySWHID) {
+		t.Errorf("FromV1(nil) error = %v, want ErrEmptySWHID", err)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	id := MustParse(helloSWHID + ";origin=https://token@github.com/example/repo;path=/internal/notes.txt;lines=2")
+	want := MustParse(helloSWHID + ";origin=https://github.com/example/repo;lines=2")
+	if got := id.Redacted(); got != want {
+		t.Errorf("Redacted() = %v, want %v", got, want)
+	}
+	if got := id.RedactedWith(RedactionPolicy{KeepPath: true}); got != MustParse(helloSWHID+";path=/internal/notes.txt;lines=2") {
+		t.Errorf("RedactedWith() = %v", got)
+	}
+	if got := (Identifier{}).Redacted(); !got.IsZero() {
+		t.Errorf("Redacted() of the zero Identifier = %v", got)
+	}
+}
@@ -0,0 +1,129 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/andrew/swhid-go/objects"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// ErrObjectCorrupt is returned when a raw Git object does not hash to its own ID.
+var ErrObjectCorrupt = errors.New("object content does not match its hash")
+
+// ByteDifference describes a line on which the raw Git object and the
+// re-serialized object disagree.
+type ByteDifference struct {
+	Line         int    // 1-based line number
+	Offset       int    // byte offset of the first differing byte in the raw object
+	Raw          string // line as stored in the repository, without the newline
+	Reserialized string // line as produced by the objects package, without the newline
+}
+
+// SerializationReport compares a raw Git object with its re-serialization.
+type SerializationReport struct {
+	Raw          []byte
+	Reserialized []byte
+	Differences  []ByteDifference
+}
+
+// Match reports whether re-serialization reproduced the raw object exactly.
+func (r *SerializationReport) Match() bool {
+	return bytes.Equal(r.Raw, r.Reserialized)
+}
+
+// FromRevisionVerbatim computes the SWHID for a Git revision by taking the
+// commit's own SHA-1 instead of re-serializing its metadata. For SHA-1
+// repositories the commit ID is the revision ID, so serializer bugs cannot
+// change the result. The raw object is checked against its ID, and the
+// returned report lists any differences between the stored commit and the
+// payload FromRevision would hash.
+func FromRevisionVerbatim(repoPath, ref string) (*Identifier, *SerializationReport, error) {
+	repo, err := openRepository(repoPath, false, false)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	return FromRevisionVerbatimRepo(repo, ref)
+}
+
+// FromRevisionVerbatimRepo is like FromRevisionVerbatim for an already open
+// repository.
+func FromRevisionVerbatimRepo(repo *git.Repository, ref string) (*Identifier, *SerializationReport, error) {
+	commit, err := resolveCommitIn(repo, ref)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	raw, err := readRawObject(repo, plumbing.CommitObject, commit.Hash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read commit: %w", err)
+	}
+
+	if plumbing.ComputeHash(plumbing.CommitObject, raw) != commit.Hash {
+		return nil, nil, fmt.Errorf("%w: commit %s", ErrObjectCorrupt, commit.Hash)
+	}
+
+	id, err := NewIdentifier(ObjectTypeRevision, commit.Hash.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reserialized := objects.SerializeRevision(revisionMetadata(repo, commit))
+	report := &SerializationReport{
+		Raw:          raw,
+		Reserialized: reserialized,
+		Differences:  compareSerialization(raw, reserialized),
+	}
+
+	return id, report, nil
+}
+
+func compareSerialization(raw, reserialized []byte) []ByteDifference {
+	rawLines := bytes.Split(raw, []byte("\n"))
+	newLines := bytes.Split(reserialized, []byte("\n"))
+
+	count := len(rawLines)
+	if len(newLines) > count {
+		count = len(newLines)
+	}
+
+	var diffs []ByteDifference
+	offset := 0
+	for i := 0; i < count; i++ {
+		var a, b []byte
+		if i < len(rawLines) {
+			a = rawLines[i]
+		}
+		if i < len(newLines) {
+			b = newLines[i]
+		}
+
+		if !bytes.Equal(a, b) || (i < len(rawLines)) != (i < len(newLines)) {
+			j := 0
+			for j < len(a) && j < len(b) && a[j] == b[j] {
+				j++
+			}
+			at := offset + j
+			if at > len(raw) {
+				at = len(raw)
+			}
+			diffs = append(diffs, ByteDifference{
+				Line:         i + 1,
+				Offset:       at,
+				Raw:          string(a),
+				Reserialized: string(b),
+			})
+		}
+
+		if i < len(rawLines) {
+			offset += len(a) + 1
+		}
+	}
+
+	return diffs
+}
@@ -0,0 +1,60 @@
+//go:build !js && !wasip1
+
+package swhid
+
+import (
+	"testing"
+)
+
+func TestFromRevisionVerbatim(t *testing.T) {
+	repoPath, _, hash := newTestRepo(t)
+
+	id, report, err := FromRevisionVerbatim(repoPath, "")
+	if err != nil {
+		t.Fatalf("FromRevisionVerbatim() error = %v", err)
+	}
+
+	if id.ObjectHash != hash.String() {
+		t.Errorf("FromRevisionVerbatim() hash = %v, want %v", id.ObjectHash, hash.String())
+	}
+
+	if !report.Match() {
+		t.Errorf("Match() = false, differences: %+v", report.Differences)
+	}
+
+	if len(report.Differences) != 0 {
+		t.Errorf("Differences = %+v, want none", report.Differences)
+	}
+}
+
+func TestCompareSerialization(t *testing.T) {
+	raw := []byte("tree abc\nauthor A <a> 1 +0200\n\nmsg\n")
+	reserialized := []byte("tree abc\nauthor A <a> 1 +0000\n\nmsg\n")
+
+	diffs := compareSerialization(raw, reserialized)
+	if len(diffs) != 1 {
+		t.Fatalf("compareSerialization() returned %d differences, want 1", len(diffs))
+	}
+
+	if diffs[0].Line != 2 {
+		t.Errorf("Line = %d, want 2", diffs[0].Line)
+	}
+
+	// "tree abc\n" is 9 bytes; the author lines diverge after "+0".
+	if diffs[0].Offset != 9+len("author A <a> 1 +0") {
+		t.Errorf("Offset = %d, want %d", diffs[0].Offset, 9+len("author A <a> 1 +0"))
+	}
+}
+
+func TestCompareSerializationExtraLine(t *testing.T) {
+	raw := []byte("tree abc\n")
+	reserialized := []byte("tree abc\nparent def\n")
+
+	diffs := compareSerialization(raw, reserialized)
+	if len(diffs) == 0 {
+		t.Fatal("compareSerialization() expected differences for extra line")
+	}
+	if diffs[0].Raw != "" || diffs[0].Reserialized != "parent def" {
+		t.Errorf("difference = %+v, want missing parent line", diffs[0])
+	}
+}
@@ -0,0 +1,39 @@
+package swhid
+
+import (
+	"fmt"
+	"maps"
+)
+
+// VersionError is returned by As when an identifier cannot be expressed in
+// the requested SWHID version. It wraps ErrInvalidVersion.
+type VersionError struct {
+	From, To int
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("%v: swh:%d cannot be derived from swh:%d", ErrInvalidVersion, e.To, e.From)
+}
+
+func (e *VersionError) Unwrap() error { return ErrInvalidVersion }
+
+// versionConverters derive an identifier of one SWHID version from one of
+// another, keyed by the source and target versions. Only version 1 is
+// published so far; a converter is added here once a later version defines
+// how its identifiers relate to those of version 1.
+var versionConverters = map[[2]int]func(*Identifier) (*Identifier, error){}
+
+// As returns the identifier of the same object in the given SWHID version,
+// or a *VersionError if it cannot be derived, for instance because the
+// version hashes objects differently. As with id's own version returns a
+// copy of id.
+func (id *Identifier) As(version int) (*Identifier, error) {
+	if version == id.Version {
+		return id.WithQualifiers(maps.Clone(id.Qualifiers)), nil
+	}
+	convert, ok := versionConverters[[2]int{id.Version, version}]
+	if !ok {
+		return nil, &VersionError{From: id.Version, To: version}
+	}
+	return convert(id)
+}
@@ -0,0 +1,34 @@
+package swhid
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAs(t *testing.T) {
+	id, err := Parse("swh:1:cnt:94a9ed024d3859793618152ea559a168bbcbb5e2;origin=https://github.com/example/repo")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	same, err := id.As(SchemeVersion)
+	if err != nil {
+		t.Fatalf("As(%d) error = %v", SchemeVersion, err)
+	}
+	if !same.Equal(id) {
+		t.Errorf("As(%d) = %s, want %s", SchemeVersion, same, id)
+	}
+	same.Qualifiers[QualifierOrigin] = "changed"
+	if id.Qualifiers[QualifierOrigin] == "changed" {
+		t.Error("As() shares its qualifiers with the receiver")
+	}
+
+	_, err = id.As(2)
+	var verr *VersionError
+	if !errors.As(err, &verr) || verr.From != 1 || verr.To != 2 {
+		t.Fatalf("As(2) error = %v, want *VersionError from 1 to 2", err)
+	}
+	if !errors.Is(err, ErrInvalidVersion) {
+		t.Errorf("As(2) error = %v, want ErrInvalidVersion", err)
+	}
+}
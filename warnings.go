@@ -0,0 +1,57 @@
+package swhid
+
+import (
+	"fmt"
+	"os"
+)
+
+// Kinds of Warning.
+const (
+	WarnSpecialFile     = "special-file"     // a FIFO, socket or device, left out of the tree
+	WarnModeFallback    = "mode-fallback"    // not in the enclosing repository's index; executable bit read from disk
+	WarnLFSPointer      = "lfs-pointer"      // a Git LFS pointer, hashed as the pointer
+	WarnDanglingSymlink = "dangling-symlink" // with FollowSymlinks, a link to nothing, hashed as a link
+)
+
+// lfsPointerPrefix starts every Git LFS pointer file.
+var lfsPointerPrefix = []byte("version https://git-lfs.github.com/spec/v1\n")
+
+// maxLFSPointerSize bounds the size of LFS pointer files, which hold only
+// a version, an object ID and a size.
+const maxLFSPointerSize = 1024
+
+// Warning is something hashing a directory did that the caller may not
+// expect but that did not stop it, reported through TreeOptions.Warn.
+type Warning struct {
+	Path    string // from the root, slash-separated
+	Kind    string
+	Message string
+}
+
+func (w Warning) String() string {
+	return w.Path + ": " + w.Message
+}
+
+// warn reports a warning about relPath to opts.Warn, if set.
+func (opts TreeOptions) warn(relPath, kind, format string, args ...any) {
+	if opts.Warn != nil {
+		opts.Warn(Warning{Path: relPath, Kind: kind, Message: fmt.Sprintf(format, args...)})
+	}
+}
+
+// specialFileKind names the type of a file that is neither regular, a
+// directory nor a symlink.
+func specialFileKind(mode os.FileMode) string {
+	switch {
+	case mode&os.ModeNamedPipe != 0:
+		return "named pipe"
+	case mode&os.ModeSocket != 0:
+		return "socket"
+	case mode&os.ModeCharDevice != 0:
+		return "character device"
+	case mode&os.ModeDevice != 0:
+		return "block device"
+	default:
+		return "special file"
+	}
+}
@@ -0,0 +1,73 @@
+//go:build unix
+
+package swhid
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/andrew/swhid-go/objects"
+)
+
+func TestTreeOptionsWarn(t *testing.T) {
+	dir, repo, _ := newTestRepo(t)
+	pointer := "version https://git-lfs.github.com/spec/v1\noid sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824\nsize 5\n"
+	if err := os.WriteFile(filepath.Join(dir, "big.bin"), []byte(pointer), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	commitAll(t, repo, "Add pointer\n")
+
+	if err := os.WriteFile(filepath.Join(dir, "new.txt"), []byte("new\n"), 0755); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := syscall.Mkfifo(filepath.Join(dir, "pipe"), 0644); err != nil {
+		t.Skipf("Cannot create a FIFO: %v", err)
+	}
+	if err := os.Symlink("missing", filepath.Join(dir, "dangling")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	want := []string{
+		"big.bin " + WarnLFSPointer,
+		"dangling " + WarnDanglingSymlink,
+		"new.txt " + WarnModeFallback,
+		"pipe " + WarnSpecialFile,
+	}
+	for _, concurrency := range []int{0, 2} {
+		var (
+			mu  sync.Mutex
+			got []string
+		)
+		opts := TreeOptions{
+			FollowSymlinks: true,
+			Concurrency:    concurrency,
+			Warn: func(w Warning) {
+				mu.Lock()
+				defer mu.Unlock()
+				got = append(got, w.Path+" "+w.Kind)
+			},
+		}
+		node, err := TreeFromDirectoryPathWithOptions(dir, opts)
+		if err != nil {
+			t.Fatalf("TreeFromDirectoryPathWithOptions() error = %v", err)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Concurrency %d: warnings = %q, want %q", concurrency, got, want)
+		}
+		for _, child := range node.Children {
+			switch {
+			case child.Name == "pipe":
+				t.Errorf("Concurrency %d: FIFO hashed as %v", concurrency, child.ID)
+			case child.Name == "new.txt" && child.Type != objects.EntryTypeExecutable:
+				// Untracked files still take their mode from disk
+				t.Errorf("Concurrency %d: new.txt has type %v, want executable", concurrency, child.Type)
+			}
+		}
+	}
+}